@@ -4,14 +4,22 @@ import (
 	"BinaryCRUD/backend/compression"
 	"BinaryCRUD/backend/crypto"
 	"BinaryCRUD/backend/dao"
+	"BinaryCRUD/backend/events"
+	"BinaryCRUD/backend/index"
+	"BinaryCRUD/backend/migrate"
+	"BinaryCRUD/backend/oplog"
 	"BinaryCRUD/backend/utils"
 	"context"
-	"encoding/binary"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
+	"time"
 )
 
 // App struct
@@ -21,21 +29,64 @@ type App struct {
 	orderDAO          *dao.OrderDAO
 	promotionDAO      *dao.PromotionDAO
 	orderPromotionDAO *dao.OrderPromotionDAO
+	templateDAO       *dao.TemplateDAO
+	auditDAO          *dao.AuditDAO
+	priceHistoryDAO   *dao.PriceHistoryDAO
+	oplog             *oplog.Log
+	events            events.Bus // data change notifications for Go subscribers and the frontend
+	stopWebhooks      func()     // stops the webhook worker, nil when no webhooks are configured
+	replication       replicationState
+	apiAddr           string      // address the REST API is served on from startup, empty to not serve it
+	apiMetrics        bool        // also serve Prometheus metrics next to the REST API
+	api               *restServer // the running REST API, nil when it is not served
+	grpcAddr          string      // address the gRPC API is served on from startup, empty to not serve it
+	grpcAPI           *grpcServer // the running gRPC API, nil when it is not served
+	remoteMu          sync.Mutex  // runs REST and gRPC requests one at a time
+	actor             string
+	uniqueItemNames   bool // reject items whose normalized name is already in use
+	currencyRates     *utils.CurrencyRates
+	compaction        compactionStatus
+	operations        operationRegistry // long operations running, for progress events and CancelOperation
+	undo              undoHistory       // recent mutations, for Undo
+	config            utils.Config      // tunables loaded from the config file, read with currentConfig
+	configMu          sync.RWMutex      // guards config, which UpdateConfig replaces while operations read it
+	dataLock          *utils.DataLock
+	readOnly          bool           // read-only mode requested by the build flag or SetReadOnly
+	readOnlyReason    string         // set when another instance holds the data directory
+	metrics           *utils.Metrics // operation, write, index and compaction counters since startup
 	logger            *Logger
 	toast             *Toast
 }
 
 // NewApp creates a new App application struct
 func NewApp() *App {
+	utils.SetDataDir(utils.DataDirFromEnv())
 	logger := NewLogger(1000) // Store up to 1000 log entries
+	config := loadConfig(logger)
 
-	return &App{
-		itemDAO:           dao.NewItemDAO(utils.BinPath("items.bin")),
+	app := &App{
+		itemDAO:           dao.NewItemDAO(utils.BinPath("items.bin"), itemDAOOptions(config)...),
 		orderDAO:          dao.NewOrderDAO(utils.BinPath("orders.bin")),
 		promotionDAO:      dao.NewPromotionDAO(utils.BinPath("promotions.bin")),
 		orderPromotionDAO: dao.NewOrderPromotionDAO(utils.BinPath("order_promotions.bin")),
+		templateDAO:       dao.NewTemplateDAO(utils.BinPath("templates.bin")),
+		auditDAO:          dao.NewAuditDAO(utils.BinPath("audit.bin")),
+		priceHistoryDAO:   dao.NewPriceHistoryDAO(utils.BinPath("price_history.bin")),
+		oplog:             oplog.New(utils.OplogPath()),
+		actor:             currentActor(),
+		currencyRates:     loadCurrencyRates(logger),
+		config:            config,
+		metrics:           utils.NewMetrics(),
 		logger:            logger,
+		readOnly:          ReadOnly == "true",
 	}
+	if app.readOnly {
+		utils.SetSidecarsReadOnly(true)
+	} else {
+		app.lockDataDir()
+	}
+	app.startWebhooks()
+	return app
 }
 
 // startup is called when the app starts. The context is saved
@@ -43,14 +94,45 @@ func NewApp() *App {
 func (a *App) startup(ctx context.Context) {
 	a.ctx = ctx
 	a.toast = NewToast(a)
+	a.forwardEventsToFrontend()
 	a.logger.Info("Application started")
+	if a.readOnlyReason != "" {
+		a.toast.Warning("Data opened read-only: " + a.readOnlyReason)
+	} else if a.readOnly {
+		a.logger.Info("Read-only mode: changes to the data are disabled")
+	}
+	a.purgeTrash()
+	if a.apiAddr != "" {
+		if err := a.startAPIServer(); err != nil {
+			a.logger.Error(err.Error())
+			a.toast.Error(err.Error())
+		}
+	}
+	if a.grpcAddr != "" {
+		if err := a.startGRPCServer(); err != nil {
+			a.logger.Error(err.Error())
+			a.toast.Error(err.Error())
+		}
+	}
 }
 
 // shutdown is called when the app is closing
 // If CleanupOnExit flag is set to "true", it cleans up all data files
 func (a *App) shutdown(ctx context.Context) {
-	if CleanupOnExit == "true" {
+	defer a.logger.Close()
+	defer a.releaseDataDir()
+	defer a.closeDAOs()
+	defer a.closeWebhooks()
+	defer a.stopReplication()
+
+	// No more API requests while the data is cleaned up and closed
+	a.stopAPIServer()
+	a.stopGRPCServer()
+	a.stopCompaction()
+
+	if CleanupOnExit == "true" && a.checkWritable() == nil {
 		a.logger.Info("Application shutting down, cleaning up files...")
+		a.closeDAOs()
 		a.cleanupOnExit()
 		a.logger.Info("Cleanup complete, goodbye!")
 	} else {
@@ -58,6 +140,58 @@ func (a *App) shutdown(ctx context.Context) {
 	}
 }
 
+// reloadDAOs recreates all DAOs, reloading (or rebuilding) their indexes from disk
+// The files kept open by the previous DAOs are closed once a running background compaction has finished
+func (a *App) reloadDAOs() {
+	a.closeDAOs()
+	a.itemDAO = dao.NewItemDAO(utils.BinPath("items.bin"), itemDAOOptions(a.currentConfig())...)
+	a.orderDAO = dao.NewOrderDAO(utils.BinPath("orders.bin"))
+	a.promotionDAO = dao.NewPromotionDAO(utils.BinPath("promotions.bin"))
+	a.orderPromotionDAO = dao.NewOrderPromotionDAO(utils.BinPath("order_promotions.bin"))
+	a.templateDAO = dao.NewTemplateDAO(utils.BinPath("templates.bin"))
+	a.auditDAO = dao.NewAuditDAO(utils.BinPath("audit.bin"))
+	a.priceHistoryDAO = dao.NewPriceHistoryDAO(utils.BinPath("price_history.bin"))
+}
+
+// itemDAOOptions returns the item DAO options set by a config
+func itemDAOOptions(config utils.Config) []dao.ItemOption {
+	if !config.GroupCommit.Enabled() {
+		return nil
+	}
+	delay := time.Duration(config.GroupCommit.MaxDelayMs) * time.Millisecond
+	return []dao.ItemOption{dao.WithGroupCommit(config.GroupCommit.MaxRecords, delay)}
+}
+
+// closeDAOs closes the files the DAOs keep open, before the files are deleted or replaced
+// A DAO used afterwards reopens its file
+// A running background compaction is waited for first, since it writes the files until it returns
+func (a *App) closeDAOs() {
+	a.waitForCompaction()
+	closers := map[string]func() error{
+		"items":            a.itemDAO.Close,
+		"orders":           a.orderDAO.Close,
+		"promotions":       a.promotionDAO.Close,
+		"order_promotions": a.orderPromotionDAO.Close,
+		"templates":        a.templateDAO.Close,
+	}
+	for name, closeFile := range closers {
+		if err := closeFile(); err != nil {
+			a.logger.Warn(fmt.Sprintf("Failed to close %s file: %v", name, err))
+		}
+	}
+}
+
+// recordOp appends a mutating operation to the oplog and the undo history and publishes its event
+// Failures are logged but never fail the operation itself
+func (a *App) recordOp(op oplog.Operation) {
+	op.Timestamp = time.Now().UTC()
+	if err := a.oplog.Append(op); err != nil {
+		a.logger.Warn(fmt.Sprintf("Failed to record %s in oplog: %v", op.Type, err))
+	}
+	a.undo.push(op)
+	a.publishOp(op)
+}
+
 // cleanupOnExit deletes all data files silently (no toasts since UI is closing)
 func (a *App) cleanupOnExit() {
 	results, err := utils.CleanupDataFiles(a.logger.Info)
@@ -80,7 +214,8 @@ type PriceCalculationResult struct {
 	Errors     []error
 }
 
-// calculateTotalPrice calculates the total price of items by reading each item's price
+// calculateTotalPrice calculates the total price of items, reading all of them in a single batch
+// Prices in other currencies are converted to the base currency
 // If strict is true, returns an error on the first missing item
 // If strict is false, skips missing items and logs warnings
 func (a *App) calculateTotalPrice(itemIDs []uint64, strict bool, entityName string) (*PriceCalculationResult, error) {
@@ -88,8 +223,16 @@ func (a *App) calculateTotalPrice(itemIDs []uint64, strict bool, entityName stri
 		ValidItems: make([]uint64, 0, len(itemIDs)),
 	}
 
+	items, err := a.itemDAO.ReadMany(itemIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read items of %s: %w", entityName, err)
+	}
+
 	for _, itemID := range itemIDs {
-		_, _, priceInCents, err := a.itemDAO.Read(itemID)
+		price, err := uint64(0), utils.WithCode(utils.CodeNotFound, fmt.Errorf("item not found"), utils.RecordDetails("item", itemID))
+		if item, ok := items[itemID]; ok {
+			price, err = a.currencyRates.ToBase(item.PriceInCents, itemCurrency(item))
+		}
 		if err != nil {
 			if strict {
 				return nil, fmt.Errorf("failed to read item %d: %w", itemID, err)
@@ -99,7 +242,7 @@ func (a *App) calculateTotalPrice(itemIDs []uint64, strict bool, entityName stri
 			continue
 		}
 		// Use safe addition to prevent overflow
-		newTotal, err := utils.SafeAddUint64(result.TotalPrice, priceInCents)
+		newTotal, err := utils.SafeAddUint64(result.TotalPrice, price)
 		if err != nil {
 			return nil, fmt.Errorf("price overflow calculating total for %s: %w", entityName, err)
 		}
@@ -111,57 +254,159 @@ func (a *App) calculateTotalPrice(itemIDs []uint64, strict bool, entityName stri
 }
 
 // AddItem writes an item to the binary file with a price in cents and returns the assigned ID
-func (a *App) AddItem(text string, priceInCents uint64) (uint64, error) {
+func (a *App) AddItem(text string, priceInCents uint64) (_ uint64, err error) {
+	start := time.Now()
+	defer a.track("AddItem", start, &err)
+	if err := a.checkWritable(); err != nil {
+		return 0, err
+	}
+
 	// Validate item name
-	if err := utils.ValidateName(text); err != nil {
+	if err := utils.ValidateEntityName("item", text); err != nil {
 		return 0, fmt.Errorf("invalid item name: %w", err)
 	}
 
 	// Validate price
-	if err := utils.ValidatePrice(priceInCents); err != nil {
+	if err := utils.ValidateEntityPrice("item", priceInCents); err != nil {
 		return 0, fmt.Errorf("invalid price: %w", err)
 	}
 
+	// Check for an active item with the same normalized name
+	duplicates, err := a.itemDAO.FindByName(text)
+	if err != nil {
+		return 0, err
+	}
+	if len(duplicates) > 0 {
+		if a.uniqueItemNames {
+			return 0, fmt.Errorf("an item named %q already exists (ID %d)", text, duplicates[0])
+		}
+		a.logger.Warn(fmt.Sprintf("Item name %q is already used by item #%d", text, duplicates[0]))
+	}
+
 	assignedID, err := a.itemDAO.Write(text, priceInCents)
 	if err != nil {
 		return 0, err
 	}
+	a.recordOp(oplog.Operation{Type: oplog.OpAddItem, ID: assignedID, Name: text, Price: priceInCents})
+	a.recordAudit(dao.AuditCreate, "item", assignedID, "", itemSummary(text, priceInCents))
 
-	a.logger.Info(fmt.Sprintf("Created item #%d: %s ($%.2f)", assignedID, text, float64(priceInCents)/100))
+	a.logger.InfoWith(fmt.Sprintf("Created item #%d: %s ($%.2f)", assignedID, text, float64(priceInCents)/100), entityLog("item", assignedID, dao.AuditCreate, start)...)
 
 	return assignedID, nil
 }
 
 // GetItem retrieves an item by ID from the binary file (uses index with automatic fallback)
-func (a *App) GetItem(id uint64) (map[string]any, error) {
-	itemID, name, priceInCents, err := a.itemDAO.Read(id)
+func (a *App) GetItem(id uint64) (_ map[string]any, err error) {
+	start := time.Now()
+	defer a.track("GetItem", start, &err)
+	item, err := a.itemDAO.ReadItem(id)
 	if err != nil {
 		return nil, err
 	}
 
-	a.logger.Info(fmt.Sprintf("Read item ID %d", id))
+	a.logger.InfoWith(fmt.Sprintf("Read item ID %d", id), entityLog("item", id, "read", start)...)
 
-	return map[string]any{
-		"id":           itemID,
-		"name":         name,
-		"priceInCents": priceInCents,
-	}, nil
+	return addExternalIDField(a.addCurrencyFields(addStockFields(map[string]any{
+		"id":           item.ID,
+		"name":         item.Name,
+		"priceInCents": item.PriceInCents,
+	}, item), item), item), nil
+}
+
+// UpdateItem changes the name and price of an item, keeping its ID and stock
+// Price changes are recorded in the item's price history
+func (a *App) UpdateItem(id uint64, text string, priceInCents uint64) (_ map[string]any, err error) {
+	start := time.Now()
+	defer a.track("UpdateItem", start, &err)
+	if err := a.checkWritable(); err != nil {
+		return nil, err
+	}
+
+	if err := utils.ValidateEntityName("item", text); err != nil {
+		return nil, fmt.Errorf("invalid item name: %w", err)
+	}
+	if err := utils.ValidateEntityPrice("item", priceInCents); err != nil {
+		return nil, fmt.Errorf("invalid price: %w", err)
+	}
+
+	item, err := a.itemDAO.ReadItem(id)
+	if err != nil {
+		return nil, err
+	}
+
+	duplicates, err := a.itemDAO.FindByName(text)
+	if err != nil {
+		return nil, err
+	}
+	for _, duplicate := range duplicates {
+		if duplicate == id {
+			continue
+		}
+		if a.uniqueItemNames {
+			return nil, fmt.Errorf("an item named %q already exists (ID %d)", text, duplicate)
+		}
+		a.logger.Warn(fmt.Sprintf("Item name %q is already used by item #%d", text, duplicate))
+		break
+	}
+
+	if item.Name == text {
+		err = a.itemDAO.UpdatePrice(id, priceInCents)
+	} else {
+		err = a.itemDAO.Update(id, text, priceInCents)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update item: %w", err)
+	}
+	a.recordOp(oplog.Operation{Type: oplog.OpUpdateItem, ID: id, Name: text, Price: priceInCents, Extensions: item.Extensions})
+	a.recordAudit(dao.AuditUpdate, "item", id, itemSummary(item.Name, item.PriceInCents), itemSummary(text, priceInCents))
+	if item.PriceInCents != priceInCents {
+		a.recordPriceChange(id, item.PriceInCents, priceInCents)
+	}
+
+	a.logger.InfoWith(fmt.Sprintf("Updated item #%d: %s ($%.2f)", id, text, float64(priceInCents)/100), entityLog("item", id, dao.AuditUpdate, start)...)
+
+	return a.GetItem(id)
 }
 
 // DeleteItem marks an item as deleted by flipping its tombstone bit
-func (a *App) DeleteItem(id uint64) error {
-	err := a.itemDAO.Delete(id)
+func (a *App) DeleteItem(id uint64) (err error) {
+	start := time.Now()
+	defer a.track("DeleteItem", start, &err)
+	if err := a.checkWritable(); err != nil {
+		return err
+	}
+
+	// The deleted item is logged so that Undo can write it back
+	op := oplog.Operation{Type: oplog.OpDeleteItem, ID: id}
+	before := ""
+	if item, err := a.itemDAO.ReadItem(id); err == nil {
+		before = itemSummary(item.Name, item.PriceInCents)
+		op.Name, op.Price, op.Extensions = item.Name, item.PriceInCents, item.Extensions
+	}
+
+	err = a.itemDAO.Delete(id)
 	if err != nil {
 		return err
 	}
+	a.recordOp(op)
+	a.recordAudit(dao.AuditDelete, "item", id, before, "")
 
-	a.logger.Info(fmt.Sprintf("Deleted item with ID: %d", id))
+	a.logger.InfoWith(fmt.Sprintf("Deleted item with ID: %d", id), entityLog("item", id, dao.AuditDelete, start)...)
+	a.checkCompactionPolicy()
 	return nil
 }
 
-// DeleteAllFiles deletes all generated data (bin, indexes, compressed, keys) but keeps seed folder
-func (a *App) DeleteAllFiles() error {
-	results, err := utils.CleanupDataFiles(a.logger.Info)
+// DeleteAllFiles moves all generated data (bin, indexes, compressed, keys) to the trash but keeps seed folder
+// The files can be brought back with RestoreFromTrash until they are purged
+func (a *App) DeleteAllFiles() (err error) {
+	defer a.track("DeleteAllFiles", time.Now(), &err)
+	if err := a.checkWritable(); err != nil {
+		return err
+	}
+
+	a.closeDAOs()
+	trash := utils.NewTrash()
+	results, err := utils.TrashDataFiles(trash)
 	if err != nil {
 		a.logger.Warn(fmt.Sprintf("Error during cleanup: %v", err))
 	}
@@ -171,6 +416,7 @@ func (a *App) DeleteAllFiles() error {
 		utils.IndexDir:      "indexes",
 		utils.CompressedDir: "compressed files",
 		utils.KeysDir:       "encryption keys",
+		utils.OplogDir:      "operation logs",
 	}
 
 	totalDeleted := 0
@@ -181,7 +427,7 @@ func (a *App) DeleteAllFiles() error {
 			if name == "" {
 				name = result.Folder
 			}
-			a.toast.Success(fmt.Sprintf("Deleted all %s (%d)", name, result.Count))
+			a.toast.Success(fmt.Sprintf("Moved all %s to trash (%d)", name, result.Count))
 		}
 	}
 
@@ -189,41 +435,68 @@ func (a *App) DeleteAllFiles() error {
 		a.toast.Info("No files to delete")
 	}
 
-	a.logger.Info(fmt.Sprintf("Deleted %d file(s) from bin, indexes, compressed, and keys folders", totalDeleted))
+	a.logger.Info(fmt.Sprintf("Moved %d file(s) from bin, indexes, compressed, keys, and oplog folders to trash entry %s", totalDeleted, trash.Name()))
+	a.purgeTrash()
 
-	// Reset RSA crypto singleton so new keys are generated on next use
+	// Reset the crypto singletons so a new data key is generated on next use
 	crypto.Reset()
 
 	// Reload all DAOs to clear in-memory indexes
-	a.itemDAO = dao.NewItemDAO(utils.BinPath("items.bin"))
-	a.orderDAO = dao.NewOrderDAO(utils.BinPath("orders.bin"))
-	a.promotionDAO = dao.NewPromotionDAO(utils.BinPath("promotions.bin"))
-	a.orderPromotionDAO = dao.NewOrderPromotionDAO(utils.BinPath("order_promotions.bin"))
-	a.logger.Info("Cleared all in-memory indexes and RSA keys")
+	a.reloadDAOs()
+	a.logger.Info("Cleared all in-memory indexes and encryption keys")
+	a.publish(events.Event{Type: events.DataReloaded})
 
 	return nil
 }
 
-// GetLogs returns all current log entries
-func (a *App) GetLogs() []LogEntry {
-	return a.logger.GetLogs()
+// GetLogs returns the log entries at or above level (all levels when empty) logged after since
+// (an RFC 3339 time, all entries when empty), keeping the most recent limit entries (all when 0)
+func (a *App) GetLogs(level string, limit int, since string) (_ []LogEntry, err error) {
+	defer a.track("GetLogs", time.Now(), &err)
+	minLevel, err := utils.ParseLogLevel(level)
+	if err != nil {
+		return nil, err
+	}
+	if limit < 0 {
+		return nil, fmt.Errorf("limit must not be negative")
+	}
+	var after time.Time
+	if since != "" {
+		if after, err = time.Parse(time.RFC3339Nano, since); err != nil {
+			return nil, fmt.Errorf("invalid since time: %w", err)
+		}
+	}
+	return a.logger.GetLogs(minLevel, limit, after), nil
+}
+
+// GetLogsForEntity returns the log entries about one record, oldest first
+// entity is "item", "order" or "promotion"
+func (a *App) GetLogsForEntity(entity string, id uint64) []LogEntry {
+	defer a.track("GetLogsForEntity", time.Now(), nil)
+	return a.logger.GetLogsForEntity(strings.ToLower(entity), id)
 }
 
 // ClearLogs clears all log entries
 func (a *App) ClearLogs() {
+	defer a.track("ClearLogs", time.Now(), nil)
 	a.logger.Clear()
 }
 
 // ItemEntry represents an item in the JSON file
 type ItemEntry struct {
-	Name         string `json:"name"`
-	PriceInCents uint64 `json:"priceInCents"`
+	Name         string  `json:"name"`
+	PriceInCents uint64  `json:"priceInCents"`
+	Stock        *uint64 `json:"stock,omitempty"`      // nil when the item does not track stock
+	Currency     string  `json:"currency,omitempty"`   // empty for the base currency
+	ExternalID   string  `json:"externalId,omitempty"` // UUID used by other systems, empty when unset
 }
 
 // PromotionEntry represents a promotion in the JSON file
 type PromotionEntry struct {
-	Name    string   `json:"name"`
-	ItemIDs []uint64 `json:"itemIDs"`
+	Name          string   `json:"name"`
+	ItemIDs       []uint64 `json:"itemIDs"`
+	DiscountType  string   `json:"discountType,omitempty"`  // "percent" or "fixed"
+	DiscountValue uint64   `json:"discountValue,omitempty"` // percentage or cents
 }
 
 // OrderEntry represents an order in the JSON file
@@ -234,9 +507,12 @@ type OrderEntry struct {
 }
 
 // OrderPromotionEntry represents an order-promotion relationship in the JSON file
+// Exports also carry when the promotion was applied and the discount it granted, when the link recorded them
 type OrderPromotionEntry struct {
-	OrderID     uint64 `json:"orderID"`
-	PromotionID uint64 `json:"promotionID"`
+	OrderID        uint64 `json:"orderID"`
+	PromotionID    uint64 `json:"promotionID"`
+	AppliedAt      string `json:"appliedAt,omitempty"` // RFC3339
+	DiscountAmount uint64 `json:"discountAmount,omitempty"`
 }
 
 // IndexEntry represents an entry in a B+ tree index
@@ -268,28 +544,214 @@ func (a *App) getIndexContentsFromTree(allEntries map[uint64]int64, indexName st
 	}
 }
 
+// GetItemCacheStats returns how many item reads were served from memory
+func (a *App) GetItemCacheStats() map[string]any {
+	defer a.track("GetItemCacheStats", time.Now(), nil)
+	stats := a.itemDAO.CacheStats()
+	return map[string]any{
+		"capacity": stats.Capacity,
+		"size":     stats.Size,
+		"hits":     stats.Hits,
+		"misses":   stats.Misses,
+	}
+}
+
+// GetIndexStatus reports which indexes have finished loading in the background since startup
+// Until an index is ready, reads scan its data file and writes wait for it
+func (a *App) GetIndexStatus() map[string]any {
+	defer a.track("GetIndexStatus", time.Now(), nil)
+	indexes := map[string]bool{
+		"items":            a.itemDAO.IndexReady(),
+		"orders":           a.orderDAO.IndexReady(),
+		"promotions":       a.promotionDAO.IndexReady(),
+		"order_promotions": a.orderPromotionDAO.IndexReady(),
+	}
+	ready := true
+	for _, indexReady := range indexes {
+		ready = ready && indexReady
+	}
+	return map[string]any{
+		"ready":   ready,
+		"indexes": indexes,
+	}
+}
+
+// GetIndexStats reports, per data file, how lookups found their records since the DAOs were created
+// Fallback scans and stale misses growing over time point at a stale or missing index
+func (a *App) GetIndexStats() map[string]any {
+	defer a.track("GetIndexStats", time.Now(), nil)
+	all := map[string]dao.IndexStats{
+		"items":            a.itemDAO.IndexStats(),
+		"orders":           a.orderDAO.IndexStats(),
+		"promotions":       a.promotionDAO.IndexStats(),
+		"order_promotions": a.orderPromotionDAO.IndexStats(),
+	}
+
+	result := make(map[string]any, len(all))
+	for name, stats := range all {
+		result[name] = map[string]any{
+			"indexedReads":  stats.IndexedReads,
+			"fallbackScans": stats.FallbackScans,
+			"staleMisses":   stats.StaleMisses,
+			"scanTimeMs":    float64(stats.ScanTime.Microseconds()) / 1000,
+			"maxScanTimeMs": float64(stats.MaxScanTime.Microseconds()) / 1000,
+		}
+	}
+	return result
+}
+
 // GetIndexContents returns the contents of the item B+ tree index for debugging
-func (a *App) GetIndexContents() (map[string]any, error) {
+func (a *App) GetIndexContents() (_ map[string]any, err error) {
+	defer a.track("GetIndexContents", time.Now(), &err)
 	tree := a.itemDAO.GetIndexTree()
 	return a.getIndexContentsFromTree(tree.GetAll(), "Item"), nil
 }
 
 // GetOrderIndexContents returns the contents of the order B+ tree index for debugging
-func (a *App) GetOrderIndexContents() (map[string]any, error) {
+func (a *App) GetOrderIndexContents() (_ map[string]any, err error) {
+	defer a.track("GetOrderIndexContents", time.Now(), &err)
 	tree := a.orderDAO.GetIndexTree()
 	return a.getIndexContentsFromTree(tree.GetAll(), "Order"), nil
 }
 
 // GetPromotionIndexContents returns the contents of the promotion B+ tree index for debugging
-func (a *App) GetPromotionIndexContents() (map[string]any, error) {
+func (a *App) GetPromotionIndexContents() (_ map[string]any, err error) {
+	defer a.track("GetPromotionIndexContents", time.Now(), &err)
 	tree := a.promotionDAO.GetIndexTree()
 	return a.getIndexContentsFromTree(tree.GetAll(), "Promotion"), nil
 }
 
+// GetIndexStructure returns the topology of the index of entity (items, orders, promotions or order_promotions)
+// B+ trees report their nodes, depth and fill factor; the order_promotions hash reports its buckets and depths
+func (a *App) GetIndexStructure(entity string) (_ any, err error) {
+	defer a.track("GetIndexStructure", time.Now(), &err)
+	switch entity {
+	case "items":
+		return a.itemDAO.GetIndexTree().Structure(), nil
+	case "orders":
+		return a.orderDAO.GetIndexTree().Structure(), nil
+	case "promotions":
+		return a.promotionDAO.GetIndexTree().Structure(), nil
+	case "order_promotions":
+		return a.orderPromotionDAO.GetHashIndex().Structure(), nil
+	default:
+		return nil, utils.WithCode(utils.CodeValidation, fmt.Errorf("unknown index: %s", entity), map[string]any{"entity": entity})
+	}
+}
+
+// RebuildIndexes rebuilds the index of each data file from the file, one file at a time
+// Cancelling keeps the current indexes of the files not rebuilt yet
+func (a *App) RebuildIndexes() (err error) {
+	defer a.track("RebuildIndexes", time.Now(), &err)
+	if err := a.checkWritable(); err != nil {
+		return err
+	}
+	if a.isCompacting() {
+		return errCompactionRunning
+	}
+
+	btree := func(rebuild func(context.Context, string, string) (*index.BTree, error)) func(context.Context, string, string) error {
+		return func(ctx context.Context, binPath, indexPath string) error {
+			_, err := rebuild(ctx, binPath, indexPath)
+			return err
+		}
+	}
+	rebuilds := []struct {
+		file    string
+		closer  func() error
+		rebuild func(ctx context.Context, binPath, indexPath string) error
+		reopen  func()
+	}{
+		{"items.bin", a.itemDAO.Close, btree(utils.RebuildRecordBTreeIndex), func() {
+			a.itemDAO = dao.NewItemDAO(utils.BinPath("items.bin"), itemDAOOptions(a.currentConfig())...)
+		}},
+		{"orders.bin", a.orderDAO.Close, btree(utils.RebuildCollectionBTreeIndex), func() {
+			a.orderDAO = dao.NewOrderDAO(utils.BinPath("orders.bin"))
+		}},
+		{"promotions.bin", a.promotionDAO.Close, btree(utils.RebuildCollectionBTreeIndex), func() {
+			a.promotionDAO = dao.NewPromotionDAO(utils.BinPath("promotions.bin"))
+		}},
+		{"order_promotions.bin", a.orderPromotionDAO.Close, func(ctx context.Context, binPath, indexPath string) error {
+			_, err := utils.RebuildExtensibleHashIndex(ctx, binPath, indexPath, utils.HashBucketSize)
+			return err
+		}, func() {
+			a.orderPromotionDAO = dao.NewOrderPromotionDAO(utils.BinPath("order_promotions.bin"))
+		}},
+		{"templates.bin", a.templateDAO.Close, btree(utils.RebuildCollectionBTreeIndex), func() {
+			a.templateDAO = dao.NewTemplateDAO(utils.BinPath("templates.bin"))
+		}},
+	}
+
+	p := a.startProgress("RebuildIndexes", int64(len(rebuilds)))
+	defer p.Finish()
+	for _, r := range rebuilds {
+		if err := p.Err(); err != nil {
+			return err
+		}
+		if err := r.closer(); err != nil {
+			a.logger.Warn(fmt.Sprintf("Failed to close %s: %v", r.file, err))
+		}
+
+		// A data file that was never written has no index to rebuild
+		// The rebuilt index is saved over the old one, which a cancelled rebuild leaves in place
+		binPath := utils.BinPath(r.file)
+		if _, err := os.Stat(binPath); err == nil {
+			if err := r.rebuild(p.ctx, binPath, utils.IndexPathFromBinFile(binPath)); err != nil {
+				r.reopen()
+				if cancelled := p.Err(); cancelled != nil {
+					return cancelled
+				}
+				return fmt.Errorf("failed to rebuild the index of %s: %w", r.file, err)
+			}
+		}
+		r.reopen()
+		p.Advance(1)
+	}
+
+	a.logger.Info(fmt.Sprintf("Rebuilt %d indexes", len(rebuilds)))
+	return nil
+}
+
 // populationResult tracks success/fail counts for a population operation
 type populationResult struct {
 	success int
 	fail    int
+	missing []MissingReference // records that list items that don't exist
+}
+
+// MissingReference is a seeded order or promotion that lists items that don't exist
+// Orders are written without those items, promotions keep them but leave them out of their total
+type MissingReference struct {
+	Entity  string   `json:"entity"` // "order" or "promotion"
+	Index   int      `json:"index"`  // position in the seed file, from 1
+	Name    string   `json:"name"`
+	ItemIDs []uint64 `json:"itemIds"`
+}
+
+// PopulateResult reports what PopulateInventory wrote and the item references it could not resolve
+type PopulateResult struct {
+	Items             int                `json:"items"`
+	Promotions        int                `json:"promotions"`
+	Orders            int                `json:"orders"`
+	OrderPromotions   int                `json:"orderPromotions"`
+	Failed            int                `json:"failed"`
+	MissingReferences []MissingReference `json:"missingReferences"`
+	Seed              int64              `json:"seed"` // dates the orders were drawn with, 0 when stamped with the current time
+}
+
+// missingItemIDs returns the item IDs of a seeded record that are not among the valid ones
+func missingItemIDs(itemIDs, valid []uint64) []uint64 {
+	found := make(map[uint64]bool, len(valid))
+	for _, id := range valid {
+		found[id] = true
+	}
+	var missing []uint64
+	for _, id := range itemIDs {
+		if !found[id] {
+			missing = append(missing, id)
+		}
+	}
+	return missing
 }
 
 // embeddedPromotion tracks order-promotion relationships from orders.json
@@ -299,7 +761,7 @@ type embeddedPromotion struct {
 }
 
 // populateItems reads and populates items from seed file
-func (a *App) populateItems() (*populationResult, error) {
+func (a *App) populateItems(p *operationProgress) (*populationResult, error) {
 	data, err := os.ReadFile(utils.SeedPath("items.json"))
 	if err != nil {
 		return nil, fmt.Errorf("failed to read items.json: %w", err)
@@ -312,14 +774,25 @@ func (a *App) populateItems() (*populationResult, error) {
 
 	a.logger.Info(fmt.Sprintf("Starting data population with %d items", len(items)))
 	result := &populationResult{}
+	p.AddTotal(int64(len(items)))
 
 	for i, item := range items {
-		_, err := a.itemDAO.Write(item.Name, item.PriceInCents)
+		if err := p.Err(); err != nil {
+			return nil, err
+		}
+		p.Advance(1)
+		var itemID uint64
+		ext, err := itemEntryExtensions(item)
+		if err == nil {
+			itemID, err = a.itemDAO.WriteExtended(nil, item.Name, item.PriceInCents, ext)
+		}
 		if err != nil {
 			a.logger.Error(fmt.Sprintf("Failed to add item %d (%s): %v", i+1, item.Name, err))
 			result.fail++
 			continue
 		}
+		a.recordOp(oplog.Operation{Type: oplog.OpAddItem, ID: itemID, Name: item.Name, Price: item.PriceInCents, Extensions: ext})
+		a.recordAudit(dao.AuditCreate, "item", itemID, "", itemSummary(item.Name, item.PriceInCents))
 		result.success++
 		a.logger.Info(fmt.Sprintf("Added item %d/%d: %s ($%.2f)", i+1, len(items), item.Name, float64(item.PriceInCents)/100))
 	}
@@ -329,7 +802,7 @@ func (a *App) populateItems() (*populationResult, error) {
 }
 
 // populatePromotions reads and populates promotions from seed file
-func (a *App) populatePromotions() *populationResult {
+func (a *App) populatePromotions(p *operationProgress) *populationResult {
 	result := &populationResult{}
 
 	data, err := os.ReadFile(utils.SeedPath("promotions.json"))
@@ -345,20 +818,37 @@ func (a *App) populatePromotions() *populationResult {
 	}
 
 	a.logger.Info(fmt.Sprintf("Starting promotion population with %d promotions", len(promotions)))
+	p.AddTotal(int64(len(promotions)))
 
 	for i, promo := range promotions {
+		if p.Err() != nil {
+			break
+		}
+		p.Advance(1)
 		priceResult, err := a.calculateTotalPrice(promo.ItemIDs, false, fmt.Sprintf("promotion '%s'", promo.Name))
 		totalPrice := uint64(0)
 		if err == nil && priceResult != nil {
 			totalPrice = priceResult.TotalPrice
+			if missing := missingItemIDs(promo.ItemIDs, priceResult.ValidItems); len(missing) > 0 {
+				result.missing = append(result.missing, MissingReference{Entity: "promotion", Index: i + 1, Name: promo.Name, ItemIDs: missing})
+			}
+		}
+
+		ext, err := discountExtensions(promo.DiscountType, promo.DiscountValue)
+		if err != nil {
+			a.logger.Error(fmt.Sprintf("Failed to add promotion %d (%s): %v", i+1, promo.Name, err))
+			result.fail++
+			continue
 		}
 
-		_, err = a.promotionDAO.Write(promo.Name, totalPrice, promo.ItemIDs)
+		promoID, err := a.promotionDAO.WriteExtended(nil, promo.Name, totalPrice, promo.ItemIDs, ext)
 		if err != nil {
 			a.logger.Error(fmt.Sprintf("Failed to add promotion %d (%s): %v", i+1, promo.Name, err))
 			result.fail++
 			continue
 		}
+		a.recordOp(oplog.Operation{Type: oplog.OpCreatePromotion, ID: promoID, Name: promo.Name, Price: totalPrice, ItemIDs: promo.ItemIDs, Extensions: ext})
+		a.recordAudit(dao.AuditCreate, "promotion", promoID, "", collectionSummary(totalPrice, promo.ItemIDs))
 		result.success++
 		a.logger.Info(fmt.Sprintf("Added promotion %d/%d: %s with %d items ($%.2f)",
 			i+1, len(promotions), promo.Name, len(promo.ItemIDs), float64(totalPrice)/100))
@@ -369,7 +859,8 @@ func (a *App) populatePromotions() *populationResult {
 }
 
 // populateOrders reads and populates orders from seed file, returns embedded promotions
-func (a *App) populateOrders() (*populationResult, []embeddedPromotion, error) {
+// Each order is stamped with the time returned by createdAt
+func (a *App) populateOrders(p *operationProgress, createdAt func() time.Time) (*populationResult, []embeddedPromotion, error) {
 	data, err := os.ReadFile(utils.SeedPath("orders.json"))
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to read orders.json: %w", err)
@@ -383,21 +874,39 @@ func (a *App) populateOrders() (*populationResult, []embeddedPromotion, error) {
 	a.logger.Info(fmt.Sprintf("Starting orders population with %d orders", len(orders)))
 	result := &populationResult{}
 	var embedded []embeddedPromotion
+	p.AddTotal(int64(len(orders)))
 
 	for i, order := range orders {
+		if err := p.Err(); err != nil {
+			return nil, nil, err
+		}
+		p.Advance(1)
 		priceResult, err := a.calculateTotalPrice(order.ItemIDs, false, fmt.Sprintf("order '%s'", order.Owner))
+		if err == nil && priceResult != nil {
+			if missing := missingItemIDs(order.ItemIDs, priceResult.ValidItems); len(missing) > 0 {
+				result.missing = append(result.missing, MissingReference{Entity: "order", Index: i + 1, Name: order.Owner, ItemIDs: missing})
+			}
+		}
 		if err != nil || priceResult == nil || len(priceResult.ValidItems) == 0 {
 			a.logger.Warn(fmt.Sprintf("Order %d (%s) has no valid items, skipping", i+1, order.Owner))
 			result.fail++
 			continue
 		}
 
-		orderID, err := a.orderDAO.Write(order.Owner, priceResult.TotalPrice, priceResult.ValidItems)
+		if err := a.reserveStock(priceResult.ValidItems); err != nil {
+			a.logger.Warn(fmt.Sprintf("Order %d (%s): %v, stock not decremented", i+1, order.Owner, err))
+		}
+
+		ext := newOrderExtensions(createdAt())
+		orderID, err := a.orderDAO.WriteExtended(nil, order.Owner, priceResult.TotalPrice, priceResult.ValidItems, ext)
 		if err != nil {
+			a.restock(priceResult.ValidItems)
 			a.logger.Error(fmt.Sprintf("Failed to add order %d (%s): %v", i+1, order.Owner, err))
 			result.fail++
 			continue
 		}
+		a.recordOp(oplog.Operation{Type: oplog.OpCreateOrder, ID: orderID, Name: order.Owner, Price: priceResult.TotalPrice, ItemIDs: priceResult.ValidItems, Extensions: ext})
+		a.recordAudit(dao.AuditCreate, "order", orderID, "", collectionSummary(priceResult.TotalPrice, priceResult.ValidItems))
 
 		if len(order.PromotionIDs) > 0 {
 			embedded = append(embedded, embeddedPromotion{orderID: orderID, promotionIDs: order.PromotionIDs})
@@ -413,7 +922,8 @@ func (a *App) populateOrders() (*populationResult, []embeddedPromotion, error) {
 }
 
 // populateOrderPromotions reads and applies order-promotion relationships from seed file
-func (a *App) populateOrderPromotions() *populationResult {
+// Each relationship is stamped with appliedAt
+func (a *App) populateOrderPromotions(p *operationProgress, appliedAt time.Time) *populationResult {
 	result := &populationResult{}
 
 	data, err := os.ReadFile(utils.SeedPath("order_promotions.json"))
@@ -429,68 +939,187 @@ func (a *App) populateOrderPromotions() *populationResult {
 	}
 
 	a.logger.Info(fmt.Sprintf("Starting order-promotion relationships with %d entries", len(orderPromotions)))
+	p.AddTotal(int64(len(orderPromotions)))
+
+	// Promotions are applied in one batch per order, orders in the order they first appear
+	var orderIDs []uint64
+	byOrder := make(map[uint64][]uint64)
+	for _, op := range orderPromotions {
+		if _, seen := byOrder[op.OrderID]; !seen {
+			orderIDs = append(orderIDs, op.OrderID)
+		}
+		byOrder[op.OrderID] = append(byOrder[op.OrderID], op.PromotionID)
+	}
 
-	for i, op := range orderPromotions {
-		if err := a.ApplyPromotionToOrder(op.OrderID, op.PromotionID); err != nil {
-			a.logger.Error(fmt.Sprintf("Failed to apply promotion %d to order %d: %v", op.PromotionID, op.OrderID, err))
-			result.fail++
-			continue
+	for _, orderID := range orderIDs {
+		if p.Err() != nil {
+			break
 		}
-		result.success++
-		a.logger.Info(fmt.Sprintf("Applied promotion #%d to order #%d (%d/%d)",
-			op.PromotionID, op.OrderID, i+1, len(orderPromotions)))
+		p.Advance(int64(len(byOrder[orderID])))
+		a.applySeedPromotions(orderID, byOrder[orderID], appliedAt, result)
 	}
 
 	a.logger.Info(fmt.Sprintf("Order-promotion relationships complete: %d succeeded, %d failed", result.success, result.fail))
 	return result
 }
 
+// applySeedPromotions applies the seeded promotions of one order in a single batch
+// When the batch fails, e.g. on a missing promotion, they are applied one at a time so the valid ones still are
+func (a *App) applySeedPromotions(orderID uint64, promotionIDs []uint64, appliedAt time.Time, result *populationResult) {
+	if _, err := a.applyPromotionsToOrder(orderID, promotionIDs, appliedAt); err == nil {
+		result.success += len(promotionIDs)
+		return
+	}
+
+	for _, promotionID := range promotionIDs {
+		if err := a.applyPromotionToOrder(orderID, promotionID, appliedAt); err != nil {
+			a.logger.Error(fmt.Sprintf("Failed to apply promotion %d to order %d: %v", promotionID, orderID, err))
+			result.fail++
+			continue
+		}
+		result.success++
+	}
+}
+
 // applyEmbeddedPromotions applies promotions embedded in orders.json
-func (a *App) applyEmbeddedPromotions(embedded []embeddedPromotion) *populationResult {
+func (a *App) applyEmbeddedPromotions(p *operationProgress, embedded []embeddedPromotion, appliedAt time.Time) *populationResult {
 	result := &populationResult{}
 	if len(embedded) == 0 {
 		return result
 	}
 
 	a.logger.Info(fmt.Sprintf("Applying %d embedded order-promotion relationships", len(embedded)))
+	p.AddTotal(int64(len(embedded)))
 
 	for _, ep := range embedded {
-		for _, promoID := range ep.promotionIDs {
-			if err := a.ApplyPromotionToOrder(ep.orderID, promoID); err != nil {
-				a.logger.Error(fmt.Sprintf("Failed to apply embedded promotion %d to order %d: %v", promoID, ep.orderID, err))
-				result.fail++
-				continue
-			}
-			result.success++
-			a.logger.Info(fmt.Sprintf("Applied embedded promotion #%d to order #%d", promoID, ep.orderID))
+		if p.Err() != nil {
+			break
 		}
+		p.Advance(1)
+		a.applySeedPromotions(ep.orderID, ep.promotionIDs, appliedAt, result)
 	}
 
 	a.logger.Info(fmt.Sprintf("Embedded order-promotion relationships complete: %d succeeded, %d failed", result.success, result.fail))
 	return result
 }
 
+// checkSeedReferences lists the records of promotions.json and orders.json that reference items that don't exist
+func (a *App) checkSeedReferences() ([]MissingReference, error) {
+	var promotions []PromotionEntry
+	if data, err := os.ReadFile(utils.SeedPath("promotions.json")); err == nil {
+		if err := json.Unmarshal(data, &promotions); err != nil {
+			return nil, fmt.Errorf("failed to parse promotions.json: %w", err)
+		}
+	}
+	data, err := os.ReadFile(utils.SeedPath("orders.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read orders.json: %w", err)
+	}
+	var orders []OrderEntry
+	if err := json.Unmarshal(data, &orders); err != nil {
+		return nil, fmt.Errorf("failed to parse orders.json: %w", err)
+	}
+
+	var referenced []uint64
+	for _, promo := range promotions {
+		referenced = append(referenced, promo.ItemIDs...)
+	}
+	for _, order := range orders {
+		referenced = append(referenced, order.ItemIDs...)
+	}
+	items, err := a.itemDAO.ReadMany(referenced)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read items: %w", err)
+	}
+	missing := func(itemIDs []uint64) []uint64 {
+		var ids []uint64
+		for _, id := range itemIDs {
+			if _, ok := items[id]; !ok {
+				ids = append(ids, id)
+			}
+		}
+		return ids
+	}
+
+	var refs []MissingReference
+	for i, promo := range promotions {
+		if ids := missing(promo.ItemIDs); len(ids) > 0 {
+			refs = append(refs, MissingReference{Entity: "promotion", Index: i + 1, Name: promo.Name, ItemIDs: ids})
+		}
+	}
+	for i, order := range orders {
+		if ids := missing(order.ItemIDs); len(ids) > 0 {
+			refs = append(refs, MissingReference{Entity: "order", Index: i + 1, Name: order.Owner, ItemIDs: ids})
+		}
+	}
+	return refs, nil
+}
+
 // PopulateInventory reads items and promotions from JSON files and adds them to the database
-func (a *App) PopulateInventory() error {
-	itemResult, err := a.populateItems()
+// Orders and applied promotions are stamped with the current time, or with dates drawn from a non-zero seed
+// so repeated runs write the same files
+// Orders and promotions listing items that don't exist are written without them and reported in MissingReferences;
+// in strict mode the seed files are checked once the items are written, and any such reference aborts
+// the run with a Validation error before a promotion or order is written
+func (a *App) PopulateInventory(seed int64, strict bool) (_ *PopulateResult, err error) {
+	defer a.track("PopulateInventory", time.Now(), &err)
+	if err := a.checkWritable(); err != nil {
+		return nil, err
+	}
+
+	a.currencyRates = loadCurrencyRates(a.logger)
+
+	// The total grows as each seed file is read
+	p := a.startProgress("PopulateInventory", 0)
+	defer p.Finish()
+
+	itemResult, err := a.populateItems(p)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	a.toast.Success(fmt.Sprintf("Created items.bin (%d items)", itemResult.success))
 
-	promoResult := a.populatePromotions()
+	if strict {
+		refs, err := a.checkSeedReferences()
+		if err != nil {
+			return nil, err
+		}
+		if len(refs) > 0 {
+			for _, ref := range refs {
+				a.logger.Error(fmt.Sprintf("%s %d (%s) references missing items %v", ref.Entity, ref.Index, ref.Name, ref.ItemIDs))
+			}
+			return &PopulateResult{Items: itemResult.success, Failed: itemResult.fail, MissingReferences: refs, Seed: seed},
+				utils.WithCode(utils.CodeValidation, fmt.Errorf("%d seed record(s) reference items that don't exist", len(refs)), map[string]any{"missingReferences": refs})
+		}
+	}
+
+	promoResult := a.populatePromotions(p)
+	if err := p.Err(); err != nil {
+		return nil, err
+	}
 	if promoResult.success > 0 {
 		a.toast.Success(fmt.Sprintf("Created promotions.bin (%d promotions)", promoResult.success))
 	}
 
-	orderResult, embedded, err := a.populateOrders()
+	createdAt := func() time.Time { return time.Now().UTC() }
+	appliedAt := time.Now().UTC()
+	if seed != 0 {
+		g, _ := newSeedGenerator(seed)
+		createdAt = g.createdAt
+		appliedAt = seedReferenceTime
+		a.logger.Info(fmt.Sprintf("Dating seed orders with seed %d", seed))
+	}
+	orderResult, embedded, err := a.populateOrders(p, createdAt)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	a.toast.Success(fmt.Sprintf("Created orders.bin (%d orders)", orderResult.success))
 
-	opResult := a.populateOrderPromotions()
-	embeddedResult := a.applyEmbeddedPromotions(embedded)
+	opResult := a.populateOrderPromotions(p, appliedAt)
+	embeddedResult := a.applyEmbeddedPromotions(p, embedded, appliedAt)
+	if err := p.Err(); err != nil {
+		return nil, err
+	}
 	totalOP := opResult.success + embeddedResult.success
 	if totalOP > 0 {
 		a.toast.Success(fmt.Sprintf("Created order_promotions.bin (%d relationships)", totalOP))
@@ -503,28 +1132,59 @@ func (a *App) PopulateInventory() error {
 	a.logger.Info(fmt.Sprintf("Total population complete: %d items + %d promotions + %d orders = %d total (%d failed)",
 		itemResult.success, promoResult.success, orderResult.success, totalSuccess, totalFail))
 
+	result := &PopulateResult{
+		Items:             itemResult.success,
+		Promotions:        promoResult.success,
+		Orders:            orderResult.success,
+		OrderPromotions:   totalOP,
+		Failed:            totalFail,
+		MissingReferences: append(promoResult.missing, orderResult.missing...),
+		Seed:              seed,
+	}
+	if n := len(result.MissingReferences); n > 0 {
+		a.logger.Warn(fmt.Sprintf("%d seeded order(s) and promotion(s) reference items that don't exist", n))
+		a.toast.Warning(fmt.Sprintf("%d seed record(s) reference missing items", n))
+	}
+
 	if totalFail > 0 {
-		return fmt.Errorf("some entries failed to add: %d succeeded, %d failed", totalSuccess, totalFail)
+		return result, fmt.Errorf("some entries failed to add: %d succeeded, %d failed", totalSuccess, totalFail)
 	}
 
-	return nil
+	return result, nil
 }
 
-// GetAllItems retrieves all items from the database, including deleted ones
-func (a *App) GetAllItems() ([]map[string]any, error) {
-	items, err := a.itemDAO.GetAll()
+// GetAllItems retrieves the items from the database, including deleted ones unless opts.ActiveOnly is set,
+// in file order or sorted as opts asks
+func (a *App) GetAllItems(opts ListOptions) (_ []map[string]any, err error) {
+	defer a.track("GetAllItems", time.Now(), &err)
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	var items []dao.Item
+	switch {
+	case opts.byIndex():
+		items, err = a.itemDAO.GetAllActiveByID(context.Background())
+	case opts.ActiveOnly:
+		items, err = a.itemDAO.GetAllActive(context.Background())
+	default:
+		items, err = a.itemDAO.GetAll()
+	}
 	if err != nil {
 		return nil, err
 	}
+	sortRecords(items, opts,
+		func(item dao.Item) uint64 { return item.ID },
+		func(item dao.Item) string { return item.Name },
+		func(item dao.Item) uint64 { return item.PriceInCents })
 
 	result := make([]map[string]any, len(items))
 	for i, item := range items {
-		result[i] = map[string]any{
+		result[i] = addExternalIDField(a.addCurrencyFields(addStockFields(map[string]any{
 			"id":           item.ID,
 			"name":         item.Name,
 			"priceInCents": item.PriceInCents,
 			"isDeleted":    item.IsDeleted,
-		}
+		}, &item), &item), &item)
 	}
 
 	a.logger.Info(fmt.Sprintf("Retrieved %d items", len(items)))
@@ -533,7 +1193,8 @@ func (a *App) GetAllItems() ([]map[string]any, error) {
 
 // SearchItems searches for items by name using pattern matching algorithm
 // algorithm: "kmp" for Knuth-Morris-Pratt, "bm" for Boyer-Moore
-func (a *App) SearchItems(pattern string, algorithm string) ([]map[string]any, error) {
+func (a *App) SearchItems(pattern string, algorithm string) (_ []map[string]any, err error) {
+	defer a.track("SearchItems", time.Now(), &err)
 	// First check if there are any items at all
 	allItems, err := a.itemDAO.GetAll()
 	if err != nil {
@@ -561,11 +1222,11 @@ func (a *App) SearchItems(pattern string, algorithm string) ([]map[string]any, e
 
 	result := make([]map[string]any, len(items))
 	for i, item := range items {
-		result[i] = map[string]any{
+		result[i] = addStockFields(map[string]any{
 			"id":           item.ID,
 			"name":         item.Name,
 			"priceInCents": item.PriceInCents,
-		}
+		}, &item)
 	}
 
 	algoName := "KMP"
@@ -576,66 +1237,121 @@ func (a *App) SearchItems(pattern string, algorithm string) ([]map[string]any, e
 	return result, nil
 }
 
-// GetAllOrders retrieves all orders, including deleted ones
-func (a *App) GetAllOrders() ([]map[string]any, error) {
-	orders, err := a.orderDAO.GetAll()
+// GetAllOrders retrieves the orders, including deleted ones unless opts.ActiveOnly is set, in file order or
+// sorted as opts asks; the name of an order is its customer and its price its total
+func (a *App) GetAllOrders(status string, opts ListOptions) (_ []map[string]any, err error) {
+	defer a.track("GetAllOrders", time.Now(), &err)
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	filter := -1
+	if status != "" {
+		s, err := utils.ParseOrderStatus(status)
+		if err != nil {
+			return nil, err
+		}
+		filter = int(s)
+	}
+
+	orders, err := listCollections(a.orderDAO.CollectionDAO, opts)
 	if err != nil {
 		return nil, err
 	}
 
-	result := make([]map[string]any, len(orders))
-	for i, order := range orders {
-		result[i] = map[string]any{
-			"id":         order.ID,
-			"customer":   order.OwnerOrName,
-			"totalPrice": order.TotalPrice,
-			"itemCount":  order.ItemCount,
-			"itemIDs":    order.ItemIDs,
-			"isDeleted":  order.IsDeleted,
+	result := make([]map[string]any, 0, len(orders))
+	for _, order := range orders {
+		orderState := orderStatus(order)
+		if filter >= 0 && int(orderState) != filter {
+			continue
 		}
+		result = append(result, addOrderFields(map[string]any{
+			"id":             order.ID,
+			"customer":       order.OwnerOrName,
+			"totalPrice":     order.TotalPrice,
+			"formattedTotal": a.formatPrice(order.TotalPrice),
+			"itemCount":      order.ItemCount,
+			"itemIDs":        order.ItemIDs,
+			"isDeleted":      order.IsDeleted,
+		}, order))
+	}
+
+	a.logger.Info(fmt.Sprintf("Retrieved %d orders", len(result)))
+	return result, nil
+}
+
+// GetOrderSummaries retrieves the ID, total and item count of all orders, including deleted ones
+// Customer names are not decrypted, so list views that don't show them load much faster than with GetAllOrders
+func (a *App) GetOrderSummaries() (_ []map[string]any, err error) {
+	defer a.track("GetOrderSummaries", time.Now(), &err)
+	orders, err := a.orderDAO.GetAllMeta()
+	if err != nil {
+		return nil, err
 	}
 
-	a.logger.Info(fmt.Sprintf("Retrieved %d orders", len(orders)))
+	result := make([]map[string]any, 0, len(orders))
+	for _, order := range orders {
+		result = append(result, map[string]any{
+			"id":             order.ID,
+			"totalPrice":     order.TotalPrice,
+			"formattedTotal": a.formatPrice(order.TotalPrice),
+			"itemCount":      order.ItemCount,
+			"isDeleted":      order.IsDeleted,
+		})
+	}
+
+	a.logger.Info(fmt.Sprintf("Retrieved %d order summaries", len(result)))
 	return result, nil
 }
 
-// GetAllPromotions retrieves all promotions, including deleted ones
-func (a *App) GetAllPromotions() ([]map[string]any, error) {
-	promotions, err := a.promotionDAO.GetAll()
+// GetAllPromotions retrieves the promotions, including deleted ones unless opts.ActiveOnly is set,
+// in file order or sorted as opts asks
+func (a *App) GetAllPromotions(opts ListOptions) (_ []map[string]any, err error) {
+	defer a.track("GetAllPromotions", time.Now(), &err)
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	promotions, err := listCollections(a.promotionDAO.CollectionDAO, opts)
 	if err != nil {
 		return nil, err
 	}
 
 	result := make([]map[string]any, len(promotions))
 	for i, promotion := range promotions {
-		result[i] = map[string]any{
+		result[i] = addDiscountFields(map[string]any{
 			"id":         promotion.ID,
 			"name":       promotion.OwnerOrName,
 			"totalPrice": promotion.TotalPrice,
 			"itemCount":  promotion.ItemCount,
 			"itemIDs":    promotion.ItemIDs,
 			"isDeleted":  promotion.IsDeleted,
-		}
+		}, promotion)
 	}
 
 	a.logger.Info(fmt.Sprintf("Retrieved %d promotions", len(promotions)))
 	return result, nil
 }
 
-// validateCollectionInput validates name and itemIDs for order/promotion creation
-func (a *App) validateCollectionInput(name string, itemIDs []uint64, entityType string) error {
-	if err := utils.ValidateName(name); err != nil {
-		return fmt.Errorf("%s name: %w", entityType, err)
+// validateCollectionInput validates name and itemIDs for order, promotion or template creation,
+// with the rules declared for the entity
+func (a *App) validateCollectionInput(name string, itemIDs []uint64, entity string) error {
+	if err := utils.ValidateEntityName(entity, name); err != nil {
+		return fmt.Errorf("%s name: %w", entity, err)
 	}
-	if err := utils.ValidateItemIDs(itemIDs); err != nil {
-		return fmt.Errorf("%s: %w", entityType, err)
+	if err := utils.ValidateEntityItemIDs(entity, itemIDs); err != nil {
+		return fmt.Errorf("%s: %w", entity, err)
 	}
 	return nil
 }
 
 // CreateOrder creates a new order with the given customer name and item IDs
-func (a *App) CreateOrder(customerName string, itemIDs []uint64) (uint64, error) {
-	if err := a.validateCollectionInput(customerName, itemIDs, "customer"); err != nil {
+func (a *App) CreateOrder(customerName string, itemIDs []uint64) (_ uint64, err error) {
+	start := time.Now()
+	defer a.track("CreateOrder", start, &err)
+	if err := a.checkWritable(); err != nil {
+		return 0, err
+	}
+
+	if err := a.validateCollectionInput(customerName, itemIDs, "order"); err != nil {
 		return 0, err
 	}
 
@@ -644,48 +1360,265 @@ func (a *App) CreateOrder(customerName string, itemIDs []uint64) (uint64, error)
 		return 0, err
 	}
 
-	assignedID, err := a.orderDAO.Write(customerName, priceResult.TotalPrice, itemIDs)
+	if err := a.reserveStock(itemIDs); err != nil {
+		return 0, err
+	}
+
+	ext := newOrderExtensions(time.Now().UTC())
+	assignedID, err := a.orderDAO.WriteExtended(nil, customerName, priceResult.TotalPrice, itemIDs, ext)
 	if err != nil {
+		a.restock(itemIDs)
 		return 0, fmt.Errorf("failed to create order: %w", err)
 	}
+	a.recordOp(oplog.Operation{Type: oplog.OpCreateOrder, ID: assignedID, Name: customerName, Price: priceResult.TotalPrice, ItemIDs: itemIDs, Extensions: ext})
+	a.recordAudit(dao.AuditCreate, "order", assignedID, "", collectionSummary(priceResult.TotalPrice, itemIDs))
 
-	a.logger.Info(fmt.Sprintf("Created order #%d for %s with %d items (total: $%.2f)",
-		assignedID, customerName, len(itemIDs), float64(priceResult.TotalPrice)/100))
+	a.logger.InfoWith(fmt.Sprintf("Created order #%d for %s with %d items (total: $%.2f)",
+		assignedID, customerName, len(itemIDs), float64(priceResult.TotalPrice)/100), entityLog("order", assignedID, dao.AuditCreate, start)...)
 
 	return assignedID, nil
 }
 
-// GetOrder retrieves an order by ID
-func (a *App) GetOrder(id uint64) (map[string]any, error) {
-	order, err := a.orderDAO.Read(id)
+// remainingItemIDs returns the item IDs of an order or template whose items still exist, to place a new order with
+// Deleted items are left out with a warning; it fails when none of them remain
+func (a *App) remainingItemIDs(itemIDs []uint64, entityType string, id uint64) ([]uint64, error) {
+	items, err := a.itemDAO.ReadMany(itemIDs)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("failed to read items of %s #%d: %w", entityType, id, err)
 	}
+	remaining := make([]uint64, 0, len(itemIDs))
+	for _, itemID := range itemIDs {
+		if _, ok := items[itemID]; !ok {
+			a.logger.Warn(fmt.Sprintf("Item ID %d of %s #%d no longer exists, leaving it out of the new order", itemID, entityType, id))
+			continue
+		}
+		remaining = append(remaining, itemID)
+	}
+	if len(remaining) == 0 {
+		return nil, utils.WithCode(utils.CodeValidation, fmt.Errorf("none of the items of %s %d exist anymore", entityType, id), utils.RecordDetails(entityType, id))
+	}
+	return remaining, nil
+}
+
+// DuplicateOrder places a new order for the customer and items of an existing one, priced at current prices
+// Items that have been deleted since are left out; it fails when none of them remain
+// Returns the ID of the new order
+func (a *App) DuplicateOrder(orderID uint64) (_ uint64, err error) {
+	defer a.track("DuplicateOrder", time.Now(), &err)
+	if err := a.checkWritable(); err != nil {
+		return 0, err
+	}
+
+	order, err := a.orderDAO.Read(orderID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read order: %w", err)
+	}
+
+	itemIDs, err := a.remainingItemIDs(order.ItemIDs, "order", orderID)
+	if err != nil {
+		return 0, err
+	}
+
+	newID, err := a.CreateOrder(order.OwnerOrName, itemIDs)
+	if err != nil {
+		return 0, err
+	}
+	a.logger.Info(fmt.Sprintf("Duplicated order #%d as #%d", orderID, newID))
+	return newID, nil
+}
+
+// GetOrder retrieves an order by ID
+func (a *App) GetOrder(id uint64) (_ map[string]any, err error) {
+	start := time.Now()
+	defer a.track("GetOrder", start, &err)
+	order, err := a.orderDAO.Read(id)
+	if err != nil {
+		return nil, err
+	}
+
+	a.logger.InfoWith(fmt.Sprintf("Retrieved order #%d for %s", id, order.OwnerOrName), entityLog("order", id, "read", start)...)
+
+	return addOrderFields(map[string]any{
+		"id":             order.ID,
+		"customerName":   order.OwnerOrName,
+		"totalPrice":     order.TotalPrice,
+		"formattedTotal": a.formatPrice(order.TotalPrice),
+		"itemCount":      order.ItemCount,
+		"itemIDs":        order.ItemIDs,
+	}, order), nil
+}
+
+// GetOrdersByCustomerName retrieves the active orders placed by a customer, matching the normalized name exactly
+// Encrypted names are found by their stored hash, so only the matching orders are decrypted
+func (a *App) GetOrdersByCustomerName(name string) (_ []map[string]any, err error) {
+	defer a.track("GetOrdersByCustomerName", time.Now(), &err)
+	if err := utils.ValidateEntityName("order", name); err != nil {
+		return nil, err
+	}
+
+	ids, err := a.orderDAO.FindByName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]map[string]any, 0, len(ids))
+	for _, id := range ids {
+		order, err := a.orderDAO.Read(id)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, addOrderFields(map[string]any{
+			"id":             order.ID,
+			"customer":       order.OwnerOrName,
+			"totalPrice":     order.TotalPrice,
+			"formattedTotal": a.formatPrice(order.TotalPrice),
+			"itemCount":      order.ItemCount,
+			"itemIDs":        order.ItemIDs,
+			"isDeleted":      order.IsDeleted,
+		}, order))
+	}
+
+	a.logger.Info(fmt.Sprintf("Found %d orders for %s", len(result), name))
+	return result, nil
+}
 
-	a.logger.Info(fmt.Sprintf("Retrieved order #%d for %s", id, order.OwnerOrName))
-
-	return map[string]any{
-		"id":           order.ID,
-		"customerName": order.OwnerOrName,
-		"totalPrice":   order.TotalPrice,
-		"itemCount":    order.ItemCount,
-		"itemIDs":      order.ItemIDs,
-	}, nil
-}
-
 // DeleteOrder marks an order as deleted
-func (a *App) DeleteOrder(id uint64) error {
-	err := a.orderDAO.Delete(id)
+func (a *App) DeleteOrder(id uint64) (err error) {
+	start := time.Now()
+	defer a.track("DeleteOrder", start, &err)
+	if err := a.checkWritable(); err != nil {
+		return err
+	}
+
+	order, err := a.orderDAO.Read(id)
+	if err != nil {
+		return err
+	}
+
+	err = a.orderDAO.Delete(id)
+	if err != nil {
+		return err
+	}
+	a.recordOp(oplog.Operation{Type: oplog.OpDeleteOrder, ID: id, Name: order.OwnerOrName, Price: order.TotalPrice, ItemIDs: order.ItemIDs, Extensions: order.Extensions})
+	a.recordAudit(dao.AuditDelete, "order", id, collectionSummary(order.TotalPrice, order.ItemIDs), "")
+
+	// Return the order's items to stock (cancelled orders were already restocked)
+	if orderStatus(order) != utils.OrderCancelled {
+		a.restock(order.ItemIDs)
+	}
+
+	a.logger.InfoWith(fmt.Sprintf("Deleted order #%d", id), entityLog("order", id, dao.AuditDelete, start)...)
+	a.checkCompactionPolicy()
+	return nil
+}
+
+// AddItemToOrder appends an item to an existing order and recalculates its total
+func (a *App) AddItemToOrder(orderID, itemID uint64) (_ map[string]any, err error) {
+	start := time.Now()
+	defer a.track("AddItemToOrder", start, &err)
+	if err := a.checkWritable(); err != nil {
+		return nil, err
+	}
+
+	order, err := a.orderDAO.Read(orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read order: %w", err)
+	}
+	if err := requirePendingOrder(order); err != nil {
+		return nil, err
+	}
+
+	if _, _, _, err := a.itemDAO.Read(itemID); err != nil {
+		return nil, fmt.Errorf("item %d not found: %w", itemID, err)
+	}
+
+	if err := a.reserveStock([]uint64{itemID}); err != nil {
+		return nil, err
+	}
+
+	itemIDs := append(append([]uint64{}, order.ItemIDs...), itemID)
+	if err := a.updateOrderItems(order, itemIDs); err != nil {
+		a.restock([]uint64{itemID})
+		return nil, err
+	}
+
+	a.logger.InfoWith(fmt.Sprintf("Added item #%d to order #%d", itemID, orderID), entityLog("order", orderID, "add_item", start)...)
+	return a.GetOrder(orderID)
+}
+
+// RemoveItemFromOrder removes one occurrence of an item from an existing order and recalculates its total
+func (a *App) RemoveItemFromOrder(orderID, itemID uint64) (_ map[string]any, err error) {
+	start := time.Now()
+	defer a.track("RemoveItemFromOrder", start, &err)
+	if err := a.checkWritable(); err != nil {
+		return nil, err
+	}
+
+	order, err := a.orderDAO.Read(orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read order: %w", err)
+	}
+	if err := requirePendingOrder(order); err != nil {
+		return nil, err
+	}
+
+	pos := -1
+	for i, id := range order.ItemIDs {
+		if id == itemID {
+			pos = i
+			break
+		}
+	}
+	if pos < 0 {
+		return nil, fmt.Errorf("item %d is not in order %d", itemID, orderID)
+	}
+	if len(order.ItemIDs) == 1 {
+		return nil, fmt.Errorf("cannot remove the last item from order %d, delete the order instead", orderID)
+	}
+
+	itemIDs := append(append([]uint64{}, order.ItemIDs[:pos]...), order.ItemIDs[pos+1:]...)
+	if err := a.updateOrderItems(order, itemIDs); err != nil {
+		return nil, err
+	}
+	a.restock([]uint64{itemID})
+
+	a.logger.InfoWith(fmt.Sprintf("Removed item #%d from order #%d", itemID, orderID), entityLog("order", orderID, "remove_item", start)...)
+	return a.GetOrder(orderID)
+}
+
+// updateOrderItems recalculates the total for a new item list and rewrites the order record
+// Items that have since been deleted stay in the list but no longer count towards the total
+func (a *App) updateOrderItems(order *dao.Collection, itemIDs []uint64) error {
+	if err := utils.ValidateEntityItemIDs("order", itemIDs); err != nil {
+		return fmt.Errorf("order: %w", err)
+	}
+
+	priceResult, err := a.calculateTotalPrice(itemIDs, false, fmt.Sprintf("order #%d", order.ID))
 	if err != nil {
 		return err
 	}
 
-	a.logger.Info(fmt.Sprintf("Deleted order #%d", id))
+	if err := a.orderDAO.Update(order.ID, order.OwnerOrName, priceResult.TotalPrice, itemIDs); err != nil {
+		return fmt.Errorf("failed to update order: %w", err)
+	}
+	a.recordOp(oplog.Operation{Type: oplog.OpUpdateOrder, ID: order.ID, Name: order.OwnerOrName, Price: priceResult.TotalPrice, ItemIDs: itemIDs, Extensions: order.Extensions})
+	a.recordAudit(dao.AuditUpdate, "order", order.ID,
+		collectionSummary(order.TotalPrice, order.ItemIDs),
+		collectionSummary(priceResult.TotalPrice, itemIDs))
+
+	a.logger.Info(fmt.Sprintf("Order #%d total recalculated: $%.2f -> $%.2f",
+		order.ID, float64(order.TotalPrice)/100, float64(priceResult.TotalPrice)/100))
 	return nil
 }
 
 // CreatePromotion creates a new promotion with the given name and item IDs
-func (a *App) CreatePromotion(promotionName string, itemIDs []uint64) (uint64, error) {
+func (a *App) CreatePromotion(promotionName string, itemIDs []uint64) (_ uint64, err error) {
+	start := time.Now()
+	defer a.track("CreatePromotion", start, &err)
+	if err := a.checkWritable(); err != nil {
+		return 0, err
+	}
+
 	if err := a.validateCollectionInput(promotionName, itemIDs, "promotion"); err != nil {
 		return 0, err
 	}
@@ -699,69 +1632,167 @@ func (a *App) CreatePromotion(promotionName string, itemIDs []uint64) (uint64, e
 	if err != nil {
 		return 0, fmt.Errorf("failed to create promotion: %w", err)
 	}
+	a.recordOp(oplog.Operation{Type: oplog.OpCreatePromotion, ID: assignedID, Name: promotionName, Price: priceResult.TotalPrice, ItemIDs: itemIDs})
+	a.recordAudit(dao.AuditCreate, "promotion", assignedID, "", collectionSummary(priceResult.TotalPrice, itemIDs))
 
-	a.logger.Info(fmt.Sprintf("Created promotion #%d: %s with %d items (total: $%.2f)",
-		assignedID, promotionName, len(itemIDs), float64(priceResult.TotalPrice)/100))
+	a.logger.InfoWith(fmt.Sprintf("Created promotion #%d: %s with %d items (total: $%.2f)",
+		assignedID, promotionName, len(itemIDs), float64(priceResult.TotalPrice)/100), entityLog("promotion", assignedID, dao.AuditCreate, start)...)
 
 	return assignedID, nil
 }
 
 // GetPromotion retrieves a promotion by ID
-func (a *App) GetPromotion(id uint64) (map[string]any, error) {
+func (a *App) GetPromotion(id uint64) (_ map[string]any, err error) {
+	start := time.Now()
+	defer a.track("GetPromotion", start, &err)
 	promotion, err := a.promotionDAO.Read(id)
 	if err != nil {
 		return nil, err
 	}
 
-	a.logger.Info(fmt.Sprintf("Retrieved promotion #%d: %s", id, promotion.OwnerOrName))
+	a.logger.InfoWith(fmt.Sprintf("Retrieved promotion #%d: %s", id, promotion.OwnerOrName), entityLog("promotion", id, "read", start)...)
 
-	return map[string]any{
+	return addDiscountFields(map[string]any{
 		"id":         promotion.ID,
 		"name":       promotion.OwnerOrName,
 		"totalPrice": promotion.TotalPrice,
 		"itemCount":  promotion.ItemCount,
 		"itemIDs":    promotion.ItemIDs,
-	}, nil
+	}, promotion), nil
 }
 
 // DeletePromotion marks a promotion as deleted
-func (a *App) DeletePromotion(id uint64) error {
-	err := a.promotionDAO.Delete(id)
+func (a *App) DeletePromotion(id uint64) (err error) {
+	start := time.Now()
+	defer a.track("DeletePromotion", start, &err)
+	if err := a.checkWritable(); err != nil {
+		return err
+	}
+
+	op := oplog.Operation{Type: oplog.OpDeletePromotion, ID: id}
+	before := ""
+	if promotion, err := a.promotionDAO.Read(id); err == nil {
+		before = collectionSummary(promotion.TotalPrice, promotion.ItemIDs)
+		op.Name, op.Price, op.ItemIDs, op.Extensions = promotion.OwnerOrName, promotion.TotalPrice, promotion.ItemIDs, promotion.Extensions
+	}
+
+	err = a.promotionDAO.Delete(id)
 	if err != nil {
 		return err
 	}
+	a.recordOp(op)
+	a.recordAudit(dao.AuditDelete, "promotion", id, before, "")
 
-	a.logger.Info(fmt.Sprintf("Deleted promotion #%d", id))
+	a.logger.InfoWith(fmt.Sprintf("Deleted promotion #%d", id), entityLog("promotion", id, dao.AuditDelete, start)...)
+	a.checkCompactionPolicy()
 	return nil
 }
 
 // ApplyPromotionToOrder applies a promotion to an order (N:N relationship)
-func (a *App) ApplyPromotionToOrder(orderID, promotionID uint64) error {
+func (a *App) ApplyPromotionToOrder(orderID, promotionID uint64) (err error) {
+	defer a.track("ApplyPromotionToOrder", time.Now(), &err)
+	return a.applyPromotionToOrder(orderID, promotionID, time.Now().UTC())
+}
+
+// applyPromotionToOrder applies a promotion to an order, stamping the relationship with appliedAt
+func (a *App) applyPromotionToOrder(orderID, promotionID uint64, appliedAt time.Time) error {
+	start := time.Now()
+	if err := a.checkWritable(); err != nil {
+		return err
+	}
+
 	// Validate order exists
-	_, err := a.orderDAO.Read(orderID)
+	order, err := a.orderDAO.Read(orderID)
 	if err != nil {
 		return fmt.Errorf("failed to read order: %w", err)
 	}
 
 	// Validate promotion exists
-	_, err = a.promotionDAO.Read(promotionID)
+	promotion, err := a.promotionDAO.Read(promotionID)
 	if err != nil {
 		return fmt.Errorf("failed to read promotion: %w", err)
 	}
 
-	// Write the order-promotion relationship
-	err = a.orderPromotionDAO.Write(orderID, promotionID)
+	// Write the order-promotion relationship, applying it twice is only a warning
+	link := appliedLink(order, promotion, appliedAt)
+	err = a.orderPromotionDAO.WriteLink(link)
+	if errors.Is(err, dao.ErrAlreadyApplied) {
+		message := fmt.Sprintf("Promotion #%d is already applied to order #%d", promotionID, orderID)
+		a.logger.Warn(message)
+		a.toast.Warning(message)
+		return nil
+	}
 	if err != nil {
 		return fmt.Errorf("failed to apply promotion: %w", err)
 	}
+	a.recordOp(oplog.Operation{Type: oplog.OpApplyPromotion, OrderID: orderID, PromotionID: promotionID, Price: link.DiscountAmount})
+	a.recordAudit(dao.AuditUpdate, "order", orderID, "", fmt.Sprintf("promotion #%d applied", promotionID))
 
-	a.logger.Info(fmt.Sprintf("Applied promotion #%d to order #%d", promotionID, orderID))
+	a.logger.InfoWith(fmt.Sprintf("Applied promotion #%d to order #%d", promotionID, orderID), entityLog("order", orderID, "apply_promotion", start)...)
 
 	return nil
 }
 
+// ApplyPromotionsToOrder applies several promotions to an order in one batch
+// The order and every promotion are checked before anything is written, so a missing one applies none;
+// promotions already applied are skipped with a warning. The relationships are appended together and
+// the hash index is saved once. Returns the promotion IDs that were applied
+func (a *App) ApplyPromotionsToOrder(orderID uint64, promotionIDs []uint64) (_ []uint64, err error) {
+	defer a.track("ApplyPromotionsToOrder", time.Now(), &err)
+	return a.applyPromotionsToOrder(orderID, promotionIDs, time.Now().UTC())
+}
+
+// applyPromotionsToOrder applies several promotions to an order in one batch, stamping the relationships with appliedAt
+func (a *App) applyPromotionsToOrder(orderID uint64, promotionIDs []uint64, appliedAt time.Time) ([]uint64, error) {
+	start := time.Now()
+	if err := a.checkWritable(); err != nil {
+		return nil, err
+	}
+	if len(promotionIDs) == 0 {
+		return nil, utils.WithCode(utils.CodeValidation, fmt.Errorf("no promotions to apply"), nil)
+	}
+
+	order, err := a.orderDAO.Read(orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read order: %w", err)
+	}
+	checked := make(map[uint64]bool)
+	links := make([]dao.OrderPromotion, 0, len(promotionIDs))
+	for _, promotionID := range promotionIDs {
+		if checked[promotionID] {
+			continue
+		}
+		promotion, err := a.promotionDAO.Read(promotionID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read promotion: %w", err)
+		}
+		checked[promotionID] = true
+		links = append(links, appliedLink(order, promotion, appliedAt))
+	}
+
+	written, err := a.orderPromotionDAO.WriteMany(links)
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply promotions: %w", err)
+	}
+	applied := make([]uint64, 0, len(written))
+	for _, link := range written {
+		applied = append(applied, link.PromotionID)
+		a.recordOp(oplog.Operation{Type: oplog.OpApplyPromotion, OrderID: orderID, PromotionID: link.PromotionID, Price: link.DiscountAmount})
+		a.recordAudit(dao.AuditUpdate, "order", orderID, "", fmt.Sprintf("promotion #%d applied", link.PromotionID))
+	}
+	if skipped := len(checked) - len(applied); skipped > 0 {
+		message := fmt.Sprintf("%d promotion(s) were already applied to order #%d", skipped, orderID)
+		a.logger.Warn(message)
+		a.toast.Warning(message)
+	}
+
+	a.logger.InfoWith(fmt.Sprintf("Applied %d promotion(s) to order #%d", len(applied), orderID), entityLog("order", orderID, "apply_promotion", start)...)
+	return applied, nil
+}
+
 // GetOrderPromotions retrieves all promotions applied to an order
-func (a *App) GetOrderPromotions(orderID uint64) ([]map[string]any, error) {
+func (a *App) GetOrderPromotions(orderID uint64) (_ []map[string]any, err error) {
+	defer a.track("GetOrderPromotions", time.Now(), &err)
 	orderPromotions, err := a.orderPromotionDAO.GetByOrderID(orderID)
 	if err != nil {
 		return nil, err
@@ -773,19 +1804,19 @@ func (a *App) GetOrderPromotions(orderID uint64) ([]map[string]any, error) {
 		promotion, err := a.promotionDAO.Read(op.PromotionID)
 		if err != nil {
 			// If promotion is deleted, still show the relationship with basic info
-			result[i] = map[string]any{
+			result[i] = addLinkFields(map[string]any{
 				"id":   op.PromotionID,
 				"name": "Deleted Promotion",
-			}
+			}, op)
 			continue
 		}
 
-		result[i] = map[string]any{
+		result[i] = addLinkFields(addDiscountFields(map[string]any{
 			"id":         op.PromotionID,
 			"name":       promotion.OwnerOrName,
 			"totalPrice": promotion.TotalPrice,
 			"itemCount":  promotion.ItemCount,
-		}
+		}, promotion), op)
 	}
 
 	a.logger.Info(fmt.Sprintf("Retrieved %d promotions for order #%d", len(result), orderID))
@@ -793,7 +1824,8 @@ func (a *App) GetOrderPromotions(orderID uint64) ([]map[string]any, error) {
 }
 
 // GetPromotionOrders retrieves all orders that have a specific promotion applied
-func (a *App) GetPromotionOrders(promotionID uint64) ([]map[string]any, error) {
+func (a *App) GetPromotionOrders(promotionID uint64) (_ []map[string]any, err error) {
+	defer a.track("GetPromotionOrders", time.Now(), &err)
 	orderPromotions, err := a.orderPromotionDAO.GetByPromotionID(promotionID)
 	if err != nil {
 		return nil, err
@@ -805,19 +1837,19 @@ func (a *App) GetPromotionOrders(promotionID uint64) ([]map[string]any, error) {
 		order, err := a.orderDAO.Read(op.OrderID)
 		if err != nil {
 			// If order is deleted, still show the relationship with basic info
-			result[i] = map[string]any{
+			result[i] = addLinkFields(map[string]any{
 				"orderID":  op.OrderID,
 				"customer": "Deleted Order",
-			}
+			}, op)
 			continue
 		}
 
-		result[i] = map[string]any{
+		result[i] = addLinkFields(map[string]any{
 			"orderID":    op.OrderID,
 			"customer":   order.OwnerOrName,
 			"totalPrice": order.TotalPrice,
 			"itemCount":  order.ItemCount,
-		}
+		}, op)
 	}
 
 	a.logger.Info(fmt.Sprintf("Retrieved %d orders for promotion #%d", len(result), promotionID))
@@ -825,18 +1857,28 @@ func (a *App) GetPromotionOrders(promotionID uint64) ([]map[string]any, error) {
 }
 
 // RemovePromotionFromOrder removes a promotion from an order
-func (a *App) RemovePromotionFromOrder(orderID, promotionID uint64) error {
-	err := a.orderPromotionDAO.Delete(orderID, promotionID)
+func (a *App) RemovePromotionFromOrder(orderID, promotionID uint64) (err error) {
+	start := time.Now()
+	defer a.track("RemovePromotionFromOrder", start, &err)
+	if err := a.checkWritable(); err != nil {
+		return err
+	}
+
+	err = a.orderPromotionDAO.Delete(orderID, promotionID)
 	if err != nil {
 		return err
 	}
+	a.recordOp(oplog.Operation{Type: oplog.OpRemovePromotion, OrderID: orderID, PromotionID: promotionID})
+	a.recordAudit(dao.AuditUpdate, "order", orderID, fmt.Sprintf("promotion #%d applied", promotionID), "")
 
-	a.logger.Info(fmt.Sprintf("Removed promotion #%d from order #%d", promotionID, orderID))
+	a.logger.InfoWith(fmt.Sprintf("Removed promotion #%d from order #%d", promotionID, orderID), entityLog("order", orderID, "remove_promotion", start)...)
+	a.checkCompactionPolicy()
 	return nil
 }
 
 // GetOrderWithPromotions retrieves an order with all its promotions
-func (a *App) GetOrderWithPromotions(orderID uint64) (map[string]any, error) {
+func (a *App) GetOrderWithPromotions(orderID uint64) (_ map[string]any, err error) {
+	defer a.track("GetOrderWithPromotions", time.Now(), &err)
 	// Get order
 	order, err := a.orderDAO.Read(orderID)
 	if err != nil {
@@ -849,37 +1891,60 @@ func (a *App) GetOrderWithPromotions(orderID uint64) (map[string]any, error) {
 		return nil, err
 	}
 
-	// Calculate combined total price (items + promotions) with overflow checking
-	combinedTotal := order.TotalPrice
+	// Each promotion's discount is computed against the order total, the sum is
+	// subtracted and the final total never goes below zero
+	discountTotal := uint64(0)
 	for _, promo := range promotions {
-		if totalPrice, ok := promo["totalPrice"].(uint64); ok {
-			newTotal, err := utils.SafeAddUint64(combinedTotal, totalPrice)
-			if err != nil {
-				return nil, fmt.Errorf("price overflow calculating combined total: %w", err)
-			}
-			combinedTotal = newTotal
+		discountType, _ := promo["discountType"].(string)
+		t, err := utils.ParseDiscountType(discountType)
+		if err != nil {
+			continue
+		}
+		value, _ := promo["discountValue"].(uint64)
+		discount := utils.Discount{Type: t, Value: value}.Amount(order.TotalPrice)
+		promo["discountAmount"] = discount
+
+		newTotal, err := utils.SafeAddUint64(discountTotal, discount)
+		if err != nil {
+			return nil, fmt.Errorf("price overflow calculating discounts: %w", err)
 		}
+		discountTotal = newTotal
+	}
+
+	finalTotal := uint64(0)
+	if discountTotal < order.TotalPrice {
+		finalTotal = order.TotalPrice - discountTotal
 	}
 
 	a.logger.Info(fmt.Sprintf("Retrieved order #%d with %d promotions", orderID, len(promotions)))
 
-	return map[string]any{
-		"id":           order.ID,
-		"customerName": order.OwnerOrName,
-		"totalPrice":   combinedTotal,
-		"promotions":   promotions,
-		"itemCount":    order.ItemCount,
-		"itemIDs":      order.ItemIDs,
-	}, nil
+	return addOrderFields(map[string]any{
+		"id":             order.ID,
+		"customerName":   order.OwnerOrName,
+		"subtotal":       order.TotalPrice,
+		"discountTotal":  discountTotal,
+		"totalPrice":     finalTotal,
+		"formattedTotal": a.formatPrice(finalTotal),
+		"promotions":     promotions,
+		"itemCount":      order.ItemCount,
+		"itemIDs":        order.ItemIDs,
+	}, order), nil
 }
 
 // CompressFile compresses a binary file using the specified algorithm
-func (a *App) CompressFile(filename string, algorithm string) (map[string]any, error) {
+// The compressed file is decompressed and checked against the original by checksum before anything is deleted;
+// the .bin file and its index are only removed when keepOriginal is false
+func (a *App) CompressFile(filename string, algorithm string, keepOriginal bool) (_ map[string]any, err error) {
+	defer a.track("CompressFile", time.Now(), &err)
+	if err := a.checkWritable(); err != nil {
+		return nil, err
+	}
+
 	inputPath := utils.BinPath(filename)
 
 	fileInfo, err := os.Stat(inputPath)
 	if err != nil {
-		return nil, fmt.Errorf("file not found: %s", filename)
+		return nil, utils.WithCode(utils.CodeNotFound, fmt.Errorf("file not found: %s", filename), map[string]any{"file": filename})
 	}
 	originalSize := fileInfo.Size()
 
@@ -887,11 +1952,6 @@ func (a *App) CompressFile(filename string, algorithm string) (map[string]any, e
 	outputPath := utils.CompressedPath(outputFilename)
 
 	compressor, err := compression.NewCompressor(algorithm)
-	if err != nil {
-		return nil, err
-	}
-	err = compressor.CompressFile(inputPath, outputPath)
-
 	if err != nil {
 		return nil, err
 	}
@@ -899,20 +1959,32 @@ func (a *App) CompressFile(filename string, algorithm string) (map[string]any, e
 		return nil, fmt.Errorf("compression failed: %w", err)
 	}
 
+	if err := compression.VerifyFile(compressor, inputPath, outputPath); err != nil {
+		os.Remove(outputPath)
+		a.logger.Error(fmt.Sprintf("Verification of %s failed, original kept: %v", outputFilename, err))
+		return nil, fmt.Errorf("verification failed: %w", err)
+	}
+
 	compressedInfo, err := os.Stat(outputPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to stat compressed file: %w", err)
 	}
 	compressedSize := compressedInfo.Size()
 
-	utils.RemoveBinFile(filename, a.logger.Info)
-	utils.RemoveIndexForBin(filename, a.logger.Info)
+	original := "kept"
+	if !keepOriginal {
+		a.closeDAOs()
+		utils.RemoveBinFile(filename, a.logger.Info)
+		utils.RemoveIndexForBin(filename, a.logger.Info)
+		a.reloadDAOs()
+		original = "removed"
+	}
 
 	ratio := float64(compressedSize) / float64(originalSize) * 100
 	spaceSaved := float64(originalSize-compressedSize) / float64(originalSize) * 100
 
-	a.logger.Info(fmt.Sprintf("Compressed %s -> %s (%.2f%% of original, saved %.2f%%)",
-		filename, outputFilename, ratio, spaceSaved))
+	a.logger.Info(fmt.Sprintf("Compressed %s -> %s (%.2f%% of original, saved %.2f%%, verified, original %s)",
+		filename, outputFilename, ratio, spaceSaved, original))
 
 	return map[string]any{
 		"outputFile":     outputFilename,
@@ -920,91 +1992,85 @@ func (a *App) CompressFile(filename string, algorithm string) (map[string]any, e
 		"compressedSize": compressedSize,
 		"ratio":          fmt.Sprintf("%.2f%%", ratio),
 		"spaceSaved":     fmt.Sprintf("%.2f%%", spaceSaved),
+		"keptOriginal":   keepOriginal,
 	}, nil
 }
 
 // CompressAllFiles compresses all .bin files into a single archive
-func (a *App) CompressAllFiles(algorithm string) (map[string]any, error) {
-	entries, err := os.ReadDir(utils.BinDir)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read bin directory: %w", err)
+// With encrypt set the compressed archive is AES-GCM encrypted under an RSA-wrapped key
+func (a *App) CompressAllFiles(algorithm string, encrypt bool) (_ map[string]any, err error) {
+	defer a.track("CompressAllFiles", time.Now(), &err)
+	if err := a.checkWritable(); err != nil {
+		return nil, err
 	}
 
-	var binFiles []string
-	for _, entry := range entries {
-		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".bin") {
-			binFiles = append(binFiles, entry.Name())
-		}
+	// Files are archived under their names without a generation, so the archive restores anywhere
+	binFiles, err := utils.CurrentBinFiles(utils.BinDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bin directory: %w", err)
 	}
 
 	if len(binFiles) == 0 {
 		return nil, fmt.Errorf("no .bin files found to compress")
 	}
 
-	// Build combined data with file markers
-	// Format: [fileCount(4)][file1NameLen(2)][file1Name][file1Size(4)][file1Data]...
-	var combined []byte
-
-	fileCountBytes := make([]byte, 4)
-	binary.BigEndian.PutUint32(fileCountBytes, uint32(len(binFiles)))
-	combined = append(combined, fileCountBytes...)
-
-	var totalOriginalSize int64
-
-	for _, filename := range binFiles {
-		data, err := os.ReadFile(utils.BinPath(filename))
-		if err != nil {
-			return nil, fmt.Errorf("failed to read %s: %w", filename, err)
-		}
-
-		totalOriginalSize += int64(len(data))
-
-		nameBytes := []byte(filename)
-		nameLenBytes := make([]byte, 2)
-		binary.BigEndian.PutUint16(nameLenBytes, uint16(len(nameBytes)))
-		combined = append(combined, nameLenBytes...)
-		combined = append(combined, nameBytes...)
-
-		sizeBytes := make([]byte, 4)
-		binary.BigEndian.PutUint32(sizeBytes, uint32(len(data)))
-		combined = append(combined, sizeBytes...)
-		combined = append(combined, data...)
-	}
-
-	outputFilename := utils.CompressedFilename("all_files", algorithm)
-
 	compressor, err := compression.NewCompressor(algorithm)
 	if err != nil {
 		return nil, err
 	}
-	compressedData, err := compressor.Compress(combined)
 
+	// The files are streamed into the compressor instead of being read into memory
+	p := a.startProgress("CompressAllFiles", 0)
+	defer p.Finish()
+	archive, totalOriginalSize, closeFiles, err := archiveReader(binFiles, p.Reader)
 	if err != nil {
-		return nil, fmt.Errorf("compression failed: %w", err)
+		return nil, err
 	}
+	defer closeFiles()
+	p.AddTotal(totalOriginalSize)
 
+	outputFilename := utils.CompressedFilename("all_files", algorithm)
 	outputPath := utils.CompressedPath(outputFilename)
 
 	if err := os.MkdirAll(utils.CompressedDir, 0700); err != nil {
 		return nil, fmt.Errorf("failed to create output directory: %w", err)
 	}
 
-	if err := os.WriteFile(outputPath, compressedData, 0600); err != nil {
-		return nil, fmt.Errorf("failed to write compressed file: %w", err)
+	if encrypt {
+		err = writeEncryptedArchive(compressor, archive, outputPath)
+	} else {
+		err = writeArchive(compressor, archive, outputPath)
+	}
+	if cancelled := p.Err(); cancelled != nil {
+		// The archive is removed on failure, the data files are still in place
+		return nil, cancelled
+	}
+	if err != nil {
+		return nil, err
 	}
 
-	compressedSize := int64(len(compressedData))
+	compressedInfo, err := os.Stat(outputPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat compressed file: %w", err)
+	}
+	compressedSize := compressedInfo.Size()
 
+	a.closeDAOs()
 	for _, filename := range binFiles {
 		utils.RemoveBinFile(filename, a.logger.Info)
 		utils.RemoveIndexForBin(filename, a.logger.Info)
 	}
+	a.reloadDAOs()
 
 	ratio := float64(compressedSize) / float64(totalOriginalSize) * 100
 	spaceSaved := float64(totalOriginalSize-compressedSize) / float64(totalOriginalSize) * 100
 
-	a.logger.Info(fmt.Sprintf("Compressed %d files -> %s (%.2f%% of original, saved %.2f%%)",
-		len(binFiles), outputFilename, ratio, spaceSaved))
+	encrypted := ""
+	if encrypt {
+		encrypted = ", encrypted"
+	}
+	a.logger.Info(fmt.Sprintf("Compressed %d files -> %s (%.2f%% of original, saved %.2f%%%s)",
+		len(binFiles), outputFilename, ratio, spaceSaved, encrypted))
 
 	return map[string]any{
 		"outputFile":     outputFilename,
@@ -1012,15 +2078,21 @@ func (a *App) CompressAllFiles(algorithm string) (map[string]any, error) {
 		"compressedSize": compressedSize,
 		"ratio":          fmt.Sprintf("%.2f%%", ratio),
 		"spaceSaved":     fmt.Sprintf("%.2f%%", spaceSaved),
+		"encrypted":      encrypt,
 	}, nil
 }
 
 // DecompressFile decompresses a compressed file
-func (a *App) DecompressFile(filename string) (map[string]any, error) {
+func (a *App) DecompressFile(filename string) (_ map[string]any, err error) {
+	defer a.track("DecompressFile", time.Now(), &err)
+	if err := a.checkWritable(); err != nil {
+		return nil, err
+	}
+
 	inputPath := utils.CompressedPath(filename)
 
 	if _, err := os.Stat(inputPath); err != nil {
-		return nil, fmt.Errorf("compressed file not found: %s", filename)
+		return nil, utils.WithCode(utils.CodeNotFound, fmt.Errorf("compressed file not found: %s", filename), map[string]any{"file": filename})
 	}
 
 	// Check if this is an all_files archive
@@ -1028,14 +2100,17 @@ func (a *App) DecompressFile(filename string) (map[string]any, error) {
 		return a.decompressAllFiles(inputPath, filename)
 	}
 
+	// Files renamed outside the app lose their algorithm suffix, so fall back to the magic bytes
 	algorithm := utils.DetectCompressionAlgorithm(filename)
+	if algorithm == utils.AlgorithmUnknown {
+		algorithm, _ = compression.DetectFileAlgorithm(inputPath)
+	}
 	if algorithm == utils.AlgorithmUnknown {
 		return nil, fmt.Errorf("unknown compression format: %s", filename)
 	}
 
 	outputFilename := utils.DecompressedFilename(filename)
 	outputPath := utils.BinPath(outputFilename)
-	var err error
 
 	compressor, err := compression.NewCompressor(algorithm)
 	if err != nil {
@@ -1047,7 +2122,14 @@ func (a *App) DecompressFile(filename string) (map[string]any, error) {
 	if err != nil {
 		return nil, err
 	}
-	if err = decompressor.DecompressFile(inputPath, outputPath); err != nil {
+	if encrypted, err := isEncryptedArchive(inputPath); err != nil {
+		return nil, err
+	} else if encrypted {
+		err = decompressEncryptedFile(decompressor, inputPath, outputPath)
+	} else {
+		err = decompressor.DecompressFile(inputPath, outputPath)
+	}
+	if err != nil {
 		return nil, fmt.Errorf("decompression failed: %w", err)
 	}
 
@@ -1068,6 +2150,8 @@ func (a *App) DecompressFile(filename string) (map[string]any, error) {
 	ratio := float64(compressedSize) / float64(originalSize) * 100
 	spaceSaved := float64(originalSize-compressedSize) / float64(originalSize) * 100
 
+	a.reloadDAOs()
+	a.signDataFiles()
 	a.logger.Info(fmt.Sprintf("Decompressed %s -> %s (%d bytes)", filename, outputFilename, originalSize))
 
 	return map[string]any{
@@ -1079,101 +2163,83 @@ func (a *App) DecompressFile(filename string) (map[string]any, error) {
 	}, nil
 }
 
-// decompressAllFiles handles decompression of the all_files archive
-func (a *App) decompressAllFiles(inputPath string, filename string) (map[string]any, error) {
-	compressedData, err := os.ReadFile(inputPath)
+// isEncryptedArchive reports whether a compressed file was encrypted when it was written
+func isEncryptedArchive(path string) (bool, error) {
+	file, err := os.Open(path)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read compressed file: %w", err)
+		return false, fmt.Errorf("failed to open compressed file: %w", err)
 	}
-	compressedSize := int64(len(compressedData))
+	defer file.Close()
 
-	algorithm := utils.DetectCompressionAlgorithm(filename)
+	magic := make([]byte, len(crypto.BlobMagic))
+	if n, _ := file.Read(magic); n < len(magic) {
+		return false, nil
+	}
+	return crypto.IsEncryptedBlob(magic), nil
+}
 
-	compressor, err := compression.NewCompressor(algorithm)
+// decompressEncryptedFile decrypts an encrypted compressed file and writes its decompressed contents
+func decompressEncryptedFile(decompressor compression.Compressor, inputPath, outputPath string) error {
+	blob, err := os.ReadFile(inputPath)
 	if err != nil {
-		return nil, fmt.Errorf("unknown compression format: %s", filename)
+		return fmt.Errorf("failed to read compressed file: %w", err)
 	}
-	data, err := compressor.Decompress(compressedData)
+	compressedData, err := crypto.DecryptBlob(blob)
 	if err != nil {
-		return nil, fmt.Errorf("decompression failed: %w", err)
+		return fmt.Errorf("decryption failed: %w", err)
+	}
+	data, err := decompressor.Decompress(compressedData)
+	if err != nil {
+		return err
 	}
-
-	// Validate decompressed size to prevent decompression bomb attacks
 	if err := utils.ValidateDecompressedSize(len(data)); err != nil {
-		return nil, fmt.Errorf("decompression security check failed: %w", err)
+		return fmt.Errorf("decompression security check failed: %w", err)
 	}
-
-	// Parse the combined format
-	// Format: [fileCount(4)][file1NameLen(2)][file1Name][file1Size(4)][file1Data]...
-	if len(data) < 4 {
-		return nil, fmt.Errorf("invalid archive format: too short")
+	if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
 	}
+	return os.WriteFile(outputPath, data, 0644)
+}
 
-	offset := 0
-	fileCount := binary.BigEndian.Uint32(data[offset : offset+4])
-	offset += 4
-
-	// Validate file count to prevent resource exhaustion
-	if err := utils.ValidateArchiveFileCount(fileCount); err != nil {
-		return nil, fmt.Errorf("invalid archive: %w", err)
+// decompressAllFiles handles decompression of the all_files archive
+func (a *App) decompressAllFiles(inputPath string, filename string) (map[string]any, error) {
+	compressedInfo, err := os.Stat(inputPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat compressed file: %w", err)
 	}
+	compressedSize := compressedInfo.Size()
 
-	if err := os.MkdirAll(utils.BinDir, 0700); err != nil {
-		return nil, fmt.Errorf("failed to create bin directory: %w", err)
+	archive, decompressor, closeArchive, err := openArchive(filename)
+	if err != nil {
+		return nil, err
 	}
+	defer closeArchive()
 
-	var totalOriginalSize int64
-	var filesRestored int
-
-	for i := uint32(0); i < fileCount; i++ {
-		if offset+2 > len(data) {
-			return nil, fmt.Errorf("invalid archive format: truncated at file %d name length", i)
-		}
-		nameLen := binary.BigEndian.Uint16(data[offset : offset+2])
-		offset += 2
-
-		// Validate filename length
-		if nameLen == 0 || nameLen > uint16(utils.MaxNameLength) {
-			return nil, fmt.Errorf("invalid archive format: invalid filename length %d at file %d", nameLen, i)
-		}
-
-		if offset+int(nameLen) > len(data) {
-			return nil, fmt.Errorf("invalid archive format: truncated at file %d name", i)
-		}
-		restoredFilename := string(data[offset : offset+int(nameLen)])
-		offset += int(nameLen)
-
-		if offset+4 > len(data) {
-			return nil, fmt.Errorf("invalid archive format: truncated at file %d size", i)
-		}
-		fileSize := binary.BigEndian.Uint32(data[offset : offset+4])
-		offset += 4
-
-		// Validate individual file size
-		if fileSize > uint32(utils.MaxRecordSize) {
-			return nil, fmt.Errorf("invalid archive format: file %d size %d exceeds maximum", i, fileSize)
-		}
-
-		if offset+int(fileSize) > len(data) {
-			return nil, fmt.Errorf("invalid archive format: truncated at file %d data", i)
-		}
-		fileData := data[offset : offset+int(fileSize)]
-		offset += int(fileSize)
+	restore := &archiveRestore{}
+	defer restore.discard()
 
-		if err := os.WriteFile(utils.BinPath(restoredFilename), fileData, 0600); err != nil {
-			return nil, fmt.Errorf("failed to write %s: %w", restoredFilename, err)
+	filesRestored, totalOriginalSize, err := scanArchive(decompressor, archive, func(name string, size int64, data io.Reader) error {
+		if err := restore.stage(name, size, data); err != nil {
+			return err
 		}
-
-		totalOriginalSize += int64(fileSize)
-		filesRestored++
-		a.logger.Info(fmt.Sprintf("Restored %s (%d bytes)", restoredFilename, fileSize))
+		a.logger.Info(fmt.Sprintf("Restored %s (%d bytes)", name, size))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	a.closeDAOs()
+	if err := restore.commit(); err != nil {
+		return nil, err
 	}
+	a.reloadDAOs()
 
 	utils.RemoveCompressedFile(filename, a.logger.Info)
 
 	ratio := float64(compressedSize) / float64(totalOriginalSize) * 100
 	spaceSaved := float64(totalOriginalSize-compressedSize) / float64(totalOriginalSize) * 100
 
+	a.signDataFiles()
 	a.logger.Info(fmt.Sprintf("Decompressed all_files archive: %d files restored (%d bytes total)", filesRestored, totalOriginalSize))
 
 	return map[string]any{
@@ -1217,7 +2283,8 @@ func (a *App) listFilesInDir(dir string, filter func(string) bool, mapper func(s
 }
 
 // GetCompressedFiles returns a list of compressed files with metadata
-func (a *App) GetCompressedFiles() ([]map[string]any, error) {
+func (a *App) GetCompressedFiles() (_ []map[string]any, err error) {
+	defer a.track("GetCompressedFiles", time.Now(), &err)
 	if _, err := os.Stat(utils.CompressedDir); os.IsNotExist(err) {
 		return []map[string]any{}, nil
 	}
@@ -1242,16 +2309,14 @@ func (a *App) GetCompressedFiles() ([]map[string]any, error) {
 		name := entry.Name()
 		algorithm := utils.DetectCompressionAlgorithm(name)
 
-		// Read original size from file header (format: 4 magic + uint32 originalSize)
+		// The header of an encrypted archive is encrypted too, so its original size is unknown
 		var originalSize int64 = 0
+		encrypted := false
 		if algorithm != utils.AlgorithmUnknown {
-			file, err := os.Open(utils.CompressedPath(name))
-			if err == nil {
-				header := make([]byte, 8) // 4 magic + 4 size
-				if n, err := file.Read(header); err == nil && n == 8 {
-					originalSize = int64(binary.LittleEndian.Uint32(header[4:8]))
-				}
-				file.Close()
+			path := utils.CompressedPath(name)
+			encrypted, _ = isEncryptedArchive(path)
+			if !encrypted {
+				originalSize, _ = compression.ReadOriginalSize(path)
 			}
 		}
 
@@ -1268,49 +2333,129 @@ func (a *App) GetCompressedFiles() ([]map[string]any, error) {
 			"algorithm":      algorithm,
 			"ratio":          fmt.Sprintf("%.2f%%", ratio),
 			"spaceSaved":     fmt.Sprintf("%.2f%%", spaceSaved),
+			"encrypted":      encrypted,
 		})
 	}
 
 	return files, nil
 }
 
-// DeleteCompressedFile deletes a compressed file
-func (a *App) DeleteCompressedFile(filename string) error {
+// DeleteCompressedFile moves a compressed file to the trash
+func (a *App) DeleteCompressedFile(filename string) (err error) {
+	defer a.track("DeleteCompressedFile", time.Now(), &err)
+	if err := a.checkWritable(); err != nil {
+		return err
+	}
+
 	filePath := utils.CompressedPath(filename)
 
 	if _, err := os.Stat(filePath); os.IsNotExist(err) {
-		return fmt.Errorf("file not found: %s", filename)
+		return utils.WithCode(utils.CodeNotFound, fmt.Errorf("file not found: %s", filename), map[string]any{"file": filename})
 	}
 
-	if err := os.Remove(filePath); err != nil {
+	trash := utils.NewTrash()
+	if err := trash.Move(filePath); err != nil {
 		return fmt.Errorf("failed to delete file: %w", err)
 	}
 
-	a.logger.Info(fmt.Sprintf("Deleted compressed file: %s", filename))
+	a.logger.Info(fmt.Sprintf("Moved compressed file %s to trash entry %s", filename, trash.Name()))
+	a.purgeTrash()
 	return nil
 }
 
 // GetBinFiles returns a list of .bin files in the data/bin directory
-func (a *App) GetBinFiles() ([]map[string]any, error) {
+func (a *App) GetBinFiles() (_ []map[string]any, err error) {
+	defer a.track("GetBinFiles", time.Now(), &err)
 	return a.listFilesInDir(
 		utils.BinDir,
-		func(name string) bool { return strings.HasSuffix(name, ".bin") },
+		func(name string) bool { return utils.IsCurrentBinFile(utils.BinDir, name) },
 		func(name string, size int64) map[string]any {
 			return map[string]any{
-				"name": name,
+				"name": utils.LogicalBinName(name),
 				"size": size,
 			}
 		},
 	)
 }
 
+// GetFileStats returns the record counts, next ID and size of every .bin file, read from the file
+// headers alone so no record is scanned. Counts include tombstoned records, activeCount excludes them
+func (a *App) GetFileStats() (_ []map[string]any, err error) {
+	defer a.track("GetFileStats", time.Now(), &err)
+	names, err := utils.CurrentBinFiles(utils.BinDir)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+
+	result := make([]map[string]any, 0, len(names))
+	for _, name := range names {
+		header, err := utils.ReadHeaderOnly(utils.BinPath(name))
+		if os.IsNotExist(err) {
+			continue // removed by a compaction since the directory was listed
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read header of %s: %w", name, err)
+		}
+		result = append(result, map[string]any{
+			"file":           name,
+			"entitiesCount":  header.EntitiesCount,
+			"tombstoneCount": header.TombstoneCount,
+			"activeCount":    header.EntitiesCount - header.TombstoneCount,
+			"nextId":         header.NextId,
+			"sizeBytes":      header.FileSize,
+			"headerBytes":    header.HeaderSize,
+			"formatVersion":  header.Version,
+			"idSize":         header.IDSize,
+		})
+	}
+	return result, nil
+}
+
+// CountItems returns the number of items from the header of the item file, without reading the records
+// activeOnly leaves deleted items out; otherwise every record of the file is counted, including the
+// tombstoned versions updates leave behind, like entitiesCount in GetFileStats
+func (a *App) CountItems(activeOnly bool) (_ int, err error) {
+	defer a.track("CountItems", time.Now(), &err)
+	return a.countRecords("items", a.itemDAO.Counts, activeOnly)
+}
+
+// CountOrders returns the number of orders like CountItems
+func (a *App) CountOrders(activeOnly bool) (_ int, err error) {
+	defer a.track("CountOrders", time.Now(), &err)
+	return a.countRecords("orders", a.orderDAO.Counts, activeOnly)
+}
+
+// CountPromotions returns the number of promotions like CountItems
+func (a *App) CountPromotions(activeOnly bool) (_ int, err error) {
+	defer a.track("CountPromotions", time.Now(), &err)
+	return a.countRecords("promotions", a.promotionDAO.Counts, activeOnly)
+}
+
+// countRecords picks the total or active count of a file, warning when its header disagreed with its index
+func (a *App) countRecords(kind string, counts func() (dao.RecordCounts, error), activeOnly bool) (int, error) {
+	result, err := counts()
+	if err != nil {
+		return 0, err
+	}
+	if result.Scanned {
+		a.logger.Warn(fmt.Sprintf("The header of the %s file disagrees with its index, counted %s by scanning the file", kind, kind))
+	}
+	if activeOnly {
+		return result.Active, nil
+	}
+	return result.Total, nil
+}
+
 // GetEncryptionEnabled returns whether RSA encryption is enabled
 func (a *App) GetEncryptionEnabled() bool {
+	defer a.track("GetEncryptionEnabled", time.Now(), nil)
 	return crypto.IsEnabled()
 }
 
 // SetEncryptionEnabled enables or disables RSA encryption
 func (a *App) SetEncryptionEnabled(enabled bool) {
+	defer a.track("SetEncryptionEnabled", time.Now(), nil)
 	crypto.SetEnabled(enabled)
 	status := "disabled"
 	if enabled {
@@ -1319,6 +2464,24 @@ func (a *App) SetEncryptionEnabled(enabled bool) {
 	a.logger.Info(fmt.Sprintf("RSA encryption %s", status))
 }
 
+// GetUniqueItemNames returns whether AddItem rejects duplicate item names
+func (a *App) GetUniqueItemNames() bool {
+	defer a.track("GetUniqueItemNames", time.Now(), nil)
+	return a.uniqueItemNames
+}
+
+// SetUniqueItemNames enables or disables rejecting duplicate item names in AddItem
+// When disabled, duplicates are only logged as warnings
+func (a *App) SetUniqueItemNames(enabled bool) {
+	defer a.track("SetUniqueItemNames", time.Now(), nil)
+	a.uniqueItemNames = enabled
+	status := "disabled"
+	if enabled {
+		status = "enabled"
+	}
+	a.logger.Info(fmt.Sprintf("Unique item names %s", status))
+}
+
 // CompactResult represents the result of a compaction operation for frontend
 type CompactResult struct {
 	ItemsRemoved           int `json:"itemsRemoved"`
@@ -1327,31 +2490,85 @@ type CompactResult struct {
 	OrdersRemoved          int `json:"ordersRemoved"`
 	PromotionsRemoved      int `json:"promotionsRemoved"`
 	OrderPromotionsRemoved int `json:"orderPromotionsRemoved"`
+
+	DeletedItemIDs       []uint64 `json:"deletedItemIds"`
+	RemovedOrderIDs      []uint64 `json:"removedOrderIds"`
+	RemovedPromotionIDs  []uint64 `json:"removedPromotionIds"`
+	AffectedOrderIDs     []uint64 `json:"affectedOrderIds"`
+	AffectedPromotionIDs []uint64 `json:"affectedPromotionIds"`
+	ReclaimedBytes       int64    `json:"reclaimedBytes"`
+}
+
+// newCompactResult converts a utils compaction result for the frontend
+func newCompactResult(result *utils.CompactResult) *CompactResult {
+	ids := func(ids []uint64) []uint64 {
+		if ids == nil {
+			return []uint64{}
+		}
+		return ids
+	}
+	return &CompactResult{
+		ItemsRemoved:           result.ItemsRemoved,
+		OrdersAffected:         result.OrdersAffected,
+		PromotionsAffected:     result.PromotionsAffected,
+		OrdersRemoved:          result.OrdersRemoved,
+		PromotionsRemoved:      result.PromotionsRemoved,
+		OrderPromotionsRemoved: result.OrderPromotionsRemoved,
+		DeletedItemIDs:         ids(result.DeletedItemIDs),
+		RemovedOrderIDs:        ids(result.RemovedOrderIDs),
+		RemovedPromotionIDs:    ids(result.RemovedPromotionIDs),
+		AffectedOrderIDs:       ids(result.AffectedOrderIDs),
+		AffectedPromotionIDs:   ids(result.AffectedPromotionIDs),
+		ReclaimedBytes:         result.ReclaimedBytes,
+	}
 }
 
 // Compact performs database compaction:
 // - Removes all tombstoned (deleted) records from binary files
 // - Updates orders/promotions to remove references to deleted items
 // - Rebuilds all indexes
-func (a *App) Compact() (*CompactResult, error) {
+func (a *App) Compact() (_ *CompactResult, err error) {
+	defer a.track("Compact", time.Now(), &err)
+	if err := a.checkWritable(); err != nil {
+		return nil, err
+	}
+
+	if err := a.beginCompaction(); err != nil {
+		return nil, err
+	}
+	defer a.endCompaction()
+
 	a.logger.Info("Starting database compaction...")
 
-	result, err := utils.CompactAll(
+	// Compacting the files, then rebuilding the indexes; once the files are replaced there is nothing to cancel
+	p := a.startProgress("Compact", 2)
+	defer p.Finish()
+
+	start := time.Now()
+	result, err := utils.CompactAll(p.ctx,
 		utils.BinPath("items.bin"),
 		utils.BinPath("orders.bin"),
 		utils.BinPath("promotions.bin"),
 		utils.BinPath("order_promotions.bin"),
+		a.itemBasePrice,
 	)
+	a.observeCompaction(start, err)
+	if cancelled := p.Err(); err != nil && cancelled != nil {
+		a.logger.Info("Compaction cancelled, no file was replaced")
+		return nil, cancelled
+	}
 	if err != nil {
 		a.logger.Error(fmt.Sprintf("Compaction failed: %v", err))
 		return nil, fmt.Errorf("compaction failed: %w", err)
 	}
 
+	p.Advance(1)
+
 	// Reload all DAOs to rebuild indexes from the compacted files
-	a.itemDAO = dao.NewItemDAO(utils.BinPath("items.bin"))
-	a.orderDAO = dao.NewOrderDAO(utils.BinPath("orders.bin"))
-	a.promotionDAO = dao.NewPromotionDAO(utils.BinPath("promotions.bin"))
-	a.orderPromotionDAO = dao.NewOrderPromotionDAO(utils.BinPath("order_promotions.bin"))
+	a.reloadDAOs()
+	p.Advance(1)
+	a.signDataFiles()
+	a.publish(events.Event{Type: events.Compacted})
 
 	a.logger.Info("Indexes rebuilt after compaction")
 
@@ -1386,12 +2603,132 @@ func (a *App) Compact() (*CompactResult, error) {
 		a.toast.Info("No tombstoned records to compact")
 	}
 
-	return &CompactResult{
-		ItemsRemoved:           result.ItemsRemoved,
-		OrdersAffected:         result.OrdersAffected,
-		PromotionsAffected:     result.PromotionsAffected,
-		OrdersRemoved:          result.OrdersRemoved,
-		PromotionsRemoved:      result.PromotionsRemoved,
-		OrderPromotionsRemoved: result.OrderPromotionsRemoved,
+	return newCompactResult(result), nil
+}
+
+// CompactPreview reports what Compact would do without rewriting anything: the records it would remove,
+// the orders and promotions whose item references it would clean, and the bytes it would reclaim
+func (a *App) CompactPreview() (_ *CompactResult, err error) {
+	defer a.track("CompactPreview", time.Now(), &err)
+
+	p := a.startProgress("CompactPreview", 1)
+	defer p.Finish()
+
+	result, err := utils.PreviewCompaction(p.ctx,
+		utils.BinPath("items.bin"),
+		utils.BinPath("orders.bin"),
+		utils.BinPath("promotions.bin"),
+		utils.BinPath("order_promotions.bin"),
+		a.itemBasePrice,
+	)
+	if cancelled := p.Err(); err != nil && cancelled != nil {
+		return nil, cancelled
+	}
+	if err != nil {
+		return nil, fmt.Errorf("compaction preview failed: %w", err)
+	}
+	p.Advance(1)
+
+	return newCompactResult(result), nil
+}
+
+// MigrateDatabase upgrades every .bin file to the current file format version
+// Files are rewritten in place (temp file + rename) and indexes are rebuilt afterwards
+// Order and promotion names still encrypted with the legacy RSA scheme are re-encrypted with AES-GCM
+func (a *App) MigrateDatabase() (_ []map[string]any, err error) {
+	defer a.track("MigrateDatabase", time.Now(), &err)
+	if err := a.checkWritable(); err != nil {
+		return nil, err
+	}
+
+	a.logger.Info(fmt.Sprintf("Starting database migration to format version %d...", utils.CurrentFormatVersion))
+
+	results, err := migrate.MigrateDir(utils.BinDir, utils.CurrentFormatVersion)
+	if err != nil {
+		a.logger.Error(fmt.Sprintf("Migration failed: %v", err))
+		return nil, fmt.Errorf("migration failed: %w", err)
+	}
+
+	migrated := 0
+	files := make([]map[string]any, len(results))
+	for i, result := range results {
+		files[i] = map[string]any{
+			"file":        result.File,
+			"fromVersion": result.FromVersion,
+			"toVersion":   result.ToVersion,
+			"migrated":    result.Migrated,
+		}
+		if result.Migrated {
+			migrated++
+			utils.RemoveIndexForBin(result.File, a.logger.Info)
+			a.logger.Info(fmt.Sprintf("Migrated %s from version %d to %d", result.File, result.FromVersion, result.ToVersion))
+		}
+	}
+
+	// Reload all DAOs so indexes are rebuilt from the migrated files
+	if migrated > 0 {
+		a.reloadDAOs()
+		a.logger.Info("Indexes rebuilt after migration")
+	}
+
+	// Names encrypted with the legacy RSA scheme are rewritten with AES-GCM
+	collectionDAOs := []*dao.CollectionDAO{a.orderDAO.CollectionDAO, a.promotionDAO.CollectionDAO}
+	for i, name := range []string{"order", "promotion"} {
+		count, err := collectionDAOs[i].ReencryptNames()
+		if err != nil {
+			a.logger.Error(fmt.Sprintf("Failed to re-encrypt %s names: %v", name, err))
+			return nil, fmt.Errorf("failed to re-encrypt %s names: %w", name, err)
+		}
+		if count > 0 {
+			a.logger.Info(fmt.Sprintf("Re-encrypted %d %s name(s) with AES-GCM", count, name))
+		}
+	}
+
+	a.signDataFiles()
+	a.logger.Info(fmt.Sprintf("Migration complete: %d of %d file(s) upgraded", migrated, len(results)))
+	return files, nil
+}
+
+// ReplayTo reconstructs the database as it was at the given RFC3339 timestamp
+// by replaying the oplog into a fresh directory under data/replay
+func (a *App) ReplayTo(timestamp string) (_ map[string]any, err error) {
+	defer a.track("ReplayTo", time.Now(), &err)
+	if err := a.checkWritable(); err != nil {
+		return nil, err
+	}
+
+	until, err := time.Parse(time.RFC3339, timestamp)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timestamp (expected RFC3339): %w", err)
+	}
+
+	ops, err := a.oplog.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	dir := filepath.Join(utils.ReplayDir, until.UTC().Format("20060102T150405Z"))
+	if err := os.RemoveAll(dir); err != nil {
+		return nil, fmt.Errorf("failed to clear replay directory: %w", err)
+	}
+
+	result, err := oplog.Replay(ops, until, dir)
+	if err != nil {
+		a.logger.Error(fmt.Sprintf("Replay failed: %v", err))
+		return nil, err
+	}
+
+	for _, replayErr := range result.Errors {
+		a.logger.Warn(fmt.Sprintf("Replay: %s", replayErr))
+	}
+	a.logger.Info(fmt.Sprintf("Replayed %d operations up to %s into %s (%d skipped, %d failed)",
+		result.Applied, until.Format(time.RFC3339), dir, result.Skipped, result.Failed))
+
+	return map[string]any{
+		"dir":     dir,
+		"applied": result.Applied,
+		"skipped": result.Skipped,
+		"failed":  result.Failed,
+		"errors":  result.Errors,
 	}, nil
 }
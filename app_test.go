@@ -0,0 +1,856 @@
+package main
+
+import (
+	"BinaryCRUD/backend/crypto"
+	"BinaryCRUD/backend/index"
+	"BinaryCRUD/backend/utils"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newTestApp creates an App whose data directory is a fresh temporary directory
+// Everything it opened is closed when the test ends
+func newTestApp(t *testing.T) *App {
+	t.Helper()
+	t.Setenv(utils.DataDirEnv, t.TempDir())
+
+	app := NewApp()
+	app.toast = NewToast(app)
+	t.Cleanup(func() {
+		app.waitForCompaction()
+		app.closeWebhooks()
+		app.closeDAOs()
+		app.releaseDataDir()
+		app.logger.Close()
+		utils.SetDataDir(utils.DefaultDataDir)
+		crypto.Reset()
+	})
+	return app
+}
+
+func TestGetOrdersByCustomerName(t *testing.T) {
+	app := newTestApp(t)
+	itemID, err := app.AddItem("Widget", 250)
+	if err != nil {
+		t.Fatalf("Failed to add item: %v", err)
+	}
+	for _, name := range []string{"Ana Lima", "Bruno", "ana  lima"} {
+		if _, err := app.CreateOrder(name, []uint64{itemID}); err != nil {
+			t.Fatalf("Failed to create order for %q: %v", name, err)
+		}
+	}
+
+	orders, err := app.GetOrdersByCustomerName("ANA LIMA")
+	if err != nil {
+		t.Fatalf("Failed to find orders: %v", err)
+	}
+	if len(orders) != 2 {
+		t.Fatalf("Expected 2 orders, got %d", len(orders))
+	}
+	for _, order := range orders {
+		if utils.NormalizeName(order["customer"].(string)) != "ana lima" {
+			t.Errorf("Unexpected customer %q", order["customer"])
+		}
+	}
+
+	if _, err := app.GetOrdersByCustomerName(""); utils.ErrorCodeOf(err) != utils.CodeValidation {
+		t.Errorf("Expected a validation error for an empty name, got %v", err)
+	}
+}
+
+func TestUpdateConfigWhileOperationsRun(t *testing.T) {
+	app := newTestApp(t)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 50; i++ {
+			app.GetCompactionPolicy()
+			app.GetAllItems(ListOptions{})
+		}
+	}()
+
+	for i := 0; i < 20; i++ {
+		config := app.GetConfig()
+		config.SlowOperationMs = i + 1
+		if _, err := app.UpdateConfig(config); err != nil {
+			t.Fatalf("Failed to update config: %v", err)
+		}
+	}
+	<-done
+
+	if got := app.GetConfig().SlowOperationMs; got != 20 {
+		t.Errorf("Expected SlowOperationMs 20, got %d", got)
+	}
+}
+
+func TestGroupCommitFromConfig(t *testing.T) {
+	app := newTestApp(t)
+	config := app.GetConfig()
+	config.GroupCommit = utils.GroupCommitConfig{MaxRecords: 2, MaxDelayMs: 60 * 60 * 1000}
+	if _, err := app.UpdateConfig(config); err != nil {
+		t.Fatalf("Failed to update config: %v", err)
+	}
+	app.reloadDAOs()
+
+	// The first item waits for a second one to fill the group
+	added := make(chan error, 1)
+	go func() {
+		_, err := app.AddItem("Burger", 899)
+		added <- err
+	}()
+	select {
+	case err := <-added:
+		t.Fatalf("Expected AddItem to wait for the group, it returned %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if _, err := app.AddItem("Fries", 349); err != nil {
+		t.Fatalf("Failed to add item: %v", err)
+	}
+	if err := <-added; err != nil {
+		t.Fatalf("Failed to add grouped item: %v", err)
+	}
+	items, err := app.GetAllItems(ListOptions{})
+	if err != nil || len(items) != 2 {
+		t.Errorf("Expected 2 items, got %d (err: %v)", len(items), err)
+	}
+}
+
+func TestZstdCompressAndDecompressRenamedFile(t *testing.T) {
+	app := newTestApp(t)
+	if _, err := app.AddItem("Burger", 899); err != nil {
+		t.Fatalf("Failed to add item: %v", err)
+	}
+
+	result, err := app.CompressFile("items.bin", "zstd", false)
+	if err != nil {
+		t.Fatalf("Failed to compress: %v", err)
+	}
+	if result["outputFile"] != "items.bin.zstd.compressed" {
+		t.Fatalf("Expected items.bin.zstd.compressed, got %v", result["outputFile"])
+	}
+
+	files, err := app.GetCompressedFiles()
+	if err != nil || len(files) != 1 {
+		t.Fatalf("Expected 1 compressed file, got %v (err: %v)", files, err)
+	}
+	if files[0]["originalSize"] != result["originalSize"] {
+		t.Errorf("Expected original size %v from the zstd header, got %v", result["originalSize"], files[0]["originalSize"])
+	}
+
+	// Without the algorithm in its name the file is recognised by its magic bytes
+	if err := os.Rename(utils.CompressedPath("items.bin.zstd.compressed"), utils.CompressedPath("items.bin.compressed")); err != nil {
+		t.Fatalf("Failed to rename: %v", err)
+	}
+	result, err = app.DecompressFile("items.bin.compressed")
+	if err != nil {
+		t.Fatalf("Failed to decompress: %v", err)
+	}
+	if result["outputFile"] != "items.bin" {
+		t.Errorf("Expected items.bin, got %v", result["outputFile"])
+	}
+	if item, err := app.GetItem(0); err != nil || item["name"] != "Burger" {
+		t.Errorf("Expected Burger after decompressing, got %v (err: %v)", item, err)
+	}
+}
+
+func TestGetIndexStructure(t *testing.T) {
+	app := newTestApp(t)
+	for _, name := range []string{"Burger", "Fries", "Soda", "Salad", "Wrap"} {
+		if _, err := app.AddItem(name, 100); err != nil {
+			t.Fatalf("Failed to add item: %v", err)
+		}
+	}
+
+	result, err := app.GetIndexStructure("items")
+	if err != nil {
+		t.Fatalf("Failed to get the item index structure: %v", err)
+	}
+	tree, ok := result.(index.TreeStructure)
+	if !ok || tree.KeyCount != 5 || tree.Depth < 2 {
+		t.Errorf("Expected a tree of 5 keys over several levels, got %+v", result)
+	}
+
+	result, err = app.GetIndexStructure("order_promotions")
+	if err != nil {
+		t.Fatalf("Failed to get the order_promotions index structure: %v", err)
+	}
+	if hash, ok := result.(index.HashStructure); !ok || hash.EntryCount != 0 || len(hash.Buckets) == 0 {
+		t.Errorf("Expected an empty hash, got %+v", result)
+	}
+
+	if _, err := app.GetIndexStructure("customers"); utils.ErrorCodeOf(err) != utils.CodeValidation {
+		t.Errorf("Expected an unknown index to be rejected, got %v", err)
+	}
+}
+
+func TestApplyPromotionsToOrder(t *testing.T) {
+	app := newTestApp(t)
+	itemID, err := app.AddItem("Burger", 899)
+	if err != nil {
+		t.Fatalf("Failed to add item: %v", err)
+	}
+	orderID, err := app.CreateOrder("Customer", []uint64{itemID})
+	if err != nil {
+		t.Fatalf("Failed to create order: %v", err)
+	}
+	var promotionIDs []uint64
+	for _, name := range []string{"Combo", "Lunch", "Weekend"} {
+		id, err := app.CreatePromotion(name, []uint64{itemID})
+		if err != nil {
+			t.Fatalf("Failed to create promotion: %v", err)
+		}
+		promotionIDs = append(promotionIDs, id)
+	}
+	if err := app.ApplyPromotionToOrder(orderID, promotionIDs[0]); err != nil {
+		t.Fatalf("Failed to apply promotion: %v", err)
+	}
+
+	// A missing promotion applies none of the batch
+	if _, err := app.ApplyPromotionsToOrder(orderID, []uint64{promotionIDs[1], 999}); err == nil {
+		t.Error("Expected a missing promotion to fail the batch")
+	}
+	if promotions, _ := app.GetOrderPromotions(orderID); len(promotions) != 1 {
+		t.Errorf("Expected the failed batch to apply nothing, got %v", promotions)
+	}
+
+	applied, err := app.ApplyPromotionsToOrder(orderID, promotionIDs)
+	if err != nil {
+		t.Fatalf("Failed to apply promotions: %v", err)
+	}
+	if len(applied) != 2 || applied[0] != promotionIDs[1] || applied[1] != promotionIDs[2] {
+		t.Errorf("Expected the 2 new promotions applied, got %v", applied)
+	}
+	if promotions, _ := app.GetOrderPromotions(orderID); len(promotions) != 3 {
+		t.Errorf("Expected 3 promotions on the order, got %v", promotions)
+	}
+}
+
+func TestDuplicateOrder(t *testing.T) {
+	app := newTestApp(t)
+	burgerID, err := app.AddItem("Burger", 899)
+	if err != nil {
+		t.Fatalf("Failed to add item: %v", err)
+	}
+	friesID, err := app.AddItem("Fries", 299)
+	if err != nil {
+		t.Fatalf("Failed to add item: %v", err)
+	}
+	orderID, err := app.CreateOrder("Customer", []uint64{burgerID, friesID, burgerID})
+	if err != nil {
+		t.Fatalf("Failed to create order: %v", err)
+	}
+
+	// The copy uses current prices and leaves deleted items out
+	if _, err := app.UpdateItem(burgerID, "Burger", 999); err != nil {
+		t.Fatalf("Failed to update item: %v", err)
+	}
+	if err := app.DeleteItem(friesID); err != nil {
+		t.Fatalf("Failed to delete item: %v", err)
+	}
+	copyID, err := app.DuplicateOrder(orderID)
+	if err != nil {
+		t.Fatalf("Failed to duplicate order: %v", err)
+	}
+	if copyID == orderID {
+		t.Fatalf("Expected a new order, got #%d", copyID)
+	}
+	duplicate, err := app.GetOrder(copyID)
+	if err != nil {
+		t.Fatalf("Failed to read order: %v", err)
+	}
+	if duplicate["customerName"] != "Customer" || duplicate["totalPrice"] != uint64(1998) {
+		t.Errorf("Expected 2 burgers at the new price for Customer, got %v", duplicate)
+	}
+
+	if err := app.DeleteItem(burgerID); err != nil {
+		t.Fatalf("Failed to delete item: %v", err)
+	}
+	if _, err := app.DuplicateOrder(orderID); utils.ErrorCodeOf(err) != utils.CodeValidation {
+		t.Errorf("Expected an order with no items left to be rejected, got %v", err)
+	}
+}
+
+func TestOrderPromotionLinkRecordsDiscountWhenApplied(t *testing.T) {
+	app := newTestApp(t)
+	config := app.GetConfig()
+	config.AutoCompact = false
+	if _, err := app.UpdateConfig(config); err != nil {
+		t.Fatalf("Failed to disable automatic compaction: %v", err)
+	}
+
+	itemID, err := app.AddItem("Burger", 1000)
+	if err != nil {
+		t.Fatalf("Failed to add item: %v", err)
+	}
+	orderID, err := app.CreateOrder("Customer", []uint64{itemID})
+	if err != nil {
+		t.Fatalf("Failed to create order: %v", err)
+	}
+	promotionID, err := app.CreatePromotion("Combo", []uint64{itemID})
+	if err != nil {
+		t.Fatalf("Failed to create promotion: %v", err)
+	}
+	if err := app.SetPromotionDiscount(promotionID, "percent", 10); err != nil {
+		t.Fatalf("Failed to set discount: %v", err)
+	}
+	if err := app.ApplyPromotionToOrder(orderID, promotionID); err != nil {
+		t.Fatalf("Failed to apply promotion: %v", err)
+	}
+
+	// The snapshot keeps the discount granted when the promotion was applied
+	if err := app.SetPromotionDiscount(promotionID, "percent", 50); err != nil {
+		t.Fatalf("Failed to set discount: %v", err)
+	}
+	// A removed link left behind makes compaction rewrite order_promotions.bin
+	otherOrder, err := app.CreateOrder("Other", []uint64{itemID})
+	if err != nil {
+		t.Fatalf("Failed to create order: %v", err)
+	}
+	if err := app.ApplyPromotionToOrder(otherOrder, promotionID); err != nil {
+		t.Fatalf("Failed to apply promotion: %v", err)
+	}
+	if err := app.RemovePromotionFromOrder(otherOrder, promotionID); err != nil {
+		t.Fatalf("Failed to remove promotion: %v", err)
+	}
+	if _, err := app.Compact(); err != nil {
+		t.Fatalf("Failed to compact: %v", err)
+	}
+
+	promotions, err := app.GetOrderPromotions(orderID)
+	if err != nil || len(promotions) != 1 {
+		t.Fatalf("Expected 1 promotion, got %v (err %v)", promotions, err)
+	}
+	if promotions[0]["appliedDiscount"] != uint64(100) {
+		t.Errorf("Expected the 10%% discount of 100 cents recorded, got %v", promotions[0]["appliedDiscount"])
+	}
+	if _, ok := promotions[0]["appliedAt"].(string); !ok {
+		t.Errorf("Expected the time the promotion was applied, got %v", promotions[0]["appliedAt"])
+	}
+}
+
+func TestGetPromotionUsageStats(t *testing.T) {
+	app := newTestApp(t)
+	itemID, err := app.AddItem("Burger", 1000)
+	if err != nil {
+		t.Fatalf("Failed to add item: %v", err)
+	}
+	used, err := app.CreatePromotion("Combo", []uint64{itemID})
+	if err != nil {
+		t.Fatalf("Failed to create promotion: %v", err)
+	}
+	if err := app.SetPromotionDiscount(used, "fixed", 150); err != nil {
+		t.Fatalf("Failed to set discount: %v", err)
+	}
+	unused, err := app.CreatePromotion("Weekend", []uint64{itemID})
+	if err != nil {
+		t.Fatalf("Failed to create promotion: %v", err)
+	}
+	for _, customer := range []string{"Ana", "Bruno", "Carla"} {
+		orderID, err := app.CreateOrder(customer, []uint64{itemID})
+		if err != nil {
+			t.Fatalf("Failed to create order: %v", err)
+		}
+		if err := app.ApplyPromotionToOrder(orderID, used); err != nil {
+			t.Fatalf("Failed to apply promotion: %v", err)
+		}
+		// Cancelled orders are not counted
+		if customer == "Carla" {
+			if _, err := app.SetOrderStatus(orderID, "cancelled"); err != nil {
+				t.Fatalf("Failed to cancel order: %v", err)
+			}
+		}
+	}
+
+	stats, err := app.GetPromotionUsageStats()
+	if err != nil {
+		t.Fatalf("Failed to get usage stats: %v", err)
+	}
+	if len(stats) != 2 || stats[0]["id"] != unused || stats[1]["id"] != used {
+		t.Fatalf("Expected the unused promotion first, got %v", stats)
+	}
+	if stats[0]["orderCount"] != uint64(0) {
+		t.Errorf("Expected the unused promotion on no orders, got %v", stats[0])
+	}
+	if stats[1]["name"] != "Combo" || stats[1]["orderCount"] != uint64(2) || stats[1]["orderTotal"] != uint64(2000) || stats[1]["discountTotal"] != uint64(300) {
+		t.Errorf("Expected 2 orders worth 2000 cents with 300 cents off, got %v", stats[1])
+	}
+}
+
+// writeSeedFiles writes the seed files PopulateInventory reads into the seed directory
+func writeSeedFiles(t *testing.T, files map[string]string) {
+	t.Helper()
+	if err := os.MkdirAll(utils.SeedDir, 0755); err != nil {
+		t.Fatalf("Failed to create seed directory: %v", err)
+	}
+	for name, content := range files {
+		if err := os.WriteFile(utils.SeedPath(name), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+}
+
+func TestPopulateInventoryReportsMissingReferences(t *testing.T) {
+	seedFiles := map[string]string{
+		"items.json":      `[{"name": "Burger", "priceInCents": 899}, {"name": "Fries", "priceInCents": 299}]`,
+		"promotions.json": `[{"name": "Combo", "itemIDs": [0, 42]}]`,
+		"orders.json":     `[{"owner": "Ana", "itemIDs": [0, 1]}, {"owner": "Bruno", "itemIDs": [1, 99, 98]}]`,
+	}
+
+	// Strict mode stops once the items are written, before any promotion or order
+	app := newTestApp(t)
+	writeSeedFiles(t, seedFiles)
+	result, err := app.PopulateInventory(0, true)
+	if utils.ErrorCodeOf(err) != utils.CodeValidation {
+		t.Fatalf("Expected strict mode to fail with Validation, got %v", err)
+	}
+	if result == nil || result.Items != 2 || len(result.MissingReferences) != 2 {
+		t.Fatalf("Expected 2 items written and 2 records reported, got %+v", result)
+	}
+	if orders, _ := app.GetAllOrders("", ListOptions{}); len(orders) != 0 {
+		t.Errorf("Expected no order written in strict mode, got %d", len(orders))
+	}
+
+	app = newTestApp(t)
+	writeSeedFiles(t, seedFiles)
+	result, err = app.PopulateInventory(0, false)
+	if err != nil {
+		t.Fatalf("Failed to populate inventory: %v", err)
+	}
+	if result.Orders != 2 || result.Promotions != 1 {
+		t.Errorf("Expected 2 orders and 1 promotion written, got %+v", result)
+	}
+	if len(result.MissingReferences) != 2 {
+		t.Fatalf("Expected 2 records reported, got %+v", result.MissingReferences)
+	}
+	promotion, order := result.MissingReferences[0], result.MissingReferences[1]
+	if promotion.Entity != "promotion" || promotion.Index != 1 || len(promotion.ItemIDs) != 1 || promotion.ItemIDs[0] != 42 {
+		t.Errorf("Expected promotion 1 to miss item 42, got %+v", promotion)
+	}
+	if order.Entity != "order" || order.Index != 2 || order.Name != "Bruno" || len(order.ItemIDs) != 2 {
+		t.Errorf("Expected order 2 to miss items 99 and 98, got %+v", order)
+	}
+}
+
+func TestNamesAreSanitizedBeforeValidation(t *testing.T) {
+	app := newTestApp(t)
+
+	itemID, err := app.AddItem("  Café   Latte ", 450)
+	if err != nil {
+		t.Fatalf("Failed to add item: %v", err)
+	}
+	item, err := app.itemDAO.ReadItem(itemID)
+	if err != nil {
+		t.Fatalf("Failed to read item: %v", err)
+	}
+	if item.Name != "Caf\u00e9 Latte" {
+		t.Errorf("Expected the stored name to be sanitized, got %q", item.Name)
+	}
+	if _, err := app.AddItem(" \t ", 450); utils.ErrorCodeOf(err) != utils.CodeValidation {
+		t.Errorf("Expected a blank name to be rejected, got %v", err)
+	}
+
+	if _, err := app.CreateOrder(" Alice \n Smith", []uint64{itemID}); err != nil {
+		t.Fatalf("Failed to create order: %v", err)
+	}
+	orders, err := app.GetOrdersByCustomerName("Alice Smith ")
+	if err != nil {
+		t.Fatalf("Failed to find orders: %v", err)
+	}
+	if len(orders) != 1 || orders[0]["customer"] != "Alice Smith" {
+		t.Errorf("Expected the order under the sanitized name, got %v", orders)
+	}
+}
+
+func TestFormatAndParsePrice(t *testing.T) {
+	app := newTestApp(t)
+
+	formatted, err := app.FormatPrice(123456, "pt-BR")
+	if err != nil {
+		t.Fatalf("Failed to format price: %v", err)
+	}
+	if formatted != "$ 1.234,56" {
+		t.Errorf("Expected $ 1.234,56, got %q", formatted)
+	}
+	if _, err := app.FormatPrice(100, "xx-XX"); utils.ErrorCodeOf(err) != utils.CodeValidation {
+		t.Errorf("Expected an unknown locale to be a Validation error, got %v", err)
+	}
+
+	cents, err := app.ParsePrice("$ 1.234,565")
+	if err != nil {
+		t.Fatalf("Failed to parse price: %v", err)
+	}
+	if cents != 123456 {
+		t.Errorf("Expected 123456 cents, got %d", cents)
+	}
+	if _, err := app.ParsePrice("50,000,000.00"); utils.ErrorCodeOf(err) != utils.CodeValidation {
+		t.Errorf("Expected a price over the maximum to be rejected, got %v", err)
+	}
+}
+
+func TestGetFileStats(t *testing.T) {
+	app := newTestApp(t)
+	config := app.GetConfig()
+	config.AutoCompact = false
+	if _, err := app.UpdateConfig(config); err != nil {
+		t.Fatalf("Failed to update config: %v", err)
+	}
+
+	first, err := app.AddItem("Burger", 899)
+	if err != nil {
+		t.Fatalf("Failed to add item: %v", err)
+	}
+	if _, err := app.AddItem("Fries", 399); err != nil {
+		t.Fatalf("Failed to add item: %v", err)
+	}
+	if err := app.DeleteItem(first); err != nil {
+		t.Fatalf("Failed to delete item: %v", err)
+	}
+
+	stats, err := app.GetFileStats()
+	if err != nil {
+		t.Fatalf("Failed to get file stats: %v", err)
+	}
+	var items map[string]any
+	for _, file := range stats {
+		if file["file"] == "items.bin" {
+			items = file
+		}
+	}
+	if items == nil {
+		t.Fatalf("Expected items.bin in %v", stats)
+	}
+	if items["entitiesCount"] != 2 || items["tombstoneCount"] != 1 || items["activeCount"] != 1 || items["nextId"] != 2 {
+		t.Errorf("Unexpected items.bin counts: %v", items)
+	}
+	if items["sizeBytes"].(int64) <= int64(items["headerBytes"].(int)) {
+		t.Errorf("Expected records past the header: %v", items)
+	}
+}
+
+func TestGetAllItemsListOptions(t *testing.T) {
+	app := newTestApp(t)
+	for _, item := range []struct {
+		name  string
+		price uint64
+	}{{"Soda", 300}, {"burger", 900}, {"Fries", 300}, {"Apple Pie", 450}} {
+		if _, err := app.AddItem(item.name, item.price); err != nil {
+			t.Fatalf("Failed to add item: %v", err)
+		}
+	}
+	if err := app.DeleteItem(3); err != nil {
+		t.Fatalf("Failed to delete item: %v", err)
+	}
+
+	ids := func(opts ListOptions) []uint64 {
+		t.Helper()
+		items, err := app.GetAllItems(opts)
+		if err != nil {
+			t.Fatalf("Failed to get items with %+v: %v", opts, err)
+		}
+		result := make([]uint64, len(items))
+		for i, item := range items {
+			result[i] = item["id"].(uint64)
+		}
+		return result
+	}
+
+	for _, tc := range []struct {
+		opts ListOptions
+		want []uint64
+	}{
+		{ListOptions{}, []uint64{0, 1, 2, 3}},
+		{ListOptions{ActiveOnly: true}, []uint64{0, 1, 2}},
+		{ListOptions{SortBy: "name"}, []uint64{3, 1, 2, 0}},
+		{ListOptions{ActiveOnly: true, SortBy: "name", Direction: "desc"}, []uint64{0, 2, 1}},
+		// Equal prices keep ascending IDs in both directions
+		{ListOptions{SortBy: "price"}, []uint64{0, 2, 3, 1}},
+		{ListOptions{SortBy: "price", Direction: "desc"}, []uint64{1, 3, 0, 2}},
+		{ListOptions{SortBy: "id", Direction: "desc"}, []uint64{3, 2, 1, 0}},
+		// Active records in ID order are read through the index
+		{ListOptions{ActiveOnly: true, SortBy: "id"}, []uint64{0, 1, 2}},
+		{ListOptions{ActiveOnly: true, SortBy: "id", Direction: "desc"}, []uint64{2, 1, 0}},
+	} {
+		if got := ids(tc.opts); fmt.Sprint(got) != fmt.Sprint(tc.want) {
+			t.Errorf("Expected %v with %+v, got %v", tc.want, tc.opts, got)
+		}
+	}
+
+	if _, err := app.GetAllItems(ListOptions{SortBy: "stock"}); utils.ErrorCodeOf(err) != utils.CodeValidation {
+		t.Errorf("Expected a validation error for an unknown sortBy, got %v", err)
+	}
+	if _, err := app.GetAllOrders("", ListOptions{Direction: "up"}); utils.ErrorCodeOf(err) != utils.CodeValidation {
+		t.Errorf("Expected a validation error for an unknown direction, got %v", err)
+	}
+}
+
+func TestCountRecordsFromHeader(t *testing.T) {
+	app := newTestApp(t)
+	config := app.GetConfig()
+	config.AutoCompact = false
+	if _, err := app.UpdateConfig(config); err != nil {
+		t.Fatalf("Failed to update config: %v", err)
+	}
+
+	for _, name := range []string{"Burger", "Fries", "Soda"} {
+		if _, err := app.AddItem(name, 299); err != nil {
+			t.Fatalf("Failed to add item: %v", err)
+		}
+	}
+	if err := app.DeleteItem(1); err != nil {
+		t.Fatalf("Failed to delete item: %v", err)
+	}
+	if _, err := app.CreateOrder("Alice", []uint64{0, 2}); err != nil {
+		t.Fatalf("Failed to create order: %v", err)
+	}
+
+	if count, err := app.CountItems(true); err != nil || count != 2 {
+		t.Errorf("Expected 2 active items, got %d (err: %v)", count, err)
+	}
+	if count, err := app.CountItems(false); err != nil || count != 3 {
+		t.Errorf("Expected 3 item records, got %d (err: %v)", count, err)
+	}
+	if count, err := app.CountOrders(true); err != nil || count != 1 {
+		t.Errorf("Expected 1 order, got %d (err: %v)", count, err)
+	}
+	if count, err := app.CountPromotions(true); err != nil || count != 0 {
+		t.Errorf("Expected no promotions, got %d (err: %v)", count, err)
+	}
+
+	// A header that lost a tombstone disagrees with the index, so the records are counted instead
+	file, err := os.OpenFile(utils.BinPath("items.bin"), os.O_RDWR, 0644)
+	if err != nil {
+		t.Fatalf("Failed to open item file: %v", err)
+	}
+	err = utils.ModifyHeader(file, func(counts *utils.HeaderCounts) { counts.TombstoneCount = 0 })
+	file.Close()
+	if err != nil {
+		t.Fatalf("Failed to modify header: %v", err)
+	}
+	if count, err := app.CountItems(true); err != nil || count != 2 {
+		t.Errorf("Expected 2 active items from a scan, got %d (err: %v)", count, err)
+	}
+}
+
+func TestGetStorageReport(t *testing.T) {
+	app := newTestApp(t)
+	config := app.GetConfig()
+	config.AutoCompact = false
+	if _, err := app.UpdateConfig(config); err != nil {
+		t.Fatalf("Failed to update config: %v", err)
+	}
+
+	first, err := app.AddItem("Burger", 899)
+	if err != nil {
+		t.Fatalf("Failed to add item: %v", err)
+	}
+	if _, err := app.AddItem("Fries", 399); err != nil {
+		t.Fatalf("Failed to add item: %v", err)
+	}
+	if err := app.DeleteItem(first); err != nil {
+		t.Fatalf("Failed to delete item: %v", err)
+	}
+
+	report, err := app.GetStorageReport()
+	if err != nil {
+		t.Fatalf("Failed to get storage report: %v", err)
+	}
+	if bin := report["bin"].(utils.DirUsage); bin.Files == 0 || bin.Bytes == 0 {
+		t.Errorf("Expected the data files in the bin usage, got %+v", bin)
+	}
+	if trash := report["trash"].(utils.DirUsage); trash.Bytes != 0 {
+		t.Errorf("Expected an empty trash, got %+v", trash)
+	}
+
+	var items map[string]any
+	for _, file := range report["files"].([]map[string]any) {
+		if file["file"] == "items.bin" {
+			items = file
+		}
+	}
+	if items == nil {
+		t.Fatalf("Expected items.bin in %v", report["files"])
+	}
+	live, tombstoned := items["liveBytes"].(int64), items["tombstoneBytes"].(int64)
+	if live == 0 || tombstoned == 0 {
+		t.Errorf("Expected live and tombstoned bytes in items.bin, got %v", items)
+	}
+	if int64(items["headerBytes"].(int))+live+tombstoned != items["sizeBytes"].(int64) {
+		t.Errorf("Expected the header and records to add up to the file size, got %v", items)
+	}
+	if items["records"] != 2 || items["activeRecords"] != 1 {
+		t.Errorf("Expected 2 records with 1 active in items.bin, got %v", items)
+	}
+	scanned, err := utils.ReadRecordBytes(utils.BinPath("items.bin"), utils.IDKeyFields)
+	if err != nil {
+		t.Fatalf("Failed to scan items.bin: %v", err)
+	}
+	if scanned.Live != live || scanned.Tombstoned != tombstoned {
+		t.Errorf("Expected the record bytes of a scan %+v, got %v", scanned, items)
+	}
+}
+
+func TestAddAndRemoveOrderItems(t *testing.T) {
+	app := newTestApp(t)
+	burger, err := app.AddItem("Burger", 899)
+	if err != nil {
+		t.Fatalf("Failed to add item: %v", err)
+	}
+	fries, err := app.AddItem("Fries", 349)
+	if err != nil {
+		t.Fatalf("Failed to add item: %v", err)
+	}
+	orderID, err := app.CreateOrder("Alice", []uint64{burger})
+	if err != nil {
+		t.Fatalf("Failed to create order: %v", err)
+	}
+
+	order, err := app.AddItemToOrder(orderID, fries)
+	if err != nil {
+		t.Fatalf("Failed to add item to order: %v", err)
+	}
+	if fmt.Sprint(order["itemIDs"]) != fmt.Sprint([]uint64{burger, fries}) || order["totalPrice"] != uint64(1248) {
+		t.Errorf("Expected both items for 1248 cents, got %v for %v", order["itemIDs"], order["totalPrice"])
+	}
+	if order, err = app.AddItemToOrder(orderID, fries); err != nil || order["totalPrice"] != uint64(1597) {
+		t.Errorf("Expected a second Fries to count twice, got %v (err %v)", order["totalPrice"], err)
+	}
+
+	order, err = app.RemoveItemFromOrder(orderID, fries)
+	if err != nil {
+		t.Fatalf("Failed to remove item from order: %v", err)
+	}
+	if fmt.Sprint(order["itemIDs"]) != fmt.Sprint([]uint64{burger, fries}) || order["totalPrice"] != uint64(1248) {
+		t.Errorf("Expected one Fries removed, got %v for %v", order["itemIDs"], order["totalPrice"])
+	}
+	stored, err := app.orderDAO.Read(orderID)
+	if err != nil || stored.TotalPrice != 1248 {
+		t.Errorf("Expected the recomputed total to be stored, got %+v (err %v)", stored, err)
+	}
+
+	if _, err := app.AddItemToOrder(orderID, 99); err == nil {
+		t.Error("Expected an unknown item to be rejected")
+	}
+	if _, err := app.RemoveItemFromOrder(orderID, 99); err == nil {
+		t.Error("Expected removing an item the order doesn't hold to fail")
+	}
+	if order, _ := app.GetOrder(orderID); order["totalPrice"] != uint64(1248) {
+		t.Errorf("Expected failed changes to leave the order alone, got %v", order["totalPrice"])
+	}
+
+	if err := app.DeleteOrder(orderID); err != nil {
+		t.Fatalf("Failed to delete order: %v", err)
+	}
+	if _, err := app.AddItemToOrder(orderID, fries); utils.ErrorCodeOf(err) != utils.CodeDeleted {
+		t.Errorf("Expected adding to a deleted order to fail as deleted, got %v", err)
+	}
+	if _, err := app.RemoveItemFromOrder(orderID, burger); utils.ErrorCodeOf(err) != utils.CodeDeleted {
+		t.Errorf("Expected removing from a deleted order to fail as deleted, got %v", err)
+	}
+}
+
+func TestReplayTo(t *testing.T) {
+	app := newTestApp(t)
+	burger, err := app.AddItem("Burger", 899)
+	if err != nil {
+		t.Fatalf("Failed to add item: %v", err)
+	}
+	if _, err := app.CreateOrder("Alice", []uint64{burger}); err != nil {
+		t.Fatalf("Failed to create order: %v", err)
+	}
+	cutoff := time.Now().UTC()
+	time.Sleep(10 * time.Millisecond)
+	if _, err := app.AddItem("Fries", 349); err != nil {
+		t.Fatalf("Failed to add item: %v", err)
+	}
+	if err := app.DeleteItem(burger); err != nil {
+		t.Fatalf("Failed to delete item: %v", err)
+	}
+
+	// Only the operations up to the cutoff are replayed, into a directory of their own
+	result, err := app.ReplayTo(cutoff.Format(time.RFC3339Nano))
+	if err != nil {
+		t.Fatalf("Failed to replay: %v", err)
+	}
+	if result["applied"] != 2 || result["skipped"] != 2 || result["failed"] != 0 {
+		t.Fatalf("Expected 2 applied and 2 skipped operations, got %v", result)
+	}
+	dir := result["dir"].(string)
+	if filepath.Dir(dir) != utils.ReplayDir {
+		t.Errorf("Expected the replay under %s, got %s", utils.ReplayDir, dir)
+	}
+	items, err := utils.SplitFileIntoEntries(filepath.Join(dir, "items.bin"))
+	if err != nil || len(items) != 1 {
+		t.Fatalf("Expected one replayed item, got %d (err %v)", len(items), err)
+	}
+	if item, _ := utils.ParseItemEntry(items[0].Data); item.Name != "Burger" || item.Tombstone != 0 {
+		t.Errorf("Expected Burger still active at the cutoff, got %+v", item)
+	}
+	if orders, err := utils.SplitFileIntoEntries(filepath.Join(dir, "orders.bin")); err != nil || len(orders) != 1 {
+		t.Errorf("Expected the order replayed, got %d (err %v)", len(orders), err)
+	}
+
+	// The live data is left as it is
+	if count, _ := app.CountItems(true); count != 1 {
+		t.Errorf("Expected the live database untouched, got %d active items", count)
+	}
+
+	if _, err := app.ReplayTo("yesterday"); err == nil {
+		t.Error("Expected a timestamp that isn't RFC3339 to be rejected")
+	}
+}
+
+func TestGetLogsForEntity(t *testing.T) {
+	app := newTestApp(t)
+	burger, err := app.AddItem("Burger", 899)
+	if err != nil {
+		t.Fatalf("Failed to add item: %v", err)
+	}
+	fries, err := app.AddItem("Fries", 349)
+	if err != nil {
+		t.Fatalf("Failed to add item: %v", err)
+	}
+	if _, err := app.GetItem(burger); err != nil {
+		t.Fatalf("Failed to get item: %v", err)
+	}
+	if _, err := app.UpdateItem(burger, "Cheeseburger", 999); err != nil {
+		t.Fatalf("Failed to update item: %v", err)
+	}
+	orderID, err := app.CreateOrder("Alice", []uint64{burger})
+	if err != nil {
+		t.Fatalf("Failed to create order: %v", err)
+	}
+
+	operations := func(entries []LogEntry) string {
+		result := make([]string, len(entries))
+		for i, entry := range entries {
+			result[i] = entry.Operation
+		}
+		return fmt.Sprint(result)
+	}
+
+	// Only the entries about the record itself, oldest first; the order of the same ID is another entity
+	// UpdateItem reads the item back for its result
+	logs := app.GetLogsForEntity("item", burger)
+	if got := operations(logs); got != "[create read update read]" {
+		t.Fatalf("Expected create, read, update and read of item %d, got %v", burger, got)
+	}
+	for _, entry := range logs {
+		if entry.Entity != "item" || entry.ID == nil || *entry.ID != burger || entry.DurationMs <= 0 {
+			t.Errorf("Expected a timed entry about item %d, got %+v", burger, entry)
+		}
+	}
+	if got := operations(app.GetLogsForEntity("Item", fries)); got != "[create]" {
+		t.Errorf("Expected the entity name to be case-insensitive, got %v", got)
+	}
+	if got := operations(app.GetLogsForEntity("order", orderID)); got != "[create]" {
+		t.Errorf("Expected only the order's own entries, got %v", got)
+	}
+	if logs := app.GetLogsForEntity("item", 42); len(logs) != 0 {
+		t.Errorf("Expected no entries for an unknown item, got %v", logs)
+	}
+}
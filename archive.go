@@ -0,0 +1,356 @@
+package main
+
+import (
+	"BinaryCRUD/backend/compression"
+	"BinaryCRUD/backend/crypto"
+	"BinaryCRUD/backend/events"
+	"BinaryCRUD/backend/utils"
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// archiveReader streams the all_files archive of the given bin files without reading them into memory
+// Format: [fileCount(4)][file1NameLen(2)][file1Name][file1Size(4)][file1Data]...
+// Each file is read up to its size when opened, so a concurrent append cannot corrupt the archive
+// track wraps the reader of each file's data, to count the bytes archived
+// Returns the archive, the total size of the files and a function closing them
+func archiveReader(binFiles []string, track func(io.Reader) io.Reader) (io.Reader, int64, func(), error) {
+	var files []*os.File
+	closeFiles := func() {
+		for _, file := range files {
+			file.Close()
+		}
+	}
+
+	header := make([]byte, 4)
+	binary.BigEndian.PutUint32(header, uint32(len(binFiles)))
+	readers := []io.Reader{bytes.NewReader(header)}
+
+	var totalSize int64
+	for _, filename := range binFiles {
+		file, err := os.Open(utils.BinPath(filename))
+		if err != nil {
+			closeFiles()
+			return nil, 0, nil, fmt.Errorf("failed to read %s: %w", filename, err)
+		}
+		files = append(files, file)
+
+		info, err := file.Stat()
+		if err != nil {
+			closeFiles()
+			return nil, 0, nil, fmt.Errorf("failed to stat %s: %w", filename, err)
+		}
+		if info.Size() > math.MaxUint32 {
+			closeFiles()
+			return nil, 0, nil, fmt.Errorf("%s is too large to archive (%d bytes)", filename, info.Size())
+		}
+		totalSize += info.Size()
+
+		var entry []byte
+		entry = binary.BigEndian.AppendUint16(entry, uint16(len(filename)))
+		entry = append(entry, filename...)
+		entry = binary.BigEndian.AppendUint32(entry, uint32(info.Size()))
+		readers = append(readers, bytes.NewReader(entry), track(io.LimitReader(file, info.Size())))
+	}
+
+	return io.MultiReader(readers...), totalSize, closeFiles, nil
+}
+
+// writeArchive compresses an archive stream into outputPath
+func writeArchive(compressor compression.Compressor, archive io.Reader, outputPath string) error {
+	output, err := os.OpenFile(outputPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to write compressed file: %w", err)
+	}
+
+	writer := bufio.NewWriter(output)
+	err = compressor.CompressStream(archive, writer)
+	if err == nil {
+		err = writer.Flush()
+	}
+	if closeErr := output.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(outputPath)
+		return fmt.Errorf("compression failed: %w", err)
+	}
+	return nil
+}
+
+// writeEncryptedArchive compresses an archive stream and writes it to outputPath encrypted
+// AES-GCM authenticates the archive as a whole, so the compressed archive is buffered in memory
+func writeEncryptedArchive(compressor compression.Compressor, archive io.Reader, outputPath string) error {
+	var compressed bytes.Buffer
+	if err := compressor.CompressStream(archive, &compressed); err != nil {
+		return fmt.Errorf("compression failed: %w", err)
+	}
+	blob, err := crypto.EncryptBlob(compressed.Bytes())
+	if err != nil {
+		return fmt.Errorf("encryption failed: %w", err)
+	}
+	if err := os.WriteFile(outputPath, blob, 0600); err != nil {
+		return fmt.Errorf("failed to write compressed file: %w", err)
+	}
+	return nil
+}
+
+// openArchive opens the compressed contents of an all_files archive with the decompressor for its algorithm
+// Encrypted archives are decrypted in memory, since AES-GCM authenticates the whole archive
+func openArchive(filename string) (io.Reader, compression.Compressor, func(), error) {
+	if !strings.HasPrefix(filename, "all_files.") {
+		return nil, nil, nil, fmt.Errorf("%s is not an all_files archive", filename)
+	}
+	inputPath := utils.CompressedPath(filename)
+
+	decompressor, err := compression.NewCompressor(utils.DetectCompressionAlgorithm(filename))
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("unknown compression format: %s", filename)
+	}
+
+	encrypted, err := isEncryptedArchive(inputPath)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if encrypted {
+		blob, err := os.ReadFile(inputPath)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to read compressed file: %w", err)
+		}
+		compressedData, err := crypto.DecryptBlob(blob)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("decryption failed: %w", err)
+		}
+		return bytes.NewReader(compressedData), decompressor, func() {}, nil
+	}
+
+	file, err := os.Open(inputPath)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to read compressed file: %w", err)
+	}
+	return file, decompressor, func() { file.Close() }, nil
+}
+
+// archiveVisitor is called for every file of an archive with a reader over its data
+// Data the visitor does not read is skipped
+type archiveVisitor func(name string, size int64, data io.Reader) error
+
+// scanArchive decompresses an archive stream from r and calls visit for every file in it
+// Decompression feeds the parser through a pipe, so neither the archive nor a file is held in memory
+// Returns the number of files and their total size
+func scanArchive(decompressor compression.Compressor, r io.Reader, visit archiveVisitor) (int, int64, error) {
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(decompressor.DecompressStream(r, pw))
+	}()
+	defer pr.Close()
+
+	fileCount, totalSize, err := parseArchive(pr, visit)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	// Read to the end so errors after the last file, like a bad checksum, are reported
+	if _, err := io.Copy(io.Discard, pr); err != nil {
+		return 0, 0, fmt.Errorf("decompression failed: %w", err)
+	}
+	return fileCount, totalSize, nil
+}
+
+// parseArchive reads the files of an all_files archive from r, calling visit for each
+func parseArchive(r io.Reader, visit archiveVisitor) (int, int64, error) {
+	countBytes := make([]byte, 4)
+	if _, err := io.ReadFull(r, countBytes); err != nil {
+		return 0, 0, fmt.Errorf("invalid archive format: too short")
+	}
+	fileCount := binary.BigEndian.Uint32(countBytes)
+
+	// Validate file count to prevent resource exhaustion
+	if err := utils.ValidateArchiveFileCount(fileCount); err != nil {
+		return 0, 0, fmt.Errorf("invalid archive: %w", err)
+	}
+
+	var totalSize int64
+	for i := uint32(0); i < fileCount; i++ {
+		nameLenBytes := make([]byte, 2)
+		if _, err := io.ReadFull(r, nameLenBytes); err != nil {
+			return 0, 0, fmt.Errorf("invalid archive format: truncated at file %d name length", i)
+		}
+		nameLen := binary.BigEndian.Uint16(nameLenBytes)
+
+		// Validate filename length
+		if nameLen == 0 || nameLen > uint16(utils.MaxNameLength) {
+			return 0, 0, fmt.Errorf("invalid archive format: invalid filename length %d at file %d", nameLen, i)
+		}
+
+		nameBytes := make([]byte, nameLen)
+		if _, err := io.ReadFull(r, nameBytes); err != nil {
+			return 0, 0, fmt.Errorf("invalid archive format: truncated at file %d name", i)
+		}
+		name := string(nameBytes)
+		if filepath.Base(name) != name {
+			return 0, 0, fmt.Errorf("invalid archive format: invalid filename %q at file %d", name, i)
+		}
+
+		sizeBytes := make([]byte, 4)
+		if _, err := io.ReadFull(r, sizeBytes); err != nil {
+			return 0, 0, fmt.Errorf("invalid archive format: truncated at file %d size", i)
+		}
+		fileSize := int64(binary.BigEndian.Uint32(sizeBytes))
+
+		// Validate the total size to prevent decompression bomb attacks
+		totalSize += fileSize
+		if err := utils.ValidateStreamedSize(totalSize); err != nil {
+			return 0, 0, fmt.Errorf("decompression security check failed: %w", err)
+		}
+
+		data := &io.LimitedReader{R: r, N: fileSize}
+		if err := visit(name, fileSize, data); err != nil {
+			return 0, 0, fmt.Errorf("file %d: %w", i, err)
+		}
+		if _, err := io.Copy(io.Discard, data); err != nil {
+			return 0, 0, fmt.Errorf("failed to read file %d: %w", i, err)
+		}
+		if data.N > 0 {
+			return 0, 0, fmt.Errorf("invalid archive format: truncated in %s data", name)
+		}
+	}
+
+	return int(fileCount), totalSize, nil
+}
+
+// archiveRestore stages the files restored from an archive as .tmp files in the bin directory
+// They only replace the data files once the whole archive was read, so a corrupt archive changes nothing
+type archiveRestore struct {
+	names []string
+}
+
+// stage copies the data of an archived file to a temp file next to its destination
+func (r *archiveRestore) stage(name string, size int64, data io.Reader) error {
+	if err := os.MkdirAll(utils.BinDir, 0700); err != nil {
+		return fmt.Errorf("failed to create bin directory: %w", err)
+	}
+	file, err := os.OpenFile(utils.BinPath(name)+".tmp", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	r.names = append(r.names, name)
+
+	_, err = io.CopyN(file, data, size)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == io.EOF {
+		return fmt.Errorf("invalid archive format: truncated in %s data", name)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", name, err)
+	}
+	return nil
+}
+
+// commit moves every staged file into place
+func (r *archiveRestore) commit() error {
+	for _, name := range r.names {
+		path := utils.BinPath(name)
+		if err := os.Rename(path+".tmp", path); err != nil {
+			return fmt.Errorf("failed to restore %s: %w", name, err)
+		}
+	}
+	r.names = nil
+	return nil
+}
+
+// discard removes staged files that were not committed
+func (r *archiveRestore) discard() {
+	for _, name := range r.names {
+		os.Remove(utils.BinPath(name) + ".tmp")
+	}
+	r.names = nil
+}
+
+// ListArchiveContents returns the name and size of every file in an all_files archive
+func (a *App) ListArchiveContents(filename string) (_ []map[string]any, err error) {
+	defer a.track("ListArchiveContents", time.Now(), &err)
+	archive, decompressor, closeArchive, err := openArchive(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer closeArchive()
+
+	files := make([]map[string]any, 0)
+	_, _, err = scanArchive(decompressor, archive, func(name string, size int64, data io.Reader) error {
+		files = append(files, map[string]any{
+			"name": name,
+			"size": size,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archive: %w", err)
+	}
+
+	return files, nil
+}
+
+// ExtractFromArchive restores a single file of an all_files archive to the bin directory
+// The archive and the other data files are left untouched; the restored file's index is rebuilt
+func (a *App) ExtractFromArchive(filename string, member string) (_ map[string]any, err error) {
+	defer a.track("ExtractFromArchive", time.Now(), &err)
+	if err := a.checkWritable(); err != nil {
+		return nil, err
+	}
+
+	if a.isCompacting() {
+		return nil, errCompactionRunning
+	}
+
+	archive, decompressor, closeArchive, err := openArchive(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer closeArchive()
+
+	restore := &archiveRestore{}
+	defer restore.discard()
+
+	var restoredSize int64 = -1
+	_, _, err = scanArchive(decompressor, archive, func(name string, size int64, data io.Reader) error {
+		if name != member {
+			return nil
+		}
+		restoredSize = size
+		return restore.stage(name, size, data)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("extraction failed: %w", err)
+	}
+	if restoredSize < 0 {
+		return nil, utils.WithCode(utils.CodeNotFound, fmt.Errorf("%s not found in %s", member, filename), map[string]any{"file": filename, "member": member})
+	}
+	a.closeDAOs()
+	if err := restore.commit(); err != nil {
+		return nil, err
+	}
+
+	// The old index no longer matches the restored file
+	utils.RemoveIndexForBin(member, a.logger.Info)
+	a.reloadDAOs()
+	a.signDataFiles()
+	a.publish(events.Event{Type: events.DataReloaded})
+
+	a.logger.Info(fmt.Sprintf("Extracted %s (%d bytes) from %s", member, restoredSize, filename))
+
+	return map[string]any{
+		"file": member,
+		"size": restoredSize,
+	}, nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"BinaryCRUD/backend/compression"
+	"BinaryCRUD/backend/utils"
+	"os"
+	"testing"
+)
+
+func TestListAndExtractFromArchive(t *testing.T) {
+	app := newTestApp(t)
+	for _, name := range []string{"Burger", "Fries"} {
+		if _, err := app.AddItem(name, 499); err != nil {
+			t.Fatalf("Failed to add item: %v", err)
+		}
+	}
+	if _, err := app.CreateOrder("Alice", []uint64{0, 1}); err != nil {
+		t.Fatalf("Failed to create order: %v", err)
+	}
+	app.waitForCompaction()
+	itemsInfo, err := os.Stat(utils.BinPath("items.bin"))
+	if err != nil {
+		t.Fatalf("Failed to stat items: %v", err)
+	}
+
+	result, err := app.CompressAllFiles(compression.AlgorithmGzip, false)
+	if err != nil {
+		t.Fatalf("CompressAllFiles failed: %v", err)
+	}
+	archive := result["outputFile"].(string)
+
+	// Every data file is listed with its size, without extracting anything
+	files, err := app.ListArchiveContents(archive)
+	if err != nil {
+		t.Fatalf("ListArchiveContents failed: %v", err)
+	}
+	sizes := map[string]any{}
+	for _, file := range files {
+		sizes[file["name"].(string)] = file["size"]
+	}
+	if sizes["items.bin"] != itemsInfo.Size() || sizes["orders.bin"] == nil {
+		t.Errorf("Expected items.bin of %d bytes and orders.bin, got %v", itemsInfo.Size(), files)
+	}
+	if _, err := os.Stat(utils.BinPath("items.bin")); !os.IsNotExist(err) {
+		t.Errorf("Expected listing to leave the bin directory alone, got %v", err)
+	}
+
+	// Extracting one file restores only its records
+	extracted, err := app.ExtractFromArchive(archive, "items.bin")
+	if err != nil {
+		t.Fatalf("ExtractFromArchive failed: %v", err)
+	}
+	if extracted["file"] != "items.bin" || extracted["size"] != itemsInfo.Size() {
+		t.Errorf("Expected items.bin of %d bytes, got %v", itemsInfo.Size(), extracted)
+	}
+	items, err := app.GetAllItems(ListOptions{ActiveOnly: true})
+	if err != nil || len(items) != 2 || items[0]["name"] != "Burger" || items[1]["name"] != "Fries" {
+		t.Errorf("Expected Burger and Fries back, got %v (err %v)", items, err)
+	}
+	if _, err := app.orderDAO.Read(0); err == nil {
+		t.Error("Expected the orders to stay in the archive")
+	}
+	if _, err := os.Stat(utils.CompressedPath(archive)); err != nil {
+		t.Errorf("Expected the archive to be kept, got %v", err)
+	}
+
+	if _, err := app.ExtractFromArchive(archive, "missing.bin"); utils.ErrorCodeOf(err) != utils.CodeNotFound {
+		t.Errorf("Expected a member not in the archive to be not found, got %v", err)
+	}
+	if _, err := app.ListArchiveContents("items.bin.gz"); err == nil {
+		t.Error("Expected a file that isn't an all_files archive to be rejected")
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"BinaryCRUD/backend/dao"
+	"fmt"
+	"os"
+	"os/user"
+	"time"
+)
+
+// currentActor returns the name of the OS user running the application
+func currentActor() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	for _, key := range []string{"USER", "USERNAME"} {
+		if name := os.Getenv(key); name != "" {
+			return name
+		}
+	}
+	return "unknown"
+}
+
+// itemSummary describes an item for the audit trail
+func itemSummary(name string, priceInCents uint64) string {
+	return fmt.Sprintf("%s ($%.2f)", name, float64(priceInCents)/100)
+}
+
+// collectionSummary describes an order, promotion or template for the audit trail
+// The name is left out: it is stored encrypted, and the audit trail is not
+func collectionSummary(totalPrice uint64, itemIDs []uint64) string {
+	return fmt.Sprintf("%d items %v ($%.2f)", len(itemIDs), itemIDs, float64(totalPrice)/100)
+}
+
+// recordAudit appends an entry to the audit trail
+// Failures are logged but never fail the operation itself
+func (a *App) recordAudit(action, entityType string, id uint64, before, after string) {
+	_, err := a.auditDAO.Write(dao.AuditEntry{
+		Timestamp:  time.Now().UTC(),
+		Actor:      a.actor,
+		Action:     action,
+		EntityType: entityType,
+		EntityID:   id,
+		Before:     before,
+		After:      after,
+	})
+	if err != nil {
+		a.logger.Warn(fmt.Sprintf("Failed to record audit entry for %s #%d: %v", entityType, id, err))
+	}
+}
+
+// GetAuditLog returns audit entries for an entity type ("item", "order", "promotion")
+// An empty entityType returns every type, a negative id returns every ID
+func (a *App) GetAuditLog(entityType string, id int) (_ []map[string]any, err error) {
+	defer a.track("GetAuditLog", time.Now(), &err)
+	var entityID *uint64
+	if id >= 0 {
+		value := uint64(id)
+		entityID = &value
+	}
+
+	entries, err := a.auditDAO.Query(entityType, entityID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]map[string]any, len(entries))
+	for i, entry := range entries {
+		result[i] = map[string]any{
+			"id":         entry.ID,
+			"timestamp":  entry.Timestamp.Format(time.RFC3339),
+			"actor":      entry.Actor,
+			"action":     entry.Action,
+			"entityType": entry.EntityType,
+			"entityID":   entry.EntityID,
+			"before":     entry.Before,
+			"after":      entry.After,
+		}
+	}
+
+	a.logger.Info(fmt.Sprintf("Retrieved %d audit entries", len(result)))
+	return result, nil
+}
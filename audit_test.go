@@ -0,0 +1,82 @@
+package main
+
+import (
+	"BinaryCRUD/backend/dao"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestGetAuditLog(t *testing.T) {
+	app := newTestApp(t)
+	burger, err := app.AddItem("Burger", 899)
+	if err != nil {
+		t.Fatalf("Failed to add item: %v", err)
+	}
+	if _, err := app.UpdateItem(burger, "Cheeseburger", 999); err != nil {
+		t.Fatalf("Failed to update item: %v", err)
+	}
+	orderID, err := app.CreateOrder("Alice Secret", []uint64{burger})
+	if err != nil {
+		t.Fatalf("Failed to create order: %v", err)
+	}
+	promotionID, err := app.CreatePromotion("Hidden Combo", []uint64{burger})
+	if err != nil {
+		t.Fatalf("Failed to create promotion: %v", err)
+	}
+	if err := app.ApplyPromotionToOrder(orderID, promotionID); err != nil {
+		t.Fatalf("Failed to apply promotion: %v", err)
+	}
+	if err := app.DeleteOrder(orderID); err != nil {
+		t.Fatalf("Failed to delete order: %v", err)
+	}
+
+	actions := func(entries []map[string]any) string {
+		result := make([]string, len(entries))
+		for i, entry := range entries {
+			result[i] = fmt.Sprintf("%v %v#%v", entry["action"], entry["entityType"], entry["entityID"])
+		}
+		return strings.Join(result, ", ")
+	}
+
+	all, err := app.GetAuditLog("", -1)
+	if err != nil {
+		t.Fatalf("Failed to get audit log: %v", err)
+	}
+	expected := "create item#0, update item#0, create order#0, create promotion#0, update order#0, delete order#0"
+	if got := actions(all); got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+	for _, entry := range all {
+		if entry["actor"] != app.actor {
+			t.Errorf("Expected every entry by %q, got %v", app.actor, entry["actor"])
+		}
+	}
+
+	item, err := app.GetAuditLog("item", int(burger))
+	if err != nil {
+		t.Fatalf("Failed to get item audit log: %v", err)
+	}
+	if len(item) != 2 || item[1]["before"] != "Burger ($8.99)" || item[1]["after"] != "Cheeseburger ($9.99)" {
+		t.Errorf("Expected the item's create and update, got %v", item)
+	}
+	if orders, _ := app.GetAuditLog("order", -1); actions(orders) != "create order#0, update order#0, delete order#0" {
+		t.Errorf("Expected only the order entries, got %q", actions(orders))
+	}
+	if none, _ := app.GetAuditLog("order", 42); len(none) != 0 {
+		t.Errorf("Expected no entries for an unknown order, got %v", none)
+	}
+
+	// Order and promotion names are stored encrypted, so the audit trail never carries them
+	for _, entry := range all {
+		for _, field := range []string{"before", "after"} {
+			summary := entry[field].(string)
+			if strings.Contains(summary, "Alice") || strings.Contains(summary, "Secret") || strings.Contains(summary, "Combo") {
+				t.Errorf("Expected no customer or promotion name in %s of %s, got %q", field, entry["action"], summary)
+			}
+		}
+		if entry["action"] == dao.AuditCreate && entry["entityType"] == "order" && entry["after"] != "1 items [0] ($9.99)" {
+			t.Errorf("Expected the order summarized by its items and total, got %q", entry["after"])
+		}
+	}
+}
@@ -0,0 +1,403 @@
+package backup
+
+import (
+	"BinaryCRUD/backend/utils"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Archive format:
+// [magic "BBAK"(4)][version(1)][flags(1)]
+// unencrypted: [crc32(4)][payload]
+// encrypted:   [salt(16)][nonce(12)][AES-GCM(payload)]
+// payload:     [fileCount(4)][pathLen(2)][path][size(4)][data]...
+// Paths are slash-separated and relative to the data directory, e.g. "bin/items.bin"
+
+// Magic identifies a backup archive
+var Magic = []byte{'B', 'B', 'A', 'K'}
+
+const (
+	// ArchiveVersion is the current backup archive format version
+	ArchiveVersion = 1
+
+	// flagEncrypted marks an archive whose payload is AES-GCM encrypted
+	flagEncrypted = 0x01
+
+	saltSize = 16
+
+	// keyIterations is the number of SHA-256 rounds used to stretch the passphrase
+	keyIterations = 100000
+)
+
+// DefaultDirs are the data subdirectories included in a backup
+var DefaultDirs = []string{"bin", "indexes", "keys", "oplog"}
+
+// ErrWrongPassphrase is returned when an encrypted archive cannot be decrypted
+var ErrWrongPassphrase = errors.New("wrong passphrase or corrupted archive")
+
+// FileEntry describes a file stored in an archive
+type FileEntry struct {
+	Path string
+	Size int
+}
+
+// Manifest describes the contents of an archive
+type Manifest struct {
+	Files     []FileEntry
+	Encrypted bool
+}
+
+// TotalSize returns the sum of all file sizes in the manifest
+func (m *Manifest) TotalSize() int64 {
+	var total int64
+	for _, f := range m.Files {
+		total += int64(f.Size)
+	}
+	return total
+}
+
+// Create writes a backup archive of the given subdirectories of dataDir to outPath
+// An empty passphrase produces an unencrypted archive
+func Create(dataDir string, dirs []string, outPath, passphrase string) (*Manifest, error) {
+	manifest := &Manifest{Encrypted: passphrase != ""}
+
+	var payload bytes.Buffer
+	payload.Write(make([]byte, 4)) // file count, filled in below
+	binDir := filepath.Join(dataDir, "bin")
+
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(filepath.Join(dataDir, dir))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to read %s: %w", dir, err)
+		}
+
+		for _, entry := range entries {
+			// Skip subdirectories, leftover temp files and the files of data file generations kept after a compaction
+			if entry.IsDir() || strings.HasSuffix(entry.Name(), ".tmp") || !utils.IsCurrentGenerationFile(binDir, entry.Name()) {
+				continue
+			}
+
+			relPath := dir + "/" + entry.Name()
+			data, err := os.ReadFile(filepath.Join(dataDir, dir, entry.Name()))
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %s: %w", relPath, err)
+			}
+
+			binary.Write(&payload, binary.BigEndian, uint16(len(relPath)))
+			payload.WriteString(relPath)
+			binary.Write(&payload, binary.BigEndian, uint32(len(data)))
+			payload.Write(data)
+
+			manifest.Files = append(manifest.Files, FileEntry{Path: relPath, Size: len(data)})
+		}
+	}
+
+	if len(manifest.Files) > utils.MaxFileCount {
+		return nil, fmt.Errorf("backup file count %d exceeds maximum of %d", len(manifest.Files), utils.MaxFileCount)
+	}
+
+	payloadBytes := payload.Bytes()
+	binary.BigEndian.PutUint32(payloadBytes[:4], uint32(len(manifest.Files)))
+
+	var output bytes.Buffer
+	output.Write(Magic)
+	output.WriteByte(ArchiveVersion)
+
+	if passphrase == "" {
+		output.WriteByte(0)
+		binary.Write(&output, binary.BigEndian, crc32.ChecksumIEEE(payloadBytes))
+		output.Write(payloadBytes)
+	} else {
+		output.WriteByte(flagEncrypted)
+		sealed, err := seal(payloadBytes, passphrase)
+		if err != nil {
+			return nil, err
+		}
+		output.Write(sealed)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(outPath), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+	if err := os.WriteFile(outPath, output.Bytes(), 0600); err != nil {
+		return nil, fmt.Errorf("failed to write backup: %w", err)
+	}
+
+	return manifest, nil
+}
+
+// Read opens a backup archive, verifies it and returns its manifest and file contents
+func Read(archivePath, passphrase string) (*Manifest, map[string][]byte, error) {
+	data, err := os.ReadFile(archivePath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read backup: %w", err)
+	}
+
+	if len(data) < len(Magic)+2 || !bytes.Equal(data[:len(Magic)], Magic) {
+		return nil, nil, fmt.Errorf("not a backup archive")
+	}
+	if data[len(Magic)] != ArchiveVersion {
+		return nil, nil, fmt.Errorf("unsupported backup version %d", data[len(Magic)])
+	}
+
+	flags := data[len(Magic)+1]
+	body := data[len(Magic)+2:]
+	manifest := &Manifest{Encrypted: flags&flagEncrypted != 0}
+
+	var payload []byte
+	if manifest.Encrypted {
+		if passphrase == "" {
+			return nil, nil, fmt.Errorf("backup is encrypted, a passphrase is required")
+		}
+		payload, err = open(body, passphrase)
+		if err != nil {
+			return nil, nil, err
+		}
+	} else {
+		if len(body) < 4 {
+			return nil, nil, fmt.Errorf("invalid backup: too short")
+		}
+		payload = body[4:]
+		if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(body[:4]) {
+			return nil, nil, fmt.Errorf("invalid backup: checksum mismatch")
+		}
+	}
+
+	files, err := parsePayload(payload, manifest)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid backup: %w", err)
+	}
+
+	return manifest, files, nil
+}
+
+// Restore replaces the given subdirectories of dataDir with the contents of a backup archive
+// Files are first extracted to a staging directory, then each subdirectory is swapped in by
+// rename. If a swap fails, the already swapped directories are rolled back.
+func Restore(archivePath, dataDir string, dirs []string, passphrase string) (*Manifest, error) {
+	manifest, files, err := Read(archivePath, passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	allowed := make(map[string]bool, len(dirs))
+	for _, dir := range dirs {
+		allowed[dir] = true
+	}
+
+	stagingDir := filepath.Join(dataDir, ".restore")
+	if err := os.RemoveAll(stagingDir); err != nil {
+		return nil, fmt.Errorf("failed to clear staging directory: %w", err)
+	}
+	defer os.RemoveAll(stagingDir)
+
+	newDir := filepath.Join(stagingDir, "new")
+	oldDir := filepath.Join(stagingDir, "old")
+
+	// Stage every directory, even empty ones, so restoring replaces stale files
+	for _, dir := range dirs {
+		if err := os.MkdirAll(filepath.Join(newDir, dir), 0700); err != nil {
+			return nil, fmt.Errorf("failed to create staging directory: %w", err)
+		}
+	}
+	if err := os.MkdirAll(oldDir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create staging directory: %w", err)
+	}
+
+	for relPath, data := range files {
+		dir, name := splitArchivePath(relPath)
+		if !allowed[dir] {
+			return nil, fmt.Errorf("backup contains unexpected path %s", relPath)
+		}
+		if err := os.WriteFile(filepath.Join(newDir, dir, name), data, 0600); err != nil {
+			return nil, fmt.Errorf("failed to stage %s: %w", relPath, err)
+		}
+	}
+
+	// Swap the staged directories into place
+	var swapped []string
+	for _, dir := range dirs {
+		livePath := filepath.Join(dataDir, dir)
+		hadLive := true
+		if err := os.Rename(livePath, filepath.Join(oldDir, dir)); err != nil {
+			if !os.IsNotExist(err) {
+				rollback(dataDir, oldDir, swapped)
+				return nil, fmt.Errorf("failed to move aside %s: %w", dir, err)
+			}
+			hadLive = false
+		}
+		if err := os.Rename(filepath.Join(newDir, dir), livePath); err != nil {
+			if hadLive {
+				os.Rename(filepath.Join(oldDir, dir), livePath)
+			}
+			rollback(dataDir, oldDir, swapped)
+			return nil, fmt.Errorf("failed to restore %s: %w", dir, err)
+		}
+		swapped = append(swapped, dir)
+	}
+
+	return manifest, nil
+}
+
+// rollback puts the previous directories back after a failed swap
+func rollback(dataDir, oldDir string, swapped []string) {
+	for _, dir := range swapped {
+		livePath := filepath.Join(dataDir, dir)
+		if _, err := os.Stat(filepath.Join(oldDir, dir)); err != nil {
+			continue
+		}
+		os.RemoveAll(livePath)
+		os.Rename(filepath.Join(oldDir, dir), livePath)
+	}
+}
+
+// parsePayload decodes the file table, validating counts, sizes and paths
+func parsePayload(payload []byte, manifest *Manifest) (map[string][]byte, error) {
+	if len(payload) < 4 {
+		return nil, fmt.Errorf("too short")
+	}
+
+	fileCount := binary.BigEndian.Uint32(payload[:4])
+	if fileCount > utils.MaxFileCount {
+		return nil, fmt.Errorf("file count %d exceeds maximum of %d", fileCount, utils.MaxFileCount)
+	}
+	offset := 4
+
+	files := make(map[string][]byte, fileCount)
+	for i := uint32(0); i < fileCount; i++ {
+		if offset+2 > len(payload) {
+			return nil, fmt.Errorf("truncated at file %d path length", i)
+		}
+		pathLen := int(binary.BigEndian.Uint16(payload[offset : offset+2]))
+		offset += 2
+
+		if pathLen == 0 || offset+pathLen > len(payload) {
+			return nil, fmt.Errorf("invalid path length at file %d", i)
+		}
+		relPath := string(payload[offset : offset+pathLen])
+		offset += pathLen
+
+		if err := validateArchivePath(relPath); err != nil {
+			return nil, err
+		}
+
+		if offset+4 > len(payload) {
+			return nil, fmt.Errorf("truncated at file %d size", i)
+		}
+		size := int(binary.BigEndian.Uint32(payload[offset : offset+4]))
+		offset += 4
+
+		if offset+size > len(payload) {
+			return nil, fmt.Errorf("truncated at file %d data", i)
+		}
+		files[relPath] = payload[offset : offset+size]
+		offset += size
+
+		manifest.Files = append(manifest.Files, FileEntry{Path: relPath, Size: size})
+	}
+
+	sort.Slice(manifest.Files, func(i, j int) bool { return manifest.Files[i].Path < manifest.Files[j].Path })
+	return files, nil
+}
+
+// validateArchivePath rejects paths that could escape the data directory
+func validateArchivePath(relPath string) error {
+	dir, name := splitArchivePath(relPath)
+	if dir == "" || name == "" || strings.Contains(relPath, "\\") ||
+		dir == "." || dir == ".." || name == "." || name == ".." || strings.Contains(name, "/") {
+		return fmt.Errorf("invalid path %q in backup", relPath)
+	}
+	return nil
+}
+
+// splitArchivePath splits "dir/name" into its directory and file name
+func splitArchivePath(relPath string) (string, string) {
+	dir, name, found := strings.Cut(relPath, "/")
+	if !found {
+		return "", relPath
+	}
+	return dir, name
+}
+
+// deriveKey stretches a passphrase into a 32-byte AES key
+func deriveKey(passphrase string, salt []byte) []byte {
+	sum := sha256.Sum256(append(append([]byte{}, salt...), passphrase...))
+	for i := 1; i < keyIterations; i++ {
+		sum = sha256.Sum256(sum[:])
+	}
+	return sum[:]
+}
+
+// seal encrypts a payload with AES-256-GCM, returning [salt][nonce][ciphertext]
+func seal(payload []byte, passphrase string) ([]byte, error) {
+	salt := make([]byte, saltSize)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	gcm, err := newGCM(deriveKey(passphrase, salt))
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	sealed := append(salt, nonce...)
+	return gcm.Seal(sealed, nonce, payload, Magic), nil
+}
+
+// open decrypts data produced by seal
+func open(data []byte, passphrase string) ([]byte, error) {
+	if len(data) < saltSize {
+		return nil, ErrWrongPassphrase
+	}
+	salt := data[:saltSize]
+
+	gcm, err := newGCM(deriveKey(passphrase, salt))
+	if err != nil {
+		return nil, err
+	}
+
+	rest := data[saltSize:]
+	if len(rest) < gcm.NonceSize() {
+		return nil, ErrWrongPassphrase
+	}
+	nonce, ciphertext := rest[:gcm.NonceSize()], rest[gcm.NonceSize():]
+
+	payload, err := gcm.Open(nil, nonce, ciphertext, Magic)
+	if err != nil {
+		return nil, ErrWrongPassphrase
+	}
+	return payload, nil
+}
+
+// newGCM creates an AES-GCM cipher for the given key
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCM: %w", err)
+	}
+	return gcm, nil
+}
@@ -0,0 +1,70 @@
+package compression
+
+// bitWriter packs bits MSB-first into bytes through a 64-bit accumulator
+type bitWriter struct {
+	out   []byte
+	acc   uint64
+	count uint
+}
+
+// writeBits appends the low n bits of code, most significant first (n <= 32)
+func (w *bitWriter) writeBits(code uint32, n uint) {
+	w.acc = w.acc<<n | uint64(code)
+	w.count += n
+	for w.count >= 8 {
+		w.count -= 8
+		w.out = append(w.out, byte(w.acc>>w.count))
+	}
+}
+
+// flush pads the last byte with zero bits and returns the output and the number of padding bits
+func (w *bitWriter) flush() ([]byte, int) {
+	padding := 0
+	if w.count > 0 {
+		padding = int(8 - w.count)
+		w.out = append(w.out, byte(w.acc<<uint(padding)))
+		w.count = 0
+	}
+	return w.out, padding
+}
+
+// bitReader reads bits MSB-first from a byte slice through a 64-bit buffer
+type bitReader struct {
+	data  []byte
+	pos   int
+	buf   uint64
+	count uint
+	left  int // bits that may still be consumed, excluding padding
+}
+
+func newBitReader(data []byte, paddingBits int) *bitReader {
+	return &bitReader{data: data, left: len(data)*8 - paddingBits}
+}
+
+// refill tops the buffer up to at least 57 bits while input remains
+func (r *bitReader) refill() {
+	for r.count <= 56 && r.pos < len(r.data) {
+		r.buf |= uint64(r.data[r.pos]) << (56 - r.count)
+		r.pos++
+		r.count += 8
+	}
+}
+
+// peek returns the next n bits without consuming them, zero-filled past the end (n <= 32)
+func (r *bitReader) peek(n uint) uint32 {
+	if r.count < n {
+		r.refill()
+	}
+	return uint32(r.buf >> (64 - n))
+}
+
+// consume drops n bits, reporting false if that reads into the padding or past the end
+func (r *bitReader) consume(n uint) bool {
+	if int(n) > r.left || n > r.count {
+		return false
+	}
+	r.buf <<= n
+	r.count -= n
+	r.left -= int(n)
+	return true
+}
@@ -1,7 +1,11 @@
 package compression
 
 import (
+	"BinaryCRUD/backend/utils"
+	"bytes"
 	"fmt"
+	"io"
+	"os"
 )
 
 // Compressor is the interface that all compression algorithms implement
@@ -10,14 +14,23 @@ type Compressor interface {
 	Decompress(data []byte) ([]byte, error)
 	CompressFile(inputPath, outputPath string) error
 	DecompressFile(inputPath, outputPath string) error
+	CompressStream(r io.Reader, w io.Writer) error
+	DecompressStream(r io.Reader, w io.Writer) error
 }
 
 // Algorithm constants
 const (
 	AlgorithmHuffman = "huffman"
 	AlgorithmLZW     = "lzw"
+	AlgorithmGzip    = "gzip"
+	AlgorithmZstd    = "zstd"
 )
 
+// Algorithms returns every algorithm NewCompressor knows
+func Algorithms() []string {
+	return []string{AlgorithmHuffman, AlgorithmLZW, AlgorithmGzip, AlgorithmZstd}
+}
+
 // NewCompressor creates a compressor for the given algorithm
 func NewCompressor(algorithm string) (Compressor, error) {
 	switch algorithm {
@@ -25,7 +38,44 @@ func NewCompressor(algorithm string) (Compressor, error) {
 		return NewHuffmanCompressor(), nil
 	case AlgorithmLZW:
 		return NewLZWCompressor(), nil
+	case AlgorithmGzip:
+		return NewGzipCompressor(), nil
+	case AlgorithmZstd:
+		return NewZstdCompressor(), nil
 	default:
 		return nil, fmt.Errorf("unknown compression algorithm: %s", algorithm)
 	}
 }
+
+// DetectAlgorithm identifies the algorithm that produced compressed data from its magic bytes
+// Returns utils.AlgorithmUnknown for data written by none of them
+func DetectAlgorithm(header []byte) string {
+	switch {
+	case bytes.HasPrefix(header, HuffmanMagic), bytes.HasPrefix(header, HuffmanCanonicalMagic), bytes.HasPrefix(header, HuffmanBlockMagic):
+		return AlgorithmHuffman
+	case bytes.HasPrefix(header, LZWMagic), bytes.HasPrefix(header, LZWBlockMagic):
+		return AlgorithmLZW
+	case bytes.HasPrefix(header, GzipMagic):
+		return AlgorithmGzip
+	case bytes.HasPrefix(header, ZstdMagic):
+		return AlgorithmZstd
+	default:
+		return utils.AlgorithmUnknown
+	}
+}
+
+// DetectFileAlgorithm identifies the algorithm that produced a compressed file from its magic bytes
+func DetectFileAlgorithm(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return utils.AlgorithmUnknown, err
+	}
+	defer file.Close()
+
+	header := make([]byte, 4)
+	n, err := io.ReadFull(file, header)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return utils.AlgorithmUnknown, fmt.Errorf("failed to read header: %w", err)
+	}
+	return DetectAlgorithm(header[:n]), nil
+}
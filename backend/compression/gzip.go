@@ -0,0 +1,116 @@
+package compression
+
+import (
+	"BinaryCRUD/backend/utils"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+)
+
+// Magic bytes of the gzip format (RFC 1952), so archives can be opened with standard tools
+var GzipMagic = []byte{0x1f, 0x8b}
+
+// GzipCompressor handles gzip compression and decompression
+type GzipCompressor struct {
+	level int
+}
+
+// NewGzipCompressor creates a new gzip compressor using the best compression level
+func NewGzipCompressor() *GzipCompressor {
+	return &GzipCompressor{level: gzip.BestCompression}
+}
+
+// NewGzipCompressorLevel creates a gzip compressor with a level from gzip.HuffmanOnly (-2) to gzip.BestCompression (9)
+// Lower levels trade compression ratio for speed on large data files
+func NewGzipCompressorLevel(level int) (*GzipCompressor, error) {
+	if level < gzip.HuffmanOnly || level > gzip.BestCompression {
+		return nil, fmt.Errorf("gzip level must be between %d and %d", gzip.HuffmanOnly, gzip.BestCompression)
+	}
+	return &GzipCompressor{level: level}, nil
+}
+
+// Compress compresses data into a gzip stream
+func (gc *GzipCompressor) Compress(data []byte) ([]byte, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("cannot compress empty data")
+	}
+
+	var output bytes.Buffer
+	writer, err := gzip.NewWriterLevel(&output, gc.level)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gzip writer: %w", err)
+	}
+	if _, err := writer.Write(data); err != nil {
+		return nil, fmt.Errorf("failed to compress: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finish gzip stream: %w", err)
+	}
+
+	return output.Bytes(), nil
+}
+
+// Decompress decompresses a gzip stream
+// Output beyond utils.MaxDecompressedSize is rejected before it is read into memory
+func (gc *GzipCompressor) Decompress(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, GzipMagic) {
+		return nil, fmt.Errorf("invalid magic bytes: not gzip data")
+	}
+
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read gzip header: %w", err)
+	}
+	defer reader.Close()
+
+	result, err := io.ReadAll(io.LimitReader(reader, utils.MaxDecompressedSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress: %w", err)
+	}
+	if len(result) > utils.MaxDecompressedSize {
+		return nil, fmt.Errorf("decompressed data exceeds maximum of %d bytes", utils.MaxDecompressedSize)
+	}
+
+	return result, nil
+}
+
+// CompressStream compresses r to w as a single gzip stream
+func (gc *GzipCompressor) CompressStream(r io.Reader, w io.Writer) error {
+	writer, err := gzip.NewWriterLevel(w, gc.level)
+	if err != nil {
+		return fmt.Errorf("failed to create gzip writer: %w", err)
+	}
+	if _, err := io.Copy(writer, r); err != nil {
+		return fmt.Errorf("failed to compress: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("failed to finish gzip stream: %w", err)
+	}
+	return nil
+}
+
+// DecompressStream decompresses a gzip stream from r to w
+// Unlike Decompress the output is not size-limited, since it never sits in memory
+func (gc *GzipCompressor) DecompressStream(r io.Reader, w io.Writer) error {
+	reader, err := gzip.NewReader(r)
+	if err != nil {
+		return fmt.Errorf("failed to read gzip header: %w", err)
+	}
+	defer reader.Close()
+
+	if _, err := io.Copy(w, reader); err != nil {
+		return fmt.Errorf("failed to decompress: %w", err)
+	}
+	return nil
+}
+
+// CompressFile compresses a file and saves it to the output path
+func (gc *GzipCompressor) CompressFile(inputPath, outputPath string) error {
+	return streamFile(inputPath, outputPath, gc.CompressStream)
+}
+
+// DecompressFile decompresses a file and saves it to the output path
+func (gc *GzipCompressor) DecompressFile(inputPath, outputPath string) error {
+	return streamFile(inputPath, outputPath, gc.DecompressStream)
+}
@@ -6,13 +6,19 @@ import (
 	"encoding/binary"
 	"fmt"
 	"io"
-	"os"
-	"path/filepath"
 )
 
 // Magic bytes to identify Huffman compressed files
+// HUFF files store the code tree; Compress writes HUFC, which stores canonical code lengths instead
 var HuffmanMagic = []byte{'H', 'U', 'F', 'F'}
 
+// HuffmanCanonicalMagic identifies Huffman data encoded with canonical codes
+// Format: [HUFC][originalSize(4)][maxLength(1)][countPerLength(2 each)][symbols in code order][paddingBits(1)][bits]
+var HuffmanCanonicalMagic = []byte{'H', 'U', 'F', 'C'}
+
+// HuffmanBlockMagic starts a stream of independently compressed blocks written by CompressStream
+var HuffmanBlockMagic = []byte{'H', 'U', 'F', 'B'}
+
 // HuffmanNode represents a node in the Huffman tree
 type HuffmanNode struct {
 	Byte   byte
@@ -41,16 +47,19 @@ func (h *nodeHeap) Pop() any {
 	return x
 }
 
+// maxCodeLength bounds canonical code lengths so a code always fits the bit reader's buffer
+const maxCodeLength = 24
+
+// lookupBits is the number of bits resolved with a single table lookup when decoding
+const lookupBits = 10
+
 type HuffmanCompressor struct {
-	root    *HuffmanNode
-	codeMap map[byte]string
-	freq    [256]int
+	root *HuffmanNode
+	freq [256]int
 }
 
 func NewHuffmanCompressor() *HuffmanCompressor {
-	return &HuffmanCompressor{
-		codeMap: make(map[byte]string),
-	}
+	return &HuffmanCompressor{}
 }
 
 // buildFrequencyTable counts the frequency of each byte in the data
@@ -120,33 +129,91 @@ func (hc *HuffmanCompressor) buildTree() {
 	hc.root = heap.Pop(h).(*HuffmanNode)
 }
 
-// buildCodeMap generates the bit codes for each byte
-func (hc *HuffmanCompressor) buildCodeMap() {
-	hc.codeMap = make(map[byte]string)
-	if hc.root == nil {
-		return
+// codeLengths returns the depth of every byte in the Huffman tree, 0 for bytes that do not occur
+// When the tree is deeper than maxCodeLength the frequencies are flattened and the tree rebuilt
+func (hc *HuffmanCompressor) codeLengths() [256]uint8 {
+	for {
+		hc.buildTree()
+
+		var lengths [256]uint8
+		maxLength := hc.collectLengths(hc.root, 0, &lengths)
+		if maxLength <= maxCodeLength {
+			return lengths
+		}
+
+		for i := range hc.freq {
+			if hc.freq[i] > 0 {
+				hc.freq[i] = (hc.freq[i] + 1) / 2
+			}
+		}
 	}
-	hc.buildCodesRecursive(hc.root, "")
 }
 
-func (hc *HuffmanCompressor) buildCodesRecursive(node *HuffmanNode, code string) {
+// collectLengths records the depth of every leaf below node and returns the deepest one
+func (hc *HuffmanCompressor) collectLengths(node *HuffmanNode, depth int, lengths *[256]uint8) int {
 	if node == nil {
-		return
+		return 0
 	}
-
 	if node.IsLeaf {
-		if code == "" {
-			code = "0" // Single node case
+		if depth == 0 {
+			depth = 1 // Single node case
+		}
+		if depth <= maxCodeLength {
+			lengths[node.Byte] = uint8(depth)
+		}
+		return depth
+	}
+	return max(hc.collectLengths(node.Left, depth+1, lengths), hc.collectLengths(node.Right, depth+1, lengths))
+}
+
+// canonicalCode describes canonical Huffman codes by the number of codes of each length
+// and the symbols in code order; codes of one length are consecutive and shorter codes sort first
+type canonicalCode struct {
+	counts  [maxCodeLength + 1]uint16
+	symbols []byte
+}
+
+// newCanonicalCode orders the symbols with a code length by (length, symbol)
+func newCanonicalCode(lengths [256]uint8) *canonicalCode {
+	cc := &canonicalCode{}
+	for length := 1; length <= maxCodeLength; length++ {
+		for symbol := 0; symbol < 256; symbol++ {
+			if int(lengths[symbol]) == length {
+				cc.counts[length]++
+				cc.symbols = append(cc.symbols, byte(symbol))
+			}
 		}
-		hc.codeMap[node.Byte] = code
-		return
 	}
+	return cc
+}
 
-	hc.buildCodesRecursive(node.Left, code+"0")
-	hc.buildCodesRecursive(node.Right, code+"1")
+// maxLength returns the longest code length in use
+func (cc *canonicalCode) maxLength() int {
+	for length := maxCodeLength; length > 0; length-- {
+		if cc.counts[length] > 0 {
+			return length
+		}
+	}
+	return 0
 }
 
-// Compress compresses the input data using Huffman coding
+// codes assigns every symbol its canonical code
+func (cc *canonicalCode) codes() (codes [256]uint32, lengths [256]uint8) {
+	code, index := uint32(0), 0
+	for length := 1; length <= maxCodeLength; length++ {
+		for i := 0; i < int(cc.counts[length]); i++ {
+			symbol := cc.symbols[index]
+			codes[symbol] = code
+			lengths[symbol] = uint8(length)
+			code++
+			index++
+		}
+		code <<= 1
+	}
+	return codes, lengths
+}
+
+// Compress compresses the input data using canonical Huffman coding
 func (hc *HuffmanCompressor) Compress(data []byte) ([]byte, error) {
 	if len(data) == 0 {
 		return nil, fmt.Errorf("cannot compress empty data")
@@ -155,100 +222,46 @@ func (hc *HuffmanCompressor) Compress(data []byte) ([]byte, error) {
 	// Step 1: Build frequency table
 	hc.buildFrequencyTable(data)
 
-	// Step 2: Build Huffman tree
-	hc.buildTree()
+	// Step 2: Build Huffman tree and take the code length of every byte from it
+	cc := newCanonicalCode(hc.codeLengths())
 
-	// Step 3: Build code map
-	hc.buildCodeMap()
+	// Step 3: Assign canonical codes, which only depend on the lengths
+	codes, lengths := cc.codes()
 
-	// Step 4: Encode data to bit string
-	var bitString bytes.Buffer
+	// Step 4: Encode data straight into bytes
+	writer := &bitWriter{out: make([]byte, 0, len(data)/2)}
 	for _, b := range data {
-		code, ok := hc.codeMap[b]
-		if !ok {
-			return nil, fmt.Errorf("no code found for byte 0x%02x", b)
-		}
-		bitString.WriteString(code)
+		writer.writeBits(codes[b], uint(lengths[b]))
 	}
+	compressedData, paddingBits := writer.flush()
 
-	// Step 5: Convert bit string to bytes
-	bits := bitString.String()
-	paddingBits := (8 - (len(bits) % 8)) % 8
-
-	// Pad with zeros
-	for i := 0; i < paddingBits; i++ {
-		bits += "0"
-	}
-
-	// Convert to bytes
-	compressedData := make([]byte, len(bits)/8)
-	for i := 0; i < len(bits); i += 8 {
-		var b byte
-		for j := 0; j < 8; j++ {
-			if bits[i+j] == '1' {
-				b |= 1 << (7 - j)
-			}
-		}
-		compressedData[i/8] = b
+	// Step 5: Build final output
+	// Format: [HUFC][originalSize(4)][maxLength(1)][countPerLength(2 each)][symbols][paddingBits(1)][compressedData]
+	maxLength := cc.maxLength()
+	output := make([]byte, 0, 10+2*maxLength+len(cc.symbols)+len(compressedData))
+	output = append(output, HuffmanCanonicalMagic...)
+	output = binary.LittleEndian.AppendUint32(output, uint32(len(data)))
+	output = append(output, byte(maxLength))
+	for length := 1; length <= maxLength; length++ {
+		output = binary.LittleEndian.AppendUint16(output, cc.counts[length])
 	}
+	output = append(output, cc.symbols...)
+	output = append(output, byte(paddingBits))
+	output = append(output, compressedData...)
 
-	// Step 6: Serialize the tree
-	treeData := hc.serializeTree()
-
-	// Step 7: Build final output
-	// Format: [HUFF][originalSize(4)][treeSize(2)][tree][paddingBits(1)][compressedData]
-	var output bytes.Buffer
-
-	// Magic bytes
-	output.Write(HuffmanMagic)
-
-	// Original size (4 bytes)
-	originalSize := make([]byte, 4)
-	binary.LittleEndian.PutUint32(originalSize, uint32(len(data)))
-	output.Write(originalSize)
-
-	// Tree size (2 bytes)
-	treeSize := make([]byte, 2)
-	binary.LittleEndian.PutUint16(treeSize, uint16(len(treeData)))
-	output.Write(treeSize)
-
-	// Tree data
-	output.Write(treeData)
-
-	// Padding bits (1 byte)
-	output.WriteByte(byte(paddingBits))
-
-	// Compressed data
-	output.Write(compressedData)
-
-	return output.Bytes(), nil
-}
-
-// serializeTree serializes the Huffman tree for storage
-// Format: For each node, if leaf: [1][byte], if internal: [0][left][right]
-func (hc *HuffmanCompressor) serializeTree() []byte {
-	var buf bytes.Buffer
-	hc.serializeNode(&buf, hc.root)
-	return buf.Bytes()
+	return output, nil
 }
 
-func (hc *HuffmanCompressor) serializeNode(buf *bytes.Buffer, node *HuffmanNode) {
-	if node == nil {
-		return
+// Decompress decompresses Huffman-encoded data, canonical (HUFC) or tree-based (HUFF)
+func (hc *HuffmanCompressor) Decompress(data []byte) ([]byte, error) {
+	if bytes.HasPrefix(data, HuffmanBlockMagic) {
+		return decompressBlockBuffer(data, hc.Decompress)
 	}
 
-	if node.IsLeaf {
-		buf.WriteByte(1) // Leaf marker
-		buf.WriteByte(node.Byte)
-	} else {
-		buf.WriteByte(0) // Internal node marker
-		hc.serializeNode(buf, node.Left)
-		hc.serializeNode(buf, node.Right)
+	if bytes.HasPrefix(data, HuffmanCanonicalMagic) {
+		return decompressCanonical(data)
 	}
-}
 
-// Decompress decompresses Huffman-encoded data
-func (hc *HuffmanCompressor) Decompress(data []byte) ([]byte, error) {
 	if len(data) < 11 { // Minimum: magic(4) + size(4) + treeSize(2) + padding(1)
 		return nil, fmt.Errorf("data too short to be valid Huffman compressed data")
 	}
@@ -300,34 +313,20 @@ func (hc *HuffmanCompressor) Decompress(data []byte) ([]byte, error) {
 		return nil, fmt.Errorf("failed to read compressed data: %w", err)
 	}
 
-	// Convert bytes to bit string
-	var bitString bytes.Buffer
-	for _, b := range compressedData {
-		for i := 7; i >= 0; i-- {
-			if b&(1<<i) != 0 {
-				bitString.WriteByte('1')
-			} else {
-				bitString.WriteByte('0')
-			}
-		}
+	// Decode by walking the tree bit by bit
+	if paddingBits > 7 {
+		return nil, fmt.Errorf("invalid padding bits: %d", paddingBits)
 	}
-
-	// Remove padding bits
-	bits := bitString.String()
-	if paddingBits > 0 && len(bits) >= int(paddingBits) {
-		bits = bits[:len(bits)-int(paddingBits)]
-	}
-
-	// Decode using tree
+	totalBits := len(compressedData)*8 - int(paddingBits)
 	output := make([]byte, 0, originalSize)
 	node := hc.root
 
-	for _, bit := range bits {
+	for i := 0; i < totalBits && uint32(len(output)) < originalSize; i++ {
 		if node == nil {
 			return nil, fmt.Errorf("invalid compressed data: null node during traversal")
 		}
 
-		if bit == '0' {
+		if compressedData[i/8]&(0x80>>(i%8)) == 0 {
 			node = node.Left
 		} else {
 			node = node.Right
@@ -340,11 +339,109 @@ func (hc *HuffmanCompressor) Decompress(data []byte) ([]byte, error) {
 		if node.IsLeaf {
 			output = append(output, node.Byte)
 			node = hc.root
+		}
+	}
+
+	if uint32(len(output)) != originalSize {
+		return nil, fmt.Errorf("decompression size mismatch: expected %d, got %d", originalSize, len(output))
+	}
+
+	return output, nil
+}
+
+// lookupEntry is a decoding table slot: the symbol of the code starting with the slot's bits and its length
+// A zero length means the code is longer than lookupBits
+type lookupEntry struct {
+	symbol byte
+	length uint8
+}
+
+// decompressCanonical decodes HUFC data with a lookup table for short codes
+// and the per-length canonical code ranges for longer ones
+func decompressCanonical(data []byte) ([]byte, error) {
+	if len(data) < 9 { // Minimum: magic(4) + size(4) + maxLength(1)
+		return nil, fmt.Errorf("data too short to be valid Huffman compressed data")
+	}
+	originalSize := binary.LittleEndian.Uint32(data[4:8])
+	maxLength := int(data[8])
+	if maxLength == 0 || maxLength > maxCodeLength {
+		return nil, fmt.Errorf("invalid maximum code length: %d", maxLength)
+	}
+	offset := 9
+
+	cc := &canonicalCode{}
+	symbolCount := 0
+	if len(data) < offset+2*maxLength {
+		return nil, fmt.Errorf("data too short for code length counts")
+	}
+	for length := 1; length <= maxLength; length++ {
+		cc.counts[length] = binary.LittleEndian.Uint16(data[offset:])
+		symbolCount += int(cc.counts[length])
+		offset += 2
+	}
+	if symbolCount == 0 || symbolCount > 256 || len(data) < offset+symbolCount+1 {
+		return nil, fmt.Errorf("invalid symbol count: %d", symbolCount)
+	}
+	cc.symbols = data[offset : offset+symbolCount]
+	offset += symbolCount
+	paddingBits := int(data[offset])
+	offset++
+	if paddingBits > 7 {
+		return nil, fmt.Errorf("invalid padding bits: %d", paddingBits)
+	}
+
+	// first[l] is the first code of length l and index[l] the position of its symbol
+	var first [maxCodeLength + 1]uint32
+	var index [maxCodeLength + 1]int
+	code, position := uint32(0), 0
+	for length := 1; length <= maxLength; length++ {
+		first[length], index[length] = code, position
+		code += uint32(cc.counts[length])
+		position += int(cc.counts[length])
+		if code > 1<<length {
+			return nil, fmt.Errorf("invalid code lengths: oversubscribed at length %d", length)
+		}
+		code <<= 1
+	}
+
+	var table [1 << lookupBits]lookupEntry
+	for length := 1; length <= min(maxLength, lookupBits); length++ {
+		for i := 0; i < int(cc.counts[length]); i++ {
+			code := first[length] + uint32(i)
+			entry := lookupEntry{symbol: cc.symbols[index[length]+i], length: uint8(length)}
+			start := code << (lookupBits - length)
+			for slot := start; slot < start+1<<(lookupBits-length); slot++ {
+				table[slot] = entry
+			}
+		}
+	}
+
+	reader := newBitReader(data[offset:], paddingBits)
+	output := make([]byte, 0, originalSize)
+	for uint32(len(output)) < originalSize {
+		if entry := table[reader.peek(lookupBits)]; entry.length > 0 {
+			if !reader.consume(uint(entry.length)) {
+				break
+			}
+			output = append(output, entry.symbol)
+			continue
+		}
 
-			if uint32(len(output)) >= originalSize {
+		decoded := false
+		for length := lookupBits + 1; length <= maxLength; length++ {
+			code := reader.peek(uint(length))
+			if code-first[length] < uint32(cc.counts[length]) {
+				if !reader.consume(uint(length)) {
+					break
+				}
+				output = append(output, cc.symbols[index[length]+int(code-first[length])])
+				decoded = true
 				break
 			}
 		}
+		if !decoded {
+			break
+		}
 	}
 
 	if uint32(len(output)) != originalSize {
@@ -381,62 +478,24 @@ func (hc *HuffmanCompressor) deserializeNode(reader *bytes.Reader) *HuffmanNode
 	}
 }
 
-// CompressFile compresses a file and saves it to the output path
-func (hc *HuffmanCompressor) CompressFile(inputPath, outputPath string) error {
-	// Read input file
-	data, err := os.ReadFile(inputPath)
-	if err != nil {
-		return fmt.Errorf("failed to read input file: %w", err)
-	}
-
-	// Compress
-	compressed, err := hc.Compress(data)
-	if err != nil {
-		return fmt.Errorf("failed to compress: %w", err)
-	}
-
-	// Ensure output directory exists
-	outputDir := filepath.Dir(outputPath)
-	if err := os.MkdirAll(outputDir, 0755); err != nil {
-		return fmt.Errorf("failed to create output directory: %w", err)
-	}
+// CompressStream compresses r to w in blocks of StreamBlockSize, so only one block is held in memory
+func (hc *HuffmanCompressor) CompressStream(r io.Reader, w io.Writer) error {
+	return compressBlocks(r, w, HuffmanBlockMagic, hc.Compress)
+}
 
-	// Write output file
-	err = os.WriteFile(outputPath, compressed, 0644)
-	if err != nil {
-		return fmt.Errorf("failed to write output file: %w", err)
-	}
+// DecompressStream decompresses a block stream from r to w, also accepting single-buffer Huffman data
+func (hc *HuffmanCompressor) DecompressStream(r io.Reader, w io.Writer) error {
+	return decompressStream(r, w, HuffmanBlockMagic, hc.Decompress)
+}
 
-	return nil
+// CompressFile compresses a file and saves it to the output path
+func (hc *HuffmanCompressor) CompressFile(inputPath, outputPath string) error {
+	return streamFile(inputPath, outputPath, hc.CompressStream)
 }
 
 // DecompressFile decompresses a file and saves it to the output path
 func (hc *HuffmanCompressor) DecompressFile(inputPath, outputPath string) error {
-	// Read input file
-	data, err := os.ReadFile(inputPath)
-	if err != nil {
-		return fmt.Errorf("failed to read input file: %w", err)
-	}
-
-	// Decompress
-	decompressed, err := hc.Decompress(data)
-	if err != nil {
-		return fmt.Errorf("failed to decompress: %w", err)
-	}
-
-	// Ensure output directory exists
-	outputDir := filepath.Dir(outputPath)
-	if err := os.MkdirAll(outputDir, 0755); err != nil {
-		return fmt.Errorf("failed to create output directory: %w", err)
-	}
-
-	// Write output file
-	err = os.WriteFile(outputPath, decompressed, 0644)
-	if err != nil {
-		return fmt.Errorf("failed to write output file: %w", err)
-	}
-
-	return nil
+	return streamFile(inputPath, outputPath, hc.DecompressStream)
 }
 
 // GetCompressionStats returns compression statistics
@@ -5,13 +5,14 @@ import (
 	"encoding/binary"
 	"fmt"
 	"io"
-	"os"
-	"path/filepath"
 )
 
 // Magic bytes to identify LZW compressed files
 var LZWMagic = []byte{'L', 'Z', 'W', 'W'}
 
+// LZWBlockMagic starts a stream of independently compressed blocks written by CompressStream
+var LZWBlockMagic = []byte{'L', 'Z', 'W', 'B'}
+
 // LZWCompressor handles LZW compression and decompression
 type LZWCompressor struct{}
 
@@ -86,6 +87,10 @@ func (lzw *LZWCompressor) Compress(data []byte) ([]byte, error) {
 
 // Decompress decompresses LZW-encoded data
 func (lzw *LZWCompressor) Decompress(data []byte) ([]byte, error) {
+	if bytes.HasPrefix(data, LZWBlockMagic) {
+		return decompressBlockBuffer(data, lzw.Decompress)
+	}
+
 	if len(data) < 12 { // Minimum: magic(4) + originalSize(4) + codeCount(4)
 		return nil, fmt.Errorf("data too short to be valid LZW compressed data")
 	}
@@ -183,52 +188,22 @@ func (lzw *LZWCompressor) Decompress(data []byte) ([]byte, error) {
 	return result, nil
 }
 
-// CompressFile compresses a file and saves it to the output path
-func (lzw *LZWCompressor) CompressFile(inputPath, outputPath string) error {
-	data, err := os.ReadFile(inputPath)
-	if err != nil {
-		return fmt.Errorf("failed to read input file: %w", err)
-	}
-
-	compressed, err := lzw.Compress(data)
-	if err != nil {
-		return fmt.Errorf("failed to compress: %w", err)
-	}
-
-	outputDir := filepath.Dir(outputPath)
-	if err := os.MkdirAll(outputDir, 0755); err != nil {
-		return fmt.Errorf("failed to create output directory: %w", err)
-	}
+// CompressStream compresses r to w in blocks of StreamBlockSize, so only one block is held in memory
+func (lzw *LZWCompressor) CompressStream(r io.Reader, w io.Writer) error {
+	return compressBlocks(r, w, LZWBlockMagic, lzw.Compress)
+}
 
-	err = os.WriteFile(outputPath, compressed, 0644)
-	if err != nil {
-		return fmt.Errorf("failed to write output file: %w", err)
-	}
+// DecompressStream decompresses a block stream from r to w, also accepting single-buffer LZW data
+func (lzw *LZWCompressor) DecompressStream(r io.Reader, w io.Writer) error {
+	return decompressStream(r, w, LZWBlockMagic, lzw.Decompress)
+}
 
-	return nil
+// CompressFile compresses a file and saves it to the output path
+func (lzw *LZWCompressor) CompressFile(inputPath, outputPath string) error {
+	return streamFile(inputPath, outputPath, lzw.CompressStream)
 }
 
 // DecompressFile decompresses a file and saves it to the output path
 func (lzw *LZWCompressor) DecompressFile(inputPath, outputPath string) error {
-	data, err := os.ReadFile(inputPath)
-	if err != nil {
-		return fmt.Errorf("failed to read input file: %w", err)
-	}
-
-	decompressed, err := lzw.Decompress(data)
-	if err != nil {
-		return fmt.Errorf("failed to decompress: %w", err)
-	}
-
-	outputDir := filepath.Dir(outputPath)
-	if err := os.MkdirAll(outputDir, 0755); err != nil {
-		return fmt.Errorf("failed to create output directory: %w", err)
-	}
-
-	err = os.WriteFile(outputPath, decompressed, 0644)
-	if err != nil {
-		return fmt.Errorf("failed to write output file: %w", err)
-	}
-
-	return nil
+	return streamFile(inputPath, outputPath, lzw.DecompressStream)
 }
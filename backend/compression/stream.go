@@ -0,0 +1,213 @@
+package compression
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// StreamBlockSize is the amount of input compressed as one independent block when streaming
+// Only one block is held in memory at a time, whatever the size of the file
+const StreamBlockSize = 1 << 20
+
+// maxStreamBlockLen bounds the compressed length of a block, which can exceed its input for incompressible data
+const maxStreamBlockLen = 8 * StreamBlockSize
+
+// compressBlocks splits r into blocks, compresses each one in memory and writes them to w
+// Format: [blockMagic(4)][block1Len(4)][block1]...[blockNLen(4)][blockN][0(4)]
+// Each block is a complete single-buffer stream (e.g. HUFF or LZWW) with its own header
+func compressBlocks(r io.Reader, w io.Writer, blockMagic []byte, compress func([]byte) ([]byte, error)) error {
+	if _, err := w.Write(blockMagic); err != nil {
+		return fmt.Errorf("failed to write magic bytes: %w", err)
+	}
+
+	block := make([]byte, StreamBlockSize)
+	lenBytes := make([]byte, 4)
+	for {
+		n, err := io.ReadFull(r, block)
+		if n > 0 {
+			compressed, cerr := compress(block[:n])
+			if cerr != nil {
+				return cerr
+			}
+			binary.BigEndian.PutUint32(lenBytes, uint32(len(compressed)))
+			if _, werr := w.Write(lenBytes); werr != nil {
+				return fmt.Errorf("failed to write block length: %w", werr)
+			}
+			if _, werr := w.Write(compressed); werr != nil {
+				return fmt.Errorf("failed to write block: %w", werr)
+			}
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read input: %w", err)
+		}
+	}
+
+	binary.BigEndian.PutUint32(lenBytes, 0)
+	if _, err := w.Write(lenBytes); err != nil {
+		return fmt.Errorf("failed to write end of stream: %w", err)
+	}
+	return nil
+}
+
+// decompressBlocks reads a stream written by compressBlocks, whose magic was already consumed
+func decompressBlocks(r io.Reader, w io.Writer, decompress func([]byte) ([]byte, error)) error {
+	lenBytes := make([]byte, 4)
+	for i := 0; ; i++ {
+		if _, err := io.ReadFull(r, lenBytes); err != nil {
+			return fmt.Errorf("truncated stream at block %d: %w", i, err)
+		}
+		blockLen := binary.BigEndian.Uint32(lenBytes)
+		if blockLen == 0 {
+			return nil
+		}
+		if blockLen > maxStreamBlockLen {
+			return fmt.Errorf("block %d length %d exceeds maximum of %d", i, blockLen, maxStreamBlockLen)
+		}
+
+		block := make([]byte, blockLen)
+		if _, err := io.ReadFull(r, block); err != nil {
+			return fmt.Errorf("truncated stream at block %d: %w", i, err)
+		}
+		data, err := decompress(block)
+		if err != nil {
+			return fmt.Errorf("block %d: %w", i, err)
+		}
+		if _, err := w.Write(data); err != nil {
+			return fmt.Errorf("failed to write output: %w", err)
+		}
+	}
+}
+
+// decompressStream decompresses either a block stream or a legacy single-buffer stream
+// Legacy streams (written before streaming existed) are read fully into memory
+func decompressStream(r io.Reader, w io.Writer, blockMagic []byte, decompress func([]byte) ([]byte, error)) error {
+	buffered := bufio.NewReader(r)
+	magic, err := buffered.Peek(len(blockMagic))
+	if err != nil {
+		return fmt.Errorf("failed to read magic bytes: %w", err)
+	}
+
+	if bytes.Equal(magic, blockMagic) {
+		buffered.Discard(len(blockMagic))
+		return decompressBlocks(buffered, w, decompress)
+	}
+
+	data, err := io.ReadAll(buffered)
+	if err != nil {
+		return fmt.Errorf("failed to read input: %w", err)
+	}
+	decompressed, err := decompress(data)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(decompressed); err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+	return nil
+}
+
+// decompressBlockBuffer decompresses an in-memory block stream
+func decompressBlockBuffer(data []byte, decompress func([]byte) ([]byte, error)) ([]byte, error) {
+	var output bytes.Buffer
+	if err := decompressBlocks(bytes.NewReader(data[4:]), &output, decompress); err != nil {
+		return nil, err
+	}
+	return output.Bytes(), nil
+}
+
+// streamFile runs inputPath through stream into outputPath, removing the output if streaming fails
+func streamFile(inputPath, outputPath string, stream func(io.Reader, io.Writer) error) error {
+	input, err := os.Open(inputPath)
+	if err != nil {
+		return fmt.Errorf("failed to read input file: %w", err)
+	}
+	defer input.Close()
+
+	if err := os.MkdirAll(filepath.Dir(outputPath), 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+	output, err := os.OpenFile(outputPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to write output file: %w", err)
+	}
+
+	writer := bufio.NewWriter(output)
+	err = stream(bufio.NewReader(input), writer)
+	if err == nil {
+		err = writer.Flush()
+	}
+	if closeErr := output.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(outputPath)
+		return err
+	}
+	return nil
+}
+
+// ReadOriginalSize returns the uncompressed size recorded in a compressed file without decompressing it
+// Block streams are walked block by block; gzip only records the size modulo 2^32
+// and zstd frames only record it when the size was known up front (see ZstdCompressor.CompressFile)
+func ReadOriginalSize(path string) (int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	header := make([]byte, 8)
+	if _, err := io.ReadFull(file, header); err != nil {
+		return 0, fmt.Errorf("failed to read header: %w", err)
+	}
+
+	switch {
+	case bytes.HasPrefix(header, HuffmanMagic), bytes.HasPrefix(header, HuffmanCanonicalMagic), bytes.HasPrefix(header, LZWMagic):
+		return int64(binary.LittleEndian.Uint32(header[4:8])), nil
+	case bytes.HasPrefix(header, HuffmanBlockMagic), bytes.HasPrefix(header, LZWBlockMagic):
+		return readBlockStreamSize(file)
+	case bytes.HasPrefix(header, GzipMagic):
+		info, err := file.Stat()
+		if err != nil {
+			return 0, err
+		}
+		trailer := make([]byte, 4)
+		if _, err := file.ReadAt(trailer, info.Size()-4); err != nil {
+			return 0, fmt.Errorf("failed to read gzip trailer: %w", err)
+		}
+		return int64(binary.LittleEndian.Uint32(trailer)), nil
+	case bytes.HasPrefix(header, ZstdMagic):
+		return readZstdFrameSize(file)
+	default:
+		return 0, fmt.Errorf("unknown compression format")
+	}
+}
+
+// readBlockStreamSize sums the original sizes in the headers of every block of a block stream
+func readBlockStreamSize(file *os.File) (int64, error) {
+	var total int64
+	offset := int64(4)
+	blockHeader := make([]byte, 12) // blockLen(4) + magic(4) + originalSize(4)
+	for {
+		if _, err := file.ReadAt(blockHeader[:4], offset); err != nil {
+			return 0, fmt.Errorf("failed to read block length: %w", err)
+		}
+		blockLen := int64(binary.BigEndian.Uint32(blockHeader[:4]))
+		if blockLen == 0 {
+			return total, nil
+		}
+		if _, err := file.ReadAt(blockHeader, offset); err != nil {
+			return 0, fmt.Errorf("failed to read block header: %w", err)
+		}
+		total += int64(binary.LittleEndian.Uint32(blockHeader[8:12]))
+		offset += 4 + blockLen
+	}
+}
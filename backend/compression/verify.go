@@ -0,0 +1,39 @@
+package compression
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+)
+
+// VerifyFile decompresses compressedPath and checks that it matches originalPath by SHA-256
+// Both files are streamed into the hashes, so neither is read into memory as a whole
+func VerifyFile(compressor Compressor, originalPath, compressedPath string) error {
+	original, err := os.Open(originalPath)
+	if err != nil {
+		return fmt.Errorf("failed to read original file: %w", err)
+	}
+	defer original.Close()
+	originalHash := sha256.New()
+	if _, err := io.Copy(originalHash, original); err != nil {
+		return fmt.Errorf("failed to read original file: %w", err)
+	}
+
+	compressed, err := os.Open(compressedPath)
+	if err != nil {
+		return fmt.Errorf("failed to read compressed file: %w", err)
+	}
+	defer compressed.Close()
+	decompressedHash := sha256.New()
+	if err := compressor.DecompressStream(bufio.NewReader(compressed), decompressedHash); err != nil {
+		return fmt.Errorf("failed to decompress: %w", err)
+	}
+
+	if !bytes.Equal(originalHash.Sum(nil), decompressedHash.Sum(nil)) {
+		return fmt.Errorf("checksum mismatch: decompressed data differs from %s", originalPath)
+	}
+	return nil
+}
@@ -0,0 +1,165 @@
+package compression
+
+import (
+	"BinaryCRUD/backend/utils"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Magic bytes of a Zstandard frame (RFC 8878), so archives can be opened with the zstd tool
+var ZstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+
+// ZstdCompressor handles Zstandard compression and decompression
+type ZstdCompressor struct {
+	level zstd.EncoderLevel
+}
+
+// NewZstdCompressor creates a new zstd compressor using the default level
+func NewZstdCompressor() *ZstdCompressor {
+	return &ZstdCompressor{level: zstd.SpeedDefault}
+}
+
+// NewZstdCompressorLevel creates a zstd compressor with a level from zstd.SpeedFastest (1) to zstd.SpeedBestCompression (4)
+// Lower levels trade compression ratio for speed on large data files
+func NewZstdCompressorLevel(level int) (*ZstdCompressor, error) {
+	if level < int(zstd.SpeedFastest) || level > int(zstd.SpeedBestCompression) {
+		return nil, fmt.Errorf("zstd level must be between %d and %d", zstd.SpeedFastest, zstd.SpeedBestCompression)
+	}
+	return &ZstdCompressor{level: zstd.EncoderLevel(level)}, nil
+}
+
+// newEncoder creates an encoder at the compressor's level
+func (zc *ZstdCompressor) newEncoder() (*zstd.Encoder, error) {
+	writer, err := zstd.NewWriter(nil, zstd.WithEncoderLevel(zc.level), zstd.WithEncoderConcurrency(1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create zstd writer: %w", err)
+	}
+	return writer, nil
+}
+
+// Compress compresses data into a single zstd frame
+func (zc *ZstdCompressor) Compress(data []byte) ([]byte, error) {
+	if len(data) == 0 {
+		return nil, fmt.Errorf("cannot compress empty data")
+	}
+
+	writer, err := zc.newEncoder()
+	if err != nil {
+		return nil, err
+	}
+	defer writer.Close()
+
+	return writer.EncodeAll(data, nil), nil
+}
+
+// Decompress decompresses a zstd stream
+// Output beyond utils.MaxDecompressedSize is rejected before it is read into memory
+func (zc *ZstdCompressor) Decompress(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, ZstdMagic) {
+		return nil, fmt.Errorf("invalid magic bytes: not zstd data")
+	}
+
+	reader, err := zstd.NewReader(bytes.NewReader(data), zstd.WithDecoderConcurrency(1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read zstd header: %w", err)
+	}
+	defer reader.Close()
+
+	result, err := io.ReadAll(io.LimitReader(reader, utils.MaxDecompressedSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress: %w", err)
+	}
+	if len(result) > utils.MaxDecompressedSize {
+		return nil, fmt.Errorf("decompressed data exceeds maximum of %d bytes", utils.MaxDecompressedSize)
+	}
+
+	return result, nil
+}
+
+// CompressStream compresses r to w as a single zstd frame
+func (zc *ZstdCompressor) CompressStream(r io.Reader, w io.Writer) error {
+	return zc.compressStream(r, w, -1)
+}
+
+// compressStream compresses r to w, recording size in the frame header unless it is negative
+func (zc *ZstdCompressor) compressStream(r io.Reader, w io.Writer, size int64) error {
+	writer, err := zc.newEncoder()
+	if err != nil {
+		return err
+	}
+	writer.ResetContentSize(w, size)
+
+	if _, err := io.Copy(writer, r); err != nil {
+		writer.Close()
+		return fmt.Errorf("failed to compress: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("failed to finish zstd stream: %w", err)
+	}
+	return nil
+}
+
+// DecompressStream decompresses a zstd stream from r to w
+// Unlike Decompress the output is not size-limited, since it never sits in memory
+func (zc *ZstdCompressor) DecompressStream(r io.Reader, w io.Writer) error {
+	reader, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+	if err != nil {
+		return fmt.Errorf("failed to read zstd header: %w", err)
+	}
+	defer reader.Close()
+
+	if _, err := io.Copy(w, reader); err != nil {
+		return fmt.Errorf("failed to decompress: %w", err)
+	}
+	return nil
+}
+
+// CompressFile compresses a file and saves it to the output path
+// The input size is recorded in the frame header so ReadOriginalSize can report it
+func (zc *ZstdCompressor) CompressFile(inputPath, outputPath string) error {
+	info, err := os.Stat(inputPath)
+	if err != nil {
+		return fmt.Errorf("failed to read input file: %w", err)
+	}
+	return streamFile(inputPath, outputPath, func(r io.Reader, w io.Writer) error {
+		return zc.compressStream(r, w, info.Size())
+	})
+}
+
+// DecompressFile decompresses a file and saves it to the output path
+func (zc *ZstdCompressor) DecompressFile(inputPath, outputPath string) error {
+	return streamFile(inputPath, outputPath, zc.DecompressStream)
+}
+
+// readZstdFrameSize returns the content size recorded in the header of a zstd frame
+// Frames that do not record it (tiny inputs, or streams of unknown length) are decoded to count it
+func readZstdFrameSize(file *os.File) (int64, error) {
+	header := make([]byte, zstd.HeaderMaxSize)
+	n, err := file.ReadAt(header, 0)
+	if err != nil && err != io.EOF {
+		return 0, fmt.Errorf("failed to read zstd header: %w", err)
+	}
+
+	var frame zstd.Header
+	if err := frame.Decode(header[:n]); err != nil {
+		return 0, fmt.Errorf("invalid zstd header: %w", err)
+	}
+	if frame.HasFCS {
+		return int64(frame.FrameContentSize), nil
+	}
+
+	reader, err := zstd.NewReader(io.NewSectionReader(file, 0, 1<<62), zstd.WithDecoderConcurrency(1))
+	if err != nil {
+		return 0, fmt.Errorf("failed to read zstd header: %w", err)
+	}
+	defer reader.Close()
+	size, err := io.Copy(io.Discard, reader)
+	if err != nil {
+		return 0, fmt.Errorf("failed to decompress: %w", err)
+	}
+	return size, nil
+}
@@ -0,0 +1,92 @@
+package crypto
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// BlobMagic starts a blob encrypted by EncryptBlob
+// Format: [magic(4)][wrappedKeyLen(4)][wrappedKey(N)][nonce(12)][AES-GCM(data)]
+var BlobMagic = []byte{'B', 'E', 'N', 'C'}
+
+// IsEncryptedBlob reports whether data was produced by EncryptBlob
+func IsEncryptedBlob(data []byte) bool {
+	return bytes.HasPrefix(data, BlobMagic)
+}
+
+// EncryptBlob encrypts data with AES-256-GCM under a random key that is stored RSA-wrapped in the blob
+// Unlike field encryption the blob carries its own key, so it can be decrypted without the keys directory
+func EncryptBlob(data []byte) ([]byte, error) {
+	rsa, err := GetInstance()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get RSA crypto instance: %w", err)
+	}
+
+	key := make([]byte, DataKeySize)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, fmt.Errorf("failed to generate blob key: %w", err)
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	wrappedKey := serializeBigInts(rsa.Encrypt(key))
+	sealed := append([]byte{}, BlobMagic...)
+	sealed = binary.BigEndian.AppendUint32(sealed, uint32(len(wrappedKey)))
+	sealed = append(sealed, wrappedKey...)
+	sealed = append(sealed, nonce...)
+	return gcm.Seal(sealed, nonce, data, BlobMagic), nil
+}
+
+// DecryptBlob unwraps the key of a blob produced by EncryptBlob and decrypts its data
+func DecryptBlob(blob []byte) ([]byte, error) {
+	if !IsEncryptedBlob(blob) {
+		return nil, errors.New("not an encrypted blob")
+	}
+	offset := len(BlobMagic)
+	if len(blob) < offset+4 {
+		return nil, errors.New("encrypted blob too short")
+	}
+	keyLen := int(binary.BigEndian.Uint32(blob[offset:]))
+	offset += 4
+	if keyLen > len(blob)-offset {
+		return nil, errors.New("encrypted blob too short")
+	}
+
+	rsa, err := GetInstance()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get RSA crypto instance: %w", err)
+	}
+	bigInts, err := deserializeBigInts(blob[offset : offset+keyLen])
+	if err != nil {
+		return nil, fmt.Errorf("failed to read blob key: %w", err)
+	}
+	key := rsa.Decrypt(bigInts)
+	if len(key) != DataKeySize {
+		return nil, fmt.Errorf("invalid blob key size %d", len(key))
+	}
+	offset += keyLen
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(blob)-offset < gcm.NonceSize() {
+		return nil, errors.New("encrypted blob too short")
+	}
+	nonce := blob[offset : offset+gcm.NonceSize()]
+	data, err := gcm.Open(nil, nonce, blob[offset+gcm.NonceSize():], BlobMagic)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt blob: %w", err)
+	}
+	return data, nil
+}
@@ -0,0 +1,238 @@
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DataKeyFile is the file in the keys directory holding the RSA-wrapped AES data key
+const DataKeyFile = "data.key"
+
+// DataKeyArchiveDir is the subdirectory of the keys directory where rotated data keys are kept for recovery
+const DataKeyArchiveDir = "archive"
+
+// DataKeySize is the size of the AES-256 data key in bytes
+const DataKeySize = 32
+
+// fieldMarker starts AES-GCM field ciphertexts: [marker(1)][nonce(12)][AES-GCM(plaintext)]
+// Legacy RSA ciphertexts start with the high byte of their big-endian count, which is 0 for any field
+const fieldMarker byte = 0x01
+
+var (
+	fieldCipher     *FieldCipher
+	fieldCipherKeys string
+)
+
+// FieldCipher encrypts record fields with AES-256-GCM under a data key stored RSA-wrapped in the keys directory
+// Fields written by the previous RSA-only scheme are still decrypted
+type FieldCipher struct {
+	rsa  *SimpleRSA
+	aead cipher.AEAD
+}
+
+// GetFieldCipher returns the field cipher for keysDir, creating and storing a data key on first use
+func GetFieldCipher(keysDir string) (*FieldCipher, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if fieldCipher != nil && fieldCipherKeys == keysDir {
+		return fieldCipher, nil
+	}
+
+	rsa, err := getInstanceLocked()
+	if err != nil {
+		return nil, err
+	}
+	key, err := loadOrCreateKey(rsa, keysDir, DataKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	fieldCipher = &FieldCipher{rsa: rsa, aead: aead}
+	fieldCipherKeys = keysDir
+	return fieldCipher, nil
+}
+
+// RotateDataKey replaces the data key in keysDir with a new random one
+// reencrypt rewrites every encrypted field from the old cipher to the new one; the new key is stored next to
+// the current one before it runs, so it is never lost, and only replaces it once reencrypt succeeded
+// The old wrapped key is archived under DataKeyArchiveDir and its path returned
+// Only the AES data key changes: the RSA key pair that wraps it is built in (see NewSimpleRSADefault) and is
+// not rotated, so a leaked private key also exposes the new data key
+func RotateDataKey(keysDir string, reencrypt func(oldCipher, newCipher *FieldCipher) error) (string, error) {
+	oldCipher, err := GetFieldCipher(keysDir)
+	if err != nil {
+		return "", err
+	}
+
+	key := make([]byte, DataKeySize)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return "", fmt.Errorf("failed to generate data key: %w", err)
+	}
+	aead, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	newCipher := &FieldCipher{rsa: oldCipher.rsa, aead: aead}
+
+	path := filepath.Join(keysDir, DataKeyFile)
+	nextPath := path + ".next"
+	if err := os.WriteFile(nextPath, serializeBigInts(oldCipher.rsa.Encrypt(key)), 0600); err != nil {
+		return "", fmt.Errorf("failed to write data key: %w", err)
+	}
+
+	if err := reencrypt(oldCipher, newCipher); err != nil {
+		os.Remove(nextPath)
+		return "", err
+	}
+
+	wrapped, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read data key: %w", err)
+	}
+	archiveDir := filepath.Join(keysDir, DataKeyArchiveDir)
+	if err := os.MkdirAll(archiveDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create key archive: %w", err)
+	}
+	archivePath := filepath.Join(archiveDir, fmt.Sprintf("data-%d.key", time.Now().UnixNano()))
+	if err := os.WriteFile(archivePath, wrapped, 0600); err != nil {
+		return "", fmt.Errorf("failed to archive data key: %w", err)
+	}
+	if err := os.Rename(nextPath, path); err != nil {
+		return "", fmt.Errorf("failed to replace data key: %w", err)
+	}
+
+	mu.Lock()
+	fieldCipher = newCipher
+	fieldCipherKeys = keysDir
+	mu.Unlock()
+
+	return archivePath, nil
+}
+
+// Reencrypt decrypts a field with c and encrypts it again with to
+func (c *FieldCipher) Reencrypt(ciphertext []byte, to *FieldCipher) ([]byte, error) {
+	plaintext, err := c.DecryptFromBytesIf(true, ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	return to.EncryptToBytesIf(true, plaintext)
+}
+
+// loadOrCreateKey unwraps the key stored in filename in keysDir, generating and wrapping a new one if there is none
+func loadOrCreateKey(rsa *SimpleRSA, keysDir, filename string) ([]byte, error) {
+	path := filepath.Join(keysDir, filename)
+
+	wrapped, err := os.ReadFile(path)
+	if err == nil {
+		bigInts, err := deserializeBigInts(wrapped)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", filename, err)
+		}
+		key := rsa.Decrypt(bigInts)
+		if len(key) != DataKeySize {
+			return nil, fmt.Errorf("invalid %s size %d", filename, len(key))
+		}
+		return key, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read %s: %w", filename, err)
+	}
+
+	key := make([]byte, DataKeySize)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, fmt.Errorf("failed to generate %s: %w", filename, err)
+	}
+
+	if err := os.MkdirAll(keysDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create keys directory: %w", err)
+	}
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, serializeBigInts(rsa.Encrypt(key)), 0600); err != nil {
+		return nil, fmt.Errorf("failed to write %s: %w", filename, err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return nil, fmt.Errorf("failed to write %s: %w", filename, err)
+	}
+	return key, nil
+}
+
+// newGCM creates an AES-GCM cipher for the given key
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCM: %w", err)
+	}
+	return gcm, nil
+}
+
+// EncryptToBytes encrypts a string when encryption is enabled globally
+func (c *FieldCipher) EncryptToBytes(plaintext string) ([]byte, error) {
+	return c.EncryptToBytesIf(IsEnabled(), plaintext)
+}
+
+// DecryptFromBytes decrypts bytes when encryption is enabled globally
+func (c *FieldCipher) DecryptFromBytes(ciphertext []byte) (string, error) {
+	return c.DecryptFromBytesIf(IsEnabled(), ciphertext)
+}
+
+// EncryptToBytesIf encrypts a string with AES-GCM when encrypt is set, ignoring the global setting
+func (c *FieldCipher) EncryptToBytesIf(encrypt bool, plaintext string) ([]byte, error) {
+	if !encrypt {
+		return []byte(plaintext), nil
+	}
+
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	sealed := append([]byte{fieldMarker}, nonce...)
+	return c.aead.Seal(sealed, nonce, []byte(plaintext), nil), nil
+}
+
+// DecryptFromBytesIf decrypts an AES-GCM or legacy RSA field when decrypt is set, ignoring the global setting
+func (c *FieldCipher) DecryptFromBytesIf(decrypt bool, ciphertext []byte) (string, error) {
+	if !decrypt {
+		return string(ciphertext), nil
+	}
+
+	if IsLegacyCiphertext(ciphertext) {
+		bigInts, err := deserializeBigInts(ciphertext)
+		if err != nil {
+			return "", err
+		}
+		return c.rsa.DecryptString(bigInts), nil
+	}
+
+	if len(ciphertext) < 1+c.aead.NonceSize() || ciphertext[0] != fieldMarker {
+		return "", errors.New("data too short")
+	}
+	nonce := ciphertext[1 : 1+c.aead.NonceSize()]
+	plaintext, err := c.aead.Open(nil, nonce, ciphertext[1+c.aead.NonceSize():], nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt field: %w", err)
+	}
+	return string(plaintext), nil
+}
+
+// IsLegacyCiphertext reports whether an encrypted field was written by the RSA-only scheme
+func IsLegacyCiphertext(ciphertext []byte) bool {
+	return len(ciphertext) > 0 && ciphertext[0] != fieldMarker
+}
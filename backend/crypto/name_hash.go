@@ -0,0 +1,53 @@
+package crypto
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+)
+
+// SearchKeyFile is the file in the keys directory holding the RSA-wrapped HMAC key that hashes names for search
+const SearchKeyFile = "search.key"
+
+// NameHashSize is the length of a name hash, a truncated HMAC-SHA256
+const NameHashSize = 16
+
+var (
+	searchKey    []byte
+	searchKeyDir string
+)
+
+// getSearchKey returns the search key for keysDir, creating and storing one on first use
+func getSearchKey(keysDir string) ([]byte, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if searchKey != nil && searchKeyDir == keysDir {
+		return searchKey, nil
+	}
+
+	rsa, err := getInstanceLocked()
+	if err != nil {
+		return nil, err
+	}
+	key, err := loadOrCreateKey(rsa, keysDir, SearchKeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	searchKey = key
+	searchKeyDir = keysDir
+	return searchKey, nil
+}
+
+// NameHash returns the keyed hash of a name under the search key in keysDir
+// Equal names hash equally, so encrypted names can be matched without decrypting them
+// The caller normalizes the name first
+func NameHash(keysDir, name string) ([]byte, error) {
+	key, err := getSearchKey(keysDir)
+	if err != nil {
+		return nil, err
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(name))
+	return mac.Sum(nil)[:NameHashSize], nil
+}
@@ -0,0 +1,33 @@
+package crypto
+
+// ReplicationKeyFile is the file in the keys directory holding the RSA-wrapped secret shared by a primary and its replicas
+// A replica authenticates with the primary's copy of this file
+const ReplicationKeyFile = "replication.key"
+
+var (
+	replicationKey    []byte
+	replicationKeyDir string
+)
+
+// ReplicationKey returns the replication secret for keysDir, creating and storing one on first use
+func ReplicationKey(keysDir string) ([]byte, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if replicationKey != nil && replicationKeyDir == keysDir {
+		return replicationKey, nil
+	}
+
+	rsa, err := getInstanceLocked()
+	if err != nil {
+		return nil, err
+	}
+	key, err := loadOrCreateKey(rsa, keysDir, ReplicationKeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	replicationKey = key
+	replicationKeyDir = keysDir
+	return replicationKey, nil
+}
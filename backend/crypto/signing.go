@@ -0,0 +1,57 @@
+package crypto
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+)
+
+// SigningKeyFile is the file in the keys directory holding the RSA-wrapped HMAC key that signs data files
+const SigningKeyFile = "signing.key"
+
+var (
+	signingKey    []byte
+	signingKeyDir string
+)
+
+// getSigningKey returns the signing key for keysDir, creating and storing one on first use
+func getSigningKey(keysDir string) ([]byte, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if signingKey != nil && signingKeyDir == keysDir {
+		return signingKey, nil
+	}
+
+	rsa, err := getInstanceLocked()
+	if err != nil {
+		return nil, err
+	}
+	key, err := loadOrCreateKey(rsa, keysDir, SigningKeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	signingKey = key
+	signingKeyDir = keysDir
+	return signingKey, nil
+}
+
+// Sign returns the HMAC-SHA256 of data under the signing key in keysDir
+func Sign(keysDir string, data []byte) ([]byte, error) {
+	key, err := getSigningKey(keysDir)
+	if err != nil {
+		return nil, err
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return mac.Sum(nil), nil
+}
+
+// VerifySignature reports whether signature is the HMAC-SHA256 of data under the signing key in keysDir
+func VerifySignature(keysDir string, data, signature []byte) (bool, error) {
+	expected, err := Sign(keysDir, data)
+	if err != nil {
+		return false, err
+	}
+	return hmac.Equal(expected, signature), nil
+}
@@ -217,6 +217,13 @@ func ExtendedGCD(a, b *big.Int) (gcd, x, y *big.Int) {
 
 // GetInstance returns the singleton SimpleRSA instance
 func GetInstance() (*SimpleRSA, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	return getInstanceLocked()
+}
+
+// getInstanceLocked returns the singleton SimpleRSA instance (must be called with mu held)
+func getInstanceLocked() (*SimpleRSA, error) {
 	var initErr error
 	once.Do(func() {
 		instance, initErr = NewSimpleRSADefault()
@@ -241,12 +248,20 @@ func SetEnabled(enable bool) {
 	enabled = enable
 }
 
-// Reset clears the singleton instance
+// Reset clears the singleton instance and the cached field cipher and keys
 func Reset() {
 	mu.Lock()
 	defer mu.Unlock()
 	instance = nil
 	once = sync.Once{}
+	fieldCipher = nil
+	fieldCipherKeys = ""
+	signingKey = nil
+	signingKeyDir = ""
+	searchKey = nil
+	searchKeyDir = ""
+	replicationKey = nil
+	replicationKeyDir = ""
 }
 
 // EncryptToBytes encrypts a string and serializes the result to bytes.
@@ -0,0 +1,101 @@
+package dao
+
+import "time"
+
+// appendBuffer holds the records of writes made in group commit mode until they are flushed together
+// It is not safe for concurrent use; the DAO lock protects it
+type appendBuffer struct {
+	group      *flushGroup   // writers of the buffered records, waiting for the flush
+	maxRecords int           // flush once this many records are buffered
+	maxDelay   time.Duration // flush at the latest this long after the first buffered record
+	data       []byte        // complete records, appended to the file in one write
+	records    []bufferedRecord
+	ids        map[uint64]bool
+	nextID     uint64 // next auto-assigned ID, valid once nextIDRead is set
+	nextIDRead bool
+	timer      *time.Timer
+}
+
+// bufferedRecord locates one record in the buffer
+type bufferedRecord struct {
+	id     uint64
+	name   string
+	offset int64 // position of the record in the buffer, added to the file end on flush
+}
+
+// flushGroup lets the writers of one group of buffered records wait for the flush that writes them
+type flushGroup struct {
+	done chan struct{}
+	err  error
+}
+
+// wait blocks until the group is flushed and returns the error of the flush
+// A nil group belongs to a write that was not buffered and returns right away
+func (g *flushGroup) wait() error {
+	if g == nil {
+		return nil
+	}
+	<-g.done
+	return g.err
+}
+
+// finish records the result of the flush and wakes up the writers of the group
+func (g *flushGroup) finish(err error) {
+	g.err = err
+	close(g.done)
+}
+
+// newAppendBuffer creates an empty buffer with the given flush thresholds
+// Without a delay nothing would flush a group that never fills, so every record is flushed on its own
+func newAppendBuffer(maxRecords int, maxDelay time.Duration) *appendBuffer {
+	if maxDelay <= 0 {
+		maxRecords = 1
+	}
+	return &appendBuffer{
+		maxRecords: max(maxRecords, 1),
+		maxDelay:   maxDelay,
+		ids:        make(map[uint64]bool),
+	}
+}
+
+// add buffers a complete record, starting the flush timer on the first one
+// Returns the group the record is flushed with
+func (b *appendBuffer) add(id uint64, name string, record []byte, flush func()) *flushGroup {
+	if len(b.records) == 0 {
+		b.group = &flushGroup{done: make(chan struct{})}
+		if b.maxDelay > 0 {
+			b.timer = time.AfterFunc(b.maxDelay, flush)
+		}
+	}
+	b.records = append(b.records, bufferedRecord{id: id, name: name, offset: int64(len(b.data))})
+	b.data = append(b.data, record...)
+	b.ids[id] = true
+	if id >= b.nextID {
+		b.nextID = id + 1
+	}
+	b.nextIDRead = true
+	return b.group
+}
+
+// has reports whether a record with the ID is buffered
+func (b *appendBuffer) has(id uint64) bool {
+	return b.ids[id]
+}
+
+// full reports whether the buffer reached its record threshold
+func (b *appendBuffer) full() bool {
+	return len(b.records) >= b.maxRecords
+}
+
+// take empties the buffer and returns its records and their group, stopping the flush timer
+// The next ID is forgotten, the flush writes it to the header where the next buffered write reads it
+func (b *appendBuffer) take() ([]byte, []bufferedRecord, *flushGroup) {
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	data, records, group := b.data, b.records, b.group
+	b.data, b.records, b.group, b.nextIDRead = nil, nil, nil, false
+	clear(b.ids)
+	return data, records, group
+}
@@ -0,0 +1,198 @@
+package dao
+
+import (
+	"BinaryCRUD/backend/utils"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Audit actions
+const (
+	AuditCreate = "create"
+	AuditUpdate = "update"
+	AuditDelete = "delete"
+)
+
+// auditActions maps actions to their stored byte value
+var auditActions = []string{AuditCreate, AuditUpdate, AuditDelete}
+
+// AuditEntry records who changed what and when
+type AuditEntry struct {
+	ID         uint64
+	Timestamp  time.Time
+	Actor      string
+	Action     string
+	EntityType string
+	EntityID   uint64
+	Before     string
+	After      string
+}
+
+// AuditDAO manages the append-only audit trail binary file
+type AuditDAO struct {
+	filePath string
+	mu       sync.Mutex
+}
+
+// NewAuditDAO creates a DAO for audit.bin
+func NewAuditDAO(filePath string) *AuditDAO {
+	return &AuditDAO{filePath: filePath}
+}
+
+// Write appends an audit entry and returns its assigned ID
+// Complete record format: [recordLength(2)][ID(idSize)][tombstone(1)][timestamp(8)][action(1)]
+// [entityTypeLen(1)][entityType][entityID(idSize)][actorLen(2)][actor][beforeLen(2)][before][afterLen(2)][after]
+func (dao *AuditDAO) Write(entry AuditEntry) (uint64, error) {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	action := -1
+	for i, a := range auditActions {
+		if a == entry.Action {
+			action = i
+		}
+	}
+	if action < 0 {
+		return 0, fmt.Errorf("unknown audit action %q", entry.Action)
+	}
+	if len(entry.EntityType) > 255 {
+		return 0, fmt.Errorf("entity type too long")
+	}
+
+	timestampBytes, err := utils.WriteFixedNumber(8, uint64(entry.Timestamp.UnixNano()))
+	if err != nil {
+		return 0, fmt.Errorf("failed to write timestamp: %w", err)
+	}
+
+	if err := utils.EnsureFileExists(dao.filePath); err != nil {
+		return 0, err
+	}
+
+	file, err := os.OpenFile(dao.filePath, os.O_RDWR, 0644)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open audit file: %w", err)
+	}
+	defer file.Close()
+
+	nextId, err := utils.NextID(file)
+	if err != nil {
+		return 0, err
+	}
+	idSize, err := utils.ReadIDSize(file)
+	if err != nil {
+		return 0, err
+	}
+	entityIDBytes, err := utils.WriteFixedNumber(idSize, entry.EntityID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to write entity ID: %w", err)
+	}
+
+	data := utils.CombineBytes(timestampBytes, []byte{byte(action), byte(len(entry.EntityType))}, []byte(entry.EntityType), entityIDBytes)
+	for _, field := range []string{entry.Actor, entry.Before, entry.After} {
+		sizeBytes, err := utils.WriteFixedNumber(2, uint64(len(field)))
+		if err != nil {
+			return 0, fmt.Errorf("audit field too long: %w", err)
+		}
+		data = append(data, sizeBytes...)
+		data = append(data, field...)
+	}
+
+	if err := utils.AppendEntry(file, data); err != nil {
+		return 0, fmt.Errorf("failed to append audit entry: %w", err)
+	}
+
+	return nextId, nil
+}
+
+// Query returns audit entries in chronological order
+// An empty entityType matches all types, a nil entityID matches all IDs
+func (dao *AuditDAO) Query(entityType string, entityID *uint64) ([]AuditEntry, error) {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	if _, err := os.Stat(dao.filePath); os.IsNotExist(err) {
+		return []AuditEntry{}, nil
+	}
+
+	entries, err := utils.SplitFileIntoEntries(dao.filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+
+	result := make([]AuditEntry, 0, len(entries))
+	for _, entry := range entries {
+		audit, err := parseAuditEntry(entry.Data, entry.IDSize)
+		if err != nil {
+			continue
+		}
+		if entityType != "" && audit.EntityType != entityType {
+			continue
+		}
+		if entityID != nil && audit.EntityID != *entityID {
+			continue
+		}
+		result = append(result, *audit)
+	}
+
+	return result, nil
+}
+
+// parseAuditEntry parses an audit record (without length prefix) of a file with IDs of idSize bytes
+func parseAuditEntry(data []byte, idSize int) (*AuditEntry, error) {
+	id, offset, err := utils.ReadFixedNumber(idSize, data, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ID: %w", err)
+	}
+	offset += utils.TombstoneSize
+
+	nanos, offset, err := utils.ReadFixedNumber(8, data, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read timestamp: %w", err)
+	}
+
+	action, offset, err := utils.ReadFixedNumber(1, data, offset)
+	if err != nil || int(action) >= len(auditActions) {
+		return nil, fmt.Errorf("invalid action")
+	}
+
+	typeLen, offset, err := utils.ReadFixedNumber(1, data, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read entity type length: %w", err)
+	}
+	if offset+int(typeLen) > len(data) {
+		return nil, fmt.Errorf("entity type exceeds record")
+	}
+	entityType := string(data[offset : offset+int(typeLen)])
+	offset += int(typeLen)
+
+	entityID, offset, err := utils.ReadFixedNumber(idSize, data, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read entity ID: %w", err)
+	}
+
+	fields := make([]string, 3)
+	for i := range fields {
+		size, next, err := utils.ReadFixedNumber(2, data, offset)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read field size: %w", err)
+		}
+		if next+int(size) > len(data) {
+			return nil, fmt.Errorf("field exceeds record")
+		}
+		fields[i] = string(data[next : next+int(size)])
+		offset = next + int(size)
+	}
+
+	return &AuditEntry{
+		ID:         id,
+		Timestamp:  time.Unix(0, int64(nanos)).UTC(),
+		Actor:      fields[0],
+		Action:     auditActions[action],
+		EntityType: entityType,
+		EntityID:   entityID,
+		Before:     fields[1],
+		After:      fields[2],
+	}, nil
+}
@@ -2,16 +2,18 @@ package dao
 
 import (
 	"BinaryCRUD/backend/crypto"
-	"BinaryCRUD/backend/index"
 	"BinaryCRUD/backend/utils"
+	"context"
 	"fmt"
 	"os"
-	"sync"
+	"slices"
+	"sort"
 )
 
-// Collection represents either an Order or Promotion
+// Collection represents an Order, Promotion or order Template
 // - For Orders: OwnerOrName is the customer name
 // - For Promotions: OwnerOrName is the promotion name
+// - For Templates: OwnerOrName is the template name
 type Collection struct {
 	ID          uint64
 	OwnerOrName string
@@ -19,136 +21,151 @@ type Collection struct {
 	ItemCount   uint64
 	ItemIDs     []uint64
 	IsDeleted   bool
+	Extensions  map[byte][]byte // optional fields such as a promotion discount
 }
 
-type CollectionDAO struct {
-	filePath  string
-	indexPath string
-	mu        sync.Mutex
-	tree      *index.BTree     // B+ tree index for fast lookups
-	crypto    *crypto.SimpleRSA // Cached crypto instance
+// CollectionMeta is the summary of a collection, read without decrypting its name
+type CollectionMeta struct {
+	ID         uint64
+	TotalPrice uint64
+	ItemCount  uint64
+	IsDeleted  bool
 }
 
-// ensureFileExists creates the file with empty header if it doesn't exist
-func (dao *CollectionDAO) ensureFileExists() error {
-	return utils.EnsureFileExists(dao.filePath)
+// CollectionDAO manages a collection file (orders, promotions or templates) on top of the shared record plumbing
+type CollectionDAO struct {
+	recordFile
+	nameHashes map[string][]uint64 // active collections by name hash, built on first search
+	hashOf     map[uint64]string   // name hash of every collection in nameHashes
+	byItem     map[uint64][]uint64 // active collections by listed item ID, built on first lookup
+	itemsOf    map[uint64][]uint64 // listed item IDs of every collection in byItem
 }
 
-// getCrypto returns the cached crypto instance, initializing it on first use
-func (dao *CollectionDAO) getCrypto() (*crypto.SimpleRSA, error) {
-	if dao.crypto != nil {
-		return dao.crypto, nil
+// CollectionOption configures a CollectionDAO
+type CollectionOption func(*CollectionDAO)
+
+// WithEncryption sets whether names are encrypted, recorded in the header when the file is created
+// An existing file keeps the setting it was created with
+func WithEncryption(enabled bool) CollectionOption {
+	return func(dao *CollectionDAO) {
+		dao.encrypt = &enabled
 	}
-	rsaCrypto, err := crypto.GetInstance()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get RSA crypto instance: %w", err)
+}
+
+// newCollectionDAO creates a CollectionDAO for filePath with its B+ tree index; kind names its records in errors
+func newCollectionDAO(kind, filePath string, opts []CollectionOption) *CollectionDAO {
+	dao := &CollectionDAO{}
+	dao.init(kind, filePath, utils.LoadCollectionDAOIndex, utils.RebuildCollectionBTreeIndex)
+	for _, opt := range opts {
+		opt(dao)
 	}
-	dao.crypto = rsaCrypto
-	return rsaCrypto, nil
+	return dao
 }
 
 // Write creates a new collection entry and returns the assigned ID
 // Complete record format: [recordLength(2)][ID(2)][tombstone(1)][nameLength(2)][name(encrypted)...][totalPrice(4)][itemCount(4)][itemIDs...]
-// Note: The ownerOrName field is RSA-encrypted before being stored
+// Note: The ownerOrName field is AES-GCM encrypted before being stored
 func (dao *CollectionDAO) Write(ownerOrName string, totalPrice uint64, itemIDs []uint64) (uint64, error) {
+	ownerOrName, err := checkRecord(dao.kind, ownerOrName, totalPrice, len(itemIDs))
+	if err != nil {
+		return 0, err
+	}
+
 	dao.mu.Lock()
 	defer dao.mu.Unlock()
 
-	// Ensure file exists
-	if err := dao.ensureFileExists(); err != nil {
-		return 0, err
-	}
+	return dao.appendUnlocked(nil, ownerOrName, totalPrice, itemIDs, nil)
+}
 
-	// Open file for read/write
-	file, err := os.OpenFile(dao.filePath, os.O_RDWR, 0644)
-	if err != nil {
-		return 0, fmt.Errorf("failed to open collection file: %w", err)
-	}
-	defer file.Close()
+// WriteWithID creates a collection entry using an explicit ID instead of the next auto-assigned one
+// Fails if an active collection with the same ID already exists
+func (dao *CollectionDAO) WriteWithID(id uint64, ownerOrName string, totalPrice uint64, itemIDs []uint64) error {
+	_, err := dao.WriteExtended(&id, ownerOrName, totalPrice, itemIDs, nil)
+	return err
+}
 
-	// Encrypt the ownerOrName field using RSA
-	rsaCrypto, err := dao.getCrypto()
+// WriteExtended creates a collection entry with extension fields stored in the record trailer
+// A nil id assigns the next ID, an explicit id must not belong to an active collection
+func (dao *CollectionDAO) WriteExtended(id *uint64, ownerOrName string, totalPrice uint64, itemIDs []uint64, ext map[byte][]byte) (uint64, error) {
+	ownerOrName, err := checkRecord(dao.kind, ownerOrName, totalPrice, len(itemIDs))
 	if err != nil {
 		return 0, err
 	}
 
-	encryptedName, err := rsaCrypto.EncryptToBytes(ownerOrName)
-	if err != nil {
-		return 0, fmt.Errorf("failed to encrypt name: %w", err)
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	if id != nil {
+		if _, found := dao.tree.get().Search(*id); found {
+			return 0, utils.WithCode(utils.CodeConflict, fmt.Errorf("%s with ID %d already exists", dao.kind, *id), utils.RecordDetails(dao.kind, *id))
+		}
 	}
 
-	// Build entry without ID and tombstone: [nameLength(2)][name(encrypted)...][totalPrice(4)][itemCount(4)][itemIDs...]
-	// ID, tombstone, and record length will be added by AppendEntry
+	return dao.appendUnlocked(id, ownerOrName, totalPrice, itemIDs, ext)
+}
 
-	// Encrypted name size (2 bytes)
-	nameSize := len(encryptedName)
-	nameSizeBytes, err := utils.WriteFixedNumber(2, uint64(nameSize))
+// appendUnlocked appends a collection record and indexes it (must be called with lock held)
+// A nil id means the next ID from the header is used
+func (dao *CollectionDAO) appendUnlocked(id *uint64, ownerOrName string, totalPrice uint64, itemIDs []uint64, ext map[byte][]byte) (uint64, error) {
+	// Encrypt the ownerOrName field when the file stores encrypted names
+	fieldCipher, err := dao.getCrypto()
 	if err != nil {
-		return 0, fmt.Errorf("failed to write name size: %w", err)
+		return 0, err
 	}
-
-	// Encrypted name (variable length)
-	nameBytes := encryptedName
-
-	// Total price (4 bytes)
-	totalPriceBytes, err := utils.WriteFixedNumber(4, totalPrice)
+	encrypted, err := dao.namesEncrypted()
 	if err != nil {
-		return 0, fmt.Errorf("failed to write total price: %w", err)
+		return 0, err
 	}
 
-	// Item count (4 bytes)
-	itemCount := uint64(len(itemIDs))
-	itemCountBytes, err := utils.WriteFixedNumber(4, itemCount)
+	encryptedName, err := fieldCipher.EncryptToBytesIf(encrypted, ownerOrName)
 	if err != nil {
-		return 0, fmt.Errorf("failed to write item count: %w", err)
+		return 0, fmt.Errorf("failed to encrypt name: %w", err)
 	}
 
-	// Combine base fields
-	entry := utils.CombineBytes(nameSizeBytes, nameBytes, totalPriceBytes, itemCountBytes)
-
-	// Add all item IDs (2 bytes each)
-	for _, itemID := range itemIDs {
-		itemIDBytes, err := utils.WriteFixedNumber(utils.IDSize, itemID)
+	// An encrypted name is stored with its hash, so searches by name do not have to decrypt it
+	if encrypted {
+		nameHash, err := utils.NameHash(ownerOrName)
 		if err != nil {
-			return 0, fmt.Errorf("failed to write item ID: %w", err)
+			return 0, err
 		}
-		entry = append(entry, itemIDBytes...)
+		ext = utils.WithExtension(ext, utils.ExtNameHash, nameHash)
+	} else if _, ok := ext[utils.ExtNameHash]; ok {
+		ext = utils.WithExtension(ext, utils.ExtNameHash, nil)
 	}
 
-	// Read header to get the next ID
-	_, _, _, nextId, err := utils.ReadHeader(file)
+	idSize, err := dao.idSize()
 	if err != nil {
-		return 0, fmt.Errorf("failed to read header: %w", err)
+		return 0, err
 	}
 
-	// Seek back to end
-	_, err = file.Seek(0, 2)
+	// Build entry without ID and tombstone, compressed when large and record compression is enabled
+	// ID, tombstone, and record length will be added when the entry is written
+	entry, err := utils.CollectionCodec.Encode(&utils.Collection{
+		OwnerOrName: string(encryptedName),
+		TotalPrice:  totalPrice,
+		ItemIDs:     itemIDs,
+		Extensions:  ext,
+	}, idSize)
 	if err != nil {
-		return 0, fmt.Errorf("failed to seek to end: %w", err)
+		return 0, err
 	}
 
-	// Get actual append position
-	appendPos, err := file.Seek(0, 1)
+	assignedID, err := dao.writeEntryUnlocked(id, entry)
 	if err != nil {
-		return 0, fmt.Errorf("failed to get append position: %w", err)
+		return 0, err
 	}
-
-	// Append the entry (ID and tombstone auto-assigned, record separator added)
-	err = utils.AppendEntry(file, entry)
-	if err != nil {
-		return 0, fmt.Errorf("failed to append collection: %w", err)
+	if dao.nameHashes != nil {
+		key, err := nameKey(ownerOrName, ext)
+		if err != nil {
+			dao.nameHashes, dao.hashOf = nil, nil
+		} else {
+			dao.indexName(key, assignedID)
+		}
 	}
-
-	// Add to B+ tree index: ID -> file offset
-	dao.tree.Insert(uint64(nextId), appendPos)
-
-	// Save index to disk
-	err = dao.tree.Save(dao.indexPath)
-	if err != nil {
-		return 0, fmt.Errorf("failed to save index: %w", err)
+	if dao.byItem != nil {
+		dao.indexItems(assignedID, itemIDs)
 	}
-
-	return uint64(nextId), nil
+	return assignedID, nil
 }
 
 // Read retrieves a collection by ID using B+ tree index with automatic fallback to sequential scan
@@ -161,54 +178,38 @@ func (dao *CollectionDAO) Read(id uint64) (*Collection, error) {
 
 // readUnlocked is the internal implementation (must be called with lock held)
 func (dao *CollectionDAO) readUnlocked(id uint64) (*Collection, error) {
-	// Open file for reading (don't create if it doesn't exist)
-	file, err := os.OpenFile(dao.filePath, os.O_RDONLY, 0644)
+	// Try the B+ tree index first, falling back to a sequential scan
+	entryData, err := dao.readEntryUnlocked(id)
 	if err != nil {
-		if os.IsNotExist(err) {
-			return nil, fmt.Errorf("failed to open collection file: file does not exist")
-		}
-		return nil, fmt.Errorf("failed to open collection file: %w", err)
-	}
-	defer file.Close()
-
-	var entryData []byte
-
-	// Try B+ tree index first
-	if offset, found := dao.tree.Search(id); found {
-		var readErr error
-		entryData, readErr = utils.ReadEntryAtOffset(file, offset)
-		if readErr != nil {
-			// Index may be stale, fall back to sequential scan
-			entryData = nil
-		}
+		return nil, err
 	}
-
-	// If index lookup failed or returned no data, fall back to sequential scan
-	if entryData == nil {
-		entryData, err = utils.FindByIDSequential(file, id)
-		if err != nil {
-			return nil, fmt.Errorf("collection not found: %w", err)
-		}
+	idSize, err := dao.handle.ids()
+	if err != nil {
+		return nil, err
 	}
 
 	// Parse the entry (returns encrypted name)
-	collection, err := utils.ParseCollectionEntry(entryData)
+	collection, err := utils.CollectionCodec.Decode(entryData, idSize)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse collection entry: %w", err)
 	}
 
 	// Check if deleted
 	if collection.Tombstone != 0x00 {
-		return nil, fmt.Errorf("collection with ID %d is deleted", collection.ID)
+		return nil, utils.WithCode(utils.CodeDeleted, fmt.Errorf("%s with ID %d is deleted", dao.kind, collection.ID), utils.RecordDetails(dao.kind, collection.ID))
 	}
 
-	// Decrypt the ownerOrName field using RSA
-	rsaCrypto, err := dao.getCrypto()
+	// Decrypt the ownerOrName field
+	fieldCipher, err := dao.getCrypto()
+	if err != nil {
+		return nil, err
+	}
+	encrypted, err := dao.namesEncrypted()
 	if err != nil {
 		return nil, err
 	}
 
-	decryptedName, err := rsaCrypto.DecryptFromBytes([]byte(collection.OwnerOrName))
+	decryptedName, err := fieldCipher.DecryptFromBytesIf(encrypted, []byte(collection.OwnerOrName))
 	if err != nil {
 		return nil, fmt.Errorf("failed to decrypt name: %w", err)
 	}
@@ -219,6 +220,7 @@ func (dao *CollectionDAO) readUnlocked(id uint64) (*Collection, error) {
 		TotalPrice:  collection.TotalPrice,
 		ItemCount:   collection.ItemCount,
 		ItemIDs:     collection.ItemIDs,
+		Extensions:  collection.Extensions,
 	}, nil
 }
 
@@ -227,52 +229,468 @@ func (dao *CollectionDAO) Delete(id uint64) error {
 	dao.mu.Lock()
 	defer dao.mu.Unlock()
 
-	return utils.DeleteFromBTreeIndex(dao.tree, dao.indexPath, dao.filePath, id, "collection")
+	return dao.deleteUnlocked(id)
+}
+
+// deleteUnlocked tombstones a collection and drops it from the name index (must be called with lock held)
+func (dao *CollectionDAO) deleteUnlocked(id uint64) error {
+	if err := dao.recordFile.deleteUnlocked(id); err != nil {
+		return err
+	}
+	dao.unindexName(id)
+	dao.unindexItems(id)
+	return nil
+}
+
+// ReplaceFile swaps the data file for the file at path like recordFile.ReplaceFile, dropping the name and item indexes
+func (dao *CollectionDAO) ReplaceFile(path string, prepare func() error) error {
+	return dao.recordFile.ReplaceFile(path, func() error {
+		dao.nameHashes, dao.hashOf = nil, nil
+		dao.byItem, dao.itemsOf = nil, nil
+		if prepare != nil {
+			return prepare()
+		}
+		return nil
+	})
+}
+
+// FindByName returns the IDs of active collections whose normalized name matches name
+// Encrypted names are matched by their stored hash, so only collections written before hashes were stored
+// are decrypted, once, when the index is built on first use
+func (dao *CollectionDAO) FindByName(name string) ([]uint64, error) {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	if dao.nameHashes == nil {
+		if err := dao.buildNameIndex(); err != nil {
+			return nil, err
+		}
+	}
+
+	key, err := nameKey(name, nil)
+	if err != nil {
+		return nil, err
+	}
+	return append([]uint64{}, dao.nameHashes[key]...), nil
+}
+
+// buildNameIndex scans the file and indexes the name hashes of all active collections (must be called with lock held)
+func (dao *CollectionDAO) buildNameIndex() error {
+	dao.nameHashes = make(map[string][]uint64)
+	dao.hashOf = make(map[uint64]string)
+	if _, err := os.Stat(dao.filePath); os.IsNotExist(err) {
+		return nil
+	}
+
+	fieldCipher, err := dao.getCrypto()
+	if err != nil {
+		return err
+	}
+	encrypted, err := dao.namesEncrypted()
+	if err != nil {
+		return err
+	}
+
+	// A rewritten record appears again later in the file, so the latest version of each ID wins
+	latest := make(map[uint64]*utils.Collection)
+	var order []uint64
+	err = utils.ScanFileEntries(dao.filePath, func(entry utils.EntryInfo) error {
+		collection, err := utils.CollectionCodec.Decode(entry.Data, entry.IDSize)
+		if err != nil {
+			return nil
+		}
+		if _, seen := latest[collection.ID]; !seen {
+			order = append(order, collection.ID)
+		}
+		latest[collection.ID] = collection
+		return nil
+	})
+	if err != nil {
+		dao.nameHashes, dao.hashOf = nil, nil
+		return fmt.Errorf("failed to build name index: %w", err)
+	}
+
+	for _, id := range order {
+		collection := latest[id]
+		if collection.Tombstone != 0x00 {
+			continue
+		}
+		name := collection.OwnerOrName
+		if _, hashed := collection.Extensions[utils.ExtNameHash]; !hashed {
+			// If decryption fails, use the raw value like GetAll (might be old unencrypted data)
+			if decrypted, err := fieldCipher.DecryptFromBytesIf(encrypted, []byte(name)); err == nil {
+				name = decrypted
+			}
+		}
+		key, err := nameKey(name, collection.Extensions)
+		if err != nil {
+			dao.nameHashes, dao.hashOf = nil, nil
+			return err
+		}
+		dao.indexName(key, id)
+	}
+	return nil
+}
+
+// nameKey returns the name index key of a collection: its stored name hash, or the hash of its plain name
+func nameKey(name string, ext map[byte][]byte) (string, error) {
+	if hash, ok := ext[utils.ExtNameHash]; ok {
+		return string(hash), nil
+	}
+	hash, err := utils.NameHash(name)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// indexName records an active collection in the name index
+func (dao *CollectionDAO) indexName(key string, id uint64) {
+	dao.unindexName(id)
+	dao.nameHashes[key] = append(dao.nameHashes[key], id)
+	dao.hashOf[id] = key
+}
+
+// unindexName drops a collection from the name index if it has been built
+func (dao *CollectionDAO) unindexName(id uint64) {
+	key, ok := dao.hashOf[id]
+	if !ok {
+		return
+	}
+	delete(dao.hashOf, id)
+	ids := dao.nameHashes[key]
+	for i, existing := range ids {
+		if existing == id {
+			dao.nameHashes[key] = append(ids[:i:i], ids[i+1:]...)
+			break
+		}
+	}
+	if len(dao.nameHashes[key]) == 0 {
+		delete(dao.nameHashes, key)
+	}
+}
+
+// FindByItem returns the IDs of active collections that list the item, in ascending order
+// Uses the in-memory item index, which is built from the file on first use
+func (dao *CollectionDAO) FindByItem(itemID uint64) ([]uint64, error) {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	if dao.byItem == nil {
+		if err := dao.buildItemIndex(); err != nil {
+			return nil, err
+		}
+	}
+	ids := append([]uint64{}, dao.byItem[itemID]...)
+	slices.Sort(ids)
+	return ids, nil
+}
+
+// buildItemIndex scans the active records and indexes the items each collection lists (must be called with lock held)
+func (dao *CollectionDAO) buildItemIndex() error {
+	dao.byItem = make(map[uint64][]uint64)
+	dao.itemsOf = make(map[uint64][]uint64)
+	if _, err := os.Stat(dao.filePath); os.IsNotExist(err) {
+		return nil
+	}
+
+	err := dao.scanEntries(context.Background(), true, func(entry utils.EntryInfo) error {
+		collection, err := utils.CollectionCodec.Decode(entry.Data, entry.IDSize)
+		if err != nil || collection.Tombstone != 0x00 {
+			return nil
+		}
+		dao.indexItems(collection.ID, collection.ItemIDs)
+		return nil
+	})
+	if err != nil {
+		dao.byItem, dao.itemsOf = nil, nil
+		return fmt.Errorf("failed to build item index: %w", err)
+	}
+	return nil
+}
+
+// indexItems records an active collection under every item it lists, once per item
+func (dao *CollectionDAO) indexItems(id uint64, itemIDs []uint64) {
+	dao.unindexItems(id)
+	listed := make([]uint64, 0, len(itemIDs))
+	for _, itemID := range itemIDs {
+		if slices.Contains(listed, itemID) {
+			continue
+		}
+		listed = append(listed, itemID)
+		dao.byItem[itemID] = append(dao.byItem[itemID], id)
+	}
+	dao.itemsOf[id] = listed
+}
+
+// unindexItems drops a collection from the item index if it has been built
+func (dao *CollectionDAO) unindexItems(id uint64) {
+	listed, ok := dao.itemsOf[id]
+	if !ok {
+		return
+	}
+	delete(dao.itemsOf, id)
+	for _, itemID := range listed {
+		ids := dao.byItem[itemID]
+		for i, existing := range ids {
+			if existing == id {
+				dao.byItem[itemID] = append(ids[:i:i], ids[i+1:]...)
+				break
+			}
+		}
+		if len(dao.byItem[itemID]) == 0 {
+			delete(dao.byItem, itemID)
+		}
+	}
+}
+
+// Update rewrites an existing collection with a new name, total and item list, keeping its ID
+// and extension fields. The old record is tombstoned and the new version is written into a free slot or appended
+func (dao *CollectionDAO) Update(id uint64, ownerOrName string, totalPrice uint64, itemIDs []uint64) error {
+	ownerOrName, err := checkRecord(dao.kind, ownerOrName, totalPrice, len(itemIDs))
+	if err != nil {
+		return err
+	}
+
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	current, err := dao.readUnlocked(id)
+	if err != nil {
+		return err
+	}
+
+	return dao.replaceUnlocked(id, ownerOrName, totalPrice, itemIDs, current.Extensions)
+}
+
+// UpdateExtensions rewrites an existing collection with new extension fields
+func (dao *CollectionDAO) UpdateExtensions(id uint64, ext map[byte][]byte) error {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	current, err := dao.readUnlocked(id)
+	if err != nil {
+		return err
+	}
+
+	return dao.replaceUnlocked(id, current.OwnerOrName, current.TotalPrice, current.ItemIDs, ext)
+}
+
+// replaceUnlocked tombstones the current record and appends its new version (must be called with lock held)
+func (dao *CollectionDAO) replaceUnlocked(id uint64, ownerOrName string, totalPrice uint64, itemIDs []uint64, ext map[byte][]byte) error {
+	if err := dao.deleteUnlocked(id); err != nil {
+		return err
+	}
+
+	_, err := dao.appendUnlocked(&id, ownerOrName, totalPrice, itemIDs, ext)
+	return err
+}
+
+// ReencryptNames rewrites the active collections whose names are still encrypted with the legacy RSA scheme
+// Returns the number of collections rewritten
+func (dao *CollectionDAO) ReencryptNames() (int, error) {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	if _, err := os.Stat(dao.filePath); os.IsNotExist(err) {
+		return 0, nil
+	}
+	encrypted, err := dao.namesEncrypted()
+	if err != nil || !encrypted {
+		return 0, err
+	}
+
+	entries, err := utils.SplitFileIntoEntries(dao.filePath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read collections: %w", err)
+	}
+
+	var legacy []uint64
+	for _, entry := range entries {
+		collection, err := utils.CollectionCodec.Decode(entry.Data, entry.IDSize)
+		if err != nil || collection.Tombstone != 0x00 {
+			continue
+		}
+		if crypto.IsLegacyCiphertext([]byte(collection.OwnerOrName)) {
+			legacy = append(legacy, collection.ID)
+		}
+	}
+
+	for i, id := range legacy {
+		current, err := dao.readUnlocked(id)
+		if err != nil {
+			return i, err
+		}
+		if err := dao.replaceUnlocked(id, current.OwnerOrName, current.TotalPrice, current.ItemIDs, current.Extensions); err != nil {
+			return i, fmt.Errorf("failed to re-encrypt collection %d: %w", id, err)
+		}
+	}
+	return len(legacy), nil
 }
 
 // GetAll retrieves all collections from the database, including deleted ones
 func (dao *CollectionDAO) GetAll() ([]*Collection, error) {
+	return dao.GetAllContext(context.Background())
+}
+
+// GetAllContext is GetAll, stopping with the context error once ctx is cancelled
+func (dao *CollectionDAO) GetAllContext(ctx context.Context) ([]*Collection, error) {
+	return dao.getAll(ctx, false)
+}
+
+// GetAllActive retrieves the collections that are not deleted, in file order
+// Deleted records are skipped through the tombstone bitmap without being decrypted or parsed
+func (dao *CollectionDAO) GetAllActive(ctx context.Context) ([]*Collection, error) {
+	return dao.getAll(ctx, true)
+}
+
+// GetAllActiveByID retrieves the collections that are not deleted in ascending ID order, read through
+// the B+ tree index without scanning the file; an index behind the file falls back to a sorted scan
+func (dao *CollectionDAO) GetAllActiveByID(ctx context.Context) ([]*Collection, error) {
 	dao.mu.Lock()
 	defer dao.mu.Unlock()
 
+	entries, idSize, ok, err := dao.activeEntriesByID(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read collections: %w", err)
+	}
+	if !ok {
+		collections, err := dao.getAllUnlocked(ctx, true)
+		if err != nil {
+			return nil, err
+		}
+		sort.Slice(collections, func(i, j int) bool { return collections[i].ID < collections[j].ID })
+		return collections, nil
+	}
+	if len(entries) == 0 {
+		return []*Collection{}, nil
+	}
+
+	fieldCipher, err := dao.getCrypto()
+	if err != nil {
+		return nil, err
+	}
+	encrypted, err := dao.namesEncrypted()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*Collection, 0, len(entries))
+	for _, entryData := range entries {
+		collection, err := utils.CollectionCodec.Decode(entryData, idSize)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s entry: %w", dao.kind, err)
+		}
+		result = append(result, collectionFromRecord(collection, fieldCipher, encrypted))
+	}
+	return result, nil
+}
+
+// collectionFromRecord converts a decoded collection record, decrypting its name
+func collectionFromRecord(collection *utils.Collection, fieldCipher *crypto.FieldCipher, encrypted bool) *Collection {
+	decryptedName, err := fieldCipher.DecryptFromBytesIf(encrypted, []byte(collection.OwnerOrName))
+	if err != nil {
+		// If decryption fails, use the raw value (might be old unencrypted data)
+		decryptedName = collection.OwnerOrName
+	}
+
+	return &Collection{
+		ID:          collection.ID,
+		OwnerOrName: decryptedName,
+		TotalPrice:  collection.TotalPrice,
+		ItemCount:   collection.ItemCount,
+		ItemIDs:     collection.ItemIDs,
+		IsDeleted:   collection.Tombstone != 0x00,
+		Extensions:  collection.Extensions,
+	}
+}
+
+// getAll scans the collections, only the active ones with activeOnly
+func (dao *CollectionDAO) getAll(ctx context.Context, activeOnly bool) ([]*Collection, error) {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+	return dao.getAllUnlocked(ctx, activeOnly)
+}
+
+// getAllUnlocked is getAll (must be called with lock held)
+func (dao *CollectionDAO) getAllUnlocked(ctx context.Context, activeOnly bool) ([]*Collection, error) {
 	// Check if file exists
 	if _, err := os.Stat(dao.filePath); os.IsNotExist(err) {
 		return []*Collection{}, nil
 	}
 
-	// Get RSA crypto instance for decryption
-	rsaCrypto, err := dao.getCrypto()
+	// Get the field cipher for decryption
+	fieldCipher, err := dao.getCrypto()
 	if err != nil {
 		return nil, err
 	}
-
-	// Use utility to split file into entries
-	entries, err := utils.SplitFileIntoEntries(dao.filePath)
+	encrypted, err := dao.namesEncrypted()
 	if err != nil {
-		return nil, fmt.Errorf("failed to read collections: %w", err)
+		return nil, err
 	}
 
-	result := make([]*Collection, 0, len(entries))
-	for _, entry := range entries {
-		collection, err := utils.ParseCollectionEntry(entry.Data)
+	// Scan the entries, parsing each one (the file may be memory-mapped)
+	result := make([]*Collection, 0)
+	positions := make(map[uint64]int)
+	err = dao.scanEntries(ctx, activeOnly, func(entry utils.EntryInfo) error {
+		if activeOnly && !activeRecord(entry.Data, entry.IDSize) {
+			return nil
+		}
+		collection, err := utils.CollectionCodec.Decode(entry.Data, entry.IDSize)
 		if err == nil {
-			// Decrypt the ownerOrName field
-			decryptedName, err := rsaCrypto.DecryptFromBytes([]byte(collection.OwnerOrName))
-			if err != nil {
-				// If decryption fails, use the raw value (might be old unencrypted data)
-				decryptedName = collection.OwnerOrName
+			c := collectionFromRecord(collection, fieldCipher, encrypted)
+
+			// A rewritten record appears again later in the file, keep only the latest version
+			if pos, seen := positions[c.ID]; seen {
+				result[pos] = c
+				return nil
 			}
+			positions[c.ID] = len(result)
+			result = append(result, c)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read collections: %w", err)
+	}
+
+	return result, nil
+}
 
-			result = append(result, &Collection{
-				ID:          collection.ID,
-				OwnerOrName: decryptedName,
-				TotalPrice:  collection.TotalPrice,
-				ItemCount:   collection.ItemCount,
-				ItemIDs:     collection.ItemIDs,
-				IsDeleted:   collection.Tombstone != 0x00,
-			})
+// GetAllMeta retrieves the summary of every collection, including deleted ones, like GetAll
+// Names are left encrypted and out of the result, which makes it much cheaper than GetAll for list views
+func (dao *CollectionDAO) GetAllMeta() ([]CollectionMeta, error) {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	result := make([]CollectionMeta, 0)
+	positions := make(map[uint64]int)
+	err := utils.ScanFileEntries(dao.filePath, func(entry utils.EntryInfo) error {
+		collection, err := utils.CollectionCodec.Decode(entry.Data, entry.IDSize)
+		if err != nil {
+			return nil
 		}
+
+		meta := CollectionMeta{
+			ID:         collection.ID,
+			TotalPrice: collection.TotalPrice,
+			ItemCount:  collection.ItemCount,
+			IsDeleted:  collection.Tombstone != 0x00,
+		}
+
+		// A rewritten record appears again later in the file, keep only the latest version
+		if pos, seen := positions[meta.ID]; seen {
+			result[pos] = meta
+			return nil
+		}
+		positions[meta.ID] = len(result)
+		result = append(result, meta)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read collections: %w", err)
 	}
 
 	return result, nil
 }
+
@@ -0,0 +1,60 @@
+package dao
+
+import (
+	"BinaryCRUD/backend/utils"
+	"os"
+)
+
+// fileHandle keeps the backing file of a DAO open between calls
+// It is not safe for concurrent use; the DAO lock protects it and every user of the returned file
+type fileHandle struct {
+	path   string
+	file   *os.File
+	idSize int // ID width read from the header of the open file, 0 until read
+}
+
+// get returns the open file, opening it on first use
+// The file is opened for reading and writing, or read-only when writing is not permitted
+// A missing file is reported with an error satisfying os.IsNotExist and is not created
+func (h *fileHandle) get() (*os.File, error) {
+	if h.file != nil {
+		return h.file, nil
+	}
+	file, err := os.OpenFile(h.path, os.O_RDWR, 0644)
+	if os.IsPermission(err) {
+		file, err = os.Open(h.path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	h.file = file
+	return file, nil
+}
+
+// ids returns the ID width of the file, opening it on first use and reading it from the header once
+func (h *fileHandle) ids() (int, error) {
+	if h.idSize != 0 {
+		return h.idSize, nil
+	}
+	file, err := h.get()
+	if err != nil {
+		return 0, err
+	}
+	idSize, err := utils.ReadIDSize(file)
+	if err != nil {
+		return 0, err
+	}
+	h.idSize = idSize
+	return idSize, nil
+}
+
+// close closes the file if it is open; the next get reopens it
+func (h *fileHandle) close() error {
+	h.idSize = 0
+	if h.file == nil {
+		return nil
+	}
+	err := h.file.Close()
+	h.file = nil
+	return err
+}
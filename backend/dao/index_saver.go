@@ -0,0 +1,90 @@
+package dao
+
+import (
+	"BinaryCRUD/backend/utils"
+	"time"
+)
+
+// indexSaveDelay is how long an index may lag behind its data file before it is saved, so a burst of
+// writes saves it once
+const indexSaveDelay = 100 * time.Millisecond
+
+// indexSaver batches the saves of the index of a DAO. The first change after a save leaves the dirty
+// marker of the index and starts a timer; the save at its end clears the marker, so the marker only
+// exists while the index on disk is behind the data file
+// It is not safe for concurrent use; the DAO lock protects it
+type indexSaver struct {
+	indexPath string
+	dirty     bool // the marker is on disk
+	unsaved   bool // the index has changes the file doesn't
+	timer     *time.Timer
+}
+
+// markDirty leaves the dirty marker before the data file changes, unless it is already there
+func (s *indexSaver) markDirty() error {
+	if s.dirty {
+		return nil
+	}
+	if err := utils.MarkIndexDirty(s.indexPath); err != nil {
+		return err
+	}
+	s.dirty = true
+	return nil
+}
+
+// schedule records that the index changed and starts the timer that calls flush, unless a save is
+// already pending
+func (s *indexSaver) schedule(flush func()) {
+	s.unsaved = true
+	if s.timer == nil {
+		s.timer = time.AfterFunc(indexSaveDelay, flush)
+	}
+}
+
+// flush saves the index with save when it is behind the data file, then clears the marker
+// A failed save leaves the marker, so the index is rebuilt when the DAO is next created
+func (s *indexSaver) flush(save func() error) error {
+	s.stop()
+	if s.unsaved {
+		if err := save(); err != nil {
+			return err
+		}
+		s.unsaved = false
+	}
+	return s.clear()
+}
+
+// logged clears the marker once a change reached the index on disk some other way, e.g. its change
+// log, unless earlier changes are still waiting for a save
+func (s *indexSaver) logged() error {
+	if s.unsaved {
+		return nil
+	}
+	return s.clear()
+}
+
+// clear removes the marker
+func (s *indexSaver) clear() error {
+	if !s.dirty {
+		return nil
+	}
+	if err := utils.ClearIndexDirty(s.indexPath); err != nil {
+		return err
+	}
+	s.dirty = false
+	return nil
+}
+
+// reset forgets a pending save once the index was rebuilt from the data file, which clears the marker
+func (s *indexSaver) reset() {
+	s.stop()
+	s.dirty, s.unsaved = false, false
+}
+
+// stop cancels the pending timer
+func (s *indexSaver) stop() {
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+}
@@ -0,0 +1,34 @@
+package dao
+
+import "time"
+
+// IndexStats counts how the lookups of a DAO found their records, to spot a stale or missing index
+// It is not safe for concurrent use; the DAO lock protects it
+type IndexStats struct {
+	IndexedReads  int           `json:"indexedReads"`  // lookups answered through the index
+	FallbackScans int           `json:"fallbackScans"` // lookups that scanned the file because the index missed, was stale or was loading
+	StaleMisses   int           `json:"staleMisses"`   // fallback scans that found an active record missing from the loaded index
+	ScanTime      time.Duration `json:"scanTime"`      // total time spent in fallback scans
+	MaxScanTime   time.Duration `json:"maxScanTime"`   // longest fallback scan
+}
+
+// indexed counts a lookup answered through the index
+func (s *IndexStats) indexed() {
+	s.IndexedReads++
+}
+
+// scanned counts a fallback scan that started at start; stale reports whether it found a record the index lacked
+func (s *IndexStats) scanned(start time.Time, stale bool) {
+	elapsed := time.Since(start)
+	s.FallbackScans++
+	s.ScanTime += elapsed
+	s.MaxScanTime = max(s.MaxScanTime, elapsed)
+	if stale {
+		s.StaleMisses++
+	}
+}
+
+// activeRecord reports whether entry data found by a scan of a file with IDs of idSize bytes holds an active record
+func activeRecord(entryData []byte, idSize int) bool {
+	return len(entryData) > idSize && entryData[idSize] == 0x00
+}
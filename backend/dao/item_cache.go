@@ -0,0 +1,91 @@
+package dao
+
+import (
+	"BinaryCRUD/backend/utils"
+	"container/list"
+	"maps"
+)
+
+// itemCache keeps the most recently read items in memory, evicting the least recently used
+// It is not safe for concurrent use; the ItemDAO lock protects it
+type itemCache struct {
+	capacity int
+	order    *list.List               // front is the most recently used
+	entries  map[uint64]*list.Element // ID -> element holding a *utils.Item
+	hits     int
+	misses   int
+}
+
+// newItemCache creates a cache holding up to capacity items, 0 disables caching
+func newItemCache(capacity int) *itemCache {
+	return &itemCache{
+		capacity: max(capacity, 0),
+		order:    list.New(),
+		entries:  make(map[uint64]*list.Element),
+	}
+}
+
+// get returns a copy of a cached item and marks it as recently used
+func (c *itemCache) get(id uint64) (*utils.Item, bool) {
+	element, ok := c.entries[id]
+	if !ok {
+		c.misses++
+		return nil, false
+	}
+	c.hits++
+	c.order.MoveToFront(element)
+	return copyItem(element.Value.(*utils.Item)), true
+}
+
+// put caches a copy of an item, evicting the least recently used one when full
+func (c *itemCache) put(item *utils.Item) {
+	if c.capacity == 0 {
+		return
+	}
+	if element, ok := c.entries[item.ID]; ok {
+		element.Value = copyItem(item)
+		c.order.MoveToFront(element)
+		return
+	}
+	c.entries[item.ID] = c.order.PushFront(copyItem(item))
+	for c.order.Len() > c.capacity {
+		c.remove(c.order.Back().Value.(*utils.Item).ID)
+	}
+}
+
+// remove drops an item from the cache
+func (c *itemCache) remove(id uint64) {
+	if element, ok := c.entries[id]; ok {
+		c.order.Remove(element)
+		delete(c.entries, id)
+	}
+}
+
+// clear drops every cached item
+func (c *itemCache) clear() {
+	c.order.Init()
+	clear(c.entries)
+}
+
+// resize changes the capacity, evicting the least recently used items that no longer fit
+func (c *itemCache) resize(capacity int) {
+	c.capacity = max(capacity, 0)
+	for c.order.Len() > c.capacity {
+		c.remove(c.order.Back().Value.(*utils.Item).ID)
+	}
+}
+
+// copyItem copies an item so callers cannot change the cached version
+func copyItem(item *utils.Item) *utils.Item {
+	copied := *item
+	copied.Extensions = maps.Clone(item.Extensions)
+	return &copied
+}
+
+// CacheStats reports the usage of an item cache
+type CacheStats struct {
+	Capacity int `json:"capacity"`
+	Size     int `json:"size"`
+	Hits     int `json:"hits"`
+	Misses   int `json:"misses"`
+}
@@ -4,117 +4,312 @@ import (
 	"BinaryCRUD/backend/index"
 	"BinaryCRUD/backend/search"
 	"BinaryCRUD/backend/utils"
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"sort"
 	"strings"
-	"sync"
+	"time"
 )
 
 // ItemDAO manages the items binary file
+// The store provides the lock, file handle, index and free slots; the DAO adds the caches and group commit
 type ItemDAO struct {
-	filePath  string
-	indexPath string
-	mu        sync.Mutex    // Protects concurrent writes to the binary file
-	tree      *index.BTree  // B+ tree index for fast lookups
+	*Store[*utils.Item]
+	names       map[string][]uint64 // Normalized name -> active IDs, built on first use
+	externalIDs map[string]uint64   // External ID -> active item ID, built on first use
+	cache       *itemCache          // Recently read items, invalidated on every change
+	buffer      *appendBuffer       // New items waiting for a group commit, nil writes each item through
 }
 
-// NewItemDAO creates a new ItemDAO instance
-func NewItemDAO(filePath string) *ItemDAO {
-	indexPath, tree := utils.InitializeDAOIndex(filePath)
+// itemSerializer stores items as [nameLength(2)][name...][price(4)][extensions...] after the ID and tombstone
+// Item names are never encrypted, so the field codec is not used
+type itemSerializer struct{}
+
+// Encode builds the stored form of an item
+func (itemSerializer) Encode(_ FieldCodec, item *utils.Item) ([]byte, error) {
+	return utils.ItemCodec.Encode(item, 0)
+}
+
+// Decode parses a stored item
+func (itemSerializer) Decode(_ FieldCodec, id uint64, data []byte) (*utils.Item, error) {
+	return utils.DecodeItemFields(id, data)
+}
+
+// ItemOption configures an ItemDAO
+type ItemOption func(*ItemDAO)
 
-	return &ItemDAO{
-		filePath:  filePath,
-		indexPath: indexPath,
-		tree:      tree,
+// WithGroupCommit buffers new items and appends them together once maxRecords are waiting or maxDelay
+// after the first one, syncing the file and index once per group instead of on every write
+// A write returns once its group is flushed, with the error of the flush, so concurrent writers share one sync;
+// use WriteDurable when a write must not wait for the group to fill
+func WithGroupCommit(maxRecords int, maxDelay time.Duration) ItemOption {
+	return func(dao *ItemDAO) {
+		dao.buffer = newAppendBuffer(maxRecords, maxDelay)
 	}
 }
 
-// ensureFileExists creates the file with empty header if it doesn't exist
-func (dao *ItemDAO) ensureFileExists() error {
-	return utils.EnsureFileExists(dao.filePath)
+// NewItemDAO creates a new ItemDAO instance
+func NewItemDAO(filePath string, opts ...ItemOption) *ItemDAO {
+	dao := &ItemDAO{
+		Store: NewStore[*utils.Item]("item", filePath, itemSerializer{}),
+		cache: newItemCache(utils.ItemCacheSize),
+	}
+	for _, opt := range opts {
+		opt(dao)
+	}
+	return dao
 }
 
 // Write adds an item to the binary file and returns the assigned ID
 // Complete record structure: [recordLength(2)][ID(2)][tombstone(1)][nameLength(2)][name...][price(4)]
 // ID, tombstone, and record length are auto-assigned by AppendEntry (tombstone is 0x00 for active records)
 func (dao *ItemDAO) Write(name string, priceInCents uint64) (uint64, error) {
-	// Lock to prevent concurrent writes
+	// Lock to prevent concurrent writes, released before waiting for a group commit
+	dao.mu.Lock()
+	id, group, err := dao.writeUnlocked(nil, name, priceInCents, nil)
+	dao.mu.Unlock()
+
+	return waitForGroup(id, group, err)
+}
+
+// WriteDurable adds an item like Write, flushing it and every item buffered before it right away
+// instead of waiting for the group to fill
+func (dao *ItemDAO) WriteDurable(name string, priceInCents uint64) (uint64, error) {
 	dao.mu.Lock()
 	defer dao.mu.Unlock()
 
-	// Ensure file exists
-	if err := dao.ensureFileExists(); err != nil {
+	id, _, err := dao.writeUnlocked(nil, name, priceInCents, nil)
+	if err != nil {
+		return 0, err
+	}
+	if err := dao.flushUnlocked(); err != nil {
 		return 0, err
 	}
+	return id, nil
+}
 
-	// Open file for read/write
-	file, err := os.OpenFile(dao.filePath, os.O_RDWR, 0644)
-	if err != nil {
-		return 0, fmt.Errorf("failed to open item file: %w", err)
+// WriteWithID adds an item using an explicit ID instead of the next auto-assigned one
+// Fails if an active item with the same ID already exists
+func (dao *ItemDAO) WriteWithID(id uint64, name string, priceInCents uint64) error {
+	_, err := dao.WriteExtended(&id, name, priceInCents, nil)
+	return err
+}
+
+// WriteExtended adds an item with extension fields stored in the record trailer
+// A nil id assigns the next ID, an explicit id must not belong to an active item
+func (dao *ItemDAO) WriteExtended(id *uint64, name string, priceInCents uint64, ext map[byte][]byte) (uint64, error) {
+	dao.mu.Lock()
+	if id != nil {
+		if _, found := dao.tree.get().Search(*id); found {
+			dao.mu.Unlock()
+			return 0, utils.WithCode(utils.CodeConflict, fmt.Errorf("item with ID %d already exists", *id), utils.RecordDetails("item", *id))
+		}
 	}
-	defer file.Close()
+	assignedID, group, err := dao.writeUnlocked(id, name, priceInCents, ext)
+	dao.mu.Unlock()
 
-	// Calculate name size
-	nameSize := len(name)
+	return waitForGroup(assignedID, group, err)
+}
 
-	// Build entry without ID and tombstone: [nameLength(2)][name...][price(4)]
-	// ID and tombstone will be added by AppendEntry
+// writeUnlocked buffers a new item in group commit mode and appends it otherwise (must be called with lock held)
+// A buffered item comes with the group to wait for, after releasing the lock, before it is on disk
+func (dao *ItemDAO) writeUnlocked(id *uint64, name string, priceInCents uint64, ext map[byte][]byte) (uint64, *flushGroup, error) {
+	name, err := checkRecord("item", name, priceInCents, 0)
+	if err != nil {
+		return 0, nil, err
+	}
+	if _, ok := ext[utils.ExtExternalID]; ok {
+		// Items with an external ID skip the buffer, so its uniqueness is checked against every written item
+		if err := dao.flushUnlocked(); err != nil {
+			return 0, nil, err
+		}
+		if err := dao.checkExternalID(ext, id); err != nil {
+			return 0, nil, err
+		}
+		assignedID, err := dao.appendUnlocked(id, name, priceInCents, ext)
+		return assignedID, nil, err
+	}
+	if dao.buffer == nil {
+		assignedID, err := dao.appendUnlocked(id, name, priceInCents, ext)
+		return assignedID, nil, err
+	}
+	return dao.bufferUnlocked(id, name, priceInCents, ext)
+}
 
-	// Name size (2 bytes - supports names up to 65535 chars)
-	nameSizeBytes, err := utils.WriteFixedNumber(2, uint64(nameSize))
+// waitForGroup waits for the group commit of a write, if it was buffered, and returns the result of the write
+func waitForGroup(id uint64, group *flushGroup, err error) (uint64, error) {
 	if err != nil {
-		return 0, fmt.Errorf("failed to write name size: %w", err)
+		return 0, err
+	}
+	if err := group.wait(); err != nil {
+		return 0, err
 	}
+	return id, nil
+}
 
-	// Name (variable length)
-	nameBytes, err := utils.WriteVariable(name)
+// appendUnlocked appends an item record and indexes it (must be called with lock held)
+// A nil id means the next ID from the header is used
+func (dao *ItemDAO) appendUnlocked(id *uint64, name string, priceInCents uint64, ext map[byte][]byte) (uint64, error) {
+	// Build entry without ID and tombstone, they are added when the entry is written
+	entry, err := dao.encodeUnlocked(&utils.Item{Name: name, Price: priceInCents, Extensions: ext})
 	if err != nil {
-		return 0, fmt.Errorf("failed to write name: %w", err)
+		return 0, err
 	}
 
-	// Price (4 bytes - supports prices up to 4,294,967,295 cents)
-	priceBytes, err := utils.WriteFixedNumber(4, priceInCents)
+	assignedID, err := dao.writeEntryUnlocked(id, entry)
 	if err != nil {
-		return 0, fmt.Errorf("failed to write price: %w", err)
+		return 0, err
 	}
+	dao.addName(name, assignedID)
+	dao.addExternalID(ext, assignedID)
+	dao.cache.remove(assignedID)
+	return assignedID, nil
+}
 
-	// Combine all fields
-	entry := utils.CombineBytes(nameSizeBytes, nameBytes, priceBytes)
+// bufferUnlocked adds an item record to the append buffer, flushing the buffer once full (must be called with lock held)
+// The error of a flush is reported through the returned group, to every writer of the group
+func (dao *ItemDAO) bufferUnlocked(id *uint64, name string, priceInCents uint64, ext map[byte][]byte) (uint64, *flushGroup, error) {
+	if id != nil && dao.buffer.has(*id) {
+		return 0, nil, utils.WithCode(utils.CodeConflict, fmt.Errorf("item with ID %d already exists", *id), utils.RecordDetails("item", *id))
+	}
 
-	// Read header to get the next ID
-	_, _, _, nextId, err := utils.ReadHeader(file)
+	// Buffered records are built at the ID width of the file they are flushed to
+	if err := dao.ensureFileExists(); err != nil {
+		return 0, nil, err
+	}
+	idSize, err := dao.handle.ids()
 	if err != nil {
-		return 0, fmt.Errorf("failed to read header: %w", err)
+		return 0, nil, fmt.Errorf("failed to read item ID size: %w", err)
 	}
 
-	// Seek back to end
-	_, err = file.Seek(0, 2)
+	entry, err := dao.encodeUnlocked(&utils.Item{Name: name, Price: priceInCents, Extensions: ext})
 	if err != nil {
-		return 0, fmt.Errorf("failed to seek to end: %w", err)
+		return 0, nil, err
 	}
 
-	// Get actual append position
-	appendPos, err := file.Seek(0, 1)
+	// IDs of buffered items count up from the header and ID mark, which only change on flush
+	if !dao.buffer.nextIDRead {
+		file, err := dao.handle.get()
+		if err != nil {
+			return 0, nil, fmt.Errorf("failed to open item file: %w", err)
+		}
+		nextId, err := utils.NextID(file)
+		if err != nil {
+			return 0, nil, err
+		}
+		dao.buffer.nextID = nextId
+	}
+	assignedID := dao.buffer.nextID
+	if id != nil {
+		assignedID = *id
+	}
+
+	record, err := utils.BuildRecord(assignedID, idSize, entry)
 	if err != nil {
-		return 0, fmt.Errorf("failed to get append position: %w", err)
+		return 0, nil, err
 	}
+	group := dao.buffer.add(assignedID, name, record, dao.flushOnTimer)
+
+	if dao.buffer.full() {
+		dao.flushUnlocked()
+	}
+	return assignedID, group, nil
+}
+
+// flushUnlocked appends the buffered items in one write and indexes them (must be called with lock held)
+// The writers waiting for the group are woken up with the result
+func (dao *ItemDAO) flushUnlocked() error {
+	if dao.buffer == nil || len(dao.buffer.records) == 0 {
+		return nil
+	}
+	data, records, group := dao.buffer.take()
+
+	err := dao.writeGroupUnlocked(data, records)
+	group.finish(err)
+	return err
+}
 
-	// Append the entry (ID auto-assigned and record separator added)
-	err = utils.AppendEntry(file, entry)
+// writeGroupUnlocked appends a group of buffered records (must be called with lock held)
+// The file and header are synced once for the whole group, the index is saved with the next changes
+func (dao *ItemDAO) writeGroupUnlocked(data []byte, records []bufferedRecord) error {
+	if err := dao.ensureFileExists(); err != nil {
+		return err
+	}
+	file, err := dao.handle.get()
 	if err != nil {
-		return 0, fmt.Errorf("failed to append item: %w", err)
+		return fmt.Errorf("failed to open item file: %w", err)
 	}
 
-	// Add to index: ID -> file offset
-	dao.tree.Insert(uint64(nextId), appendPos)
+	// Record the IDs of the group as handed out before they reach the file, like AppendEntryWithID
+	var next uint64
+	for _, record := range records {
+		next = max(next, record.id+1)
+	}
+	if err := utils.RaiseIDMark(file.Name(), next); err != nil {
+		return err
+	}
 
-	// Save index to disk
-	err = dao.tree.Save(dao.indexPath)
+	start, err := file.Seek(0, io.SeekEnd)
 	if err != nil {
-		return 0, fmt.Errorf("failed to save index: %w", err)
+		return fmt.Errorf("failed to seek to end: %w", err)
+	}
+	if err := dao.markIndexDirtyUnlocked(); err != nil {
+		return err
+	}
+	if _, err := file.WriteAt(data, start); err != nil {
+		// Drop a partial group so the file does not end in a torn record
+		file.Truncate(start)
+		return fmt.Errorf("failed to append buffered items: %w", err)
+	}
+	utils.CountBytesWritten(len(data))
+	if err := file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync buffered items: %w", err)
 	}
 
-	return uint64(nextId), nil
+	// Never move nextId backwards so auto-assigned IDs stay unique, like AppendEntryWithID
+	err = utils.ModifyHeader(file, func(counts *utils.HeaderCounts) {
+		counts.EntitiesCount += len(records)
+		for _, record := range records {
+			if int(record.id) >= counts.NextId {
+				counts.NextId = int(record.id) + 1
+			}
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update header: %w", err)
+	}
+
+	tree := dao.tree.get()
+	offsets := make([]int64, len(records))
+	for i, record := range records {
+		tree.Insert(record.id, start+record.offset)
+		dao.addName(record.name, record.id)
+		dao.cache.remove(record.id)
+		offsets[i] = start + record.offset
+	}
+	dao.markSlots(false, offsets...)
+	dao.scheduleIndexSaveUnlocked()
+	return nil
+}
+
+// flushOnTimer flushes the append buffer once the delay of its first item ran out
+// A failure reaches the writers waiting for the group
+func (dao *ItemDAO) flushOnTimer() {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	dao.flushUnlocked()
+}
+
+// Flush writes the items buffered in group commit mode to disk without waiting for the group to fill
+func (dao *ItemDAO) Flush() error {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	return dao.flushUnlocked()
 }
 
 // Read retrieves an item by ID using the B+ tree index with automatic fallback to sequential scan
@@ -123,48 +318,249 @@ func (dao *ItemDAO) Read(id uint64) (uint64, string, uint64, error) {
 	dao.mu.Lock()
 	defer dao.mu.Unlock()
 
-	// Open file for reading (don't create if it doesn't exist)
-	file, err := os.OpenFile(dao.filePath, os.O_RDONLY, 0644)
+	if err := dao.flushUnlocked(); err != nil {
+		return 0, "", 0, err
+	}
+
+	item, err := dao.readUnlocked(id)
 	if err != nil {
-		if os.IsNotExist(err) {
-			return 0, "", 0, fmt.Errorf("failed to open item file: file does not exist")
-		}
-		return 0, "", 0, fmt.Errorf("failed to open item file: %w", err)
+		return 0, "", 0, err
 	}
-	defer file.Close()
 
-	var entryData []byte
+	return item.ID, item.Name, item.Price, nil
+}
+
+// ReadItem retrieves an active item by ID including its extension fields
+func (dao *ItemDAO) ReadItem(id uint64) (*Item, error) {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
 
-	// Try B+ tree index first
-	if offset, found := dao.tree.Search(id); found {
-		var readErr error
-		entryData, readErr = utils.ReadEntryAtOffset(file, offset)
-		if readErr != nil {
-			// Index may be stale, log and fall back to sequential scan
-			entryData = nil
+	if err := dao.flushUnlocked(); err != nil {
+		return nil, err
+	}
+
+	item, err := dao.readUnlocked(id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Item{
+		ID:           item.ID,
+		Name:         item.Name,
+		PriceInCents: item.Price,
+		Extensions:   item.Extensions,
+	}, nil
+}
+
+// ReadMany retrieves the active items with the given IDs, keyed by ID
+// Cached items are served from memory; the others are read in one pass over the file in offset order
+// IDs of missing or deleted items are left out of the result
+func (dao *ItemDAO) ReadMany(ids []uint64) (map[uint64]*Item, error) {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	if err := dao.flushUnlocked(); err != nil {
+		return nil, err
+	}
+
+	found := make(map[uint64]*utils.Item, len(ids))
+	type located struct {
+		id     uint64
+		offset int64
+	}
+	var pending []located
+	var unindexed []uint64
+	for _, id := range ids {
+		if _, done := found[id]; done {
+			continue
+		}
+		if item, ok := dao.cache.get(id); ok {
+			found[id] = item
+			continue
+		}
+		if offset, ok := searchIfReady(dao.tree, id); ok {
+			found[id] = nil
+			pending = append(pending, located{id, offset})
+		} else {
+			unindexed = append(unindexed, id)
 		}
 	}
 
-	// If index lookup failed or returned no data, fall back to sequential scan
-	if entryData == nil {
-		entryData, err = utils.FindByIDSequential(file, id)
+	if len(pending) > 0 {
+		sort.Slice(pending, func(i, j int) bool { return pending[i].offset < pending[j].offset })
+
+		file, err := dao.handle.get()
+		if err != nil {
+			return nil, fmt.Errorf("failed to open item file: %w", err)
+		}
+		idSize, err := dao.handle.ids()
 		if err != nil {
-			return 0, "", 0, fmt.Errorf("item not found: %w", err)
+			return nil, fmt.Errorf("failed to read item ID size: %w", err)
+		}
+
+		for _, p := range pending {
+			entryData, err := utils.ReadEntryAtOffset(file, p.offset)
+			if err == nil {
+				item, err := utils.ItemCodec.Decode(entryData, idSize)
+				if err == nil && item.ID == p.id {
+					dao.stats.indexed()
+					if item.Tombstone == 0x00 {
+						found[p.id] = item
+						dao.cache.put(item)
+					}
+					continue
+				}
+			}
+			// The index is stale for this item, look it up the slow way
+			unindexed = append(unindexed, p.id)
+		}
+	}
+
+	for _, id := range unindexed {
+		if item, err := dao.readUnlocked(id); err == nil {
+			found[id] = item
 		}
 	}
 
-	// Parse the entry
-	item, err := utils.ParseItemEntry(entryData)
+	items := make(map[uint64]*Item, len(found))
+	for id, item := range found {
+		if item == nil {
+			continue
+		}
+		items[id] = &Item{
+			ID:           item.ID,
+			Name:         item.Name,
+			PriceInCents: item.Price,
+			Extensions:   item.Extensions,
+		}
+	}
+	return items, nil
+}
+
+// readUnlocked reads and parses an active item record (must be called with lock held)
+// Recently read items come from the cache without touching the file
+func (dao *ItemDAO) readUnlocked(id uint64) (*utils.Item, error) {
+	if item, ok := dao.cache.get(id); ok {
+		return item, nil
+	}
+
+	item, err := dao.Store.readUnlocked(id)
 	if err != nil {
-		return 0, "", 0, fmt.Errorf("failed to parse item entry: %w", err)
+		return nil, err
 	}
+	dao.cache.put(item)
+	return item, nil
+}
 
-	// Check if item is deleted
-	if item.Tombstone != 0x00 {
-		return 0, "", 0, fmt.Errorf("deleted item id %d", item.ID)
+// UpdateExtensions rewrites an existing item with new extension fields, keeping its ID, name and price
+// The old record is tombstoned and the new version is written into a free slot or appended
+func (dao *ItemDAO) UpdateExtensions(id uint64, ext map[byte][]byte) error {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	if err := dao.flushUnlocked(); err != nil {
+		return err
 	}
 
-	return item.ID, item.Name, item.Price, nil
+	current, err := dao.readUnlocked(id)
+	if err != nil {
+		return err
+	}
+	if err := dao.checkExternalID(ext, &id); err != nil {
+		return err
+	}
+
+	if err := dao.deleteUnlocked(id); err != nil {
+		return err
+	}
+
+	_, err = dao.appendUnlocked(&id, current.Name, current.Price, ext)
+	return err
+}
+
+// Update rewrites an existing item with a new name and price, keeping its ID and extension fields
+// The old record is tombstoned and the new version is written into a free slot or appended
+func (dao *ItemDAO) Update(id uint64, name string, priceInCents uint64) error {
+	name, err := checkRecord("item", name, priceInCents, 0)
+	if err != nil {
+		return err
+	}
+
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	if err := dao.flushUnlocked(); err != nil {
+		return err
+	}
+
+	current, err := dao.readUnlocked(id)
+	if err != nil {
+		return err
+	}
+
+	if err := dao.deleteUnlocked(id); err != nil {
+		return err
+	}
+	dao.removeName(current.Name, id)
+
+	_, err = dao.appendUnlocked(&id, name, priceInCents, current.Extensions)
+	return err
+}
+
+// UpdatePrice overwrites the price of an item in its record, without rewriting or re-appending it
+// Falls back to a full rewrite when the index does not point at the item's active record
+func (dao *ItemDAO) UpdatePrice(id uint64, priceInCents uint64) error {
+	if err := utils.CheckPriceRule("item", priceInCents); err != nil {
+		return err
+	}
+
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	if err := dao.flushUnlocked(); err != nil {
+		return err
+	}
+
+	priceBytes, err := utils.WriteFixedNumber(4, priceInCents)
+	if err != nil {
+		return fmt.Errorf("invalid price: %w", err)
+	}
+
+	file, err := dao.handle.get()
+	if err != nil {
+		return fmt.Errorf("failed to open item file: %w", err)
+	}
+	idSize, err := dao.handle.ids()
+	if err != nil {
+		return fmt.Errorf("failed to read item ID size: %w", err)
+	}
+
+	if offset, found := dao.tree.get().Search(id); found {
+		entryData, err := utils.ReadEntryAtOffset(file, offset)
+		if err == nil {
+			item, parseErr := utils.ItemCodec.Decode(entryData, idSize)
+			if parseErr == nil && item.ID == id && item.Tombstone == 0x00 {
+				dao.stats.indexed()
+				priceOffset, err := utils.ItemPriceOffset(entryData, idSize)
+				if err != nil {
+					return err
+				}
+				dao.cache.remove(id)
+				return utils.PatchField(file, offset, priceOffset, priceBytes)
+			}
+		}
+	}
+
+	// Index is stale, rewrite the record instead
+	current, err := dao.readUnlocked(id)
+	if err != nil {
+		return err
+	}
+	if err := dao.deleteUnlocked(id); err != nil {
+		return err
+	}
+	_, err = dao.appendUnlocked(&id, current.Name, priceInCents, current.Extensions)
+	return err
 }
 
 // Delete marks an item as deleted by flipping its tombstone bit
@@ -173,12 +569,243 @@ func (dao *ItemDAO) Delete(id uint64) error {
 	dao.mu.Lock()
 	defer dao.mu.Unlock()
 
-	return utils.DeleteFromBTreeIndex(dao.tree, dao.indexPath, dao.filePath, id, "item")
+	if err := dao.flushUnlocked(); err != nil {
+		return err
+	}
+
+	name := ""
+	if dao.names != nil {
+		if item, err := dao.readUnlocked(id); err == nil {
+			name = item.Name
+		}
+	}
+
+	if err := dao.deleteUnlocked(id); err != nil {
+		return err
+	}
+
+	dao.removeName(name, id)
+	return nil
+}
+
+// deleteUnlocked tombstones an item and frees its record slot (must be called with lock held)
+func (dao *ItemDAO) deleteUnlocked(id uint64) error {
+	dao.removeExternalID(id)
+	dao.cache.remove(id)
+	return dao.Store.deleteUnlocked(id)
+}
+
+// DeleteWhere tombstones every active item that match accepts, in a single pass over the file
+// The index is saved once for all of them; returns the deleted items
+func (dao *ItemDAO) DeleteWhere(match func(item *utils.Item) bool) ([]*utils.Item, error) {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	if err := dao.flushUnlocked(); err != nil {
+		return nil, err
+	}
+
+	matched := make(map[uint64]*utils.Item)
+	ids, err := dao.deleteWhereUnlocked(func(entryData []byte, idSize int) bool {
+		item, err := utils.ItemCodec.Decode(entryData, idSize)
+		if err != nil || !match(item) {
+			return false
+		}
+		matched[item.ID] = item
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	deleted := make([]*utils.Item, 0, len(ids))
+	for _, id := range ids {
+		item := matched[id]
+		dao.removeExternalID(id)
+		dao.cache.remove(id)
+		dao.removeName(item.Name, id)
+		deleted = append(deleted, item)
+	}
+	return deleted, nil
+}
+
+// FindByName returns the IDs of active items whose normalized name matches name
+// Uses the in-memory name index, which is built from the file on first use
+func (dao *ItemDAO) FindByName(name string) ([]uint64, error) {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	if dao.names == nil {
+		if err := dao.buildNameIndex(); err != nil {
+			return nil, err
+		}
+	}
+
+	key := utils.NormalizeName(name)
+	ids := append([]uint64{}, dao.names[key]...)
+
+	// Buffered items join the name index when they are flushed, checking them here keeps the group open
+	if dao.buffer != nil {
+		for _, record := range dao.buffer.records {
+			if utils.NormalizeName(record.name) == key {
+				ids = append(ids, record.id)
+			}
+		}
+	}
+	return ids, nil
+}
+
+// buildNameIndex scans the file and indexes the names of all active items (must be called with lock held)
+func (dao *ItemDAO) buildNameIndex() error {
+	names := make(map[string][]uint64)
+
+	if _, err := os.Stat(dao.filePath); err == nil {
+		entries, err := utils.SplitFileIntoEntries(dao.filePath)
+		if err != nil {
+			return fmt.Errorf("failed to build name index: %w", err)
+		}
+		for _, entry := range entries {
+			item, err := utils.ItemCodec.Decode(entry.Data, entry.IDSize)
+			if err != nil || item.Tombstone != 0x00 {
+				continue
+			}
+			key := utils.NormalizeName(item.Name)
+			names[key] = append(names[key], item.ID)
+		}
+	}
+
+	dao.names = names
+	return nil
+}
+
+// addName records an active item in the name index if it has been built
+func (dao *ItemDAO) addName(name string, id uint64) {
+	if dao.names == nil {
+		return
+	}
+	key := utils.NormalizeName(name)
+	for _, existing := range dao.names[key] {
+		if existing == id {
+			return
+		}
+	}
+	dao.names[key] = append(dao.names[key], id)
+}
+
+// removeName drops a deleted item from the name index if it has been built
+func (dao *ItemDAO) removeName(name string, id uint64) {
+	if dao.names == nil {
+		return
+	}
+	key := utils.NormalizeName(name)
+	ids := dao.names[key]
+	for i, existing := range ids {
+		if existing == id {
+			dao.names[key] = append(ids[:i:i], ids[i+1:]...)
+			break
+		}
+	}
+	if len(dao.names[key]) == 0 {
+		delete(dao.names, key)
+	}
+}
+
+// FindByExternalID returns the ID of the active item with the given external ID
+// Uses the in-memory external ID index, which is built from the file on first use
+func (dao *ItemDAO) FindByExternalID(externalID string) (uint64, error) {
+	key, err := utils.NormalizeExternalID(externalID)
+	if err != nil {
+		return 0, err
+	}
+
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	if err := dao.flushUnlocked(); err != nil {
+		return 0, err
+	}
+	if err := dao.buildExternalIDIndex(); err != nil {
+		return 0, err
+	}
+
+	id, found := dao.externalIDs[key]
+	if !found {
+		return 0, utils.WithCode(utils.CodeNotFound, fmt.Errorf("item with external ID %s not found", key), map[string]any{"entity": "item", "externalId": key})
+	}
+	return id, nil
+}
+
+// buildExternalIDIndex scans the file and indexes the external IDs of all active items if not done yet (must be called with lock held)
+func (dao *ItemDAO) buildExternalIDIndex() error {
+	if dao.externalIDs != nil {
+		return nil
+	}
+	externalIDs := make(map[string]uint64)
+
+	if _, err := os.Stat(dao.filePath); err == nil {
+		entries, err := utils.SplitFileIntoEntries(dao.filePath)
+		if err != nil {
+			return fmt.Errorf("failed to build external ID index: %w", err)
+		}
+		for _, entry := range entries {
+			item, err := utils.ItemCodec.Decode(entry.Data, entry.IDSize)
+			if err != nil || item.Tombstone != 0x00 {
+				continue
+			}
+			if key, err := utils.DecodeExternalID(item.Extensions); err == nil && key != "" {
+				externalIDs[key] = item.ID
+			}
+		}
+	}
+
+	dao.externalIDs = externalIDs
+	return nil
+}
+
+// checkExternalID fails when the external ID in ext belongs to an active item other than id (must be called with lock held)
+// A nil id is a new item, which may not reuse any external ID
+func (dao *ItemDAO) checkExternalID(ext map[byte][]byte, id *uint64) error {
+	key, err := utils.DecodeExternalID(ext)
+	if err != nil || key == "" {
+		return err
+	}
+	if err := dao.buildExternalIDIndex(); err != nil {
+		return err
+	}
+	if owner, found := dao.externalIDs[key]; found && (id == nil || owner != *id) {
+		return utils.WithCode(utils.CodeConflict, fmt.Errorf("external ID %s already belongs to item %d", key, owner),
+			map[string]any{"entity": "item", "id": owner, "externalId": key})
+	}
+	return nil
+}
+
+// addExternalID records the external ID of an active item in the index if it has been built
+func (dao *ItemDAO) addExternalID(ext map[byte][]byte, id uint64) {
+	if dao.externalIDs == nil {
+		return
+	}
+	if key, err := utils.DecodeExternalID(ext); err == nil && key != "" {
+		dao.externalIDs[key] = id
+	}
+}
+
+// removeExternalID drops the external ID of an item about to be deleted from the index if it has been built
+func (dao *ItemDAO) removeExternalID(id uint64) {
+	if dao.externalIDs == nil {
+		return
+	}
+	item, err := dao.readUnlocked(id)
+	if err != nil {
+		return
+	}
+	if key, err := utils.DecodeExternalID(item.Extensions); err == nil && dao.externalIDs[key] == id {
+		delete(dao.externalIDs, key)
+	}
 }
 
 // GetIndexTree returns the B+ tree for debugging purposes
 func (dao *ItemDAO) GetIndexTree() *index.BTree {
-	return dao.tree
+	return dao.tree.get()
 }
 
 // Item represents an item record
@@ -187,35 +814,111 @@ type Item struct {
 	Name         string
 	PriceInCents uint64
 	IsDeleted    bool
+	Extensions   map[byte][]byte // optional fields such as the stock quantity
 }
 
 // GetAll retrieves all items from the database, including deleted ones
 func (dao *ItemDAO) GetAll() ([]Item, error) {
+	return dao.GetAllContext(context.Background())
+}
+
+// GetAllContext is GetAll, stopping with the context error once ctx is cancelled
+func (dao *ItemDAO) GetAllContext(ctx context.Context) ([]Item, error) {
+	return dao.getAll(ctx, false)
+}
+
+// GetAllActive retrieves the items that are not deleted, in file order
+// Deleted records are skipped through the tombstone bitmap without being parsed
+func (dao *ItemDAO) GetAllActive(ctx context.Context) ([]Item, error) {
+	return dao.getAll(ctx, true)
+}
+
+// GetAllActiveByID retrieves the items that are not deleted in ascending ID order, read through the
+// B+ tree index without scanning the file; an index behind the file falls back to a sorted scan
+func (dao *ItemDAO) GetAllActiveByID(ctx context.Context) ([]Item, error) {
 	dao.mu.Lock()
 	defer dao.mu.Unlock()
 
-	// Check if file exists
-	if _, err := os.Stat(dao.filePath); os.IsNotExist(err) {
-		return []Item{}, nil
+	if err := dao.flushUnlocked(); err != nil {
+		return nil, err
 	}
 
-	// Use utility to split file into entries
-	entries, err := utils.SplitFileIntoEntries(dao.filePath)
+	entries, idSize, ok, err := dao.activeEntriesByID(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read items: %w", err)
 	}
+	if !ok {
+		items, err := dao.getAllUnlocked(ctx, true)
+		if err != nil {
+			return nil, err
+		}
+		sort.Slice(items, func(i, j int) bool { return items[i].ID < items[j].ID })
+		return items, nil
+	}
 
 	items := make([]Item, 0, len(entries))
-	for _, entry := range entries {
-		item, err := utils.ParseItemEntry(entry.Data)
+	for _, entryData := range entries {
+		item, err := utils.ItemCodec.Decode(entryData, idSize)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse item entry: %w", err)
+		}
+		items = append(items, itemFromRecord(item))
+	}
+	return items, nil
+}
+
+// itemFromRecord converts a decoded item record
+func itemFromRecord(item *utils.Item) Item {
+	return Item{
+		ID:           item.ID,
+		Name:         item.Name,
+		PriceInCents: item.Price,
+		IsDeleted:    item.Tombstone != 0x00,
+		Extensions:   item.Extensions,
+	}
+}
+
+// getAll scans the items, only the active ones with activeOnly
+func (dao *ItemDAO) getAll(ctx context.Context, activeOnly bool) ([]Item, error) {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	if err := dao.flushUnlocked(); err != nil {
+		return nil, err
+	}
+	return dao.getAllUnlocked(ctx, activeOnly)
+}
+
+// getAllUnlocked is getAll after the buffered items were written (must be called with lock held)
+func (dao *ItemDAO) getAllUnlocked(ctx context.Context, activeOnly bool) ([]Item, error) {
+	// Check if file exists
+	if _, err := os.Stat(dao.filePath); os.IsNotExist(err) {
+		return []Item{}, nil
+	}
+
+	// Scan the entries, parsing each one (the file may be memory-mapped)
+	items := make([]Item, 0)
+	positions := make(map[uint64]int)
+	err := dao.scanEntries(ctx, activeOnly, func(entry utils.EntryInfo) error {
+		if activeOnly && !activeRecord(entry.Data, entry.IDSize) {
+			return nil
+		}
+		item, err := utils.ItemCodec.Decode(entry.Data, entry.IDSize)
 		if err == nil {
-			items = append(items, Item{
-				ID:           item.ID,
-				Name:         item.Name,
-				PriceInCents: item.Price,
-				IsDeleted:    item.Tombstone != 0x00,
-			})
+			i := itemFromRecord(item)
+
+			// A rewritten record appears again later in the file, keep only the latest version
+			if pos, seen := positions[i.ID]; seen {
+				items[pos] = i
+				return nil
+			}
+			positions[i.ID] = len(items)
+			items = append(items, i)
 		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read items: %w", err)
 	}
 
 	return items, nil
@@ -272,3 +975,94 @@ func (dao *ItemDAO) SearchByName(pattern string, algorithm SearchAlgorithm) ([]I
 
 	return results, nil
 }
+
+// Counts is recordFile.Counts, after writing the buffered items
+func (dao *ItemDAO) Counts() (RecordCounts, error) {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	if err := dao.flushUnlocked(); err != nil {
+		return RecordCounts{}, err
+	}
+	return dao.countsUnlocked()
+}
+
+// RecordBytes is recordFile.RecordBytes, after writing the buffered items
+func (dao *ItemDAO) RecordBytes() (utils.RecordBytes, error) {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	if err := dao.flushUnlocked(); err != nil {
+		return utils.RecordBytes{}, err
+	}
+	return dao.recordBytesUnlocked()
+}
+
+// WithFileLocked runs fn while the item file is locked against writes
+func (dao *ItemDAO) WithFileLocked(fn func(filePath string) error) error {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	if err := dao.flushUnlocked(); err != nil {
+		return err
+	}
+
+	return fn(dao.filePath)
+}
+
+// ReplaceFile swaps the item file for the file at path and rebuilds the index from it
+// prepare runs first under the same lock, so no write can happen between it and the swap
+func (dao *ItemDAO) ReplaceFile(path string, prepare func() error) error {
+	return dao.Store.ReplaceFile(path, func() error {
+		if err := dao.flushUnlocked(); err != nil {
+			return err
+		}
+		if prepare != nil {
+			if err := prepare(); err != nil {
+				return err
+			}
+		}
+		dao.names = nil
+		dao.externalIDs = nil
+		dao.cache.clear()
+		return nil
+	})
+}
+
+// Close writes buffered items and closes the item file kept open between calls; the DAO reopens it when used again
+func (dao *ItemDAO) Close() error {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	// A failed flush must not keep the file open
+	flushErr := dao.flushUnlocked()
+
+	// Let a background load finish before the files are moved or removed, then save the pending changes
+	dao.tree.get()
+	saveErr := dao.saveIndexUnlocked()
+	if err := dao.handle.close(); err != nil {
+		return err
+	}
+	return errors.Join(flushErr, saveErr)
+}
+
+// SetCacheSize changes how many recently read items are kept in memory, 0 disables the cache
+func (dao *ItemDAO) SetCacheSize(capacity int) {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	dao.cache.resize(capacity)
+}
+
+// CacheStats returns the capacity, size and hit counts of the item cache
+func (dao *ItemDAO) CacheStats() CacheStats {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	return CacheStats{
+		Capacity: dao.cache.capacity,
+		Size:     dao.cache.order.Len(),
+		Hits:     dao.cache.hits,
+		Misses:   dao.cache.misses,
+	}
+}
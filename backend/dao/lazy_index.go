@@ -0,0 +1,60 @@
+package dao
+
+import "BinaryCRUD/backend/index"
+
+// lazyIndex holds an index loaded in the background, so creating a DAO does not wait for a
+// large index to be read or rebuilt
+// Writes wait for the index with get; point reads can use peek and scan the file while it warms up
+type lazyIndex[T any] struct {
+	done  chan struct{}
+	value T
+}
+
+// loadIndexAsync starts loading an index on its own goroutine
+func loadIndexAsync[T any](load func() T) *lazyIndex[T] {
+	l := &lazyIndex[T]{done: make(chan struct{})}
+	go func() {
+		defer close(l.done)
+		l.value = load()
+	}()
+	return l
+}
+
+// loadedIndex wraps an index that is already in memory
+func loadedIndex[T any](value T) *lazyIndex[T] {
+	l := &lazyIndex[T]{done: make(chan struct{}), value: value}
+	close(l.done)
+	return l
+}
+
+// get returns the index, waiting for it to finish loading
+func (l *lazyIndex[T]) get() T {
+	<-l.done
+	return l.value
+}
+
+// peek returns the index without waiting; ok is false while it is still loading
+func (l *lazyIndex[T]) peek() (value T, ok bool) {
+	select {
+	case <-l.done:
+		return l.value, true
+	default:
+		return value, false
+	}
+}
+
+// ready reports whether the index has finished loading
+func (l *lazyIndex[T]) ready() bool {
+	_, ok := l.peek()
+	return ok
+}
+
+// searchIfReady looks an ID up in a B+ tree index that may still be loading, reporting it as not
+// found until the index is ready so the caller scans the file instead of waiting
+func searchIfReady(tree *lazyIndex[*index.BTree], id uint64) (int64, bool) {
+	loaded, ok := tree.peek()
+	if !ok {
+		return 0, false
+	}
+	return loaded.Search(id)
+}
@@ -2,7 +2,6 @@ package dao
 
 import (
 	"BinaryCRUD/backend/index"
-	"BinaryCRUD/backend/utils"
 )
 
 // OrderDAO wraps CollectionDAO for orders
@@ -11,19 +10,11 @@ type OrderDAO struct {
 }
 
 // NewOrderDAO creates a DAO for orders.bin with B+ Tree index
-func NewOrderDAO(filePath string) *OrderDAO {
-	indexPath, tree := utils.InitializeCollectionDAOIndex(filePath)
-
-	return &OrderDAO{
-		CollectionDAO: &CollectionDAO{
-			filePath:  filePath,
-			indexPath: indexPath,
-			tree:      tree,
-		},
-	}
+func NewOrderDAO(filePath string, opts ...CollectionOption) *OrderDAO {
+	return &OrderDAO{CollectionDAO: newCollectionDAO("order", filePath, opts)}
 }
 
 // GetIndexTree returns the B+ tree index
 func (dao *OrderDAO) GetIndexTree() *index.BTree {
-	return dao.tree
+	return dao.tree.get()
 }
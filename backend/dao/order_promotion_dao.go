@@ -3,33 +3,52 @@ package dao
 import (
 	"BinaryCRUD/backend/index"
 	"BinaryCRUD/backend/utils"
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"sync"
+	"time"
 )
 
+// ErrAlreadyApplied is returned when a promotion is already applied to an order
+var ErrAlreadyApplied = utils.WithCode(utils.CodeConflict, errors.New("promotion already applied to order"), nil)
+
 // OrderPromotion represents the N:N relationship between Orders and Promotions
+// AppliedAt and DiscountAmount are zero for links written before they were recorded
 type OrderPromotion struct {
-	OrderID     uint64
-	PromotionID uint64
+	OrderID        uint64
+	PromotionID    uint64
+	AppliedAt      time.Time // when the promotion was applied
+	DiscountAmount uint64    // discount in cents the promotion granted on the order when applied
 }
 
 type OrderPromotionDAO struct {
 	filePath  string
 	indexPath string
-	hashIndex *index.ExtensibleHash
+	hashIndex *lazyIndex[*index.ExtensibleHash] // Loaded in the background
 	mu        sync.Mutex
+	handle    fileHandle // The order_promotion file, kept open between calls
+	saves     indexSaver // Index changes not saved in full yet
+	stats     IndexStats // How lookups found their records
 }
 
 // NewOrderPromotionDAO creates a DAO for order_promotions.bin
 func NewOrderPromotionDAO(filePath string) *OrderPromotionDAO {
-	// Use the utility function that handles rebuild on corruption
-	indexPath, hashIndex := utils.InitializeOrderPromotionIndex(filePath, 4)
+	indexPath := utils.IndexPathFromBinFile(filePath)
+	bucketSize := utils.HashBucketSize
+
+	// Use the utility function that handles rebuild on corruption, off the caller's goroutine
+	hashIndex := loadIndexAsync(func() *index.ExtensibleHash {
+		return utils.LoadOrderPromotionIndex(filePath, indexPath, bucketSize)
+	})
 
 	return &OrderPromotionDAO{
 		filePath:  filePath,
 		indexPath: indexPath,
 		hashIndex: hashIndex,
+		handle:    fileHandle{path: filePath},
+		saves:     indexSaver{indexPath: indexPath},
 	}
 }
 
@@ -38,30 +57,40 @@ func (dao *OrderPromotionDAO) ensureFileExists() error {
 	return utils.EnsureFileExists(dao.filePath)
 }
 
-// Write creates a new order-promotion relationship
-// Binary format with composite primary key: [recordLength(2)][orderID(2)][promotionID(2)][tombstone(1)]
+// Write creates a new order-promotion relationship applied at appliedAt, without a discount snapshot
+func (dao *OrderPromotionDAO) Write(orderID, promotionID uint64, appliedAt time.Time) error {
+	return dao.WriteLink(OrderPromotion{OrderID: orderID, PromotionID: promotionID, AppliedAt: appliedAt})
+}
+
+// WriteLink creates a new order-promotion relationship with its metadata
+// Binary format with composite primary key:
+// [recordLength(2)][orderID(idSize)][promotionID(idSize)][tombstone(1)][appliedAt(8)][discountAmount(4)]
 // The composite key is (orderID, promotionID) - no auto-generated ID
-func (dao *OrderPromotionDAO) Write(orderID, promotionID uint64) error {
+func (dao *OrderPromotionDAO) WriteLink(link OrderPromotion) error {
 	dao.mu.Lock()
 	defer dao.mu.Unlock()
 
+	orderID, promotionID := link.OrderID, link.PromotionID
+
 	// Ensure file exists
 	if err := dao.ensureFileExists(); err != nil {
 		return err
 	}
 
-	// Check for duplicate using hash index (fast O(1) lookup)
-	_, exists := dao.hashIndex.Search(orderID, promotionID)
+	// Check for duplicate in the index and the data file
+	exists, err := dao.existsUnlocked(orderID, promotionID)
+	if err != nil {
+		return err
+	}
 	if exists {
-		return fmt.Errorf("order-promotion relationship already exists (orderID=%d, promotionID=%d)", orderID, promotionID)
+		return fmt.Errorf("%w (orderID=%d, promotionID=%d)", ErrAlreadyApplied, orderID, promotionID)
 	}
 
-	// Open file for read/write
-	file, err := os.OpenFile(dao.filePath, os.O_RDWR, 0644)
+	// Get the open file for read/write
+	file, err := dao.handle.get()
 	if err != nil {
 		return fmt.Errorf("failed to open order_promotion file: %w", err)
 	}
-	defer file.Close()
 
 	// Get current file offset before writing (for index)
 	fileInfo, err := file.Stat()
@@ -70,86 +99,270 @@ func (dao *OrderPromotionDAO) Write(orderID, promotionID uint64) error {
 	}
 	entryOffset := fileInfo.Size()
 
-	// Build entry data: [orderID(2)][promotionID(2)][tombstone(1)]
-
-	// Order ID (2 bytes)
-	orderIDBytes, err := utils.WriteFixedNumber(utils.IDSize, orderID)
+	idSize, err := dao.handle.ids()
 	if err != nil {
-		return fmt.Errorf("failed to write order ID: %w", err)
+		return fmt.Errorf("failed to read order_promotion ID size: %w", err)
 	}
 
-	// Promotion ID (2 bytes)
-	promotionIDBytes, err := utils.WriteFixedNumber(utils.IDSize, promotionID)
+	// Build entry data: [orderID(idSize)][promotionID(idSize)][tombstone(1)][appliedAt(8)][discountAmount(4)]
+	entryData, err := utils.OrderPromotionCodec.Encode(linkRecord(link), idSize)
 	if err != nil {
-		return fmt.Errorf("failed to write promotion ID: %w", err)
+		return err
 	}
 
-	// Tombstone (1 byte) - 0x00 for active
-	tombstone := []byte{0x00}
-
-	// Combine all fields: [orderID][promotionID][tombstone]
-	entryData := make([]byte, 0, utils.IDSize+utils.IDSize+utils.TombstoneSize)
-	entryData = append(entryData, orderIDBytes...)
-	entryData = append(entryData, promotionIDBytes...)
-	entryData = append(entryData, tombstone...)
-
 	// Use the manual append utility to write the entry with proper formatting and header updates
+	if err := dao.markIndexDirtyUnlocked(); err != nil {
+		return err
+	}
 	err = utils.AppendEntryManual(file, entryData)
 	if err != nil {
 		return fmt.Errorf("failed to append entry: %w", err)
 	}
 
-	// Add to hash index
-	err = dao.hashIndex.Insert(orderID, promotionID, entryOffset)
+	// Add to hash index, logged with the generation the index now matches
+	_, _, _, generation, err := utils.ReadHeader(file)
 	if err != nil {
-		return fmt.Errorf("failed to update index: %w", err)
+		return fmt.Errorf("failed to read header: %w", err)
 	}
-
-	// Persist index
-	err = dao.hashIndex.Save(dao.indexPath)
+	err = dao.hashIndex.get().LogInsert(dao.indexPath, orderID, promotionID, entryOffset, uint64(generation))
 	if err != nil {
-		return fmt.Errorf("failed to save index: %w", err)
+		return fmt.Errorf("failed to update index: %w", err)
+	}
+	if err := dao.saves.logged(); err != nil {
+		return err
 	}
 
-	return nil
+	return dao.checkpointIfDue()
 }
 
-// GetByOrderID retrieves all promotions applied to an order
-func (dao *OrderPromotionDAO) GetByOrderID(orderID uint64) ([]*OrderPromotion, error) {
+// WriteMany creates several order-promotion relationships at once
+// Links that already exist, or are repeated in links, are skipped; the file is checked for them in
+// a single scan, the new relationships are appended together and the hash index is saved once
+// Returns the links that were written
+func (dao *OrderPromotionDAO) WriteMany(links []OrderPromotion) ([]OrderPromotion, error) {
 	dao.mu.Lock()
 	defer dao.mu.Unlock()
 
-	// Use hash index for fast lookup
-	entries := dao.hashIndex.GetByOrderID(orderID)
+	if err := dao.ensureFileExists(); err != nil {
+		return nil, err
+	}
+
+	// Links the index doesn't know are looked up in the file, in case their index entry was lost
+	type linkKey struct{ orderID, promotionID uint64 }
+	pending := make(map[linkKey]bool)
+	for _, link := range links {
+		if _, exists := dao.hashIndex.get().Search(link.OrderID, link.PromotionID); !exists {
+			pending[linkKey{link.OrderID, link.PromotionID}] = true
+		}
+	}
+	if len(pending) > 0 {
+		start := time.Now()
+		repaired := false
+		err := utils.IterateEntries(dao.filePath, func(entry utils.EntryWithOffset) error {
+			op, err := utils.OrderPromotionCodec.Decode(entry.Data, entry.IDSize)
+			key := linkKey{}
+			if err == nil {
+				key = linkKey{op.OrderID, op.PromotionID}
+			}
+			if err == nil && op.Tombstone == 0x00 && pending[key] {
+				delete(pending, key)
+				repaired = true
+				return dao.hashIndex.get().Insert(op.OrderID, op.PromotionID, entry.Offset)
+			}
+			return nil
+		})
+		dao.stats.scanned(start, repaired)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan order_promotion file: %w", err)
+		}
+	}
+
+	file, err := dao.handle.get()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open order_promotion file: %w", err)
+	}
+	idSize, err := dao.handle.ids()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read order_promotion ID size: %w", err)
+	}
+
+	written := make([]OrderPromotion, 0, len(pending))
+	entries := make([][]byte, 0, len(pending))
+	for _, link := range links {
+		key := linkKey{link.OrderID, link.PromotionID}
+		if !pending[key] {
+			continue
+		}
+		delete(pending, key)
+		entryData, err := utils.OrderPromotionCodec.Encode(linkRecord(link), idSize)
+		if err != nil {
+			return nil, err
+		}
+		written = append(written, link)
+		entries = append(entries, entryData)
+	}
+	if len(entries) == 0 {
+		return written, nil
+	}
+
+	if err := dao.markIndexDirtyUnlocked(); err != nil {
+		return nil, err
+	}
+	offsets, err := utils.AppendEntriesManual(file, entries)
+	if err != nil {
+		return nil, fmt.Errorf("failed to append entries: %w", err)
+	}
+
+	// Index the new relationships and save the index in full once with the next changes, which also empties its log
+	for i, link := range written {
+		if err := dao.hashIndex.get().Insert(link.OrderID, link.PromotionID, offsets[i]); err != nil {
+			return nil, fmt.Errorf("failed to update index: %w", err)
+		}
+	}
+	_, _, _, generation, err := utils.ReadHeader(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+	dao.hashIndex.get().SetGeneration(uint64(generation))
+	dao.scheduleIndexSaveUnlocked()
+	return written, nil
+}
+
+// linkRecord converts a relationship to the active record stored for it
+func linkRecord(link OrderPromotion) *utils.OrderPromotion {
+	return &utils.OrderPromotion{
+		OrderID:        link.OrderID,
+		PromotionID:    link.PromotionID,
+		AppliedAt:      link.AppliedAt,
+		DiscountAmount: link.DiscountAmount,
+	}
+}
+
+// readLinksUnlocked returns the relationships of index entries with the metadata stored in their records
+// A record that can't be read still yields its IDs (must be called with lock held)
+func (dao *OrderPromotionDAO) readLinksUnlocked(entries []index.HashEntry) []*OrderPromotion {
+	file, fileErr := dao.handle.get()
+	idSize, idErr := dao.handle.ids()
 
 	result := make([]*OrderPromotion, len(entries))
 	for i, entry := range entries {
-		result[i] = &OrderPromotion{
-			OrderID:     entry.OrderID,
-			PromotionID: entry.PromotionID,
+		link := &OrderPromotion{OrderID: entry.OrderID, PromotionID: entry.PromotionID}
+		if fileErr == nil && idErr == nil {
+			if data, err := utils.ReadEntryAtOffset(file, entry.Offset); err == nil {
+				if record, err := utils.OrderPromotionCodec.Decode(data, idSize); err == nil &&
+					record.OrderID == entry.OrderID && record.PromotionID == entry.PromotionID {
+					link.AppliedAt, link.DiscountAmount = record.AppliedAt, record.DiscountAmount
+				}
+			}
 		}
+		result[i] = link
 	}
+	return result
+}
 
-	return result, nil
+// checkpointIfDue saves the full hash index once enough changes are logged, which also empties the log
+// (must be called with lock held)
+func (dao *OrderPromotionDAO) checkpointIfDue() error {
+	if dao.hashIndex.get().Logged() < utils.HashCheckpointInterval {
+		return nil
+	}
+	if err := dao.hashIndex.get().Save(dao.indexPath); err != nil {
+		return fmt.Errorf("failed to save index: %w", err)
+	}
+	return nil
 }
 
-// GetByPromotionID retrieves all orders that have a specific promotion applied
-func (dao *OrderPromotionDAO) GetByPromotionID(promotionID uint64) ([]*OrderPromotion, error) {
+// markIndexDirtyUnlocked leaves the dirty marker of the index before the data file changes
+// The index is waited for first, so a load still running doesn't take the marker for a missed save
+// (must be called with lock held)
+func (dao *OrderPromotionDAO) markIndexDirtyUnlocked() error {
+	dao.hashIndex.get()
+	return dao.saves.markDirty()
+}
+
+// scheduleIndexSaveUnlocked saves the changed index in full once indexSaveDelay has passed, along with
+// the changes made until then (must be called with lock held)
+func (dao *OrderPromotionDAO) scheduleIndexSaveUnlocked() {
+	dao.saves.schedule(dao.saveIndexOnTimer)
+}
+
+// saveIndexOnTimer saves the index once the delay after its first unsaved change ran out
+// A failure leaves the marker; the next change or Close tries again
+func (dao *OrderPromotionDAO) saveIndexOnTimer() {
 	dao.mu.Lock()
 	defer dao.mu.Unlock()
 
-	// Use hash index for fast lookup
-	entries := dao.hashIndex.GetByPromotionID(promotionID)
+	dao.saveIndexUnlocked()
+}
 
-	result := make([]*OrderPromotion, len(entries))
-	for i, entry := range entries {
-		result[i] = &OrderPromotion{
-			OrderID:     entry.OrderID,
-			PromotionID: entry.PromotionID,
+// saveIndexUnlocked saves the index in full if it has changes not saved yet, which also empties its log,
+// and clears the dirty marker (must be called with lock held)
+func (dao *OrderPromotionDAO) saveIndexUnlocked() error {
+	return dao.saves.flush(func() error {
+		if err := dao.hashIndex.get().Save(dao.indexPath); err != nil {
+			return fmt.Errorf("failed to save index: %w", err)
 		}
+		return nil
+	})
+}
+
+// Exists reports whether an active relationship between the order and promotion exists
+func (dao *OrderPromotionDAO) Exists(orderID, promotionID uint64) (bool, error) {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	return dao.existsUnlocked(orderID, promotionID)
+}
+
+// existsUnlocked checks the hash index, then scans the data file in case the index entry was lost
+// An active entry missing from the index is added back (must be called with lock held)
+func (dao *OrderPromotionDAO) existsUnlocked(orderID, promotionID uint64) (bool, error) {
+	if _, exists := dao.hashIndex.get().Search(orderID, promotionID); exists {
+		dao.stats.indexed()
+		return true, nil
+	}
+
+	start := time.Now()
+	found := int64(-1)
+	err := utils.IterateEntries(dao.filePath, func(entry utils.EntryWithOffset) error {
+		op, err := utils.OrderPromotionCodec.Decode(entry.Data, entry.IDSize)
+		if err == nil && op.Tombstone == 0x00 && op.OrderID == orderID && op.PromotionID == promotionID {
+			found = entry.Offset
+		}
+		return nil
+	})
+	dao.stats.scanned(start, found >= 0)
+	if err != nil {
+		return false, fmt.Errorf("failed to scan order_promotion file: %w", err)
+	}
+	if found < 0 {
+		return false, nil
+	}
+
+	if err := dao.hashIndex.get().Insert(orderID, promotionID, found); err != nil {
+		return true, fmt.Errorf("failed to repair index: %w", err)
 	}
+	return true, nil
+}
+
+// GetByOrderID retrieves all promotions applied to an order
+func (dao *OrderPromotionDAO) GetByOrderID(orderID uint64) ([]*OrderPromotion, error) {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
 
-	return result, nil
+	// Use hash index for fast lookup
+	entries := dao.hashIndex.get().GetByOrderID(orderID)
+	return dao.readLinksUnlocked(entries), nil
+}
+
+// GetByPromotionID retrieves all orders that have a specific promotion applied
+func (dao *OrderPromotionDAO) GetByPromotionID(promotionID uint64) ([]*OrderPromotion, error) {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	// Use hash index for fast lookup
+	entries := dao.hashIndex.get().GetByPromotionID(promotionID)
+	return dao.readLinksUnlocked(entries), nil
 }
 
 // GetAll retrieves all non-deleted order-promotion relationships
@@ -158,17 +371,8 @@ func (dao *OrderPromotionDAO) GetAll() ([]*OrderPromotion, error) {
 	defer dao.mu.Unlock()
 
 	// Use hash index for fast retrieval
-	entries := dao.hashIndex.GetAll()
-
-	result := make([]*OrderPromotion, len(entries))
-	for i, entry := range entries {
-		result[i] = &OrderPromotion{
-			OrderID:     entry.OrderID,
-			PromotionID: entry.PromotionID,
-		}
-	}
-
-	return result, nil
+	entries := dao.hashIndex.get().GetAll()
+	return dao.readLinksUnlocked(entries), nil
 }
 
 // Delete removes an order-promotion relationship by marking it as deleted
@@ -177,23 +381,150 @@ func (dao *OrderPromotionDAO) Delete(orderID, promotionID uint64) error {
 	dao.mu.Lock()
 	defer dao.mu.Unlock()
 
-	// Remove from hash index first
-	err := dao.hashIndex.Delete(orderID, promotionID)
+	if _, exists := dao.hashIndex.get().Search(orderID, promotionID); !exists {
+		return utils.WithCode(utils.CodeNotFound, fmt.Errorf("key not found: orderID=%d, promotionID=%d", orderID, promotionID),
+			map[string]any{"entity": "order_promotion", "orderId": orderID, "promotionId": promotionID})
+	}
+
+	// Use the generic soft delete utility for composite keys (without mutex since we already hold it)
+	if err := dao.markIndexDirtyUnlocked(); err != nil {
+		return err
+	}
+	if err := utils.SoftDeleteByCompositeKey(dao.filePath, orderID, promotionID, nil); err != nil {
+		return err
+	}
+
+	generation, err := utils.ReadGeneration(dao.filePath)
 	if err != nil {
-		return fmt.Errorf("key not found: orderID=%d, promotionID=%d", orderID, promotionID)
+		return err
+	}
+	if err := dao.hashIndex.get().LogDelete(dao.indexPath, orderID, promotionID, generation); err != nil {
+		return fmt.Errorf("failed to update index: %w", err)
+	}
+	if err := dao.saves.logged(); err != nil {
+		return err
+	}
+	return dao.checkpointIfDue()
+}
+
+// DeleteMany removes several order-promotion relationships in a single pass over the file
+// The hash index is saved once for all of them; links that don't exist are ignored
+// Returns the links that were removed
+func (dao *OrderPromotionDAO) DeleteMany(links []OrderPromotion) ([]OrderPromotion, error) {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	if _, err := os.Stat(dao.filePath); os.IsNotExist(err) || len(links) == 0 {
+		return []OrderPromotion{}, nil
 	}
 
-	// Save updated index
-	err = dao.hashIndex.Save(dao.indexPath)
+	wanted := make(map[[2]uint64]bool, len(links))
+	for _, link := range links {
+		wanted[[2]uint64{link.OrderID, link.PromotionID}] = true
+	}
+	if err := dao.markIndexDirtyUnlocked(); err != nil {
+		return nil, err
+	}
+	keys, err := utils.SoftDeleteCompositeWhere(dao.filePath, func(entryData []byte, idSize int) bool {
+		op, err := utils.OrderPromotionCodec.Decode(entryData, idSize)
+		return err == nil && wanted[[2]uint64{op.OrderID, op.PromotionID}]
+	})
+	dao.scheduleIndexSaveUnlocked()
 	if err != nil {
-		return fmt.Errorf("failed to save index: %w", err)
+		return nil, err
 	}
 
-	// Use the generic soft delete utility for composite keys (without mutex since we already hold it)
-	return utils.SoftDeleteByCompositeKey(dao.filePath, orderID, promotionID, nil)
+	removed := make([]OrderPromotion, 0, len(keys))
+	for _, key := range keys {
+		dao.hashIndex.get().Delete(key[0], key[1])
+		removed = append(removed, OrderPromotion{OrderID: key[0], PromotionID: key[1]})
+	}
+	if len(removed) == 0 {
+		return removed, nil
+	}
+
+	// Save the index in full once with the next changes, recording the generation the tombstones advanced it to
+	generation, err := utils.ReadGeneration(dao.filePath)
+	if err != nil {
+		return nil, err
+	}
+	dao.hashIndex.get().SetGeneration(generation)
+	return removed, nil
 }
 
 // GetHashIndex returns the hash index for debugging/inspection
 func (dao *OrderPromotionDAO) GetHashIndex() *index.ExtensibleHash {
-	return dao.hashIndex
+	return dao.hashIndex.get()
+}
+
+// IndexStats returns how order_promotion lookups found their entries since the DAO was created
+func (dao *OrderPromotionDAO) IndexStats() IndexStats {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	return dao.stats
+}
+
+// IndexReady reports whether the hash index has finished loading; until then calls wait for it
+func (dao *OrderPromotionDAO) IndexReady() bool {
+	return dao.hashIndex.ready()
+}
+
+// WaitIndex blocks until the hash index has finished loading
+func (dao *OrderPromotionDAO) WaitIndex() {
+	dao.hashIndex.get()
+}
+
+// WithFileLocked runs fn while the order_promotion file is locked against writes
+func (dao *OrderPromotionDAO) WithFileLocked(fn func(filePath string) error) error {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	return fn(dao.filePath)
+}
+
+// Close closes the order_promotion file kept open between calls; the DAO reopens it when used again
+func (dao *OrderPromotionDAO) Close() error {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	// Let a background load finish before the files are moved or removed, then save the pending changes and
+	// fold the log into the index
+	err := dao.saveIndexUnlocked()
+	if err == nil && dao.hashIndex.get().Logged() > 0 {
+		if saveErr := dao.hashIndex.get().Save(dao.indexPath); saveErr != nil {
+			err = fmt.Errorf("failed to save index: %w", saveErr)
+		}
+	}
+	return errors.Join(err, dao.handle.close())
+}
+
+// ReplaceFile swaps the order_promotion file for the file at path and rebuilds the index from it
+// prepare runs first under the same lock, so no write can happen between it and the swap
+func (dao *OrderPromotionDAO) ReplaceFile(path string, prepare func() error) error {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	if prepare != nil {
+		if err := prepare(); err != nil {
+			return err
+		}
+	}
+
+	// A load still running would save the old index over the rebuilt one
+	dao.hashIndex.get()
+
+	// The open file is the one being replaced, the next call opens the new one
+	dao.handle.close()
+	if err := os.Rename(path, dao.filePath); err != nil {
+		return fmt.Errorf("failed to replace order_promotion file: %w", err)
+	}
+
+	hashIndex, err := utils.RebuildExtensibleHashIndex(context.Background(), dao.filePath, dao.indexPath, 4)
+	if err != nil {
+		return fmt.Errorf("failed to rebuild order_promotion index: %w", err)
+	}
+	dao.hashIndex = loadedIndex(hashIndex)
+	dao.saves.reset()
+	return nil
 }
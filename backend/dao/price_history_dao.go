@@ -0,0 +1,139 @@
+package dao
+
+import (
+	"BinaryCRUD/backend/utils"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// PriceChange records a price an item had before it was changed
+type PriceChange struct {
+	ID        uint64
+	ItemID    uint64
+	Timestamp time.Time
+	OldPrice  uint64
+	NewPrice  uint64
+}
+
+// PriceHistoryDAO manages the append-only price history binary file
+type PriceHistoryDAO struct {
+	filePath string
+	mu       sync.Mutex
+}
+
+// NewPriceHistoryDAO creates a DAO for price_history.bin
+func NewPriceHistoryDAO(filePath string) *PriceHistoryDAO {
+	return &PriceHistoryDAO{filePath: filePath}
+}
+
+// Write appends a price change and returns its assigned ID
+// Complete record format: [recordLength(2)][ID(idSize)][tombstone(1)][itemID(idSize)][timestamp(8)][oldPrice(4)][newPrice(4)]
+func (dao *PriceHistoryDAO) Write(change PriceChange) (uint64, error) {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	timestampBytes, err := utils.WriteFixedNumber(8, uint64(change.Timestamp.UnixNano()))
+	if err != nil {
+		return 0, fmt.Errorf("failed to write timestamp: %w", err)
+	}
+	oldPriceBytes, err := utils.WriteFixedNumber(4, change.OldPrice)
+	if err != nil {
+		return 0, fmt.Errorf("failed to write old price: %w", err)
+	}
+	newPriceBytes, err := utils.WriteFixedNumber(4, change.NewPrice)
+	if err != nil {
+		return 0, fmt.Errorf("failed to write new price: %w", err)
+	}
+
+	if err := utils.EnsureFileExists(dao.filePath); err != nil {
+		return 0, err
+	}
+
+	file, err := os.OpenFile(dao.filePath, os.O_RDWR, 0644)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open price history file: %w", err)
+	}
+	defer file.Close()
+
+	nextId, err := utils.NextID(file)
+	if err != nil {
+		return 0, err
+	}
+	idSize, err := utils.ReadIDSize(file)
+	if err != nil {
+		return 0, err
+	}
+	itemIDBytes, err := utils.WriteFixedNumber(idSize, change.ItemID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to write item ID: %w", err)
+	}
+
+	data := utils.CombineBytes(itemIDBytes, timestampBytes, oldPriceBytes, newPriceBytes)
+	if err := utils.AppendEntry(file, data); err != nil {
+		return 0, fmt.Errorf("failed to append price change: %w", err)
+	}
+
+	return nextId, nil
+}
+
+// GetByItemID returns the price changes of an item in chronological order
+func (dao *PriceHistoryDAO) GetByItemID(itemID uint64) ([]PriceChange, error) {
+	dao.mu.Lock()
+	defer dao.mu.Unlock()
+
+	if _, err := os.Stat(dao.filePath); os.IsNotExist(err) {
+		return []PriceChange{}, nil
+	}
+
+	entries, err := utils.SplitFileIntoEntries(dao.filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read price history: %w", err)
+	}
+
+	result := []PriceChange{}
+	for _, entry := range entries {
+		change, err := parsePriceChange(entry.Data, entry.IDSize)
+		if err != nil || change.ItemID != itemID {
+			continue
+		}
+		result = append(result, *change)
+	}
+
+	return result, nil
+}
+
+// parsePriceChange parses a price history record (without length prefix) of a file with IDs of idSize bytes
+func parsePriceChange(data []byte, idSize int) (*PriceChange, error) {
+	id, offset, err := utils.ReadFixedNumber(idSize, data, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ID: %w", err)
+	}
+	offset += utils.TombstoneSize
+
+	itemID, offset, err := utils.ReadFixedNumber(idSize, data, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read item ID: %w", err)
+	}
+	nanos, offset, err := utils.ReadFixedNumber(8, data, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read timestamp: %w", err)
+	}
+	oldPrice, offset, err := utils.ReadFixedNumber(4, data, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read old price: %w", err)
+	}
+	newPrice, _, err := utils.ReadFixedNumber(4, data, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read new price: %w", err)
+	}
+
+	return &PriceChange{
+		ID:        id,
+		ItemID:    itemID,
+		Timestamp: time.Unix(0, int64(nanos)).UTC(),
+		OldPrice:  oldPrice,
+		NewPrice:  newPrice,
+	}, nil
+}
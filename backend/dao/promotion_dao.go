@@ -2,7 +2,6 @@ package dao
 
 import (
 	"BinaryCRUD/backend/index"
-	"BinaryCRUD/backend/utils"
 )
 
 // PromotionDAO wraps CollectionDAO for promotions
@@ -11,19 +10,11 @@ type PromotionDAO struct {
 }
 
 // NewPromotionDAO creates a DAO for promotions.bin with B+ Tree index
-func NewPromotionDAO(filePath string) *PromotionDAO {
-	indexPath, tree := utils.InitializeCollectionDAOIndex(filePath)
-
-	return &PromotionDAO{
-		CollectionDAO: &CollectionDAO{
-			filePath:  filePath,
-			indexPath: indexPath,
-			tree:      tree,
-		},
-	}
+func NewPromotionDAO(filePath string, opts ...CollectionOption) *PromotionDAO {
+	return &PromotionDAO{CollectionDAO: newCollectionDAO("promotion", filePath, opts)}
 }
 
 // GetIndexTree returns the B+ tree index
 func (dao *PromotionDAO) GetIndexTree() *index.BTree {
-	return dao.tree
+	return dao.tree.get()
 }
@@ -0,0 +1,583 @@
+package dao
+
+import (
+	"BinaryCRUD/backend/crypto"
+	"BinaryCRUD/backend/index"
+	"BinaryCRUD/backend/utils"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// recordFile is the plumbing shared by DAOs of records keyed by ID: the lock, the file kept open between
+// calls, the B+ tree index loaded in the background, the free record slots, the tombstone bitmap and the
+// name encryption setting
+// DAOs embed it and build their record formats on top
+type recordFile struct {
+	kind      string // entity name used in errors, e.g. "order"
+	filePath  string
+	indexPath string
+	mu        sync.Mutex
+	tree      *lazyIndex[*index.BTree] // B+ tree index for fast lookups, loaded in the background
+	rebuild   func(ctx context.Context, filePath, indexPath string) (*index.BTree, error)
+	free      *utils.FreeList        // Tombstoned record slots reused by new records, built on first write
+	deleted   *utils.TombstoneBitmap // Tombstoned record slots skipped by active scans, loaded on first use
+	encrypt   *bool                  // Name encryption recorded in the header of a new file, nil follows the global setting
+	handle    fileHandle             // The data file, kept open between calls
+	saves     indexSaver             // Index changes not saved yet
+	stats     IndexStats             // How lookups found their records
+}
+
+// init sets up the record file at filePath and starts loading its index with load
+// rebuild recreates the index when the file is replaced
+func (f *recordFile) init(kind, filePath string, load func(filePath, indexPath string) *index.BTree, rebuild func(ctx context.Context, filePath, indexPath string) (*index.BTree, error)) {
+	indexPath := utils.IndexPathFromBinFile(filePath)
+
+	f.kind = kind
+	f.filePath = filePath
+	f.indexPath = indexPath
+	f.handle = fileHandle{path: filePath}
+	f.saves = indexSaver{indexPath: indexPath}
+	f.rebuild = rebuild
+
+	// Loading or rebuilding the index can take a while, so it happens off the caller's goroutine
+	f.tree = loadIndexAsync(func() *index.BTree {
+		return load(filePath, indexPath)
+	})
+}
+
+// ensureFileExists creates the file with empty header if it doesn't exist
+func (f *recordFile) ensureFileExists() error {
+	var flags byte
+	if f.encrypt != nil {
+		flags = utils.FlagNamesPlaintext
+		if *f.encrypt {
+			flags = utils.FlagNamesEncrypted
+		}
+	}
+	return utils.EnsureFileExistsWithFlags(f.filePath, flags)
+}
+
+// NamesEncrypted reports whether names are stored encrypted
+// Files whose header doesn't record it follow the global crypto setting
+func (f *recordFile) NamesEncrypted() (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.namesEncrypted()
+}
+
+// namesEncrypted reads the name encryption from the file header (must be called with lock held)
+func (f *recordFile) namesEncrypted() (bool, error) {
+	flags, err := utils.ReadHeaderFlagsFromPath(f.filePath)
+	if err != nil {
+		return false, err
+	}
+	switch {
+	case flags&utils.FlagNamesEncrypted != 0:
+		return true, nil
+	case flags&utils.FlagNamesPlaintext != 0:
+		return false, nil
+	}
+	if _, err := os.Stat(f.filePath); os.IsNotExist(err) && f.encrypt != nil {
+		// Not created yet, the header will record the configured setting
+		return *f.encrypt, nil
+	}
+	return crypto.IsEnabled(), nil
+}
+
+// idSize returns the ID width of the file, creating it first so a new file gets the configured width
+// (must be called with lock held)
+func (f *recordFile) idSize() (int, error) {
+	if err := f.ensureFileExists(); err != nil {
+		return 0, err
+	}
+	idSize, err := f.handle.ids()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read %s ID size: %w", f.kind, err)
+	}
+	return idSize, nil
+}
+
+// getCrypto returns the field cipher for the data key in the keys directory
+func (f *recordFile) getCrypto() (*crypto.FieldCipher, error) {
+	fieldCipher, err := crypto.GetFieldCipher(utils.KeysDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get field cipher: %w", err)
+	}
+	return fieldCipher, nil
+}
+
+// checkRecord sanitizes the name of a record about to be written and applies the rules of its entity,
+// rejecting names that are blank once sanitized. It returns the name to store
+func checkRecord(entity, name string, price uint64, itemCount int) (string, error) {
+	name = utils.SanitizeName(name)
+	if err := utils.ValidateEntityName(entity, name); err != nil {
+		return "", err
+	}
+	if err := utils.CheckRecordRules(entity, name, price, itemCount); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+// writeEntryUnlocked stores an entry under id, or the next ID from the header and ID mark when id is nil, and indexes it
+// entry holds the record without ID and tombstone (must be called with lock held)
+func (f *recordFile) writeEntryUnlocked(id *uint64, entry []byte) (uint64, error) {
+	if err := f.ensureFileExists(); err != nil {
+		return 0, err
+	}
+
+	file, err := f.handle.get()
+	if err != nil {
+		return 0, fmt.Errorf("failed to open %s file: %w", f.kind, err)
+	}
+
+	// Read the next ID from the header and the ID mark
+	nextId, err := utils.NextID(file)
+	if err != nil {
+		return 0, err
+	}
+	assignedID := nextId
+	if id != nil {
+		assignedID = *id
+	}
+
+	free, err := f.freeList()
+	if err != nil {
+		return 0, err
+	}
+
+	// Write into the space of a deleted record when one fits, otherwise append
+	if err := f.markIndexDirtyUnlocked(); err != nil {
+		return 0, err
+	}
+	appendPos, err := free.WriteEntryWithID(file, assignedID, entry)
+	if err != nil {
+		return 0, fmt.Errorf("failed to append %s: %w", f.kind, err)
+	}
+
+	// Add to B+ tree index: ID -> file offset
+	f.tree.get().Insert(assignedID, appendPos)
+	f.markSlots(false, appendPos)
+	f.scheduleIndexSaveUnlocked()
+
+	return assignedID, nil
+}
+
+// markIndexDirtyUnlocked leaves the dirty marker of the index before the data file changes
+// The index is waited for first, so a load still running doesn't take the marker for a missed save
+// (must be called with lock held)
+func (f *recordFile) markIndexDirtyUnlocked() error {
+	f.tree.get()
+	return f.saves.markDirty()
+}
+
+// scheduleIndexSaveUnlocked saves the changed index once indexSaveDelay has passed, along with the
+// changes made until then (must be called with lock held)
+func (f *recordFile) scheduleIndexSaveUnlocked() {
+	f.saves.schedule(f.saveIndexOnTimer)
+}
+
+// saveIndexOnTimer saves the index once the delay after its first unsaved change ran out
+// A failure leaves the marker; the next change or Close tries again
+func (f *recordFile) saveIndexOnTimer() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.saveIndexUnlocked()
+}
+
+// saveIndexUnlocked saves the index if it has changes not saved yet and clears the dirty marker
+// A failed save leaves the marker, so the index is rebuilt when the DAO is next created (must be called with lock held)
+func (f *recordFile) saveIndexUnlocked() error {
+	return f.saves.flush(func() error {
+		if err := f.tree.get().Save(f.indexPath); err != nil {
+			return fmt.Errorf("failed to save index: %w", err)
+		}
+		return nil
+	})
+}
+
+// readEntryUnlocked returns the entry data of the latest record with the ID, active or not
+// The index is tried first; while it is still loading or when it misses, the file is scanned (must be called with lock held)
+func (f *recordFile) readEntryUnlocked(id uint64) ([]byte, error) {
+	// Get the open file (don't create it if it doesn't exist)
+	file, err := f.handle.get()
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, utils.WithCode(utils.CodeNotFound, fmt.Errorf("failed to open %s file: file does not exist", f.kind), utils.RecordDetails(f.kind, id))
+		}
+		return nil, fmt.Errorf("failed to open %s file: %w", f.kind, err)
+	}
+
+	if offset, found := searchIfReady(f.tree, id); found {
+		entryData, err := utils.ReadEntryAtOffset(file, offset)
+		if err == nil {
+			f.stats.indexed()
+			return entryData, nil
+		}
+		// Index may be stale, fall back to sequential scan
+	}
+
+	idSize, err := f.handle.ids()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s ID size: %w", f.kind, err)
+	}
+
+	start := time.Now()
+	entryData, err := utils.FindByIDSequential(file, id)
+	f.stats.scanned(start, err == nil && activeRecord(entryData, idSize) && f.tree.ready())
+	if err != nil {
+		return nil, utils.WithDetails(fmt.Errorf("%s not found: %w", f.kind, err), utils.RecordDetails(f.kind, id))
+	}
+	return entryData, nil
+}
+
+// deleteUnlocked tombstones a record and frees its slot (must be called with lock held)
+func (f *recordFile) deleteUnlocked(id uint64) error {
+	tree := f.tree.get()
+	offset, indexed := tree.Search(id)
+	if indexed {
+		if err := f.markIndexDirtyUnlocked(); err != nil {
+			return err
+		}
+	}
+	if err := tree.Delete(id); err != nil {
+		return utils.WithCode(utils.CodeNotFound, fmt.Errorf("%s not found: %w", f.kind, err), utils.RecordDetails(f.kind, id))
+	}
+	f.scheduleIndexSaveUnlocked()
+	if err := utils.SoftDeleteByID(f.filePath, id, nil, nil); err != nil {
+		return err
+	}
+
+	if f.free != nil && indexed {
+		file, err := f.handle.get()
+		if err == nil {
+			err = f.free.Add(file, offset)
+		}
+		if err != nil {
+			// The index did not point at the deleted record, rescan the file on the next write
+			f.free = nil
+		}
+	}
+	if indexed {
+		f.markSlots(true, offset)
+	} else {
+		f.deleted = nil
+	}
+	return nil
+}
+
+// deleteWhereUnlocked tombstones every active record that match accepts in one pass over the file and
+// removes them from the index, saved once with the next changes (must be called with lock held)
+// Returns the IDs of the deleted records
+func (f *recordFile) deleteWhereUnlocked(match func(entryData []byte, idSize int) bool) ([]uint64, error) {
+	if _, err := os.Stat(f.filePath); os.IsNotExist(err) {
+		return []uint64{}, nil
+	}
+
+	if err := f.markIndexDirtyUnlocked(); err != nil {
+		return nil, err
+	}
+	ids, err := utils.SoftDeleteWhere(f.filePath, match)
+	f.scheduleIndexSaveUnlocked()
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete %ss: %w", f.kind, err)
+	}
+	if len(ids) == 0 {
+		return ids, nil
+	}
+
+	tree := f.tree.get()
+	offsets := make([]int64, 0, len(ids))
+	for _, id := range ids {
+		if offset, found := tree.Search(id); found {
+			offsets = append(offsets, offset)
+		}
+		tree.Delete(id)
+	}
+
+	// The freed slots are found by rescanning the file on the next write
+	f.free = nil
+	if len(offsets) == len(ids) {
+		f.markSlots(true, offsets...)
+	} else {
+		f.deleted = nil
+	}
+	return ids, nil
+}
+
+// tombstones returns the tombstone bitmap of the file, loading it on first use (must be called with lock held)
+func (f *recordFile) tombstones() (*utils.TombstoneBitmap, error) {
+	if f.deleted == nil {
+		deleted, err := utils.LoadTombstoneBitmap(f.filePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load deleted %s slots: %w", f.kind, err)
+		}
+		f.deleted = deleted
+	}
+	return f.deleted, nil
+}
+
+// markSlots records in the tombstone bitmap, once it is loaded, that the records at offsets were
+// tombstoned or written. Each record is read back first, so a stale index offset can't flip the bit of
+// another record. A bitmap that can't follow is dropped; the one on disk no longer matches the file,
+// so it is rebuilt on next use (must be called with lock held)
+func (f *recordFile) markSlots(tombstoned bool, offsets ...int64) {
+	if f.deleted == nil {
+		return
+	}
+	file, err := f.handle.get()
+	if err != nil {
+		f.deleted = nil
+		return
+	}
+	idSize, err := f.handle.ids()
+	if err != nil {
+		f.deleted = nil
+		return
+	}
+	for _, offset := range offsets {
+		entryData, err := utils.ReadEntryAtOffset(file, offset)
+		if err == nil && activeRecord(entryData, idSize) == tombstoned {
+			err = fmt.Errorf("record at offset %d is not in the expected state", offset)
+		}
+		if err == nil {
+			err = f.deleted.Set(f.filePath, offset, tombstoned)
+		}
+		if err != nil {
+			f.deleted = nil
+			return
+		}
+	}
+	if err := f.deleted.Flush(f.filePath); err != nil {
+		f.deleted = nil
+	}
+}
+
+// scanEntries calls fn with every entry of the file, stopping with the context error once ctx is cancelled
+// With activeOnly, the records the tombstone bitmap marks as deleted are skipped without being parsed;
+// fn must still check the tombstone, since records written after the bitmap was taken are passed on
+// (must be called with lock held)
+func (f *recordFile) scanEntries(ctx context.Context, activeOnly bool, fn func(entry utils.EntryInfo) error) error {
+	var deleted *utils.TombstoneBitmap
+	if activeOnly {
+		var err error
+		if deleted, err = f.tombstones(); err != nil {
+			return err
+		}
+	}
+
+	slot := 0
+	return utils.ScanFileEntries(f.filePath, func(entry utils.EntryInfo) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		skip := deleted != nil && deleted.Tombstoned(slot)
+		slot++
+		if skip {
+			return nil
+		}
+		return fn(entry)
+	})
+}
+
+// activeEntriesByID returns the entry data of the active records in ascending ID order, read at the offsets
+// of the B+ tree index instead of scanning the file. ok is false when an offset doesn't hold the active
+// record of its ID; the index is then behind the file and the caller scans it instead
+// (must be called with lock held)
+func (f *recordFile) activeEntriesByID(ctx context.Context) (entries [][]byte, idSize int, ok bool, err error) {
+	file, err := f.handle.get()
+	if os.IsNotExist(err) {
+		return [][]byte{}, 0, true, nil
+	}
+	if err != nil {
+		return nil, 0, false, fmt.Errorf("failed to open %s file: %w", f.kind, err)
+	}
+	if idSize, err = f.handle.ids(); err != nil {
+		return nil, 0, false, fmt.Errorf("failed to read %s ID size: %w", f.kind, err)
+	}
+
+	tree := f.tree.get()
+	entries = make([][]byte, 0, tree.Size())
+	ok = true
+	tree.Ascend(func(id uint64, offset int64) bool {
+		if err = ctx.Err(); err != nil {
+			return false
+		}
+		entryData, readErr := utils.ReadEntryAtOffset(file, offset)
+		if readErr != nil || !activeRecord(entryData, idSize) {
+			ok = false
+			return false
+		}
+		if recordID, _, idErr := utils.ReadFixedNumber(idSize, entryData, 0); idErr != nil || recordID != id {
+			ok = false
+			return false
+		}
+		entries = append(entries, entryData)
+		return true
+	})
+	if err != nil {
+		return nil, 0, false, err
+	}
+	return entries, idSize, ok, nil
+}
+
+// freeList returns the free record slots of the file, scanning it on first use (must be called with lock held)
+func (f *recordFile) freeList() (*utils.FreeList, error) {
+	if f.free == nil {
+		free, err := utils.BuildFreeList(f.filePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan deleted %ss: %w", f.kind, err)
+		}
+		f.free = free
+	}
+	return f.free, nil
+}
+
+// IndexStats returns how lookups found their records since the DAO was created
+func (f *recordFile) IndexStats() IndexStats {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.stats
+}
+
+// IndexReady reports whether the index has finished loading; until then reads scan the file
+func (f *recordFile) IndexReady() bool {
+	return f.tree.ready()
+}
+
+// WaitIndex blocks until the index has finished loading
+func (f *recordFile) WaitIndex() {
+	f.tree.get()
+}
+
+// RecordCounts is how many records a data file holds
+type RecordCounts struct {
+	Total   int  // records in the file, tombstoned ones included
+	Active  int  // records that are not tombstoned
+	Scanned bool // the header disagreed with the index, so the counts were taken by scanning the file
+}
+
+// Counts returns the record counts kept in the header, without reading the records
+// The active count is checked against the size of the index; when they disagree the file is scanned instead
+func (f *recordFile) Counts() (RecordCounts, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.countsUnlocked()
+}
+
+// countsUnlocked is Counts (must be called with lock held)
+func (f *recordFile) countsUnlocked() (RecordCounts, error) {
+	header, err := utils.ReadHeaderOnly(f.filePath)
+	if os.IsNotExist(err) {
+		return RecordCounts{}, nil
+	}
+	if err != nil {
+		return RecordCounts{}, fmt.Errorf("failed to read %s header: %w", f.kind, err)
+	}
+
+	counts := RecordCounts{Total: header.EntitiesCount, Active: header.EntitiesCount - header.TombstoneCount}
+	if counts.Active >= 0 && counts.Active == f.tree.get().Size() {
+		return counts, nil
+	}
+
+	bitmap, err := utils.BuildTombstoneBitmap(f.filePath)
+	if err != nil {
+		return RecordCounts{}, fmt.Errorf("failed to count %ss: %w", f.kind, err)
+	}
+	return RecordCounts{Total: bitmap.Slots(), Active: bitmap.Slots() - bitmap.Count(), Scanned: true}, nil
+}
+
+// RecordBytes returns the bytes taken by the active and tombstoned records, from the tombstone bitmap
+// A bitmap that no longer matches the file is rebuilt first
+func (f *recordFile) RecordBytes() (utils.RecordBytes, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.recordBytesUnlocked()
+}
+
+// recordBytesUnlocked is RecordBytes (must be called with lock held)
+func (f *recordFile) recordBytesUnlocked() (utils.RecordBytes, error) {
+	deleted, err := f.tombstones()
+	if err != nil {
+		return utils.RecordBytes{}, err
+	}
+	if bytes, err := deleted.RecordBytes(f.filePath); err == nil {
+		return bytes, nil
+	}
+
+	f.deleted = nil
+	if deleted, err = f.tombstones(); err != nil {
+		return utils.RecordBytes{}, err
+	}
+	return deleted.RecordBytes(f.filePath)
+}
+
+// WithFileLocked runs fn while the data file is locked against writes
+func (f *recordFile) WithFileLocked(fn func(filePath string) error) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return fn(f.filePath)
+}
+
+// Close closes the data file kept open between calls; the DAO reopens it when used again
+func (f *recordFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	// Let a background load finish before the files are moved or removed, then save the pending changes
+	f.tree.get()
+	saveErr := f.saveIndexUnlocked()
+	return errors.Join(saveErr, f.handle.close())
+}
+
+// ReplaceFile swaps the data file for the file at path and rebuilds the index from it
+// prepare runs first under the same lock, so no write can happen between it and the swap
+func (f *recordFile) ReplaceFile(path string, prepare func() error) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if prepare != nil {
+		if err := prepare(); err != nil {
+			return err
+		}
+	}
+
+	// A load still running would save the old index over the rebuilt one
+	f.tree.get()
+
+	// The open file is the one being replaced, the next call opens the new one
+	f.handle.close()
+	if err := os.Rename(path, f.filePath); err != nil {
+		return fmt.Errorf("failed to replace %s file: %w", f.kind, err)
+	}
+
+	// The new file is already in place, so its index is rebuilt to the end
+	tree, err := f.rebuild(context.Background(), f.filePath, f.indexPath)
+	if err != nil {
+		return fmt.Errorf("failed to rebuild %s index: %w", f.kind, err)
+	}
+	f.tree = loadedIndex(tree)
+	f.saves.reset()
+	f.free = nil
+
+	// Compaction removed the tombstoned records, so the bitmap of the new file is built along with its index
+	deleted, err := utils.BuildTombstoneBitmap(f.filePath)
+	if err == nil {
+		err = deleted.Flush(f.filePath)
+	}
+	if err != nil {
+		deleted = nil
+	}
+	f.deleted = deleted
+	return nil
+}
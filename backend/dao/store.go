@@ -0,0 +1,212 @@
+package dao
+
+import (
+	"BinaryCRUD/backend/crypto"
+	"BinaryCRUD/backend/utils"
+	"context"
+	"fmt"
+)
+
+// Record is an entity kept in a Store, identified by the ID the store assigned it
+type Record interface {
+	RecordID() uint64
+}
+
+// Serializer converts the records of a Store to and from the bytes stored after their ID and tombstone
+// String fields that should be encrypted like collection names go through the codec
+type Serializer[T Record] interface {
+	Encode(codec FieldCodec, record T) ([]byte, error)
+	Decode(codec FieldCodec, id uint64, data []byte) (T, error)
+}
+
+// FieldCodec encrypts string fields when the store file keeps names encrypted and passes them through otherwise
+// The header and data key are only read when a field is sealed or opened, so records without such fields
+// never touch the keys
+type FieldCodec struct {
+	file *recordFile
+}
+
+// cipher returns the field cipher and whether the file keeps names encrypted (must be called with lock held)
+func (c FieldCodec) cipher() (*crypto.FieldCipher, bool, error) {
+	encrypted, err := c.file.namesEncrypted()
+	if err != nil || !encrypted {
+		return nil, false, err
+	}
+	fieldCipher, err := c.file.getCrypto()
+	if err != nil {
+		return nil, false, err
+	}
+	return fieldCipher, true, nil
+}
+
+// Seal returns the stored form of a string field
+func (c FieldCodec) Seal(value string) ([]byte, error) {
+	fieldCipher, encrypted, err := c.cipher()
+	if err != nil || !encrypted {
+		return []byte(value), err
+	}
+	sealed, err := fieldCipher.EncryptToBytesIf(true, value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt field: %w", err)
+	}
+	return sealed, nil
+}
+
+// Open returns the string field stored as data
+func (c FieldCodec) Open(data []byte) (string, error) {
+	fieldCipher, encrypted, err := c.cipher()
+	if err != nil || !encrypted {
+		return string(data), err
+	}
+	value, err := fieldCipher.DecryptFromBytesIf(true, data)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt field: %w", err)
+	}
+	return value, nil
+}
+
+// Store is a DAO for any record type, with the locking, file handling, indexing and encryption of the
+// collection DAO; a new entity type only needs a Serializer
+// Records use the common [recordLength(2)][ID(idSize)][tombstone(1)][payload...] layout and are never compressed,
+// since the compression flag would be ambiguous with an arbitrary payload
+// Data key rotation only rewrites collection names, so sealed store fields keep the key they were written with
+type Store[T Record] struct {
+	recordFile
+	serializer Serializer[T]
+}
+
+// NewStore creates a Store for filePath with its B+ tree index
+func NewStore[T Record](kind, filePath string, serializer Serializer[T]) *Store[T] {
+	store := &Store[T]{serializer: serializer}
+	store.init(kind, filePath, utils.LoadRecordIndex, utils.RebuildRecordBTreeIndex)
+	return store
+}
+
+// codec returns the field codec matching the name encryption of the file (must be called with lock held)
+func (s *Store[T]) codec() FieldCodec {
+	return FieldCodec{file: &s.recordFile}
+}
+
+// Write stores a new record and returns the ID assigned to it; the ID of record is ignored
+func (s *Store[T]) Write(record T) (uint64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, err := s.encodeUnlocked(record)
+	if err != nil {
+		return 0, err
+	}
+	return s.writeEntryUnlocked(nil, entry)
+}
+
+// encodeUnlocked encodes a record into the entry stored after its ID and tombstone (must be called with lock held)
+func (s *Store[T]) encodeUnlocked(record T) ([]byte, error) {
+	entry, err := s.serializer.Encode(s.codec(), record)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s: %w", s.kind, err)
+	}
+	return entry, nil
+}
+
+// Read retrieves an active record by ID
+func (s *Store[T]) Read(id uint64) (T, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.readUnlocked(id)
+}
+
+// readUnlocked reads and decodes an active record (must be called with lock held)
+func (s *Store[T]) readUnlocked(id uint64) (T, error) {
+	var zero T
+	entryData, err := s.readEntryUnlocked(id)
+	if err != nil {
+		return zero, err
+	}
+	idSize, err := s.handle.ids()
+	if err != nil {
+		return zero, err
+	}
+	if !activeRecord(entryData, idSize) {
+		return zero, utils.WithCode(utils.CodeDeleted, fmt.Errorf("%s with ID %d is deleted", s.kind, id), utils.RecordDetails(s.kind, id))
+	}
+
+	return s.decode(s.codec(), id, entryData, idSize)
+}
+
+// decode decodes the payload of a record's entry data, read from a file with IDs of idSize bytes
+func (s *Store[T]) decode(codec FieldCodec, id uint64, entryData []byte, idSize int) (T, error) {
+	payload := append([]byte(nil), entryData[idSize+utils.TombstoneSize:]...)
+	record, err := s.serializer.Decode(codec, id, payload)
+	if err != nil {
+		return record, fmt.Errorf("failed to decode %s %d: %w", s.kind, id, err)
+	}
+	return record, nil
+}
+
+// Update replaces the active record with the ID of record, keeping the ID
+// The old record is tombstoned and the new version is written into a free slot or appended
+func (s *Store[T]) Update(record T) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := record.RecordID()
+	if _, err := s.readUnlocked(id); err != nil {
+		return err
+	}
+	entry, err := s.encodeUnlocked(record)
+	if err != nil {
+		return err
+	}
+	if err := s.deleteUnlocked(id); err != nil {
+		return err
+	}
+	_, err = s.writeEntryUnlocked(&id, entry)
+	return err
+}
+
+// Delete marks a record as deleted by flipping the tombstone bit
+func (s *Store[T]) Delete(id uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.deleteUnlocked(id)
+}
+
+// GetAll retrieves every active record in file order
+// Records that fail to decode are skipped, like unparsable collections
+func (s *Store[T]) GetAll() ([]T, error) {
+	return s.GetAllContext(context.Background())
+}
+
+// GetAllContext is GetAll, stopping with the context error once ctx is cancelled
+func (s *Store[T]) GetAllContext(ctx context.Context) ([]T, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	codec := s.codec()
+
+	// Only one version of a record is active, older versions are tombstoned even when the
+	// new one went into an earlier free slot
+	result := make([]T, 0)
+	err := utils.ScanFileEntries(s.filePath, func(entry utils.EntryInfo) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if !activeRecord(entry.Data, entry.IDSize) {
+			return nil
+		}
+		id, _, err := utils.ReadFixedNumber(entry.IDSize, entry.Data, 0)
+		if err != nil {
+			return nil
+		}
+		if record, err := s.decode(codec, id, entry.Data, entry.IDSize); err == nil {
+			result = append(result, record)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %ss: %w", s.kind, err)
+	}
+	return result, nil
+}
@@ -0,0 +1,20 @@
+package dao
+
+import (
+	"BinaryCRUD/backend/index"
+)
+
+// TemplateDAO wraps CollectionDAO for order templates, saved item lists without a customer
+type TemplateDAO struct {
+	*CollectionDAO
+}
+
+// NewTemplateDAO creates a DAO for templates.bin with B+ Tree index
+func NewTemplateDAO(filePath string, opts ...CollectionOption) *TemplateDAO {
+	return &TemplateDAO{CollectionDAO: newCollectionDAO("template", filePath, opts)}
+}
+
+// GetIndexTree returns the B+ tree index
+func (dao *TemplateDAO) GetIndexTree() *index.BTree {
+	return dao.tree.get()
+}
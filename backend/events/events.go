@@ -0,0 +1,120 @@
+package events
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Type names a kind of data change
+type Type string
+
+const (
+	ItemCreated      Type = "ItemCreated"
+	ItemUpdated      Type = "ItemUpdated"
+	ItemDeleted      Type = "ItemDeleted"
+	OrderCreated     Type = "OrderCreated"
+	OrderUpdated     Type = "OrderUpdated"
+	OrderDeleted     Type = "OrderDeleted"
+	PromotionCreated Type = "PromotionCreated"
+	PromotionUpdated Type = "PromotionUpdated"
+	PromotionDeleted Type = "PromotionDeleted"
+	PromotionApplied Type = "PromotionApplied"
+	PromotionRemoved Type = "PromotionRemoved"
+	Compacted        Type = "Compacted"
+	DataReloaded     Type = "DataReloaded" // the data files were replaced, e.g. by a restore or a data directory switch
+)
+
+// Types lists every event type
+var Types = []Type{
+	ItemCreated, ItemUpdated, ItemDeleted,
+	OrderCreated, OrderUpdated, OrderDeleted,
+	PromotionCreated, PromotionUpdated, PromotionDeleted, PromotionApplied, PromotionRemoved,
+	Compacted, DataReloaded,
+}
+
+// Known reports whether t is one of the event types
+func Known(t Type) bool {
+	for _, known := range Types {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
+// Event is a single data change
+// ID is the changed record, OrderID/PromotionID are set for promotion links
+type Event struct {
+	Type        Type      `json:"type"`
+	ID          uint64    `json:"id"`
+	OrderID     uint64    `json:"orderId"`
+	PromotionID uint64    `json:"promotionId"`
+	Timestamp   time.Time `json:"timestamp"`
+}
+
+// Handler receives published events
+type Handler func(Event)
+
+// Bus delivers events to its subscribers synchronously, in subscription order
+// The zero value is ready to use
+type Bus struct {
+	mu       sync.Mutex
+	nextID   int
+	handlers map[int]subscription
+}
+
+// subscription is a handler and the event types it wants, nil meaning all
+type subscription struct {
+	id     int
+	types  map[Type]bool
+	handle Handler
+}
+
+// Subscribe registers a handler for the given event types, or for every event when none are given
+// The returned function removes the subscription
+func (b *Bus) Subscribe(handler Handler, types ...Type) func() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.handlers == nil {
+		b.handlers = make(map[int]subscription)
+	}
+	sub := subscription{id: b.nextID, handle: handler}
+	if len(types) > 0 {
+		sub.types = make(map[Type]bool, len(types))
+		for _, t := range types {
+			sub.types[t] = true
+		}
+	}
+	b.handlers[sub.id] = sub
+	b.nextID++
+
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.handlers, sub.id)
+	}
+}
+
+// Publish stamps the event if needed and calls every matching handler
+// Handlers run outside the bus lock, so they may subscribe, unsubscribe or publish
+func (b *Bus) Publish(event Event) {
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now().UTC()
+	}
+
+	b.mu.Lock()
+	matching := make([]subscription, 0, len(b.handlers))
+	for _, sub := range b.handlers {
+		if sub.types == nil || sub.types[event.Type] {
+			matching = append(matching, sub)
+		}
+	}
+	b.mu.Unlock()
+
+	sort.Slice(matching, func(i, j int) bool { return matching[i].id < matching[j].id })
+	for _, sub := range matching {
+		sub.handle(event)
+	}
+}
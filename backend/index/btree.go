@@ -235,6 +235,24 @@ func (t *BTree) GetAll() map[uint64]int64 {
 	return result
 }
 
+// Ascend calls fn with every entry in ascending ID order, following the leaf chain, until fn returns false
+func (t *BTree) Ascend(fn func(id uint64, offset int64) bool) {
+	// Find leftmost leaf
+	node := t.root
+	for !node.isLeaf {
+		node = node.children[0]
+	}
+
+	for node != nil {
+		for i := range node.keys {
+			if !fn(node.keys[i], node.offsets[i]) {
+				return
+			}
+		}
+		node = node.next
+	}
+}
+
 // Size returns the number of entries in the tree
 func (t *BTree) Size() int {
 	count := 0
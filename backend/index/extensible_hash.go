@@ -3,6 +3,7 @@ package index
 import (
 	"encoding/binary"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 )
@@ -13,6 +14,9 @@ type ExtensibleHash struct {
 	globalDepth  int
 	bucketSize   int
 	directory    []*Bucket
+	generation   uint64 // generation of the data file the index was built from
+	hasGeneration bool  // false for indexes saved before generations were tracked
+	logged       int    // changes appended to the delta log since the last full save
 }
 
 // Bucket holds entries with the same hash prefix
@@ -47,6 +51,17 @@ func NewExtensibleHash(bucketSize int) *ExtensibleHash {
 	}
 }
 
+// Generation returns the data file generation the index matches, false if it is unknown
+func (h *ExtensibleHash) Generation() (uint64, bool) {
+	return h.generation, h.hasGeneration
+}
+
+// SetGeneration records the data file generation the index matches, saved with the index
+func (h *ExtensibleHash) SetGeneration(generation uint64) {
+	h.generation = generation
+	h.hasGeneration = true
+}
+
 // hash combines orderID and promotionID into a hash value
 func (h *ExtensibleHash) hash(orderID, promotionID uint64) uint64 {
 	// Simple hash combining both IDs
@@ -353,6 +368,16 @@ func (h *ExtensibleHash) Save(filePath string) error {
 		}
 	}
 
+	// Write generation trailer (8 bytes), omitted when unknown
+	if h.hasGeneration {
+		generation := make([]byte, 8)
+		binary.LittleEndian.PutUint64(generation, h.generation)
+		if _, err := file.Write(generation); err != nil {
+			cleanup()
+			return fmt.Errorf("failed to write generation: %w", err)
+		}
+	}
+
 	// Sync to disk
 	if err := file.Sync(); err != nil {
 		cleanup()
@@ -371,6 +396,12 @@ func (h *ExtensibleHash) Save(filePath string) error {
 		return fmt.Errorf("failed to rename temp file: %w", err)
 	}
 
+	// The full index now includes every logged change
+	if err := os.Remove(HashLogPath(filePath)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove index log: %w", err)
+	}
+	h.logged = 0
+
 	return nil
 }
 
@@ -445,9 +476,22 @@ func LoadExtensibleHash(filePath string) (*ExtensibleHash, error) {
 		directory[i] = buckets[bucketID]
 	}
 
-	return &ExtensibleHash{
+	hash := &ExtensibleHash{
 		globalDepth: globalDepth,
 		bucketSize:  bucketSize,
 		directory:   directory,
-	}, nil
+	}
+
+	// Read optional generation trailer
+	generation := make([]byte, 8)
+	if n, _ := io.ReadFull(file, generation); n == 8 {
+		hash.SetGeneration(binary.LittleEndian.Uint64(generation))
+	}
+
+	// Apply changes logged since the index was saved
+	if err := hash.replayLog(filePath); err != nil {
+		return nil, err
+	}
+
+	return hash, nil
 }
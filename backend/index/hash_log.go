@@ -0,0 +1,120 @@
+package index
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+)
+
+// HashLogExt is appended to the path of a hash index to name its delta log
+// The log holds the changes made since the index was last saved in full, so a write appends one small
+// record instead of rewriting the whole index; Save checkpoints the log back into the index file
+const HashLogExt = ".log"
+
+// Delta log operations
+const (
+	hashLogInsert byte = 1
+	hashLogDelete byte = 2
+)
+
+// hashLogRecordSize is the size of a delta log record:
+// [op(1)][orderID(8)][promotionID(8)][offset(8)][generation(8)], little endian like the index file
+const hashLogRecordSize = 1 + 8*4
+
+// HashLogPath returns the path of the delta log kept beside a hash index file
+func HashLogPath(indexPath string) string {
+	return indexPath + HashLogExt
+}
+
+// LogInsert inserts an entry and appends the change to the delta log of the index saved at indexPath
+// generation is the data file generation after the change, restored when the log is replayed
+func (h *ExtensibleHash) LogInsert(indexPath string, orderID, promotionID uint64, offset int64, generation uint64) error {
+	if err := h.Insert(orderID, promotionID, offset); err != nil {
+		return err
+	}
+	h.SetGeneration(generation)
+	return h.appendLog(indexPath, hashLogInsert, orderID, promotionID, offset, generation)
+}
+
+// LogDelete removes an entry and appends the change to the delta log of the index saved at indexPath
+func (h *ExtensibleHash) LogDelete(indexPath string, orderID, promotionID uint64, generation uint64) error {
+	if err := h.Delete(orderID, promotionID); err != nil {
+		return err
+	}
+	h.SetGeneration(generation)
+	return h.appendLog(indexPath, hashLogDelete, orderID, promotionID, 0, generation)
+}
+
+// Logged returns the number of changes in the delta log since the index was last saved in full
+func (h *ExtensibleHash) Logged() int {
+	return h.logged
+}
+
+// appendLog appends one record to the delta log and syncs it
+func (h *ExtensibleHash) appendLog(indexPath string, op byte, orderID, promotionID uint64, offset int64, generation uint64) error {
+	record := make([]byte, hashLogRecordSize)
+	record[0] = op
+	binary.LittleEndian.PutUint64(record[1:9], orderID)
+	binary.LittleEndian.PutUint64(record[9:17], promotionID)
+	binary.LittleEndian.PutUint64(record[17:25], uint64(offset))
+	binary.LittleEndian.PutUint64(record[25:33], generation)
+
+	file, err := os.OpenFile(HashLogPath(indexPath), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open index log: %w", err)
+	}
+	if _, err := file.Write(record); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to append to index log: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to sync index log: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to close index log: %w", err)
+	}
+
+	h.logged++
+	return nil
+}
+
+// replayLog applies the changes logged after the index at indexPath was saved
+// Records at or below the generation of the saved index are already part of it and skipped, which makes
+// replaying a log left behind by an interrupted checkpoint harmless; a torn record at the end is ignored
+func (h *ExtensibleHash) replayLog(indexPath string) error {
+	data, err := os.ReadFile(HashLogPath(indexPath))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read index log: %w", err)
+	}
+
+	baseGeneration, tracked := h.Generation()
+	for pos := 0; pos+hashLogRecordSize <= len(data); pos += hashLogRecordSize {
+		record := data[pos : pos+hashLogRecordSize]
+		orderID := binary.LittleEndian.Uint64(record[1:9])
+		promotionID := binary.LittleEndian.Uint64(record[9:17])
+		offset := int64(binary.LittleEndian.Uint64(record[17:25]))
+		generation := binary.LittleEndian.Uint64(record[25:33])
+		if tracked && generation <= baseGeneration {
+			continue
+		}
+
+		switch record[0] {
+		case hashLogInsert:
+			h.Delete(orderID, promotionID)
+			if err := h.Insert(orderID, promotionID, offset); err != nil {
+				return fmt.Errorf("failed to replay index log: %w", err)
+			}
+		case hashLogDelete:
+			h.Delete(orderID, promotionID)
+		default:
+			return fmt.Errorf("unknown index log operation %d at offset %d", record[0], pos)
+		}
+		h.SetGeneration(generation)
+		h.logged++
+	}
+	return nil
+}
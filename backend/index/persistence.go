@@ -1,6 +1,7 @@
 package index
 
 import (
+	"bufio"
 	"encoding/binary"
 	"fmt"
 	"os"
@@ -25,9 +26,12 @@ func (t *BTree) Save(path string) error {
 	// Get all entries
 	entries := t.GetAll()
 
+	// Buffer the small writes, one syscall per field made saving large indexes slow
+	writer := bufio.NewWriter(file)
+
 	// Write count
 	count := uint64(len(entries))
-	if err := binary.Write(file, binary.BigEndian, count); err != nil {
+	if err := binary.Write(writer, binary.BigEndian, count); err != nil {
 		file.Close()
 		os.Remove(tempPath)
 		return fmt.Errorf("failed to write count: %w", err)
@@ -35,18 +39,24 @@ func (t *BTree) Save(path string) error {
 
 	// Write each entry
 	for id, offset := range entries {
-		if err := binary.Write(file, binary.BigEndian, id); err != nil {
+		if err := binary.Write(writer, binary.BigEndian, id); err != nil {
 			file.Close()
 			os.Remove(tempPath)
 			return fmt.Errorf("failed to write id: %w", err)
 		}
-		if err := binary.Write(file, binary.BigEndian, offset); err != nil {
+		if err := binary.Write(writer, binary.BigEndian, offset); err != nil {
 			file.Close()
 			os.Remove(tempPath)
 			return fmt.Errorf("failed to write offset: %w", err)
 		}
 	}
 
+	if err := writer.Flush(); err != nil {
+		file.Close()
+		os.Remove(tempPath)
+		return fmt.Errorf("failed to flush index: %w", err)
+	}
+
 	// Sync to disk
 	if err := file.Sync(); err != nil {
 		file.Close()
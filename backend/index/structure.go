@@ -0,0 +1,118 @@
+package index
+
+// NodeInfo describes one node of a B+ tree, numbered breadth-first from the root (0)
+type NodeInfo struct {
+	ID       int      `json:"id"`
+	Level    int      `json:"level"` // 0 for the root
+	Leaf     bool     `json:"leaf"`
+	Keys     []uint64 `json:"keys"`
+	Children []int    `json:"children,omitempty"` // node IDs, internal nodes only
+	Next     int      `json:"next"`               // next leaf ID, -1 for the last leaf and internal nodes
+}
+
+// TreeStructure is the topology of a B+ tree, for index visualization
+type TreeStructure struct {
+	Order      int        `json:"order"`
+	Depth      int        `json:"depth"` // levels, 1 for a tree that is a single leaf
+	Nodes      []NodeInfo `json:"nodes"`
+	NodeCount  int        `json:"nodeCount"`
+	LeafCount  int        `json:"leafCount"`
+	KeyCount   int        `json:"keyCount"`   // keys stored in the leaves
+	FillFactor float64    `json:"fillFactor"` // keys held by all nodes over their capacity (order-1 each)
+}
+
+// Structure walks the tree breadth-first and returns its nodes and fill statistics
+func (t *BTree) Structure() TreeStructure {
+	structure := TreeStructure{Order: t.order, Nodes: make([]NodeInfo, 0)}
+
+	ids := map[*BNode]int{t.root: 0}
+	queue := []*BNode{t.root}
+	levels := []int{0}
+	visited := make([]*BNode, 0)
+	totalKeys := 0
+	for len(queue) > 0 {
+		node, level := queue[0], levels[0]
+		queue, levels = queue[1:], levels[1:]
+
+		info := NodeInfo{
+			ID:    ids[node],
+			Level: level,
+			Leaf:  node.isLeaf,
+			Keys:  append([]uint64{}, node.keys...),
+			Next:  -1,
+		}
+		for _, child := range node.children {
+			ids[child] = len(ids)
+			info.Children = append(info.Children, ids[child])
+			queue = append(queue, child)
+			levels = append(levels, level+1)
+		}
+		if node.isLeaf {
+			structure.LeafCount++
+			structure.KeyCount += len(node.keys)
+		}
+
+		structure.Nodes = append(structure.Nodes, info)
+		visited = append(visited, node)
+		structure.Depth = max(structure.Depth, level+1)
+		totalKeys += len(node.keys)
+	}
+
+	// Leaves link to the next leaf, resolved once every node has an ID
+	for i, node := range visited {
+		if next, ok := ids[node.next]; ok && node.isLeaf {
+			structure.Nodes[i].Next = next
+		}
+	}
+
+	structure.NodeCount = len(structure.Nodes)
+	if capacity := structure.NodeCount * (t.order - 1); capacity > 0 {
+		structure.FillFactor = float64(totalKeys) / float64(capacity)
+	}
+	return structure
+}
+
+// BucketInfo describes one bucket of an extensible hash
+type BucketInfo struct {
+	ID         int   `json:"id"`
+	LocalDepth int   `json:"localDepth"`
+	Entries    int   `json:"entries"`
+	Slots      []int `json:"slots"` // directory slots pointing at the bucket
+}
+
+// HashStructure is the layout of an extensible hash, for index visualization
+type HashStructure struct {
+	GlobalDepth   int          `json:"globalDepth"`
+	BucketSize    int          `json:"bucketSize"`
+	DirectorySize int          `json:"directorySize"`
+	Buckets       []BucketInfo `json:"buckets"`
+	EntryCount    int          `json:"entryCount"`
+	Occupancy     float64      `json:"occupancy"` // entries over the capacity of all buckets
+}
+
+// Structure returns the buckets of the hash in directory order with their occupancy
+func (h *ExtensibleHash) Structure() HashStructure {
+	structure := HashStructure{
+		GlobalDepth:   h.globalDepth,
+		BucketSize:    h.bucketSize,
+		DirectorySize: len(h.directory),
+		Buckets:       make([]BucketInfo, 0),
+	}
+
+	ids := make(map[*Bucket]int)
+	for slot, bucket := range h.directory {
+		id, seen := ids[bucket]
+		if !seen {
+			id = len(structure.Buckets)
+			ids[bucket] = id
+			structure.Buckets = append(structure.Buckets, BucketInfo{ID: id, LocalDepth: bucket.localDepth, Entries: len(bucket.entries)})
+			structure.EntryCount += len(bucket.entries)
+		}
+		structure.Buckets[id].Slots = append(structure.Buckets[id].Slots, slot)
+	}
+
+	if capacity := len(structure.Buckets) * h.bucketSize; capacity > 0 {
+		structure.Occupancy = float64(structure.EntryCount) / float64(capacity)
+	}
+	return structure
+}
@@ -0,0 +1,275 @@
+package migrate
+
+import (
+	"BinaryCRUD/backend/utils"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Legacy persistence format (separator based, no magic, no length prefixes):
+// Header:     [entitiesCount(4)][0x1F][tombstoneCount(4)][0x1F][nextId(4)][0x1E]
+// Item:       [ID(2)][tombstone(1)][0x1F][nameSize(2)][name][0x1F][price(4)][0x1E]
+// Collection: [ID(2)][tombstone(1)][0x1F][nameSize(2)][name][0x1F][totalPrice(4)][0x1F][itemCount(4)][0x1F][itemIDs(2 each)][0x1E]
+const (
+	// UnitSeparator separates fields inside a legacy record
+	UnitSeparator = 0x1F
+
+	// RecordSeparator marks the end of a legacy header or record
+	RecordSeparator = 0x1E
+
+	// legacyHeaderSize is the size of the legacy header including separators
+	legacyHeaderSize = utils.HeaderFieldSize*3 + 3
+)
+
+// RecordKind selects the record layout used by a legacy file
+type RecordKind int
+
+const (
+	// KindItem is the layout of items.bin
+	KindItem RecordKind = iota
+	// KindCollection is the layout shared by orders.bin and promotions.bin
+	KindCollection
+)
+
+// legacyHeader holds the counters stored in a header of either format
+type legacyHeader struct {
+	entitiesCount  int
+	tombstoneCount int
+	nextId         int
+}
+
+// ConvertLegacyToDAO reads a legacy separator-format file and writes it in the DAO format
+// IDs and tombstones are preserved and keep their utils.LegacyIDSize width, returns the number of records converted
+func ConvertLegacyToDAO(srcPath, dstPath string, kind RecordKind) (int, error) {
+	data, err := os.ReadFile(srcPath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read legacy file: %w", err)
+	}
+
+	header, records, err := parseLegacyFile(data, kind)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse %s: %w", srcPath, err)
+	}
+
+	basename := utils.LogicalBinName(filepath.Base(dstPath))
+	filename := basename[:len(basename)-len(filepath.Ext(basename))]
+
+	var output bytes.Buffer
+	headerBytes, err := utils.WriteHeaderWithIDSize(filename, 0, utils.LegacyIDSize, header.entitiesCount, header.tombstoneCount, header.nextId)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create header: %w", err)
+	}
+	output.Write(headerBytes)
+
+	for _, record := range records {
+		lengthBytes, err := utils.WriteFixedNumber(utils.RecordLengthSize, uint64(len(record)))
+		if err != nil {
+			return 0, fmt.Errorf("failed to write record length: %w", err)
+		}
+		output.Write(lengthBytes)
+		output.Write(record)
+	}
+
+	if err := replaceFile(dstPath, output.Bytes()); err != nil {
+		return 0, err
+	}
+
+	return len(records), nil
+}
+
+// ConvertDAOToLegacy reads a DAO-format file and writes it in the legacy separator format
+// IDs and tombstones are preserved, narrowed to utils.LegacyIDSize; returns the number of records converted
+func ConvertDAOToLegacy(srcPath, dstPath string, kind RecordKind) (int, error) {
+	file, err := os.Open(srcPath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open file: %w", err)
+	}
+	_, entitiesCount, tombstoneCount, nextId, err := utils.ReadHeader(file)
+	file.Close()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read header: %w", err)
+	}
+
+	entries, err := utils.SplitFileIntoEntries(srcPath)
+	if err != nil {
+		return 0, err
+	}
+
+	var output bytes.Buffer
+	output.Write(encodeLegacyHeader(legacyHeader{entitiesCount, tombstoneCount, nextId}))
+
+	for _, entry := range entries {
+		record, err := encodeLegacyRecord(entry.Data, kind, entry.IDSize)
+		if err != nil {
+			return 0, fmt.Errorf("failed to convert record at offset %d: %w", entry.Position, err)
+		}
+		output.Write(record)
+	}
+
+	if err := replaceFile(dstPath, output.Bytes()); err != nil {
+		return 0, err
+	}
+
+	return len(entries), nil
+}
+
+// parseLegacyFile decodes a legacy file into its header and DAO-format record bodies
+// Each returned record is [ID(2)][tombstone(1)][payload] without length prefix
+func parseLegacyFile(data []byte, kind RecordKind) (legacyHeader, [][]byte, error) {
+	r := &legacyReader{data: data}
+
+	var header legacyHeader
+	if len(data) < legacyHeaderSize {
+		return header, nil, fmt.Errorf("file too small for legacy header")
+	}
+	header.entitiesCount = int(decodeUint(r.bytes(utils.HeaderFieldSize)))
+	r.expect(UnitSeparator)
+	header.tombstoneCount = int(decodeUint(r.bytes(utils.HeaderFieldSize)))
+	r.expect(UnitSeparator)
+	header.nextId = int(decodeUint(r.bytes(utils.HeaderFieldSize)))
+	r.expect(RecordSeparator)
+	if r.err != nil {
+		return header, nil, fmt.Errorf("invalid legacy header: %w", r.err)
+	}
+
+	var records [][]byte
+	for r.pos < len(data) {
+		start := r.pos
+		var record []byte
+
+		record = append(record, r.bytes(utils.LegacyIDSize+utils.TombstoneSize)...)
+		r.expect(UnitSeparator)
+		nameSize := r.bytes(2)
+		record = append(record, nameSize...)
+		record = append(record, r.bytes(int(decodeUint(nameSize)))...)
+		r.expect(UnitSeparator)
+
+		switch kind {
+		case KindItem:
+			record = append(record, r.bytes(4)...)
+		case KindCollection:
+			record = append(record, r.bytes(4)...)
+			r.expect(UnitSeparator)
+			itemCount := r.bytes(4)
+			record = append(record, itemCount...)
+			r.expect(UnitSeparator)
+			record = append(record, r.bytes(int(decodeUint(itemCount))*utils.LegacyIDSize)...)
+		default:
+			return header, nil, fmt.Errorf("unknown record kind %d", kind)
+		}
+		r.expect(RecordSeparator)
+
+		if r.err != nil {
+			return header, nil, fmt.Errorf("invalid record at offset %d: %w", start, r.err)
+		}
+		records = append(records, record)
+	}
+
+	return header, records, nil
+}
+
+// encodeLegacyHeader encodes header counters in the legacy format
+func encodeLegacyHeader(header legacyHeader) []byte {
+	buf := make([]byte, 0, legacyHeaderSize)
+	buf = binary.BigEndian.AppendUint32(buf, uint32(header.entitiesCount))
+	buf = append(buf, UnitSeparator)
+	buf = binary.BigEndian.AppendUint32(buf, uint32(header.tombstoneCount))
+	buf = append(buf, UnitSeparator)
+	buf = binary.BigEndian.AppendUint32(buf, uint32(header.nextId))
+	return append(buf, RecordSeparator)
+}
+
+// encodeLegacyRecord inserts the legacy separators into a DAO-format record body with IDs of idSize bytes
+func encodeLegacyRecord(entry []byte, kind RecordKind, idSize int) ([]byte, error) {
+	r := &legacyReader{data: entry}
+	var record []byte
+
+	record = append(record, r.legacyID(idSize)...)
+	record = append(record, r.bytes(utils.TombstoneSize)...)
+	record = append(record, UnitSeparator)
+	nameSize := r.bytes(2)
+	record = append(record, nameSize...)
+	record = append(record, r.bytes(int(decodeUint(nameSize)))...)
+	record = append(record, UnitSeparator)
+
+	switch kind {
+	case KindItem:
+		record = append(record, r.bytes(4)...)
+	case KindCollection:
+		record = append(record, r.bytes(4)...)
+		record = append(record, UnitSeparator)
+		itemCount := r.bytes(4)
+		record = append(record, itemCount...)
+		record = append(record, UnitSeparator)
+		for i := uint64(0); i < decodeUint(itemCount) && r.err == nil; i++ {
+			record = append(record, r.legacyID(idSize)...)
+		}
+	default:
+		return nil, fmt.Errorf("unknown record kind %d", kind)
+	}
+
+	if r.err != nil {
+		return nil, r.err
+	}
+	if r.pos != len(entry) {
+		return nil, fmt.Errorf("unexpected %d trailing bytes", len(entry)-r.pos)
+	}
+
+	return append(record, RecordSeparator), nil
+}
+
+// legacyReader is a bounds-checked cursor that remembers the first error
+type legacyReader struct {
+	data []byte
+	pos  int
+	err  error
+}
+
+// bytes returns the next n bytes, or nil once an error has occurred
+func (r *legacyReader) bytes(n int) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if n < 0 || r.pos+n > len(r.data) {
+		r.err = fmt.Errorf("unexpected end of data at offset %d", r.pos)
+		return nil
+	}
+	b := r.data[r.pos : r.pos+n]
+	r.pos += n
+	return b
+}
+
+// legacyID reads the next ID of idSize bytes and returns it at utils.LegacyIDSize
+// An ID too large for the legacy width sets the reader error
+func (r *legacyReader) legacyID(idSize int) []byte {
+	id := r.bytes(idSize)
+	if r.err != nil {
+		return nil
+	}
+	narrowed, err := utils.WriteFixedNumber(utils.LegacyIDSize, decodeUint(id))
+	if err != nil {
+		r.err = fmt.Errorf("ID at offset %d does not fit the legacy format: %w", r.pos-idSize, err)
+		return nil
+	}
+	return narrowed
+}
+
+// decodeUint decodes a big-endian unsigned number of any size
+func decodeUint(b []byte) uint64 {
+	var value uint64
+	for _, v := range b {
+		value = value<<8 | uint64(v)
+	}
+	return value
+}
+
+// expect consumes a separator byte
+func (r *legacyReader) expect(separator byte) {
+	b := r.bytes(1)
+	if r.err == nil && b[0] != separator {
+		r.err = fmt.Errorf("expected separator 0x%02X at offset %d, got 0x%02X", separator, r.pos-1, b[0])
+	}
+}
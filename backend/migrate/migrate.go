@@ -0,0 +1,243 @@
+package migrate
+
+import (
+	"BinaryCRUD/backend/utils"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Result describes the outcome of migrating a single file
+type Result struct {
+	File        string
+	FromVersion int
+	ToVersion   int
+	Migrated    bool
+}
+
+// upgradeStep rewrites the contents of a file from one format version to the next
+type upgradeStep func(data []byte) ([]byte, error)
+
+// upgradeSteps maps a source version to the step that upgrades it by exactly one version
+var upgradeSteps = map[int]upgradeStep{
+	utils.FormatVersionLegacy:         upgradeLegacyMagic,
+	utils.FormatVersionFlags - 1:      upgradeHeaderFlags,
+	utils.FormatVersionIDSize - 1:     upgradeHeaderIDSize,
+	utils.FormatVersionWideNextID - 1: upgradeWideNextID,
+}
+
+// DetectVersion reads the format version of a binary data file
+func DetectVersion(path string) (int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	return utils.ReadHeaderVersion(file)
+}
+
+// MigrateFile upgrades a binary data file in place to the target format version
+// The upgraded contents are written to a temp file which then replaces the original,
+// so a failure midway leaves the original file untouched
+func MigrateFile(path string, targetVersion int) (*Result, error) {
+	if targetVersion < utils.FormatVersionLegacy || targetVersion > utils.CurrentFormatVersion {
+		return nil, fmt.Errorf("unsupported target version %d", targetVersion)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	version, err := utils.VersionFromMagic(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to detect version of %s: %w", path, err)
+	}
+
+	result := &Result{
+		File:        filepath.Base(path),
+		FromVersion: version,
+		ToVersion:   version,
+	}
+
+	if version > targetVersion {
+		return nil, fmt.Errorf("cannot downgrade %s from version %d to %d", path, version, targetVersion)
+	}
+	if version == targetVersion {
+		return result, nil
+	}
+
+	// Apply one step per version until the target is reached
+	for version < targetVersion {
+		step, ok := upgradeSteps[version]
+		if !ok {
+			return nil, fmt.Errorf("no upgrade path from version %d", version)
+		}
+		data, err = step(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to upgrade %s from version %d: %w", path, version, err)
+		}
+		version++
+	}
+
+	if err := replaceFile(path, data); err != nil {
+		return nil, err
+	}
+
+	result.ToVersion = version
+	result.Migrated = true
+	return result, nil
+}
+
+// MigrateDir upgrades every .bin file in a directory to the target format version
+func MigrateDir(dir string, targetVersion int) ([]*Result, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []*Result{}, nil
+		}
+		return nil, fmt.Errorf("failed to read directory %s: %w", dir, err)
+	}
+
+	results := make([]*Result, 0, len(entries))
+	for _, entry := range entries {
+		// Older generations kept after a compaction are not migrated
+		if entry.IsDir() || !utils.IsCurrentBinFile(dir, entry.Name()) {
+			continue
+		}
+
+		result, err := MigrateFile(filepath.Join(dir, entry.Name()), targetVersion)
+		if err != nil {
+			return results, err
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// replaceFile atomically replaces a file's contents using temp file + rename
+func replaceFile(path string, data []byte) error {
+	tmpPath := path + ".tmp"
+	tmpFile, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+
+	if _, err := tmpFile.Write(data); err != nil {
+		tmpFile.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write temp file: %w", err)
+	}
+
+	if err := tmpFile.Sync(); err != nil {
+		tmpFile.Close()
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to sync temp file: %w", err)
+	}
+
+	if err := tmpFile.Close(); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to close temp file: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to replace %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// upgradeLegacyMagic upgrades a version 1 file to version 2
+// The layout is unchanged, only the magic bytes now carry the format version
+func upgradeLegacyMagic(data []byte) ([]byte, error) {
+	magic, err := utils.MagicForVersion(utils.FormatVersionLegacy + 1)
+	if err != nil {
+		return nil, err
+	}
+
+	upgraded := make([]byte, len(data))
+	copy(upgraded, data)
+	copy(upgraded[:utils.MagicSize], magic)
+	return upgraded, nil
+}
+
+// upgradeHeaderFlags upgrades a version 2 file to version 3
+// An empty flags byte is inserted after the header counts, so names keep following the global crypto setting
+func upgradeHeaderFlags(data []byte) ([]byte, error) {
+	if len(data) < utils.MagicSize+utils.FilenameLengthSize {
+		return nil, fmt.Errorf("data too short for header")
+	}
+	headerSize := utils.HeaderSizeForVersion(utils.FormatVersionFlags-1, int(data[utils.MagicSize]))
+	if len(data) < headerSize {
+		return nil, fmt.Errorf("data too short for header with filename")
+	}
+
+	magic, err := utils.MagicForVersion(utils.FormatVersionFlags)
+	if err != nil {
+		return nil, err
+	}
+
+	upgraded := make([]byte, 0, len(data)+utils.HeaderFlagsSize)
+	upgraded = append(upgraded, magic...)
+	upgraded = append(upgraded, data[utils.MagicSize:headerSize]...)
+	upgraded = append(upgraded, 0)
+	upgraded = append(upgraded, data[headerSize:]...)
+	return upgraded, nil
+}
+
+// upgradeHeaderIDSize upgrades a version 3 file to version 4
+// The ID size byte is inserted after the flags, recording the width the records were written with
+func upgradeHeaderIDSize(data []byte) ([]byte, error) {
+	if len(data) < utils.MagicSize+utils.FilenameLengthSize {
+		return nil, fmt.Errorf("data too short for header")
+	}
+	headerSize := utils.HeaderSizeForVersion(utils.FormatVersionIDSize-1, int(data[utils.MagicSize]))
+	if len(data) < headerSize {
+		return nil, fmt.Errorf("data too short for header with filename")
+	}
+
+	magic, err := utils.MagicForVersion(utils.FormatVersionIDSize)
+	if err != nil {
+		return nil, err
+	}
+
+	upgraded := make([]byte, 0, len(data)+utils.HeaderIDSizeSize)
+	upgraded = append(upgraded, magic...)
+	upgraded = append(upgraded, data[utils.MagicSize:headerSize]...)
+	upgraded = append(upgraded, utils.LegacyIDSize)
+	upgraded = append(upgraded, data[headerSize:]...)
+	return upgraded, nil
+}
+
+// upgradeWideNextID upgrades a version 4 file to version 5
+// The nextId header field is widened to 8 bytes so the file can later take 8 byte IDs,
+// the records keep the ID size they were written with
+func upgradeWideNextID(data []byte) ([]byte, error) {
+	if len(data) < utils.MagicSize+utils.FilenameLengthSize {
+		return nil, fmt.Errorf("data too short for header")
+	}
+	filenameLen := int(data[utils.MagicSize])
+	headerSize := utils.HeaderSizeForVersion(utils.FormatVersionWideNextID-1, filenameLen)
+	if len(data) < headerSize {
+		return nil, fmt.Errorf("data too short for header with filename")
+	}
+
+	magic, err := utils.MagicForVersion(utils.FormatVersionWideNextID)
+	if err != nil {
+		return nil, err
+	}
+
+	nextIdStart := utils.MagicSize + utils.FilenameLengthSize + filenameLen + utils.HeaderFieldSize*2
+	padding := make([]byte, utils.WideNextIDFieldSize-utils.HeaderFieldSize)
+
+	// Fixed numbers are big endian, so zero padding in front keeps the value
+	upgraded := make([]byte, 0, len(data)+len(padding))
+	upgraded = append(upgraded, magic...)
+	upgraded = append(upgraded, data[utils.MagicSize:nextIdStart]...)
+	upgraded = append(upgraded, padding...)
+	upgraded = append(upgraded, data[nextIdStart:]...)
+	return upgraded, nil
+}
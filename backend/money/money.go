@@ -0,0 +1,221 @@
+package money
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// DefaultLocale is the locale amounts are written in when none is given
+const DefaultLocale = "en-US"
+
+// maxDigits bounds the whole part of a parsed amount, so its value in cents fits in a uint64
+const maxDigits = 16
+
+// Locale describes how a locale writes amounts of money
+type Locale struct {
+	Decimal     string
+	Thousand    string
+	SymbolAfter bool // symbol goes after the amount
+	SymbolSpace bool // a space separates the symbol from the amount
+}
+
+// locales lists the conventions of the known locales
+var locales = map[string]Locale{
+	"en-US": {Decimal: ".", Thousand: ","},
+	"en-GB": {Decimal: ".", Thousand: ","},
+	"pt-BR": {Decimal: ",", Thousand: ".", SymbolSpace: true},
+	"de-DE": {Decimal: ",", Thousand: ".", SymbolAfter: true, SymbolSpace: true},
+	"es-ES": {Decimal: ",", Thousand: ".", SymbolAfter: true, SymbolSpace: true},
+	"fr-FR": {Decimal: ",", Thousand: "\u202f", SymbolAfter: true, SymbolSpace: true},
+}
+
+// symbols lists the symbols of known currencies, others are written with their code
+var symbols = map[string]string{
+	"USD": "$",
+	"GBP": "£",
+	"EUR": "€",
+	"BRL": "R$",
+}
+
+// homeLocales is the locale each known currency is written in by default
+var homeLocales = map[string]string{
+	"USD": "en-US",
+	"GBP": "en-GB",
+	"EUR": "de-DE",
+	"BRL": "pt-BR",
+}
+
+// LookupLocale returns the conventions of a locale, DefaultLocale for an empty name
+func LookupLocale(name string) (Locale, error) {
+	if name == "" {
+		name = DefaultLocale
+	}
+	locale, ok := locales[name]
+	if !ok {
+		return Locale{}, fmt.Errorf("unknown locale %q", name)
+	}
+	return locale, nil
+}
+
+// HomeLocale returns the locale a currency is written in by default, DefaultLocale for unknown currencies
+func HomeLocale(code string) string {
+	if name, ok := homeLocales[code]; ok {
+		return name
+	}
+	return DefaultLocale
+}
+
+// Format writes an amount in cents of a currency with the conventions of a locale, e.g. $1,234.56 or 1.234,56 €
+// Currencies without a known symbol are written with their code after the amount, as in 1,234.56 CHF
+func Format(cents uint64, code string, locale Locale) string {
+	units := fmt.Sprintf("%d", cents/100)
+	var grouped strings.Builder
+	for i, digit := range units {
+		if i > 0 && (len(units)-i)%3 == 0 {
+			grouped.WriteString(locale.Thousand)
+		}
+		grouped.WriteRune(digit)
+	}
+	amount := fmt.Sprintf("%s%s%02d", grouped.String(), locale.Decimal, cents%100)
+
+	symbol, known := symbols[code]
+	if !known {
+		return amount + " " + code
+	}
+	space := ""
+	if locale.SymbolSpace {
+		space = " "
+	}
+	if locale.SymbolAfter {
+		return amount + space + symbol
+	}
+	return symbol + space + amount
+}
+
+// Parse reads an amount of money written in any of the known locales and returns it in cents
+// A currency symbol or code may come before or after the amount; spaces only group thousands. When both
+// "." and "," appear the last one is the decimal separator; a single one followed by exactly three digits
+// groups thousands, as in 1.234 or 1,234. Group separators must split the whole part in groups of three digits
+// Fractions finer than a cent are rounded half to even, so 0.125 is 12 cents and 0.135 is 14
+func Parse(text string) (uint64, error) {
+	if strings.ContainsRune(text, '-') {
+		return 0, fmt.Errorf("negative amount %q", text)
+	}
+	number := trimCurrency(text)
+	for _, r := range number {
+		if (r < '0' || r > '9') && r != '.' && r != ',' && !unicode.IsSpace(r) {
+			return 0, fmt.Errorf("invalid character %q in amount %q", r, text)
+		}
+	}
+
+	whole, fraction, err := splitDecimal(number)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount %q: %w", text, err)
+	}
+	whole, err = ungroup(whole)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount %q: %w", text, err)
+	}
+	if whole == "" && fraction == "" {
+		return 0, fmt.Errorf("no amount in %q", text)
+	}
+	whole = strings.TrimLeft(whole, "0")
+	if len(whole) > maxDigits {
+		return 0, fmt.Errorf("amount %q is too large", text)
+	}
+
+	var cents uint64
+	for _, digit := range whole + (fraction + "00")[:2] {
+		cents = cents*10 + uint64(digit-'0')
+	}
+	if len(fraction) > 2 && roundsUp(fraction[2:], cents%2 == 1) {
+		cents++
+	}
+	return cents, nil
+}
+
+// trimCurrency removes the currency symbol or code written before or, failing that, after an amount, and the spaces around it
+func trimCurrency(text string) string {
+	isCurrency := func(r rune) bool {
+		return unicode.IsLetter(r) || unicode.Is(unicode.Sc, r)
+	}
+	text = strings.TrimFunc(text, unicode.IsSpace)
+	if trimmed := strings.TrimLeftFunc(text, isCurrency); trimmed != text {
+		text = trimmed
+	} else {
+		text = strings.TrimRightFunc(text, isCurrency)
+	}
+	return strings.TrimFunc(text, unicode.IsSpace)
+}
+
+// splitDecimal splits a number into its whole part, still holding group separators, and its fraction
+// Fails when the decimal separator appears more than once
+func splitDecimal(number string) (string, string, error) {
+	last := strings.LastIndexAny(number, ".,")
+	if last < 0 {
+		return number, "", nil
+	}
+	separator := number[last : last+1]
+	other := ","
+	if separator == "," {
+		other = "."
+	}
+	count := strings.Count(number, separator)
+	if !strings.Contains(number, other) {
+		// Only one kind of separator: repeated, or a single one before three digits, groups thousands
+		grouped := count > 1 || (len(number)-last-1 == 3 && last > 0 && last <= 3 && number[0] != '0')
+		if grouped {
+			return number, "", nil
+		}
+	} else if count > 1 {
+		return "", "", fmt.Errorf("more than one decimal separator %q", separator)
+	}
+
+	fraction := number[last+1:]
+	if strings.IndexFunc(fraction, unicode.IsSpace) >= 0 {
+		return "", "", fmt.Errorf("space in the fraction")
+	}
+	return number[:last], fraction, nil
+}
+
+// ungroup removes the group separators from the whole part of a number
+// Fails when separators of different kinds are mixed or don't split the digits in groups of three
+func ungroup(whole string) (string, error) {
+	var kind rune
+	var digits strings.Builder
+	groups := []int{0}
+	for _, r := range whole {
+		if r >= '0' && r <= '9' {
+			digits.WriteRune(r)
+			groups[len(groups)-1]++
+			continue
+		}
+		if unicode.IsSpace(r) {
+			r = ' '
+		}
+		if kind != 0 && r != kind {
+			return "", fmt.Errorf("mixed group separators")
+		}
+		kind = r
+		groups = append(groups, 0)
+	}
+
+	for i, size := range groups {
+		if len(groups) > 1 && ((i == 0 && (size == 0 || size > 3)) || (i > 0 && size != 3)) {
+			return "", fmt.Errorf("misplaced group separator")
+		}
+	}
+	return digits.String(), nil
+}
+
+// roundsUp reports whether the digits past the cents round the amount up, half to even
+func roundsUp(rest string, odd bool) bool {
+	if rest[0] != '5' {
+		return rest[0] > '5'
+	}
+	if strings.TrimRight(rest[1:], "0") != "" {
+		return true
+	}
+	return odd
+}
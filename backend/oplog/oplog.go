@@ -0,0 +1,430 @@
+package oplog
+
+import (
+	"BinaryCRUD/backend/crypto"
+	"BinaryCRUD/backend/utils"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// OpType identifies a mutating operation
+type OpType byte
+
+const (
+	OpAddItem OpType = iota + 1
+	OpDeleteItem
+	OpCreateOrder
+	OpDeleteOrder
+	OpCreatePromotion
+	OpDeletePromotion
+	OpApplyPromotion
+	OpRemovePromotion
+	OpUpdateOrder
+	OpUpdatePromotion
+	OpUpdateItem
+)
+
+// String returns the operation name
+func (t OpType) String() string {
+	switch t {
+	case OpAddItem:
+		return "AddItem"
+	case OpDeleteItem:
+		return "DeleteItem"
+	case OpCreateOrder:
+		return "CreateOrder"
+	case OpDeleteOrder:
+		return "DeleteOrder"
+	case OpCreatePromotion:
+		return "CreatePromotion"
+	case OpDeletePromotion:
+		return "DeletePromotion"
+	case OpApplyPromotion:
+		return "ApplyPromotion"
+	case OpRemovePromotion:
+		return "RemovePromotion"
+	case OpUpdateOrder:
+		return "UpdateOrder"
+	case OpUpdatePromotion:
+		return "UpdatePromotion"
+	case OpUpdateItem:
+		return "UpdateItem"
+	default:
+		return fmt.Sprintf("Unknown(%d)", byte(t))
+	}
+}
+
+// sealsName reports whether operations of the type log their name encrypted
+// Orders and promotions keep their names encrypted in their files, so the log must not hold them in plain text
+func (t OpType) sealsName() bool {
+	switch t {
+	case OpCreateOrder, OpDeleteOrder, OpUpdateOrder, OpCreatePromotion, OpDeletePromotion, OpUpdatePromotion:
+		return true
+	}
+	return false
+}
+
+// Operation is a single logged mutation
+// ID is the entity ID (the assigned ID for creates), OrderID/PromotionID are used by link operations
+// Name is always plain text in memory; order and promotion names are encrypted with the field cipher in the file
+type Operation struct {
+	Timestamp   time.Time
+	Type        OpType
+	ID          uint64
+	Name        string
+	Price       uint64
+	ItemIDs     []uint64
+	OrderID     uint64
+	PromotionID uint64
+	Extensions  map[byte][]byte // record extension fields such as a promotion discount
+}
+
+// Magic identifies an operation log file whose records hold IDs of IDSize bytes
+var Magic = []byte{'O', 'P', 'L', '4'}
+
+// LegacyMagic identifies an operation log written with utils.LegacyIDSize byte IDs
+// Such logs are still read and appended to at their own width
+var LegacyMagic = []byte{'O', 'P', 'L', 'G'}
+
+// IDSize is the width of the IDs in the records of new logs
+const IDSize = utils.DefaultIDSize
+
+// nameEncryptedFlag is set in the name length of a record whose name is encrypted
+const nameEncryptedFlag = 0x8000
+
+// Log is an append-only operation log file
+// File format: [magic(4)] then records [recordLength(4)][record...]
+// Record: [timestamp(8, unix nanos)][type(1)][ID(n)][nameLen(2)][name][price(4)][itemCount(4)][itemIDs(n each)][orderID(n)][promotionID(n)][extensions...]
+// where n is the ID width given by the magic and the high bit of nameLen marks a name encrypted with the field cipher
+type Log struct {
+	path string
+	mu   sync.Mutex
+}
+
+// New creates a Log backed by the given file, the file is created on first append
+func New(path string) *Log {
+	return &Log{path: path}
+}
+
+// Path returns the log file path
+func (l *Log) Path() string {
+	return l.path
+}
+
+// IDSizeFromMagic returns the ID width of the log that data starts with
+func IDSizeFromMagic(data []byte) (int, error) {
+	switch {
+	case bytes.HasPrefix(data, Magic):
+		return IDSize, nil
+	case bytes.HasPrefix(data, LegacyMagic):
+		return utils.LegacyIDSize, nil
+	}
+	return 0, fmt.Errorf("invalid oplog: bad magic bytes")
+}
+
+// Append writes an operation to the end of the log and syncs it to disk
+func (l *Log) Append(op Operation) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(l.path), 0700); err != nil {
+		return fmt.Errorf("failed to create oplog directory: %w", err)
+	}
+
+	file, err := os.OpenFile(l.path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open oplog: %w", err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat oplog: %w", err)
+	}
+
+	var buf bytes.Buffer
+	idSize := IDSize
+	if info.Size() == 0 {
+		buf.Write(Magic)
+	} else {
+		magic := make([]byte, len(Magic))
+		if _, err := file.ReadAt(magic, 0); err != nil {
+			return fmt.Errorf("failed to read oplog magic: %w", err)
+		}
+		if idSize, err = IDSizeFromMagic(magic); err != nil {
+			return err
+		}
+	}
+
+	record, err := encodeOperation(op, idSize)
+	if err != nil {
+		return err
+	}
+	binary.Write(&buf, binary.BigEndian, uint32(len(record)))
+	buf.Write(record)
+
+	if _, err := file.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to write oplog: %w", err)
+	}
+
+	return file.Sync()
+}
+
+// ReadAll returns every operation in the log, in append order
+// A truncated final record (from a crash mid-append) is ignored
+func (l *Log) ReadAll() ([]Operation, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	data, err := os.ReadFile(l.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []Operation{}, nil
+		}
+		return nil, fmt.Errorf("failed to read oplog: %w", err)
+	}
+
+	idSize, err := IDSizeFromMagic(data)
+	if err != nil {
+		return nil, err
+	}
+
+	ops, _, err := DecodeRecords(data, len(Magic), idSize)
+	return ops, err
+}
+
+// ReencryptNames rewrites every encrypted name in the log with reencrypt, e.g. from an old data key to a new one
+// The log is replaced atomically once every name was rewritten; returns how many names were
+func (l *Log) ReencryptNames(reencrypt func(stored []byte) ([]byte, error)) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	data, err := os.ReadFile(l.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("failed to read oplog: %w", err)
+	}
+	idSize, err := IDSizeFromMagic(data)
+	if err != nil {
+		return 0, err
+	}
+
+	var buf bytes.Buffer
+	buf.Write(data[:len(Magic)])
+	count := 0
+	offset := len(Magic)
+	for offset+4 <= len(data) {
+		length := int(binary.BigEndian.Uint32(data[offset : offset+4]))
+		if offset+4+length > len(data) {
+			break
+		}
+		record := data[offset+4 : offset+4+length]
+		offset += 4 + length
+
+		nameAt := 9 + idSize
+		if len(record) < nameAt+2 {
+			return 0, fmt.Errorf("invalid oplog record at offset %d: record too short", offset-4-length)
+		}
+		nameLen := int(binary.BigEndian.Uint16(record[nameAt : nameAt+2]))
+		if nameLen&nameEncryptedFlag != 0 {
+			size := nameLen &^ nameEncryptedFlag
+			if len(record) < nameAt+2+size {
+				return 0, fmt.Errorf("invalid oplog record at offset %d: name exceeds record", offset-4-length)
+			}
+			name, err := reencrypt(record[nameAt+2 : nameAt+2+size])
+			if err != nil {
+				return 0, fmt.Errorf("failed to re-encrypt oplog name: %w", err)
+			}
+			rewritten := make([]byte, 0, len(record)-size+len(name))
+			rewritten = append(rewritten, record[:nameAt]...)
+			rewritten = binary.BigEndian.AppendUint16(rewritten, uint16(len(name)|nameEncryptedFlag))
+			rewritten = append(rewritten, name...)
+			record = append(rewritten, record[nameAt+2+size:]...)
+			count++
+		}
+		binary.Write(&buf, binary.BigEndian, uint32(len(record)))
+		buf.Write(record)
+	}
+	if count == 0 {
+		return 0, nil
+	}
+
+	tempPath := l.path + ".tmp"
+	if err := os.WriteFile(tempPath, buf.Bytes(), 0600); err != nil {
+		return 0, fmt.Errorf("failed to write oplog: %w", err)
+	}
+	if err := os.Rename(tempPath, l.path); err != nil {
+		os.Remove(tempPath)
+		return 0, fmt.Errorf("failed to replace oplog: %w", err)
+	}
+	return count, nil
+}
+
+// DecodeRecords parses the length-prefixed records of log data starting at offset
+// It stops before an incomplete final record and returns the offset just past the last complete one
+func DecodeRecords(data []byte, offset int, idSize int) ([]Operation, int, error) {
+	ops := []Operation{}
+	for offset+4 <= len(data) {
+		length := int(binary.BigEndian.Uint32(data[offset : offset+4]))
+		if offset+4+length > len(data) {
+			break
+		}
+		op, err := decodeOperation(data[offset+4:offset+4+length], idSize)
+		if err != nil {
+			return nil, offset, fmt.Errorf("invalid oplog record at offset %d: %w", offset, err)
+		}
+		ops = append(ops, op)
+		offset += 4 + length
+	}
+	return ops, offset, nil
+}
+
+// encodeOperation serializes an operation record with IDs of idSize bytes
+func encodeOperation(op Operation, idSize int) ([]byte, error) {
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.BigEndian, op.Timestamp.UnixNano())
+	buf.WriteByte(byte(op.Type))
+
+	idBytes, err := utils.WriteFixedNumber(idSize, op.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode ID: %w", err)
+	}
+	buf.Write(idBytes)
+
+	name := []byte(op.Name)
+	nameSize := uint64(len(name))
+	if op.Type.sealsName() && len(name) > 0 {
+		fieldCipher, err := crypto.GetFieldCipher(utils.KeysDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get field cipher: %w", err)
+		}
+		if name, err = fieldCipher.EncryptToBytesIf(true, op.Name); err != nil {
+			return nil, fmt.Errorf("failed to encrypt name: %w", err)
+		}
+		nameSize = uint64(len(name)) | nameEncryptedFlag
+	}
+	if len(name) >= nameEncryptedFlag {
+		return nil, fmt.Errorf("name of %d bytes is too long", len(name))
+	}
+	nameSizeBytes, err := utils.WriteFixedNumber(2, nameSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode name size: %w", err)
+	}
+	buf.Write(nameSizeBytes)
+	buf.Write(name)
+
+	priceBytes, err := utils.WriteFixedNumber(4, op.Price)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode price: %w", err)
+	}
+	buf.Write(priceBytes)
+
+	binary.Write(&buf, binary.BigEndian, uint32(len(op.ItemIDs)))
+	for _, itemID := range op.ItemIDs {
+		b, err := utils.WriteFixedNumber(idSize, itemID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode item ID: %w", err)
+		}
+		buf.Write(b)
+	}
+
+	for _, id := range []uint64{op.OrderID, op.PromotionID} {
+		b, err := utils.WriteFixedNumber(idSize, id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode link ID: %w", err)
+		}
+		buf.Write(b)
+	}
+
+	extensionBytes, err := utils.EncodeExtensions(op.Extensions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode extensions: %w", err)
+	}
+	buf.Write(extensionBytes)
+
+	return buf.Bytes(), nil
+}
+
+// decodeOperation parses an operation record with IDs of idSize bytes
+func decodeOperation(data []byte, idSize int) (Operation, error) {
+	var op Operation
+
+	if len(data) < 9 {
+		return op, fmt.Errorf("record too short")
+	}
+	op.Timestamp = time.Unix(0, int64(binary.BigEndian.Uint64(data[:8]))).UTC()
+	op.Type = OpType(data[8])
+	offset := 9
+
+	id, offset, err := utils.ReadFixedNumber(idSize, data, offset)
+	if err != nil {
+		return op, fmt.Errorf("failed to read ID: %w", err)
+	}
+	op.ID = id
+
+	nameLen, offset, err := utils.ReadFixedNumber(2, data, offset)
+	if err != nil {
+		return op, fmt.Errorf("failed to read name length: %w", err)
+	}
+	encrypted := nameLen&nameEncryptedFlag != 0
+	nameLen &^= nameEncryptedFlag
+	if nameLen > 0 {
+		op.Name, offset, err = utils.ReadFixedString(int(nameLen), data, offset)
+		if err != nil {
+			return op, fmt.Errorf("failed to read name: %w", err)
+		}
+	}
+	if encrypted {
+		fieldCipher, err := crypto.GetFieldCipher(utils.KeysDir)
+		if err != nil {
+			return op, fmt.Errorf("failed to get field cipher: %w", err)
+		}
+		if op.Name, err = fieldCipher.DecryptFromBytesIf(true, []byte(op.Name)); err != nil {
+			return op, fmt.Errorf("failed to decrypt name: %w", err)
+		}
+	}
+
+	op.Price, offset, err = utils.ReadFixedNumber(4, data, offset)
+	if err != nil {
+		return op, fmt.Errorf("failed to read price: %w", err)
+	}
+
+	itemCount, offset, err := utils.ReadFixedNumber(4, data, offset)
+	if err != nil {
+		return op, fmt.Errorf("failed to read item count: %w", err)
+	}
+	if itemCount*uint64(idSize) > uint64(len(data)-offset) {
+		return op, fmt.Errorf("item count %d exceeds record size", itemCount)
+	}
+
+	op.ItemIDs = make([]uint64, itemCount)
+	for i := range op.ItemIDs {
+		op.ItemIDs[i], offset, err = utils.ReadFixedNumber(idSize, data, offset)
+		if err != nil {
+			return op, fmt.Errorf("failed to read item ID: %w", err)
+		}
+	}
+
+	op.OrderID, offset, err = utils.ReadFixedNumber(idSize, data, offset)
+	if err != nil {
+		return op, fmt.Errorf("failed to read order ID: %w", err)
+	}
+	op.PromotionID, offset, err = utils.ReadFixedNumber(idSize, data, offset)
+	if err != nil {
+		return op, fmt.Errorf("failed to read promotion ID: %w", err)
+	}
+
+	op.Extensions, err = utils.DecodeExtensions(data[offset:])
+	if err != nil {
+		return op, fmt.Errorf("failed to read extensions: %w", err)
+	}
+
+	return op, nil
+}
@@ -0,0 +1,158 @@
+package oplog
+
+import (
+	"BinaryCRUD/backend/crypto"
+	"BinaryCRUD/backend/utils"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ReplayResult summarizes a replay run
+type ReplayResult struct {
+	Dir     string
+	Applied int
+	Skipped int // operations after the target time
+	Failed  int
+	Errors  []string
+}
+
+// Replay applies every operation with a timestamp at or before until to a fresh set of
+// .bin files in dir. Records keep the IDs they were originally assigned.
+// Files are written with utils directly (no indexes), so replaying never touches the live
+// index files; indexes are rebuilt from the .bin files when they are loaded by a DAO.
+func Replay(ops []Operation, until time.Time, dir string) (*ReplayResult, error) {
+	if entries, err := os.ReadDir(dir); err == nil && len(entries) > 0 {
+		return nil, fmt.Errorf("replay directory %s is not empty", dir)
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create replay directory: %w", err)
+	}
+
+	result := &ReplayResult{Dir: dir, Errors: []string{}}
+	for _, op := range ops {
+		if op.Timestamp.After(until) {
+			result.Skipped++
+			continue
+		}
+		if err := Apply(op, dir); err != nil {
+			result.Failed++
+			result.Errors = append(result.Errors, fmt.Sprintf("%s at %s: %v", op.Type, op.Timestamp.Format(time.RFC3339Nano), err))
+			continue
+		}
+		result.Applied++
+	}
+
+	return result, nil
+}
+
+// Apply performs a single operation against the .bin files in dir
+func Apply(op Operation, dir string) error {
+	itemsPath := filepath.Join(dir, "items.bin")
+	ordersPath := filepath.Join(dir, "orders.bin")
+	promotionsPath := filepath.Join(dir, "promotions.bin")
+	orderPromotionsPath := filepath.Join(dir, "order_promotions.bin")
+
+	switch op.Type {
+	case OpAddItem, OpUpdateItem:
+		idSize, err := utils.IDSizeFromPath(itemsPath)
+		if err != nil {
+			return err
+		}
+		entry, err := utils.ItemCodec.Encode(&utils.Item{Name: op.Name, Price: op.Price, Extensions: op.Extensions}, idSize)
+		if err != nil {
+			return err
+		}
+		// An update replaces the previous version of the record
+		if op.Type == OpUpdateItem {
+			if err := utils.SoftDeleteByID(itemsPath, op.ID, nil, nil); err != nil {
+				return err
+			}
+		}
+		return appendWithID(itemsPath, op.ID, entry)
+
+	case OpCreateOrder, OpCreatePromotion, OpUpdateOrder, OpUpdatePromotion:
+		fieldCipher, err := crypto.GetFieldCipher(utils.KeysDir)
+		if err != nil {
+			return fmt.Errorf("failed to get field cipher: %w", err)
+		}
+		encryptedName, err := fieldCipher.EncryptToBytes(op.Name)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt name: %w", err)
+		}
+		path := ordersPath
+		if op.Type == OpCreatePromotion || op.Type == OpUpdatePromotion {
+			path = promotionsPath
+		}
+		idSize, err := utils.IDSizeFromPath(path)
+		if err != nil {
+			return err
+		}
+		entry, err := utils.CollectionCodec.Encode(&utils.Collection{
+			OwnerOrName: string(encryptedName),
+			TotalPrice:  op.Price,
+			ItemIDs:     op.ItemIDs,
+			Extensions:  op.Extensions,
+		}, idSize)
+		if err != nil {
+			return err
+		}
+		// An update replaces the previous version of the record
+		if op.Type == OpUpdateOrder || op.Type == OpUpdatePromotion {
+			if err := utils.SoftDeleteByID(path, op.ID, nil, nil); err != nil {
+				return err
+			}
+		}
+		return appendWithID(path, op.ID, entry)
+
+	case OpDeleteItem:
+		return utils.SoftDeleteByID(itemsPath, op.ID, nil, nil)
+	case OpDeleteOrder:
+		return utils.SoftDeleteByID(ordersPath, op.ID, nil, nil)
+	case OpDeletePromotion:
+		return utils.SoftDeleteByID(promotionsPath, op.ID, nil, nil)
+
+	case OpApplyPromotion:
+		if err := utils.EnsureFileExists(orderPromotionsPath); err != nil {
+			return err
+		}
+		file, err := os.OpenFile(orderPromotionsPath, os.O_RDWR, 0644)
+		if err != nil {
+			return fmt.Errorf("failed to open order_promotion file: %w", err)
+		}
+		defer file.Close()
+		idSize, err := utils.ReadIDSize(file)
+		if err != nil {
+			return err
+		}
+		// The link was applied when the operation was logged, with the discount logged as its price
+		link := &utils.OrderPromotion{OrderID: op.OrderID, PromotionID: op.PromotionID, AppliedAt: op.Timestamp, DiscountAmount: op.Price}
+		entry, err := utils.OrderPromotionCodec.Encode(link, idSize)
+		if err != nil {
+			return err
+		}
+		return utils.AppendEntryManual(file, entry)
+
+	case OpRemovePromotion:
+		return utils.SoftDeleteByCompositeKey(orderPromotionsPath, op.OrderID, op.PromotionID, nil)
+
+	default:
+		return fmt.Errorf("unknown operation type %d", op.Type)
+	}
+}
+
+// appendWithID appends an entry with an explicit ID, creating the file if needed
+func appendWithID(path string, id uint64, entry []byte) error {
+	if err := utils.EnsureFileExists(path); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(path, os.O_RDWR, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", filepath.Base(path), err)
+	}
+	defer file.Close()
+
+	return utils.AppendEntryWithID(file, id, entry)
+}
@@ -0,0 +1,226 @@
+package query
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Row is one record of a table, keyed by column name
+// Values are numbers (any integer type or float64), strings or bools
+type Row map[string]any
+
+// Source is a table a query can run against
+type Source interface {
+	// Columns lists the columns of the table in display order
+	Columns() []string
+	// Scan returns every row of the table
+	Scan() ([]Row, error)
+	// Lookup returns the rows matching an equality condition through an index
+	// ok is false when no index covers the column, and the query falls back to Scan
+	Lookup(condition Condition) (rows []Row, ok bool, err error)
+}
+
+// Result holds the rows selected by a query and how they were found
+type Result struct {
+	Columns []string
+	Rows    []Row
+	Plan    string // e.g. "index lookup on id" or "full scan"
+}
+
+// Execute runs a parsed query against a source
+// An equality condition the source can look up through an index replaces the full scan;
+// every condition is still checked against the rows it returns
+func Execute(q *Query, source Source) (*Result, error) {
+	known := make(map[string]bool)
+	for _, column := range source.Columns() {
+		known[column] = true
+	}
+	columns := q.Columns
+	if columns == nil {
+		columns = source.Columns()
+	}
+	for _, column := range columns {
+		if !known[column] {
+			return nil, fmt.Errorf("unknown column %q in %s", column, q.Table)
+		}
+	}
+	for _, condition := range q.Where {
+		if !known[condition.Column] {
+			return nil, fmt.Errorf("unknown column %q in %s", condition.Column, q.Table)
+		}
+	}
+	for _, order := range q.OrderBy {
+		if !known[order.Column] {
+			return nil, fmt.Errorf("unknown column %q in %s", order.Column, q.Table)
+		}
+	}
+
+	rows, plan, err := candidateRows(q, source)
+	if err != nil {
+		return nil, err
+	}
+
+	matched := make([]Row, 0, len(rows))
+	for _, row := range rows {
+		ok, err := matchesAll(row, q.Where)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			matched = append(matched, row)
+		}
+	}
+
+	if len(q.OrderBy) > 0 {
+		sort.SliceStable(matched, func(i, j int) bool {
+			for _, order := range q.OrderBy {
+				c := compareValues(matched[i][order.Column], matched[j][order.Column])
+				if c != 0 {
+					return (c < 0) != order.Desc
+				}
+			}
+			return false
+		})
+	}
+
+	if q.Limit > 0 && len(matched) > q.Limit {
+		matched = matched[:q.Limit]
+	}
+
+	projected := make([]Row, len(matched))
+	for i, row := range matched {
+		projected[i] = make(Row, len(columns))
+		for _, column := range columns {
+			projected[i][column] = row[column]
+		}
+	}
+
+	return &Result{Columns: columns, Rows: projected, Plan: plan}, nil
+}
+
+// candidateRows returns the rows to filter, from an index lookup when one applies
+func candidateRows(q *Query, source Source) ([]Row, string, error) {
+	for _, condition := range q.Where {
+		if condition.Op != "=" {
+			continue
+		}
+		rows, ok, err := source.Lookup(condition)
+		if err != nil {
+			return nil, "", err
+		}
+		if ok {
+			return rows, "index lookup on " + condition.Column, nil
+		}
+	}
+
+	rows, err := source.Scan()
+	if err != nil {
+		return nil, "", err
+	}
+	return rows, "full scan", nil
+}
+
+// matchesAll reports whether a row satisfies every condition
+func matchesAll(row Row, conditions []Condition) (bool, error) {
+	for _, condition := range conditions {
+		ok, err := matches(row[condition.Column], condition)
+		if err != nil {
+			return false, err
+		}
+		if !ok {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// matches evaluates one condition against a column value
+func matches(value any, condition Condition) (bool, error) {
+	if condition.Op == "LIKE" {
+		text, ok := value.(string)
+		if !ok {
+			return false, fmt.Errorf("LIKE needs a text column, %s is not", condition.Column)
+		}
+		return likePattern(condition.Value.(string)).MatchString(text), nil
+	}
+
+	_, valueIsNumber := toNumber(value)
+	_, literalIsNumber := toNumber(condition.Value)
+	if valueIsNumber != literalIsNumber {
+		if valueIsNumber {
+			return false, fmt.Errorf("%s is a number, compare it with a number", condition.Column)
+		}
+		return false, fmt.Errorf("%s is text, compare it with a 'string'", condition.Column)
+	}
+
+	c := compareValues(value, condition.Value)
+	switch condition.Op {
+	case "=":
+		return c == 0, nil
+	case "!=":
+		return c != 0, nil
+	case "<":
+		return c < 0, nil
+	case "<=":
+		return c <= 0, nil
+	case ">":
+		return c > 0, nil
+	case ">=":
+		return c >= 0, nil
+	}
+	return false, fmt.Errorf("unknown operator %q", condition.Op)
+}
+
+// likePattern compiles a LIKE pattern, where % matches any run of characters and _ one character
+// Matching is case-insensitive
+func likePattern(pattern string) *regexp.Regexp {
+	var expr strings.Builder
+	expr.WriteString("(?is)^")
+	for _, r := range pattern {
+		switch r {
+		case '%':
+			expr.WriteString(".*")
+		case '_':
+			expr.WriteString(".")
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	expr.WriteString("$")
+	return regexp.MustCompile(expr.String())
+}
+
+// toNumber converts the numeric types used in rows to float64
+func toNumber(value any) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case uint64:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+// compareValues orders two values: numbers numerically, everything else as case-insensitive text
+func compareValues(a, b any) int {
+	x, aNumber := toNumber(a)
+	y, bNumber := toNumber(b)
+	if aNumber && bNumber {
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(strings.ToLower(fmt.Sprint(a)), strings.ToLower(fmt.Sprint(b)))
+}
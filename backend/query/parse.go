@@ -0,0 +1,305 @@
+package query
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Query is a parsed SELECT statement
+// Grammar: SELECT *|col[, col...] FROM table [WHERE cond [AND cond...]] [ORDER BY col [ASC|DESC][, ...]] [LIMIT n]
+type Query struct {
+	Table   string
+	Columns []string // nil selects every column
+	Where   []Condition
+	OrderBy []Order
+	Limit   int // 0 means no limit
+}
+
+// Condition compares a column with a literal: column op value
+type Condition struct {
+	Column string
+	Op     string // =, !=, <, <=, >, >= or LIKE
+	Value  any    // float64 or string
+}
+
+// Order is one sort key of ORDER BY
+type Order struct {
+	Column string
+	Desc   bool
+}
+
+// token kinds produced by tokenize
+const (
+	tokenWord = iota
+	tokenNumber
+	tokenString
+	tokenSymbol
+)
+
+type token struct {
+	kind int
+	text string
+}
+
+// tokenize splits a statement into words, numbers, quoted strings and symbols
+func tokenize(input string) ([]token, error) {
+	var tokens []token
+	runes := []rune(input)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_') {
+				i++
+			}
+			tokens = append(tokens, token{tokenWord, string(runes[start:i])})
+		case unicode.IsDigit(r) || (r == '-' && i+1 < len(runes) && unicode.IsDigit(runes[i+1])):
+			start := i
+			i++
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, token{tokenNumber, string(runes[start:i])})
+		case r == '\'':
+			// Quotes inside a string are doubled: 'it''s'
+			var text strings.Builder
+			i++
+			for {
+				if i >= len(runes) {
+					return nil, fmt.Errorf("unterminated string")
+				}
+				if runes[i] == '\'' {
+					if i+1 < len(runes) && runes[i+1] == '\'' {
+						text.WriteRune('\'')
+						i += 2
+						continue
+					}
+					i++
+					break
+				}
+				text.WriteRune(runes[i])
+				i++
+			}
+			tokens = append(tokens, token{tokenString, text.String()})
+		case strings.ContainsRune("<>!", r) && i+1 < len(runes) && runes[i+1] == '=', r == '<' && i+1 < len(runes) && runes[i+1] == '>':
+			tokens = append(tokens, token{tokenSymbol, string(runes[i : i+2])})
+			i += 2
+		case strings.ContainsRune("*,=<>", r):
+			tokens = append(tokens, token{tokenSymbol, string(r)})
+			i++
+		default:
+			return nil, fmt.Errorf("unexpected character %q", r)
+		}
+	}
+	return tokens, nil
+}
+
+// parser walks the tokens of one statement
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+// peek returns the next token without consuming it, ok is false at the end
+func (p *parser) peek() (token, bool) {
+	if p.pos >= len(p.tokens) {
+		return token{}, false
+	}
+	return p.tokens[p.pos], true
+}
+
+// next consumes the next token
+func (p *parser) next() (token, error) {
+	tok, ok := p.peek()
+	if !ok {
+		return token{}, fmt.Errorf("unexpected end of query")
+	}
+	p.pos++
+	return tok, nil
+}
+
+// keyword reports whether the next token is the given keyword and consumes it if so
+func (p *parser) keyword(word string) bool {
+	tok, ok := p.peek()
+	if ok && tok.kind == tokenWord && strings.EqualFold(tok.text, word) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// expectKeyword consumes a required keyword
+func (p *parser) expectKeyword(word string) error {
+	if !p.keyword(word) {
+		tok, ok := p.peek()
+		if !ok {
+			return fmt.Errorf("expected %s at end of query", word)
+		}
+		return fmt.Errorf("expected %s, got %q", word, tok.text)
+	}
+	return nil
+}
+
+// symbol reports whether the next token is the given symbol and consumes it if so
+func (p *parser) symbol(text string) bool {
+	tok, ok := p.peek()
+	if ok && tok.kind == tokenSymbol && tok.text == text {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// identifier consumes a column or table name, lowercased
+func (p *parser) identifier(what string) (string, error) {
+	tok, err := p.next()
+	if err != nil {
+		return "", fmt.Errorf("expected %s: %w", what, err)
+	}
+	if tok.kind != tokenWord {
+		return "", fmt.Errorf("expected %s, got %q", what, tok.text)
+	}
+	return strings.ToLower(tok.text), nil
+}
+
+// Parse parses a SELECT statement
+// Keywords, table and column names are case-insensitive; string literals use single quotes
+func Parse(input string) (*Query, error) {
+	tokens, err := tokenize(input)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	q := &Query{}
+
+	if err := p.expectKeyword("SELECT"); err != nil {
+		return nil, err
+	}
+	if !p.symbol("*") {
+		for {
+			column, err := p.identifier("column")
+			if err != nil {
+				return nil, err
+			}
+			q.Columns = append(q.Columns, column)
+			if !p.symbol(",") {
+				break
+			}
+		}
+	}
+
+	if err := p.expectKeyword("FROM"); err != nil {
+		return nil, err
+	}
+	if q.Table, err = p.identifier("table"); err != nil {
+		return nil, err
+	}
+
+	if p.keyword("WHERE") {
+		for {
+			condition, err := p.condition()
+			if err != nil {
+				return nil, err
+			}
+			q.Where = append(q.Where, condition)
+			if !p.keyword("AND") {
+				break
+			}
+		}
+	}
+
+	if p.keyword("ORDER") {
+		if err := p.expectKeyword("BY"); err != nil {
+			return nil, err
+		}
+		for {
+			column, err := p.identifier("column")
+			if err != nil {
+				return nil, err
+			}
+			order := Order{Column: column}
+			if p.keyword("DESC") {
+				order.Desc = true
+			} else {
+				p.keyword("ASC")
+			}
+			q.OrderBy = append(q.OrderBy, order)
+			if !p.symbol(",") {
+				break
+			}
+		}
+	}
+
+	if p.keyword("LIMIT") {
+		tok, err := p.next()
+		if err != nil {
+			return nil, fmt.Errorf("expected limit: %w", err)
+		}
+		limit, err := strconv.Atoi(tok.text)
+		if tok.kind != tokenNumber || err != nil || limit <= 0 {
+			return nil, fmt.Errorf("invalid limit %q", tok.text)
+		}
+		q.Limit = limit
+	}
+
+	if tok, ok := p.peek(); ok {
+		return nil, fmt.Errorf("unexpected %q", tok.text)
+	}
+	return q, nil
+}
+
+// condition parses column op literal
+func (p *parser) condition() (Condition, error) {
+	column, err := p.identifier("column")
+	if err != nil {
+		return Condition{}, err
+	}
+
+	var op string
+	if p.keyword("LIKE") {
+		op = "LIKE"
+	} else {
+		tok, err := p.next()
+		if err != nil {
+			return Condition{}, fmt.Errorf("expected operator: %w", err)
+		}
+		switch tok.text {
+		case "=", "!=", "<", "<=", ">", ">=":
+			op = tok.text
+		case "<>":
+			op = "!="
+		default:
+			return Condition{}, fmt.Errorf("unknown operator %q", tok.text)
+		}
+	}
+
+	tok, err := p.next()
+	if err != nil {
+		return Condition{}, fmt.Errorf("expected value: %w", err)
+	}
+	var value any
+	switch tok.kind {
+	case tokenNumber:
+		number, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return Condition{}, fmt.Errorf("invalid number %q", tok.text)
+		}
+		value = number
+	case tokenString:
+		value = tok.text
+	default:
+		return Condition{}, fmt.Errorf("expected a number or 'string', got %q", tok.text)
+	}
+	if op == "LIKE" {
+		if _, ok := value.(string); !ok {
+			return Condition{}, fmt.Errorf("LIKE needs a 'string' pattern")
+		}
+	}
+
+	return Condition{Column: column, Op: op, Value: value}, nil
+}
@@ -0,0 +1,198 @@
+package replication
+
+import (
+	"BinaryCRUD/backend/oplog"
+	"BinaryCRUD/backend/utils"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// ReplicatedFiles are the data files rebuilt from the oplog of the primary
+var ReplicatedFiles = []string{"items.bin", "orders.bin", "promotions.bin", "order_promotions.bin"}
+
+// SyncResult summarizes one sync of a replica
+type SyncResult struct {
+	Applied int      // operations applied to the data files
+	Failed  int      // operations that could not be applied
+	Errors  []string // why operations failed
+	Reset   bool     // the primary log was reset, so the replica data was cleared
+	Offset  int64    // size of the local log after the sync
+	Behind  int64    // log bytes the primary has that the replica has not fetched yet
+}
+
+// Replica mirrors a primary by copying its oplog and applying every new operation to local data files
+// The local log is a byte-for-byte prefix of the primary's, so its size is the replication offset
+// Operations are applied to a working copy in BinDir; Export copies its files out for the DAOs
+type Replica struct {
+	Source  Source
+	LogPath string // local copy of the primary's oplog
+	BinDir  string // working copy of the replicated .bin files
+}
+
+// Prepare checks that the replica can start syncing into liveDir, the directory the DAOs read
+// A new replica needs liveDir without data and starts from an empty working copy;
+// a replica with a log resumes, which needs the working copy it left behind
+func (r *Replica) Prepare(liveDir string) error {
+	size, err := r.logSize()
+	if err != nil {
+		return err
+	}
+	if size > 0 {
+		if _, err := os.Stat(r.BinDir); err != nil {
+			return fmt.Errorf("a replica needs an empty data directory, this one has an oplog of its own")
+		}
+		return nil
+	}
+
+	for _, name := range ReplicatedFiles {
+		if _, err := os.Stat(utils.ResolveBinPath(liveDir, name)); err == nil {
+			return fmt.Errorf("a replica needs an empty data directory, %s already exists", name)
+		}
+	}
+	return r.Reset()
+}
+
+// Sync fetches the log bytes the replica is missing and applies their operations
+// Operations that fail to apply are reported and skipped, like a replay
+func (r *Replica) Sync() (*SyncResult, error) {
+	offset, err := r.logSize()
+	if err != nil {
+		return nil, err
+	}
+
+	chunk, size, err := r.Source.Fetch(offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch from %s: %w", r.Source, err)
+	}
+	if size < offset {
+		if err := r.Reset(); err != nil {
+			return nil, err
+		}
+		return &SyncResult{Errors: []string{}, Reset: true, Behind: size}, nil
+	}
+
+	result := &SyncResult{Errors: []string{}, Offset: offset, Behind: size - offset}
+	start := 0
+	var idSize int
+	if offset == 0 {
+		if len(chunk) < len(oplog.Magic) {
+			return result, nil
+		}
+		if idSize, err = oplog.IDSizeFromMagic(chunk); err != nil {
+			return nil, fmt.Errorf("primary oplog has bad magic bytes")
+		}
+		start = len(oplog.Magic)
+	} else if idSize, err = r.logIDSize(); err != nil {
+		return nil, err
+	}
+
+	ops, end, err := oplog.DecodeRecords(chunk, start, idSize)
+	if err != nil {
+		return nil, err
+	}
+	if end == 0 {
+		return result, nil
+	}
+
+	if err := os.MkdirAll(r.BinDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create replica data directory: %w", err)
+	}
+	for _, op := range ops {
+		if err := oplog.Apply(op, r.BinDir); err != nil {
+			result.Failed++
+			result.Errors = append(result.Errors, fmt.Sprintf("%s of ID %d: %v", op.Type, op.ID, err))
+			continue
+		}
+		result.Applied++
+	}
+
+	if err := r.appendLog(chunk[:end]); err != nil {
+		return nil, err
+	}
+	result.Offset = offset + int64(end)
+	result.Behind = size - result.Offset
+	return result, nil
+}
+
+// Reset removes the local log and the replicated data files, so the next sync starts over
+func (r *Replica) Reset() error {
+	paths := []string{r.LogPath}
+	for _, name := range ReplicatedFiles {
+		paths = append(paths, filepath.Join(r.BinDir, name))
+	}
+	for _, path := range paths {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to reset replica: %w", err)
+		}
+	}
+	return nil
+}
+
+// Export copies a replicated file from the working copy to dst, as an empty data file when it has no records yet
+func (r *Replica) Export(name, dst string) error {
+	src := filepath.Join(r.BinDir, name)
+	if err := utils.EnsureFileExists(src); err != nil {
+		return err
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("failed to open replicated %s: %w", name, err)
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", dst, err)
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return fmt.Errorf("failed to copy replicated %s: %w", name, err)
+	}
+	return out.Close()
+}
+
+// logSize returns the size of the local log, 0 when it does not exist
+func (r *Replica) logSize() (int64, error) {
+	info, err := os.Stat(r.LogPath)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to stat replica oplog: %w", err)
+	}
+	return info.Size(), nil
+}
+
+// logIDSize returns the ID width of the local log, copied from the primary along with its magic
+func (r *Replica) logIDSize() (int, error) {
+	file, err := os.Open(r.LogPath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open replica oplog: %w", err)
+	}
+	defer file.Close()
+
+	magic := make([]byte, len(oplog.Magic))
+	if _, err := io.ReadFull(file, magic); err != nil {
+		return 0, fmt.Errorf("failed to read replica oplog magic: %w", err)
+	}
+	return oplog.IDSizeFromMagic(magic)
+}
+
+// appendLog appends log bytes copied from the primary to the local log
+func (r *Replica) appendLog(data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(r.LogPath), 0700); err != nil {
+		return fmt.Errorf("failed to create replica oplog directory: %w", err)
+	}
+	file, err := os.OpenFile(r.LogPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open replica oplog: %w", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(data); err != nil {
+		return fmt.Errorf("failed to write replica oplog: %w", err)
+	}
+	return file.Sync()
+}
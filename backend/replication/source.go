@@ -0,0 +1,63 @@
+package replication
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// MaxChunkSize bounds the log bytes shipped by a single fetch
+const MaxChunkSize = 1 << 20
+
+// Source ships the oplog of a primary
+type Source interface {
+	// Fetch returns up to MaxChunkSize log bytes starting at offset and the current size of the log
+	// A size smaller than offset means the primary log was reset
+	Fetch(offset int64) (chunk []byte, size int64, err error)
+	// String describes the primary for logs
+	String() string
+}
+
+// DirSource reads the oplog file of a primary through a shared directory
+type DirSource struct {
+	Path string // the primary's oplog file
+}
+
+// Fetch reads the primary's log file from offset
+func (s DirSource) Fetch(offset int64) ([]byte, int64, error) {
+	return readChunk(s.Path, offset)
+}
+
+// String returns the path of the primary's log
+func (s DirSource) String() string {
+	return s.Path
+}
+
+// readChunk reads up to MaxChunkSize bytes of a log file from offset
+// A missing log is an empty one
+func readChunk(path string, offset int64) ([]byte, int64, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, 0, nil
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to open primary oplog: %w", err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to stat primary oplog: %w", err)
+	}
+	size := info.Size()
+	if offset >= size {
+		return nil, size, nil
+	}
+
+	length := min(size-offset, MaxChunkSize)
+	chunk := make([]byte, length)
+	if _, err := file.ReadAt(chunk, offset); err != nil && err != io.EOF {
+		return nil, 0, fmt.Errorf("failed to read primary oplog: %w", err)
+	}
+	return chunk, size, nil
+}
@@ -0,0 +1,315 @@
+package replication
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// Protocol: the primary opens a connection with [magic(4)][serverNonce(16)], the replica answers with
+// [clientNonce(16)][proof(32)] and the primary with its own [proof(32)], each proof an HMAC of both nonces under
+// the shared secret. Every frame after that carries an HMAC under a key derived from the secret and both nonces,
+// covering its direction and sequence number, so frames cannot be forged, replayed or reordered
+// The replica then sends [offset(8)][mac(32)], the primary answers [logSize(8)][chunkLength(4)][chunk][mac(32)]
+// A connection serves any number of requests
+const ioTimeout = 30 * time.Second
+
+// handshakeMagic starts every replication connection
+var handshakeMagic = []byte("BCRP")
+
+const (
+	nonceSize = 16
+	macSize   = sha256.Size
+)
+
+// Server ships the oplog of a primary to replicas over TCP
+// Only replicas holding the same secret are served
+type Server struct {
+	listener net.Listener
+	logPath  string
+	secret   []byte
+	logf     func(string)
+	wg       sync.WaitGroup
+	mu       sync.Mutex
+	conns    map[net.Conn]bool
+}
+
+// Listen starts a server shipping the log file at logPath on addr to replicas that know secret
+// An address without a host, such as ":7070", listens on localhost only
+func Listen(addr, logPath string, secret []byte, logf func(string)) (*Server, error) {
+	if len(secret) == 0 {
+		return nil, fmt.Errorf("a replication secret is required")
+	}
+	listener, err := net.Listen("tcp", listenAddr(addr))
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen for replicas: %w", err)
+	}
+	s := &Server{listener: listener, logPath: logPath, secret: secret, logf: logf, conns: make(map[net.Conn]bool)}
+	s.wg.Add(1)
+	go s.accept()
+	return s, nil
+}
+
+// listenAddr binds an address without a host to localhost
+// Replicas on other machines need the host given explicitly, e.g. "0.0.0.0:7070"
+func listenAddr(addr string) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err == nil && host == "" {
+		return net.JoinHostPort("127.0.0.1", port)
+	}
+	return addr
+}
+
+// Addr returns the address the server listens on
+func (s *Server) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// Close stops accepting replicas and closes their connections
+func (s *Server) Close() error {
+	err := s.listener.Close()
+	s.mu.Lock()
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+	return err
+}
+
+// accept serves replicas until the listener is closed
+func (s *Server) accept() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				s.logf(fmt.Sprintf("Replication listener failed: %v", err))
+			}
+			return
+		}
+		s.mu.Lock()
+		s.conns[conn] = true
+		s.mu.Unlock()
+		s.wg.Add(1)
+		go s.serve(conn)
+	}
+}
+
+// serve answers the fetches of one replica connection once it has authenticated
+func (s *Server) serve(conn net.Conn) {
+	defer s.wg.Done()
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+		conn.Close()
+	}()
+
+	conn.SetDeadline(time.Now().Add(ioTimeout))
+	key, err := s.handshake(conn)
+	if err != nil {
+		s.logf(fmt.Sprintf("Replica %s rejected: %v", conn.RemoteAddr(), err))
+		return
+	}
+
+	request := make([]byte, 8+macSize)
+	for seq := uint64(0); ; seq++ {
+		conn.SetDeadline(time.Now().Add(ioTimeout))
+		if _, err := io.ReadFull(conn, request); err != nil {
+			return
+		}
+		if !hmac.Equal(request[8:], frameMAC(key, 'Q', seq, request[:8])) {
+			s.logf(fmt.Sprintf("Replica %s sent a frame with a bad MAC", conn.RemoteAddr()))
+			return
+		}
+		offset := int64(binary.BigEndian.Uint64(request[:8]))
+
+		chunk, size, err := readChunk(s.logPath, offset)
+		if err != nil {
+			s.logf(fmt.Sprintf("Replication fetch from %s failed: %v", conn.RemoteAddr(), err))
+			return
+		}
+
+		header := make([]byte, 12)
+		binary.BigEndian.PutUint64(header[0:8], uint64(size))
+		binary.BigEndian.PutUint32(header[8:12], uint32(len(chunk)))
+		response := make([]byte, 0, len(header)+len(chunk)+macSize)
+		response = append(append(response, header...), chunk...)
+		response = append(response, frameMAC(key, 'R', seq, header, chunk)...)
+		if _, err := conn.Write(response); err != nil {
+			return
+		}
+	}
+}
+
+// handshake proves to a replica that the primary knows the secret and checks that the replica does too
+// Returns the key authenticating the frames of the connection
+func (s *Server) handshake(conn net.Conn) ([]byte, error) {
+	serverNonce := make([]byte, nonceSize)
+	if _, err := rand.Read(serverNonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	if _, err := conn.Write(append(append([]byte{}, handshakeMagic...), serverNonce...)); err != nil {
+		return nil, err
+	}
+
+	reply := make([]byte, nonceSize+macSize)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return nil, fmt.Errorf("no handshake: %w", err)
+	}
+	clientNonce := reply[:nonceSize]
+	if !hmac.Equal(reply[nonceSize:], handshakeMAC(s.secret, "replica", serverNonce, clientNonce)) {
+		return nil, fmt.Errorf("wrong replication secret")
+	}
+	if _, err := conn.Write(handshakeMAC(s.secret, "primary", serverNonce, clientNonce)); err != nil {
+		return nil, err
+	}
+	return handshakeMAC(s.secret, "session", serverNonce, clientNonce), nil
+}
+
+// handshakeMAC is the HMAC of a role and both nonces under the shared secret
+func handshakeMAC(secret []byte, role string, serverNonce, clientNonce []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(role))
+	mac.Write(serverNonce)
+	mac.Write(clientNonce)
+	return mac.Sum(nil)
+}
+
+// frameMAC is the HMAC of a frame under the session key, covering its direction ('Q' for requests,
+// 'R' for responses) and sequence number
+func frameMAC(key []byte, direction byte, seq uint64, parts ...[]byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte{direction})
+	binary.Write(mac, binary.BigEndian, seq)
+	for _, part := range parts {
+		mac.Write(part)
+	}
+	return mac.Sum(nil)
+}
+
+// TCPSource fetches the oplog of a primary from its replication server, authenticating with the shared secret
+type TCPSource struct {
+	Addr   string
+	Secret []byte
+	conn   net.Conn
+	key    []byte // authenticates the frames of conn
+	seq    uint64 // sequence number of the next request on conn
+}
+
+// Fetch requests the log from offset, reconnecting after a failed request
+func (s *TCPSource) Fetch(offset int64) ([]byte, int64, error) {
+	chunk, size, err := s.fetch(offset)
+	if err != nil && s.conn != nil {
+		s.conn.Close()
+		s.conn = nil
+	}
+	return chunk, size, err
+}
+
+// fetch performs one request on the current connection
+func (s *TCPSource) fetch(offset int64) ([]byte, int64, error) {
+	if s.conn == nil {
+		if err := s.connect(); err != nil {
+			return nil, 0, err
+		}
+	}
+	s.conn.SetDeadline(time.Now().Add(ioTimeout))
+
+	request := make([]byte, 8, 8+macSize)
+	binary.BigEndian.PutUint64(request, uint64(offset))
+	request = append(request, frameMAC(s.key, 'Q', s.seq, request)...)
+	if _, err := s.conn.Write(request); err != nil {
+		return nil, 0, fmt.Errorf("failed to send fetch: %w", err)
+	}
+
+	header := make([]byte, 12)
+	if _, err := io.ReadFull(s.conn, header); err != nil {
+		return nil, 0, fmt.Errorf("failed to read fetch response: %w", err)
+	}
+	size := int64(binary.BigEndian.Uint64(header[0:8]))
+	length := binary.BigEndian.Uint32(header[8:12])
+	if length > MaxChunkSize {
+		return nil, 0, fmt.Errorf("chunk of %d bytes exceeds the maximum of %d", length, MaxChunkSize)
+	}
+
+	chunk := make([]byte, length+macSize)
+	if _, err := io.ReadFull(s.conn, chunk); err != nil {
+		return nil, 0, fmt.Errorf("failed to read log chunk: %w", err)
+	}
+	chunk, mac := chunk[:length], chunk[length:]
+	if !hmac.Equal(mac, frameMAC(s.key, 'R', s.seq, header, chunk)) {
+		return nil, 0, fmt.Errorf("log chunk from the primary has a bad MAC")
+	}
+	s.seq++
+	return chunk, size, nil
+}
+
+// connect dials the primary and runs the handshake, proving the replica knows the secret and checking the primary does
+func (s *TCPSource) connect() error {
+	conn, err := net.DialTimeout("tcp", s.Addr, ioTimeout)
+	if err != nil {
+		return fmt.Errorf("failed to connect to primary: %w", err)
+	}
+	conn.SetDeadline(time.Now().Add(ioTimeout))
+
+	greeting := make([]byte, len(handshakeMagic)+nonceSize)
+	if _, err := io.ReadFull(conn, greeting); err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to read handshake: %w", err)
+	}
+	if !bytes.Equal(greeting[:len(handshakeMagic)], handshakeMagic) {
+		conn.Close()
+		return fmt.Errorf("%s is not a replication server", s.Addr)
+	}
+	serverNonce := greeting[len(handshakeMagic):]
+	clientNonce := make([]byte, nonceSize)
+	if _, err := rand.Read(clientNonce); err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	reply := append(append([]byte{}, clientNonce...), handshakeMAC(s.Secret, "replica", serverNonce, clientNonce)...)
+	if _, err := conn.Write(reply); err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to send handshake: %w", err)
+	}
+	proof := make([]byte, macSize)
+	if _, err := io.ReadFull(conn, proof); err != nil {
+		conn.Close()
+		return fmt.Errorf("primary rejected the replication secret: %w", err)
+	}
+	if !hmac.Equal(proof, handshakeMAC(s.Secret, "primary", serverNonce, clientNonce)) {
+		conn.Close()
+		return fmt.Errorf("primary failed to prove it knows the replication secret")
+	}
+
+	s.conn = conn
+	s.key = handshakeMAC(s.Secret, "session", serverNonce, clientNonce)
+	s.seq = 0
+	return nil
+}
+
+// Close closes the connection to the primary
+func (s *TCPSource) Close() error {
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+// String returns the address of the primary
+func (s *TCPSource) String() string {
+	return "tcp://" + s.Addr
+}
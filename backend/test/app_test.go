@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"os"
 	"testing"
+	"time"
 )
 
 // Mock logger for testing
@@ -282,7 +283,7 @@ func (a *TestApp) ApplyPromotionToOrder(orderID, promotionID uint64) error {
 	if err != nil {
 		return fmt.Errorf("failed to read promotion: %w", err)
 	}
-	err = a.orderPromotionDAO.Write(orderID, promotionID)
+	err = a.orderPromotionDAO.Write(orderID, promotionID, time.Now().UTC())
 	if err != nil {
 		return fmt.Errorf("failed to apply promotion: %w", err)
 	}
@@ -0,0 +1,68 @@
+package test
+
+import (
+	"BinaryCRUD/backend/dao"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAuditDAOWriteAndQuery(t *testing.T) {
+	auditDAO := dao.NewAuditDAO(filepath.Join(t.TempDir(), "audit.bin"))
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	entries := []dao.AuditEntry{
+		{Timestamp: now, Actor: "alice", Action: dao.AuditCreate, EntityType: "item", EntityID: 0, After: "Burger ($8.99)"},
+		{Timestamp: now.Add(time.Second), Actor: "alice", Action: dao.AuditCreate, EntityType: "order", EntityID: 0, After: "John"},
+		{Timestamp: now.Add(2 * time.Second), Actor: "bob", Action: dao.AuditDelete, EntityType: "item", EntityID: 0, Before: "Burger ($8.99)"},
+	}
+	for i, entry := range entries {
+		id, err := auditDAO.Write(entry)
+		if err != nil {
+			t.Fatalf("failed to write audit entry: %v", err)
+		}
+		if id != uint64(i) {
+			t.Errorf("expected audit ID %d, got %d", i, id)
+		}
+	}
+
+	itemID := uint64(0)
+	itemLog, err := auditDAO.Query("item", &itemID)
+	if err != nil {
+		t.Fatalf("failed to query audit log: %v", err)
+	}
+	if len(itemLog) != 2 {
+		t.Fatalf("expected 2 item entries, got %d", len(itemLog))
+	}
+	last := itemLog[1]
+	if last.Action != dao.AuditDelete || last.Actor != "bob" || last.Before != "Burger ($8.99)" || last.After != "" {
+		t.Errorf("unexpected audit entry: %+v", last)
+	}
+	if !last.Timestamp.Equal(entries[2].Timestamp) {
+		t.Errorf("expected timestamp %v, got %v", entries[2].Timestamp, last.Timestamp)
+	}
+
+	all, err := auditDAO.Query("", nil)
+	if err != nil {
+		t.Fatalf("failed to query audit log: %v", err)
+	}
+	if len(all) != 3 {
+		t.Errorf("expected 3 entries, got %d", len(all))
+	}
+
+	if _, err := auditDAO.Write(dao.AuditEntry{Action: "rename", EntityType: "item"}); err == nil {
+		t.Error("expected error for unknown action")
+	}
+}
+
+func TestAuditDAOQueryMissingFile(t *testing.T) {
+	auditDAO := dao.NewAuditDAO(filepath.Join(t.TempDir(), "audit.bin"))
+
+	entries, err := auditDAO.Query("item", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("expected no entries, got %d", len(entries))
+	}
+}
@@ -0,0 +1,112 @@
+package test
+
+import (
+	"BinaryCRUD/backend/backup"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// createBackupTestDir creates a data directory with a few files in bin and indexes
+func createBackupTestDir(t *testing.T) string {
+	dataDir := t.TempDir()
+	files := map[string]string{
+		"bin/items.bin":         "items data",
+		"bin/orders.bin":        "orders data",
+		"indexes/items.idx":     "items index",
+		"indexes/items.idx.tmp": "leftover",
+	}
+	for relPath, content := range files {
+		path := filepath.Join(dataDir, relPath)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", relPath, err)
+		}
+	}
+	return dataDir
+}
+
+func TestBackupRoundTrip(t *testing.T) {
+	dataDir := createBackupTestDir(t)
+	archive := filepath.Join(t.TempDir(), "backup.bak")
+
+	manifest, err := backup.Create(dataDir, backup.DefaultDirs, archive, "")
+	if err != nil {
+		t.Fatalf("failed to create backup: %v", err)
+	}
+	if len(manifest.Files) != 3 {
+		t.Fatalf("expected 3 files (temp file skipped), got %d", len(manifest.Files))
+	}
+
+	// Modify live data after the backup
+	os.WriteFile(filepath.Join(dataDir, "bin", "items.bin"), []byte("changed"), 0644)
+	os.WriteFile(filepath.Join(dataDir, "bin", "extra.bin"), []byte("extra"), 0644)
+
+	if _, err := backup.Restore(archive, dataDir, backup.DefaultDirs, ""); err != nil {
+		t.Fatalf("failed to restore: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dataDir, "bin", "items.bin"))
+	if err != nil || string(data) != "items data" {
+		t.Errorf("expected restored items.bin, got %q (%v)", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(dataDir, "bin", "extra.bin")); !os.IsNotExist(err) {
+		t.Error("files created after the backup should be removed by restore")
+	}
+	if _, err := os.Stat(filepath.Join(dataDir, ".restore")); !os.IsNotExist(err) {
+		t.Error("staging directory was not cleaned up")
+	}
+}
+
+func TestBackupEncrypted(t *testing.T) {
+	dataDir := createBackupTestDir(t)
+	archive := filepath.Join(t.TempDir(), "backup.bak")
+
+	if _, err := backup.Create(dataDir, backup.DefaultDirs, archive, "secret"); err != nil {
+		t.Fatalf("failed to create backup: %v", err)
+	}
+
+	if _, _, err := backup.Read(archive, ""); err == nil {
+		t.Error("expected error reading encrypted backup without passphrase")
+	}
+	if _, _, err := backup.Read(archive, "wrong"); !errors.Is(err, backup.ErrWrongPassphrase) {
+		t.Errorf("expected ErrWrongPassphrase, got %v", err)
+	}
+
+	manifest, files, err := backup.Read(archive, "secret")
+	if err != nil {
+		t.Fatalf("failed to read backup: %v", err)
+	}
+	if !manifest.Encrypted {
+		t.Error("expected manifest to report encryption")
+	}
+	if string(files["indexes/items.idx"]) != "items index" {
+		t.Errorf("unexpected index content: %q", files["indexes/items.idx"])
+	}
+}
+
+func TestBackupRejectsCorruption(t *testing.T) {
+	dataDir := createBackupTestDir(t)
+	archive := filepath.Join(t.TempDir(), "backup.bak")
+
+	if _, err := backup.Create(dataDir, backup.DefaultDirs, archive, ""); err != nil {
+		t.Fatalf("failed to create backup: %v", err)
+	}
+
+	data, _ := os.ReadFile(archive)
+	data[len(data)-1] ^= 0xFF
+	os.WriteFile(archive, data, 0644)
+
+	if _, err := backup.Restore(archive, dataDir, backup.DefaultDirs, ""); err == nil {
+		t.Error("expected checksum error for corrupted backup")
+	}
+
+	// Live data must be untouched after a failed restore
+	content, _ := os.ReadFile(filepath.Join(dataDir, "bin", "orders.bin"))
+	if string(content) != "orders data" {
+		t.Errorf("live data changed after failed restore: %q", content)
+	}
+}
@@ -0,0 +1,61 @@
+package test
+
+import (
+	"BinaryCRUD/backend/compression"
+	"BinaryCRUD/backend/crypto"
+	"bytes"
+	"testing"
+)
+
+func TestEncryptBlobRoundTrip(t *testing.T) {
+	compressor, err := compression.NewCompressor(compression.AlgorithmHuffman)
+	if err != nil {
+		t.Fatalf("failed to create compressor: %v", err)
+	}
+	original := bytes.Repeat([]byte("orders and promotions "), 50)
+	compressed, err := compressor.Compress(original)
+	if err != nil {
+		t.Fatalf("failed to compress: %v", err)
+	}
+
+	blob, err := crypto.EncryptBlob(compressed)
+	if err != nil {
+		t.Fatalf("failed to encrypt: %v", err)
+	}
+	if !crypto.IsEncryptedBlob(blob) {
+		t.Error("expected the blob to be detected as encrypted")
+	}
+	if crypto.IsEncryptedBlob(compressed) {
+		t.Error("expected the plain archive not to be detected as encrypted")
+	}
+	if bytes.Contains(blob, compressed[:16]) {
+		t.Error("expected the compressed data not to appear in the blob")
+	}
+
+	decrypted, err := crypto.DecryptBlob(blob)
+	if err != nil {
+		t.Fatalf("failed to decrypt: %v", err)
+	}
+	data, err := compressor.Decompress(decrypted)
+	if err != nil {
+		t.Fatalf("failed to decompress: %v", err)
+	}
+	if !bytes.Equal(data, original) {
+		t.Error("round trip changed the data")
+	}
+}
+
+func TestDecryptBlobRejectsTamperedData(t *testing.T) {
+	blob, err := crypto.EncryptBlob([]byte("archive"))
+	if err != nil {
+		t.Fatalf("failed to encrypt: %v", err)
+	}
+
+	blob[len(blob)-1] ^= 0xFF
+	if _, err := crypto.DecryptBlob(blob); err == nil {
+		t.Error("expected tampered blob to fail")
+	}
+	if _, err := crypto.DecryptBlob(blob[:6]); err == nil {
+		t.Error("expected truncated blob to fail")
+	}
+}
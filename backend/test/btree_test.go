@@ -2,6 +2,7 @@ package test
 
 import (
 	"BinaryCRUD/backend/index"
+	"fmt"
 	"os"
 	"testing"
 )
@@ -134,3 +135,33 @@ func TestBTreeManyInserts(t *testing.T) {
 		t.Errorf("Expected size 100, got %d", tree.Size())
 	}
 }
+
+func TestBTreeAscend(t *testing.T) {
+	tree := index.NewBTree(4)
+	for _, id := range []uint64{42, 7, 19, 3, 88, 56, 21, 64, 1, 30} {
+		if err := tree.Insert(id, int64(id*10)); err != nil {
+			t.Fatalf("Insert %d failed: %v", id, err)
+		}
+	}
+
+	var ids []uint64
+	tree.Ascend(func(id uint64, offset int64) bool {
+		if offset != int64(id*10) {
+			t.Errorf("ID %d: expected offset %d, got %d", id, id*10, offset)
+		}
+		ids = append(ids, id)
+		return true
+	})
+	if fmt.Sprint(ids) != "[1 3 7 19 21 30 42 56 64 88]" {
+		t.Errorf("Expected ascending IDs, got %v", ids)
+	}
+
+	var first []uint64
+	tree.Ascend(func(id uint64, offset int64) bool {
+		first = append(first, id)
+		return len(first) < 3
+	})
+	if fmt.Sprint(first) != "[1 3 7]" {
+		t.Errorf("Expected the walk to stop after 3 IDs, got %v", first)
+	}
+}
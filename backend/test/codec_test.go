@@ -0,0 +1,103 @@
+package test
+
+import (
+	"BinaryCRUD/backend/utils"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// entryWithHeader prefixes an encoded entry with the ID and active tombstone the file layer adds
+func entryWithHeader(t *testing.T, id uint64, entry []byte) []byte {
+	idBytes, err := utils.WriteFixedNumber(utils.IDSize, id)
+	if err != nil {
+		t.Fatalf("failed to write ID: %v", err)
+	}
+	return utils.CombineBytes(idBytes, []byte{0x00}, entry)
+}
+
+func TestItemCodecRoundTrip(t *testing.T) {
+	item := &utils.Item{ID: 7, Name: "Espresso", Price: 350, Extensions: map[byte][]byte{1: []byte("hot")}}
+
+	entry, err := utils.ItemCodec.Encode(item, utils.IDSize)
+	if err != nil {
+		t.Fatalf("failed to encode item: %v", err)
+	}
+	decoded, err := utils.ItemCodec.Decode(entryWithHeader(t, item.ID, entry), utils.IDSize)
+	if err != nil {
+		t.Fatalf("failed to decode item: %v", err)
+	}
+	if !reflect.DeepEqual(decoded, item) {
+		t.Errorf("expected %+v, got %+v", item, decoded)
+	}
+}
+
+func TestCollectionCodecRoundTrip(t *testing.T) {
+	utils.RecordCompressionEnabled = true
+	defer func() { utils.RecordCompressionEnabled = false }()
+
+	itemIDs := make([]uint64, 200)
+	for i := range itemIDs {
+		itemIDs[i] = uint64(i%5 + 1)
+	}
+	collection := &utils.Collection{
+		ID:          3,
+		OwnerOrName: strings.Repeat("Catering order ", 10),
+		TotalPrice:  99900,
+		ItemCount:   uint64(len(itemIDs)),
+		ItemIDs:     itemIDs,
+		Extensions:  map[byte][]byte{2: {0x10}},
+	}
+
+	entry, err := utils.CollectionCodec.Encode(collection, utils.IDSize)
+	if err != nil {
+		t.Fatalf("failed to encode collection: %v", err)
+	}
+	entryData := entryWithHeader(t, collection.ID, entry)
+	if !utils.IsCompressedEntry(entryData, utils.IDSize) {
+		t.Error("expected a large collection to be compressed")
+	}
+	decoded, err := utils.CollectionCodec.Decode(entryData, utils.IDSize)
+	if err != nil {
+		t.Fatalf("failed to decode collection: %v", err)
+	}
+	if !reflect.DeepEqual(decoded, collection) {
+		t.Errorf("expected %+v, got %+v", collection, decoded)
+	}
+}
+
+func TestOrderPromotionCodecRoundTrip(t *testing.T) {
+	op := &utils.OrderPromotion{OrderID: 12, PromotionID: 4, Tombstone: 0x01}
+
+	entry, err := utils.OrderPromotionCodec.Encode(op, utils.IDSize)
+	if err != nil {
+		t.Fatalf("failed to encode relationship: %v", err)
+	}
+	decoded, err := utils.OrderPromotionCodec.Decode(entry, utils.IDSize)
+	if err != nil {
+		t.Fatalf("failed to decode relationship: %v", err)
+	}
+	if *decoded != *op {
+		t.Errorf("expected %+v, got %+v", op, decoded)
+	}
+}
+
+func TestOrderPromotionCodecMetadata(t *testing.T) {
+	op := &utils.OrderPromotion{OrderID: 12, PromotionID: 4, AppliedAt: time.Date(2025, 1, 2, 3, 4, 5, 6, time.UTC), DiscountAmount: 899}
+
+	entry, err := utils.OrderPromotionCodec.Encode(op, utils.IDSize)
+	if err != nil {
+		t.Fatalf("failed to encode relationship: %v", err)
+	}
+	if len(entry) != utils.IDSize*2+utils.TombstoneSize+utils.LinkMetadataSize {
+		t.Errorf("expected the metadata trailer, got %d bytes", len(entry))
+	}
+	decoded, err := utils.OrderPromotionCodec.Decode(entry, utils.IDSize)
+	if err != nil {
+		t.Fatalf("failed to decode relationship: %v", err)
+	}
+	if !decoded.AppliedAt.Equal(op.AppliedAt) || decoded.DiscountAmount != op.DiscountAmount {
+		t.Errorf("expected %+v, got %+v", op, decoded)
+	}
+}
@@ -1,7 +1,10 @@
 package test
 
 import (
+	"BinaryCRUD/backend/crypto"
 	"BinaryCRUD/backend/dao"
+	"BinaryCRUD/backend/utils"
+	"context"
 	"os"
 	"strings"
 	"testing"
@@ -355,3 +358,220 @@ func TestCollectionDAOOrdersVsPromotions(t *testing.T) {
 		t.Logf("Orders and promotions maintain separate ID sequences (both start at 0)")
 	}
 }
+
+func TestCollectionDAOWriteWithID(t *testing.T) {
+	testFile := "/tmp/test_collection_write_with_id.bin"
+	defer cleanupCollectionTest(testFile)
+
+	collectionDAO := dao.NewOrderDAO(testFile)
+
+	if err := collectionDAO.WriteWithID(3, "John Doe", 1500, []uint64{1, 2}); err != nil {
+		t.Fatalf("Failed to write order with ID 3: %v", err)
+	}
+
+	order, err := collectionDAO.Read(3)
+	if err != nil {
+		t.Fatalf("Failed to read order 3: %v", err)
+	}
+	if order.OwnerOrName != "John Doe" || order.TotalPrice != 1500 || len(order.ItemIDs) != 2 {
+		t.Errorf("Unexpected order: %+v", order)
+	}
+
+	if err := collectionDAO.WriteWithID(3, "Jane Smith", 899, []uint64{4}); err == nil {
+		t.Error("Expected error when writing duplicate ID")
+	}
+
+	nextID, err := collectionDAO.Write("Jane Smith", 899, []uint64{4})
+	if err != nil {
+		t.Fatalf("Failed to write order: %v", err)
+	}
+	if nextID != 4 {
+		t.Errorf("Expected next auto-assigned ID 4, got %d", nextID)
+	}
+}
+
+func TestCollectionDAOUpdate(t *testing.T) {
+	testFile := "/tmp/test_collection_update.bin"
+	defer cleanupCollectionTest(testFile)
+
+	collectionDAO := dao.NewOrderDAO(testFile)
+
+	id, _ := collectionDAO.Write("John Doe", 1500, []uint64{1, 2})
+	collectionDAO.Write("Jane Smith", 899, []uint64{4})
+
+	if err := collectionDAO.Update(id, "John Doe", 2000, []uint64{1, 2, 3}); err != nil {
+		t.Fatalf("Failed to update order: %v", err)
+	}
+
+	order, err := collectionDAO.Read(id)
+	if err != nil {
+		t.Fatalf("Failed to read updated order: %v", err)
+	}
+	if order.TotalPrice != 2000 || len(order.ItemIDs) != 3 {
+		t.Errorf("Unexpected updated order: %+v", order)
+	}
+
+	// GetAll only reports the latest version of a rewritten record
+	all, err := collectionDAO.GetAll()
+	if err != nil {
+		t.Fatalf("Failed to get all orders: %v", err)
+	}
+	if len(all) != 2 {
+		t.Fatalf("Expected 2 orders, got %d", len(all))
+	}
+	if all[0].ID != id || all[0].IsDeleted || all[0].TotalPrice != 2000 {
+		t.Errorf("Expected latest active version first, got %+v", all[0])
+	}
+
+	// A reloaded DAO rebuilds its index from the file and finds the new version
+	os.Remove("data/indexes/test_collection_update.idx")
+	reloaded := dao.NewOrderDAO(testFile)
+	order, err = reloaded.Read(id)
+	if err != nil || order.TotalPrice != 2000 {
+		t.Errorf("Expected updated order after reload, got %+v (%v)", order, err)
+	}
+
+	if err := collectionDAO.Update(99, "Nobody", 0, []uint64{1}); err == nil {
+		t.Error("Expected error when updating missing order")
+	}
+}
+
+func TestCollectionDAOWithEncryption(t *testing.T) {
+	orderFile := "/tmp/test_collection_encrypted_orders.bin"
+	promoFile := "/tmp/test_collection_plaintext_promos.bin"
+	defer cleanupCollectionTest(orderFile)
+	defer cleanupCollectionTest(promoFile)
+	defer crypto.SetEnabled(crypto.IsEnabled())
+
+	orderDAO := dao.NewOrderDAO(orderFile, dao.WithEncryption(true))
+	promoDAO := dao.NewPromotionDAO(promoFile, dao.WithEncryption(false))
+	orderID, err := orderDAO.Write("Secret Owner", 100, []uint64{1})
+	if err != nil {
+		t.Fatalf("Failed to write order: %v", err)
+	}
+	if _, err := promoDAO.Write("Summer Sale", 0, []uint64{1}); err != nil {
+		t.Fatalf("Failed to write promotion: %v", err)
+	}
+
+	orderData, _ := os.ReadFile(orderFile)
+	if strings.Contains(string(orderData), "Secret Owner") {
+		t.Error("Expected the order name to be encrypted on disk")
+	}
+	promoData, _ := os.ReadFile(promoFile)
+	if !strings.Contains(string(promoData), "Summer Sale") {
+		t.Error("Expected the promotion name to be plain text on disk")
+	}
+
+	// The header decides, not the global setting or the options of a later DAO
+	crypto.SetEnabled(false)
+	reloaded := dao.NewOrderDAO(orderFile, dao.WithEncryption(false))
+	order, err := reloaded.Read(orderID)
+	if err != nil {
+		t.Fatalf("Failed to read order: %v", err)
+	}
+	if order.OwnerOrName != "Secret Owner" {
+		t.Errorf("Expected 'Secret Owner', got '%s'", order.OwnerOrName)
+	}
+	if encrypted, err := reloaded.NamesEncrypted(); err != nil || !encrypted {
+		t.Errorf("Expected encrypted names, got %v (err: %v)", encrypted, err)
+	}
+
+	crypto.SetEnabled(true)
+	promos, err := dao.NewPromotionDAO(promoFile).GetAll()
+	if err != nil {
+		t.Fatalf("Failed to read promotions: %v", err)
+	}
+	if len(promos) != 1 || promos[0].OwnerOrName != "Summer Sale" {
+		t.Errorf("Expected 'Summer Sale', got %+v", promos)
+	}
+}
+
+func TestCollectionDAOFindByItem(t *testing.T) {
+	testFile := "/tmp/test_collection_find_by_item.bin"
+	defer cleanupCollectionTest(testFile)
+	os.Remove(testFile)
+
+	collectionDAO := dao.NewOrderDAO(testFile)
+	first, err := collectionDAO.Write("John Doe", 1500, []uint64{1, 2, 2})
+	if err != nil {
+		t.Fatalf("Failed to write order: %v", err)
+	}
+	second, err := collectionDAO.Write("Jane Smith", 899, []uint64{2})
+	if err != nil {
+		t.Fatalf("Failed to write order: %v", err)
+	}
+	if ids, err := collectionDAO.FindByItem(2); err != nil || len(ids) != 2 || ids[0] != first || ids[1] != second {
+		t.Fatalf("Expected both orders to list item 2 once each, got %v (err %v)", ids, err)
+	}
+
+	// Writes after the index was built keep it up to date
+	if err := collectionDAO.Update(first, "John Doe", 500, []uint64{3}); err != nil {
+		t.Fatalf("Failed to update order: %v", err)
+	}
+	if err := collectionDAO.Delete(second); err != nil {
+		t.Fatalf("Failed to delete order: %v", err)
+	}
+	third, err := collectionDAO.Write("Bob", 300, []uint64{3})
+	if err != nil {
+		t.Fatalf("Failed to write order: %v", err)
+	}
+	if ids, _ := collectionDAO.FindByItem(2); len(ids) != 0 {
+		t.Errorf("Expected no order to list item 2 anymore, got %v", ids)
+	}
+	if ids, _ := collectionDAO.FindByItem(3); len(ids) != 2 || ids[0] != first || ids[1] != third {
+		t.Errorf("Expected orders %d and %d to list item 3, got %v", first, third, ids)
+	}
+
+	// A new DAO builds the same index from the file
+	if ids, err := dao.NewOrderDAO(testFile).FindByItem(3); err != nil || len(ids) != 2 || ids[0] != first || ids[1] != third {
+		t.Errorf("Expected orders %d and %d to list item 3 after reload, got %v (err %v)", first, third, ids, err)
+	}
+}
+
+func TestCollectionDAOGetAllActiveByID(t *testing.T) {
+	testFile := "/tmp/test_collection_active_by_id.bin"
+	defer cleanupCollectionTest(testFile)
+	os.Remove(testFile)
+
+	collectionDAO := dao.NewOrderDAO(testFile)
+	for _, name := range []string{"John Doe", "Jane Smith", "Bob"} {
+		if _, err := collectionDAO.Write(name, 1500, []uint64{1}); err != nil {
+			t.Fatalf("Failed to write order: %v", err)
+		}
+	}
+	if err := collectionDAO.Update(0, "John Doe", 500, []uint64{2}); err != nil {
+		t.Fatalf("Failed to update order: %v", err)
+	}
+	if err := collectionDAO.Delete(1); err != nil {
+		t.Fatalf("Failed to delete order: %v", err)
+	}
+
+	collections, err := collectionDAO.GetAllActiveByID(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to get orders: %v", err)
+	}
+	if len(collections) != 2 || collections[0].ID != 0 || collections[1].ID != 2 {
+		t.Fatalf("Expected orders 0 and 2 in ID order, got %+v", collections)
+	}
+	if collections[0].OwnerOrName != "John Doe" || collections[0].TotalPrice != 500 || collections[1].OwnerOrName != "Bob" {
+		t.Errorf("Expected the latest versions with decrypted names, got %+v and %+v", collections[0], collections[1])
+	}
+}
+
+func TestCollectionDAOSanitizesNames(t *testing.T) {
+	testFile := "/tmp/test_collection_sanitize.bin"
+	defer cleanupCollectionTest(testFile)
+	os.Remove(testFile)
+
+	collectionDAO := dao.NewOrderDAO(testFile)
+	id, err := collectionDAO.Write(" Alice \n Smith", 1500, []uint64{1})
+	if err != nil {
+		t.Fatalf("Failed to write order: %v", err)
+	}
+	if order, err := collectionDAO.Read(id); err != nil || order.OwnerOrName != "Alice Smith" {
+		t.Errorf("Expected the stored name to be sanitized, got %+v (err %v)", order, err)
+	}
+	if err := collectionDAO.Update(id, " \t ", 1500, []uint64{1}); utils.ErrorCodeOf(err) != utils.CodeValidation {
+		t.Errorf("Expected a blank name to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,258 @@
+package test
+
+import (
+	"BinaryCRUD/backend/dao"
+	"BinaryCRUD/backend/utils"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// setupCompactionFiles writes two items (the second deleted) and an order referencing both
+func setupCompactionFiles(t *testing.T, prefix string) (string, string, string, string) {
+	dir := t.TempDir()
+	name := func(base string) string {
+		return filepath.Join(dir, fmt.Sprintf("%s_%s_%d.bin", prefix, base, os.Getpid()))
+	}
+	itemsPath, ordersPath, promotionsPath, opPath := name("items"), name("orders"), name("promotions"), name("op")
+	t.Cleanup(func() {
+		for _, path := range []string{itemsPath, ordersPath} {
+			os.Remove(utils.IndexPathFromBinFile(path))
+		}
+	})
+
+	itemDAO := dao.NewItemDAO(itemsPath)
+	kept, err := itemDAO.Write("Burger", 899)
+	if err != nil {
+		t.Fatalf("failed to write item: %v", err)
+	}
+	removed, err := itemDAO.Write("Fries", 399)
+	if err != nil {
+		t.Fatalf("failed to write item: %v", err)
+	}
+	if err := itemDAO.Delete(removed); err != nil {
+		t.Fatalf("failed to delete item: %v", err)
+	}
+
+	orderDAO := dao.NewOrderDAO(ordersPath)
+	if _, err := orderDAO.Write("John", 1298, []uint64{kept, removed}); err != nil {
+		t.Fatalf("failed to write order: %v", err)
+	}
+
+	return itemsPath, ordersPath, promotionsPath, opPath
+}
+
+func TestCompactAllRemovesDeletedItems(t *testing.T) {
+	itemsPath, ordersPath, promotionsPath, opPath := setupCompactionFiles(t, "compact_ok")
+
+	result, err := utils.CompactAll(context.Background(), itemsPath, ordersPath, promotionsPath, opPath, nil)
+	if err != nil {
+		t.Fatalf("CompactAll failed: %v", err)
+	}
+	if result.ItemsRemoved != 1 || result.OrdersAffected != 1 {
+		t.Errorf("unexpected result: %+v", result)
+	}
+
+	entries, err := utils.SplitFileIntoEntries(ordersPath)
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("expected 1 order entry, got %d (err %v)", len(entries), err)
+	}
+	order, err := utils.ParseCollectionEntry(entries[0].Data)
+	if err != nil {
+		t.Fatalf("failed to parse order: %v", err)
+	}
+	if len(order.ItemIDs) != 1 || order.ItemIDs[0] != 0 {
+		t.Errorf("expected order to reference only item 0, got %v", order.ItemIDs)
+	}
+	if order.TotalPrice != 899 {
+		t.Errorf("expected total recalculated to 899, got %d", order.TotalPrice)
+	}
+
+	for _, path := range []string{itemsPath, ordersPath} {
+		if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+			t.Errorf("staged file %s.tmp left behind", path)
+		}
+		if _, err := os.Stat(path + ".bak"); !os.IsNotExist(err) {
+			t.Errorf("backup file %s.bak left behind", path)
+		}
+	}
+}
+
+func TestCompactAllRecalculatesTotalsWithPriceLookup(t *testing.T) {
+	itemsPath, ordersPath, promotionsPath, opPath := setupCompactionFiles(t, "compact_price")
+
+	// Pretend every item is priced in a currency worth twice the base
+	double := func(item *utils.Item) (uint64, error) { return item.Price * 2, nil }
+	if _, err := utils.CompactAll(context.Background(), itemsPath, ordersPath, promotionsPath, opPath, double); err != nil {
+		t.Fatalf("CompactAll failed: %v", err)
+	}
+
+	entries, err := utils.SplitFileIntoEntries(ordersPath)
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("expected 1 order entry, got %d (err %v)", len(entries), err)
+	}
+	order, err := utils.ParseCollectionEntry(entries[0].Data)
+	if err != nil {
+		t.Fatalf("failed to parse order: %v", err)
+	}
+	if order.TotalPrice != 1798 {
+		t.Errorf("expected total 1798, got %d", order.TotalPrice)
+	}
+}
+
+func TestCompactAllLeavesFilesUntouchedOnFailure(t *testing.T) {
+	itemsPath, ordersPath, promotionsPath, opPath := setupCompactionFiles(t, "compact_fail")
+
+	itemsBefore, err := os.ReadFile(itemsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ordersBefore, err := os.ReadFile(ordersPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A directory in the way of the staged orders file makes staging fail after items were staged
+	if err := os.Mkdir(ordersPath+".tmp", 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := utils.CompactAll(context.Background(), itemsPath, ordersPath, promotionsPath, opPath, nil); err == nil {
+		t.Fatal("expected CompactAll to fail")
+	}
+
+	itemsAfter, _ := os.ReadFile(itemsPath)
+	ordersAfter, _ := os.ReadFile(ordersPath)
+	if !bytes.Equal(itemsBefore, itemsAfter) || !bytes.Equal(ordersBefore, ordersAfter) {
+		t.Error("files were modified by a failed compaction")
+	}
+	if _, err := os.Stat(itemsPath + ".tmp"); !os.IsNotExist(err) {
+		t.Error("staged items file left behind")
+	}
+}
+
+func TestCompactAllStopsWhenCancelled(t *testing.T) {
+	itemsPath, ordersPath, promotionsPath, opPath := setupCompactionFiles(t, "compact_cancel")
+
+	itemsBefore, err := os.ReadFile(itemsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ordersBefore, err := os.ReadFile(ordersPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = utils.CompactAll(ctx, itemsPath, ordersPath, promotionsPath, opPath, nil)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if code := utils.ErrorCodeOf(err); code != utils.CodeCancelled {
+		t.Errorf("expected code %s, got %s", utils.CodeCancelled, code)
+	}
+
+	itemsAfter, _ := os.ReadFile(itemsPath)
+	ordersAfter, _ := os.ReadFile(ordersPath)
+	if !bytes.Equal(itemsBefore, itemsAfter) || !bytes.Equal(ordersBefore, ordersAfter) {
+		t.Error("files were modified by a cancelled compaction")
+	}
+	for _, path := range []string{itemsPath, ordersPath} {
+		if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+			t.Errorf("staged file %s.tmp left behind", path)
+		}
+	}
+}
+
+// readOrders parses every order record in ordersPath
+func readOrders(t *testing.T, ordersPath string) []*utils.Collection {
+	t.Helper()
+	entries, err := utils.SplitFileIntoEntries(ordersPath)
+	if err != nil {
+		t.Fatalf("failed to read orders: %v", err)
+	}
+	orders := make([]*utils.Collection, len(entries))
+	for i, entry := range entries {
+		if orders[i], err = utils.ParseCollectionEntry(entry.Data); err != nil {
+			t.Fatalf("failed to parse order: %v", err)
+		}
+	}
+	return orders
+}
+
+func TestCompactFilesKeepsItemsCreatedAfterItemsSnapshot(t *testing.T) {
+	itemsPath, ordersPath, promotionsPath, opPath := setupCompactionFiles(t, "compact_snapshot")
+
+	// Online compaction copies items.bin before orders.bin
+	snapshotPath := filepath.Join(t.TempDir(), "items_snapshot.bin")
+	data, err := os.ReadFile(itemsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(snapshotPath, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// An item and an order using it are created between the two copies
+	itemDAO := dao.NewItemDAO(itemsPath)
+	created, err := itemDAO.Write("Milkshake", 500)
+	if err != nil {
+		t.Fatalf("failed to write item: %v", err)
+	}
+	itemDAO.Close()
+	orderDAO := dao.NewOrderDAO(ordersPath)
+	if _, err := orderDAO.Write("Jane", 899, []uint64{created, 1}); err != nil {
+		t.Fatalf("failed to write order: %v", err)
+	}
+	orderDAO.Close()
+
+	result, err := utils.CompactFiles(context.Background(), snapshotPath, ordersPath, promotionsPath, opPath, nil)
+	if err != nil {
+		t.Fatalf("CompactFiles failed: %v", err)
+	}
+	if result.OrdersAffected != 2 {
+		t.Errorf("expected 2 affected orders, got %+v", result)
+	}
+
+	orders := readOrders(t, ordersPath)
+	if len(orders) != 2 {
+		t.Fatalf("expected 2 orders, got %d", len(orders))
+	}
+	if len(orders[0].ItemIDs) != 1 || orders[0].ItemIDs[0] != 0 || orders[0].TotalPrice != 899 {
+		t.Errorf("expected the first order to keep item 0 at 899, got %v at %d", orders[0].ItemIDs, orders[0].TotalPrice)
+	}
+	if len(orders[1].ItemIDs) != 1 || orders[1].ItemIDs[0] != created {
+		t.Errorf("expected the second order to keep item %d, got %v", created, orders[1].ItemIDs)
+	}
+	if orders[1].TotalPrice != 500 {
+		t.Errorf("expected the deleted item's price to be subtracted from the stored total, got %d", orders[1].TotalPrice)
+	}
+}
+
+func TestCompactFilesDropsItemsWhoseSlotWasReused(t *testing.T) {
+	itemsPath, ordersPath, promotionsPath, opPath := setupCompactionFiles(t, "compact_reused")
+
+	// The new item takes the deleted item's slot, so no tombstone is left for ID 1
+	itemDAO := dao.NewItemDAO(itemsPath)
+	if _, err := itemDAO.Write("Chips", 399); err != nil {
+		t.Fatalf("failed to write item: %v", err)
+	}
+	itemDAO.Close()
+
+	if _, err := utils.CompactFiles(context.Background(), itemsPath, ordersPath, promotionsPath, opPath, nil); err != nil {
+		t.Fatalf("CompactFiles failed: %v", err)
+	}
+
+	orders := readOrders(t, ordersPath)
+	if len(orders) != 1 || len(orders[0].ItemIDs) != 1 || orders[0].ItemIDs[0] != 0 {
+		t.Fatalf("expected the order to reference only item 0, got %+v", orders)
+	}
+	if orders[0].TotalPrice != 899 {
+		t.Errorf("expected total recalculated to 899, got %d", orders[0].TotalPrice)
+	}
+}
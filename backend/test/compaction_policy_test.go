@@ -0,0 +1,48 @@
+package test
+
+import (
+	"BinaryCRUD/backend/utils"
+	"path/filepath"
+	"testing"
+)
+
+func TestCompactionPolicy(t *testing.T) {
+	itemsPath, _, _, _ := setupCompactionFiles(t, "policy")
+
+	stats, err := utils.ReadFragmentation(itemsPath)
+	if err != nil {
+		t.Fatalf("failed to read fragmentation: %v", err)
+	}
+	if stats.EntitiesCount != 2 || stats.TombstoneCount != 1 || stats.SizeBytes == 0 {
+		t.Fatalf("unexpected fragmentation: %+v", stats)
+	}
+
+	policy := utils.DefaultCompactionPolicy()
+	if !policy.ShouldCompact(stats) {
+		t.Errorf("expected 50%% tombstones to exceed the default policy")
+	}
+
+	policy.TombstoneRatio = 0.6
+	if policy.ShouldCompact(stats) {
+		t.Errorf("expected 50%% tombstones to stay under a 60%% threshold")
+	}
+
+	policy.MaxFileBytes = stats.SizeBytes - 1
+	if !policy.ShouldCompact(stats) {
+		t.Errorf("expected file size threshold to trigger compaction")
+	}
+
+	policy.MinTombstones = 2
+	if policy.ShouldCompact(stats) {
+		t.Errorf("expected minimum tombstone count to suppress compaction")
+	}
+
+	if err := (utils.CompactionPolicy{TombstoneRatio: 1.5}).Validate(); err == nil {
+		t.Errorf("expected ratio above 1 to be rejected")
+	}
+
+	missing, err := utils.ReadFragmentation(filepath.Join(t.TempDir(), "missing.bin"))
+	if err != nil || missing.EntitiesCount != 0 || policy.ShouldCompact(missing) {
+		t.Errorf("expected missing file to have no fragmentation, got %+v (%v)", missing, err)
+	}
+}
@@ -0,0 +1,135 @@
+package test
+
+import (
+	"BinaryCRUD/backend/compression"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// benchmarkData builds ~64KB of records shaped like items.bin
+func benchmarkData() []byte {
+	var data bytes.Buffer
+	for i := 0; data.Len() < 64*1024; i++ {
+		fmt.Fprintf(&data, "\x00\x14%c%cItem number %05d\x00\x03\x83\x00", byte(i>>8), byte(i), i)
+	}
+	return data.Bytes()
+}
+
+func benchmarkCompressor(b *testing.B, compressor compression.Compressor) {
+	data := benchmarkData()
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+
+	var compressed []byte
+	for i := 0; i < b.N; i++ {
+		var err error
+		if compressed, err = compressor.Compress(data); err != nil {
+			b.Fatalf("Compression failed: %v", err)
+		}
+	}
+	b.ReportMetric(float64(len(compressed))/float64(len(data))*100, "%size")
+}
+
+func BenchmarkCompressHuffman(b *testing.B) {
+	benchmarkCompressor(b, compression.NewHuffmanCompressor())
+}
+
+func BenchmarkCompressLZW(b *testing.B) {
+	benchmarkCompressor(b, compression.NewLZWCompressor())
+}
+
+func BenchmarkCompressGzip(b *testing.B) {
+	benchmarkCompressor(b, compression.NewGzipCompressor())
+}
+
+func BenchmarkCompressGzipFastest(b *testing.B) {
+	compressor, err := compression.NewGzipCompressorLevel(gzip.BestSpeed)
+	if err != nil {
+		b.Fatal(err)
+	}
+	benchmarkCompressor(b, compressor)
+}
+
+func TestGzipCompressorLevel(t *testing.T) {
+	if _, err := compression.NewGzipCompressorLevel(10); err == nil {
+		t.Error("expected level 10 to be rejected")
+	}
+
+	data := benchmarkData()
+	fast, err := compression.NewGzipCompressorLevel(gzip.BestSpeed)
+	if err != nil {
+		t.Fatalf("failed to create compressor: %v", err)
+	}
+	compressed, err := fast.Compress(data)
+	if err != nil {
+		t.Fatalf("Compression failed: %v", err)
+	}
+	decompressed, err := fast.Decompress(compressed)
+	if err != nil {
+		t.Fatalf("Decompression failed: %v", err)
+	}
+	if !bytes.Equal(data, decompressed) {
+		t.Error("Decompressed data doesn't match original")
+	}
+}
+
+func TestAlgorithmsAreRegistered(t *testing.T) {
+	for _, algorithm := range compression.Algorithms() {
+		compressor, err := compression.NewCompressor(algorithm)
+		if err != nil {
+			t.Errorf("%s is listed but not registered: %v", algorithm, err)
+			continue
+		}
+		compressed, err := compressor.Compress([]byte("registered algorithm"))
+		if err != nil {
+			t.Errorf("%s: compression failed: %v", algorithm, err)
+		}
+		if _, err := compressor.Decompress(compressed); err != nil {
+			t.Errorf("%s: decompression failed: %v", algorithm, err)
+		}
+	}
+}
+
+func benchmarkDecompressor(b *testing.B, compressor compression.Compressor) {
+	data := benchmarkData()
+	compressed, err := compressor.Compress(data)
+	if err != nil {
+		b.Fatalf("Compression failed: %v", err)
+	}
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := compressor.Decompress(compressed); err != nil {
+			b.Fatalf("Decompression failed: %v", err)
+		}
+	}
+}
+
+func BenchmarkDecompressHuffman(b *testing.B) {
+	benchmarkDecompressor(b, compression.NewHuffmanCompressor())
+}
+
+func BenchmarkDecompressLZW(b *testing.B) {
+	benchmarkDecompressor(b, compression.NewLZWCompressor())
+}
+
+func BenchmarkCompressZstd(b *testing.B) {
+	benchmarkCompressor(b, compression.NewZstdCompressor())
+}
+
+func BenchmarkCompressZstdFastest(b *testing.B) {
+	compressor, err := compression.NewZstdCompressorLevel(int(zstd.SpeedFastest))
+	if err != nil {
+		b.Fatal(err)
+	}
+	benchmarkCompressor(b, compressor)
+}
+
+func BenchmarkDecompressZstd(b *testing.B) {
+	benchmarkDecompressor(b, compression.NewZstdCompressor())
+}
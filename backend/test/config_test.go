@@ -0,0 +1,111 @@
+package test
+
+import (
+	"BinaryCRUD/backend/utils"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+
+	config, err := utils.LoadConfig(filepath.Join(dir, "missing.json"))
+	if err != nil || !reflect.DeepEqual(config, utils.DefaultConfig()) {
+		t.Fatalf("expected defaults for a missing file, got %+v (err %v)", config, err)
+	}
+
+	// Fields missing from the file keep their defaults
+	partial := filepath.Join(dir, "partial.json")
+	if err := os.WriteFile(partial, []byte(`{"maxNameLength": 40, "compaction": {"tombstoneRatio": 0.5}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	config, err = utils.LoadConfig(partial)
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	if config.MaxNameLength != 40 || config.Compaction.TombstoneRatio != 0.5 || config.MaxItemsPerCollection != utils.DefaultMaxItemsPerCollection {
+		t.Errorf("unexpected config %+v", config)
+	}
+
+	invalid := filepath.Join(dir, "invalid.json")
+	if err := os.WriteFile(invalid, []byte(`{"maxPrice": 5000000000}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if config, err := utils.LoadConfig(invalid); err == nil || !reflect.DeepEqual(config, utils.DefaultConfig()) {
+		t.Errorf("expected a price above the storable maximum to be rejected, got %+v", config)
+	}
+
+	invalidIDSize := filepath.Join(dir, "id_size.json")
+	if err := os.WriteFile(invalidIDSize, []byte(`{"idSize": 3}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := utils.LoadConfig(invalidIDSize); err == nil || !strings.Contains(err.Error(), "idSize") {
+		t.Errorf("expected an unsupported ID size to be rejected, got %v", err)
+	}
+
+	negativeSlow := filepath.Join(dir, "slow.json")
+	if err := os.WriteFile(negativeSlow, []byte(`{"slowOperationMs": -1}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := utils.LoadConfig(negativeSlow); err == nil || !strings.Contains(err.Error(), "slowOperationMs") {
+		t.Errorf("expected a negative slow operation threshold to be rejected, got %v", err)
+	}
+
+	invalidLevel := filepath.Join(dir, "log_level.json")
+	if err := os.WriteFile(invalidLevel, []byte(`{"logging": {"level": "verbose"}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := utils.LoadConfig(invalidLevel); err == nil || !strings.Contains(err.Error(), "logging") {
+		t.Errorf("expected an unknown log level to be rejected, got %v", err)
+	}
+
+	noDelay := filepath.Join(dir, "group_commit.json")
+	if err := os.WriteFile(noDelay, []byte(`{"groupCommit": {"maxRecords": 10}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := utils.LoadConfig(noDelay); err == nil || !strings.Contains(err.Error(), "groupCommit") {
+		t.Errorf("expected group commit without a delay to be rejected, got %v", err)
+	}
+
+	for name, rules := range map[string]string{
+		"unknown_entity.json": `{"rules": {"customer": {"maxNameLength": 10}}}`,
+		"bad_chars.json":      `{"rules": {"item": {"allowedChars": "a-z\\"}}}`,
+		"price_bounds.json":   `{"rules": {"order": {"minPrice": 500, "maxPrice": 100}}}`,
+		"item_cap.json":       `{"rules": {"item": {"maxItems": 3}}}`,
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(rules), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := utils.LoadConfig(path); err == nil || !strings.Contains(err.Error(), "rules") {
+			t.Errorf("expected the rules of %s to be rejected, got %v", name, err)
+		}
+	}
+
+	saved := filepath.Join(dir, "saved", "config.json")
+	if err := utils.SaveConfig(saved, config); err != nil {
+		t.Fatalf("failed to save config: %v", err)
+	}
+	if reloaded, err := utils.LoadConfig(saved); err != nil || !reflect.DeepEqual(reloaded, config) {
+		t.Errorf("expected saved config to load back, got %+v (err %v)", reloaded, err)
+	}
+}
+
+func TestApplyConfig(t *testing.T) {
+	defer utils.ApplyConfig(utils.DefaultConfig())
+
+	config := utils.DefaultConfig()
+	config.MaxNameLength = 5
+	config.MaxPrice = 1000
+	utils.ApplyConfig(config)
+
+	if err := utils.ValidateName("Cheeseburger"); err != utils.ErrNameTooLong || !strings.Contains(err.Error(), "5") {
+		t.Errorf("expected the configured name limit, got %v", err)
+	}
+	if err := utils.ValidatePrice(1001); err == nil {
+		t.Error("expected the configured price limit")
+	}
+}
@@ -0,0 +1,77 @@
+package test
+
+import (
+	"BinaryCRUD/backend/utils"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCurrencyRatesToBase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "currencies.json")
+	if err := os.WriteFile(path, []byte(`{"base":"USD","rates":{"EUR":1.08}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	rates, err := utils.LoadCurrencyRates(path)
+	if err != nil {
+		t.Fatalf("failed to load rates: %v", err)
+	}
+
+	tests := []struct {
+		cents    uint64
+		code     string
+		expected uint64
+	}{
+		{1000, "", 1000},
+		{1000, "USD", 1000},
+		{1000, "EUR", 1080},
+		{999, "EUR", 1079},
+	}
+	for _, tt := range tests {
+		got, err := rates.ToBase(tt.cents, tt.code)
+		if err != nil {
+			t.Fatalf("ToBase(%d, %q) failed: %v", tt.cents, tt.code, err)
+		}
+		if got != tt.expected {
+			t.Errorf("ToBase(%d, %q) = %d, expected %d", tt.cents, tt.code, got, tt.expected)
+		}
+	}
+
+	if _, err := rates.ToBase(100, "JPY"); err == nil {
+		t.Error("expected error for currency without a rate")
+	}
+
+	missing, err := utils.LoadCurrencyRates(filepath.Join(t.TempDir(), "missing.json"))
+	if err != nil || missing.Base != utils.BaseCurrency {
+		t.Errorf("expected default rates for missing file, got %+v (err %v)", missing, err)
+	}
+}
+
+func TestCurrencyExtensionAndFormat(t *testing.T) {
+	data, err := utils.EncodeCurrency("EUR")
+	if err != nil {
+		t.Fatalf("failed to encode currency: %v", err)
+	}
+	code, err := utils.DecodeCurrency(map[byte][]byte{utils.ExtCurrency: data})
+	if err != nil || code != "EUR" {
+		t.Errorf("expected EUR, got %q (err %v)", code, err)
+	}
+	if _, err := utils.EncodeCurrency("eur"); err == nil {
+		t.Error("expected error for lowercase currency code")
+	}
+
+	formats := map[string]string{
+		"USD": "$1,234.56",
+		"EUR": "1.234,56 €",
+		"BRL": "R$ 1.234,56",
+		"CHF": "1,234.56 CHF",
+	}
+	for code, expected := range formats {
+		if got := utils.FormatPrice(123456, code); got != expected {
+			t.Errorf("FormatPrice(123456, %s) = %q, expected %q", code, got, expected)
+		}
+	}
+	if got := utils.FormatPrice(5, "USD"); got != "$0.05" {
+		t.Errorf("FormatPrice(5, USD) = %q", got)
+	}
+}
@@ -1,11 +1,14 @@
 package test
 
 import (
+	"BinaryCRUD/backend/dao"
 	"BinaryCRUD/backend/index"
 	"BinaryCRUD/backend/utils"
 	"fmt"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 )
 
 func TestInitializeDAOIndexCreatesCorrectPath(t *testing.T) {
@@ -196,3 +199,126 @@ func TestInitializeDAOIndexPreservesIndexPath(t *testing.T) {
 		}
 	}
 }
+
+func TestDirtyIndexRebuiltOnLoad(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "test_dirty_index.bin")
+	indexPath := utils.IndexPathFromBinFile(testFile)
+	defer os.Remove(indexPath)
+	defer os.Remove(utils.IndexDirtyPath(indexPath))
+	defer os.Remove(utils.TombstoneBitmapPath(testFile))
+
+	itemDAO := dao.NewItemDAO(testFile)
+	if _, err := itemDAO.Write("Burger", 899); err != nil {
+		t.Fatalf("Failed to write item: %v", err)
+	}
+	if err := itemDAO.Close(); err != nil {
+		t.Fatalf("Failed to close: %v", err)
+	}
+	stale, err := os.ReadFile(indexPath)
+	if err != nil {
+		t.Fatalf("Failed to read index: %v", err)
+	}
+	if _, err := itemDAO.Write("Fries", 399); err != nil {
+		t.Fatalf("Failed to write item: %v", err)
+	}
+	if !utils.IndexDirty(indexPath) {
+		t.Fatal("Expected the marker while the index is behind the data file")
+	}
+	if err := itemDAO.Close(); err != nil {
+		t.Fatalf("Failed to close: %v", err)
+	}
+	if utils.IndexDirty(indexPath) {
+		t.Fatal("Expected the marker to be cleared once the index was saved")
+	}
+
+	// Without the marker the saved index is trusted, even when it misses a record
+	if err := os.WriteFile(indexPath, stale, 0644); err != nil {
+		t.Fatalf("Failed to restore stale index: %v", err)
+	}
+	if size := dao.NewItemDAO(testFile).GetIndexTree().Size(); size != 1 {
+		t.Errorf("Expected the unmarked index to be loaded as saved, got %d entries", size)
+	}
+
+	// The process died between the append and the index save: the marker makes the next load rebuild
+	if err := os.WriteFile(indexPath, stale, 0644); err != nil {
+		t.Fatalf("Failed to restore stale index: %v", err)
+	}
+	if err := utils.MarkIndexDirty(indexPath); err != nil {
+		t.Fatalf("Failed to mark index dirty: %v", err)
+	}
+	if size := dao.NewItemDAO(testFile).GetIndexTree().Size(); size != 2 {
+		t.Errorf("Expected the dirty index to be rebuilt with 2 entries, got %d", size)
+	}
+	if utils.IndexDirty(indexPath) {
+		t.Error("Expected the rebuild to clear the marker")
+	}
+}
+
+func TestIndexSavedOnceAfterBurstOfWrites(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "test_index_save_delay.bin")
+	indexPath := utils.IndexPathFromBinFile(testFile)
+	defer os.Remove(indexPath)
+	defer os.Remove(utils.IndexDirtyPath(indexPath))
+
+	itemDAO := dao.NewItemDAO(testFile)
+	defer itemDAO.Close()
+	for _, name := range []string{"Burger", "Fries", "Soda"} {
+		if _, err := itemDAO.Write(name, 100); err != nil {
+			t.Fatalf("Failed to write item: %v", err)
+		}
+	}
+	if _, err := os.Stat(indexPath); !os.IsNotExist(err) {
+		t.Errorf("Expected the index to wait for the end of the burst, got %v", err)
+	}
+
+	// The timer saves the index without another write or Close, then clears the marker
+	deadline := time.Now().Add(2 * time.Second)
+	for utils.IndexDirty(indexPath) && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if utils.IndexDirty(indexPath) {
+		t.Fatal("Expected the marker to be cleared once the index was saved")
+	}
+	saved, err := index.Load(indexPath)
+	if err != nil || saved.Size() != 3 {
+		t.Fatalf("Expected the saved index to hold 3 items, got %v", err)
+	}
+}
+
+func TestWriteWaitsForIndexLoadBeforeMarking(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "test_index_marked_after_load.bin")
+	indexPath := utils.IndexPathFromBinFile(testFile)
+	defer os.Remove(indexPath)
+	defer os.Remove(utils.IndexDirtyPath(indexPath))
+
+	itemDAO := dao.NewItemDAO(testFile)
+	if _, err := itemDAO.Write("Burger", 100); err != nil {
+		t.Fatalf("Failed to write item: %v", err)
+	}
+	fries, err := itemDAO.Write("Fries", 100)
+	if err != nil {
+		t.Fatalf("Failed to write item: %v", err)
+	}
+	if err := itemDAO.Close(); err != nil {
+		t.Fatalf("Failed to close: %v", err)
+	}
+
+	// An index missing a record but saved without a marker is trusted; a write made while it loads
+	// must not leave the marker early and make the load rebuild it
+	stale, err := index.Load(indexPath)
+	if err != nil {
+		t.Fatalf("Failed to load index: %v", err)
+	}
+	stale.Delete(fries)
+	if err := stale.Save(indexPath); err != nil {
+		t.Fatalf("Failed to save stale index: %v", err)
+	}
+	reopened := dao.NewItemDAO(testFile)
+	defer reopened.Close()
+	if _, err := reopened.Write("Soda", 100); err != nil {
+		t.Fatalf("Failed to write item: %v", err)
+	}
+	if size := reopened.GetIndexTree().Size(); size != 2 {
+		t.Errorf("Expected the saved index to be loaded and then updated, got %d entries", size)
+	}
+}
@@ -0,0 +1,65 @@
+package test
+
+import (
+	"BinaryCRUD/backend/utils"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSetDataDir(t *testing.T) {
+	root := t.TempDir()
+	utils.SetDataDir(root)
+	defer utils.SetDataDir(utils.DefaultDataDir)
+
+	if got := utils.BinPath("items.bin"); got != filepath.Join(root, "bin", "items.bin") {
+		t.Errorf("unexpected bin path %s", got)
+	}
+	if got := utils.IndexPathFromBinFile("/elsewhere/items.bin"); got != filepath.Join(root, "indexes", "items.idx") {
+		t.Errorf("unexpected index path %s", got)
+	}
+}
+
+func TestMoveDataDir(t *testing.T) {
+	from, to := t.TempDir(), filepath.Join(t.TempDir(), "moved")
+	for _, file := range []string{"bin/items.bin", "indexes/items.idx", "seed/items.json"} {
+		path := filepath.Join(from, file)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(file), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	moved, err := utils.MoveDataDir(from, to)
+	if err != nil {
+		t.Fatalf("failed to move data: %v", err)
+	}
+	if len(moved) != 3 {
+		t.Errorf("expected bin, indexes and seed to be moved, got %v", moved)
+	}
+
+	for _, file := range []string{"bin/items.bin", "indexes/items.idx", "seed/items.json"} {
+		if data, err := os.ReadFile(filepath.Join(to, file)); err != nil || string(data) != file {
+			t.Errorf("expected %s in the new directory (err %v)", file, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(from, "bin")); !os.IsNotExist(err) {
+		t.Error("expected bin to be moved out of the old directory")
+	}
+	if _, err := os.Stat(filepath.Join(from, "seed", "items.json")); err != nil {
+		t.Error("expected seed files to be kept in the old directory")
+	}
+
+	// Moving back onto a directory that already holds data is refused
+	if err := os.MkdirAll(filepath.Join(from, "bin"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(from, "bin", "orders.bin"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := utils.MoveDataDir(to, from); err == nil {
+		t.Error("expected moving onto existing data to fail")
+	}
+}
@@ -0,0 +1,34 @@
+package test
+
+import (
+	"BinaryCRUD/backend/utils"
+	"errors"
+	"runtime"
+	"testing"
+)
+
+func TestLockDataDirIsExclusive(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("advisory file locks are not available on Windows")
+	}
+	dir := t.TempDir()
+
+	first, err := utils.LockDataDir(dir)
+	if err != nil {
+		t.Fatalf("failed to lock data directory: %v", err)
+	}
+
+	if _, err := utils.LockDataDir(dir); !errors.Is(err, utils.ErrDataDirLocked) {
+		t.Fatalf("expected ErrDataDirLocked for a second lock, got %v", err)
+	}
+
+	if err := first.Release(); err != nil {
+		t.Fatalf("failed to release lock: %v", err)
+	}
+
+	second, err := utils.LockDataDir(dir)
+	if err != nil {
+		t.Fatalf("expected lock to be free after release, got %v", err)
+	}
+	second.Release()
+}
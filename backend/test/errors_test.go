@@ -0,0 +1,83 @@
+package test
+
+import (
+	"BinaryCRUD/backend/dao"
+	"BinaryCRUD/backend/utils"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+)
+
+func TestErrorCodeOfUnclassified(t *testing.T) {
+	if code := utils.ErrorCodeOf(errors.New("boom")); code != utils.CodeInternal {
+		t.Errorf("Expected Internal, got %s", code)
+	}
+}
+
+func TestErrorCodeOfWrapped(t *testing.T) {
+	err := fmt.Errorf("creating order: %w", utils.ErrNameEmpty)
+	if code := utils.ErrorCodeOf(err); code != utils.CodeValidation {
+		t.Errorf("Expected Validation, got %s", code)
+	}
+	if !errors.Is(err, utils.ErrValidation) {
+		t.Error("Expected errors.Is to match the Validation sentinel")
+	}
+	if errors.Is(err, utils.ErrNotFound) {
+		t.Error("Expected errors.Is not to match the NotFound sentinel")
+	}
+}
+
+func TestErrorCodeOfFileErrors(t *testing.T) {
+	_, err := os.Open("/nonexistent/binarycrud/file.bin")
+	if code := utils.ErrorCodeOf(fmt.Errorf("open: %w", err)); code != utils.CodeIO {
+		t.Errorf("Expected IO, got %s", code)
+	}
+}
+
+func TestErrorDetailsOuterWins(t *testing.T) {
+	inner := utils.WithCode(utils.CodeNotFound, errors.New("entry not found"), map[string]any{"id": uint64(1), "file": "items.bin"})
+	outer := utils.WithDetails(fmt.Errorf("item not found: %w", inner), utils.RecordDetails("item", 7))
+
+	if code := utils.ErrorCodeOf(outer); code != utils.CodeNotFound {
+		t.Errorf("Expected the inner NotFound code, got %s", code)
+	}
+	details := utils.ErrorDetails(outer)
+	if details["id"] != uint64(7) || details["entity"] != "item" || details["file"] != "items.bin" {
+		t.Errorf("Unexpected details %v", details)
+	}
+}
+
+func TestWithCodeNil(t *testing.T) {
+	if utils.WithCode(utils.CodeIO, nil, nil) != nil {
+		t.Error("Expected a nil error to stay nil")
+	}
+}
+
+func TestItemDAOErrorCodes(t *testing.T) {
+	utils.SetDataDir(t.TempDir())
+	t.Cleanup(func() { utils.SetDataDir(utils.DefaultDataDir) })
+	itemDAO := dao.NewItemDAO(utils.BinPath("items.bin"))
+	defer itemDAO.Close()
+
+	id, err := itemDAO.Write("Coded", 100)
+	if err != nil {
+		t.Fatalf("Failed to write item: %v", err)
+	}
+
+	_, _, _, err = itemDAO.Read(id + 10)
+	if code := utils.ErrorCodeOf(err); code != utils.CodeNotFound {
+		t.Errorf("Expected NotFound for a missing item, got %s (%v)", code, err)
+	}
+	if details := utils.ErrorDetails(err); details["entity"] != "item" || details["id"] != id+10 {
+		t.Errorf("Unexpected details %v", details)
+	}
+
+	if err := itemDAO.Delete(id); err != nil {
+		t.Fatalf("Failed to delete item: %v", err)
+	}
+	_, _, _, err = itemDAO.Read(id)
+	if code := utils.ErrorCodeOf(err); code != utils.CodeDeleted {
+		t.Errorf("Expected Deleted for a deleted item, got %s (%v)", code, err)
+	}
+}
@@ -0,0 +1,67 @@
+package test
+
+import (
+	"BinaryCRUD/backend/events"
+	"testing"
+)
+
+func TestEventBusDeliversInSubscriptionOrder(t *testing.T) {
+	var bus events.Bus
+	var calls []string
+
+	bus.Subscribe(func(e events.Event) { calls = append(calls, "first:"+string(e.Type)) })
+	bus.Subscribe(func(e events.Event) { calls = append(calls, "second:"+string(e.Type)) })
+	bus.Publish(events.Event{Type: events.ItemCreated, ID: 7})
+
+	if len(calls) != 2 || calls[0] != "first:ItemCreated" || calls[1] != "second:ItemCreated" {
+		t.Errorf("unexpected deliveries %v", calls)
+	}
+}
+
+func TestEventBusFiltersByType(t *testing.T) {
+	var bus events.Bus
+	var received []events.Event
+
+	bus.Subscribe(func(e events.Event) { received = append(received, e) }, events.OrderDeleted, events.Compacted)
+	bus.Publish(events.Event{Type: events.ItemCreated, ID: 1})
+	bus.Publish(events.Event{Type: events.OrderDeleted, ID: 2})
+	bus.Publish(events.Event{Type: events.Compacted})
+
+	if len(received) != 2 || received[0].ID != 2 || received[1].Type != events.Compacted {
+		t.Fatalf("unexpected events %+v", received)
+	}
+	if received[0].Timestamp.IsZero() {
+		t.Error("expected Publish to stamp the event")
+	}
+}
+
+func TestEventBusUnsubscribe(t *testing.T) {
+	var bus events.Bus
+	count := 0
+
+	unsubscribe := bus.Subscribe(func(events.Event) { count++ })
+	bus.Publish(events.Event{Type: events.ItemUpdated})
+	unsubscribe()
+	bus.Publish(events.Event{Type: events.ItemUpdated})
+
+	if count != 1 {
+		t.Errorf("expected 1 delivery, got %d", count)
+	}
+}
+
+func TestEventBusHandlerCanPublish(t *testing.T) {
+	var bus events.Bus
+	var seen []events.Type
+
+	bus.Subscribe(func(e events.Event) {
+		seen = append(seen, e.Type)
+		if e.Type == events.Compacted {
+			bus.Publish(events.Event{Type: events.DataReloaded})
+		}
+	})
+	bus.Publish(events.Event{Type: events.Compacted})
+
+	if len(seen) != 2 || seen[1] != events.DataReloaded {
+		t.Errorf("unexpected events %v", seen)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"BinaryCRUD/backend/index"
 	"fmt"
 	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -330,3 +331,78 @@ func TestExtensibleHashSameOrderDifferentPromotions(t *testing.T) {
 		}
 	}
 }
+
+func TestExtensibleHashReplaysLog(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "hash.idx")
+
+	h := index.NewExtensibleHash(4)
+	h.Insert(1, 10, 100)
+	h.SetGeneration(1)
+	if err := h.Save(filePath); err != nil {
+		t.Fatalf("Failed to save: %v", err)
+	}
+	if err := h.LogInsert(filePath, 2, 20, 200, 2); err != nil {
+		t.Fatalf("Failed to log insert: %v", err)
+	}
+	if err := h.LogDelete(filePath, 1, 10, 3); err != nil {
+		t.Fatalf("Failed to log delete: %v", err)
+	}
+
+	// A record torn by a crash is ignored
+	logFile, err := os.OpenFile(index.HashLogPath(filePath), os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatalf("Failed to open log: %v", err)
+	}
+	logFile.Write([]byte{1, 3, 0, 0})
+	logFile.Close()
+
+	loaded, err := index.LoadExtensibleHash(filePath)
+	if err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+	if _, found := loaded.Search(1, 10); found {
+		t.Error("Expected logged delete to be replayed")
+	}
+	if offset, found := loaded.Search(2, 20); !found || offset != 200 {
+		t.Errorf("Expected logged insert at offset 200, got %d (found=%v)", offset, found)
+	}
+	if generation, _ := loaded.Generation(); generation != 3 || loaded.Logged() != 2 {
+		t.Errorf("Expected generation 3 with 2 logged changes, got %d and %d", generation, loaded.Logged())
+	}
+
+	// Saving checkpoints the log
+	if err := loaded.Save(filePath); err != nil {
+		t.Fatalf("Failed to save: %v", err)
+	}
+	if _, err := os.Stat(index.HashLogPath(filePath)); !os.IsNotExist(err) {
+		t.Errorf("Expected checkpoint to remove the log, got %v", err)
+	}
+}
+
+func TestExtensibleHashSkipsCheckpointedLog(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "hash.idx")
+
+	h := index.NewExtensibleHash(4)
+	if err := h.LogInsert(filePath, 1, 10, 100, 1); err != nil {
+		t.Fatalf("Failed to log insert: %v", err)
+	}
+	logData, err := os.ReadFile(index.HashLogPath(filePath))
+	if err != nil {
+		t.Fatalf("Failed to read log: %v", err)
+	}
+	if err := h.Save(filePath); err != nil {
+		t.Fatalf("Failed to save: %v", err)
+	}
+
+	// A crash between saving the index and removing the log leaves records the index already has
+	if err := os.WriteFile(index.HashLogPath(filePath), logData, 0644); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := index.LoadExtensibleHash(filePath)
+	if err != nil {
+		t.Fatalf("Failed to load: %v", err)
+	}
+	if loaded.Size() != 1 || loaded.Logged() != 0 {
+		t.Errorf("Expected 1 entry and no replayed changes, got %d and %d", loaded.Size(), loaded.Logged())
+	}
+}
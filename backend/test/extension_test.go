@@ -0,0 +1,130 @@
+package test
+
+import (
+	"BinaryCRUD/backend/dao"
+	"BinaryCRUD/backend/utils"
+	"testing"
+	"time"
+)
+
+func TestExtensionsRoundTrip(t *testing.T) {
+	ext := map[byte][]byte{0x02: {0xAA}, utils.ExtDiscount: {0x01, 0, 0, 0, 10}}
+
+	data, err := utils.EncodeExtensions(ext)
+	if err != nil {
+		t.Fatalf("failed to encode extensions: %v", err)
+	}
+	if data[0] != utils.ExtDiscount {
+		t.Errorf("expected extensions sorted by tag, first tag 0x%02x", data[0])
+	}
+
+	decoded, err := utils.DecodeExtensions(data)
+	if err != nil {
+		t.Fatalf("failed to decode extensions: %v", err)
+	}
+	if len(decoded) != 2 || decoded[0x02][0] != 0xAA {
+		t.Errorf("unexpected extensions: %v", decoded)
+	}
+
+	if _, err := utils.DecodeExtensions(data[:len(data)-1]); err == nil {
+		t.Error("expected error for truncated extension")
+	}
+}
+
+func TestDiscountAmount(t *testing.T) {
+	tests := []struct {
+		discount utils.Discount
+		total    uint64
+		expected uint64
+	}{
+		{utils.Discount{Type: utils.DiscountNone}, 1000, 0},
+		{utils.Discount{Type: utils.DiscountPercent, Value: 25}, 1000, 250},
+		{utils.Discount{Type: utils.DiscountFixed, Value: 300}, 1000, 300},
+		{utils.Discount{Type: utils.DiscountFixed, Value: 3000}, 1000, 1000},
+	}
+	for _, tt := range tests {
+		if got := tt.discount.Amount(tt.total); got != tt.expected {
+			t.Errorf("%+v on %d: expected %d, got %d", tt.discount, tt.total, tt.expected, got)
+		}
+	}
+
+	if _, err := utils.EncodeDiscount(utils.Discount{Type: utils.DiscountPercent, Value: 150}); err == nil {
+		t.Error("expected error for percentage over 100")
+	}
+}
+
+func TestPromotionDiscountPersistsThroughUpdate(t *testing.T) {
+	testFile := "/tmp/test_promotion_discount.bin"
+	defer cleanupCollectionTest(testFile)
+
+	promotionDAO := dao.NewPromotionDAO(testFile)
+	discount, _ := utils.EncodeDiscount(utils.Discount{Type: utils.DiscountPercent, Value: 20})
+
+	id, err := promotionDAO.WriteExtended(nil, "Combo", 1200, []uint64{1, 2}, map[byte][]byte{utils.ExtDiscount: discount})
+	if err != nil {
+		t.Fatalf("failed to write promotion: %v", err)
+	}
+
+	if err := promotionDAO.Update(id, "Combo Deluxe", 1500, []uint64{1, 2, 3}); err != nil {
+		t.Fatalf("failed to update promotion: %v", err)
+	}
+
+	promotion, err := promotionDAO.Read(id)
+	if err != nil {
+		t.Fatalf("failed to read promotion: %v", err)
+	}
+	d, err := utils.DecodeDiscount(promotion.Extensions)
+	if err != nil || d.Type != utils.DiscountPercent || d.Value != 20 {
+		t.Errorf("expected 20%% discount to survive update, got %+v (%v)", d, err)
+	}
+}
+
+func TestOrderStatusTransitions(t *testing.T) {
+	tests := []struct {
+		from, to byte
+		allowed  bool
+	}{
+		{utils.OrderPending, utils.OrderPaid, true},
+		{utils.OrderPending, utils.OrderCancelled, true},
+		{utils.OrderPending, utils.OrderShipped, false},
+		{utils.OrderPaid, utils.OrderShipped, true},
+		{utils.OrderPaid, utils.OrderPending, false},
+		{utils.OrderShipped, utils.OrderCancelled, false},
+		{utils.OrderCancelled, utils.OrderPaid, false},
+	}
+	for _, tt := range tests {
+		err := utils.ValidateOrderTransition(tt.from, tt.to)
+		if (err == nil) != tt.allowed {
+			t.Errorf("%s -> %s: expected allowed=%v, got err=%v",
+				utils.OrderStatusName(tt.from), utils.OrderStatusName(tt.to), tt.allowed, err)
+		}
+	}
+
+	status, err := utils.DecodeOrderStatus(nil)
+	if err != nil || status != utils.OrderPending {
+		t.Errorf("expected orders without status to be pending, got %d (%v)", status, err)
+	}
+	if _, err := utils.ParseOrderStatus("refunded"); err == nil {
+		t.Error("expected error for unknown status")
+	}
+}
+
+func TestTimestampExtension(t *testing.T) {
+	created := time.Date(2024, 3, 15, 10, 30, 0, 123, time.UTC)
+	ext := map[byte][]byte{utils.ExtCreatedAt: utils.EncodeTimestamp(created)}
+
+	got, ok, err := utils.DecodeTimestamp(ext, utils.ExtCreatedAt)
+	if err != nil || !ok {
+		t.Fatalf("DecodeTimestamp failed: ok=%v err=%v", ok, err)
+	}
+	if !got.Equal(created) {
+		t.Fatalf("expected %v, got %v", created, got)
+	}
+
+	if _, ok, err := utils.DecodeTimestamp(nil, utils.ExtCreatedAt); ok || err != nil {
+		t.Fatalf("expected missing timestamp, got ok=%v err=%v", ok, err)
+	}
+	if _, _, err := utils.DecodeTimestamp(map[byte][]byte{utils.ExtCreatedAt: {1, 2}}, utils.ExtCreatedAt); err == nil {
+		t.Fatal("expected error for truncated timestamp")
+	}
+}
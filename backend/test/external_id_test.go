@@ -0,0 +1,100 @@
+package test
+
+import (
+	"BinaryCRUD/backend/dao"
+	"BinaryCRUD/backend/utils"
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+const testExternalID = "123e4567-e89b-12d3-a456-426614174000"
+
+// externalIDExtensions returns the record extensions for an external ID
+func externalIDExtensions(t *testing.T, externalID string) map[byte][]byte {
+	data, err := utils.EncodeExternalID(externalID)
+	if err != nil {
+		t.Fatalf("failed to encode external ID: %v", err)
+	}
+	return map[byte][]byte{utils.ExtExternalID: data}
+}
+
+func TestExternalIDExtension(t *testing.T) {
+	ext := externalIDExtensions(t, "123E4567-E89B-12D3-A456-426614174000")
+	if len(ext[utils.ExtExternalID]) != 16 {
+		t.Errorf("expected a 16 byte UUID, got %d bytes", len(ext[utils.ExtExternalID]))
+	}
+	if externalID, err := utils.DecodeExternalID(ext); err != nil || externalID != testExternalID {
+		t.Errorf("expected %s, got %q (err %v)", testExternalID, externalID, err)
+	}
+	if externalID, err := utils.DecodeExternalID(nil); err != nil || externalID != "" {
+		t.Errorf("expected no external ID, got %q (err %v)", externalID, err)
+	}
+
+	for _, invalid := range []string{"", "not-a-uuid", "123e4567e89b12d3a456426614174000", "123e4567-e89b-12d3-a456-42661417400g"} {
+		if _, err := utils.EncodeExternalID(invalid); err == nil {
+			t.Errorf("expected %q to be rejected", invalid)
+		}
+	}
+}
+
+func TestItemDAOFindByExternalID(t *testing.T) {
+	utils.SetDataDir(t.TempDir())
+	t.Cleanup(func() { utils.SetDataDir(utils.DefaultDataDir) })
+	dir := t.TempDir()
+	itemsPath := filepath.Join(dir, "items.bin")
+
+	itemDAO := dao.NewItemDAO(itemsPath)
+	if _, err := itemDAO.Write("Fries", 349); err != nil {
+		t.Fatalf("failed to write item: %v", err)
+	}
+	id, err := itemDAO.WriteExtended(nil, "Burger", 899, externalIDExtensions(t, testExternalID))
+	if err != nil {
+		t.Fatalf("failed to write item: %v", err)
+	}
+
+	if found, err := itemDAO.FindByExternalID("123E4567-E89B-12D3-A456-426614174000"); err != nil || found != id {
+		t.Errorf("expected item %d, got %d (err %v)", id, found, err)
+	}
+	if _, err := itemDAO.FindByExternalID("00000000-0000-0000-0000-000000000000"); err == nil {
+		t.Error("expected an unknown external ID to fail")
+	}
+
+	// External IDs are unique among active items
+	if _, err := itemDAO.WriteExtended(nil, "Shake", 500, externalIDExtensions(t, testExternalID)); err == nil {
+		t.Error("expected a duplicate external ID to be rejected")
+	}
+
+	// Updating the item keeps its external ID
+	if err := itemDAO.Update(id, "Cheeseburger", 999); err != nil {
+		t.Fatalf("failed to update item: %v", err)
+	}
+	if found, err := itemDAO.FindByExternalID(testExternalID); err != nil || found != id {
+		t.Errorf("expected item %d after the update, got %d (err %v)", id, found, err)
+	}
+
+	// References survive compaction, which moves records around
+	if err := itemDAO.Delete(0); err != nil {
+		t.Fatalf("failed to delete item: %v", err)
+	}
+	_, err = utils.CompactFiles(context.Background(), itemsPath, filepath.Join(dir, "orders.bin"), filepath.Join(dir, "promotions.bin"),
+		filepath.Join(dir, "order_promotions.bin"), nil)
+	if err != nil {
+		t.Fatalf("failed to compact: %v", err)
+	}
+	reopened := dao.NewItemDAO(itemsPath)
+	if found, err := reopened.FindByExternalID(testExternalID); err != nil || found != id {
+		t.Errorf("expected item %d after compaction, got %d (err %v)", id, found, err)
+	}
+
+	// A deleted item frees its external ID
+	if err := reopened.Delete(id); err != nil {
+		t.Fatalf("failed to delete item: %v", err)
+	}
+	if _, err := reopened.FindByExternalID(testExternalID); err == nil {
+		t.Error("expected the external ID of a deleted item not to be found")
+	}
+	if _, err := reopened.WriteExtended(nil, "Shake", 500, externalIDExtensions(t, testExternalID)); err != nil {
+		t.Errorf("expected the freed external ID to be reusable: %v", err)
+	}
+}
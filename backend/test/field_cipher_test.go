@@ -0,0 +1,216 @@
+package test
+
+import (
+	"BinaryCRUD/backend/crypto"
+	"BinaryCRUD/backend/dao"
+	"BinaryCRUD/backend/utils"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFieldCipherRoundTrip(t *testing.T) {
+	crypto.Reset()
+	defer crypto.Reset()
+	keysDir := t.TempDir()
+
+	fieldCipher, err := crypto.GetFieldCipher(keysDir)
+	if err != nil {
+		t.Fatalf("failed to get field cipher: %v", err)
+	}
+
+	encrypted, err := fieldCipher.EncryptToBytesIf(true, "Alice Wonderland")
+	if err != nil {
+		t.Fatalf("failed to encrypt: %v", err)
+	}
+	if crypto.IsLegacyCiphertext(encrypted) {
+		t.Error("expected an AES-GCM ciphertext")
+	}
+
+	rsa, _ := crypto.GetInstance()
+	crypto.SetEnabled(true)
+	legacy, _ := rsa.EncryptToBytes("Alice Wonderland")
+	if len(encrypted) >= len(legacy) {
+		t.Errorf("expected AES-GCM ciphertext (%d bytes) to be smaller than RSA (%d bytes)", len(encrypted), len(legacy))
+	}
+
+	// The wrapped data key survives a reset, so old ciphertexts stay readable
+	if _, err := os.Stat(filepath.Join(keysDir, crypto.DataKeyFile)); err != nil {
+		t.Fatalf("expected the data key to be stored: %v", err)
+	}
+	crypto.Reset()
+	fieldCipher, err = crypto.GetFieldCipher(keysDir)
+	if err != nil {
+		t.Fatalf("failed to reload field cipher: %v", err)
+	}
+
+	for _, ciphertext := range [][]byte{encrypted, legacy} {
+		decrypted, err := fieldCipher.DecryptFromBytesIf(true, ciphertext)
+		if err != nil {
+			t.Fatalf("failed to decrypt: %v", err)
+		}
+		if decrypted != "Alice Wonderland" {
+			t.Errorf("expected 'Alice Wonderland', got '%s'", decrypted)
+		}
+	}
+}
+
+func TestFieldCipherRejectsTamperedData(t *testing.T) {
+	crypto.Reset()
+	defer crypto.Reset()
+
+	fieldCipher, err := crypto.GetFieldCipher(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to get field cipher: %v", err)
+	}
+	encrypted, _ := fieldCipher.EncryptToBytesIf(true, "Alice")
+	encrypted[len(encrypted)-1] ^= 0xFF
+
+	if _, err := fieldCipher.DecryptFromBytesIf(true, encrypted); err == nil {
+		t.Error("expected tampered ciphertext to fail")
+	}
+}
+
+func TestCollectionDAOReencryptNames(t *testing.T) {
+	testFile := "/tmp/test_collection_reencrypt.bin"
+	defer cleanupCollectionTest(testFile)
+	defer crypto.SetEnabled(crypto.IsEnabled())
+	crypto.SetEnabled(true)
+
+	// Start with a record written by the legacy RSA scheme
+	rsa, _ := crypto.GetInstance()
+	legacyName, _ := rsa.EncryptToBytes("Old Owner")
+	entry, err := utils.BuildCollectionEntry(legacyName, 200, []uint64{2})
+	if err != nil {
+		t.Fatalf("failed to build entry: %v", err)
+	}
+	if err := utils.EnsureFileExists(testFile); err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+	file, err := os.OpenFile(testFile, os.O_RDWR, 0644)
+	if err != nil {
+		t.Fatalf("failed to open file: %v", err)
+	}
+	err = utils.AppendEntryWithID(file, 0, entry)
+	file.Close()
+	if err != nil {
+		t.Fatalf("failed to append legacy entry: %v", err)
+	}
+	if _, err := utils.RebuildCollectionBTreeIndex(context.Background(), testFile, utils.IndexPathFromBinFile(testFile)); err != nil {
+		t.Fatalf("failed to index legacy entry: %v", err)
+	}
+
+	orderDAO := dao.NewOrderDAO(testFile)
+	if _, err := orderDAO.Write("New Owner", 100, []uint64{1}); err != nil {
+		t.Fatalf("failed to write order: %v", err)
+	}
+
+	count, err := orderDAO.ReencryptNames()
+	if err != nil {
+		t.Fatalf("failed to re-encrypt: %v", err)
+	}
+	if count != 1 {
+		t.Errorf("expected 1 re-encrypted name, got %d", count)
+	}
+
+	entries, _ := utils.SplitFileIntoEntries(testFile)
+	for _, entry := range entries {
+		collection, err := utils.ParseCollectionEntry(entry.Data)
+		if err == nil && collection.Tombstone == 0x00 && crypto.IsLegacyCiphertext([]byte(collection.OwnerOrName)) {
+			t.Errorf("collection %d still has a legacy ciphertext", collection.ID)
+		}
+	}
+
+	order, err := orderDAO.Read(0)
+	if err != nil {
+		t.Fatalf("failed to read order: %v", err)
+	}
+	if order.OwnerOrName != "Old Owner" || order.TotalPrice != 200 {
+		t.Errorf("unexpected order after re-encryption: %+v", order)
+	}
+}
+
+func TestRotateDataKeyReencryptsNames(t *testing.T) {
+	utils.SetDataDir(t.TempDir())
+	defer utils.SetDataDir(utils.DefaultDataDir)
+	crypto.Reset()
+	defer crypto.Reset()
+	defer crypto.SetEnabled(crypto.IsEnabled())
+	crypto.SetEnabled(true)
+
+	ordersPath := utils.BinPath("orders.bin")
+	orderDAO := dao.NewOrderDAO(ordersPath)
+	id, err := orderDAO.Write("Alice", 100, []uint64{1})
+	if err != nil {
+		t.Fatalf("failed to write order: %v", err)
+	}
+	before, _ := os.ReadFile(ordersPath)
+	oldKey, _ := os.ReadFile(filepath.Join(utils.KeysDir, crypto.DataKeyFile))
+
+	rewritten := 0
+	archived, err := crypto.RotateDataKey(utils.KeysDir, func(oldCipher, newCipher *crypto.FieldCipher) error {
+		count, err := utils.RewriteCollectionNames(func(stored []byte) ([]byte, error) {
+			return oldCipher.Reencrypt(stored, newCipher)
+		}, ordersPath)
+		rewritten = count
+		return err
+	})
+	if err != nil {
+		t.Fatalf("failed to rotate key: %v", err)
+	}
+	if rewritten != 1 {
+		t.Errorf("expected 1 rewritten name, got %d", rewritten)
+	}
+
+	archivedKey, err := os.ReadFile(archived)
+	if err != nil || string(archivedKey) != string(oldKey) {
+		t.Errorf("expected the old key to be archived at %s (err: %v)", archived, err)
+	}
+	newKey, _ := os.ReadFile(filepath.Join(utils.KeysDir, crypto.DataKeyFile))
+	if string(newKey) == string(oldKey) {
+		t.Error("expected a new data key")
+	}
+	// The rewritten file is the next generation of orders.bin
+	ordersPath = utils.BinPath("orders.bin")
+	after, _ := os.ReadFile(ordersPath)
+	if string(after) == string(before) {
+		t.Error("expected the name to be re-encrypted")
+	}
+
+	// A fresh cipher loads the new key from disk and reads the rewritten name
+	crypto.Reset()
+	order, err := dao.NewOrderDAO(ordersPath).Read(id)
+	if err != nil {
+		t.Fatalf("failed to read order: %v", err)
+	}
+	if order.OwnerOrName != "Alice" {
+		t.Errorf("expected 'Alice', got '%s'", order.OwnerOrName)
+	}
+}
+
+func TestRotateDataKeyKeepsOldKeyOnFailure(t *testing.T) {
+	keysDir := t.TempDir()
+	crypto.Reset()
+	defer crypto.Reset()
+
+	if _, err := crypto.GetFieldCipher(keysDir); err != nil {
+		t.Fatalf("failed to get field cipher: %v", err)
+	}
+	oldKey, _ := os.ReadFile(filepath.Join(keysDir, crypto.DataKeyFile))
+
+	_, err := crypto.RotateDataKey(keysDir, func(oldCipher, newCipher *crypto.FieldCipher) error {
+		return os.ErrPermission
+	})
+	if err == nil {
+		t.Fatal("expected rotation to fail")
+	}
+
+	key, _ := os.ReadFile(filepath.Join(keysDir, crypto.DataKeyFile))
+	if string(key) != string(oldKey) {
+		t.Error("expected the old key to stay in place")
+	}
+	if _, err := os.Stat(filepath.Join(keysDir, crypto.DataKeyArchiveDir)); !os.IsNotExist(err) {
+		t.Error("expected no archived key")
+	}
+}
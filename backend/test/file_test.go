@@ -2,6 +2,8 @@ package test
 
 import (
 	"os"
+	"path/filepath"
+	"sync"
 	"testing"
 
 	"BinaryCRUD/backend/utils"
@@ -101,20 +103,24 @@ func TestWriteHeaderToFile(t *testing.T) {
 		t.Fatalf("failed to read file: %v", err)
 	}
 
-	// Header format: [magic(4)][filenameLen(1)][filename(N)][entitiesCount(4)][tombstoneCount(4)][nextId(4)]
+	// Header format: [magic(4)][filenameLen(1)][filename(N)][entitiesCount(4)][tombstoneCount(4)][nextId(8)][flags(1)][idSize(1)]
 	expectedSize := utils.CalculateHeaderSize("test.bin")
 	if len(data) != expectedSize {
 		t.Errorf("expected header size %d, got %d", expectedSize, len(data))
 	}
 
 	// Verify magic bytes
-	if string(data[:4]) != string(utils.BDATMagic) {
-		t.Errorf("expected magic %v, got %v", utils.BDATMagic, data[:4])
+	expectedMagic, err := utils.MagicForVersion(utils.CurrentFormatVersion)
+	if err != nil {
+		t.Fatalf("failed to get magic: %v", err)
+	}
+	if string(data[:4]) != string(expectedMagic) {
+		t.Errorf("expected magic %v, got %v", expectedMagic, data[:4])
 	}
 
 	// Verify the numeric fields at the end (after magic + filenameLen + filename)
 	// For "test.bin" (8 bytes): offset = 4 + 1 + 8 = 13
-	expectedSuffix := []byte{0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00, 0x03}
+	expectedSuffix := []byte{0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x03, 0x00, 0x04}
 	suffixOffset := utils.MagicSize + utils.FilenameLengthSize + len("test.bin")
 	actualSuffix := data[suffixOffset:]
 	if string(actualSuffix) != string(expectedSuffix) {
@@ -189,6 +195,43 @@ func TestReadHeader(t *testing.T) {
 	file.Close()
 }
 
+func TestReadHeaderOnly(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "stats.bin")
+
+	header, err := utils.WriteHeaderWithFlags("stats.bin", utils.FlagNamesEncrypted, 7, 2, 9)
+	if err != nil {
+		t.Fatalf("failed to create header: %v", err)
+	}
+	// Bytes past the header don't have to parse as records
+	if err := os.WriteFile(testFile, append(header, 0xFF, 0xFF, 0xFF), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	stats, err := utils.ReadHeaderOnly(testFile)
+	if err != nil {
+		t.Fatalf("failed to read header: %v", err)
+	}
+	if stats.Filename != "stats.bin" || stats.EntitiesCount != 7 || stats.TombstoneCount != 2 || stats.NextId != 9 {
+		t.Errorf("unexpected header fields: %+v", stats)
+	}
+	if stats.Flags != utils.FlagNamesEncrypted || stats.IDSize != utils.IDSize || stats.Version != utils.CurrentFormatVersion {
+		t.Errorf("unexpected header flags, ID size or version: %+v", stats)
+	}
+	if stats.HeaderSize != len(header) || stats.FileSize != int64(len(header)+3) {
+		t.Errorf("expected header size %d and file size %d, got %+v", len(header), len(header)+3, stats)
+	}
+
+	if _, err := utils.ReadHeaderOnly(filepath.Join(t.TempDir(), "missing.bin")); !os.IsNotExist(err) {
+		t.Errorf("expected a not-exist error for a missing file, got %v", err)
+	}
+	if err := os.WriteFile(testFile, header[:3], 0644); err != nil {
+		t.Fatalf("failed to truncate file: %v", err)
+	}
+	if _, err := utils.ReadHeaderOnly(testFile); err == nil {
+		t.Error("expected an error for a truncated header")
+	}
+}
+
 func TestUpdateHeader(t *testing.T) {
 	testFile := "/tmp/test_update_header.bin"
 	defer os.Remove(testFile)
@@ -234,6 +277,63 @@ func TestUpdateHeader(t *testing.T) {
 	file.Close()
 }
 
+func TestModifyHeaderConcurrent(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "modify_header.bin")
+
+	file, err := utils.CreateFile(testFile)
+	if err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+	header, err := utils.WriteHeader("test.bin", 0, 0, 0)
+	if err != nil {
+		t.Fatalf("failed to create header: %v", err)
+	}
+	if err := utils.WriteHeaderToFile(file, header); err != nil {
+		t.Fatalf("failed to write header: %v", err)
+	}
+	file.Close()
+
+	// Separate handles behave like separate processes for the file lock
+	const writers, updates = 4, 25
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			f, err := os.OpenFile(testFile, os.O_RDWR, 0644)
+			if err != nil {
+				t.Errorf("failed to open file: %v", err)
+				return
+			}
+			defer f.Close()
+			for j := 0; j < updates; j++ {
+				err := utils.ModifyHeader(f, func(counts *utils.HeaderCounts) {
+					counts.EntitiesCount++
+					counts.NextId += 2
+				})
+				if err != nil {
+					t.Errorf("failed to modify header: %v", err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	file, err = os.Open(testFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	filename, entitiesCount, tombstoneCount, nextId, err := utils.ReadHeader(file)
+	if err != nil {
+		t.Fatalf("failed to read header: %v", err)
+	}
+	if filename != "test.bin" || entitiesCount != writers*updates || tombstoneCount != 0 || nextId != 2*writers*updates {
+		t.Errorf("unexpected header %s %d/%d/%d", filename, entitiesCount, tombstoneCount, nextId)
+	}
+}
+
 func TestAppendEntry(t *testing.T) {
 	testFile := "/tmp/test_append_entry.bin"
 	defer os.Remove(testFile)
@@ -0,0 +1,102 @@
+package test
+
+import (
+	"BinaryCRUD/backend/dao"
+	"BinaryCRUD/backend/utils"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestItemDAOReusesDeletedRecordSpace(t *testing.T) {
+	itemsPath := filepath.Join(t.TempDir(), fmt.Sprintf("free_items_%d.bin", os.Getpid()))
+	t.Cleanup(func() { os.Remove(utils.IndexPathFromBinFile(itemsPath)) })
+
+	itemDAO := dao.NewItemDAO(itemsPath)
+	for _, name := range []string{"Cheeseburger", "Fries", "Soda"} {
+		if _, err := itemDAO.Write(name, 100); err != nil {
+			t.Fatalf("failed to write item: %v", err)
+		}
+	}
+	if err := itemDAO.Delete(0); err != nil {
+		t.Fatalf("failed to delete item: %v", err)
+	}
+	before, err := os.Stat(itemsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A shorter record fits into the deleted slot with padding
+	id, err := itemDAO.Write("Salad", 450)
+	if err != nil {
+		t.Fatalf("failed to write item: %v", err)
+	}
+	after, err := os.Stat(itemsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after.Size() != before.Size() {
+		t.Errorf("expected file size %d to stay the same, got %d", before.Size(), after.Size())
+	}
+
+	item, err := itemDAO.ReadItem(id)
+	if err != nil || item.Name != "Salad" || item.PriceInCents != 450 {
+		t.Fatalf("unexpected reused item %+v (err %v)", item, err)
+	}
+	if item.Extensions != nil {
+		t.Errorf("expected padding to be hidden, got extensions %v", item.Extensions)
+	}
+	if id != 3 {
+		t.Errorf("expected a fresh ID 3, got %d", id)
+	}
+
+	file, err := os.Open(itemsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, entitiesCount, tombstoneCount, _, err := utils.ReadHeader(file)
+	file.Close()
+	if err != nil || entitiesCount != 3 || tombstoneCount != 0 {
+		t.Errorf("expected 3 entities and no tombstones, got %d and %d (err %v)", entitiesCount, tombstoneCount, err)
+	}
+
+	// A longer record does not fit and is appended
+	if _, err := itemDAO.Write("Double Cheeseburger", 1299); err != nil {
+		t.Fatalf("failed to write item: %v", err)
+	}
+	grown, err := os.Stat(itemsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if grown.Size() <= after.Size() {
+		t.Errorf("expected the file to grow, size stayed %d", grown.Size())
+	}
+
+	// The reused slot survives an index rebuild
+	reloaded := dao.NewItemDAO(itemsPath)
+	if _, name, _, err := reloaded.Read(id); err != nil || name != "Salad" {
+		t.Errorf("expected Salad after reload, got %q (err %v)", name, err)
+	}
+}
+
+func TestPaddingExtension(t *testing.T) {
+	if _, err := utils.EncodePadding(utils.PaddingOverhead - 1); err == nil {
+		t.Error("expected padding smaller than its header to be rejected")
+	}
+
+	stock, _ := utils.EncodeStock(7)
+	trailer, _ := utils.EncodeExtensions(map[byte][]byte{utils.ExtStock: stock})
+	padding, err := utils.EncodePadding(10)
+	if err != nil || len(padding) != 10 {
+		t.Fatalf("expected 10 bytes of padding, got %d (err %v)", len(padding), err)
+	}
+
+	ext, err := utils.DecodeExtensions(append(trailer, padding...))
+	if err != nil || len(ext) != 1 {
+		t.Fatalf("expected only the stock extension, got %v (err %v)", ext, err)
+	}
+	if quantity, ok, _ := utils.DecodeStock(ext); !ok || quantity != 7 {
+		t.Errorf("expected stock 7, got %d", quantity)
+	}
+}
@@ -0,0 +1,86 @@
+package test
+
+import (
+	"BinaryCRUD/backend/compression"
+	"BinaryCRUD/backend/utils"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGzipCompressDecompress(t *testing.T) {
+	gz := compression.NewGzipCompressor()
+
+	original := bytes.Repeat([]byte("Hello, World! This is a test of gzip compression. "), 20)
+
+	compressed, err := gz.Compress(original)
+	if err != nil {
+		t.Fatalf("Compression failed: %v", err)
+	}
+	if !bytes.HasPrefix(compressed, compression.GzipMagic) {
+		t.Errorf("Expected gzip magic bytes, got %x", compressed[:2])
+	}
+	if len(compressed) >= len(original) {
+		t.Errorf("Compressed size (%d) should be smaller than original (%d)", len(compressed), len(original))
+	}
+
+	decompressed, err := gz.Decompress(compressed)
+	if err != nil {
+		t.Fatalf("Decompression failed: %v", err)
+	}
+	if !bytes.Equal(original, decompressed) {
+		t.Errorf("Decompressed data doesn't match original")
+	}
+}
+
+func TestGzipFileReadableByStandardLibrary(t *testing.T) {
+	dir := t.TempDir()
+	inputPath := filepath.Join(dir, "items.bin")
+	outputPath := filepath.Join(dir, utils.CompressedFilename("items.bin", compression.AlgorithmGzip))
+	original := bytes.Repeat([]byte{0x00, 0x0B, 'B', 'u', 'r', 'g', 'e', 'r'}, 50)
+	if err := os.WriteFile(inputPath, original, 0644); err != nil {
+		t.Fatalf("failed to write input: %v", err)
+	}
+
+	compressor, err := compression.NewCompressor(utils.DetectCompressionAlgorithm(filepath.Base(outputPath)))
+	if err != nil {
+		t.Fatalf("failed to create compressor: %v", err)
+	}
+	if err := compressor.CompressFile(inputPath, outputPath); err != nil {
+		t.Fatalf("CompressFile failed: %v", err)
+	}
+
+	file, err := os.Open(outputPath)
+	if err != nil {
+		t.Fatalf("failed to open output: %v", err)
+	}
+	defer file.Close()
+	reader, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatalf("output is not a gzip stream: %v", err)
+	}
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("failed to read gzip stream: %v", err)
+	}
+	if !bytes.Equal(data, original) {
+		t.Error("gzip stream doesn't match original")
+	}
+	if name := utils.DecompressedFilename(filepath.Base(outputPath)); name != "items.bin" {
+		t.Errorf("expected items.bin, got %s", name)
+	}
+}
+
+func TestGzipRejectsInvalidData(t *testing.T) {
+	gz := compression.NewGzipCompressor()
+
+	if _, err := gz.Decompress([]byte("LZWW not gzip")); err == nil {
+		t.Error("expected non-gzip data to fail")
+	}
+	if _, err := gz.Compress(nil); err == nil {
+		t.Error("expected empty data to fail")
+	}
+}
@@ -0,0 +1,96 @@
+package test
+
+import (
+	"BinaryCRUD/backend/compression"
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+func TestHuffmanWritesCanonicalFormat(t *testing.T) {
+	hc := compression.NewHuffmanCompressor()
+
+	compressed, err := hc.Compress([]byte("canonical codes"))
+	if err != nil {
+		t.Fatalf("Compression failed: %v", err)
+	}
+	if !bytes.HasPrefix(compressed, compression.HuffmanCanonicalMagic) {
+		t.Errorf("expected HUFC magic, got %q", compressed[:4])
+	}
+}
+
+func TestHuffmanDecodesTreeFormat(t *testing.T) {
+	hc := compression.NewHuffmanCompressor()
+
+	// "AAB" in the tree format: tree [0][1 'A'][1 'B'], codes A=0 B=1, bits 001 + 5 padding bits
+	legacy := []byte{'H', 'U', 'F', 'F', 3, 0, 0, 0, 5, 0, 0, 1, 'A', 1, 'B', 5, 0x20}
+
+	decompressed, err := hc.Decompress(legacy)
+	if err != nil {
+		t.Fatalf("Decompression failed: %v", err)
+	}
+	if string(decompressed) != "AAB" {
+		t.Errorf("expected AAB, got %q", decompressed)
+	}
+}
+
+func TestHuffmanLimitsCodeLengths(t *testing.T) {
+	hc := compression.NewHuffmanCompressor()
+
+	// Fibonacci frequencies give the deepest possible tree, deeper than the code length limit
+	var original []byte
+	a, b := 1, 1
+	for symbol := 0; symbol < 28; symbol++ {
+		original = append(original, bytes.Repeat([]byte{byte(symbol)}, a)...)
+		a, b = b, a+b
+	}
+
+	compressed, err := hc.Compress(original)
+	if err != nil {
+		t.Fatalf("Compression failed: %v", err)
+	}
+	decompressed, err := hc.Decompress(compressed)
+	if err != nil {
+		t.Fatalf("Decompression failed: %v", err)
+	}
+	if !bytes.Equal(original, decompressed) {
+		t.Error("Decompressed data doesn't match original")
+	}
+}
+
+func TestHuffmanRandomData(t *testing.T) {
+	hc := compression.NewHuffmanCompressor()
+	rng := rand.New(rand.NewSource(42))
+
+	for _, size := range []int{1, 7, 1000, 100000} {
+		original := make([]byte, size)
+		for i := range original {
+			// Skewed distribution so code lengths vary
+			original[i] = byte(rng.ExpFloat64() * 20)
+		}
+
+		compressed, err := hc.Compress(original)
+		if err != nil {
+			t.Fatalf("size %d: Compression failed: %v", size, err)
+		}
+		decompressed, err := hc.Decompress(compressed)
+		if err != nil {
+			t.Fatalf("size %d: Decompression failed: %v", size, err)
+		}
+		if !bytes.Equal(original, decompressed) {
+			t.Errorf("size %d: Decompressed data doesn't match original", size)
+		}
+	}
+}
+
+func TestHuffmanRejectsTruncatedData(t *testing.T) {
+	hc := compression.NewHuffmanCompressor()
+
+	compressed, err := hc.Compress(bytes.Repeat([]byte("truncate me "), 100))
+	if err != nil {
+		t.Fatalf("Compression failed: %v", err)
+	}
+	if _, err := hc.Decompress(compressed[:len(compressed)/2]); err == nil {
+		t.Error("expected truncated data to fail")
+	}
+}
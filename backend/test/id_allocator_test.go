@@ -0,0 +1,139 @@
+package test
+
+import (
+	"BinaryCRUD/backend/dao"
+	"BinaryCRUD/backend/utils"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// regressNextID moves the header nextId of a data file back, as a failed header write would leave it
+func regressNextID(t *testing.T, path string, nextId int) {
+	file, err := os.OpenFile(path, os.O_RDWR, 0644)
+	if err != nil {
+		t.Fatalf("failed to open %s: %v", path, err)
+	}
+	defer file.Close()
+	err = utils.ModifyHeader(file, func(counts *utils.HeaderCounts) {
+		counts.NextId = nextId
+	})
+	if err != nil {
+		t.Fatalf("failed to update header: %v", err)
+	}
+}
+
+func TestIDMarkSurvivesHeaderRegression(t *testing.T) {
+	utils.SetDataDir(t.TempDir())
+	t.Cleanup(func() { utils.SetDataDir(utils.DefaultDataDir) })
+	path := utils.BinPath("items.bin")
+
+	itemDAO := dao.NewItemDAO(path)
+	for _, name := range []string{"Burger", "Fries", "Soda"} {
+		if _, err := itemDAO.Write(name, 500); err != nil {
+			t.Fatalf("failed to write item: %v", err)
+		}
+	}
+	regressNextID(t, path, 1)
+
+	id, err := dao.NewItemDAO(path).Write("Shake", 600)
+	if err != nil {
+		t.Fatalf("failed to write item: %v", err)
+	}
+	if id != 3 {
+		t.Errorf("expected ID 3 after the header regressed, got %d", id)
+	}
+	if mark, err := utils.ReadIDMark(path); err != nil || mark != 4 {
+		t.Errorf("expected mark 4, got %d (err %v)", mark, err)
+	}
+}
+
+func TestIDMarkSurvivesCompaction(t *testing.T) {
+	utils.SetDataDir(t.TempDir())
+	t.Cleanup(func() { utils.SetDataDir(utils.DefaultDataDir) })
+	dir := t.TempDir()
+	path := filepath.Join(dir, "items.bin")
+
+	itemDAO := dao.NewItemDAO(path)
+	for _, name := range []string{"Burger", "Fries"} {
+		if _, err := itemDAO.Write(name, 500); err != nil {
+			t.Fatalf("failed to write item: %v", err)
+		}
+	}
+	if err := itemDAO.Delete(1); err != nil {
+		t.Fatalf("failed to delete item: %v", err)
+	}
+
+	// Compaction sets nextId past the highest remaining ID, which is below the deleted one
+	_, err := utils.CompactFiles(context.Background(), path, filepath.Join(dir, "orders.bin"), filepath.Join(dir, "promotions.bin"),
+		filepath.Join(dir, "order_promotions.bin"), nil)
+	if err != nil {
+		t.Fatalf("failed to compact: %v", err)
+	}
+
+	id, err := dao.NewItemDAO(path).Write("Soda", 300)
+	if err != nil {
+		t.Fatalf("failed to write item: %v", err)
+	}
+	if id != 2 {
+		t.Errorf("expected the deleted ID not to be reused, got %d", id)
+	}
+}
+
+func TestIDMarkResetForNewFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "items.bin")
+	if err := utils.RaiseIDMark(path, 10); err != nil {
+		t.Fatalf("failed to raise mark: %v", err)
+	}
+	if err := utils.RaiseIDMark(path, 5); err != nil {
+		t.Fatalf("failed to raise mark: %v", err)
+	}
+	if mark, _ := utils.ReadIDMark(path); mark != 10 {
+		t.Errorf("expected the mark never to move back, got %d", mark)
+	}
+
+	// The mark of a removed file does not carry over to a new file of the same name
+	if err := utils.EnsureFileExists(path); err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+	if mark, _ := utils.ReadIDMark(path); mark != 0 {
+		t.Errorf("expected no mark for a new file, got %d", mark)
+	}
+}
+
+func TestVerifyBinFileAuditsIDs(t *testing.T) {
+	utils.SetDataDir(t.TempDir())
+	t.Cleanup(func() { utils.SetDataDir(utils.DefaultDataDir) })
+	path := utils.BinPath("items.bin")
+
+	itemDAO := dao.NewItemDAO(path)
+	for _, name := range []string{"Burger", "Fries"} {
+		if _, err := itemDAO.Write(name, 500); err != nil {
+			t.Fatalf("failed to write item: %v", err)
+		}
+	}
+	if err := itemDAO.WriteWithID(5, "Soda", 300); err != nil {
+		t.Fatalf("failed to write item: %v", err)
+	}
+
+	check, err := utils.VerifyBinFile(path)
+	if err != nil {
+		t.Fatalf("verify failed: %v", err)
+	}
+	if !check.OK() || check.NextID != 6 || check.IDGaps != 3 {
+		t.Errorf("expected next ID 6 with 3 gaps and no problems, got %+v", check)
+	}
+
+	// Without the mark a regressed header would hand out an ID a record holds
+	os.Remove(utils.IDMarkPath(path))
+	regressNextID(t, path, 1)
+	check, err = utils.VerifyBinFile(path)
+	if err != nil {
+		t.Fatalf("verify failed: %v", err)
+	}
+	if check.OK() || !strings.Contains(strings.Join(check.Problems, "\n"), "handed out again") {
+		t.Errorf("expected ID reuse to be reported, got %v", check.Problems)
+	}
+}
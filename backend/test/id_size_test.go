@@ -0,0 +1,291 @@
+package test
+
+import (
+	"BinaryCRUD/backend/dao"
+	"BinaryCRUD/backend/migrate"
+	"BinaryCRUD/backend/utils"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// idSizeOf reads the ID width recorded in the header of a file
+func idSizeOf(t *testing.T, path string) int {
+	idSize, err := utils.IDSizeFromPath(path)
+	if err != nil {
+		t.Fatalf("failed to read ID size: %v", err)
+	}
+	return idSize
+}
+
+func TestItemDAOKeepsLegacyIDSize(t *testing.T) {
+	utils.SetDataDir(t.TempDir())
+	t.Cleanup(func() { utils.SetDataDir(utils.DefaultDataDir) })
+	itemsPath := filepath.Join(t.TempDir(), "items.bin")
+
+	// Files created while 2-byte IDs were configured keep them after the default changes
+	utils.IDSize = utils.LegacyIDSize
+	legacyDAO := dao.NewItemDAO(itemsPath)
+	if _, err := legacyDAO.Write("Tea", 500); err != nil {
+		utils.IDSize = utils.DefaultIDSize
+		t.Fatalf("failed to write item: %v", err)
+	}
+	utils.IDSize = utils.DefaultIDSize
+
+	itemDAO := dao.NewItemDAO(itemsPath)
+	id, err := itemDAO.Write("Coffee", 700)
+	if err != nil {
+		t.Fatalf("failed to write item: %v", err)
+	}
+	if idSizeOf(t, itemsPath) != utils.LegacyIDSize {
+		t.Errorf("expected the file to keep %d-byte IDs", utils.LegacyIDSize)
+	}
+
+	item, err := itemDAO.ReadItem(id)
+	if err != nil || item.Name != "Coffee" {
+		t.Fatalf("expected Coffee, got %+v (err %v)", item, err)
+	}
+	items, err := itemDAO.GetAll()
+	if err != nil || len(items) != 2 {
+		t.Errorf("expected 2 items, got %d (err %v)", len(items), err)
+	}
+
+	// IDs that do not fit the width of the file are rejected instead of wrapping
+	if err := itemDAO.WriteWithID(70000, "Juice", 300); err == nil {
+		t.Error("expected an ID above 65535 to be rejected by a 2-byte file")
+	}
+}
+
+func TestItemDAOWideIDs(t *testing.T) {
+	utils.SetDataDir(t.TempDir())
+	t.Cleanup(func() { utils.SetDataDir(utils.DefaultDataDir) })
+	itemsPath := filepath.Join(t.TempDir(), "items.bin")
+
+	itemDAO := dao.NewItemDAO(itemsPath)
+	if err := itemDAO.WriteWithID(70000, "Burger", 899); err != nil {
+		t.Fatalf("failed to write item: %v", err)
+	}
+	if idSizeOf(t, itemsPath) != utils.DefaultIDSize {
+		t.Errorf("expected new files to use %d-byte IDs", utils.DefaultIDSize)
+	}
+
+	item, err := itemDAO.ReadItem(70000)
+	if err != nil || item.Name != "Burger" || item.PriceInCents != 899 {
+		t.Fatalf("expected Burger/899, got %+v (err %v)", item, err)
+	}
+	if _, err := itemDAO.ReadItem(70000 % 65536); err == nil {
+		t.Error("expected the wide ID not to wrap around")
+	}
+}
+
+func TestMigrateFileRecordsLegacyIDSize(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "items.bin")
+
+	utils.IDSize = utils.LegacyIDSize
+	err := createTestFileWithItems(testFile)
+	utils.IDSize = utils.DefaultIDSize
+	if err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+	rewriteHeaderVersion(t, testFile, utils.FormatVersionIDSize-1)
+
+	if _, err := migrate.MigrateFile(testFile, utils.CurrentFormatVersion); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	if idSizeOf(t, testFile) != utils.LegacyIDSize {
+		t.Errorf("expected a migrated file to record %d-byte IDs", utils.LegacyIDSize)
+	}
+
+	entries, err := utils.SplitFileIntoEntries(testFile)
+	if err != nil || len(entries) != 3 {
+		t.Fatalf("expected 3 entries, got %d (err %v)", len(entries), err)
+	}
+	for i, entry := range entries {
+		item, err := utils.ItemCodec.Decode(entry.Data, entry.IDSize)
+		if err != nil || item.ID != uint64(i) {
+			t.Errorf("expected item %d, got %+v (err %v)", i, item, err)
+		}
+	}
+}
+
+func TestCompactWidensIDs(t *testing.T) {
+	dir := t.TempDir()
+	testFile := filepath.Join(dir, "items.bin")
+
+	utils.IDSize = utils.LegacyIDSize
+	err := createTestFileWithItems(testFile)
+	utils.IDSize = utils.DefaultIDSize
+	if err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+
+	// Compaction only rewrites a file with something to remove
+	if err := utils.SoftDeleteByID(testFile, 1, nil, nil); err != nil {
+		t.Fatalf("failed to delete item: %v", err)
+	}
+
+	ordersPath := filepath.Join(dir, "orders.bin")
+	promotionsPath := filepath.Join(dir, "promotions.bin")
+	opPath := filepath.Join(dir, "order_promotions.bin")
+	if _, err := utils.CompactFiles(context.Background(), testFile, ordersPath, promotionsPath, opPath, nil); err != nil {
+		t.Fatalf("failed to compact: %v", err)
+	}
+	if idSizeOf(t, testFile) != utils.DefaultIDSize {
+		t.Errorf("expected compaction to rewrite the file with %d-byte IDs", utils.DefaultIDSize)
+	}
+
+	entries, err := utils.SplitFileIntoEntries(testFile)
+	if err != nil || len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d (err %v)", len(entries), err)
+	}
+	for i, id := range []uint64{0, 2} {
+		item, err := utils.ItemCodec.Decode(entries[i].Data, entries[i].IDSize)
+		if err != nil || item.ID != id {
+			t.Errorf("expected item %d, got %+v (err %v)", id, item, err)
+		}
+	}
+}
+
+func TestItemDAOEightByteIDs(t *testing.T) {
+	utils.SetDataDir(t.TempDir())
+	t.Cleanup(func() { utils.SetDataDir(utils.DefaultDataDir) })
+	itemsPath := filepath.Join(t.TempDir(), "items.bin")
+
+	utils.IDSize = utils.WideIDSize
+	t.Cleanup(func() { utils.IDSize = utils.DefaultIDSize })
+
+	// An ID past the 4 byte range, which the nextId header field must also hold
+	const wideID = uint64(1) << 33
+	itemDAO := dao.NewItemDAO(itemsPath)
+	if err := itemDAO.WriteWithID(wideID, "Burger", 899); err != nil {
+		t.Fatalf("failed to write item: %v", err)
+	}
+	nextID, err := itemDAO.Write("Fries", 399)
+	if err != nil {
+		t.Fatalf("failed to write item: %v", err)
+	}
+	if nextID != wideID+1 {
+		t.Errorf("expected the next ID to be %d, got %d", wideID+1, nextID)
+	}
+	if err := itemDAO.Close(); err != nil {
+		t.Fatalf("failed to close DAO: %v", err)
+	}
+	if idSizeOf(t, itemsPath) != utils.WideIDSize {
+		t.Errorf("expected the file to record %d-byte IDs", utils.WideIDSize)
+	}
+
+	// A fresh DAO reads the wide IDs back through the saved index and header
+	reopened := dao.NewItemDAO(itemsPath)
+	item, err := reopened.ReadItem(wideID)
+	if err != nil || item.Name != "Burger" || item.PriceInCents != 899 {
+		t.Fatalf("expected Burger/899, got %+v (err %v)", item, err)
+	}
+	if _, err := reopened.ReadItem(wideID % (1 << 32)); err == nil {
+		t.Error("expected the 8-byte ID not to wrap around")
+	}
+	id, err := reopened.Write("Soda", 250)
+	if err != nil || id != wideID+2 {
+		t.Errorf("expected ID %d after reopening, got %d (err %v)", wideID+2, id, err)
+	}
+}
+
+func TestFourByteFileRejectsEightByteIDs(t *testing.T) {
+	utils.SetDataDir(t.TempDir())
+	t.Cleanup(func() { utils.SetDataDir(utils.DefaultDataDir) })
+	itemsPath := filepath.Join(t.TempDir(), "items.bin")
+
+	itemDAO := dao.NewItemDAO(itemsPath)
+	if err := itemDAO.WriteWithID(uint64(1)<<33, "Burger", 899); err == nil {
+		t.Error("expected an ID above the 4 byte range to be rejected by a 4-byte file")
+	}
+}
+
+func TestMigrateFileWidensNextID(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "items.bin")
+	if err := createTestFileWithItems(testFile); err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+	rewriteHeaderVersion(t, testFile, utils.FormatVersionWideNextID-1)
+
+	data, err := os.ReadFile(testFile)
+	if err != nil {
+		t.Fatalf("failed to read test file: %v", err)
+	}
+	_, _, _, wantNextID, oldHeaderSize, err := utils.ReadHeaderFromBytes(data)
+	if err != nil {
+		t.Fatalf("failed to read header: %v", err)
+	}
+
+	result, err := migrate.MigrateFile(testFile, utils.CurrentFormatVersion)
+	if err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	if !result.Migrated || result.FromVersion != utils.FormatVersionWideNextID-1 {
+		t.Errorf("unexpected result: %+v", result)
+	}
+
+	data, err = os.ReadFile(testFile)
+	if err != nil {
+		t.Fatalf("failed to read migrated file: %v", err)
+	}
+	_, entitiesCount, _, nextID, headerSize, err := utils.ReadHeaderFromBytes(data)
+	if err != nil {
+		t.Fatalf("failed to read migrated header: %v", err)
+	}
+	if nextID != wantNextID || entitiesCount != 3 {
+		t.Errorf("expected nextId %d and 3 entities, got %d and %d", wantNextID, nextID, entitiesCount)
+	}
+	if headerSize != oldHeaderSize+utils.WideNextIDFieldSize-utils.HeaderFieldSize {
+		t.Errorf("expected the header to grow by %d bytes, got %d", utils.WideNextIDFieldSize-utils.HeaderFieldSize, headerSize-oldHeaderSize)
+	}
+	if idSizeOf(t, testFile) != utils.DefaultIDSize {
+		t.Errorf("expected the migrated file to keep %d-byte IDs", utils.DefaultIDSize)
+	}
+
+	entries, err := utils.SplitFileIntoEntries(testFile)
+	if err != nil || len(entries) != 3 {
+		t.Fatalf("expected 3 entries, got %d (err %v)", len(entries), err)
+	}
+	for i, entry := range entries {
+		item, err := utils.ItemCodec.Decode(entry.Data, entry.IDSize)
+		if err != nil || item.ID != uint64(i) {
+			t.Errorf("expected item %d, got %+v (err %v)", i, item, err)
+		}
+	}
+}
+
+func TestCompactWidensToEightByteIDs(t *testing.T) {
+	dir := t.TempDir()
+	testFile := filepath.Join(dir, "items.bin")
+	if err := createTestFileWithItems(testFile); err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+	if err := utils.SoftDeleteByID(testFile, 1, nil, nil); err != nil {
+		t.Fatalf("failed to delete item: %v", err)
+	}
+
+	utils.IDSize = utils.WideIDSize
+	t.Cleanup(func() { utils.IDSize = utils.DefaultIDSize })
+
+	ordersPath := filepath.Join(dir, "orders.bin")
+	promotionsPath := filepath.Join(dir, "promotions.bin")
+	opPath := filepath.Join(dir, "order_promotions.bin")
+	if _, err := utils.CompactFiles(context.Background(), testFile, ordersPath, promotionsPath, opPath, nil); err != nil {
+		t.Fatalf("failed to compact: %v", err)
+	}
+	if idSizeOf(t, testFile) != utils.WideIDSize {
+		t.Errorf("expected compaction to rewrite the file with %d-byte IDs", utils.WideIDSize)
+	}
+
+	entries, err := utils.SplitFileIntoEntries(testFile)
+	if err != nil || len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d (err %v)", len(entries), err)
+	}
+	for i, id := range []uint64{0, 2} {
+		item, err := utils.ItemCodec.Decode(entries[i].Data, entries[i].IDSize)
+		if err != nil || item.ID != id {
+			t.Errorf("expected item %d, got %+v (err %v)", id, item, err)
+		}
+	}
+}
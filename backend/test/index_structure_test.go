@@ -0,0 +1,92 @@
+package test
+
+import (
+	"BinaryCRUD/backend/index"
+	"testing"
+)
+
+func TestBTreeStructure(t *testing.T) {
+	tree := index.NewBTree(4)
+	single := tree.Structure()
+	if single.Depth != 1 || single.NodeCount != 1 || !single.Nodes[0].Leaf || single.FillFactor != 0 {
+		t.Errorf("Expected a single empty leaf, got %+v", single)
+	}
+
+	for id := uint64(0); id < 20; id++ {
+		if err := tree.Insert(id, int64(id)*10); err != nil {
+			t.Fatalf("Insert failed: %v", err)
+		}
+	}
+
+	structure := tree.Structure()
+	if structure.Order != 4 || structure.KeyCount != 20 || structure.Depth < 2 {
+		t.Fatalf("Unexpected structure %+v", structure)
+	}
+	if structure.NodeCount != len(structure.Nodes) || structure.FillFactor <= 0 || structure.FillFactor > 1 {
+		t.Errorf("Unexpected node count or fill factor %+v", structure)
+	}
+
+	// Following the leaf chain from the first leaf visits every key in order
+	first := -1
+	for _, node := range structure.Nodes {
+		if node.Leaf {
+			first = node.ID
+			break
+		}
+		if len(node.Children) != len(node.Keys)+1 {
+			t.Errorf("Internal node %d has %d keys and %d children", node.ID, len(node.Keys), len(node.Children))
+		}
+	}
+	var keys []uint64
+	leaves := 0
+	for id := first; id != -1; id = structure.Nodes[id].Next {
+		if structure.Nodes[id].Level != structure.Depth-1 {
+			t.Errorf("Leaf %d is on level %d of %d", id, structure.Nodes[id].Level, structure.Depth)
+		}
+		keys = append(keys, structure.Nodes[id].Keys...)
+		leaves++
+	}
+	if leaves != structure.LeafCount || len(keys) != 20 {
+		t.Fatalf("Expected %d leaves holding 20 keys, got %d leaves and %d keys", structure.LeafCount, leaves, len(keys))
+	}
+	for i, key := range keys {
+		if key != uint64(i) {
+			t.Fatalf("Expected key %d at position %d, got %d", i, i, key)
+		}
+	}
+}
+
+func TestExtensibleHashStructure(t *testing.T) {
+	hash := index.NewExtensibleHash(2)
+	for i := uint64(0); i < 10; i++ {
+		if err := hash.Insert(i, i+100, int64(i)); err != nil {
+			t.Fatalf("Insert failed: %v", err)
+		}
+	}
+
+	structure := hash.Structure()
+	if structure.GlobalDepth != hash.GetGlobalDepth() || structure.DirectorySize != hash.GetDirectorySize() || structure.BucketSize != 2 {
+		t.Errorf("Unexpected depths %+v", structure)
+	}
+	if structure.EntryCount != 10 {
+		t.Errorf("Expected 10 entries, got %d", structure.EntryCount)
+	}
+
+	slots := 0
+	for _, bucket := range structure.Buckets {
+		if bucket.LocalDepth > structure.GlobalDepth {
+			t.Errorf("Bucket %d has local depth %d above the global depth", bucket.ID, bucket.LocalDepth)
+		}
+		if len(bucket.Slots) == 0 || bucket.Entries > structure.BucketSize {
+			t.Errorf("Unexpected bucket %+v", bucket)
+		}
+		slots += len(bucket.Slots)
+	}
+	if slots != structure.DirectorySize {
+		t.Errorf("Expected the buckets to cover %d slots, got %d", structure.DirectorySize, slots)
+	}
+	expected := float64(10) / float64(len(structure.Buckets)*2)
+	if structure.Occupancy != expected {
+		t.Errorf("Expected occupancy %.2f, got %.2f", expected, structure.Occupancy)
+	}
+}
@@ -2,8 +2,16 @@ package test
 
 import (
 	"BinaryCRUD/backend/dao"
+	"BinaryCRUD/backend/index"
+	"BinaryCRUD/backend/utils"
+	"context"
+	"errors"
+	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestItemDAOWrite(t *testing.T) {
@@ -34,16 +42,19 @@ func TestItemDAOWrite(t *testing.T) {
 		t.Fatalf("Failed to write third item: %v", err)
 	}
 
-	// Verify index was created
-	if _, err := os.Stat(testIdx); os.IsNotExist(err) {
-		t.Error("Index file was not created")
-	}
-
 	// Verify index has 3 entries
 	tree := itemDAO.GetIndexTree()
 	if tree.Size() != 3 {
 		t.Errorf("Expected 3 entries in index, got %d", tree.Size())
 	}
+
+	// Verify index was saved once the DAO was closed
+	if err := itemDAO.Close(); err != nil {
+		t.Fatalf("Failed to close: %v", err)
+	}
+	if _, err := os.Stat(testIdx); os.IsNotExist(err) {
+		t.Error("Index file was not created")
+	}
 }
 
 func TestItemDAOReadWithIndex(t *testing.T) {
@@ -342,3 +353,711 @@ func TestItemDAOConcurrentWrites(t *testing.T) {
 		}
 	}
 }
+
+func TestItemDAOWriteWithID(t *testing.T) {
+	testFile := "/tmp/test_item_write_with_id.bin"
+	testIdx := "data/indexes/test_item_write_with_id.idx"
+	defer os.Remove(testFile)
+	defer os.Remove(testIdx)
+	os.MkdirAll("data/indexes", 0755)
+
+	itemDAO := dao.NewItemDAO(testFile)
+
+	// Write items with explicit, non-contiguous IDs
+	if err := itemDAO.WriteWithID(5, "Burger", 899); err != nil {
+		t.Fatalf("Failed to write item with ID 5: %v", err)
+	}
+	if err := itemDAO.WriteWithID(2, "Fries", 349); err != nil {
+		t.Fatalf("Failed to write item with ID 2: %v", err)
+	}
+
+	id, name, price, err := itemDAO.Read(5)
+	if err != nil {
+		t.Fatalf("Failed to read item 5: %v", err)
+	}
+	if id != 5 || name != "Burger" || price != 899 {
+		t.Errorf("Unexpected item: id=%d name=%s price=%d", id, name, price)
+	}
+
+	// Duplicate ID must be rejected
+	if err := itemDAO.WriteWithID(2, "Soda", 199); err == nil {
+		t.Error("Expected error when writing duplicate ID")
+	}
+
+	// Auto-assigned IDs continue after the highest explicit ID
+	nextID, err := itemDAO.Write("Soda", 199)
+	if err != nil {
+		t.Fatalf("Failed to write item: %v", err)
+	}
+	if nextID != 6 {
+		t.Errorf("Expected next auto-assigned ID 6, got %d", nextID)
+	}
+}
+
+func TestItemDAOUpdateExtensions(t *testing.T) {
+	testFile := "/tmp/test_item_update_extensions.bin"
+	testIdx := "data/indexes/test_item_update_extensions.idx"
+	defer os.Remove(testFile)
+	defer os.Remove(testIdx)
+	os.MkdirAll("data/indexes", 0755)
+
+	itemDAO := dao.NewItemDAO(testFile)
+
+	stock, _ := utils.EncodeStock(10)
+	id, err := itemDAO.WriteExtended(nil, "Burger", 899, map[byte][]byte{utils.ExtStock: stock})
+	if err != nil {
+		t.Fatalf("Failed to write item: %v", err)
+	}
+	itemDAO.Write("Fries", 349)
+
+	stock, _ = utils.EncodeStock(7)
+	if err := itemDAO.UpdateExtensions(id, map[byte][]byte{utils.ExtStock: stock}); err != nil {
+		t.Fatalf("Failed to update item: %v", err)
+	}
+
+	item, err := itemDAO.ReadItem(id)
+	if err != nil {
+		t.Fatalf("Failed to read item: %v", err)
+	}
+	quantity, tracked, err := utils.DecodeStock(item.Extensions)
+	if err != nil || !tracked || quantity != 7 {
+		t.Errorf("Expected stock 7, got %d (tracked=%v, err=%v)", quantity, tracked, err)
+	}
+	if item.Name != "Burger" || item.PriceInCents != 899 {
+		t.Errorf("Update changed name or price: %+v", item)
+	}
+
+	// Only the latest version is listed
+	items, err := itemDAO.GetAll()
+	if err != nil {
+		t.Fatalf("Failed to get all items: %v", err)
+	}
+	if len(items) != 2 || items[0].IsDeleted {
+		t.Errorf("Expected 2 active items, got %+v", items)
+	}
+
+	// Items without the extension do not track stock
+	fries, _ := itemDAO.ReadItem(1)
+	if _, tracked, _ := utils.DecodeStock(fries.Extensions); tracked {
+		t.Error("Expected Fries not to track stock")
+	}
+}
+
+func TestItemDAOFindByName(t *testing.T) {
+	testFile := "/tmp/test_item_find_by_name.bin"
+	testIdx := "data/indexes/test_item_find_by_name.idx"
+	defer os.Remove(testFile)
+	defer os.Remove(testIdx)
+	os.MkdirAll("data/indexes", 0755)
+
+	itemDAO := dao.NewItemDAO(testFile)
+	burgerID, _ := itemDAO.Write("Cheese  Burger", 899)
+	itemDAO.Write("Fries", 349)
+
+	ids, err := itemDAO.FindByName("  cheese burger ")
+	if err != nil {
+		t.Fatalf("Failed to find by name: %v", err)
+	}
+	if len(ids) != 1 || ids[0] != burgerID {
+		t.Errorf("Expected [%d], got %v", burgerID, ids)
+	}
+
+	// Index is kept in sync after it has been built
+	friesID, _ := itemDAO.Write("FRIES", 399)
+	ids, _ = itemDAO.FindByName("fries")
+	if len(ids) != 2 {
+		t.Errorf("Expected 2 items named fries, got %v", ids)
+	}
+
+	itemDAO.Delete(friesID)
+	itemDAO.Delete(burgerID)
+	if ids, _ := itemDAO.FindByName("cheese burger"); len(ids) != 0 {
+		t.Errorf("Expected deleted item to be removed from name index, got %v", ids)
+	}
+
+	// A fresh DAO rebuilds the name index from the file
+	reloaded := dao.NewItemDAO(testFile)
+	ids, _ = reloaded.FindByName("Fries")
+	if len(ids) != 1 {
+		t.Errorf("Expected 1 active item named fries after reload, got %v", ids)
+	}
+}
+
+func TestItemDAOCachesReads(t *testing.T) {
+	utils.SetDataDir(t.TempDir())
+	defer utils.SetDataDir(utils.DefaultDataDir)
+
+	itemDAO := dao.NewItemDAO(utils.BinPath("items.bin"))
+	id, err := itemDAO.Write("Burger", 899)
+	if err != nil {
+		t.Fatalf("Failed to write item: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, _, _, err := itemDAO.Read(id); err != nil {
+			t.Fatalf("Failed to read item: %v", err)
+		}
+	}
+	stats := itemDAO.CacheStats()
+	if stats.Misses != 1 || stats.Hits != 2 {
+		t.Errorf("Expected 1 miss and 2 hits, got %+v", stats)
+	}
+
+	// Changes invalidate the cached item
+	if err := itemDAO.UpdatePrice(id, 999); err != nil {
+		t.Fatalf("Failed to update price: %v", err)
+	}
+	if _, _, price, _ := itemDAO.Read(id); price != 999 {
+		t.Errorf("Expected updated price 999, got %d", price)
+	}
+	if err := itemDAO.Update(id, "Cheeseburger", 1099); err != nil {
+		t.Fatalf("Failed to update item: %v", err)
+	}
+	if _, name, _, _ := itemDAO.Read(id); name != "Cheeseburger" {
+		t.Errorf("Expected updated name, got %s", name)
+	}
+	if err := itemDAO.Delete(id); err != nil {
+		t.Fatalf("Failed to delete item: %v", err)
+	}
+	if _, _, _, err := itemDAO.Read(id); err == nil {
+		t.Error("Expected deleted item to be unreadable")
+	}
+}
+
+func TestItemDAOCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	utils.SetDataDir(t.TempDir())
+	defer utils.SetDataDir(utils.DefaultDataDir)
+
+	itemDAO := dao.NewItemDAO(utils.BinPath("items.bin"))
+	itemDAO.SetCacheSize(2)
+	for _, name := range []string{"Burger", "Fries", "Soda"} {
+		if _, err := itemDAO.Write(name, 100); err != nil {
+			t.Fatalf("Failed to write item: %v", err)
+		}
+	}
+
+	// Reading 0, 1, 0, 2 evicts 1, the least recently used
+	for _, id := range []uint64{0, 1, 0, 2, 0, 1} {
+		if _, _, _, err := itemDAO.Read(id); err != nil {
+			t.Fatalf("Failed to read item %d: %v", id, err)
+		}
+	}
+	stats := itemDAO.CacheStats()
+	if stats.Size != 2 || stats.Hits != 2 || stats.Misses != 4 {
+		t.Errorf("Expected 2 cached items, 2 hits and 4 misses, got %+v", stats)
+	}
+
+	itemDAO.SetCacheSize(0)
+	if _, _, _, err := itemDAO.Read(0); err != nil {
+		t.Fatalf("Failed to read item: %v", err)
+	}
+	if stats := itemDAO.CacheStats(); stats.Size != 0 {
+		t.Errorf("Expected a disabled cache to stay empty, got %+v", stats)
+	}
+}
+
+func TestItemDAOReadMany(t *testing.T) {
+	utils.SetDataDir(t.TempDir())
+	defer utils.SetDataDir(utils.DefaultDataDir)
+
+	itemDAO := dao.NewItemDAO(utils.BinPath("items.bin"))
+	for _, name := range []string{"Burger", "Fries", "Soda", "Salad"} {
+		if _, err := itemDAO.Write(name, 100); err != nil {
+			t.Fatalf("Failed to write item: %v", err)
+		}
+	}
+	if err := itemDAO.Delete(2); err != nil {
+		t.Fatalf("Failed to delete item: %v", err)
+	}
+	// Rewriting an item moves its record after the others
+	if err := itemDAO.Update(0, "Cheeseburger", 1099); err != nil {
+		t.Fatalf("Failed to update item: %v", err)
+	}
+
+	items, err := itemDAO.ReadMany([]uint64{3, 0, 2, 0, 1, 42})
+	if err != nil {
+		t.Fatalf("ReadMany failed: %v", err)
+	}
+	if len(items) != 3 {
+		t.Fatalf("Expected 3 items (deleted and missing IDs left out), got %d", len(items))
+	}
+	if items[0].Name != "Cheeseburger" || items[0].PriceInCents != 1099 {
+		t.Errorf("Expected the updated item 0, got %+v", items[0])
+	}
+	if items[1].Name != "Fries" || items[3].Name != "Salad" {
+		t.Errorf("Unexpected items: %+v, %+v", items[1], items[3])
+	}
+	if _, ok := items[2]; ok {
+		t.Error("Expected deleted item 2 to be left out")
+	}
+
+	// The items read are now cached
+	before := itemDAO.CacheStats()
+	if _, err := itemDAO.ReadMany([]uint64{0, 1, 3}); err != nil {
+		t.Fatalf("ReadMany failed: %v", err)
+	}
+	if after := itemDAO.CacheStats(); after.Hits-before.Hits != 3 {
+		t.Errorf("Expected 3 cache hits, got %d", after.Hits-before.Hits)
+	}
+}
+
+func TestItemDAOReopensFileAfterCloseAndReplace(t *testing.T) {
+	utils.SetDataDir(t.TempDir())
+	defer utils.SetDataDir(utils.DefaultDataDir)
+
+	itemDAO := dao.NewItemDAO(utils.BinPath("items.bin"))
+	defer itemDAO.Close()
+	if _, err := itemDAO.Write("Burger", 899); err != nil {
+		t.Fatalf("Failed to write item: %v", err)
+	}
+
+	// A closed DAO opens its file again when used
+	if err := itemDAO.Close(); err != nil {
+		t.Fatalf("Failed to close DAO: %v", err)
+	}
+	if _, err := itemDAO.Write("Fries", 349); err != nil {
+		t.Fatalf("Failed to write after close: %v", err)
+	}
+
+	// Replacing the file switches the open file to the new one
+	replacement := utils.BinPath("replacement.bin")
+	other := dao.NewItemDAO(replacement)
+	if _, err := other.Write("Pizza", 1299); err != nil {
+		t.Fatalf("Failed to write replacement item: %v", err)
+	}
+	other.Close()
+	os.Remove(utils.IndexPathFromBinFile(replacement))
+
+	if err := itemDAO.ReplaceFile(replacement, nil); err != nil {
+		t.Fatalf("Failed to replace file: %v", err)
+	}
+	if _, name, _, err := itemDAO.Read(0); err != nil || name != "Pizza" {
+		t.Errorf("Expected Pizza from the replaced file, got %q (err: %v)", name, err)
+	}
+	if _, err := itemDAO.Write("Soda", 199); err != nil {
+		t.Fatalf("Failed to write after replace: %v", err)
+	}
+	items, err := itemDAO.GetAll()
+	if err != nil || len(items) != 2 {
+		t.Errorf("Expected 2 items in the replaced file, got %d (err: %v)", len(items), err)
+	}
+}
+
+func TestItemDAOLoadsIndexInBackground(t *testing.T) {
+	utils.SetDataDir(t.TempDir())
+	t.Cleanup(func() { utils.SetDataDir(utils.DefaultDataDir) })
+
+	// A corrupt index makes the DAO rebuild it from the data file
+	itemsPath := utils.BinPath("items.bin")
+	os.MkdirAll(utils.BinDir, 0755)
+	os.MkdirAll(utils.IndexDir, 0755)
+	writeItemsFile(t, itemsPath, 20000)
+	if err := os.WriteFile(utils.IndexPathFromBinFile(itemsPath), []byte{0xFF}, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	itemDAO := dao.NewItemDAO(itemsPath)
+	defer itemDAO.Close()
+
+	// Reads succeed whether or not the index is ready yet
+	if _, name, _, err := itemDAO.Read(19999); err != nil || name != "Item number 019998" {
+		t.Errorf("Expected item 19999 before the index is ready, got %q (err: %v)", name, err)
+	}
+	items, err := itemDAO.ReadMany([]uint64{1, 2})
+	if err != nil || len(items) != 2 {
+		t.Errorf("Expected 2 items from ReadMany, got %d (err: %v)", len(items), err)
+	}
+
+	// Writes wait for the index, so the new item is indexed
+	id, err := itemDAO.Write("Burger", 899)
+	if err != nil {
+		t.Fatalf("Failed to write item: %v", err)
+	}
+	if !itemDAO.IndexReady() {
+		t.Error("Expected the index to be ready after a write")
+	}
+	tree := itemDAO.GetIndexTree()
+	if tree.Size() != 20001 {
+		t.Errorf("Expected 20001 indexed items, got %d", tree.Size())
+	}
+	if _, found := tree.Search(id); !found {
+		t.Errorf("Expected item %d in the index", id)
+	}
+}
+
+// storedItemCount returns the entity count in the header of an item file on disk
+func storedItemCount(t *testing.T, path string) int {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return 0
+	}
+	if err != nil {
+		t.Fatalf("Failed to read item file: %v", err)
+	}
+	_, count, _, _, _, err := utils.ReadHeaderFromBytes(data)
+	if err != nil {
+		t.Fatalf("Failed to read header: %v", err)
+	}
+	return count
+}
+
+func TestItemDAOGroupCommitFlushesWhenFull(t *testing.T) {
+	utils.SetDataDir(t.TempDir())
+	t.Cleanup(func() { utils.SetDataDir(utils.DefaultDataDir) })
+
+	itemsPath := utils.BinPath("items.bin")
+	itemDAO := dao.NewItemDAO(itemsPath, dao.WithGroupCommit(3, time.Hour))
+	defer itemDAO.Close()
+
+	// Writers wait for their group to be flushed
+	written := make(chan error, 2)
+	for _, name := range []string{"Burger", "Fries"} {
+		go func() {
+			_, err := itemDAO.Write(name, 100)
+			written <- err
+		}()
+	}
+	select {
+	case err := <-written:
+		t.Fatalf("Expected the write to wait for a full group, it returned %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	if count := storedItemCount(t, itemsPath); count != 0 {
+		t.Errorf("Expected buffered items to stay off disk, found %d", count)
+	}
+
+	// The write that fills the group flushes it for everyone
+	if _, err := itemDAO.Write("Soda", 100); err != nil {
+		t.Fatalf("Failed to write item: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := <-written; err != nil {
+			t.Errorf("Failed to write a grouped item: %v", err)
+		}
+	}
+	if count := storedItemCount(t, itemsPath); count != 3 {
+		t.Errorf("Expected the full buffer to be flushed, found %d items", count)
+	}
+	for id := uint64(0); id < 3; id++ {
+		if _, found := itemDAO.GetIndexTree().Search(id); !found {
+			t.Errorf("Expected item %d in the index after the flush", id)
+		}
+	}
+}
+
+func TestItemDAOGroupCommitReadsSeeBufferedItems(t *testing.T) {
+	utils.SetDataDir(t.TempDir())
+	t.Cleanup(func() { utils.SetDataDir(utils.DefaultDataDir) })
+
+	itemDAO := dao.NewItemDAO(utils.BinPath("items.bin"), dao.WithGroupCommit(100, time.Hour))
+	defer itemDAO.Close()
+
+	written := make(chan error, 1)
+	go func() {
+		_, err := itemDAO.Write("Burger", 899)
+		written <- err
+	}()
+
+	// A read flushes the buffer, which also completes the waiting write
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		_, name, price, err := itemDAO.Read(0)
+		if err == nil {
+			if name != "Burger" || price != 899 {
+				t.Errorf("Expected the buffered Burger, got %q %d", name, price)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the buffered item to be readable: %v", err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if err := <-written; err != nil {
+		t.Errorf("Failed to write item: %v", err)
+	}
+}
+
+func TestItemDAOGroupCommitRejectsBufferedID(t *testing.T) {
+	utils.SetDataDir(t.TempDir())
+	t.Cleanup(func() { utils.SetDataDir(utils.DefaultDataDir) })
+
+	itemDAO := dao.NewItemDAO(utils.BinPath("items.bin"), dao.WithGroupCommit(100, time.Hour))
+	defer itemDAO.Close()
+
+	// Explicit IDs are checked against buffered items too, so one of the writes fails right away
+	written := make(chan error, 2)
+	for _, name := range []string{"Burger", "Duplicate"} {
+		go func() {
+			written <- itemDAO.WriteWithID(7, name, 100)
+		}()
+	}
+	if err := <-written; utils.ErrorCodeOf(err) != utils.CodeConflict {
+		t.Errorf("Expected a conflict for the ID of a buffered item, got %v", err)
+	}
+
+	// The other one is buffered until the group is flushed
+	if err := itemDAO.Flush(); err != nil {
+		t.Fatalf("Failed to flush: %v", err)
+	}
+	if err := <-written; err != nil {
+		t.Errorf("Failed to write item: %v", err)
+	}
+}
+
+func TestItemDAOGroupCommitFlushesAfterDelay(t *testing.T) {
+	utils.SetDataDir(t.TempDir())
+	t.Cleanup(func() { utils.SetDataDir(utils.DefaultDataDir) })
+
+	itemsPath := utils.BinPath("items.bin")
+	itemDAO := dao.NewItemDAO(itemsPath, dao.WithGroupCommit(100, 10*time.Millisecond))
+	defer itemDAO.Close()
+
+	start := time.Now()
+	if _, err := itemDAO.Write("Burger", 899); err != nil {
+		t.Fatalf("Failed to write item: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 10*time.Millisecond {
+		t.Errorf("Expected the write to wait for the delay, it returned after %v", elapsed)
+	}
+	if count := storedItemCount(t, itemsPath); count != 1 {
+		t.Errorf("Expected the item on disk once the write returned, found %d", count)
+	}
+}
+
+func TestItemDAOGroupCommitReportsFlushError(t *testing.T) {
+	utils.SetDataDir(t.TempDir())
+	t.Cleanup(func() { utils.SetDataDir(utils.DefaultDataDir) })
+
+	// A directory in place of the dirty marker makes marking the index fail when the group is flushed
+	itemsPath := utils.BinPath("items.bin")
+	blocked := filepath.Join(utils.IndexDirtyPath(utils.IndexPathFromBinFile(itemsPath)), "blocked")
+	if err := os.MkdirAll(blocked, 0755); err != nil {
+		t.Fatalf("Failed to block the marker path: %v", err)
+	}
+	itemDAO := dao.NewItemDAO(itemsPath, dao.WithGroupCommit(100, 10*time.Millisecond))
+	defer itemDAO.Close()
+
+	if _, err := itemDAO.Write("Burger", 899); err == nil || !strings.Contains(err.Error(), "failed to mark index dirty") {
+		t.Errorf("Expected the write to report the failed flush, got %v", err)
+	}
+}
+
+func TestItemDAOWriteDurable(t *testing.T) {
+	utils.SetDataDir(t.TempDir())
+	t.Cleanup(func() { utils.SetDataDir(utils.DefaultDataDir) })
+
+	itemsPath := utils.BinPath("items.bin")
+	itemDAO := dao.NewItemDAO(itemsPath, dao.WithGroupCommit(100, time.Hour))
+	defer itemDAO.Close()
+
+	// A durable write does not wait for the group to fill
+	for _, name := range []string{"Burger", "Fries"} {
+		if _, err := itemDAO.WriteDurable(name, 349); err != nil {
+			t.Fatalf("Failed to write durable item: %v", err)
+		}
+	}
+	if count := storedItemCount(t, itemsPath); count != 2 {
+		t.Errorf("Expected both durable writes on disk, found %d items", count)
+	}
+
+	// A new DAO over the file sees everything written so far
+	reopened := dao.NewItemDAO(itemsPath)
+	defer reopened.Close()
+	items, err := reopened.GetAll()
+	if err != nil || len(items) != 2 {
+		t.Errorf("Expected 2 items after reopening, got %d (err: %v)", len(items), err)
+	}
+}
+
+func TestItemDAOIndexStats(t *testing.T) {
+	utils.SetDataDir(t.TempDir())
+	t.Cleanup(func() { utils.SetDataDir(utils.DefaultDataDir) })
+
+	itemDAO := dao.NewItemDAO(utils.BinPath("items.bin"))
+	defer itemDAO.Close()
+	itemDAO.SetCacheSize(0)
+
+	for _, name := range []string{"Burger", "Fries"} {
+		if _, err := itemDAO.Write(name, 100); err != nil {
+			t.Fatalf("Failed to write item: %v", err)
+		}
+	}
+	if _, _, _, err := itemDAO.Read(0); err != nil {
+		t.Fatalf("Failed to read item: %v", err)
+	}
+	if stats := itemDAO.IndexStats(); stats.IndexedReads != 1 || stats.FallbackScans != 0 {
+		t.Errorf("Expected 1 indexed read and no scans, got %+v", stats)
+	}
+
+	// An item missing from the index is found by a scan, counted as a stale miss
+	itemDAO.GetIndexTree().Delete(1)
+	if _, name, _, err := itemDAO.Read(1); err != nil || name != "Fries" {
+		t.Fatalf("Expected Fries through the fallback scan, got %q (err: %v)", name, err)
+	}
+	// A missing item scans too, but the index was right not to have it
+	if _, _, _, err := itemDAO.Read(99); err == nil {
+		t.Fatal("Expected an error for a missing item")
+	}
+
+	stats := itemDAO.IndexStats()
+	if stats.FallbackScans != 2 || stats.StaleMisses != 1 {
+		t.Errorf("Expected 2 fallback scans and 1 stale miss, got %+v", stats)
+	}
+	if stats.ScanTime <= 0 || stats.MaxScanTime > stats.ScanTime {
+		t.Errorf("Expected scan times to be recorded, got %+v", stats)
+	}
+}
+
+func TestItemDAOGetAllContextStopsWhenCancelled(t *testing.T) {
+	dir := t.TempDir()
+	itemDAO := dao.NewItemDAO(dir + "/items.bin")
+	t.Cleanup(func() { os.Remove(utils.IndexPathFromBinFile(dir + "/items.bin")) })
+	for _, name := range []string{"Burger", "Fries"} {
+		if _, err := itemDAO.Write(name, 499); err != nil {
+			t.Fatalf("failed to write item: %v", err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if items, err := itemDAO.GetAllContext(ctx); err != nil || len(items) != 2 {
+		t.Fatalf("expected 2 items, got %d (err %v)", len(items), err)
+	}
+	cancel()
+	if _, err := itemDAO.GetAllContext(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestItemDAODeleteWhere(t *testing.T) {
+	dir := t.TempDir()
+	itemDAO := dao.NewItemDAO(dir + "/items.bin")
+	t.Cleanup(func() { os.Remove(utils.IndexPathFromBinFile(dir + "/items.bin")) })
+	var ids []uint64
+	for _, item := range []struct {
+		name  string
+		price uint64
+	}{{"Old Burger", 0}, {"Fries", 0}, {"Old Soda", 199}, {"Salad", 599}} {
+		id, err := itemDAO.Write(item.name, item.price)
+		if err != nil {
+			t.Fatalf("failed to write item: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	if _, err := itemDAO.FindByName("fries"); err != nil {
+		t.Fatalf("failed to build name index: %v", err)
+	}
+
+	deleted, err := itemDAO.DeleteWhere(func(item *utils.Item) bool { return item.Price == 0 })
+	if err != nil {
+		t.Fatalf("DeleteWhere failed: %v", err)
+	}
+	if len(deleted) != 2 || deleted[0].ID != ids[0] || deleted[1].ID != ids[1] {
+		t.Fatalf("expected items %d and %d deleted, got %d", ids[0], ids[1], len(deleted))
+	}
+
+	for _, id := range ids[:2] {
+		if _, err := itemDAO.ReadItem(id); err == nil {
+			t.Errorf("expected item %d to be deleted", id)
+		}
+	}
+	if ids, _ := itemDAO.FindByName("Fries"); len(ids) != 0 {
+		t.Errorf("expected Fries removed from the name index, got %v", ids)
+	}
+	if _, err := itemDAO.ReadItem(ids[2]); err != nil {
+		t.Errorf("expected item %d to be kept: %v", ids[2], err)
+	}
+
+	// Already deleted items don't match again
+	deleted, err = itemDAO.DeleteWhere(func(item *utils.Item) bool { return item.Price == 0 })
+	if err != nil || len(deleted) != 0 {
+		t.Errorf("expected nothing left to delete, got %v (err %v)", deleted, err)
+	}
+
+	file, err := os.Open(dir + "/items.bin")
+	if err != nil {
+		t.Fatalf("failed to open items file: %v", err)
+	}
+	defer file.Close()
+	_, _, tombstones, _, err := utils.ReadHeader(file)
+	if err != nil || tombstones != 2 {
+		t.Errorf("expected 2 tombstones in the header, got %d (err %v)", tombstones, err)
+	}
+}
+
+func TestItemDAOGetAllActiveByIDWalksTheIndex(t *testing.T) {
+	dir := t.TempDir()
+	testFile := dir + "/items.bin"
+	indexPath := utils.IndexPathFromBinFile(testFile)
+	itemDAO := dao.NewItemDAO(testFile)
+	for _, name := range []string{"Burger", "Fries", "Soda", "Shake"} {
+		if _, err := itemDAO.Write(name, 499); err != nil {
+			t.Fatalf("failed to write item: %v", err)
+		}
+	}
+	// The rewritten Burger moves behind Shake in the file and Fries is left out
+	if err := itemDAO.Update(0, "Cheeseburger", 599); err != nil {
+		t.Fatalf("failed to update item: %v", err)
+	}
+	if err := itemDAO.Delete(1); err != nil {
+		t.Fatalf("failed to delete item: %v", err)
+	}
+
+	ids := func(d *dao.ItemDAO) []uint64 {
+		t.Helper()
+		items, err := d.GetAllActiveByID(context.Background())
+		if err != nil {
+			t.Fatalf("failed to get items: %v", err)
+		}
+		result := make([]uint64, len(items))
+		for i, item := range items {
+			result[i] = item.ID
+		}
+		return result
+	}
+	if got := ids(itemDAO); fmt.Sprint(got) != "[0 2 3]" {
+		t.Errorf("expected [0 2 3], got %v", got)
+	}
+	if err := itemDAO.Close(); err != nil {
+		t.Fatalf("failed to close: %v", err)
+	}
+
+	// An index saved with the offset of a deleted record is behind the file, so the records are scanned
+	stale, err := index.Load(indexPath)
+	if err != nil {
+		t.Fatalf("failed to load index: %v", err)
+	}
+	offset, _ := stale.Search(3)
+	if err := stale.Delete(3); err != nil {
+		t.Fatalf("failed to delete from index: %v", err)
+	}
+	if err := stale.Insert(1, offset); err != nil {
+		t.Fatalf("failed to insert into index: %v", err)
+	}
+	if err := stale.Save(indexPath); err != nil {
+		t.Fatalf("failed to save stale index: %v", err)
+	}
+	reopened := dao.NewItemDAO(testFile)
+	defer reopened.Close()
+	if got := ids(reopened); fmt.Sprint(got) != "[0 2 3]" {
+		t.Errorf("expected the scan to find [0 2 3], got %v", got)
+	}
+}
+
+func TestItemDAOSanitizesNames(t *testing.T) {
+	dir := t.TempDir()
+	itemDAO := dao.NewItemDAO(dir + "/items.bin")
+	t.Cleanup(func() { itemDAO.Close() })
+
+	id, err := itemDAO.Write("  Café   Latte ", 450)
+	if err != nil {
+		t.Fatalf("failed to write item: %v", err)
+	}
+	if item, err := itemDAO.ReadItem(id); err != nil || item.Name != "Café Latte" {
+		t.Errorf("expected the stored name to be sanitized, got %+v (err %v)", item, err)
+	}
+	if _, err := itemDAO.Write(" \t ", 450); utils.ErrorCodeOf(err) != utils.CodeValidation {
+		t.Errorf("expected a blank name to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,156 @@
+package test
+
+import (
+	"BinaryCRUD/backend/migrate"
+	"BinaryCRUD/backend/utils"
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// legacyItemFile builds a legacy separator-format file with two items, the second deleted
+func legacyItemFile() []byte {
+	return []byte{
+		0x00, 0x00, 0x00, 0x02, 0x1F, 0x00, 0x00, 0x00, 0x01, 0x1F, 0x00, 0x00, 0x00, 0x02, 0x1E, // header
+		0x00, 0x00, 0x00, 0x1F, 0x00, 0x03, 'T', 'e', 'a', 0x1F, 0x00, 0x00, 0x01, 0xF4, 0x1E, // Tea, 500
+		0x00, 0x01, 0x01, 0x1F, 0x00, 0x02, 'O', 'J', 0x1F, 0x00, 0x00, 0x00, 0x64, 0x1E, // OJ, 100 (deleted)
+	}
+}
+
+// legacyCollectionFile builds a legacy separator-format file with one order of two items
+func legacyCollectionFile() []byte {
+	return []byte{
+		0x00, 0x00, 0x00, 0x01, 0x1F, 0x00, 0x00, 0x00, 0x00, 0x1F, 0x00, 0x00, 0x00, 0x01, 0x1E, // header
+		0x00, 0x00, 0x00, 0x1F, 0x00, 0x03, 'B', 'o', 'b', 0x1F, 0x00, 0x00, 0x02, 0x58, 0x1F, // Bob, 600
+		0x00, 0x00, 0x00, 0x02, 0x1F, 0x00, 0x00, 0x00, 0x01, 0x1E, // items [0, 1]
+	}
+}
+
+func TestConvertLegacyItemsRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	legacyPath := filepath.Join(dir, "legacy_items.bin")
+	daoPath := filepath.Join(dir, "items.bin")
+	backPath := filepath.Join(dir, "back.bin")
+
+	legacy := legacyItemFile()
+	if err := os.WriteFile(legacyPath, legacy, 0644); err != nil {
+		t.Fatalf("failed to write legacy file: %v", err)
+	}
+
+	count, err := migrate.ConvertLegacyToDAO(legacyPath, daoPath, migrate.KindItem)
+	if err != nil {
+		t.Fatalf("failed to convert: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("expected 2 records, got %d", count)
+	}
+
+	file, err := os.Open(daoPath)
+	if err != nil {
+		t.Fatalf("failed to open converted file: %v", err)
+	}
+	filename, entities, tombstones, nextId, err := utils.ReadHeader(file)
+	file.Close()
+	if err != nil {
+		t.Fatalf("failed to read header: %v", err)
+	}
+	if filename != "items" || entities != 2 || tombstones != 1 || nextId != 2 {
+		t.Errorf("unexpected header: %s %d %d %d", filename, entities, tombstones, nextId)
+	}
+
+	entries, err := utils.SplitFileIntoEntries(daoPath)
+	if err != nil {
+		t.Fatalf("failed to split entries: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(entries))
+	}
+	first, err := utils.ItemCodec.Decode(entries[0].Data, entries[0].IDSize)
+	if err != nil {
+		t.Fatalf("failed to parse item: %v", err)
+	}
+	if first.Name != "Tea" || first.Price != 500 || first.Tombstone != 0 {
+		t.Errorf("unexpected first item: %+v", first)
+	}
+	second, err := utils.ItemCodec.Decode(entries[1].Data, entries[1].IDSize)
+	if err != nil {
+		t.Fatalf("failed to parse item: %v", err)
+	}
+	if second.ID != 1 || second.Tombstone != 1 {
+		t.Errorf("expected deleted item with ID 1, got %+v", second)
+	}
+
+	// Converting back must reproduce the original legacy bytes
+	if _, err := migrate.ConvertDAOToLegacy(daoPath, backPath, migrate.KindItem); err != nil {
+		t.Fatalf("failed to convert back: %v", err)
+	}
+	back, err := os.ReadFile(backPath)
+	if err != nil {
+		t.Fatalf("failed to read converted file: %v", err)
+	}
+	if !bytes.Equal(back, legacy) {
+		t.Errorf("round trip mismatch:\nexpected %v\ngot      %v", legacy, back)
+	}
+}
+
+func TestConvertLegacyCollectionsRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	legacyPath := filepath.Join(dir, "legacy_orders.bin")
+	daoPath := filepath.Join(dir, "orders.bin")
+	backPath := filepath.Join(dir, "back.bin")
+
+	legacy := legacyCollectionFile()
+	if err := os.WriteFile(legacyPath, legacy, 0644); err != nil {
+		t.Fatalf("failed to write legacy file: %v", err)
+	}
+
+	if _, err := migrate.ConvertLegacyToDAO(legacyPath, daoPath, migrate.KindCollection); err != nil {
+		t.Fatalf("failed to convert: %v", err)
+	}
+
+	entries, err := utils.SplitFileIntoEntries(daoPath)
+	if err != nil {
+		t.Fatalf("failed to split entries: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 entry, got %d", len(entries))
+	}
+	order, err := utils.CollectionCodec.Decode(entries[0].Data, entries[0].IDSize)
+	if err != nil {
+		t.Fatalf("failed to parse collection: %v", err)
+	}
+	if order.OwnerOrName != "Bob" || order.TotalPrice != 600 || len(order.ItemIDs) != 2 || order.ItemIDs[1] != 1 {
+		t.Errorf("unexpected order: %+v", order)
+	}
+
+	if _, err := migrate.ConvertDAOToLegacy(daoPath, backPath, migrate.KindCollection); err != nil {
+		t.Fatalf("failed to convert back: %v", err)
+	}
+	back, err := os.ReadFile(backPath)
+	if err != nil {
+		t.Fatalf("failed to read converted file: %v", err)
+	}
+	if !bytes.Equal(back, legacy) {
+		t.Errorf("round trip mismatch:\nexpected %v\ngot      %v", legacy, back)
+	}
+}
+
+func TestConvertLegacyRejectsMalformed(t *testing.T) {
+	dir := t.TempDir()
+	legacyPath := filepath.Join(dir, "bad.bin")
+
+	// Missing record separator at the end of the only record
+	data := legacyItemFile()
+	data = data[:len(data)-1]
+	if err := os.WriteFile(legacyPath, data, 0644); err != nil {
+		t.Fatalf("failed to write legacy file: %v", err)
+	}
+
+	if _, err := migrate.ConvertLegacyToDAO(legacyPath, filepath.Join(dir, "items.bin"), migrate.KindItem); err == nil {
+		t.Error("expected error for truncated legacy file")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "items.bin")); !os.IsNotExist(err) {
+		t.Error("no output file should be written on failure")
+	}
+}
@@ -0,0 +1,81 @@
+package test
+
+import (
+	"BinaryCRUD/backend/utils"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseLogLevel(t *testing.T) {
+	cases := map[string]slog.Level{
+		"":      slog.LevelDebug,
+		"debug": slog.LevelDebug,
+		"INFO":  slog.LevelInfo,
+		"warn":  slog.LevelWarn,
+		"error": slog.LevelError,
+	}
+	for name, want := range cases {
+		if got, err := utils.ParseLogLevel(name); err != nil || got != want {
+			t.Errorf("ParseLogLevel(%q) = %v (err %v), want %v", name, got, err, want)
+		}
+	}
+	if _, err := utils.ParseLogLevel("verbose"); err == nil {
+		t.Error("expected an unknown level to be rejected")
+	}
+
+	config := utils.DefaultLogConfig()
+	config.MaxFiles = -1
+	if err := config.Validate(); err == nil {
+		t.Error("expected a negative file count to be rejected")
+	}
+}
+
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "app.log")
+	file, err := utils.OpenRotatingFile(path, 20, 2)
+	if err != nil {
+		t.Fatalf("failed to open log file: %v", err)
+	}
+
+	// Every line is 10 bytes, so each file holds two
+	for _, line := range []string{"line0001\n", "line0002\n", "line0003\n", "line0004\n", "line0005\n", "line0006\n", "line0007\n"} {
+		if _, err := file.Write([]byte(line)); err != nil {
+			t.Fatalf("failed to write: %v", err)
+		}
+	}
+	file.Close()
+
+	expected := map[string]string{
+		path:                          "line0007\n",
+		utils.RotatedLogPath(path, 1): "line0005\nline0006\n",
+		utils.RotatedLogPath(path, 2): "line0003\nline0004\n",
+	}
+	for p, want := range expected {
+		data, err := os.ReadFile(p)
+		if err != nil || string(data) != want {
+			t.Errorf("%s: expected %q, got %q (err %v)", filepath.Base(p), want, data, err)
+		}
+	}
+	if _, err := os.Stat(utils.RotatedLogPath(path, 3)); !os.IsNotExist(err) {
+		t.Error("expected the oldest lines to be dropped")
+	}
+
+	// Reopening appends to the current file and counts its size
+	file, err = utils.OpenRotatingFile(path, 20, 2)
+	if err != nil {
+		t.Fatalf("failed to reopen log file: %v", err)
+	}
+	file.Write([]byte("line0008\n"))
+	file.Write([]byte("line0009\n"))
+	file.Close()
+	data, _ := os.ReadFile(path)
+	if strings.Count(string(data), "\n") != 1 || !strings.HasPrefix(string(data), "line0009") {
+		t.Errorf("expected the reopened file to rotate at its limit, got %q", data)
+	}
+	if _, err := file.Write([]byte("late\n")); err == nil {
+		t.Error("expected writes to a closed log file to fail")
+	}
+}
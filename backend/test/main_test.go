@@ -0,0 +1,25 @@
+package test
+
+import (
+	"fmt"
+	"os"
+	"testing"
+)
+
+// TestMain runs the tests from a scratch directory, so the data directory the DAOs default to
+// (indexes, keys, oplogs) is created there instead of inside the source tree
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "binarycrud-test-")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to create test directory: %v\n", err)
+		os.Exit(1)
+	}
+	if err := os.Chdir(dir); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to enter test directory: %v\n", err)
+		os.Exit(1)
+	}
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
@@ -0,0 +1,153 @@
+package test
+
+import (
+	"BinaryCRUD/backend/dao"
+	"BinaryCRUD/backend/utils"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGenerationFilenames(t *testing.T) {
+	if got := utils.GenerationFilename("items.bin", 0); got != "items.bin" {
+		t.Errorf("expected items.bin, got %s", got)
+	}
+	if got := utils.GenerationFilename("order_promotions.bin", 3); got != "order_promotions.gen3.bin" {
+		t.Errorf("expected order_promotions.gen3.bin, got %s", got)
+	}
+
+	cases := map[string]string{
+		"items.gen2.bin":  "items.bin",
+		"items.bin":       "items.bin",
+		"items.genx.bin":  "items.genx.bin",
+		"items.gen0.bin":  "items.gen0.bin",
+		"my.gen.gen4.bin": "my.gen.bin",
+	}
+	for name, want := range cases {
+		if got := utils.LogicalBinName(name); got != want {
+			t.Errorf("LogicalBinName(%s) = %s, want %s", name, got, want)
+		}
+	}
+}
+
+// compactInBinDir writes two items to the bin directory, deletes the second and compacts
+func compactInBinDir(t *testing.T) *dao.ItemDAO {
+	itemDAO := dao.NewItemDAO(utils.BinPath("items.bin"))
+	var last uint64
+	for _, name := range []string{"Burger", "Fries"} {
+		id, err := itemDAO.Write(name, 100)
+		if err != nil {
+			t.Fatalf("failed to write item: %v", err)
+		}
+		last = id
+	}
+	// IDs keep counting up across compactions, so the deleted item is the last one written
+	if err := itemDAO.Delete(last); err != nil {
+		t.Fatalf("failed to delete item: %v", err)
+	}
+
+	_, err := utils.CompactAll(context.Background(), utils.BinPath("items.bin"), utils.BinPath("orders.bin"),
+		utils.BinPath("promotions.bin"), utils.BinPath("order_promotions.bin"), nil)
+	if err != nil {
+		t.Fatalf("CompactAll failed: %v", err)
+	}
+	return itemDAO
+}
+
+func TestCompactAllSwitchesGenerationThroughManifest(t *testing.T) {
+	utils.SetDataDir(t.TempDir())
+	t.Cleanup(func() { utils.SetDataDir(utils.DefaultDataDir) })
+
+	before := compactInBinDir(t)
+
+	if got := utils.BinPath("items.bin"); got != filepath.Join(utils.BinDir, "items.gen1.bin") {
+		t.Fatalf("expected items.bin to resolve to generation 1, got %s", got)
+	}
+	if _, err := os.Stat(filepath.Join(utils.BinDir, utils.ManifestFile)); err != nil {
+		t.Fatalf("manifest not written: %v", err)
+	}
+
+	// A reader that resolved the file before compaction still sees its complete snapshot
+	if _, _, _, err := before.Read(0); err != nil {
+		t.Errorf("old generation no longer readable: %v", err)
+	}
+
+	after := dao.NewItemDAO(utils.BinPath("items.bin"))
+	items, err := after.GetAll()
+	if err != nil {
+		t.Fatalf("failed to read compacted items: %v", err)
+	}
+	if len(items) != 1 || items[0].Name != "Burger" {
+		t.Errorf("expected only Burger after compaction, got %+v", items)
+	}
+
+	data, err := os.ReadFile(utils.BinPath("items.bin"))
+	if err != nil {
+		t.Fatalf("failed to read compacted items: %v", err)
+	}
+	filename, _, _, _, _, err := utils.ReadHeaderFromBytes(data)
+	if err != nil {
+		t.Fatalf("failed to read header: %v", err)
+	}
+	if filename != "items" {
+		t.Errorf("expected header name items, got %s", filename)
+	}
+
+	names, err := utils.CurrentBinFiles(utils.BinDir)
+	if err != nil {
+		t.Fatalf("failed to list bin files: %v", err)
+	}
+	if len(names) != 1 || names[0] != "items.bin" {
+		t.Errorf("expected only the current items.bin, got %v", names)
+	}
+}
+
+func TestCompactAllKeepsOnlyPreviousGeneration(t *testing.T) {
+	utils.SetDataDir(t.TempDir())
+	t.Cleanup(func() { utils.SetDataDir(utils.DefaultDataDir) })
+
+	compactInBinDir(t)
+	compactInBinDir(t)
+
+	if got := utils.BinPath("items.bin"); got != filepath.Join(utils.BinDir, "items.gen2.bin") {
+		t.Fatalf("expected items.bin to resolve to generation 2, got %s", got)
+	}
+	if _, err := os.Stat(filepath.Join(utils.BinDir, "items.bin")); !os.IsNotExist(err) {
+		t.Error("generation 0 was not removed")
+	}
+	if _, err := os.Stat(filepath.Join(utils.BinDir, "items.gen1.bin")); err != nil {
+		t.Errorf("previous generation was removed: %v", err)
+	}
+	if utils.IsCurrentBinFile(utils.BinDir, "items.gen1.bin") {
+		t.Error("previous generation reported as current")
+	}
+}
+
+func TestIsCurrentGenerationFile(t *testing.T) {
+	utils.SetDataDir(t.TempDir())
+	t.Cleanup(func() { utils.SetDataDir(utils.DefaultDataDir) })
+
+	compactInBinDir(t)
+
+	cases := map[string]bool{
+		"items.bin":          false,
+		"items.bin.sig":      false,
+		"items.idx":          false,
+		"items.tomb":         false,
+		"items.gen1.bin":     true,
+		"items.gen1.bin.sig": true,
+		"items.gen1.idx":     true,
+		"items.gen1.tomb":    true,
+		"items.bin.ids":      true,
+		"orders.bin":         true,
+		"orders.idx":         true,
+		"manifest.json":      true,
+		"data.key":           true,
+	}
+	for name, want := range cases {
+		if got := utils.IsCurrentGenerationFile(utils.BinDir, name); got != want {
+			t.Errorf("IsCurrentGenerationFile(%s) = %v, want %v", name, got, want)
+		}
+	}
+}
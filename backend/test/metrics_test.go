@@ -0,0 +1,71 @@
+package test
+
+import (
+	"BinaryCRUD/backend/dao"
+	"BinaryCRUD/backend/utils"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetricsSnapshot(t *testing.T) {
+	utils.SetDataDir(t.TempDir())
+	t.Cleanup(func() { utils.SetDataDir(utils.DefaultDataDir) })
+
+	metrics := utils.NewMetrics()
+	metrics.ObserveOperation("AddItem", time.Second, nil)
+	metrics.ObserveOperation("AddItem", time.Second, nil)
+	metrics.ObserveOperation("GetItem", time.Second, errors.New("item not found"))
+	metrics.ObservePanic("GetOrder")
+	metrics.ObserveOperation("GetOrder", time.Second, errors.New("GetOrder failed unexpectedly"))
+	metrics.ObserveCompaction(2 * time.Second)
+	metrics.ObserveCompaction(time.Second)
+
+	itemDAO := dao.NewItemDAO(utils.BinPath("items.bin"))
+	if _, err := itemDAO.Write("Burger", 899); err != nil {
+		t.Fatalf("failed to write item: %v", err)
+	}
+
+	snapshot := metrics.Snapshot()
+	if snapshot.Operations["AddItem"] != 2 || snapshot.Operations["GetItem"] != 1 || snapshot.OperationSeconds["AddItem"] != 2 {
+		t.Errorf("unexpected operation counts: %v %v", snapshot.Operations, snapshot.OperationSeconds)
+	}
+	if snapshot.Errors["GetItem"] != 1 || snapshot.Errors["AddItem"] != 0 || snapshot.Panics["GetOrder"] != 1 {
+		t.Errorf("unexpected error counts: %v %v", snapshot.Errors, snapshot.Panics)
+	}
+	if snapshot.BytesWritten == 0 {
+		t.Error("expected the item write to be counted")
+	}
+	if snapshot.Compactions != 2 || snapshot.CompactionSeconds != 3 || snapshot.MaxCompactionSeconds != 2 || snapshot.LastCompactionSeconds != 1 {
+		t.Errorf("unexpected compaction metrics: %+v", snapshot)
+	}
+
+	if ratio := snapshot.IndexHitRatio(); ratio != 1 {
+		t.Errorf("expected a hit ratio of 1 before any lookup, got %v", ratio)
+	}
+	snapshot.IndexedReads, snapshot.FallbackScans = 3, 1
+	if ratio := snapshot.IndexHitRatio(); ratio != 0.75 {
+		t.Errorf("expected a hit ratio of 0.75, got %v", ratio)
+	}
+
+	var out strings.Builder
+	if err := snapshot.WritePrometheus(&out); err != nil {
+		t.Fatalf("failed to write metrics: %v", err)
+	}
+	for _, line := range []string{
+		"# TYPE bincrud_operations_total counter",
+		`bincrud_operations_total{operation="AddItem"} 2`,
+		`bincrud_operation_errors_total{operation="GetItem"} 1`,
+		`bincrud_operation_panics_total{operation="GetOrder"} 1`,
+		`bincrud_operation_duration_seconds_sum{operation="AddItem"} 2`,
+		`bincrud_index_lookups_total{result="scan"} 1`,
+		"bincrud_index_hit_ratio 0.75",
+		"bincrud_compaction_duration_seconds_sum 3",
+		"bincrud_compaction_duration_seconds_count 2",
+	} {
+		if !strings.Contains(out.String(), line+"\n") {
+			t.Errorf("expected %q in:\n%s", line, out.String())
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package test
+
+import (
+	"BinaryCRUD/backend/migrate"
+	"BinaryCRUD/backend/utils"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// createLegacyTestFile creates a version 1 (BDAT) file containing 3 items
+func createLegacyTestFile(t *testing.T, filePath string) {
+	if err := createTestFileWithItems(filePath); err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+
+	rewriteHeaderVersion(t, filePath, utils.FormatVersionLegacy)
+}
+
+// rewriteHeaderVersion replaces the header of a file with a header of an older format version
+func rewriteHeaderVersion(t *testing.T, filePath string, version int) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		t.Fatalf("failed to read test file: %v", err)
+	}
+	filename, entitiesCount, tombstoneCount, nextId, headerSize, err := utils.ReadHeaderFromBytes(data)
+	if err != nil {
+		t.Fatalf("failed to read header: %v", err)
+	}
+	header, err := utils.WriteHeaderVersion(version, filename, entitiesCount, tombstoneCount, nextId)
+	if err != nil {
+		t.Fatalf("failed to write header: %v", err)
+	}
+	if err := os.WriteFile(filePath, append(header, data[headerSize:]...), 0644); err != nil {
+		t.Fatalf("failed to write test file: %v", err)
+	}
+}
+
+func TestMigrateFileUpgradesLegacy(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "items.bin")
+	createLegacyTestFile(t, testFile)
+
+	version, err := migrate.DetectVersion(testFile)
+	if err != nil {
+		t.Fatalf("failed to detect version: %v", err)
+	}
+	if version != utils.FormatVersionLegacy {
+		t.Fatalf("expected version %d, got %d", utils.FormatVersionLegacy, version)
+	}
+
+	result, err := migrate.MigrateFile(testFile, utils.CurrentFormatVersion)
+	if err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	if !result.Migrated || result.FromVersion != utils.FormatVersionLegacy || result.ToVersion != utils.CurrentFormatVersion {
+		t.Errorf("unexpected result: %+v", result)
+	}
+
+	version, err = migrate.DetectVersion(testFile)
+	if err != nil {
+		t.Fatalf("failed to detect version: %v", err)
+	}
+	if version != utils.CurrentFormatVersion {
+		t.Errorf("expected version %d after migration, got %d", utils.CurrentFormatVersion, version)
+	}
+
+	// Records must survive the migration untouched
+	entries, err := utils.SplitFileIntoEntries(testFile)
+	if err != nil {
+		t.Fatalf("failed to split entries: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 entries, got %d", len(entries))
+	}
+	item, err := utils.ParseItemEntry(entries[2].Data)
+	if err != nil {
+		t.Fatalf("failed to parse item: %v", err)
+	}
+	if item.Name != "Item" || item.Price != 300 {
+		t.Errorf("unexpected item after migration: %+v", item)
+	}
+
+	// No temp file should be left behind
+	if _, err := os.Stat(testFile + ".tmp"); !os.IsNotExist(err) {
+		t.Error("temp file was not cleaned up")
+	}
+}
+
+func TestMigrateFileAddsHeaderFlags(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "items.bin")
+	if err := createTestFileWithItems(testFile); err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+	rewriteHeaderVersion(t, testFile, utils.FormatVersionFlags-1)
+
+	result, err := migrate.MigrateFile(testFile, utils.FormatVersionFlags)
+	if err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	if !result.Migrated || result.FromVersion != utils.FormatVersionFlags-1 {
+		t.Errorf("unexpected result: %+v", result)
+	}
+
+	flags, err := utils.ReadHeaderFlagsFromPath(testFile)
+	if err != nil {
+		t.Fatalf("failed to read flags: %v", err)
+	}
+	if flags != 0 {
+		t.Errorf("expected no flags after migration, got %#x", flags)
+	}
+
+	entries, err := utils.SplitFileIntoEntries(testFile)
+	if err != nil {
+		t.Fatalf("failed to split entries: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 entries, got %d", len(entries))
+	}
+}
+
+func TestMigrateFileAlreadyCurrent(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "items.bin")
+	if err := createTestFileWithItems(testFile); err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+
+	result, err := migrate.MigrateFile(testFile, utils.CurrentFormatVersion)
+	if err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	if result.Migrated {
+		t.Error("expected no migration for a file already at the current version")
+	}
+}
+
+func TestMigrateFileRejectsDowngrade(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "items.bin")
+	if err := createTestFileWithItems(testFile); err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+
+	if _, err := migrate.MigrateFile(testFile, utils.FormatVersionLegacy); err == nil {
+		t.Error("expected error when downgrading")
+	}
+	if _, err := migrate.MigrateFile(testFile, utils.CurrentFormatVersion+1); err == nil {
+		t.Error("expected error for unsupported target version")
+	}
+}
+
+func TestMigrateDir(t *testing.T) {
+	dir := t.TempDir()
+	createLegacyTestFile(t, filepath.Join(dir, "items.bin"))
+	if err := createTestFileWithItems(filepath.Join(dir, "orders.bin")); err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("ignored"), 0644); err != nil {
+		t.Fatalf("failed to create non-bin file: %v", err)
+	}
+
+	results, err := migrate.MigrateDir(dir, utils.CurrentFormatVersion)
+	if err != nil {
+		t.Fatalf("failed to migrate dir: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(results))
+	}
+
+	migrated := 0
+	for _, result := range results {
+		if result.Migrated {
+			migrated++
+		}
+	}
+	if migrated != 1 {
+		t.Errorf("expected 1 migrated file, got %d", migrated)
+	}
+
+	// A missing directory is not an error
+	results, err = migrate.MigrateDir(filepath.Join(dir, "missing"), utils.CurrentFormatVersion)
+	if err != nil || len(results) != 0 {
+		t.Errorf("expected empty result for missing dir, got %v, %v", results, err)
+	}
+}
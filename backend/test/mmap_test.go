@@ -0,0 +1,118 @@
+package test
+
+import (
+	"BinaryCRUD/backend/dao"
+	"BinaryCRUD/backend/utils"
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// enableMmapReads turns memory-mapped reads on for the duration of a test
+func enableMmapReads(t *testing.T) {
+	utils.MmapReads = true
+	t.Cleanup(func() { utils.MmapReads = false })
+}
+
+func TestOpenMappedFileMatchesContent(t *testing.T) {
+	enableMmapReads(t)
+	path := filepath.Join(t.TempDir(), "data.bin")
+	content := []byte("memory-mapped content")
+	if err := os.WriteFile(path, content, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := utils.OpenMappedFile(path)
+	if err != nil {
+		t.Fatalf("failed to open mapped file: %v", err)
+	}
+	if !bytes.Equal(file.Bytes(), content) {
+		t.Errorf("expected %q, got %q", content, file.Bytes())
+	}
+	if runtime.GOOS != "windows" && !file.Mapped() {
+		t.Error("expected the file to be memory-mapped")
+	}
+	if err := file.Close(); err != nil {
+		t.Errorf("failed to close mapped file: %v", err)
+	}
+}
+
+func TestOpenMappedFileFallsBackForEmptyFiles(t *testing.T) {
+	enableMmapReads(t)
+	path := filepath.Join(t.TempDir(), "empty.bin")
+	if err := os.WriteFile(path, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := utils.OpenMappedFile(path)
+	if err != nil {
+		t.Fatalf("failed to open empty file: %v", err)
+	}
+	defer file.Close()
+	if file.Mapped() || len(file.Bytes()) != 0 {
+		t.Errorf("expected an empty file to be read, got mapped=%v len=%d", file.Mapped(), len(file.Bytes()))
+	}
+}
+
+func TestMmapReadsGetAllAndRebuild(t *testing.T) {
+	utils.SetDataDir(t.TempDir())
+	defer utils.SetDataDir(utils.DefaultDataDir)
+
+	itemsPath := utils.BinPath("items.bin")
+	itemDAO := dao.NewItemDAO(itemsPath)
+	for _, name := range []string{"Burger", "Fries", "Soda"} {
+		if _, err := itemDAO.Write(name, 100); err != nil {
+			t.Fatalf("failed to write item: %v", err)
+		}
+	}
+	if err := itemDAO.Delete(1); err != nil {
+		t.Fatalf("failed to delete item: %v", err)
+	}
+	want, err := itemDAO.GetAll()
+	if err != nil {
+		t.Fatalf("failed to read items: %v", err)
+	}
+
+	enableMmapReads(t)
+	got, err := itemDAO.GetAll()
+	if err != nil {
+		t.Fatalf("failed to read mapped items: %v", err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d items, got %d", len(want), len(got))
+	}
+	for i := range want {
+		if got[i].ID != want[i].ID || got[i].Name != want[i].Name || got[i].IsDeleted != want[i].IsDeleted {
+			t.Errorf("item %d: expected %+v, got %+v", i, want[i], got[i])
+		}
+	}
+
+	tree, err := utils.RebuildBTreeIndex(context.Background(), itemsPath, utils.IndexPathFromBinFile(itemsPath))
+	if err != nil {
+		t.Fatalf("failed to rebuild index from mapped file: %v", err)
+	}
+	if tree.Size() != 2 {
+		t.Errorf("expected 2 active items in the rebuilt index, got %d", tree.Size())
+	}
+	for _, id := range []uint64{0, 2} {
+		offset, found := tree.Search(id)
+		if !found {
+			t.Fatalf("item %d missing from the rebuilt index", id)
+		}
+		file, err := os.Open(itemsPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := utils.ReadEntryAtOffset(file, offset)
+		file.Close()
+		if err != nil {
+			t.Fatalf("rebuilt offset of item %d is wrong: %v", id, err)
+		}
+		if item, err := utils.ParseItemEntry(data); err != nil || item.ID != id {
+			t.Errorf("rebuilt offset of item %d points at another record", id)
+		}
+	}
+}
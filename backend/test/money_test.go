@@ -0,0 +1,108 @@
+package test
+
+import (
+	"BinaryCRUD/backend/money"
+	"testing"
+)
+
+func TestMoneyFormatLocales(t *testing.T) {
+	formats := map[string]string{
+		"":      "$1,234,567.89",
+		"en-US": "$1,234,567.89",
+		"pt-BR": "$ 1.234.567,89",
+		"de-DE": "1.234.567,89 $",
+		"fr-FR": "1 234 567,89 $",
+	}
+	for name, expected := range formats {
+		locale, err := money.LookupLocale(name)
+		if err != nil {
+			t.Fatalf("Failed to look up locale %q: %v", name, err)
+		}
+		if got := money.Format(123456789, "USD", locale); got != expected {
+			t.Errorf("Format(123456789, USD, %q) = %q, expected %q", name, got, expected)
+		}
+	}
+
+	locale, _ := money.LookupLocale("en-US")
+	if got := money.Format(7, "CHF", locale); got != "0.07 CHF" {
+		t.Errorf("Expected an unknown currency to be written with its code, got %q", got)
+	}
+	if _, err := money.LookupLocale("xx-XX"); err == nil {
+		t.Error("Expected an error for an unknown locale")
+	}
+}
+
+func TestMoneyParse(t *testing.T) {
+	amounts := map[string]uint64{
+		"12":           1200,
+		"12.5":         1250,
+		"$1,234.56":    123456,
+		"1.234,56 €":   123456,
+		"R$ 1.234.567": 123456700,
+		"1,234":        123400,
+		"0,125":        12,
+		"0.135":        14,
+		"0.1251":       13,
+		"1234.565":     123456,
+		"1 234,50":     123450,
+		".99":          99,
+		"USD 3":        300,
+		"12 USD":       1200,
+		"12.":          1200,
+	}
+	for text, expected := range amounts {
+		got, err := money.Parse(text)
+		if err != nil {
+			t.Errorf("Parse(%q) failed: %v", text, err)
+			continue
+		}
+		if got != expected {
+			t.Errorf("Parse(%q) = %d, expected %d", text, got, expected)
+		}
+	}
+
+	for _, text := range []string{"", "$", "-5.00", "12#", "12345678901234567.00"} {
+		if _, err := money.Parse(text); err == nil {
+			t.Errorf("Expected Parse(%q) to fail", text)
+		}
+	}
+}
+
+func TestMoneyParseRejectsMalformedAmounts(t *testing.T) {
+	tests := []struct {
+		text   string
+		reason string
+	}{
+		{"1e5", "letter between digits"},
+		{"12abc34", "letters between digits"},
+		{"US 12 D", "code split around the amount"},
+		{"1,2.3.4", "decimal separator repeated"},
+		{"1.234,5,6", "decimal separator repeated"},
+		{"1.23,45", "group of two digits"},
+		{"1,2345.00", "group of four digits"},
+		{"1,,234", "empty group"},
+		{"1.2.3.4", "groups of one digit"},
+		{",234,567", "grouping with no leading digits"},
+		{"1 234.567,00", "spaces and dots both grouping"},
+		{"12,3 4", "space in the fraction"},
+		{".", "no digits"},
+	}
+	for _, tt := range tests {
+		if got, err := money.Parse(tt.text); err == nil {
+			t.Errorf("Parse(%q) = %d, expected an error (%s)", tt.text, got, tt.reason)
+		}
+	}
+}
+
+func TestMoneyParseRoundTrip(t *testing.T) {
+	for _, name := range []string{"en-US", "en-GB", "pt-BR", "de-DE", "es-ES", "fr-FR"} {
+		locale, _ := money.LookupLocale(name)
+		for _, cents := range []uint64{0, 5, 99, 100000, 987654321} {
+			text := money.Format(cents, "EUR", locale)
+			got, err := money.Parse(text)
+			if err != nil || got != cents {
+				t.Errorf("Parse(%q) = %d, %v, expected %d", text, got, err, cents)
+			}
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package test
+
+import (
+	"BinaryCRUD/backend/dao"
+	"BinaryCRUD/backend/utils"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOnlineCompactionCatchesUpConcurrentWrites(t *testing.T) {
+	dir := t.TempDir()
+	itemsPath := filepath.Join(dir, fmt.Sprintf("online_items_%d.bin", os.Getpid()))
+	t.Cleanup(func() { os.Remove(utils.IndexPathFromBinFile(itemsPath)) })
+
+	itemDAO := dao.NewItemDAO(itemsPath)
+	for _, name := range []string{"Burger", "Fries", "Soda"} {
+		if _, err := itemDAO.Write(name, 100); err != nil {
+			t.Fatalf("failed to write item: %v", err)
+		}
+	}
+	if err := itemDAO.Delete(1); err != nil {
+		t.Fatalf("failed to delete item: %v", err)
+	}
+
+	copyPath := filepath.Join(dir, "items_copy.bin")
+	var snapshot *utils.FileSnapshot
+	err := itemDAO.WithFileLocked(func(filePath string) error {
+		var err error
+		snapshot, err = utils.TakeSnapshot(filePath, copyPath, utils.IDKeyFields)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("failed to take snapshot: %v", err)
+	}
+
+	result, err := utils.CompactFiles(context.Background(), copyPath, filepath.Join(dir, "o.bin"), filepath.Join(dir, "p.bin"), filepath.Join(dir, "op.bin"), nil)
+	if err != nil {
+		t.Fatalf("failed to compact snapshot: %v", err)
+	}
+	if result.ItemsRemoved != 1 {
+		t.Errorf("expected 1 item removed, got %d", result.ItemsRemoved)
+	}
+
+	// Writes made while the copy was being compacted
+	newID, err := itemDAO.Write("Shake", 450)
+	if err != nil {
+		t.Fatalf("failed to write item: %v", err)
+	}
+	if err := itemDAO.Delete(2); err != nil {
+		t.Fatalf("failed to delete item: %v", err)
+	}
+	if err := itemDAO.Update(0, "Cheeseburger", 999); err != nil {
+		t.Fatalf("failed to update item: %v", err)
+	}
+
+	err = itemDAO.ReplaceFile(copyPath, func() error {
+		_, err := snapshot.CatchUp()
+		return err
+	})
+	if err != nil {
+		t.Fatalf("failed to replace file: %v", err)
+	}
+
+	items, err := itemDAO.GetAll()
+	if err != nil {
+		t.Fatalf("failed to list items: %v", err)
+	}
+	active := map[uint64]string{}
+	for _, item := range items {
+		if !item.IsDeleted {
+			active[item.ID] = item.Name
+		}
+	}
+	if len(active) != 2 || active[0] != "Cheeseburger" || active[newID] != "Shake" {
+		t.Errorf("unexpected active items after switch: %v", active)
+	}
+
+	if _, _, _, err := itemDAO.Read(1); err == nil {
+		t.Error("item removed by compaction is still readable")
+	}
+	if _, _, _, err := itemDAO.Read(2); err == nil {
+		t.Error("item deleted during compaction is still readable")
+	}
+
+	next, err := itemDAO.Write("Salad", 500)
+	if err != nil || next != newID+1 {
+		t.Errorf("expected next ID %d after switch, got %d (err %v)", newID+1, next, err)
+	}
+}
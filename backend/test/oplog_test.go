@@ -0,0 +1,169 @@
+package test
+
+import (
+	"BinaryCRUD/backend/crypto"
+	"BinaryCRUD/backend/oplog"
+	"BinaryCRUD/backend/utils"
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestOplogAppendAndReadAll(t *testing.T) {
+	log := oplog.New(filepath.Join(t.TempDir(), "oplog", "oplog.bin"))
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	ops := []oplog.Operation{
+		{Timestamp: base, Type: oplog.OpAddItem, ID: 0, Name: "Burger", Price: 899},
+		{Timestamp: base.Add(time.Second), Type: oplog.OpCreateOrder, ID: 0, Name: "John", Price: 899, ItemIDs: []uint64{0}},
+		{Timestamp: base.Add(2 * time.Second), Type: oplog.OpDeleteItem, ID: 0},
+	}
+	for _, op := range ops {
+		if err := log.Append(op); err != nil {
+			t.Fatalf("failed to append: %v", err)
+		}
+	}
+
+	read, err := log.ReadAll()
+	if err != nil {
+		t.Fatalf("failed to read oplog: %v", err)
+	}
+	if len(read) != len(ops) {
+		t.Fatalf("expected %d operations, got %d", len(ops), len(read))
+	}
+	if !read[1].Timestamp.Equal(ops[1].Timestamp) || read[1].Type != oplog.OpCreateOrder ||
+		read[1].Name != "John" || len(read[1].ItemIDs) != 1 {
+		t.Errorf("unexpected operation: %+v", read[1])
+	}
+	if read[2].Name != "" || read[2].Type != oplog.OpDeleteItem {
+		t.Errorf("unexpected delete operation: %+v", read[2])
+	}
+}
+
+func TestOplogEncryptsOrderAndPromotionNames(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "oplog.bin")
+	log := oplog.New(path)
+	ops := []oplog.Operation{
+		{Timestamp: time.Now(), Type: oplog.OpAddItem, Name: "Burger", Price: 899},
+		{Timestamp: time.Now(), Type: oplog.OpCreateOrder, Name: "Jane Customer", Price: 899, ItemIDs: []uint64{0}},
+		{Timestamp: time.Now(), Type: oplog.OpDeletePromotion, ID: 3, Name: "Secret Combo", Price: 899, ItemIDs: []uint64{0}},
+	}
+	for _, op := range ops {
+		if err := log.Append(op); err != nil {
+			t.Fatalf("failed to append: %v", err)
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read oplog file: %v", err)
+	}
+	for _, name := range []string{"Jane Customer", "Secret Combo"} {
+		if bytes.Contains(data, []byte(name)) {
+			t.Errorf("expected %q to be encrypted in the oplog file", name)
+		}
+	}
+	if !bytes.Contains(data, []byte("Burger")) {
+		t.Error("expected item names to stay readable in the oplog file")
+	}
+
+	fieldCipher, err := crypto.GetFieldCipher(utils.KeysDir)
+	if err != nil {
+		t.Fatalf("failed to get field cipher: %v", err)
+	}
+	count, err := log.ReencryptNames(func(stored []byte) ([]byte, error) {
+		return fieldCipher.Reencrypt(stored, fieldCipher)
+	})
+	if err != nil || count != 2 {
+		t.Fatalf("expected 2 names re-encrypted, got %d (err %v)", count, err)
+	}
+
+	read, err := log.ReadAll()
+	if err != nil {
+		t.Fatalf("failed to read oplog: %v", err)
+	}
+	for i, op := range read {
+		if op.Name != ops[i].Name {
+			t.Errorf("operation %d: expected name %q, got %q", i, ops[i].Name, op.Name)
+		}
+	}
+}
+
+func TestOplogIgnoresTruncatedTail(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "oplog.bin")
+	log := oplog.New(path)
+
+	log.Append(oplog.Operation{Timestamp: time.Now(), Type: oplog.OpAddItem, Name: "Tea", Price: 100})
+	log.Append(oplog.Operation{Timestamp: time.Now(), Type: oplog.OpAddItem, ID: 1, Name: "Coffee", Price: 200})
+
+	// Simulate a crash in the middle of the last append
+	data, _ := os.ReadFile(path)
+	os.WriteFile(path, data[:len(data)-3], 0600)
+
+	read, err := log.ReadAll()
+	if err != nil {
+		t.Fatalf("failed to read oplog: %v", err)
+	}
+	if len(read) != 1 {
+		t.Errorf("expected 1 complete operation, got %d", len(read))
+	}
+}
+
+func TestOplogReplayTo(t *testing.T) {
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	ops := []oplog.Operation{
+		{Timestamp: base, Type: oplog.OpAddItem, ID: 0, Name: "Burger", Price: 899},
+		{Timestamp: base.Add(1 * time.Minute), Type: oplog.OpAddItem, ID: 1, Name: "Fries", Price: 349},
+		{Timestamp: base.Add(2 * time.Minute), Type: oplog.OpCreateOrder, ID: 0, Name: "John", Price: 1248, ItemIDs: []uint64{0, 1}},
+		{Timestamp: base.Add(3 * time.Minute), Type: oplog.OpCreatePromotion, ID: 0, Name: "Combo", Price: 1248, ItemIDs: []uint64{0, 1}},
+		{Timestamp: base.Add(4 * time.Minute), Type: oplog.OpApplyPromotion, OrderID: 0, PromotionID: 0},
+		{Timestamp: base.Add(5 * time.Minute), Type: oplog.OpRemovePromotion, OrderID: 0, PromotionID: 0},
+		{Timestamp: base.Add(6 * time.Minute), Type: oplog.OpApplyPromotion, OrderID: 0, PromotionID: 0},
+		{Timestamp: base.Add(7 * time.Minute), Type: oplog.OpDeleteItem, ID: 0},
+	}
+
+	// State just before the item was deleted
+	dir := filepath.Join(t.TempDir(), "replay")
+	result, err := oplog.Replay(ops, base.Add(6*time.Minute), dir)
+	if err != nil {
+		t.Fatalf("replay failed: %v", err)
+	}
+	if result.Applied != 7 || result.Skipped != 1 || result.Failed != 0 {
+		t.Fatalf("unexpected replay result: %+v", result)
+	}
+
+	entries, err := utils.SplitFileIntoEntries(filepath.Join(dir, "items.bin"))
+	if err != nil {
+		t.Fatalf("failed to read replayed items: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 items, got %d", len(entries))
+	}
+	item, _ := utils.ParseItemEntry(entries[0].Data)
+	if item.Name != "Burger" || item.Tombstone != 0 {
+		t.Errorf("expected active Burger, got %+v", item)
+	}
+
+	// The link was removed and re-applied, so exactly one active link remains
+	links, err := utils.SplitFileIntoEntries(filepath.Join(dir, "order_promotions.bin"))
+	if err != nil {
+		t.Fatalf("failed to read replayed links: %v", err)
+	}
+	active := 0
+	for _, link := range links {
+		op, _ := utils.ParseOrderPromotionEntry(link.Data)
+		if op.Tombstone == 0 {
+			active++
+		}
+	}
+	if active != 1 {
+		t.Errorf("expected 1 active link, got %d", active)
+	}
+
+	// Replaying into a non-empty directory is refused
+	if _, err := oplog.Replay(ops, base, dir); err == nil {
+		t.Error("expected error when replaying into a non-empty directory")
+	}
+}
@@ -2,6 +2,8 @@ package test
 
 import (
 	"BinaryCRUD/backend/dao"
+	"BinaryCRUD/backend/utils"
+	"fmt"
 	"os"
 	"strings"
 	"testing"
@@ -295,3 +297,163 @@ func TestOrderDAOCreateSequentialIDs(t *testing.T) {
 		}
 	}
 }
+
+func TestOrderDAOGetAllMeta(t *testing.T) {
+	testFile := "/tmp/test_order_getall_meta.bin"
+	defer cleanupOrderTest(testFile)
+
+	orderDAO := dao.NewOrderDAO(testFile, dao.WithEncryption(true))
+	for i := 0; i < 3; i++ {
+		if _, err := orderDAO.Write("Customer", uint64((i+1)*1000), []uint64{1, 2}); err != nil {
+			t.Fatalf("Failed to create order %d: %v", i, err)
+		}
+	}
+	if err := orderDAO.Update(1, "Customer", 5000, []uint64{1, 2, 3}); err != nil {
+		t.Fatalf("Failed to update order: %v", err)
+	}
+	if err := orderDAO.Delete(2); err != nil {
+		t.Fatalf("Failed to delete order: %v", err)
+	}
+
+	meta, err := orderDAO.GetAllMeta()
+	if err != nil {
+		t.Fatalf("Failed to get order summaries: %v", err)
+	}
+	orders, err := orderDAO.GetAll()
+	if err != nil {
+		t.Fatalf("Failed to get all orders: %v", err)
+	}
+	if len(meta) != len(orders) {
+		t.Fatalf("Expected %d summaries, got %d", len(orders), len(meta))
+	}
+	for i, order := range orders {
+		want := dao.CollectionMeta{ID: order.ID, TotalPrice: order.TotalPrice, ItemCount: order.ItemCount, IsDeleted: order.IsDeleted}
+		if meta[i] != want {
+			t.Errorf("Summary %d: expected %+v, got %+v", i, want, meta[i])
+		}
+	}
+	if meta[1].TotalPrice != 5000 || meta[1].ItemCount != 3 {
+		t.Errorf("Expected the updated order, got %+v", meta[1])
+	}
+	if !meta[2].IsDeleted {
+		t.Error("Expected order 2 to be deleted")
+	}
+}
+
+func TestOrderDAOFindByName(t *testing.T) {
+	testFile := "/tmp/test_order_find_by_name.bin"
+	cleanupOrderTest(testFile)
+	defer cleanupOrderTest(testFile)
+
+	orderDAO := dao.NewOrderDAO(testFile, dao.WithEncryption(true))
+	names := []string{"Alice Smith", "Bob", "  alice   SMITH "}
+	for _, name := range names {
+		if _, err := orderDAO.Write(name, 1000, []uint64{1}); err != nil {
+			t.Fatalf("Failed to create order for %q: %v", name, err)
+		}
+	}
+
+	// Encrypted names are stored with the hash searches use
+	order, err := orderDAO.Read(0)
+	if err != nil {
+		t.Fatalf("Failed to read order: %v", err)
+	}
+	if len(order.Extensions[utils.ExtNameHash]) != 16 {
+		t.Fatalf("Expected a 16-byte name hash, got %x", order.Extensions[utils.ExtNameHash])
+	}
+
+	assertFound := func(name string, want []uint64) {
+		t.Helper()
+		ids, err := orderDAO.FindByName(name)
+		if err != nil {
+			t.Fatalf("Failed to find orders for %q: %v", name, err)
+		}
+		if fmt.Sprint(ids) != fmt.Sprint(want) {
+			t.Errorf("Orders for %q: expected %v, got %v", name, want, ids)
+		}
+	}
+	assertFound("alice smith", []uint64{0, 2})
+	assertFound("Bob", []uint64{1})
+	assertFound("Carol", []uint64{})
+
+	// Writes after the index is built keep it up to date
+	if _, err := orderDAO.Write("Carol", 500, []uint64{2}); err != nil {
+		t.Fatalf("Failed to create order: %v", err)
+	}
+	if err := orderDAO.Update(1, "Alice Smith", 2000, []uint64{1, 2}); err != nil {
+		t.Fatalf("Failed to update order: %v", err)
+	}
+	if err := orderDAO.Delete(0); err != nil {
+		t.Fatalf("Failed to delete order: %v", err)
+	}
+	assertFound("Carol", []uint64{3})
+	assertFound("Bob", []uint64{})
+	assertFound("ALICE SMITH", []uint64{2, 1})
+
+	// A fresh DAO builds the same index from the file
+	orderDAO = dao.NewOrderDAO(testFile, dao.WithEncryption(true))
+	assertFound("alice smith", []uint64{1, 2})
+	assertFound("carol", []uint64{3})
+}
+
+func TestOrderDAOFindByNamePlaintext(t *testing.T) {
+	testFile := "/tmp/test_order_find_by_name_plain.bin"
+	cleanupOrderTest(testFile)
+	defer cleanupOrderTest(testFile)
+
+	orderDAO := dao.NewOrderDAO(testFile, dao.WithEncryption(false))
+	if _, err := orderDAO.Write("Dave", 1000, []uint64{1}); err != nil {
+		t.Fatalf("Failed to create order: %v", err)
+	}
+
+	order, err := orderDAO.Read(0)
+	if err != nil {
+		t.Fatalf("Failed to read order: %v", err)
+	}
+	if _, ok := order.Extensions[utils.ExtNameHash]; ok {
+		t.Error("Expected no name hash on a plaintext name")
+	}
+
+	ids, err := orderDAO.FindByName(" dave ")
+	if err != nil {
+		t.Fatalf("Failed to find orders: %v", err)
+	}
+	if len(ids) != 1 || ids[0] != 0 {
+		t.Errorf("Expected [0], got %v", ids)
+	}
+}
+
+// benchmarkOrderList writes 200 encrypted orders and lists them with list
+func benchmarkOrderList(b *testing.B, list func(*dao.OrderDAO) error) {
+	testFile := "/tmp/bench_order_list.bin"
+	cleanupOrderTest(testFile)
+	defer cleanupOrderTest(testFile)
+
+	orderDAO := dao.NewOrderDAO(testFile, dao.WithEncryption(true))
+	for i := 0; i < 200; i++ {
+		if _, err := orderDAO.Write("Customer", 1000, []uint64{1}); err != nil {
+			b.Fatalf("Failed to create order: %v", err)
+		}
+	}
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if err := list(orderDAO); err != nil {
+			b.Fatalf("Failed to list orders: %v", err)
+		}
+	}
+}
+
+func BenchmarkOrderGetAll(b *testing.B) {
+	benchmarkOrderList(b, func(orderDAO *dao.OrderDAO) error {
+		_, err := orderDAO.GetAll()
+		return err
+	})
+}
+
+func BenchmarkOrderGetAllMeta(b *testing.B) {
+	benchmarkOrderList(b, func(orderDAO *dao.OrderDAO) error {
+		_, err := orderDAO.GetAllMeta()
+		return err
+	})
+}
@@ -2,11 +2,18 @@ package test
 
 import (
 	"BinaryCRUD/backend/dao"
+	"BinaryCRUD/backend/index"
+	"BinaryCRUD/backend/utils"
+	"errors"
 	"fmt"
 	"os"
 	"testing"
+	"time"
 )
 
+// opTestAppliedAt is the time relationships written with Write are applied at
+var opTestAppliedAt = time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
 // opTestCounter provides unique IDs for order_promotion test files
 var opTestCounter uint64
 
@@ -19,6 +26,7 @@ func createOPTestFile(prefix string) (string, func()) {
 	cleanup := func() {
 		os.Remove(testFile)
 		os.Remove(indexFile)
+		os.Remove(index.HashLogPath(indexFile))
 	}
 	return testFile, cleanup
 }
@@ -30,13 +38,13 @@ func TestOrderPromotionDAOWrite(t *testing.T) {
 	opDAO := dao.NewOrderPromotionDAO(testFile)
 
 	// Write first relationship
-	err := opDAO.Write(1, 5)
+	err := opDAO.Write(1, 5, opTestAppliedAt)
 	if err != nil {
 		t.Fatalf("Failed to write order-promotion relationship: %v", err)
 	}
 
 	// Write second relationship
-	err = opDAO.Write(2, 5)
+	err = opDAO.Write(2, 5, opTestAppliedAt)
 	if err != nil {
 		t.Fatalf("Failed to write second relationship: %v", err)
 	}
@@ -54,15 +62,27 @@ func TestOrderPromotionDAOPreventDuplicates(t *testing.T) {
 	opDAO := dao.NewOrderPromotionDAO(testFile)
 
 	// Write relationship
-	err := opDAO.Write(1, 5)
+	err := opDAO.Write(1, 5, opTestAppliedAt)
 	if err != nil {
 		t.Fatalf("Failed to write relationship: %v", err)
 	}
 
 	// Try to write same relationship again
-	err = opDAO.Write(1, 5)
-	if err == nil {
-		t.Error("Expected error when writing duplicate relationship, got nil")
+	err = opDAO.Write(1, 5, opTestAppliedAt)
+	if !errors.Is(err, dao.ErrAlreadyApplied) {
+		t.Errorf("Expected ErrAlreadyApplied when writing duplicate relationship, got %v", err)
+	}
+
+	// The data file is checked even when the index entry is gone
+	if err := opDAO.GetHashIndex().Delete(1, 5); err != nil {
+		t.Fatalf("Failed to purge index entry: %v", err)
+	}
+	err = opDAO.Write(1, 5, opTestAppliedAt)
+	if !errors.Is(err, dao.ErrAlreadyApplied) {
+		t.Errorf("Expected ErrAlreadyApplied after index entry was purged, got %v", err)
+	}
+	if exists, _ := opDAO.Exists(1, 5); !exists {
+		t.Error("Expected relationship to exist")
 	}
 }
 
@@ -73,9 +93,9 @@ func TestOrderPromotionDAOGetByOrderID(t *testing.T) {
 	opDAO := dao.NewOrderPromotionDAO(testFile)
 
 	// Write relationships
-	_ = opDAO.Write(1, 5)
-	_ = opDAO.Write(1, 7)
-	_ = opDAO.Write(2, 5)
+	_ = opDAO.Write(1, 5, opTestAppliedAt)
+	_ = opDAO.Write(1, 7, opTestAppliedAt)
+	_ = opDAO.Write(2, 5, opTestAppliedAt)
 
 	// Get promotions for order 1
 	promos, err := opDAO.GetByOrderID(1)
@@ -103,7 +123,7 @@ func TestOrderPromotionDAODelete(t *testing.T) {
 	opDAO := dao.NewOrderPromotionDAO(testFile)
 
 	// Write and delete
-	_ = opDAO.Write(1, 5)
+	_ = opDAO.Write(1, 5, opTestAppliedAt)
 	err := opDAO.Delete(1, 5)
 	if err != nil {
 		t.Fatalf("Failed to delete relationship: %v", err)
@@ -123,10 +143,10 @@ func TestOrderPromotionDAOGetOrderPromotions(t *testing.T) {
 	opDAO := dao.NewOrderPromotionDAO(testFile)
 
 	// Write multiple promotions for one order
-	_ = opDAO.Write(1, 5)
-	_ = opDAO.Write(1, 7)
-	_ = opDAO.Write(1, 9)
-	_ = opDAO.Write(2, 5) // Different order
+	_ = opDAO.Write(1, 5, opTestAppliedAt)
+	_ = opDAO.Write(1, 7, opTestAppliedAt)
+	_ = opDAO.Write(1, 9, opTestAppliedAt)
+	_ = opDAO.Write(2, 5, opTestAppliedAt) // Different order
 
 	// Get promotions for order 1
 	promos, err := opDAO.GetByOrderID(1)
@@ -154,10 +174,10 @@ func TestOrderPromotionDAOGetPromotionOrders(t *testing.T) {
 	opDAO := dao.NewOrderPromotionDAO(testFile)
 
 	// Write multiple orders for one promotion
-	_ = opDAO.Write(1, 5)
-	_ = opDAO.Write(2, 5)
-	_ = opDAO.Write(3, 5)
-	_ = opDAO.Write(1, 7) // Different promotion
+	_ = opDAO.Write(1, 5, opTestAppliedAt)
+	_ = opDAO.Write(2, 5, opTestAppliedAt)
+	_ = opDAO.Write(3, 5, opTestAppliedAt)
+	_ = opDAO.Write(1, 7, opTestAppliedAt) // Different promotion
 
 	// Get orders for promotion 5
 	orders, err := opDAO.GetByPromotionID(5)
@@ -185,9 +205,9 @@ func TestOrderPromotionDAOGetAll(t *testing.T) {
 	opDAO := dao.NewOrderPromotionDAO(testFile)
 
 	// Write multiple relationships
-	_ = opDAO.Write(1, 5)
-	_ = opDAO.Write(1, 7)
-	_ = opDAO.Write(2, 5)
+	_ = opDAO.Write(1, 5, opTestAppliedAt)
+	_ = opDAO.Write(1, 7, opTestAppliedAt)
+	_ = opDAO.Write(2, 5, opTestAppliedAt)
 
 	// Get all relationships
 	all, err := opDAO.GetAll()
@@ -207,8 +227,8 @@ func TestOrderPromotionDAODeletedRelationships(t *testing.T) {
 	opDAO := dao.NewOrderPromotionDAO(testFile)
 
 	// Write and delete
-	_ = opDAO.Write(1, 5)
-	_ = opDAO.Write(1, 7)
+	_ = opDAO.Write(1, 5, opTestAppliedAt)
+	_ = opDAO.Write(1, 7, opTestAppliedAt)
 	_ = opDAO.Delete(1, 5)
 
 	// Get promotions for order 1 (should only get non-deleted)
@@ -260,7 +280,7 @@ func TestOrderPromotionDAOMultipleOrdersMultiplePromotions(t *testing.T) {
 	}
 
 	for _, rel := range relationships {
-		err := opDAO.Write(rel.orderID, rel.promotionID)
+		err := opDAO.Write(rel.orderID, rel.promotionID, opTestAppliedAt)
 		if err != nil {
 			t.Fatalf("Failed to write relationship (%d, %d): %v", rel.orderID, rel.promotionID, err)
 		}
@@ -284,3 +304,278 @@ func TestOrderPromotionDAOMultipleOrdersMultiplePromotions(t *testing.T) {
 		t.Errorf("Expected promotion 7 to have 2 orders, got %d", len(orders7))
 	}
 }
+
+func TestOrderPromotionDAORebuildsStaleIndex(t *testing.T) {
+	testFile, cleanup := createOPTestFile("test_op_stale")
+	defer cleanup()
+
+	opDAO := dao.NewOrderPromotionDAO(testFile)
+	if err := opDAO.Write(1, 5, opTestAppliedAt); err != nil {
+		t.Fatalf("Failed to write relationship: %v", err)
+	}
+
+	// Append a link behind the DAO's back, as oplog replay does
+	file, err := os.OpenFile(testFile, os.O_RDWR, 0644)
+	if err != nil {
+		t.Fatalf("Failed to open data file: %v", err)
+	}
+	entry, err := utils.BuildOrderPromotionEntry(2, 6)
+	if err != nil {
+		t.Fatalf("Failed to build entry: %v", err)
+	}
+	if err := utils.AppendEntryManual(file, entry); err != nil {
+		t.Fatalf("Failed to append entry: %v", err)
+	}
+	file.Close()
+
+	reloaded := dao.NewOrderPromotionDAO(testFile)
+	all, err := reloaded.GetAll()
+	if err != nil {
+		t.Fatalf("Failed to get relationships: %v", err)
+	}
+	if len(all) != 2 {
+		t.Errorf("Expected stale index to be rebuilt with 2 relationships, got %d", len(all))
+	}
+
+	generation, _ := reloaded.GetHashIndex().Generation()
+	dataGeneration, err := utils.ReadGeneration(testFile)
+	if err != nil || generation != dataGeneration {
+		t.Errorf("Expected index generation %d to match data generation %d (err %v)", generation, dataGeneration, err)
+	}
+}
+
+func TestOrderPromotionDAOIndexStats(t *testing.T) {
+	testFile, cleanup := createOPTestFile("test_op_index_stats")
+	defer cleanup()
+
+	opDAO := dao.NewOrderPromotionDAO(testFile)
+	if err := opDAO.Write(1, 5, opTestAppliedAt); err != nil {
+		t.Fatalf("Failed to write relationship: %v", err)
+	}
+	if exists, err := opDAO.Exists(1, 5); err != nil || !exists {
+		t.Fatalf("Expected relationship to exist, got %v (err: %v)", exists, err)
+	}
+
+	// An entry lost from the index is found by a scan and repaired
+	opDAO.GetHashIndex().Delete(1, 5)
+	if exists, err := opDAO.Exists(1, 5); err != nil || !exists {
+		t.Fatalf("Expected relationship to be found by a scan, got %v (err: %v)", exists, err)
+	}
+	if exists, _ := opDAO.Exists(1, 5); !exists {
+		t.Fatal("Expected the repaired index to find the relationship")
+	}
+
+	// The duplicate check of Write scans for the new entry too, but finds nothing
+	stats := opDAO.IndexStats()
+	if stats.IndexedReads != 2 || stats.FallbackScans != 2 || stats.StaleMisses != 1 {
+		t.Errorf("Expected 2 indexed reads, 2 scans and 1 stale miss, got %+v", stats)
+	}
+}
+
+func TestOrderPromotionDAOLogsIndexChanges(t *testing.T) {
+	testFile, cleanup := createOPTestFile("test_op_index_log")
+	defer cleanup()
+
+	opDAO := dao.NewOrderPromotionDAO(testFile)
+	if err := opDAO.Write(1, 5, opTestAppliedAt); err != nil {
+		t.Fatalf("Failed to write relationship: %v", err)
+	}
+	if err := opDAO.Close(); err != nil {
+		t.Fatalf("Failed to close: %v", err)
+	}
+
+	// Later changes only reach the log until the next checkpoint
+	if err := opDAO.Write(2, 5, opTestAppliedAt); err != nil {
+		t.Fatalf("Failed to write relationship: %v", err)
+	}
+	if err := opDAO.Delete(1, 5); err != nil {
+		t.Fatalf("Failed to delete relationship: %v", err)
+	}
+
+	reloaded := dao.NewOrderPromotionDAO(testFile)
+	if logged := reloaded.GetHashIndex().Logged(); logged != 2 {
+		t.Errorf("Expected the 2 logged changes to be replayed, got %d", logged)
+	}
+	all, err := reloaded.GetAll()
+	if err != nil {
+		t.Fatalf("Failed to get relationships: %v", err)
+	}
+	if len(all) != 1 || all[0].OrderID != 2 {
+		t.Errorf("Expected only order 2 to remain, got %+v", all)
+	}
+}
+
+func TestOrderPromotionDAOCheckpointsIndexLog(t *testing.T) {
+	testFile, cleanup := createOPTestFile("test_op_checkpoint")
+	defer cleanup()
+
+	opDAO := dao.NewOrderPromotionDAO(testFile)
+	for i := 1; i <= utils.HashCheckpointInterval; i++ {
+		if err := opDAO.Write(uint64(i), 1, opTestAppliedAt); err != nil {
+			t.Fatalf("Failed to write relationship %d: %v", i, err)
+		}
+	}
+
+	if logged := opDAO.GetHashIndex().Logged(); logged != 0 {
+		t.Errorf("Expected a checkpoint after %d changes, %d still logged", utils.HashCheckpointInterval, logged)
+	}
+	logPath := index.HashLogPath(utils.IndexPathFromBinFile(testFile))
+	if _, err := os.Stat(logPath); !os.IsNotExist(err) {
+		t.Errorf("Expected checkpoint to remove the log, got %v", err)
+	}
+}
+
+func TestOrderPromotionDAOWriteMany(t *testing.T) {
+	testFile, cleanup := createOPTestFile("test_op_write_many")
+	defer cleanup()
+
+	opDAO := dao.NewOrderPromotionDAO(testFile)
+	if err := opDAO.Write(1, 5, opTestAppliedAt); err != nil {
+		t.Fatalf("Failed to write relationship: %v", err)
+	}
+
+	// Existing and repeated links are skipped
+	applied, err := opDAO.WriteMany([]dao.OrderPromotion{
+		{OrderID: 1, PromotionID: 5}, {OrderID: 1, PromotionID: 6}, {OrderID: 1, PromotionID: 7}, {OrderID: 1, PromotionID: 6},
+	})
+	if err != nil {
+		t.Fatalf("Failed to write relationships: %v", err)
+	}
+	if len(applied) != 2 || applied[0].PromotionID != 6 || applied[1].PromotionID != 7 {
+		t.Errorf("Expected promotions 6 and 7 applied, got %+v", applied)
+	}
+
+	// The index is saved in full with the batch, so a reload finds it without a log
+	if err := opDAO.Close(); err != nil {
+		t.Fatalf("Failed to close: %v", err)
+	}
+	if logged := opDAO.GetHashIndex().Logged(); logged != 0 {
+		t.Errorf("Expected the batch to save the index, %d changes still logged", logged)
+	}
+	reloaded := dao.NewOrderPromotionDAO(testFile)
+	promotions, err := reloaded.GetByOrderID(1)
+	if err != nil || len(promotions) != 3 {
+		t.Errorf("Expected 3 promotions on order 1 after reload, got %d (err %v)", len(promotions), err)
+	}
+	generation, _ := reloaded.GetHashIndex().Generation()
+	dataGeneration, err := utils.ReadGeneration(testFile)
+	if err != nil || generation != dataGeneration {
+		t.Errorf("Expected index generation %d to match data generation %d (err %v)", generation, dataGeneration, err)
+	}
+
+	applied, err = reloaded.WriteMany([]dao.OrderPromotion{{OrderID: 1, PromotionID: 5}, {OrderID: 1, PromotionID: 6}})
+	if err != nil || len(applied) != 0 {
+		t.Errorf("Expected nothing applied twice, got %v (err %v)", applied, err)
+	}
+}
+
+func TestOrderPromotionDAOLinkMetadata(t *testing.T) {
+	testFile, cleanup := createOPTestFile("test_op_metadata")
+	defer cleanup()
+
+	opDAO := dao.NewOrderPromotionDAO(testFile)
+	appliedAt := time.Date(2025, 3, 14, 12, 0, 0, 0, time.UTC)
+	if err := opDAO.WriteLink(dao.OrderPromotion{OrderID: 1, PromotionID: 5, AppliedAt: appliedAt, DiscountAmount: 250}); err != nil {
+		t.Fatalf("Failed to write relationship: %v", err)
+	}
+
+	// A link written before metadata was recorded has neither field
+	file, err := os.OpenFile(testFile, os.O_RDWR, 0644)
+	if err != nil {
+		t.Fatalf("Failed to open data file: %v", err)
+	}
+	entry, err := utils.BuildOrderPromotionEntry(2, 5)
+	if err != nil {
+		t.Fatalf("Failed to build entry: %v", err)
+	}
+	if err := utils.AppendEntryManual(file, entry); err != nil {
+		t.Fatalf("Failed to append entry: %v", err)
+	}
+	file.Close()
+
+	links, err := dao.NewOrderPromotionDAO(testFile).GetByPromotionID(5)
+	if err != nil || len(links) != 2 {
+		t.Fatalf("Expected 2 links, got %d (err %v)", len(links), err)
+	}
+	for _, link := range links {
+		switch link.OrderID {
+		case 1:
+			if !link.AppliedAt.Equal(appliedAt) || link.DiscountAmount != 250 {
+				t.Errorf("Expected the metadata to be read back, got %+v", link)
+			}
+		case 2:
+			if !link.AppliedAt.IsZero() || link.DiscountAmount != 0 {
+				t.Errorf("Expected no metadata on the old link, got %+v", link)
+			}
+		}
+	}
+}
+
+func TestOrderPromotionDAODeleteMany(t *testing.T) {
+	testFile, cleanup := createOPTestFile("test_op_delete_many")
+	defer cleanup()
+
+	opDAO := dao.NewOrderPromotionDAO(testFile)
+	if _, err := opDAO.WriteMany([]dao.OrderPromotion{
+		{OrderID: 1, PromotionID: 5}, {OrderID: 1, PromotionID: 6}, {OrderID: 2, PromotionID: 5},
+	}); err != nil {
+		t.Fatalf("Failed to write relationships: %v", err)
+	}
+
+	// Links that don't exist are skipped
+	removed, err := opDAO.DeleteMany([]dao.OrderPromotion{{OrderID: 1, PromotionID: 6}, {OrderID: 2, PromotionID: 5}, {OrderID: 3, PromotionID: 5}})
+	if err != nil {
+		t.Fatalf("Failed to delete relationships: %v", err)
+	}
+	if len(removed) != 2 {
+		t.Errorf("Expected 2 links removed, got %+v", removed)
+	}
+
+	reloaded := dao.NewOrderPromotionDAO(testFile)
+	links, err := reloaded.GetAll()
+	if err != nil || len(links) != 1 || links[0].OrderID != 1 || links[0].PromotionID != 5 {
+		t.Errorf("Expected only order 1, promotion 5 left after reload, got %v (err %v)", links, err)
+	}
+	generation, _ := reloaded.GetHashIndex().Generation()
+	dataGeneration, err := utils.ReadGeneration(testFile)
+	if err != nil || generation != dataGeneration {
+		t.Errorf("Expected index generation %d to match data generation %d (err %v)", generation, dataGeneration, err)
+	}
+}
+
+func TestOrderPromotionDAOMarksIndexDirtyUntilSaved(t *testing.T) {
+	testFile, cleanup := createOPTestFile("test_op_dirty_marker")
+	defer cleanup()
+	indexPath := utils.IndexPathFromBinFile(testFile)
+	defer os.Remove(utils.IndexDirtyPath(indexPath))
+
+	// A single change reaches the index log right away, so the marker is already gone
+	opDAO := dao.NewOrderPromotionDAO(testFile)
+	if err := opDAO.Write(1, 5, opTestAppliedAt); err != nil {
+		t.Fatalf("Failed to write relationship: %v", err)
+	}
+	if utils.IndexDirty(indexPath) {
+		t.Error("Expected no marker once the change was logged")
+	}
+
+	// A batch is only in memory until the index is saved in full
+	if _, err := opDAO.WriteMany([]dao.OrderPromotion{{OrderID: 1, PromotionID: 6}, {OrderID: 2, PromotionID: 6}}); err != nil {
+		t.Fatalf("Failed to write relationships: %v", err)
+	}
+	if !utils.IndexDirty(indexPath) {
+		t.Fatal("Expected the marker while the batch is not saved")
+	}
+
+	// A load that finds the marker rebuilds the index from the data file
+	all, err := dao.NewOrderPromotionDAO(testFile).GetAll()
+	if err != nil || len(all) != 3 {
+		t.Errorf("Expected the rebuilt index to hold 3 relationships, got %d (err %v)", len(all), err)
+	}
+
+	if err := opDAO.Close(); err != nil {
+		t.Fatalf("Failed to close: %v", err)
+	}
+	if utils.IndexDirty(indexPath) {
+		t.Error("Expected the marker to be cleared once the index was saved")
+	}
+}
@@ -0,0 +1,122 @@
+package test
+
+import (
+	"BinaryCRUD/backend/dao"
+	"BinaryCRUD/backend/utils"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPriceHistoryDAOByItem(t *testing.T) {
+	historyDAO := dao.NewPriceHistoryDAO(filepath.Join(t.TempDir(), "price_history.bin"))
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	changes := []dao.PriceChange{
+		{ItemID: 1, Timestamp: now, OldPrice: 899, NewPrice: 999},
+		{ItemID: 2, Timestamp: now.Add(time.Second), OldPrice: 100, NewPrice: 150},
+		{ItemID: 1, Timestamp: now.Add(2 * time.Second), OldPrice: 999, NewPrice: 1099},
+	}
+	for _, change := range changes {
+		if _, err := historyDAO.Write(change); err != nil {
+			t.Fatalf("failed to write price change: %v", err)
+		}
+	}
+
+	history, err := historyDAO.GetByItemID(1)
+	if err != nil {
+		t.Fatalf("failed to read price history: %v", err)
+	}
+	if len(history) != 2 {
+		t.Fatalf("expected 2 changes for item 1, got %d", len(history))
+	}
+	if history[0].OldPrice != 899 || history[1].OldPrice != 999 || history[1].NewPrice != 1099 {
+		t.Errorf("unexpected history: %+v", history)
+	}
+	if !history[1].Timestamp.Equal(now.Add(2 * time.Second)) {
+		t.Errorf("unexpected timestamp %v", history[1].Timestamp)
+	}
+
+	empty, err := historyDAO.GetByItemID(3)
+	if err != nil || len(empty) != 0 {
+		t.Errorf("expected no history for item 3, got %v (err %v)", empty, err)
+	}
+}
+
+func TestItemDAOUpdate(t *testing.T) {
+	itemDAO := dao.NewItemDAO(filepath.Join(t.TempDir(), "items.bin"))
+
+	id, err := itemDAO.Write("Burger", 899)
+	if err != nil {
+		t.Fatalf("failed to write item: %v", err)
+	}
+	if err := itemDAO.Update(id, "Cheeseburger", 999); err != nil {
+		t.Fatalf("failed to update item: %v", err)
+	}
+
+	_, name, price, err := itemDAO.Read(id)
+	if err != nil {
+		t.Fatalf("failed to read item: %v", err)
+	}
+	if name != "Cheeseburger" || price != 999 {
+		t.Errorf("expected Cheeseburger/999, got %s/%d", name, price)
+	}
+
+	if ids, _ := itemDAO.FindByName("burger"); len(ids) != 0 {
+		t.Errorf("old name still indexed: %v", ids)
+	}
+	items, err := itemDAO.GetAll()
+	if err != nil {
+		t.Fatalf("failed to list items: %v", err)
+	}
+	if len(items) != 1 {
+		t.Errorf("expected 1 item after update, got %d", len(items))
+	}
+}
+
+func TestItemDAOUpdatePrice(t *testing.T) {
+	// A shared index left by an earlier test would point at another file's offsets
+	utils.SetDataDir(t.TempDir())
+	t.Cleanup(func() { utils.SetDataDir(utils.DefaultDataDir) })
+
+	itemsPath := filepath.Join(t.TempDir(), "items.bin")
+	itemDAO := dao.NewItemDAO(itemsPath)
+
+	stock, _ := utils.EncodeStock(5)
+	id, err := itemDAO.WriteExtended(nil, "Burger", 899, map[byte][]byte{utils.ExtStock: stock})
+	if err != nil {
+		t.Fatalf("failed to write item: %v", err)
+	}
+	before, err := os.Stat(itemsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := itemDAO.UpdatePrice(id, 1099); err != nil {
+		t.Fatalf("failed to update price: %v", err)
+	}
+
+	item, err := itemDAO.ReadItem(id)
+	if err != nil {
+		t.Fatalf("failed to read item: %v", err)
+	}
+	if item.Name != "Burger" || item.PriceInCents != 1099 {
+		t.Errorf("expected Burger/1099, got %s/%d", item.Name, item.PriceInCents)
+	}
+	if quantity, ok, _ := utils.DecodeStock(item.Extensions); !ok || quantity != 5 {
+		t.Errorf("expected stock to be kept, got %d", quantity)
+	}
+
+	after, err := os.Stat(itemsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if after.Size() != before.Size() {
+		t.Errorf("expected the record to be patched in place, file grew from %d to %d", before.Size(), after.Size())
+	}
+
+	if err := itemDAO.UpdatePrice(42, 100); err == nil {
+		t.Error("expected updating a missing item to fail")
+	}
+}
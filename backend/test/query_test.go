@@ -0,0 +1,158 @@
+package test
+
+import (
+	"BinaryCRUD/backend/query"
+	"testing"
+)
+
+// memorySource is a query source over fixed rows with an index on id
+type memorySource struct {
+	rows    []query.Row
+	lookups int
+}
+
+func (s *memorySource) Columns() []string {
+	return []string{"id", "name", "price"}
+}
+
+func (s *memorySource) Scan() ([]query.Row, error) {
+	return s.rows, nil
+}
+
+func (s *memorySource) Lookup(condition query.Condition) ([]query.Row, bool, error) {
+	if condition.Column != "id" {
+		return nil, false, nil
+	}
+	s.lookups++
+	var rows []query.Row
+	for _, row := range s.rows {
+		if float64(row["id"].(uint64)) == condition.Value {
+			rows = append(rows, row)
+		}
+	}
+	return rows, true, nil
+}
+
+func newMemorySource() *memorySource {
+	return &memorySource{rows: []query.Row{
+		{"id": uint64(0), "name": "Burger", "price": uint64(1250)},
+		{"id": uint64(1), "name": "Fries", "price": uint64(450)},
+		{"id": uint64(2), "name": "Soda", "price": uint64(300)},
+		{"id": uint64(3), "name": "Cheeseburger", "price": uint64(1400)},
+	}}
+}
+
+func runQuery(t *testing.T, source query.Source, statement string) *query.Result {
+	t.Helper()
+	q, err := query.Parse(statement)
+	if err != nil {
+		t.Fatalf("failed to parse %q: %v", statement, err)
+	}
+	result, err := query.Execute(q, source)
+	if err != nil {
+		t.Fatalf("failed to run %q: %v", statement, err)
+	}
+	return result
+}
+
+func TestQueryParse(t *testing.T) {
+	q, err := query.Parse("select name, price from Items where price > 500 and name like 'b%' order by price desc, name limit 20")
+	if err != nil {
+		t.Fatalf("failed to parse: %v", err)
+	}
+	if q.Table != "items" || len(q.Columns) != 2 || len(q.Where) != 2 || len(q.OrderBy) != 2 || q.Limit != 20 {
+		t.Errorf("unexpected query %+v", q)
+	}
+	if q.Where[0].Op != ">" || q.Where[0].Value != 500.0 || q.Where[1].Op != "LIKE" {
+		t.Errorf("unexpected conditions %+v", q.Where)
+	}
+	if !q.OrderBy[0].Desc || q.OrderBy[1].Desc {
+		t.Errorf("unexpected order %+v", q.OrderBy)
+	}
+
+	q, err = query.Parse("SELECT * FROM items WHERE name = 'it''s'")
+	if err != nil || q.Columns != nil || q.Where[0].Value != "it's" {
+		t.Errorf("unexpected parse of quoted string: %+v, %v", q, err)
+	}
+}
+
+func TestQueryParseErrors(t *testing.T) {
+	for _, statement := range []string{
+		"",
+		"DELETE FROM items",
+		"SELECT * items",
+		"SELECT * FROM items WHERE price",
+		"SELECT * FROM items WHERE price ~ 3",
+		"SELECT * FROM items WHERE name = 'open",
+		"SELECT * FROM items LIMIT 0",
+		"SELECT * FROM items LIMIT 5 extra",
+		"SELECT * FROM items WHERE price LIKE 5",
+	} {
+		if _, err := query.Parse(statement); err == nil {
+			t.Errorf("expected %q to fail", statement)
+		}
+	}
+}
+
+func TestQueryFilterSortLimit(t *testing.T) {
+	source := newMemorySource()
+
+	result := runQuery(t, source, "SELECT name FROM items WHERE price > 400 ORDER BY price DESC LIMIT 2")
+	if result.Plan != "full scan" {
+		t.Errorf("expected a full scan, got %s", result.Plan)
+	}
+	if len(result.Rows) != 2 || result.Rows[0]["name"] != "Cheeseburger" || result.Rows[1]["name"] != "Burger" {
+		t.Errorf("unexpected rows %v", result.Rows)
+	}
+	if _, selected := result.Rows[0]["price"]; selected {
+		t.Error("expected only the selected columns")
+	}
+
+	result = runQuery(t, source, "SELECT * FROM items WHERE name LIKE '%BURGER' ORDER BY name")
+	if len(result.Rows) != 2 || result.Rows[0]["name"] != "Burger" {
+		t.Errorf("unexpected LIKE rows %v", result.Rows)
+	}
+
+	result = runQuery(t, source, "SELECT * FROM items WHERE price <> 450 AND price <= 1250")
+	if len(result.Rows) != 2 {
+		t.Errorf("expected 2 rows, got %v", result.Rows)
+	}
+}
+
+func TestQueryUsesIndexLookup(t *testing.T) {
+	source := newMemorySource()
+
+	result := runQuery(t, source, "SELECT * FROM items WHERE price > 100 AND id = 1")
+	if result.Plan != "index lookup on id" || source.lookups != 1 {
+		t.Errorf("expected an index lookup, got %s", result.Plan)
+	}
+	if len(result.Rows) != 1 || result.Rows[0]["name"] != "Fries" {
+		t.Errorf("unexpected rows %v", result.Rows)
+	}
+
+	// Conditions are still applied to the rows of the lookup
+	result = runQuery(t, source, "SELECT * FROM items WHERE id = 1 AND price > 1000")
+	if len(result.Rows) != 0 {
+		t.Errorf("expected no rows, got %v", result.Rows)
+	}
+}
+
+func TestQueryRejectsUnknownColumnsAndTypes(t *testing.T) {
+	source := newMemorySource()
+	for _, statement := range []string{
+		"SELECT color FROM items",
+		"SELECT * FROM items WHERE color = 'red'",
+		"SELECT * FROM items ORDER BY color",
+		"SELECT * FROM items WHERE price = 'cheap'",
+		"SELECT * FROM items WHERE name > 3",
+		"SELECT * FROM items WHERE price LIKE '1%'",
+	} {
+		q, err := query.Parse(statement)
+		if err != nil {
+			t.Fatalf("failed to parse %q: %v", statement, err)
+		}
+		if _, err := query.Execute(q, source); err == nil {
+			t.Errorf("expected %q to fail", statement)
+		}
+	}
+}
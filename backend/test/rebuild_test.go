@@ -0,0 +1,149 @@
+package test
+
+import (
+	"BinaryCRUD/backend/index"
+	"BinaryCRUD/backend/utils"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// setRebuildWorkers changes the rebuild parallelism for the duration of a test
+func setRebuildWorkers(tb testing.TB, workers int) {
+	previous := utils.RebuildWorkers
+	utils.RebuildWorkers = workers
+	tb.Cleanup(func() { utils.RebuildWorkers = previous })
+}
+
+// writeItemsFile writes an items file of count records without going through a DAO
+// IDs only have 2 bytes, so past 65535 records they repeat like updated items: the first record of an
+// ID is live and later ones are tombstoned
+func writeItemsFile(tb testing.TB, path string, count int) {
+	const maxID = 65535
+	header, err := utils.WriteHeader("items", min(count, maxID), max(count-maxID, 0), min(count, maxID)+1)
+	if err != nil {
+		tb.Fatalf("failed to write header: %v", err)
+	}
+
+	var data bytes.Buffer
+	data.Write(header)
+	for i := 0; i < count; i++ {
+		entry, err := utils.BuildItemEntry(fmt.Sprintf("Item number %06d", i), uint64(i%10000))
+		if err != nil {
+			tb.Fatalf("failed to build entry: %v", err)
+		}
+		tombstone := byte(0x00)
+		if i >= maxID {
+			tombstone = 0x01
+		}
+		length, _ := utils.WriteFixedNumber(utils.RecordLengthSize, uint64(utils.IDSize+utils.TombstoneSize+len(entry)))
+		id, _ := utils.WriteFixedNumber(utils.IDSize, uint64(i%maxID+1))
+		data.Write(utils.CombineBytes(length, id, []byte{tombstone}, entry))
+	}
+	if err := os.WriteFile(path, data.Bytes(), 0644); err != nil {
+		tb.Fatal(err)
+	}
+}
+
+// rebuildItems rebuilds the index of an items file with the given parallelism
+func rebuildItems(tb testing.TB, path string, workers int) *index.BTree {
+	setRebuildWorkers(tb, workers)
+	tree, err := utils.RebuildBTreeIndex(context.Background(), path, utils.IndexPathFromBinFile(path))
+	if err != nil {
+		tb.Fatalf("rebuild with %d worker(s) failed: %v", workers, err)
+	}
+	return tree
+}
+
+func TestParallelRebuildMatchesSequential(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "items.bin")
+	writeItemsFile(t, path, 70000)
+
+	sequential := rebuildItems(t, path, 1)
+	parallel := rebuildItems(t, path, 7)
+
+	if sequential.Size() != 65535 || parallel.Size() != sequential.Size() {
+		t.Fatalf("expected 65535 live items, got %d sequential and %d parallel", sequential.Size(), parallel.Size())
+	}
+	for id := uint64(1); id <= 65535; id++ {
+		want, _ := sequential.Search(id)
+		got, found := parallel.Search(id)
+		if !found || got != want {
+			t.Fatalf("item %d: expected offset %d, got %d (found=%v)", id, want, got, found)
+		}
+	}
+}
+
+func TestParallelRebuildOfOrderPromotions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "order_promotions.bin")
+	header, err := utils.WriteHeader("order_promotions", 6000, 0, 0)
+	if err != nil {
+		t.Fatalf("failed to write header: %v", err)
+	}
+	data := bytes.NewBuffer(header)
+	for i := 0; i < 6000; i++ {
+		entry, err := utils.BuildOrderPromotionEntry(uint64(i/3+1), uint64(i%3+1))
+		if err != nil {
+			t.Fatalf("failed to build entry: %v", err)
+		}
+		length, _ := utils.WriteFixedNumber(utils.RecordLengthSize, uint64(len(entry)))
+		data.Write(utils.CombineBytes(length, entry))
+	}
+	if err := os.WriteFile(path, data.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	setRebuildWorkers(t, 4)
+	hashIndex, err := utils.RebuildExtensibleHashIndex(context.Background(), path, utils.IndexPathFromBinFile(path), 4)
+	if err != nil {
+		t.Fatalf("rebuild failed: %v", err)
+	}
+	if hashIndex.Size() != 6000 {
+		t.Errorf("expected 6000 entries, got %d", hashIndex.Size())
+	}
+	if _, found := hashIndex.Search(2000, 3); !found {
+		t.Error("expected order 2000 to have promotion 3")
+	}
+}
+
+// benchmarkRebuild rebuilds the index of a 100k record items file
+func benchmarkRebuild(b *testing.B, workers int) {
+	path := filepath.Join(b.TempDir(), "items.bin")
+	writeItemsFile(b, path, 100000)
+	setRebuildWorkers(b, workers)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := utils.RebuildBTreeIndex(context.Background(), path, utils.IndexPathFromBinFile(path)); err != nil {
+			b.Fatalf("rebuild failed: %v", err)
+		}
+	}
+}
+
+func TestRebuildStopsWhenCancelled(t *testing.T) {
+	dir := t.TempDir()
+	path, indexPath := filepath.Join(dir, "items.bin"), filepath.Join(dir, "items.idx")
+	writeItemsFile(t, path, 1000)
+	setRebuildWorkers(t, 4)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := utils.RebuildBTreeIndex(ctx, path, indexPath); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if _, err := os.Stat(indexPath); !os.IsNotExist(err) {
+		t.Error("a cancelled rebuild saved an index")
+	}
+}
+
+func BenchmarkRebuildIndexSequential(b *testing.B) {
+	benchmarkRebuild(b, 1)
+}
+
+func BenchmarkRebuildIndexParallel(b *testing.B) {
+	benchmarkRebuild(b, 0)
+}
@@ -0,0 +1,133 @@
+package test
+
+import (
+	"BinaryCRUD/backend/dao"
+	"BinaryCRUD/backend/utils"
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// repeatedItemIDs returns n item IDs cycling through a few values, as in an order with many copies of an item
+func repeatedItemIDs(n int) []uint64 {
+	ids := make([]uint64, n)
+	for i := range ids {
+		ids[i] = uint64(i % 4)
+	}
+	return ids
+}
+
+func TestCompressEntryRoundTrip(t *testing.T) {
+	utils.RecordCompressionEnabled = true
+	defer func() { utils.RecordCompressionEnabled = false }()
+
+	itemIDs := repeatedItemIDs(500)
+	entry, err := utils.BuildCollectionEntry([]byte("Alice"), 12345, itemIDs)
+	if err != nil {
+		t.Fatalf("failed to build entry: %v", err)
+	}
+	compressed, err := utils.CompressEntry(entry)
+	if err != nil {
+		t.Fatalf("failed to compress entry: %v", err)
+	}
+	if len(compressed) >= len(entry) {
+		t.Fatalf("expected the entry to shrink, got %d bytes from %d", len(compressed), len(entry))
+	}
+
+	// Prefix the ID and tombstone the way records are stored
+	record := entryWithHeader(t, 7, compressed)
+	if !utils.IsCompressedEntry(record, utils.IDSize) {
+		t.Error("expected the record to be flagged as compressed")
+	}
+	collection, err := utils.ParseCollectionEntry(record)
+	if err != nil {
+		t.Fatalf("failed to parse compressed entry: %v", err)
+	}
+	if collection.ID != 7 || collection.OwnerOrName != "Alice" || collection.TotalPrice != 12345 {
+		t.Errorf("unexpected collection %+v", collection)
+	}
+	if len(collection.ItemIDs) != len(itemIDs) || collection.ItemIDs[499] != itemIDs[499] {
+		t.Errorf("item IDs were not restored")
+	}
+}
+
+func TestCompressEntrySkipsSmallOrDisabled(t *testing.T) {
+	entry, err := utils.BuildCollectionEntry([]byte("Bob"), 100, repeatedItemIDs(500))
+	if err != nil {
+		t.Fatalf("failed to build entry: %v", err)
+	}
+
+	// Disabled by default
+	unchanged, err := utils.CompressEntry(entry)
+	if err != nil || !bytes.Equal(unchanged, entry) {
+		t.Error("expected the entry to be left as is when compression is disabled")
+	}
+
+	utils.RecordCompressionEnabled = true
+	defer func() { utils.RecordCompressionEnabled = false }()
+
+	small, err := utils.BuildCollectionEntry([]byte("Bob"), 100, []uint64{1, 2})
+	if err != nil {
+		t.Fatalf("failed to build entry: %v", err)
+	}
+	unchanged, err = utils.CompressEntry(small)
+	if err != nil || !bytes.Equal(unchanged, small) {
+		t.Error("expected a small entry to be left as is")
+	}
+}
+
+func TestExpandEntryRejectsCorruptPayload(t *testing.T) {
+	record := []byte{0x00, 0x01, 0x00, 0x80, 0x00, 0x00, 0x00, 0x00, 0xFF}
+	if _, err := utils.ExpandEntry(record, utils.LegacyIDSize); err == nil {
+		t.Error("expected a compressed length beyond the record to fail")
+	}
+}
+
+func TestOrderDAOCompressedRecords(t *testing.T) {
+	utils.SetDataDir(t.TempDir())
+	defer utils.SetDataDir(utils.DefaultDataDir)
+
+	utils.RecordCompressionEnabled = true
+	defer func() { utils.RecordCompressionEnabled = false }()
+
+	orderPath := filepath.Join(utils.BinDir, "orders.bin")
+	orderDAO := dao.NewOrderDAO(orderPath)
+
+	itemIDs := repeatedItemIDs(800)
+	bigID, err := orderDAO.Write("Carol", 8000, itemIDs)
+	if err != nil {
+		t.Fatalf("failed to write order: %v", err)
+	}
+	smallID, err := orderDAO.Write("Dave", 10, []uint64{1})
+	if err != nil {
+		t.Fatalf("failed to write order: %v", err)
+	}
+
+	info, err := os.Stat(orderPath)
+	if err != nil {
+		t.Fatalf("failed to stat orders: %v", err)
+	}
+	if info.Size() > int64(len(itemIDs)*utils.IDSize) {
+		t.Errorf("expected orders.bin to be smaller than the raw item list, got %d bytes", info.Size())
+	}
+
+	order, err := orderDAO.Read(bigID)
+	if err != nil {
+		t.Fatalf("failed to read compressed order: %v", err)
+	}
+	if order.OwnerOrName != "Carol" || len(order.ItemIDs) != len(itemIDs) {
+		t.Errorf("unexpected order %s with %d items", order.OwnerOrName, len(order.ItemIDs))
+	}
+	order, err = orderDAO.Read(smallID)
+	if err != nil || order.OwnerOrName != "Dave" {
+		t.Errorf("failed to read plain order: %v", err)
+	}
+
+	// Records stay readable after compression is switched off
+	utils.RecordCompressionEnabled = false
+	order, err = orderDAO.Read(bigID)
+	if err != nil || len(order.ItemIDs) != len(itemIDs) {
+		t.Errorf("failed to read compressed order with compression disabled: %v", err)
+	}
+}
@@ -0,0 +1,266 @@
+package test
+
+import (
+	"BinaryCRUD/backend/oplog"
+	"BinaryCRUD/backend/replication"
+	"BinaryCRUD/backend/utils"
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// replicaItems returns the names of the active items in a replica, in file order
+func replicaItems(t *testing.T, binDir string) []string {
+	t.Helper()
+	entries, err := utils.SplitFileIntoEntries(filepath.Join(binDir, "items.bin"))
+	if err != nil {
+		t.Fatalf("failed to read replica items: %v", err)
+	}
+	names := []string{}
+	for _, entry := range entries {
+		item, err := utils.ParseItemEntry(entry.Data)
+		if err != nil {
+			t.Fatalf("failed to parse replica item: %v", err)
+		}
+		if item.Tombstone == 0 {
+			names = append(names, item.Name)
+		}
+	}
+	return names
+}
+
+// newTestReplica returns a primary log and a replica in separate directories of a temp data dir
+func newTestReplica(t *testing.T) (*oplog.Log, *replication.Replica) {
+	root := t.TempDir()
+	utils.SetDataDir(root)
+	t.Cleanup(func() { utils.SetDataDir(utils.DefaultDataDir) })
+
+	primary := oplog.New(filepath.Join(root, "primary", "oplog", "oplog.bin"))
+	replica := &replication.Replica{
+		Source:  replication.DirSource{Path: primary.Path()},
+		LogPath: filepath.Join(root, "replica", "oplog", "oplog.bin"),
+		BinDir:  filepath.Join(root, "replica", "bin"),
+	}
+	return primary, replica
+}
+
+func appendOps(t *testing.T, log *oplog.Log, ops ...oplog.Operation) {
+	t.Helper()
+	for _, op := range ops {
+		op.Timestamp = time.Now().UTC()
+		if err := log.Append(op); err != nil {
+			t.Fatalf("failed to append: %v", err)
+		}
+	}
+}
+
+func TestReplicaMirrorsPrimaryLog(t *testing.T) {
+	primary, replica := newTestReplica(t)
+
+	// Nothing to fetch before the primary writes
+	result, err := replica.Sync()
+	if err != nil || result.Applied != 0 {
+		t.Fatalf("unexpected sync of an empty primary: %+v, %v", result, err)
+	}
+
+	appendOps(t, primary,
+		oplog.Operation{Type: oplog.OpAddItem, ID: 0, Name: "Burger", Price: 899},
+		oplog.Operation{Type: oplog.OpAddItem, ID: 1, Name: "Fries", Price: 450},
+		oplog.Operation{Type: oplog.OpCreateOrder, ID: 0, Name: "Alice", Price: 1349, ItemIDs: []uint64{0, 1}},
+	)
+	result, err = replica.Sync()
+	if err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+	if result.Applied != 3 || result.Failed != 0 || result.Behind != 0 {
+		t.Fatalf("unexpected sync result %+v", result)
+	}
+	if names := replicaItems(t, replica.BinDir); len(names) != 2 || names[1] != "Fries" {
+		t.Errorf("unexpected replica items %v", names)
+	}
+
+	appendOps(t, primary, oplog.Operation{Type: oplog.OpDeleteItem, ID: 0})
+	if result, err := replica.Sync(); err != nil || result.Applied != 1 {
+		t.Fatalf("unexpected second sync: %+v, %v", result, err)
+	}
+	if names := replicaItems(t, replica.BinDir); len(names) != 1 || names[0] != "Fries" {
+		t.Errorf("expected the delete to be replicated, got %v", names)
+	}
+
+	// The local log is an exact copy, so a later sync has nothing to apply
+	primaryLog, _ := os.ReadFile(primary.Path())
+	replicaLog, _ := os.ReadFile(replica.LogPath)
+	if !bytes.Equal(primaryLog, replicaLog) {
+		t.Error("expected the replica log to match the primary log")
+	}
+	if result, err := replica.Sync(); err != nil || result.Applied != 0 {
+		t.Errorf("expected an idle sync, got %+v, %v", result, err)
+	}
+}
+
+func TestReplicaSkipsIncompleteRecord(t *testing.T) {
+	primary, replica := newTestReplica(t)
+	appendOps(t, primary,
+		oplog.Operation{Type: oplog.OpAddItem, ID: 0, Name: "Burger", Price: 899},
+		oplog.Operation{Type: oplog.OpAddItem, ID: 1, Name: "Fries", Price: 450},
+	)
+
+	// Ship the log as if the primary were in the middle of its second append
+	full, _ := os.ReadFile(primary.Path())
+	partial := filepath.Join(t.TempDir(), "partial.bin")
+	os.WriteFile(partial, full[:len(full)-3], 0600)
+	replica.Source = replication.DirSource{Path: partial}
+
+	result, err := replica.Sync()
+	if err != nil || result.Applied != 1 || result.Behind == 0 {
+		t.Fatalf("expected one applied operation and a pending record, got %+v, %v", result, err)
+	}
+
+	replica.Source = replication.DirSource{Path: primary.Path()}
+	if result, err := replica.Sync(); err != nil || result.Applied != 1 {
+		t.Fatalf("expected the completed record to be applied, got %+v, %v", result, err)
+	}
+	if names := replicaItems(t, replica.BinDir); len(names) != 2 {
+		t.Errorf("expected 2 items, got %v", names)
+	}
+}
+
+func TestReplicaResetsWhenPrimaryLogShrinks(t *testing.T) {
+	primary, replica := newTestReplica(t)
+	appendOps(t, primary, oplog.Operation{Type: oplog.OpAddItem, ID: 0, Name: "Burger", Price: 899})
+	if _, err := replica.Sync(); err != nil {
+		t.Fatalf("sync failed: %v", err)
+	}
+
+	os.Remove(primary.Path())
+	result, err := replica.Sync()
+	if err != nil || !result.Reset {
+		t.Fatalf("expected a reset, got %+v, %v", result, err)
+	}
+	if _, err := os.Stat(filepath.Join(replica.BinDir, "items.bin")); !os.IsNotExist(err) {
+		t.Error("expected the replica data to be cleared")
+	}
+
+	appendOps(t, primary, oplog.Operation{Type: oplog.OpAddItem, ID: 0, Name: "Soda", Price: 300})
+	replica.Sync()
+	if names := replicaItems(t, replica.BinDir); len(names) != 1 || names[0] != "Soda" {
+		t.Errorf("expected the replica to start over, got %v", names)
+	}
+}
+
+func TestReplicaOverTCP(t *testing.T) {
+	primary, replica := newTestReplica(t)
+	appendOps(t, primary,
+		oplog.Operation{Type: oplog.OpAddItem, ID: 0, Name: "Burger", Price: 899},
+		oplog.Operation{Type: oplog.OpAddItem, ID: 1, Name: "Fries", Price: 450},
+	)
+
+	secret := []byte("shared replication secret")
+	server, err := replication.Listen(":0", primary.Path(), secret, func(string) {})
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer server.Close()
+	if !strings.HasPrefix(server.Addr(), "127.0.0.1:") {
+		t.Errorf("expected an address without a host to listen on localhost, got %s", server.Addr())
+	}
+
+	source := &replication.TCPSource{Addr: server.Addr(), Secret: secret}
+	defer source.Close()
+	replica.Source = source
+
+	if result, err := replica.Sync(); err != nil || result.Applied != 2 {
+		t.Fatalf("unexpected sync over TCP: %+v, %v", result, err)
+	}
+	appendOps(t, primary, oplog.Operation{Type: oplog.OpDeleteItem, ID: 1})
+	if result, err := replica.Sync(); err != nil || result.Applied != 1 {
+		t.Fatalf("unexpected second sync over TCP: %+v, %v", result, err)
+	}
+	if names := replicaItems(t, replica.BinDir); len(names) != 1 || names[0] != "Burger" {
+		t.Errorf("unexpected replica items %v", names)
+	}
+
+	// Syncing fails while the primary is down
+	server.Close()
+	if _, err := replica.Sync(); err == nil {
+		t.Error("expected a sync against a stopped server to fail")
+	}
+}
+
+func TestReplicaOverTCPRequiresTheSecret(t *testing.T) {
+	primary, replica := newTestReplica(t)
+	appendOps(t, primary, oplog.Operation{Type: oplog.OpAddItem, ID: 0, Name: "Burger", Price: 899})
+
+	if _, err := replication.Listen("127.0.0.1:0", primary.Path(), nil, func(string) {}); err == nil {
+		t.Fatal("expected a server without a secret to be refused")
+	}
+	rejected := make(chan string, 1)
+	server, err := replication.Listen("127.0.0.1:0", primary.Path(), []byte("primary secret"), func(message string) {
+		select {
+		case rejected <- message:
+		default:
+		}
+	})
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	defer server.Close()
+
+	source := &replication.TCPSource{Addr: server.Addr(), Secret: []byte("guessed secret")}
+	defer source.Close()
+	replica.Source = source
+	if _, err := replica.Sync(); err == nil {
+		t.Fatal("expected a replica with the wrong secret to be refused")
+	}
+	if _, err := os.Stat(filepath.Join(replica.BinDir, "items.bin")); !os.IsNotExist(err) {
+		t.Errorf("expected nothing replicated without the secret, got %v", err)
+	}
+	select {
+	case message := <-rejected:
+		if !strings.Contains(message, "wrong replication secret") {
+			t.Errorf("unexpected rejection log %q", message)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("expected the primary to log the rejected replica")
+	}
+}
+
+func TestReplicaPrepare(t *testing.T) {
+	primary, replica := newTestReplica(t)
+	liveDir := filepath.Join(t.TempDir(), "bin")
+	if err := replica.Prepare(liveDir); err != nil {
+		t.Fatalf("expected an empty replica to be accepted: %v", err)
+	}
+
+	// A replica with a log resumes from its working copy
+	appendOps(t, primary, oplog.Operation{Type: oplog.OpAddItem, ID: 0, Name: "Burger", Price: 899})
+	replica.Sync()
+	if err := replica.Prepare(liveDir); err != nil {
+		t.Fatalf("expected a replica to resume: %v", err)
+	}
+
+	// Export copies the working copy, creating empty files for tables without records
+	exported := filepath.Join(liveDir, "promotions.bin")
+	os.MkdirAll(liveDir, 0755)
+	if err := replica.Export("promotions.bin", exported); err != nil {
+		t.Fatalf("failed to export: %v", err)
+	}
+	if entries, err := utils.SplitFileIntoEntries(exported); err != nil || len(entries) != 0 {
+		t.Errorf("expected an empty promotions file, got %d entries (err %v)", len(entries), err)
+	}
+
+	// A log without a working copy belongs to the instance itself
+	os.RemoveAll(replica.BinDir)
+	if err := replica.Prepare(liveDir); err == nil {
+		t.Error("expected a data directory with its own oplog to be rejected")
+	}
+
+	// Live data without a replica log is rejected
+	os.Remove(replica.LogPath)
+	if err := replica.Prepare(liveDir); err == nil {
+		t.Error("expected local data without a replica log to be rejected")
+	}
+}
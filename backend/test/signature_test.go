@@ -0,0 +1,70 @@
+package test
+
+import (
+	"BinaryCRUD/backend/crypto"
+	"BinaryCRUD/backend/dao"
+	"BinaryCRUD/backend/utils"
+	"os"
+	"testing"
+)
+
+func TestSigningDetectsTampering(t *testing.T) {
+	utils.SetDataDir(t.TempDir())
+	defer utils.SetDataDir(utils.DefaultDataDir)
+	crypto.Reset()
+	defer crypto.Reset()
+	defer func(enabled bool) { utils.SigningEnabled = enabled }(utils.SigningEnabled)
+	utils.SigningEnabled = true
+
+	path := utils.BinPath("items.bin")
+	itemDAO := dao.NewItemDAO(path)
+	id, err := itemDAO.Write("Signed Item", 1500)
+	if err != nil {
+		t.Fatalf("failed to write item: %v", err)
+	}
+	if err := itemDAO.Delete(id); err != nil {
+		t.Fatalf("failed to delete item: %v", err)
+	}
+
+	status, err := utils.VerifyFileSignature(path)
+	if err != nil {
+		t.Fatalf("failed to verify: %v", err)
+	}
+	if status != utils.SignatureValid {
+		t.Fatalf("expected %s after writes, got %s", utils.SignatureValid, status)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read file: %v", err)
+	}
+	data[len(data)-1] ^= 0xFF
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("failed to tamper with file: %v", err)
+	}
+	if status, _ := utils.VerifyFileSignature(path); status != utils.SignatureTampered {
+		t.Errorf("expected %s after tampering, got %s", utils.SignatureTampered, status)
+	}
+
+	if err := utils.RemoveSignatures(); err != nil {
+		t.Fatalf("failed to remove signatures: %v", err)
+	}
+	if status, _ := utils.VerifyFileSignature(path); status != utils.SignatureMissing {
+		t.Errorf("expected %s without a signature, got %s", utils.SignatureMissing, status)
+	}
+}
+
+func TestSigningDisabledWritesNoSignature(t *testing.T) {
+	utils.SetDataDir(t.TempDir())
+	defer utils.SetDataDir(utils.DefaultDataDir)
+	defer func(enabled bool) { utils.SigningEnabled = enabled }(utils.SigningEnabled)
+	utils.SigningEnabled = false
+
+	path := utils.BinPath("items.bin")
+	if _, err := dao.NewItemDAO(path).Write("Unsigned Item", 900); err != nil {
+		t.Fatalf("failed to write item: %v", err)
+	}
+	if _, err := os.Stat(utils.SignaturePath(path)); !os.IsNotExist(err) {
+		t.Error("expected no signature file when signing is disabled")
+	}
+}
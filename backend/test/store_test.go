@@ -0,0 +1,172 @@
+package test
+
+import (
+	"BinaryCRUD/backend/crypto"
+	"BinaryCRUD/backend/dao"
+	"BinaryCRUD/backend/utils"
+	"bytes"
+	"fmt"
+	"os"
+	"testing"
+)
+
+// customer is a minimal entity kept in a generic store
+type customer struct {
+	ID     uint64
+	Name   string
+	Phone  string
+	Visits uint64
+}
+
+// RecordID returns the ID of the customer
+func (c customer) RecordID() uint64 {
+	return c.ID
+}
+
+// customerSerializer stores a customer as [nameLength(2)][name(encrypted)][phoneLength(1)][phone][visits(4)]
+type customerSerializer struct{}
+
+// Encode builds the stored form of a customer
+func (customerSerializer) Encode(codec dao.FieldCodec, c customer) ([]byte, error) {
+	name, err := codec.Seal(c.Name)
+	if err != nil {
+		return nil, err
+	}
+	nameLength, err := utils.WriteFixedNumber(2, uint64(len(name)))
+	if err != nil {
+		return nil, err
+	}
+	visits, err := utils.WriteFixedNumber(4, c.Visits)
+	if err != nil {
+		return nil, err
+	}
+	return utils.CombineBytes(nameLength, name, []byte{byte(len(c.Phone))}, []byte(c.Phone), visits), nil
+}
+
+// Decode parses a stored customer
+func (customerSerializer) Decode(codec dao.FieldCodec, id uint64, data []byte) (customer, error) {
+	nameLength, offset, err := utils.ReadFixedNumber(2, data, 0)
+	if err != nil || offset+int(nameLength)+1 > len(data) {
+		return customer{}, fmt.Errorf("customer record truncated")
+	}
+	name, err := codec.Open(data[offset : offset+int(nameLength)])
+	if err != nil {
+		return customer{}, err
+	}
+	offset += int(nameLength)
+	phoneEnd := offset + 1 + int(data[offset])
+	if phoneEnd > len(data) {
+		return customer{}, fmt.Errorf("customer record truncated")
+	}
+	visits, _, err := utils.ReadFixedNumber(4, data, phoneEnd)
+	if err != nil {
+		return customer{}, err
+	}
+	return customer{ID: id, Name: name, Phone: string(data[offset+1 : phoneEnd]), Visits: visits}, nil
+}
+
+func TestStoreCRUD(t *testing.T) {
+	utils.SetDataDir(t.TempDir())
+	t.Cleanup(func() { utils.SetDataDir(utils.DefaultDataDir) })
+
+	store := dao.NewStore[customer]("customer", utils.BinPath("customers.bin"), customerSerializer{})
+	aliceID, err := store.Write(customer{Name: "Alice", Phone: "555-0100", Visits: 3})
+	if err != nil {
+		t.Fatalf("failed to write customer: %v", err)
+	}
+	bobID, err := store.Write(customer{Name: "Bob", Phone: "555-0101"})
+	if err != nil {
+		t.Fatalf("failed to write customer: %v", err)
+	}
+
+	alice, err := store.Read(aliceID)
+	if err != nil || alice.Name != "Alice" || alice.Phone != "555-0100" || alice.Visits != 3 {
+		t.Fatalf("expected Alice with 3 visits, got %+v (err: %v)", alice, err)
+	}
+
+	alice.Visits++
+	if err := store.Update(alice); err != nil {
+		t.Fatalf("failed to update customer: %v", err)
+	}
+	if err := store.Delete(bobID); err != nil {
+		t.Fatalf("failed to delete customer: %v", err)
+	}
+	if _, err := store.Read(bobID); err == nil {
+		t.Error("expected reading a deleted customer to fail")
+	}
+
+	all, err := store.GetAll()
+	if err != nil {
+		t.Fatalf("failed to list customers: %v", err)
+	}
+	if len(all) != 1 || all[0].ID != aliceID || all[0].Visits != 4 {
+		t.Errorf("expected only Alice with 4 visits, got %+v", all)
+	}
+
+	// A new store over the same file finds the records through its loaded index
+	store.Close()
+	reopened := dao.NewStore[customer]("customer", utils.BinPath("customers.bin"), customerSerializer{})
+	if got, err := reopened.Read(aliceID); err != nil || got.Visits != 4 {
+		t.Errorf("expected Alice with 4 visits after reopening, got %+v (err: %v)", got, err)
+	}
+}
+
+func TestStoreEncryptsSealedFields(t *testing.T) {
+	utils.SetDataDir(t.TempDir())
+	t.Cleanup(func() { utils.SetDataDir(utils.DefaultDataDir) })
+	crypto.Reset()
+	defer crypto.Reset()
+	defer crypto.SetEnabled(crypto.IsEnabled())
+	crypto.SetEnabled(true)
+
+	store := dao.NewStore[customer]("customer", utils.BinPath("customers.bin"), customerSerializer{})
+	id, err := store.Write(customer{Name: "Carol Secret", Phone: "555-0102"})
+	if err != nil {
+		t.Fatalf("failed to write customer: %v", err)
+	}
+
+	raw, err := os.ReadFile(utils.BinPath("customers.bin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(raw, []byte("Carol Secret")) {
+		t.Error("expected the sealed name to be encrypted on disk")
+	}
+	if !bytes.Contains(raw, []byte("555-0102")) {
+		t.Error("expected the unsealed phone to be stored as is")
+	}
+	if got, err := store.Read(id); err != nil || got.Name != "Carol Secret" {
+		t.Errorf("expected the name to decrypt, got %+v (err: %v)", got, err)
+	}
+}
+
+func TestItemDAOUsesStoreWithoutKeys(t *testing.T) {
+	utils.SetDataDir(t.TempDir())
+	t.Cleanup(func() { utils.SetDataDir(utils.DefaultDataDir) })
+	crypto.Reset()
+	defer crypto.Reset()
+
+	itemDAO := dao.NewItemDAO(utils.BinPath("items.bin"))
+	defer itemDAO.Close()
+	id, err := itemDAO.Write("Burger", 899)
+	if err != nil {
+		t.Fatalf("failed to write item: %v", err)
+	}
+
+	// The store API reads the same records as the item DAO
+	item, err := itemDAO.Store.Read(id)
+	if err != nil || item.Name != "Burger" || item.Price != 899 {
+		t.Fatalf("expected Burger through the store, got %+v (err: %v)", item, err)
+	}
+	if err := itemDAO.Delete(id); err != nil {
+		t.Fatalf("failed to delete item: %v", err)
+	}
+	if _, err := itemDAO.Store.Read(id); utils.ErrorCodeOf(err) != utils.CodeDeleted {
+		t.Errorf("expected the deleted item to be reported as deleted, got %v", err)
+	}
+
+	// Item names are never sealed, so no data key is created for them
+	if _, err := os.Stat(utils.KeysDir); !os.IsNotExist(err) {
+		t.Errorf("expected no keys directory, got %v", err)
+	}
+}
@@ -0,0 +1,134 @@
+package test
+
+import (
+	"BinaryCRUD/backend/compression"
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// streamTestData returns data spanning several stream blocks
+func streamTestData() []byte {
+	return bytes.Repeat([]byte("streamed record 0123456789 "), compression.StreamBlockSize*3/2/27)
+}
+
+func TestCompressStreamRoundTrip(t *testing.T) {
+	original := streamTestData()
+
+	for _, algorithm := range compression.Algorithms() {
+		t.Run(algorithm, func(t *testing.T) {
+			compressor, err := compression.NewCompressor(algorithm)
+			if err != nil {
+				t.Fatalf("failed to create compressor: %v", err)
+			}
+
+			var compressed bytes.Buffer
+			if err := compressor.CompressStream(bytes.NewReader(original), &compressed); err != nil {
+				t.Fatalf("CompressStream failed: %v", err)
+			}
+
+			var decompressed bytes.Buffer
+			if err := compressor.DecompressStream(bytes.NewReader(compressed.Bytes()), &decompressed); err != nil {
+				t.Fatalf("DecompressStream failed: %v", err)
+			}
+			if !bytes.Equal(original, decompressed.Bytes()) {
+				t.Error("stream round trip changed the data")
+			}
+
+			// In-memory decompression accepts streams too
+			data, err := compressor.Decompress(compressed.Bytes())
+			if err != nil {
+				t.Fatalf("Decompress of a stream failed: %v", err)
+			}
+			if !bytes.Equal(original, data) {
+				t.Error("Decompress of a stream changed the data")
+			}
+		})
+	}
+}
+
+func TestDecompressStreamAcceptsSingleBuffer(t *testing.T) {
+	original := []byte("compressed before streaming existed, compressed before streaming existed")
+
+	for _, compressor := range []compression.Compressor{compression.NewHuffmanCompressor(), compression.NewLZWCompressor()} {
+		compressed, err := compressor.Compress(original)
+		if err != nil {
+			t.Fatalf("Compress failed: %v", err)
+		}
+		var decompressed bytes.Buffer
+		if err := compressor.DecompressStream(bytes.NewReader(compressed), &decompressed); err != nil {
+			t.Fatalf("DecompressStream failed: %v", err)
+		}
+		if !bytes.Equal(original, decompressed.Bytes()) {
+			t.Error("single-buffer data changed")
+		}
+	}
+}
+
+func TestDecompressStreamRejectsTruncatedStream(t *testing.T) {
+	compressor := compression.NewLZWCompressor()
+	var compressed bytes.Buffer
+	if err := compressor.CompressStream(bytes.NewReader(streamTestData()), &compressed); err != nil {
+		t.Fatalf("CompressStream failed: %v", err)
+	}
+
+	truncated := compressed.Bytes()[:compressed.Len()/2]
+	var decompressed bytes.Buffer
+	if err := compressor.DecompressStream(bytes.NewReader(truncated), &decompressed); err == nil {
+		t.Error("expected truncated stream to fail")
+	}
+}
+
+func TestReadOriginalSize(t *testing.T) {
+	dir := t.TempDir()
+	inputPath := filepath.Join(dir, "items.bin")
+	original := streamTestData()
+	if err := os.WriteFile(inputPath, original, 0644); err != nil {
+		t.Fatalf("failed to write input: %v", err)
+	}
+
+	for _, algorithm := range compression.Algorithms() {
+		compressor, err := compression.NewCompressor(algorithm)
+		if err != nil {
+			t.Fatalf("failed to create compressor: %v", err)
+		}
+		outputPath := filepath.Join(dir, "items.bin."+algorithm+".compressed")
+		if err := compressor.CompressFile(inputPath, outputPath); err != nil {
+			t.Fatalf("%s: CompressFile failed: %v", algorithm, err)
+		}
+
+		size, err := compression.ReadOriginalSize(outputPath)
+		if err != nil {
+			t.Fatalf("%s: ReadOriginalSize failed: %v", algorithm, err)
+		}
+		if size != int64(len(original)) {
+			t.Errorf("%s: expected original size %d, got %d", algorithm, len(original), size)
+		}
+	}
+}
+
+func TestVerifyFile(t *testing.T) {
+	dir := t.TempDir()
+	inputPath := filepath.Join(dir, "orders.bin")
+	otherPath := filepath.Join(dir, "items.bin")
+	compressedPath := filepath.Join(dir, "orders.bin.lzw.compressed")
+	if err := os.WriteFile(inputPath, []byte("orders to compress, orders to compress"), 0644); err != nil {
+		t.Fatalf("failed to write input: %v", err)
+	}
+	if err := os.WriteFile(otherPath, []byte("something else entirely"), 0644); err != nil {
+		t.Fatalf("failed to write input: %v", err)
+	}
+
+	compressor := compression.NewLZWCompressor()
+	if err := compressor.CompressFile(inputPath, compressedPath); err != nil {
+		t.Fatalf("CompressFile failed: %v", err)
+	}
+
+	if err := compression.VerifyFile(compressor, inputPath, compressedPath); err != nil {
+		t.Errorf("expected verification to pass: %v", err)
+	}
+	if err := compression.VerifyFile(compressor, otherPath, compressedPath); err == nil {
+		t.Error("expected verification against another file to fail")
+	}
+}
@@ -0,0 +1,283 @@
+package test
+
+import (
+	"BinaryCRUD/backend/dao"
+	"BinaryCRUD/backend/utils"
+	"context"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTombstoneBitmapFollowsDeletes(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "test_tombstone_items.bin")
+	defer os.Remove(utils.IndexPathFromBinFile(testFile))
+	defer os.Remove(utils.TombstoneBitmapPath(testFile))
+
+	itemDAO := dao.NewItemDAO(testFile)
+	for _, name := range []string{"Burger", "Fries", "Soda", "Shake"} {
+		if _, err := itemDAO.Write(name, 100); err != nil {
+			t.Fatalf("Failed to write item: %v", err)
+		}
+	}
+	if err := itemDAO.Delete(1); err != nil {
+		t.Fatalf("Failed to delete item: %v", err)
+	}
+
+	// The first active scan builds the bitmap, later writes keep it up to date
+	items, err := itemDAO.GetAllActive(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to get active items: %v", err)
+	}
+	if len(items) != 3 {
+		t.Fatalf("Expected 3 active items, got %d", len(items))
+	}
+	if err := itemDAO.Delete(2); err != nil {
+		t.Fatalf("Failed to delete item: %v", err)
+	}
+	if err := itemDAO.Update(0, "Cheeseburger", 150); err != nil {
+		t.Fatalf("Failed to update item: %v", err)
+	}
+	if _, err := itemDAO.Write("Water", 50); err != nil {
+		t.Fatalf("Failed to write item: %v", err)
+	}
+
+	items, err = itemDAO.GetAllActive(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to get active items: %v", err)
+	}
+	names := map[string]bool{}
+	for _, item := range items {
+		if item.IsDeleted {
+			t.Errorf("Expected only active items, got deleted item %d", item.ID)
+		}
+		names[item.Name] = true
+	}
+	if len(items) != 3 || !names["Cheeseburger"] || !names["Shake"] || !names["Water"] {
+		t.Errorf("Expected Cheeseburger, Shake and Water, got %+v", items)
+	}
+
+	// The saved bitmap was kept up to date rather than left for a rebuild: it describes the file as it is now
+	sidecar, err := os.ReadFile(utils.TombstoneBitmapPath(testFile))
+	if err != nil {
+		t.Fatalf("Failed to read bitmap: %v", err)
+	}
+	info, err := os.Stat(testFile)
+	if err != nil {
+		t.Fatalf("Failed to stat file: %v", err)
+	}
+	if size := binary.BigEndian.Uint64(sidecar[4:12]); int64(size) != info.Size() {
+		t.Errorf("Expected the saved bitmap to describe %d bytes, got %d", info.Size(), size)
+	}
+
+	// The saved bitmap matches a fresh scan of the file
+	saved, err := utils.LoadTombstoneBitmap(testFile)
+	if err != nil {
+		t.Fatalf("Failed to load bitmap: %v", err)
+	}
+	built, err := utils.BuildTombstoneBitmap(testFile)
+	if err != nil {
+		t.Fatalf("Failed to build bitmap: %v", err)
+	}
+	if saved.Slots() != built.Slots() || saved.Count() != built.Count() {
+		t.Fatalf("Expected the saved bitmap to match the file, got %d/%d slots and %d/%d tombstones",
+			saved.Slots(), built.Slots(), saved.Count(), built.Count())
+	}
+	for slot := 0; slot < built.Slots(); slot++ {
+		if saved.Tombstoned(slot) != built.Tombstoned(slot) {
+			t.Errorf("Slot %d differs from the file", slot)
+		}
+	}
+}
+
+func TestTombstoneBitmapRebuiltWhenStale(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "test_tombstone_stale.bin")
+	defer os.Remove(utils.IndexPathFromBinFile(testFile))
+	defer os.Remove(utils.TombstoneBitmapPath(testFile))
+
+	orderDAO := dao.NewOrderDAO(testFile)
+	for _, name := range []string{"Alice", "Bob", "Carol"} {
+		if _, err := orderDAO.Write(name, 100, []uint64{1}); err != nil {
+			t.Fatalf("Failed to write order: %v", err)
+		}
+	}
+	if _, err := utils.LoadTombstoneBitmap(testFile); err != nil {
+		t.Fatalf("Failed to load bitmap: %v", err)
+	}
+
+	// Deleted through a DAO that never loaded the bitmap, so the saved one no longer matches the file
+	if err := dao.NewOrderDAO(testFile).Delete(1); err != nil {
+		t.Fatalf("Failed to delete order: %v", err)
+	}
+	bitmap, err := utils.LoadTombstoneBitmap(testFile)
+	if err != nil {
+		t.Fatalf("Failed to load bitmap: %v", err)
+	}
+	if bitmap.Slots() != 3 || bitmap.Count() != 1 || !bitmap.Tombstoned(1) {
+		t.Errorf("Expected the stale bitmap to be rebuilt with slot 1 tombstoned, got %d slots and %d tombstones", bitmap.Slots(), bitmap.Count())
+	}
+
+	orders, err := orderDAO.GetAllActive(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to get active orders: %v", err)
+	}
+	if len(orders) != 2 || orders[0].OwnerOrName != "Alice" || orders[1].OwnerOrName != "Carol" {
+		t.Errorf("Expected Alice and Carol, got %+v", orders)
+	}
+
+	if err := os.WriteFile(utils.TombstoneBitmapPath(testFile), []byte("garbage"), 0644); err != nil {
+		t.Fatalf("Failed to corrupt bitmap: %v", err)
+	}
+	if bitmap, err := utils.LoadTombstoneBitmap(testFile); err != nil || bitmap.Slots() != 3 {
+		t.Errorf("Expected a corrupt bitmap to be rebuilt, got %v", err)
+	}
+}
+
+func TestTombstoneBitmapFlushesInPlace(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "test_tombstone_flush.bin")
+	defer os.Remove(utils.IndexPathFromBinFile(testFile))
+	defer os.Remove(utils.TombstoneBitmapPath(testFile))
+	defer os.Remove(utils.TombstoneSlotsPath(testFile))
+
+	itemDAO := dao.NewItemDAO(testFile)
+	for _, name := range []string{"Burger", "Fries", "Soda"} {
+		if _, err := itemDAO.Write(name, 100); err != nil {
+			t.Fatalf("Failed to write item: %v", err)
+		}
+	}
+	if _, err := itemDAO.GetAllActive(context.Background()); err != nil {
+		t.Fatalf("Failed to get active items: %v", err)
+	}
+	if err := itemDAO.Delete(0); err != nil {
+		t.Fatalf("Failed to delete item: %v", err)
+	}
+	before, err := os.Stat(utils.TombstoneBitmapPath(testFile))
+	if err != nil {
+		t.Fatalf("Failed to stat bitmap: %v", err)
+	}
+
+	// A DAO opened later updates the saved bitmap from its slot offsets, writing into the same file
+	reopened := dao.NewItemDAO(testFile)
+	if _, err := reopened.GetAllActive(context.Background()); err != nil {
+		t.Fatalf("Failed to get active items: %v", err)
+	}
+	if err := reopened.Delete(2); err != nil {
+		t.Fatalf("Failed to delete item: %v", err)
+	}
+	if _, err := reopened.Write("Water", 50); err != nil {
+		t.Fatalf("Failed to write item: %v", err)
+	}
+	after, err := os.Stat(utils.TombstoneBitmapPath(testFile))
+	if err != nil {
+		t.Fatalf("Failed to stat bitmap: %v", err)
+	}
+	if !os.SameFile(before, after) {
+		t.Error("Expected the bitmap to be updated in place rather than rewritten")
+	}
+
+	saved, err := utils.LoadTombstoneBitmap(testFile)
+	if err != nil {
+		t.Fatalf("Failed to load bitmap: %v", err)
+	}
+	built, err := utils.BuildTombstoneBitmap(testFile)
+	if err != nil {
+		t.Fatalf("Failed to build bitmap: %v", err)
+	}
+	if saved.Slots() != built.Slots() || saved.Count() != built.Count() {
+		t.Fatalf("Expected the saved bitmap to match the file, got %d slots and %d tombstones, want %d and %d",
+			saved.Slots(), saved.Count(), built.Slots(), built.Count())
+	}
+	for slot := 0; slot < built.Slots(); slot++ {
+		if saved.Tombstoned(slot) != built.Tombstoned(slot) {
+			t.Errorf("Slot %d differs from a fresh scan", slot)
+		}
+	}
+
+	// The slot offsets were written along with the bitmap, one per slot after a 12-byte header
+	slots, err := os.ReadFile(utils.TombstoneSlotsPath(testFile))
+	if err != nil {
+		t.Fatalf("Failed to read slot offsets: %v", err)
+	}
+	if len(slots) != 12+8*built.Slots() {
+		t.Errorf("Expected %d slot offsets, got %d bytes", built.Slots(), len(slots))
+	}
+}
+
+func TestTombstoneBitmapNotSavedWhileReadOnly(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "test_tombstone_read_only.bin")
+	defer os.Remove(utils.IndexPathFromBinFile(testFile))
+	defer os.Remove(utils.TombstoneBitmapPath(testFile))
+	defer os.Remove(utils.TombstoneSlotsPath(testFile))
+
+	itemDAO := dao.NewItemDAO(testFile)
+	for _, name := range []string{"Burger", "Fries"} {
+		if _, err := itemDAO.Write(name, 100); err != nil {
+			t.Fatalf("Failed to write item: %v", err)
+		}
+	}
+
+	utils.SetSidecarsReadOnly(true)
+	defer utils.SetSidecarsReadOnly(false)
+	bitmap, err := utils.LoadTombstoneBitmap(testFile)
+	if err != nil || bitmap.Slots() != 2 {
+		t.Fatalf("Expected a bitmap of 2 slots, got %v", err)
+	}
+	if _, err := os.Stat(utils.TombstoneBitmapPath(testFile)); !os.IsNotExist(err) {
+		t.Errorf("Expected no bitmap saved while read-only, got %v", err)
+	}
+
+	utils.SetSidecarsReadOnly(false)
+	if err := bitmap.Flush(testFile); err != nil {
+		t.Fatalf("Failed to flush bitmap: %v", err)
+	}
+	if saved, err := utils.LoadTombstoneBitmap(testFile); err != nil || saved.Slots() != 2 {
+		t.Errorf("Expected the bitmap to be saved whole once writable, got %v", err)
+	}
+}
+
+func TestTombstoneBitmapRecordBytes(t *testing.T) {
+	testFile := filepath.Join(t.TempDir(), "test_tombstone_record_bytes.bin")
+	defer os.Remove(utils.IndexPathFromBinFile(testFile))
+	defer os.Remove(utils.TombstoneBitmapPath(testFile))
+	defer os.Remove(utils.TombstoneSlotsPath(testFile))
+
+	itemDAO := dao.NewItemDAO(testFile)
+	for _, name := range []string{"Burger", "Large Fries", "Soda"} {
+		if _, err := itemDAO.Write(name, 100); err != nil {
+			t.Fatalf("Failed to write item: %v", err)
+		}
+	}
+	if err := itemDAO.Delete(1); err != nil {
+		t.Fatalf("Failed to delete item: %v", err)
+	}
+
+	scanned, err := utils.ReadRecordBytes(testFile, utils.IDKeyFields)
+	if err != nil {
+		t.Fatalf("Failed to scan records: %v", err)
+	}
+	bytes, err := itemDAO.RecordBytes()
+	if err != nil {
+		t.Fatalf("Failed to get record bytes: %v", err)
+	}
+	if bytes != scanned {
+		t.Errorf("Expected the record bytes of a scan %+v, got %+v", scanned, bytes)
+	}
+
+	// A bitmap loaded from its files takes the sizes from the saved slot offsets
+	bitmap, err := utils.LoadTombstoneBitmap(testFile)
+	if err != nil {
+		t.Fatalf("Failed to load bitmap: %v", err)
+	}
+	if bytes, err := bitmap.RecordBytes(testFile); err != nil || bytes != scanned {
+		t.Errorf("Expected the record bytes of a scan %+v, got %+v (err %v)", scanned, bytes, err)
+	}
+
+	// Once the data file changes behind its back the bitmap can't tell
+	if _, err := dao.NewItemDAO(testFile).Write("Water", 50); err != nil {
+		t.Fatalf("Failed to write item: %v", err)
+	}
+	if _, err := bitmap.RecordBytes(testFile); err == nil {
+		t.Error("Expected a stale bitmap to be refused")
+	}
+}
@@ -0,0 +1,86 @@
+package test
+
+import (
+	"BinaryCRUD/backend/utils"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// writeDataFile writes a file of the data directory with its relative path as content
+func writeDataFile(t *testing.T, rel string) string {
+	t.Helper()
+	path := filepath.Join(utils.DataDir, rel)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(rel), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestTrashMoveAndRestore(t *testing.T) {
+	utils.SetDataDir(t.TempDir())
+	defer utils.SetDataDir(utils.DefaultDataDir)
+
+	trash := utils.NewTrash()
+	for _, rel := range []string{"bin/items.bin", "compressed/items.huffman.bin"} {
+		if err := trash.Move(writeDataFile(t, rel)); err != nil {
+			t.Fatalf("failed to move %s to trash: %v", rel, err)
+		}
+	}
+	if err := trash.Move(filepath.Join(t.TempDir(), "outside.bin")); err == nil {
+		t.Error("expected a file outside the data directory to be refused")
+	}
+
+	entries, err := utils.ListTrash()
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("expected 1 trash entry, got %d (err %v)", len(entries), err)
+	}
+	if entries[0].Name != trash.Name() || !reflect.DeepEqual(entries[0].Files, []string{"bin/items.bin", "compressed/items.huffman.bin"}) {
+		t.Errorf("unexpected trash entry %+v", entries[0])
+	}
+
+	// A file created since the deletion is moved aside by the restore
+	writeDataFile(t, "bin/items.bin")
+	restored, replaced, err := utils.RestoreFromTrash(trash.Name())
+	if err != nil {
+		t.Fatalf("failed to restore: %v", err)
+	}
+	if len(restored) != 2 || replaced == "" {
+		t.Errorf("expected 2 files restored and 1 replaced, got %v and %q", restored, replaced)
+	}
+	if _, err := os.Stat(filepath.Join(utils.CompressedDir, "items.huffman.bin")); err != nil {
+		t.Errorf("compressed file not restored: %v", err)
+	}
+	if _, _, err := utils.RestoreFromTrash(trash.Name()); utils.ErrorCodeOf(err) != utils.CodeNotFound {
+		t.Errorf("expected a restored entry to be gone, got %v", err)
+	}
+	if _, _, err := utils.RestoreFromTrash("../bin"); utils.ErrorCodeOf(err) != utils.CodeValidation {
+		t.Errorf("expected an invalid entry name to be refused, got %v", err)
+	}
+}
+
+func TestPurgeTrash(t *testing.T) {
+	utils.SetDataDir(t.TempDir())
+	defer utils.SetDataDir(utils.DefaultDataDir)
+
+	trash := utils.NewTrash()
+	if err := trash.Move(writeDataFile(t, "bin/orders.bin")); err != nil {
+		t.Fatal(err)
+	}
+
+	if purged, err := utils.PurgeTrash(time.Now().Add(-time.Hour)); err != nil || len(purged) != 0 {
+		t.Fatalf("expected a recent entry to be kept, purged %v (err %v)", purged, err)
+	}
+	purged, err := utils.PurgeTrash(time.Now().Add(time.Hour))
+	if err != nil || !reflect.DeepEqual(purged, []string{trash.Name()}) {
+		t.Fatalf("expected the entry to be purged, purged %v (err %v)", purged, err)
+	}
+	if entries, _ := utils.ListTrash(); len(entries) != 0 {
+		t.Errorf("expected an empty trash, got %+v", entries)
+	}
+}
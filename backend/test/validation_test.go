@@ -65,6 +65,40 @@ func TestValidateNameSpecialCharacters(t *testing.T) {
 	}
 }
 
+// ==================== Name Sanitization Tests ====================
+
+func TestSanitizeName(t *testing.T) {
+	cases := map[string]string{
+		"  Cheese Burger  ":     "Cheese Burger",
+		"Cheese \t\n  Burger":   "Cheese Burger",
+		"Cafe\u0301":            "Caf\u00e9",
+		"\u00a0Caf\u00e9\u3000": "Caf\u00e9",
+		"日本語名前":                 "日本語名前",
+		"   ":                   "",
+	}
+	for input, expected := range cases {
+		if got := utils.SanitizeName(input); got != expected {
+			t.Errorf("SanitizeName(%q) = %q, expected %q", input, got, expected)
+		}
+	}
+}
+
+func TestSanitizeNameDisabled(t *testing.T) {
+	defer utils.ApplyConfig(utils.DefaultConfig())
+
+	config := utils.DefaultConfig()
+	config.SanitizeNames = false
+	utils.ApplyConfig(config)
+
+	if got := utils.SanitizeName(" Cafe\u0301 "); got != " Cafe\u0301 " {
+		t.Errorf("Expected the name unchanged, got %q", got)
+	}
+	// Duplicate detection still folds the forms together
+	if utils.NormalizeName(" Cafe\u0301 ") != utils.NormalizeName("caf\u00e9") {
+		t.Error("Expected both forms to normalize to the same key")
+	}
+}
+
 // ==================== Item IDs Validation Tests ====================
 
 func TestValidateItemIDsEmpty(t *testing.T) {
@@ -340,3 +374,48 @@ func TestValidationConstantsAreSensible(t *testing.T) {
 		t.Error("MaxDecompressedSize should be >= MaxRecordSize")
 	}
 }
+
+// ==================== Validation Rule Tests ====================
+
+func TestValidationRules(t *testing.T) {
+	defer utils.ApplyConfig(utils.DefaultConfig())
+
+	config := utils.DefaultConfig()
+	config.Rules = map[string]utils.ValidationRule{
+		"item":  {MaxNameLength: 8, MinPrice: 50, AllowedChars: `\p{L} -`},
+		"order": {MaxItems: 2, MaxPrice: 1000},
+	}
+	utils.ApplyConfig(config)
+
+	if err := utils.ValidateEntityName("item", "Burger"); err != nil {
+		t.Errorf("expected an allowed item name, got %v", err)
+	}
+	if err := utils.ValidateEntityName("item", "Cheeseburger"); utils.ErrorCodeOf(err) != utils.CodeValidation || !strings.Contains(err.Error(), "8") {
+		t.Errorf("expected the item name limit of 8, got %v", err)
+	}
+	if err := utils.ValidateEntityName("item", "Burger #2"); utils.ErrorCodeOf(err) != utils.CodeValidation {
+		t.Errorf("expected characters outside the class to be rejected, got %v", err)
+	}
+	if err := utils.ValidateEntityPrice("item", 10); utils.ErrorCodeOf(err) != utils.CodeValidation {
+		t.Errorf("expected the item minimum price, got %v", err)
+	}
+
+	// Entities without a rule keep the global limits
+	if err := utils.ValidateEntityName("promotion", "Burger #2 with a long name"); err != nil {
+		t.Errorf("expected promotions to keep the global limits, got %v", err)
+	}
+	if err := utils.ValidateEntityItemIDs("order", []uint64{1, 2, 3}); utils.ErrorCodeOf(err) != utils.CodeValidation {
+		t.Errorf("expected the order item cap of 2, got %v", err)
+	}
+	if err := utils.ValidateEntityItemIDs("promotion", []uint64{1, 2, 3}); err != nil {
+		t.Errorf("expected promotions to keep the global item cap, got %v", err)
+	}
+
+	// Records checked on write only see the declared rules
+	if err := utils.CheckRecordRules("order", "Customer", 1500, 1); utils.ErrorCodeOf(err) != utils.CodeValidation {
+		t.Errorf("expected the order total limit, got %v", err)
+	}
+	if err := utils.CheckRecordRules("promotion", "", 0, 0); err != nil {
+		t.Errorf("expected no rule for promotions, got %v", err)
+	}
+}
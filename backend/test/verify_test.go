@@ -0,0 +1,94 @@
+package test
+
+import (
+	"BinaryCRUD/backend/dao"
+	"BinaryCRUD/backend/utils"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestVerifyBinFiles(t *testing.T) {
+	utils.SetDataDir(t.TempDir())
+	defer utils.SetDataDir(utils.DefaultDataDir)
+
+	itemDAO := dao.NewItemDAO(utils.BinPath("items.bin"))
+	for _, name := range []string{"Burger", "Fries", "Soda"} {
+		if _, err := itemDAO.Write(name, 500); err != nil {
+			t.Fatalf("failed to write item: %v", err)
+		}
+	}
+	if err := itemDAO.Delete(1); err != nil {
+		t.Fatalf("failed to delete item: %v", err)
+	}
+	orderDAO := dao.NewOrderDAO(utils.BinPath("orders.bin"))
+	if _, err := orderDAO.Write("Alice", 1000, []uint64{0, 2}); err != nil {
+		t.Fatalf("failed to write order: %v", err)
+	}
+
+	checks, err := utils.VerifyBinFiles()
+	if err != nil {
+		t.Fatalf("verify failed: %v", err)
+	}
+	if len(checks) != 2 {
+		t.Fatalf("expected 2 checked files, got %d", len(checks))
+	}
+	for _, check := range checks {
+		if !check.OK() {
+			t.Errorf("%s: unexpected problems %v", check.File, check.Problems)
+		}
+		if check.File == "items.bin" && (check.Records != 3 || check.Tombstones != 1) {
+			t.Errorf("expected 3 items with 1 tombstone, got %d and %d", check.Records, check.Tombstones)
+		}
+	}
+}
+
+func TestVerifyBinFileDetectsDamage(t *testing.T) {
+	utils.SetDataDir(t.TempDir())
+	defer utils.SetDataDir(utils.DefaultDataDir)
+
+	path := utils.BinPath("items.bin")
+	itemDAO := dao.NewItemDAO(path)
+	if _, err := itemDAO.Write("Burger", 500); err != nil {
+		t.Fatalf("failed to write item: %v", err)
+	}
+
+	// A partial record length after the last record
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatalf("failed to open items: %v", err)
+	}
+	file.Write([]byte{0x00})
+	file.Close()
+
+	check, err := utils.VerifyBinFile(path)
+	if err != nil {
+		t.Fatalf("verify failed: %v", err)
+	}
+	if check.OK() {
+		t.Error("expected trailing bytes to be reported")
+	}
+
+	// A header whose counts do not match the records
+	other := filepath.Join(utils.BinDir, "orders.bin")
+	orderDAO := dao.NewOrderDAO(other)
+	if _, err := orderDAO.Write("Alice", 500, []uint64{0}); err != nil {
+		t.Fatalf("failed to write order: %v", err)
+	}
+	file, err = os.OpenFile(other, os.O_RDWR, 0644)
+	if err != nil {
+		t.Fatalf("failed to open orders: %v", err)
+	}
+	if err := utils.UpdateHeader(file, 5, 0, 1); err != nil {
+		t.Fatalf("failed to update header: %v", err)
+	}
+	file.Close()
+
+	check, err = utils.VerifyBinFile(other)
+	if err != nil {
+		t.Fatalf("verify failed: %v", err)
+	}
+	if check.OK() {
+		t.Error("expected the record count mismatch to be reported")
+	}
+}
@@ -0,0 +1,199 @@
+package test
+
+import (
+	"BinaryCRUD/backend/events"
+	"BinaryCRUD/backend/utils"
+	"BinaryCRUD/backend/webhook"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// webhookReceiver records the requests posted to a test server
+type webhookReceiver struct {
+	mu       sync.Mutex
+	statuses []int // status to answer for each request, 200 once exhausted
+	requests []*http.Request
+	bodies   [][]byte
+	received chan struct{}
+}
+
+func newWebhookReceiver(t *testing.T, statuses ...int) (*webhookReceiver, *httptest.Server) {
+	receiver := &webhookReceiver{statuses: statuses, received: make(chan struct{}, 16)}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		receiver.mu.Lock()
+		status := http.StatusOK
+		if len(receiver.requests) < len(receiver.statuses) {
+			status = receiver.statuses[len(receiver.requests)]
+		}
+		receiver.requests = append(receiver.requests, r)
+		receiver.bodies = append(receiver.bodies, body)
+		receiver.mu.Unlock()
+		w.WriteHeader(status)
+		receiver.received <- struct{}{}
+	}))
+	t.Cleanup(server.Close)
+	return receiver, server
+}
+
+// wait blocks until n requests arrived
+func (r *webhookReceiver) wait(t *testing.T, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		select {
+		case <-r.received:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for webhook request %d", i+1)
+		}
+	}
+}
+
+func TestWebhookDeliversSignedPayload(t *testing.T) {
+	receiver, server := newWebhookReceiver(t)
+	dispatcher := webhook.NewDispatcher([]utils.Webhook{{URL: server.URL, Secret: "s3cret"}}, func(string) {})
+	defer dispatcher.Close()
+
+	dispatcher.Send(webhook.Payload{
+		Event: events.Event{Type: events.OrderCreated, ID: 4},
+		Data:  map[string]any{"customerName": "Alice"},
+	})
+	receiver.wait(t, 1)
+
+	req, body := receiver.requests[0], receiver.bodies[0]
+	if req.Header.Get(webhook.EventHeader) != "OrderCreated" {
+		t.Errorf("unexpected event header %q", req.Header.Get(webhook.EventHeader))
+	}
+	if req.Header.Get(webhook.SignatureHeader) != "sha256="+webhook.Sign(body, "s3cret") {
+		t.Error("signature does not match the body")
+	}
+
+	var payload map[string]any
+	if err := json.Unmarshal(body, &payload); err != nil {
+		t.Fatalf("invalid payload: %v", err)
+	}
+	data, _ := payload["data"].(map[string]any)
+	if payload["type"] != "OrderCreated" || payload["id"] != 4.0 || data["customerName"] != "Alice" {
+		t.Errorf("unexpected payload %s", body)
+	}
+}
+
+func TestWebhookRetriesWithBackoff(t *testing.T) {
+	receiver, server := newWebhookReceiver(t, http.StatusInternalServerError, http.StatusServiceUnavailable)
+	dispatcher := webhook.NewDispatcher([]utils.Webhook{{URL: server.URL}}, func(string) {})
+	dispatcher.Backoff = 10 * time.Millisecond
+	defer dispatcher.Close()
+
+	dispatcher.Send(webhook.Payload{Event: events.Event{Type: events.ItemCreated, ID: 1}})
+	receiver.wait(t, 3)
+
+	select {
+	case <-receiver.received:
+		t.Error("expected no request after the successful retry")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestWebhookGivesUpAfterMaxAttempts(t *testing.T) {
+	receiver, server := newWebhookReceiver(t, 500, 500, 500, 500, 500)
+	var mu sync.Mutex
+	var logs []string
+	dispatcher := webhook.NewDispatcher([]utils.Webhook{{URL: server.URL}}, func(message string) {
+		mu.Lock()
+		logs = append(logs, message)
+		mu.Unlock()
+	})
+	dispatcher.Backoff = time.Millisecond
+	dispatcher.MaxAttempts = 2
+	defer dispatcher.Close()
+
+	dispatcher.Send(webhook.Payload{Event: events.Event{Type: events.ItemDeleted, ID: 1}})
+	receiver.wait(t, 2)
+	time.Sleep(50 * time.Millisecond)
+
+	receiver.mu.Lock()
+	count := len(receiver.requests)
+	receiver.mu.Unlock()
+	if count != 2 {
+		t.Errorf("expected 2 attempts, got %d", count)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(logs) != 2 {
+		t.Errorf("expected a retry and a give-up message, got %v", logs)
+	}
+}
+
+func TestWebhookSlowHookDoesNotBlockOthers(t *testing.T) {
+	release := make(chan struct{})
+	stalled := make(chan struct{}, 1)
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		stalled <- struct{}{}
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	t.Cleanup(slow.Close)
+	t.Cleanup(func() { close(release) })
+	receiver, fast := newWebhookReceiver(t)
+
+	dispatcher := webhook.NewDispatcher([]utils.Webhook{{URL: slow.URL}, {URL: fast.URL}}, func(string) {})
+	dispatcher.Send(webhook.Payload{Event: events.Event{Type: events.OrderCreated, ID: 1}})
+	dispatcher.Send(webhook.Payload{Event: events.Event{Type: events.OrderCreated, ID: 2}})
+
+	// The second hook receives both events while the first is stuck on the first one
+	select {
+	case <-stalled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the slow webhook")
+	}
+	receiver.wait(t, 2)
+
+	// Close cancels the stuck request instead of waiting for it to time out
+	start := time.Now()
+	dispatcher.Close()
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("expected Close to return promptly, took %s", elapsed)
+	}
+}
+
+func TestWebhookEventFilter(t *testing.T) {
+	defaults := webhook.NewDispatcher([]utils.Webhook{{URL: "http://localhost:1"}}, func(string) {})
+	defer defaults.Close()
+	if !defaults.Wants(events.OrderCreated) || !defaults.Wants(events.PromotionApplied) {
+		t.Error("expected creates and promotion links by default")
+	}
+	if defaults.Wants(events.ItemUpdated) || defaults.Wants(events.Compacted) {
+		t.Error("expected updates and maintenance events to be opt-in")
+	}
+
+	custom := webhook.NewDispatcher([]utils.Webhook{{URL: "http://localhost:1", Events: []events.Type{events.Compacted}}}, func(string) {})
+	defer custom.Close()
+	if !custom.Wants(events.Compacted) || custom.Wants(events.OrderCreated) {
+		t.Error("expected only the listed events")
+	}
+}
+
+func TestWebhookConfigValidation(t *testing.T) {
+	config := utils.DefaultConfig()
+	config.Webhooks = []utils.Webhook{{URL: "https://example.com/hook", Events: []events.Type{events.OrderCreated}}}
+	if err := config.Validate(); err != nil {
+		t.Errorf("expected a valid config: %v", err)
+	}
+
+	for _, hook := range []utils.Webhook{
+		{URL: "ftp://example.com"},
+		{URL: "example.com/hook"},
+		{URL: "https://example.com", Events: []events.Type{"OrderShipped"}},
+	} {
+		config.Webhooks = []utils.Webhook{hook}
+		if err := config.Validate(); err == nil {
+			t.Errorf("expected %+v to be rejected", hook)
+		}
+	}
+}
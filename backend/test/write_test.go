@@ -74,15 +74,17 @@ func TestWriteHeader(t *testing.T) {
 		t.Errorf("unexpected error: %v", err)
 	}
 
-	// Expected format: [magic(4)][filenameLen(1)][filename(N)][entitiesCount(4)][tombstoneCount(4)][nextId(4)]
-	// For "test.bin" (8 bytes): 4 + 1 + 8 + 4 + 4 + 4 = 25 bytes
+	// Expected format: [magic(4)][filenameLen(1)][filename(N)][entitiesCount(4)][tombstoneCount(4)][nextId(8)][flags(1)][idSize(1)]
+	// For "test.bin" (8 bytes): 4 + 1 + 8 + 4 + 4 + 8 + 1 + 1 = 31 bytes
 	expected := []byte{
-		'B', 'D', 'A', 'T', // magic
+		'B', 'D', 'V', 0x05, // magic (format version 5)
 		8,                            // filename length
 		't', 'e', 's', 't', '.', 'b', 'i', 'n', // filename
 		0x00, 0x00, 0x00, 0x01, // entitiesCount = 1
 		0x00, 0x00, 0x00, 0x02, // tombstoneCount = 2
-		0x00, 0x00, 0x00, 0x03, // nextId = 3
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x03, // nextId = 3
+		0x00,                   // flags = none
+		0x04,                   // idSize = 4
 	}
 	if !bytes.Equal(result, expected) {
 		t.Errorf("expected %v, got %v", expected, result)
@@ -96,14 +98,16 @@ func TestWriteHeaderWithZeros(t *testing.T) {
 		t.Errorf("unexpected error: %v", err)
 	}
 
-	// Expected format: [magic(4)][filenameLen(1)][filename(0)][entitiesCount(4)][tombstoneCount(4)][nextId(4)]
-	// For empty filename: 4 + 1 + 0 + 4 + 4 + 4 = 17 bytes
+	// Expected format: [magic(4)][filenameLen(1)][filename(0)][entitiesCount(4)][tombstoneCount(4)][nextId(8)][flags(1)][idSize(1)]
+	// For empty filename: 4 + 1 + 0 + 4 + 4 + 8 + 1 + 1 = 23 bytes
 	expected := []byte{
-		'B', 'D', 'A', 'T', // magic
+		'B', 'D', 'V', 0x05, // magic (format version 5)
 		0,                  // filename length = 0
 		0x00, 0x00, 0x00, 0x00, // entitiesCount = 0
 		0x00, 0x00, 0x00, 0x00, // tombstoneCount = 0
-		0x00, 0x00, 0x00, 0x00, // nextId = 0
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // nextId = 0
+		0x00,                   // flags = none
+		0x04,                   // idSize = 4
 	}
 	if !bytes.Equal(result, expected) {
 		t.Errorf("expected %v, got %v", expected, result)
@@ -117,16 +121,18 @@ func TestWriteHeaderWithLargeValues(t *testing.T) {
 		t.Errorf("unexpected error: %v", err)
 	}
 
-	// Expected format: [magic(4)][filenameLen(1)][filename(N)][entitiesCount(4)][tombstoneCount(4)][nextId(4)]
+	// Expected format: [magic(4)][filenameLen(1)][filename(N)][entitiesCount(4)][tombstoneCount(4)][nextId(8)][flags(1)][idSize(1)]
 	// 100 = 0x64, 50 = 0x32, 200 = 0xC8
-	// For "data.bin" (8 bytes): 4 + 1 + 8 + 4 + 4 + 4 = 25 bytes
+	// For "data.bin" (8 bytes): 4 + 1 + 8 + 4 + 4 + 8 + 1 + 1 = 31 bytes
 	expected := []byte{
-		'B', 'D', 'A', 'T', // magic
+		'B', 'D', 'V', 0x05, // magic (format version 5)
 		8,                            // filename length
 		'd', 'a', 't', 'a', '.', 'b', 'i', 'n', // filename
 		0x00, 0x00, 0x00, 0x64, // entitiesCount = 100
 		0x00, 0x00, 0x00, 0x32, // tombstoneCount = 50
-		0x00, 0x00, 0x00, 0xc8, // nextId = 200
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xc8, // nextId = 200
+		0x00,                   // flags = none
+		0x04,                   // idSize = 4
 	}
 	if !bytes.Equal(result, expected) {
 		t.Errorf("expected %v, got %v", expected, result)
@@ -0,0 +1,159 @@
+package test
+
+import (
+	"BinaryCRUD/backend/compression"
+	"BinaryCRUD/backend/utils"
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestZstdCompressDecompress(t *testing.T) {
+	zc := compression.NewZstdCompressor()
+
+	original := bytes.Repeat([]byte("Hello, World! This is a test of zstd compression. "), 20)
+
+	compressed, err := zc.Compress(original)
+	if err != nil {
+		t.Fatalf("Compression failed: %v", err)
+	}
+	if !bytes.HasPrefix(compressed, compression.ZstdMagic) {
+		t.Errorf("Expected zstd magic bytes, got %x", compressed[:4])
+	}
+	if len(compressed) >= len(original) {
+		t.Errorf("Compressed size (%d) should be smaller than original (%d)", len(compressed), len(original))
+	}
+
+	decompressed, err := zc.Decompress(compressed)
+	if err != nil {
+		t.Fatalf("Decompression failed: %v", err)
+	}
+	if !bytes.Equal(original, decompressed) {
+		t.Errorf("Decompressed data doesn't match original")
+	}
+}
+
+func TestZstdFileReadableByReferenceDecoder(t *testing.T) {
+	dir := t.TempDir()
+	inputPath := filepath.Join(dir, "items.bin")
+	outputPath := filepath.Join(dir, utils.CompressedFilename("items.bin", compression.AlgorithmZstd))
+	original := bytes.Repeat([]byte{0x00, 0x0B, 'B', 'u', 'r', 'g', 'e', 'r'}, 50)
+	if err := os.WriteFile(inputPath, original, 0644); err != nil {
+		t.Fatalf("failed to write input: %v", err)
+	}
+
+	compressor, err := compression.NewCompressor(utils.DetectCompressionAlgorithm(filepath.Base(outputPath)))
+	if err != nil {
+		t.Fatalf("failed to create compressor: %v", err)
+	}
+	if err := compressor.CompressFile(inputPath, outputPath); err != nil {
+		t.Fatalf("CompressFile failed: %v", err)
+	}
+
+	compressed, err := os.ReadFile(outputPath)
+	if err != nil {
+		t.Fatalf("failed to read output: %v", err)
+	}
+	var header zstd.Header
+	if err := header.Decode(compressed); err != nil {
+		t.Fatalf("output is not a zstd frame: %v", err)
+	}
+	if !header.HasFCS || header.FrameContentSize != uint64(len(original)) {
+		t.Errorf("expected the frame to record size %d, got %d (recorded %v)", len(original), header.FrameContentSize, header.HasFCS)
+	}
+
+	decoder, err := zstd.NewReader(nil)
+	if err != nil {
+		t.Fatalf("failed to create decoder: %v", err)
+	}
+	defer decoder.Close()
+	data, err := decoder.DecodeAll(compressed, nil)
+	if err != nil {
+		t.Fatalf("failed to decode zstd frame: %v", err)
+	}
+	if !bytes.Equal(data, original) {
+		t.Error("zstd frame doesn't match original")
+	}
+	if name := utils.DecompressedFilename(filepath.Base(outputPath)); name != "items.bin" {
+		t.Errorf("expected items.bin, got %s", name)
+	}
+}
+
+func TestZstdRejectsInvalidData(t *testing.T) {
+	zc := compression.NewZstdCompressor()
+
+	if _, err := zc.Decompress([]byte("LZWW not zstd")); err == nil {
+		t.Error("expected non-zstd data to fail")
+	}
+	if _, err := zc.Decompress(append(append([]byte{}, compression.ZstdMagic...), "garbage"...)); err == nil {
+		t.Error("expected a corrupt frame to fail")
+	}
+	if _, err := zc.Compress(nil); err == nil {
+		t.Error("expected empty data to fail")
+	}
+}
+
+func TestZstdCompressorLevel(t *testing.T) {
+	for _, level := range []int{0, 5} {
+		if _, err := compression.NewZstdCompressorLevel(level); err == nil {
+			t.Errorf("expected level %d to be rejected", level)
+		}
+	}
+
+	data := benchmarkData()
+	for level := int(zstd.SpeedFastest); level <= int(zstd.SpeedBestCompression); level++ {
+		compressor, err := compression.NewZstdCompressorLevel(level)
+		if err != nil {
+			t.Fatalf("failed to create compressor at level %d: %v", level, err)
+		}
+		compressed, err := compressor.Compress(data)
+		if err != nil {
+			t.Fatalf("level %d: compression failed: %v", level, err)
+		}
+		decompressed, err := compressor.Decompress(compressed)
+		if err != nil {
+			t.Fatalf("level %d: decompression failed: %v", level, err)
+		}
+		if !bytes.Equal(data, decompressed) {
+			t.Errorf("level %d: decompressed data doesn't match original", level)
+		}
+	}
+}
+
+func TestDetectAlgorithmFromMagicBytes(t *testing.T) {
+	dir := t.TempDir()
+	data := benchmarkData()
+
+	for _, algorithm := range compression.Algorithms() {
+		compressor, err := compression.NewCompressor(algorithm)
+		if err != nil {
+			t.Fatalf("failed to create compressor: %v", err)
+		}
+		compressed, err := compressor.Compress(data)
+		if err != nil {
+			t.Fatalf("%s: compression failed: %v", algorithm, err)
+		}
+		if got := compression.DetectAlgorithm(compressed); got != algorithm {
+			t.Errorf("expected %s data to be detected, got %s", algorithm, got)
+		}
+
+		// A file without the algorithm in its name is still recognised by its contents
+		path := filepath.Join(dir, "renamed."+algorithm)
+		if err := os.WriteFile(path, compressed, 0644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+		if got, err := compression.DetectFileAlgorithm(path); err != nil || got != algorithm {
+			t.Errorf("expected %s file to be detected, got %s (err %v)", algorithm, got, err)
+		}
+	}
+
+	if got := compression.DetectAlgorithm([]byte("plain")); got != utils.AlgorithmUnknown {
+		t.Errorf("expected unknown data, got %s", got)
+	}
+	if got := utils.DetectCompressionAlgorithm("items.bin.zstd.compressed"); got != utils.AlgorithmZstd {
+		t.Errorf("expected zstd from the filename, got %s", got)
+	}
+}
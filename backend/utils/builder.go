@@ -0,0 +1,22 @@
+package utils
+
+// BuildItemEntry builds an item entry without ID and tombstone with ItemCodec
+// Format: [nameLength(2)][name...][price(4)]
+func BuildItemEntry(name string, priceInCents uint64) ([]byte, error) {
+	return ItemCodec.Encode(&Item{Name: name, Price: priceInCents}, IDSize)
+}
+
+// BuildCollectionEntry builds a collection entry without ID, tombstone, extensions or compression
+// for a file with the configured IDSize
+// The name is stored as given, callers encrypt it beforehand
+// Format: [nameLength(2)][name...][totalPrice(4)][itemCount(4)][itemIDs...]
+func BuildCollectionEntry(name []byte, totalPrice uint64, itemIDs []uint64) ([]byte, error) {
+	return encodeCollectionFields(name, totalPrice, itemIDs, IDSize)
+}
+
+// BuildOrderPromotionEntry builds an active order-promotion entry for AppendEntryManual
+// to a file with the configured IDSize
+// Format: [orderID(IDSize)][promotionID(IDSize)][tombstone(1)]
+func BuildOrderPromotionEntry(orderID, promotionID uint64) ([]byte, error) {
+	return OrderPromotionCodec.Encode(&OrderPromotion{OrderID: orderID, PromotionID: promotionID}, IDSize)
+}
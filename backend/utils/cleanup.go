@@ -11,20 +11,32 @@ type FolderCleanupResult struct {
 	Count  int
 }
 
-// CleanupDataFiles deletes all generated data files (bin, indexes, compressed, keys)
+// CleanupDataFiles deletes all generated data files (bin, indexes, compressed, keys, oplog)
 // but preserves seed data. Returns per-folder results.
 func CleanupDataFiles(log LogFunc) ([]FolderCleanupResult, error) {
+	return cleanupDataFiles(func(path string) error { return RemoveFile(path, log) })
+}
+
+// TrashDataFiles moves the files deleted by CleanupDataFiles into trash instead
+// The keys are trashed with the data, since encrypted names cannot be read without them
+func TrashDataFiles(trash *Trash) ([]FolderCleanupResult, error) {
+	return cleanupDataFiles(trash.Move)
+}
+
+// cleanupDataFiles calls remove on every generated data file
+func cleanupDataFiles(remove func(path string) error) ([]FolderCleanupResult, error) {
 	foldersToClean := []string{
 		BinDir,
 		IndexDir,
 		CompressedDir,
 		KeysDir,
+		OplogDir,
 	}
 
 	results := make([]FolderCleanupResult, 0, len(foldersToClean))
 
 	for _, folder := range foldersToClean {
-		count, err := cleanFolder(folder, log)
+		count, err := cleanFolder(folder, remove)
 		if err != nil {
 			return results, err
 		}
@@ -60,7 +72,7 @@ func CleanupTestFiles(prefix string) error {
 }
 
 // cleanFolder removes all files (not directories) from a folder
-func cleanFolder(folder string, log LogFunc) (int, error) {
+func cleanFolder(folder string, remove func(path string) error) (int, error) {
 	// Check if folder exists
 	if _, err := os.Stat(folder); os.IsNotExist(err) {
 		return 0, nil
@@ -78,7 +90,7 @@ func cleanFolder(folder string, log LogFunc) (int, error) {
 		}
 
 		filePath := filepath.Join(folder, entry.Name())
-		if err := RemoveFile(filePath, log); err == nil {
+		if err := remove(filePath); err == nil {
 			count++
 		}
 	}
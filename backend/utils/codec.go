@@ -0,0 +1,339 @@
+package utils
+
+import (
+	"fmt"
+	"time"
+)
+
+// Codec encodes and decodes the entries of one entity type, so each record layout is defined in this file only
+// Encode returns the entry that follows the ID and tombstone, which are added when the record is written;
+// Decode parses a whole entry as read from the file, without the record length prefix
+// IDs, including the item IDs of collections, are idSize bytes wide as recorded in the file header
+type Codec[T any] interface {
+	Encode(record *T, idSize int) ([]byte, error)
+	Decode(entryData []byte, idSize int) (*T, error)
+}
+
+// Codecs of the stored entities, shared by the DAOs, compaction, oplog replay and verification
+var (
+	ItemCodec           Codec[Item]           = itemCodec{}
+	CollectionCodec     Codec[Collection]     = collectionCodec{}
+	OrderPromotionCodec Codec[OrderPromotion] = orderPromotionCodec{}
+)
+
+// itemCodec stores items as [ID(idSize)][tombstone(1)][nameLength(2)][name...][price(4)][extensions...]
+type itemCodec struct{}
+
+// Encode builds an item entry without ID and tombstone
+func (itemCodec) Encode(item *Item, _ int) ([]byte, error) {
+	// Name size (2 bytes - supports names up to 65535 chars)
+	nameSizeBytes, err := WriteFixedNumber(2, uint64(len(item.Name)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to write name size: %w", err)
+	}
+
+	// Name (variable length)
+	nameBytes, err := WriteVariable(item.Name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write name: %w", err)
+	}
+
+	// Price (4 bytes - supports prices up to 4,294,967,295 cents)
+	priceBytes, err := WriteFixedNumber(4, item.Price)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write price: %w", err)
+	}
+
+	extensionBytes, err := EncodeExtensions(item.Extensions)
+	if err != nil {
+		return nil, err
+	}
+
+	return CombineBytes(nameSizeBytes, nameBytes, priceBytes, extensionBytes), nil
+}
+
+// Decode parses an item entry
+func (itemCodec) Decode(entryData []byte, idSize int) (*Item, error) {
+	parseOffset := 0
+
+	// Read ID
+	entryID, parseOffset, err := ReadFixedNumber(idSize, entryData, parseOffset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ID: %w", err)
+	}
+
+	// Read tombstone byte
+	if parseOffset >= len(entryData) {
+		return nil, fmt.Errorf("entry too short for tombstone")
+	}
+	tombstone := entryData[parseOffset]
+	parseOffset += TombstoneSize
+
+	item, err := DecodeItemFields(entryID, entryData[parseOffset:])
+	if err != nil {
+		return nil, err
+	}
+	item.Tombstone = tombstone
+	return item, nil
+}
+
+// DecodeItemFields parses the fields of an active item stored after its ID and tombstone
+func DecodeItemFields(id uint64, data []byte) (*Item, error) {
+	// Read name size
+	nameSize, parseOffset, err := ReadFixedNumber(2, data, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read name size: %w", err)
+	}
+
+	// Read name
+	name, parseOffset, err := ReadFixedString(int(nameSize), data, parseOffset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read name: %w", err)
+	}
+
+	// Read price
+	price, parseOffset, err := ReadFixedNumber(4, data, parseOffset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read price: %w", err)
+	}
+
+	// Read optional extension trailer
+	extensions, err := DecodeExtensions(data[parseOffset:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to read extensions: %w", err)
+	}
+
+	return &Item{
+		ID:         id,
+		Name:       name,
+		Price:      price,
+		Extensions: extensions,
+	}, nil
+}
+
+// ItemPriceOffset returns the offset of the price field within an item entry
+func ItemPriceOffset(entryData []byte, idSize int) (int, error) {
+	nameSize, parseOffset, err := ReadFixedNumber(2, entryData, idSize+TombstoneSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read name size: %w", err)
+	}
+	priceOffset := parseOffset + int(nameSize)
+	if priceOffset+4 > len(entryData) {
+		return 0, fmt.Errorf("entry too short for price")
+	}
+	return priceOffset, nil
+}
+
+// collectionCodec stores orders and promotions as
+// [ID(idSize)][tombstone(1)][nameLength(2)][name...][totalPrice(4)][itemCount(4)][itemIDs(idSize each)...][extensions...]
+// The name is stored as given, callers encrypt it beforehand; large entries are compressed
+type collectionCodec struct{}
+
+// Encode builds a collection entry without ID and tombstone, compressed when record compression applies
+func (collectionCodec) Encode(c *Collection, idSize int) ([]byte, error) {
+	entry, err := encodeCollectionFields([]byte(c.OwnerOrName), c.TotalPrice, c.ItemIDs, idSize)
+	if err != nil {
+		return nil, err
+	}
+	extensionBytes, err := EncodeExtensions(c.Extensions)
+	if err != nil {
+		return nil, err
+	}
+	return CompressEntry(append(entry, extensionBytes...))
+}
+
+// encodeCollectionFields builds the fixed fields of a collection entry, before extensions and compression
+func encodeCollectionFields(name []byte, totalPrice uint64, itemIDs []uint64, idSize int) ([]byte, error) {
+	nameSizeBytes, err := WriteFixedNumber(2, uint64(len(name)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to write name size: %w", err)
+	}
+
+	totalPriceBytes, err := WriteFixedNumber(4, totalPrice)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write total price: %w", err)
+	}
+
+	itemCountBytes, err := WriteFixedNumber(4, uint64(len(itemIDs)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to write item count: %w", err)
+	}
+
+	entry := CombineBytes(nameSizeBytes, name, totalPriceBytes, itemCountBytes)
+
+	// Item IDs (idSize bytes each)
+	for _, itemID := range itemIDs {
+		itemIDBytes, err := WriteFixedNumber(idSize, itemID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to write item ID: %w", err)
+		}
+		entry = append(entry, itemIDBytes...)
+	}
+
+	return entry, nil
+}
+
+// Decode parses a collection entry, decompressing it first when needed
+func (collectionCodec) Decode(entryData []byte, idSize int) (*Collection, error) {
+	entryData, err := ExpandEntry(entryData, idSize)
+	if err != nil {
+		return nil, err
+	}
+
+	parseOffset := 0
+
+	// Read ID
+	entryID, parseOffset, err := ReadFixedNumber(idSize, entryData, parseOffset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ID: %w", err)
+	}
+
+	// Read tombstone byte
+	if parseOffset >= len(entryData) {
+		return nil, fmt.Errorf("entry too short for tombstone")
+	}
+	tombstone := entryData[parseOffset]
+	parseOffset += TombstoneSize
+
+	// Read name size
+	nameSize, parseOffset, err := ReadFixedNumber(2, entryData, parseOffset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read name size: %w", err)
+	}
+
+	// Read name
+	ownerOrName, parseOffset, err := ReadFixedString(int(nameSize), entryData, parseOffset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read name: %w", err)
+	}
+
+	// Read total price
+	totalPrice, parseOffset, err := ReadFixedNumber(4, entryData, parseOffset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read total price: %w", err)
+	}
+
+	// Read item count
+	itemCount, parseOffset, err := ReadFixedNumber(4, entryData, parseOffset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read item count: %w", err)
+	}
+
+	// Read item IDs (idSize bytes each)
+	itemIDs := make([]uint64, itemCount)
+	for i := uint64(0); i < itemCount; i++ {
+		itemID, newOffset, err := ReadFixedNumber(idSize, entryData, parseOffset)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read item ID %d: %w", i, err)
+		}
+		itemIDs[i] = itemID
+		parseOffset = newOffset
+	}
+
+	// Read optional extension trailer
+	extensions, err := DecodeExtensions(entryData[parseOffset:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to read extensions: %w", err)
+	}
+
+	return &Collection{
+		ID:          entryID,
+		OwnerOrName: ownerOrName,
+		TotalPrice:  totalPrice,
+		ItemCount:   itemCount,
+		ItemIDs:     itemIDs,
+		Tombstone:   tombstone,
+		Extensions:  extensions,
+	}, nil
+}
+
+// orderPromotionCodec stores relationships as [orderID(idSize)][promotionID(idSize)][tombstone(1)],
+// followed by [appliedAt(8)][discountAmount(4)] when the link records them (unix nanoseconds and cents)
+// Composite key entries have no ID field, so Encode returns the whole entry including the tombstone
+type orderPromotionCodec struct{}
+
+// Encode builds an order-promotion entry for AppendEntryManual
+func (orderPromotionCodec) Encode(op *OrderPromotion, idSize int) ([]byte, error) {
+	orderIDBytes, err := WriteFixedNumber(idSize, op.OrderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write order ID: %w", err)
+	}
+
+	promotionIDBytes, err := WriteFixedNumber(idSize, op.PromotionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write promotion ID: %w", err)
+	}
+
+	if op.AppliedAt.IsZero() && op.DiscountAmount == 0 {
+		return CombineBytes(orderIDBytes, promotionIDBytes, []byte{op.Tombstone}), nil
+	}
+
+	// Metadata, only written when known so links without it keep their original size
+	appliedAt := uint64(0)
+	if !op.AppliedAt.IsZero() {
+		appliedAt = uint64(op.AppliedAt.UnixNano())
+	}
+	appliedAtBytes, err := WriteFixedNumber(8, appliedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write applied-at time: %w", err)
+	}
+	discountBytes, err := WriteFixedNumber(4, op.DiscountAmount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write discount amount: %w", err)
+	}
+
+	return CombineBytes(orderIDBytes, promotionIDBytes, []byte{op.Tombstone}, appliedAtBytes, discountBytes), nil
+}
+
+// Decode parses an order-promotion entry
+func (orderPromotionCodec) Decode(entryData []byte, idSize int) (*OrderPromotion, error) {
+	if len(entryData) < idSize*2+TombstoneSize {
+		return nil, fmt.Errorf("entry too short: expected at least %d bytes, got %d", idSize*2+TombstoneSize, len(entryData))
+	}
+
+	offset := 0
+
+	// Read orderID
+	orderID, newOffset, err := ReadFixedNumber(idSize, entryData, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read order ID: %w", err)
+	}
+	offset = newOffset
+
+	// Read promotionID
+	promotionID, newOffset, err := ReadFixedNumber(idSize, entryData, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read promotion ID: %w", err)
+	}
+	offset = newOffset
+
+	// Read tombstone
+	if offset >= len(entryData) {
+		return nil, fmt.Errorf("entry too short for tombstone")
+	}
+	tombstone := entryData[offset]
+	offset += TombstoneSize
+
+	link := &OrderPromotion{
+		OrderID:     orderID,
+		PromotionID: promotionID,
+		Tombstone:   tombstone,
+	}
+
+	// Read the metadata trailer, absent from links written before it was recorded
+	if len(entryData) >= offset+LinkMetadataSize {
+		appliedAt, newOffset, err := ReadFixedNumber(8, entryData, offset)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read applied-at time: %w", err)
+		}
+		discount, _, err := ReadFixedNumber(4, entryData, newOffset)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read discount amount: %w", err)
+		}
+		if appliedAt != 0 {
+			link.AppliedAt = time.Unix(0, int64(appliedAt)).UTC()
+		}
+		link.DiscountAmount = discount
+	}
+	return link, nil
+}
@@ -1,7 +1,10 @@
 package utils
 
 import (
+	"BinaryCRUD/backend/index"
+	"context"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 )
@@ -15,15 +18,71 @@ type CompactResult struct {
 	PromotionsRemoved        int      // Number of tombstoned promotions physically removed
 	OrderPromotionsRemoved   int      // Number of tombstoned order-promotions physically removed
 	DeletedItemIDs           []uint64 // IDs of items that were removed
+	RemovedOrderIDs          []uint64 // IDs of deleted orders removed, without the older versions of updated ones
+	RemovedPromotionIDs      []uint64 // IDs of deleted promotions removed, without the older versions of updated ones
+	AffectedOrderIDs         []uint64 // IDs of orders that had item references cleaned
+	AffectedPromotionIDs     []uint64 // IDs of promotions that had item references cleaned
+	ReclaimedBytes           int64    // size of the rewritten files subtracted from the size of the originals
 }
 
+// ItemPriceFunc returns the price of an item as counted in collection totals
+type ItemPriceFunc func(item *Item) (uint64, error)
+
 // CompactAll performs compaction on all binary files:
 // 1. Identifies tombstoned items
 // 2. Removes tombstoned items from items.bin
-// 3. Updates orders/promotions to remove references to deleted items
+// 3. Updates orders/promotions to remove references to deleted items and recalculates their totals
 // 4. Removes tombstoned orders/promotions/order_promotions
-// 5. Deletes all index files (they will be rebuilt on next DAO init)
-func CompactAll(itemsPath, ordersPath, promotionsPath, orderPromotionsPath string) (*CompactResult, error) {
+// 5. Deletes all index files and tombstone bitmaps (they will be rebuilt on next DAO init and first active scan)
+// Rewritten files are staged and only replace the originals once all of them were written,
+// so a failure leaves every file untouched
+// Files in the bin directory are staged as their next generation and switched to through the
+// manifest, so readers never find a file missing; elsewhere they are staged as .tmp and renamed
+// price converts item prices for the recalculated totals; nil uses the stored price
+// ctx is checked between records: a cancelled compaction stops before replacing any file and returns ctx.Err()
+func CompactAll(ctx context.Context, itemsPath, ordersPath, promotionsPath, orderPromotionsPath string, price ItemPriceFunc) (*CompactResult, error) {
+	result, err := CompactFiles(ctx, itemsPath, ordersPath, promotionsPath, orderPromotionsPath, price)
+	if err != nil {
+		return nil, err
+	}
+
+	// Step 5: Delete all index files
+	if err := deleteAllIndexes(); err != nil {
+		return nil, fmt.Errorf("failed to delete indexes: %w", err)
+	}
+
+	return result, nil
+}
+
+// CompactFiles performs steps 1-4 of CompactAll without touching any index
+func CompactFiles(ctx context.Context, itemsPath, ordersPath, promotionsPath, orderPromotionsPath string, price ItemPriceFunc) (*CompactResult, error) {
+	stage := &compactionStage{}
+	defer stage.discard()
+
+	result, err := compactFiles(ctx, itemsPath, ordersPath, promotionsPath, orderPromotionsPath, price, stage)
+	if err != nil {
+		return nil, err
+	}
+
+	// Replace all rewritten files at once, unless the compaction was cancelled meanwhile
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := stage.commit(); err != nil {
+		return nil, fmt.Errorf("failed to replace compacted files: %w", err)
+	}
+
+	return result, nil
+}
+
+// PreviewCompaction runs the analysis of CompactFiles without writing anything
+// The rewritten files are only measured, so the result is what CompactAll would report on the same files
+func PreviewCompaction(ctx context.Context, itemsPath, ordersPath, promotionsPath, orderPromotionsPath string, price ItemPriceFunc) (*CompactResult, error) {
+	return compactFiles(ctx, itemsPath, ordersPath, promotionsPath, orderPromotionsPath, price, &compactionStage{dryRun: true})
+}
+
+// compactFiles stages the compacted copy of every file that changes, leaving the commit to the caller
+func compactFiles(ctx context.Context, itemsPath, ordersPath, promotionsPath, orderPromotionsPath string, price ItemPriceFunc, stage *compactionStage) (*CompactResult, error) {
 	result := &CompactResult{}
 
 	// Step 1: Get all tombstoned item IDs before compacting
@@ -33,63 +92,219 @@ func CompactAll(itemsPath, ordersPath, promotionsPath, orderPromotionsPath strin
 	}
 	result.DeletedItemIDs = deletedItemIDs
 
+	deletedSet := make(map[uint64]bool)
+	for _, id := range deletedItemIDs {
+		deletedSet[id] = true
+	}
+
+	itemPrices, err := getItemPrices(itemsPath, price, false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read item prices: %w", err)
+	}
+	deletedPrices, err := getItemPrices(itemsPath, price, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read deleted item prices: %w", err)
+	}
+	nextItemID, err := itemsNextID(itemsPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read items header: %w", err)
+	}
+	items := itemRefs{deleted: deletedSet, prices: itemPrices, deletedPrices: deletedPrices, nextID: nextItemID}
+
 	// Step 2: Compact items.bin
-	itemsRemoved, err := compactItems(itemsPath)
+	itemsRemoved, err := compactItems(ctx, itemsPath, stage)
 	if err != nil {
 		return nil, fmt.Errorf("failed to compact items: %w", err)
 	}
 	result.ItemsRemoved = itemsRemoved
 
-	// Step 3: Update orders and promotions to remove deleted item references
-	if len(deletedItemIDs) > 0 {
-		deletedSet := make(map[uint64]bool)
-		for _, id := range deletedItemIDs {
-			deletedSet[id] = true
-		}
-
-		ordersAffected, err := cleanCollectionItemRefs(ordersPath, deletedSet)
-		if err != nil {
-			return nil, fmt.Errorf("failed to clean order item refs: %w", err)
-		}
-		result.OrdersAffected = ordersAffected
-
-		promotionsAffected, err := cleanCollectionItemRefs(promotionsPath, deletedSet)
-		if err != nil {
-			return nil, fmt.Errorf("failed to clean promotion item refs: %w", err)
-		}
-		result.PromotionsAffected = promotionsAffected
-	}
-
-	// Step 4: Compact orders.bin
-	ordersRemoved, err := compactCollections(ordersPath)
+	// Steps 3-4: Remove deleted item references and tombstoned orders/promotions
+	orders, err := compactCollections(ctx, ordersPath, items, stage)
 	if err != nil {
 		return nil, fmt.Errorf("failed to compact orders: %w", err)
 	}
-	result.OrdersRemoved = ordersRemoved
+	result.OrdersAffected = len(orders.affectedIDs)
+	result.OrdersRemoved = orders.removed
+	result.AffectedOrderIDs = orders.affectedIDs
+	result.RemovedOrderIDs = orders.removedIDs
 
-	// Step 5: Compact promotions.bin
-	promotionsRemoved, err := compactCollections(promotionsPath)
+	promotions, err := compactCollections(ctx, promotionsPath, items, stage)
 	if err != nil {
 		return nil, fmt.Errorf("failed to compact promotions: %w", err)
 	}
-	result.PromotionsRemoved = promotionsRemoved
+	result.PromotionsAffected = len(promotions.affectedIDs)
+	result.PromotionsRemoved = promotions.removed
+	result.AffectedPromotionIDs = promotions.affectedIDs
+	result.RemovedPromotionIDs = promotions.removedIDs
 
-	// Step 6: Compact order_promotions.bin
-	opRemoved, err := compactOrderPromotions(orderPromotionsPath)
+	opRemoved, err := compactOrderPromotions(ctx, orderPromotionsPath, stage)
 	if err != nil {
 		return nil, fmt.Errorf("failed to compact order_promotions: %w", err)
 	}
 	result.OrderPromotionsRemoved = opRemoved
+	result.ReclaimedBytes = stage.reclaimed
 
-	// Step 7: Delete all index files
-	if err := deleteAllIndexes(); err != nil {
-		return nil, fmt.Errorf("failed to delete indexes: %w", err)
+	return result, nil
+}
+
+// compactionStage tracks rewritten files waiting to replace their originals
+// A dry run stage writes nothing: the rewritten files are only measured
+type compactionStage struct {
+	paths     []string
+	staged    map[string]string // staged file of each original
+	dryRun    bool
+	reclaimed int64 // bytes saved by the rewritten files so far
+}
+
+// stagedFile is the rewritten copy of a file being written, counting its size
+type stagedFile struct {
+	original string
+	file     *os.File // nil in a dry run
+	size     int64
+}
+
+// Write appends p to the staged file
+func (f *stagedFile) Write(p []byte) (int, error) {
+	f.size += int64(len(p))
+	if f.file == nil {
+		return len(p), nil
 	}
+	return f.file.Write(p)
+}
 
-	return result, nil
+// Close closes the staged file without flushing it
+func (f *stagedFile) Close() error {
+	if f.file == nil {
+		return nil
+	}
+	return f.file.Close()
+}
+
+// generational reports whether a file is replaced through a new generation instead of a rename
+func generational(filePath string) bool {
+	return filepath.Clean(filepath.Dir(filePath)) == filepath.Clean(BinDir)
+}
+
+// stagedPath returns where the rewritten copy of filePath is staged
+func stagedPath(filePath string) string {
+	if !generational(filePath) {
+		return filePath + ".tmp"
+	}
+	dir := filepath.Dir(filePath)
+	name := LogicalBinName(filepath.Base(filePath))
+	return filepath.Join(dir, GenerationFilename(name, ManifestGeneration(dir, name)+1))
+}
+
+// create opens the staged file for filePath and writes its header, keeping the header flags of filePath
+func (s *compactionStage) create(filePath string, entitiesCount, nextId int) (*stagedFile, error) {
+	flags, err := ReadHeaderFlagsFromPath(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read header flags: %w", err)
+	}
+
+	tmpFile := &stagedFile{original: filePath}
+	if !s.dryRun {
+		tmpPath := stagedPath(filePath)
+		if tmpFile.file, err = os.Create(tmpPath); err != nil {
+			return nil, fmt.Errorf("failed to create temp file: %w", err)
+		}
+		s.paths = append(s.paths, filePath)
+		if s.staged == nil {
+			s.staged = make(map[string]string)
+		}
+		s.staged[filePath] = tmpPath
+	}
+
+	basename := LogicalBinName(filepath.Base(filePath))
+	filename := basename[:len(basename)-len(filepath.Ext(basename))]
+
+	header, err := WriteHeaderWithFlags(filename, flags, entitiesCount, 0, nextId)
+	if err != nil {
+		tmpFile.Close()
+		return nil, fmt.Errorf("failed to write header: %w", err)
+	}
+	if _, err := tmpFile.Write(header); err != nil {
+		tmpFile.Close()
+		return nil, fmt.Errorf("failed to write header to file: %w", err)
+	}
+
+	return tmpFile, nil
+}
+
+// finish flushes a staged file to disk and closes it, counting the bytes it saves
+func (s *compactionStage) finish(tmpFile *stagedFile) error {
+	info, err := os.Stat(tmpFile.original)
+	if err != nil {
+		tmpFile.Close()
+		return fmt.Errorf("failed to stat %s: %w", tmpFile.original, err)
+	}
+	s.reclaimed += info.Size() - tmpFile.size
+
+	if tmpFile.file == nil {
+		return nil
+	}
+	if err := tmpFile.file.Sync(); err != nil {
+		tmpFile.Close()
+		return fmt.Errorf("failed to sync temp file: %w", err)
+	}
+	if err := tmpFile.Close(); err != nil {
+		return fmt.Errorf("failed to close temp file: %w", err)
+	}
+	return nil
+}
+
+// commit makes the staged files current
+// Generational files are switched to at once by the manifest; other originals are moved aside as .bak,
+// the staged files renamed into place and the backups removed, restoring the originals if any rename fails
+func (s *compactionStage) commit() error {
+	var replaced []string
+	var generations []string
+	rollback := func() {
+		for _, path := range replaced {
+			os.Rename(path+".bak", path)
+		}
+	}
+
+	for _, path := range s.paths {
+		if generational(path) {
+			generations = append(generations, LogicalBinName(filepath.Base(path)))
+			continue
+		}
+		if err := os.Rename(path, path+".bak"); err != nil {
+			rollback()
+			return fmt.Errorf("failed to back up %s: %w", path, err)
+		}
+		replaced = append(replaced, path)
+		if err := os.Rename(path+".tmp", path); err != nil {
+			rollback()
+			return fmt.Errorf("failed to replace %s: %w", path, err)
+		}
+	}
+
+	if len(generations) > 0 {
+		if err := commitGenerations(BinDir, generations); err != nil {
+			rollback()
+			return err
+		}
+	}
+
+	for _, path := range replaced {
+		os.Remove(path + ".bak")
+	}
+	s.paths = nil
+	return nil
+}
+
+// discard removes staged files that were not committed
+func (s *compactionStage) discard() {
+	for _, path := range s.paths {
+		os.Remove(s.staged[path])
+	}
+	s.paths = nil
 }
 
 // getDeletedItemIDs returns a list of all tombstoned item IDs
+// IDs whose record was rewritten (an active version exists later in the file) are not deleted
 func getDeletedItemIDs(itemsPath string) ([]uint64, error) {
 	if _, err := os.Stat(itemsPath); os.IsNotExist(err) {
 		return []uint64{}, nil
@@ -100,23 +315,62 @@ func getDeletedItemIDs(itemsPath string) ([]uint64, error) {
 		return nil, err
 	}
 
-	var deletedIDs []uint64
+	active := make(map[uint64]bool)
+	var tombstoned []uint64
 	for _, entry := range entries {
-		item, err := ParseItemEntry(entry.Data)
+		item, err := ItemCodec.Decode(entry.Data, entry.IDSize)
 		if err != nil {
 			continue
 		}
 		if item.Tombstone != 0x00 {
-			deletedIDs = append(deletedIDs, item.ID)
+			tombstoned = append(tombstoned, item.ID)
+		} else {
+			active[item.ID] = true
+		}
+	}
+
+	var deletedIDs []uint64
+	for _, id := range tombstoned {
+		if !active[id] {
+			deletedIDs = append(deletedIDs, id)
 		}
 	}
 
 	return deletedIDs, nil
 }
 
-// compactItems removes tombstoned items and rewrites the file
+// getItemPrices returns the price of every active item, or of every tombstoned item when deleted is set, keyed by ID
+// Returns nil when the items file does not exist
+func getItemPrices(itemsPath string, price ItemPriceFunc, deleted bool) (map[uint64]uint64, error) {
+	if _, err := os.Stat(itemsPath); os.IsNotExist(err) {
+		return nil, nil
+	}
+	prices := make(map[uint64]uint64)
+
+	entries, err := SplitFileIntoEntries(itemsPath)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		item, err := ItemCodec.Decode(entry.Data, entry.IDSize)
+		if err != nil || (item.Tombstone != 0x00) != deleted {
+			continue
+		}
+		prices[item.ID] = item.Price
+		if price != nil {
+			if prices[item.ID], err = price(item); err != nil {
+				return nil, fmt.Errorf("failed to price item %d: %w", item.ID, err)
+			}
+		}
+	}
+
+	return prices, nil
+}
+
+// compactItems stages a copy of items.bin without tombstoned items
 // Returns the number of items removed
-func compactItems(filePath string) (int, error) {
+func compactItems(ctx context.Context, filePath string, stage *compactionStage) (int, error) {
 	if _, err := os.Stat(filePath); os.IsNotExist(err) {
 		return 0, nil
 	}
@@ -131,7 +385,10 @@ func compactItems(filePath string) (int, error) {
 	removedCount := 0
 
 	for _, entry := range entries {
-		item, err := ParseItemEntry(entry.Data)
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+		item, err := ItemCodec.Decode(entry.Data, entry.IDSize)
 		if err != nil {
 			continue
 		}
@@ -146,19 +403,11 @@ func compactItems(filePath string) (int, error) {
 		return 0, nil
 	}
 
-	// Rewrite the file with only active items
-	return removedCount, rewriteItemsFile(filePath, activeItems)
+	return removedCount, stageItemsFile(ctx, filePath, activeItems, stage)
 }
 
-// rewriteItemsFile rewrites items.bin with the given items
-func rewriteItemsFile(filePath string, items []*Item) error {
-	// Create temp file
-	tmpPath := filePath + ".tmp"
-	tmpFile, err := os.Create(tmpPath)
-	if err != nil {
-		return fmt.Errorf("failed to create temp file: %w", err)
-	}
-
+// stageItemsFile writes the staged copy of items.bin with the given items
+func stageItemsFile(ctx context.Context, filePath string, items []*Item, stage *compactionStage) error {
 	// Find the max ID to set nextId correctly
 	maxID := uint64(0)
 	for _, item := range items {
@@ -167,271 +416,248 @@ func rewriteItemsFile(filePath string, items []*Item) error {
 		}
 	}
 
-	// Extract filename from path
-	basename := filepath.Base(filePath)
-	filename := basename[:len(basename)-len(filepath.Ext(basename))]
-
-	// Write header: entitiesCount = len(items), tombstoneCount = 0, nextId = maxID + 1
-	header, err := WriteHeader(filename, len(items), 0, int(maxID)+1)
+	// Header: entitiesCount = len(items), tombstoneCount = 0, nextId = maxID + 1
+	tmpFile, err := stage.create(filePath, len(items), int(maxID)+1)
 	if err != nil {
-		tmpFile.Close()
-		os.Remove(tmpPath)
-		return fmt.Errorf("failed to write header: %w", err)
-	}
-
-	if _, err := tmpFile.Write(header); err != nil {
-		tmpFile.Close()
-		os.Remove(tmpPath)
-		return fmt.Errorf("failed to write header to file: %w", err)
+		return err
 	}
 
 	// Write each item
 	for _, item := range items {
+		if err := ctx.Err(); err != nil {
+			tmpFile.Close()
+			return err
+		}
 		if err := writeItemEntry(tmpFile, item); err != nil {
 			tmpFile.Close()
-			os.Remove(tmpPath)
 			return fmt.Errorf("failed to write item %d: %w", item.ID, err)
 		}
 	}
 
-	tmpFile.Sync()
-	tmpFile.Close()
-
-	// Replace original with temp
-	return os.Rename(tmpPath, filePath)
+	return stage.finish(tmpFile)
 }
 
-// writeItemEntry writes a single item entry to the file
-// Format: [recordLength(2)][ID(2)][tombstone(1)][nameLength(2)][name...][price(4)]
-func writeItemEntry(file *os.File, item *Item) error {
-	// Build entry data: [nameLength(2)][name...][price(4)]
-	nameSizeBytes, err := WriteFixedNumber(2, uint64(len(item.Name)))
+// writeItemEntry writes a single active item record to the file
+func writeItemEntry(file io.Writer, item *Item) error {
+	entry, err := ItemCodec.Encode(item, IDSize)
 	if err != nil {
 		return err
 	}
+	return writeRecord(file, item.ID, entry)
+}
 
-	nameBytes := []byte(item.Name)
-
-	priceBytes, err := WriteFixedNumber(4, item.Price)
+// writeRecord writes the active record of an entry with the given ID
+// Staged files are created with the configured IDSize, so compaction also widens legacy files
+func writeRecord(file io.Writer, id uint64, entry []byte) error {
+	record, err := BuildRecord(id, IDSize, entry)
 	if err != nil {
 		return err
 	}
+	_, err = file.Write(record)
+	return err
+}
 
-	entryData := CombineBytes(nameSizeBytes, nameBytes, priceBytes)
+// itemRefs describes the items file as read by compaction, to decide which item references a collection keeps
+type itemRefs struct {
+	deleted       map[uint64]bool   // tombstoned items
+	prices        map[uint64]uint64 // active items, nil when there is no items file
+	deletedPrices map[uint64]uint64 // tombstoned items
+	nextID        uint64            // every ID below was handed out when the items file was read
+}
 
-	// Build complete record: [recordLength(2)][ID(2)][tombstone(1)][entryData]
-	recordLength := IDSize + TombstoneSize + len(entryData)
+// dropped reports whether a collection loses its reference to itemID
+// Tombstoned items are dropped, and so are IDs handed out before the items file was read that have no record
+// left: the item was deleted and its slot reused by another item. Any other unknown ID belongs to an item
+// created after the items file was read (online compaction reads it before the collection files) and is kept
+func (r itemRefs) dropped(itemID uint64) bool {
+	if r.deleted[itemID] {
+		return true
+	}
+	if r.prices == nil {
+		return false
+	}
+	_, exists := r.prices[itemID]
+	return !exists && itemID < r.nextID
+}
 
-	lengthBytes, err := WriteFixedNumber(RecordLengthSize, uint64(recordLength))
+// known reports whether the price of itemID is known
+func (r itemRefs) known(itemID uint64) bool {
+	_, exists := r.prices[itemID]
+	return exists
+}
+
+// itemsNextID returns the next ID recorded in the items file header, 0 when the file does not exist
+func itemsNextID(itemsPath string) (uint64, error) {
+	file, err := os.Open(itemsPath)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
 	if err != nil {
-		return err
+		return 0, err
 	}
+	defer file.Close()
 
-	idBytes, err := WriteFixedNumber(IDSize, item.ID)
+	_, _, _, nextID, err := ReadHeader(file)
 	if err != nil {
-		return err
+		return 0, err
 	}
+	return uint64(nextID), nil
+}
 
-	tombstoneBytes := []byte{0x00}
-
-	record := CombineBytes(lengthBytes, idBytes, tombstoneBytes, entryData)
-
-	_, err = file.Write(record)
-	return err
+// collectionChanges describes what compaction changes in a collection file
+type collectionChanges struct {
+	affectedIDs []uint64 // collections that had item references cleaned
+	removed     int      // tombstoned records dropped
+	removedIDs  []uint64 // collections deleted for good, without the older versions of updated ones
 }
 
-// cleanCollectionItemRefs removes deleted item IDs from all collections in a file
-// Returns the number of collections that were modified
-func cleanCollectionItemRefs(filePath string, deletedItemIDs map[uint64]bool) (int, error) {
+// compactCollections stages a copy of a collection file without tombstoned collections
+// and without references to deleted items, recalculating the total of every cleaned collection
+// A collection referencing items unknown to the compaction keeps its stored total, less the prices of the
+// deleted items it no longer references
+func compactCollections(ctx context.Context, filePath string, items itemRefs, stage *compactionStage) (collectionChanges, error) {
+	var changes collectionChanges
 	if _, err := os.Stat(filePath); os.IsNotExist(err) {
-		return 0, nil
+		return changes, nil
 	}
 
 	entries, err := SplitFileIntoEntries(filePath)
 	if err != nil {
-		return 0, err
+		return changes, err
 	}
 
-	// Parse all collections and track which need updating
-	var collections []*Collection
-	affectedCount := 0
+	var activeCollections []*Collection
+	var tombstoned []uint64
+	active := make(map[uint64]bool)
 
 	for _, entry := range entries {
-		collection, err := ParseCollectionEntry(entry.Data)
+		if err := ctx.Err(); err != nil {
+			return changes, err
+		}
+		collection, err := CollectionCodec.Decode(entry.Data, entry.IDSize)
 		if err != nil {
 			continue
 		}
+		if collection.Tombstone != 0x00 {
+			changes.removed++
+			tombstoned = append(tombstoned, collection.ID)
+			continue
+		}
+		active[collection.ID] = true
 
 		// Filter out deleted item IDs
-		var newItemIDs []uint64
-		hadDeletions := false
+		var newItemIDs, droppedIDs []uint64
+		hasUnknown := false
 		for _, itemID := range collection.ItemIDs {
-			if !deletedItemIDs[itemID] {
-				newItemIDs = append(newItemIDs, itemID)
-			} else {
-				hadDeletions = true
+			if items.dropped(itemID) {
+				droppedIDs = append(droppedIDs, itemID)
+				continue
+			}
+			newItemIDs = append(newItemIDs, itemID)
+			if items.prices != nil && !items.known(itemID) {
+				hasUnknown = true
 			}
 		}
 
-		if hadDeletions && collection.Tombstone == 0x00 {
-			affectedCount++
+		if len(droppedIDs) > 0 {
+			changes.affectedIDs = append(changes.affectedIDs, collection.ID)
 			collection.ItemIDs = newItemIDs
 			collection.ItemCount = uint64(len(newItemIDs))
-			// Note: TotalPrice would need recalculation but we don't have item prices here
-			// The price will be stale but this is acceptable for compaction
+			if hasUnknown {
+				collection.TotalPrice = reducedTotal(collection.TotalPrice, droppedIDs, items.deletedPrices)
+			} else if collection.TotalPrice, err = collectionTotal(newItemIDs, items.prices); err != nil {
+				return changes, fmt.Errorf("collection %d: %w", collection.ID, err)
+			}
 		}
 
-		collections = append(collections, collection)
+		activeCollections = append(activeCollections, collection)
 	}
 
-	if affectedCount == 0 {
-		return 0, nil
+	seen := make(map[uint64]bool)
+	for _, id := range tombstoned {
+		if !active[id] && !seen[id] {
+			seen[id] = true
+			changes.removedIDs = append(changes.removedIDs, id)
+		}
 	}
 
-	// Rewrite the file with updated collections
-	return affectedCount, rewriteCollectionsFile(filePath, collections)
-}
-
-// compactCollections removes tombstoned collections and rewrites the file
-// Returns the number of collections removed
-func compactCollections(filePath string) (int, error) {
-	if _, err := os.Stat(filePath); os.IsNotExist(err) {
-		return 0, nil
+	if len(changes.affectedIDs) == 0 && changes.removed == 0 {
+		return changes, nil
 	}
 
-	entries, err := SplitFileIntoEntries(filePath)
-	if err != nil {
-		return 0, err
-	}
+	return changes, stageCollectionsFile(ctx, filePath, activeCollections, stage)
+}
 
-	var activeCollections []*Collection
-	removedCount := 0
+// reducedTotal subtracts the prices of the dropped items from a stored total, never going below zero
+// Items without a price (their record was reused) subtract nothing
+func reducedTotal(total uint64, droppedIDs []uint64, prices map[uint64]uint64) uint64 {
+	for _, itemID := range droppedIDs {
+		price := prices[itemID]
+		if price >= total {
+			return 0
+		}
+		total -= price
+	}
+	return total
+}
 
-	for _, entry := range entries {
-		collection, err := ParseCollectionEntry(entry.Data)
-		if err != nil {
+// collectionTotal sums the prices of the given items, skipping items that no longer exist
+func collectionTotal(itemIDs []uint64, itemPrices map[uint64]uint64) (uint64, error) {
+	total := uint64(0)
+	for _, itemID := range itemIDs {
+		price, ok := itemPrices[itemID]
+		if !ok {
 			continue
 		}
-		if collection.Tombstone == 0x00 {
-			activeCollections = append(activeCollections, collection)
-		} else {
-			removedCount++
+		sum, err := SafeAddUint64(total, price)
+		if err != nil {
+			return 0, fmt.Errorf("price overflow recalculating total: %w", err)
 		}
+		total = sum
 	}
-
-	if removedCount == 0 {
-		return 0, nil
-	}
-
-	return removedCount, rewriteCollectionsFile(filePath, activeCollections)
+	return total, nil
 }
 
-// rewriteCollectionsFile rewrites a collection file with the given collections
-func rewriteCollectionsFile(filePath string, collections []*Collection) error {
-	tmpPath := filePath + ".tmp"
-	tmpFile, err := os.Create(tmpPath)
-	if err != nil {
-		return fmt.Errorf("failed to create temp file: %w", err)
-	}
-
-	// Find max ID and count active
+// stageCollectionsFile writes the staged copy of a collection file with the given collections
+func stageCollectionsFile(ctx context.Context, filePath string, collections []*Collection, stage *compactionStage) error {
 	maxID := uint64(0)
-	activeCount := 0
 	for _, c := range collections {
 		if c.ID > maxID {
 			maxID = c.ID
 		}
-		if c.Tombstone == 0x00 {
-			activeCount++
-		}
 	}
 
-	basename := filepath.Base(filePath)
-	filename := basename[:len(basename)-len(filepath.Ext(basename))]
-
-	header, err := WriteHeader(filename, activeCount, 0, int(maxID)+1)
+	tmpFile, err := stage.create(filePath, len(collections), int(maxID)+1)
 	if err != nil {
-		tmpFile.Close()
-		os.Remove(tmpPath)
-		return fmt.Errorf("failed to write header: %w", err)
-	}
-
-	if _, err := tmpFile.Write(header); err != nil {
-		tmpFile.Close()
-		os.Remove(tmpPath)
-		return fmt.Errorf("failed to write header to file: %w", err)
+		return err
 	}
 
-	// Write each collection (only active ones after compaction, but all during ref cleaning)
 	for _, c := range collections {
+		if err := ctx.Err(); err != nil {
+			tmpFile.Close()
+			return err
+		}
 		if err := writeCollectionEntry(tmpFile, c); err != nil {
 			tmpFile.Close()
-			os.Remove(tmpPath)
 			return fmt.Errorf("failed to write collection %d: %w", c.ID, err)
 		}
 	}
 
-	tmpFile.Sync()
-	tmpFile.Close()
-
-	return os.Rename(tmpPath, filePath)
+	return stage.finish(tmpFile)
 }
 
-// writeCollectionEntry writes a single collection entry
-// Format: [recordLength(2)][ID(2)][tombstone(1)][nameLength(2)][name...][totalPrice(4)][itemCount(4)][itemIDs...]
-func writeCollectionEntry(file *os.File, c *Collection) error {
-	// Name (already encrypted in OwnerOrName if encryption was used)
-	nameBytes := []byte(c.OwnerOrName)
-	nameSizeBytes, err := WriteFixedNumber(2, uint64(len(nameBytes)))
-	if err != nil {
-		return err
-	}
-
-	totalPriceBytes, err := WriteFixedNumber(4, c.TotalPrice)
-	if err != nil {
-		return err
-	}
-
-	itemCountBytes, err := WriteFixedNumber(4, c.ItemCount)
-	if err != nil {
-		return err
-	}
-
-	// Build item IDs bytes
-	var itemIDsBytes []byte
-	for _, itemID := range c.ItemIDs {
-		idBytes, err := WriteFixedNumber(IDSize, itemID)
-		if err != nil {
-			return err
-		}
-		itemIDsBytes = append(itemIDsBytes, idBytes...)
-	}
-
-	entryData := CombineBytes(nameSizeBytes, nameBytes, totalPriceBytes, itemCountBytes, itemIDsBytes)
-
-	// Build complete record
-	recordLength := IDSize + TombstoneSize + len(entryData)
-
-	lengthBytes, err := WriteFixedNumber(RecordLengthSize, uint64(recordLength))
-	if err != nil {
-		return err
-	}
-
-	idBytes, err := WriteFixedNumber(IDSize, c.ID)
+// writeCollectionEntry writes a single active collection record
+func writeCollectionEntry(file io.Writer, c *Collection) error {
+	// Name is already encrypted in OwnerOrName if encryption was used
+	entry, err := CollectionCodec.Encode(c, IDSize)
 	if err != nil {
 		return err
 	}
-
-	tombstoneBytes := []byte{c.Tombstone}
-
-	record := CombineBytes(lengthBytes, idBytes, tombstoneBytes, entryData)
-
-	_, err = file.Write(record)
-	return err
+	return writeRecord(file, c.ID, entry)
 }
 
-// compactOrderPromotions removes tombstoned order-promotion relationships
-func compactOrderPromotions(filePath string) (int, error) {
+// compactOrderPromotions stages a copy of order_promotions.bin without tombstoned relationships
+func compactOrderPromotions(ctx context.Context, filePath string, stage *compactionStage) (int, error) {
 	if _, err := os.Stat(filePath); os.IsNotExist(err) {
 		return 0, nil
 	}
@@ -445,7 +671,10 @@ func compactOrderPromotions(filePath string) (int, error) {
 	removedCount := 0
 
 	for _, entry := range entries {
-		op, err := ParseOrderPromotionEntry(entry.Data)
+		if err := ctx.Err(); err != nil {
+			return 0, err
+		}
+		op, err := OrderPromotionCodec.Decode(entry.Data, entry.IDSize)
 		if err != nil {
 			continue
 		}
@@ -460,78 +689,48 @@ func compactOrderPromotions(filePath string) (int, error) {
 		return 0, nil
 	}
 
-	return removedCount, rewriteOrderPromotionsFile(filePath, activeOPs)
-}
-
-// rewriteOrderPromotionsFile rewrites order_promotions.bin with the given relationships
-func rewriteOrderPromotionsFile(filePath string, ops []*OrderPromotion) error {
-	tmpPath := filePath + ".tmp"
-	tmpFile, err := os.Create(tmpPath)
+	generation, err := ReadGeneration(filePath)
 	if err != nil {
-		return fmt.Errorf("failed to create temp file: %w", err)
+		return 0, err
 	}
 
-	basename := filepath.Base(filePath)
-	filename := basename[:len(basename)-len(filepath.Ext(basename))]
-
-	// order_promotions doesn't use nextId (composite key), so keep it at 0
-	header, err := WriteHeader(filename, len(ops), 0, 0)
+	// nextId holds the generation for composite key tables, bump it so old indexes are stale
+	tmpFile, err := stage.create(filePath, len(activeOPs), int(generation)+1)
 	if err != nil {
-		tmpFile.Close()
-		os.Remove(tmpPath)
-		return fmt.Errorf("failed to write header: %w", err)
-	}
-
-	if _, err := tmpFile.Write(header); err != nil {
-		tmpFile.Close()
-		os.Remove(tmpPath)
-		return fmt.Errorf("failed to write header to file: %w", err)
+		return 0, err
 	}
-
-	for _, op := range ops {
+	for _, op := range activeOPs {
+		if err := ctx.Err(); err != nil {
+			tmpFile.Close()
+			return 0, err
+		}
 		if err := writeOrderPromotionEntry(tmpFile, op); err != nil {
 			tmpFile.Close()
-			os.Remove(tmpPath)
-			return fmt.Errorf("failed to write order_promotion: %w", err)
+			return 0, fmt.Errorf("failed to write order_promotion: %w", err)
 		}
 	}
 
-	tmpFile.Sync()
-	tmpFile.Close()
-
-	return os.Rename(tmpPath, filePath)
+	return removedCount, stage.finish(tmpFile)
 }
 
-// writeOrderPromotionEntry writes a single order-promotion entry
-// Format: [recordLength(2)][orderID(2)][promotionID(2)][tombstone(1)]
-func writeOrderPromotionEntry(file *os.File, op *OrderPromotion) error {
-	orderIDBytes, err := WriteFixedNumber(IDSize, op.OrderID)
+// writeOrderPromotionEntry writes a single order-promotion record
+// Format: [recordLength(2)][orderID(IDSize)][promotionID(IDSize)][tombstone(1)]
+func writeOrderPromotionEntry(file io.Writer, op *OrderPromotion) error {
+	entryData, err := OrderPromotionCodec.Encode(op, IDSize)
 	if err != nil {
 		return err
 	}
 
-	promotionIDBytes, err := WriteFixedNumber(IDSize, op.PromotionID)
+	lengthBytes, err := WriteFixedNumber(RecordLengthSize, uint64(len(entryData)))
 	if err != nil {
 		return err
 	}
 
-	tombstoneBytes := []byte{0x00}
-
-	entryData := CombineBytes(orderIDBytes, promotionIDBytes, tombstoneBytes)
-
-	recordLength := len(entryData)
-	lengthBytes, err := WriteFixedNumber(RecordLengthSize, uint64(recordLength))
-	if err != nil {
-		return err
-	}
-
-	record := CombineBytes(lengthBytes, entryData)
-
-	_, err = file.Write(record)
+	_, err = file.Write(CombineBytes(lengthBytes, entryData))
 	return err
 }
 
-// deleteAllIndexes removes all .idx files from the indexes directory
+// deleteAllIndexes removes all .idx files, with their hash logs, tombstone bitmaps, slot offsets and dirty markers, from the indexes directory
 func deleteAllIndexes() error {
 	indexDir := IndexDir
 
@@ -548,7 +747,7 @@ func deleteAllIndexes() error {
 		if entry.IsDir() {
 			continue
 		}
-		if filepath.Ext(entry.Name()) == ".idx" {
+		if ext := filepath.Ext(entry.Name()); ext == ".idx" || ext == index.HashLogExt || ext == TombstoneBitmapExt || ext == TombstoneSlotsExt || ext == IndexDirtyExt {
 			indexPath := filepath.Join(indexDir, entry.Name())
 			if err := os.Remove(indexPath); err != nil {
 				return fmt.Errorf("failed to remove index %s: %w", entry.Name(), err)
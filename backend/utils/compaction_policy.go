@@ -0,0 +1,75 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+)
+
+// CompactionPolicy decides when a data file is fragmented enough to compact
+// A zero threshold disables that rule
+type CompactionPolicy struct {
+	TombstoneRatio float64 `json:"tombstoneRatio"` // compact when tombstoneCount/entitiesCount exceeds this (0-1)
+	MaxFileBytes   int64   `json:"maxFileBytes"`   // compact when the file is larger than this and has tombstones
+	MinTombstones  int     `json:"minTombstones"`  // never compact files with fewer tombstones than this
+}
+
+// DefaultCompactionPolicy compacts files with more than 30% tombstoned records
+func DefaultCompactionPolicy() CompactionPolicy {
+	return CompactionPolicy{TombstoneRatio: 0.3, MinTombstones: 1}
+}
+
+// Validate checks the policy thresholds
+func (p CompactionPolicy) Validate() error {
+	if p.TombstoneRatio < 0 || p.TombstoneRatio > 1 {
+		return fmt.Errorf("tombstone ratio %v must be between 0 and 1", p.TombstoneRatio)
+	}
+	if p.MaxFileBytes < 0 || p.MinTombstones < 0 {
+		return fmt.Errorf("compaction thresholds cannot be negative")
+	}
+	return nil
+}
+
+// FileFragmentation describes how much of a data file is tombstoned
+type FileFragmentation struct {
+	Path           string
+	SizeBytes      int64
+	EntitiesCount  int
+	TombstoneCount int
+}
+
+// Ratio returns the fraction of records that are tombstoned
+func (f FileFragmentation) Ratio() float64 {
+	if f.EntitiesCount == 0 {
+		return 0
+	}
+	return float64(f.TombstoneCount) / float64(f.EntitiesCount)
+}
+
+// ReadFragmentation reads the record counts of a data file from its header
+// A missing file has no fragmentation
+func ReadFragmentation(filePath string) (FileFragmentation, error) {
+	stats := FileFragmentation{Path: filePath}
+
+	header, err := ReadHeaderOnly(filePath)
+	if os.IsNotExist(err) {
+		return stats, nil
+	}
+	if err != nil {
+		return stats, err
+	}
+	stats.SizeBytes = header.FileSize
+	stats.EntitiesCount = header.EntitiesCount
+	stats.TombstoneCount = header.TombstoneCount
+	return stats, nil
+}
+
+// ShouldCompact reports whether the policy asks for a file to be compacted
+func (p CompactionPolicy) ShouldCompact(f FileFragmentation) bool {
+	if f.TombstoneCount == 0 || f.TombstoneCount < p.MinTombstones {
+		return false
+	}
+	if p.TombstoneRatio > 0 && f.Ratio() > p.TombstoneRatio {
+		return true
+	}
+	return p.MaxFileBytes > 0 && f.SizeBytes > p.MaxFileBytes
+}
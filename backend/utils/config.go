@@ -0,0 +1,199 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ConfigFile is the name of the config file inside the data directory
+const ConfigFile = "config.json"
+
+// Upper bounds for the config limits, imposed by the record format
+const (
+	maxConfigNameLength = 4096  // names are stored with a 2-byte length in records of at most 64KB
+	maxConfigItems      = 10000 // 4-byte item IDs must fit in one collection record
+)
+
+// Config holds the tunables loaded from the config file
+type Config struct {
+	MaxNameLength         int                       `json:"maxNameLength"`
+	MaxItemsPerCollection int                       `json:"maxItemsPerCollection"`
+	MaxPrice              uint64                    `json:"maxPrice"`
+	BTreeOrder            int                       `json:"btreeOrder"`
+	HashBucketSize        int                       `json:"hashBucketSize"`
+	ItemCacheSize         int                       `json:"itemCacheSize"`
+	IDSize                int                       `json:"idSize"`
+	AutoCompact           bool                      `json:"autoCompact"`
+	Compaction            CompactionPolicy          `json:"compaction"`
+	SignFiles             bool                      `json:"signFiles"`
+	CompressRecords       bool                      `json:"compressRecords"`
+	MmapReads             bool                      `json:"mmapReads"`
+	SanitizeNames         bool                      `json:"sanitizeNames"` // NFC, trimmed and collapsed whitespace
+	RebuildWorkers        int                       `json:"rebuildWorkers"`
+	Webhooks              []Webhook                 `json:"webhooks,omitempty"`
+	Logging               LogConfig                 `json:"logging"`
+	SlowOperationMs       int                       `json:"slowOperationMs"` // 0 never logs slow calls
+	GroupCommit           GroupCommitConfig         `json:"groupCommit"`
+	TrashRetentionDays    int                       `json:"trashRetentionDays"` // 0 keeps deleted files until restored
+	Rules                 map[string]ValidationRule `json:"rules,omitempty"`    // per entity: item, order, promotion, template
+}
+
+// GroupCommitConfig batches item writes into one sync per group, see dao.WithGroupCommit
+// Zero MaxRecords writes every item on its own
+type GroupCommitConfig struct {
+	MaxRecords int `json:"maxRecords"`
+	MaxDelayMs int `json:"maxDelayMs"` // how long the first write of a group waits for others
+}
+
+// Enabled reports whether item writes are grouped
+func (g GroupCommitConfig) Enabled() bool {
+	return g.MaxRecords > 0
+}
+
+// Validate checks that an enabled group has a delay, so a group that never fills is still written
+func (g GroupCommitConfig) Validate() error {
+	if g.MaxRecords < 0 || g.MaxDelayMs < 0 {
+		return fmt.Errorf("maxRecords and maxDelayMs must not be negative")
+	}
+	if g.Enabled() && g.MaxDelayMs == 0 {
+		return fmt.Errorf("maxDelayMs must be positive when maxRecords is set")
+	}
+	return nil
+}
+
+// DefaultConfig returns the built-in tunables
+func DefaultConfig() Config {
+	return Config{
+		MaxNameLength:         DefaultMaxNameLength,
+		MaxItemsPerCollection: DefaultMaxItemsPerCollection,
+		MaxPrice:              DefaultMaxPrice,
+		BTreeOrder:            DefaultBTreeOrder,
+		HashBucketSize:        DefaultHashBucketSize,
+		ItemCacheSize:         DefaultItemCacheSize,
+		IDSize:                DefaultIDSize,
+		AutoCompact:           true,
+		SanitizeNames:         true,
+		Compaction:            DefaultCompactionPolicy(),
+		Logging:               DefaultLogConfig(),
+		SlowOperationMs:       DefaultSlowOperationMs,
+		TrashRetentionDays:    DefaultTrashRetentionDays,
+	}
+}
+
+// ConfigPath returns the path of the config file in the data directory
+func ConfigPath() string {
+	return filepath.Join(DataDir, ConfigFile)
+}
+
+// Validate checks every tunable against the limits of the file format
+func (c Config) Validate() error {
+	if c.MaxNameLength < 1 || c.MaxNameLength > maxConfigNameLength {
+		return fmt.Errorf("maxNameLength must be between 1 and %d", maxConfigNameLength)
+	}
+	if c.MaxItemsPerCollection < 1 || c.MaxItemsPerCollection > maxConfigItems {
+		return fmt.Errorf("maxItemsPerCollection must be between 1 and %d", maxConfigItems)
+	}
+	if c.MaxPrice < 1 || c.MaxPrice > DefaultMaxPrice {
+		return fmt.Errorf("maxPrice must be between 1 and %d", uint64(DefaultMaxPrice))
+	}
+	if c.BTreeOrder < 3 {
+		return fmt.Errorf("btreeOrder must be at least 3")
+	}
+	if c.HashBucketSize < 2 {
+		return fmt.Errorf("hashBucketSize must be at least 2")
+	}
+	if c.ItemCacheSize < 0 {
+		return fmt.Errorf("itemCacheSize must not be negative")
+	}
+	if !ValidIDSize(c.IDSize) {
+		return fmt.Errorf("idSize must be %d, %d or %d", LegacyIDSize, DefaultIDSize, WideIDSize)
+	}
+	if c.RebuildWorkers < 0 {
+		return fmt.Errorf("rebuildWorkers must not be negative")
+	}
+	if c.SlowOperationMs < 0 {
+		return fmt.Errorf("slowOperationMs must not be negative")
+	}
+	if c.TrashRetentionDays < 0 {
+		return fmt.Errorf("trashRetentionDays must not be negative")
+	}
+	if err := c.Compaction.Validate(); err != nil {
+		return fmt.Errorf("compaction: %w", err)
+	}
+	if err := c.Logging.Validate(); err != nil {
+		return fmt.Errorf("logging: %w", err)
+	}
+	if err := c.GroupCommit.Validate(); err != nil {
+		return fmt.Errorf("groupCommit: %w", err)
+	}
+	for _, webhook := range c.Webhooks {
+		if err := webhook.Validate(); err != nil {
+			return err
+		}
+	}
+	for entity, rule := range c.Rules {
+		if err := rule.Validate(entity); err != nil {
+			return fmt.Errorf("rules: %w", err)
+		}
+	}
+	return nil
+}
+
+// LoadConfig reads a config file, using the defaults for a missing file or missing fields
+func LoadConfig(path string) (Config, error) {
+	config := DefaultConfig()
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return config, nil
+	}
+	if err != nil {
+		return config, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	if err := json.Unmarshal(data, &config); err != nil {
+		return DefaultConfig(), fmt.Errorf("failed to parse config: %w", err)
+	}
+	if err := config.Validate(); err != nil {
+		return DefaultConfig(), fmt.Errorf("invalid config: %w", err)
+	}
+	return config, nil
+}
+
+// SaveConfig writes a config file
+func SaveConfig(path string, config Config) error {
+	data, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode config: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	return nil
+}
+
+// ApplyConfig makes the validation limits and index tunables of a config take effect
+// Index tunables only apply to indexes created afterwards and the ID size only to files created afterwards
+func ApplyConfig(config Config) {
+	MaxNameLength = config.MaxNameLength
+	MaxItemsPerCollection = config.MaxItemsPerCollection
+	MaxPrice = config.MaxPrice
+	BTreeOrder = config.BTreeOrder
+	HashBucketSize = config.HashBucketSize
+	ItemCacheSize = config.ItemCacheSize
+	IDSize = config.IDSize
+	SigningEnabled = config.SignFiles
+	RecordCompressionEnabled = config.CompressRecords
+	MmapReads = config.MmapReads
+	SanitizeNames = config.SanitizeNames
+	RebuildWorkers = config.RebuildWorkers
+	SetValidationRules(config.Rules)
+
+	ErrNameTooLong = WithCode(CodeValidation, fmt.Errorf("name exceeds maximum length of %d characters", MaxNameLength), nil)
+	ErrTooManyItems = WithCode(CodeValidation, fmt.Errorf("exceeds maximum of %d items", MaxItemsPerCollection), nil)
+}
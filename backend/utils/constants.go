@@ -5,58 +5,175 @@ import (
 	"strings"
 )
 
-// BDATMagic is the magic bytes for binary data files
+// BDATMagic is the magic bytes for legacy (version 1) binary data files
 var BDATMagic = []byte{'B', 'D', 'A', 'T'}
 
+// VersionedMagicPrefix prefixes the magic of versioned binary data files
+// The fourth magic byte holds the format version: [B][D][V][version]
+var VersionedMagicPrefix = []byte{'B', 'D', 'V'}
+
 const (
-	// IDSize is the size of the ID field in bytes
-	IDSize = 2
+	// LegacyIDSize is the ID width in bytes of files older than FormatVersionIDSize, which do not record it
+	LegacyIDSize = 2
+
+	// DefaultIDSize is the ID width in bytes of new files
+	DefaultIDSize = 4
+
+	// WideIDSize is the ID width in bytes for tables that outgrow DefaultIDSize
+	// Only files from FormatVersionWideNextID on can record it, older ones cannot count that far
+	WideIDSize = 8
 
 	// TombstoneSize is the size of the tombstone field in bytes
 	TombstoneSize = 1
 
+	// LinkMetadataSize is the size of the optional order-promotion trailer: [appliedAt(8)][discountAmount(4)]
+	LinkMetadataSize = 12
+
 	// RecordLengthSize is the size of the record length prefix in bytes
 	RecordLengthSize = 2
 
 	// HeaderFieldSize is the size of each header field in bytes
 	HeaderFieldSize = 4
 
+	// WideNextIDFieldSize is the size of the nextId header field from FormatVersionWideNextID on
+	WideNextIDFieldSize = 8
+
 	// MagicSize is the size of the magic bytes
 	MagicSize = 4
 
+	// FormatVersionLegacy is the original file format, identified by the BDAT magic
+	FormatVersionLegacy = 1
+
+	// FormatVersionFlags is the first format version with a flags byte at the end of the header
+	FormatVersionFlags = 3
+
+	// FormatVersionIDSize is the first format version with an ID size byte after the flags
+	FormatVersionIDSize = 4
+
+	// FormatVersionWideNextID is the first format version with an 8 byte nextId header field
+	FormatVersionWideNextID = 5
+
+	// CurrentFormatVersion is the format version written for new files
+	CurrentFormatVersion = 5
+
 	// FilenameLengthSize is the size of the filename length field
 	FilenameLengthSize = 1
 
 	// HeaderFixedSize is the fixed portion of the header (magic + counts)
 	// Format: [magic(4)][filenameLen(1)][filename(N)][entitiesCount(4)][tombstoneCount(4)][nextId(4)]
 	// The variable part is filename, fixed part = 4 + 1 + 4 + 4 + 4 = 17 bytes + filename
+	// From FormatVersionWideNextID on nextId takes 8 bytes, see HeaderSizeForVersion
 	HeaderFixedSize = MagicSize + FilenameLengthSize + (HeaderFieldSize * 3)
 
+	// HeaderFlagsSize is the size of the flags byte that ends the header from FormatVersionFlags on
+	HeaderFlagsSize = 1
+
+	// HeaderIDSizeSize is the size of the ID size byte that follows the flags from FormatVersionIDSize on
+	HeaderIDSizeSize = 1
+
 	// DefaultBTreeOrder is the default order for B+ tree indices
 	DefaultBTreeOrder = 4
 
-	// Data directory paths
-	DataDir       = "data"
+	// DefaultHashBucketSize is the default bucket size of extensible hash indices
+	DefaultHashBucketSize = 4
+
+	// HashCheckpointInterval is the number of logged hash index changes after which the full index is saved
+	HashCheckpointInterval = 64
+
+	// DefaultItemCacheSize is the default number of recently read items kept in memory
+	DefaultItemCacheSize = 256
+
+	// DefaultSlowOperationMs is how long an App call may take before it is logged as slow
+	DefaultSlowOperationMs = 500
+
+	// DefaultTrashRetentionDays is how long deleted files are kept in the trash before they are purged
+	DefaultTrashRetentionDays = 30
+
+	// Compression algorithms
+	AlgorithmHuffman = "huffman"
+	AlgorithmLZW     = "lzw"
+	AlgorithmGzip    = "gzip"
+	AlgorithmZstd    = "zstd"
+	AlgorithmUnknown = "unknown"
+)
+
+// Index tunables used when an index is created, set through the config file (see ApplyConfig)
+var (
+	BTreeOrder     = DefaultBTreeOrder
+	HashBucketSize = DefaultHashBucketSize
+)
+
+// IDSize is the ID width in bytes of new files, set through the config file (see ApplyConfig)
+// Existing files keep the width recorded in their header, see ReadIDSize
+var IDSize = DefaultIDSize
+
+// ItemCacheSize is the item cache capacity of new item DAOs, set through the config file (see ApplyConfig)
+var ItemCacheSize = DefaultItemCacheSize
+
+// Data directory paths, relative to the working directory unless SetDataDir chose another root
+var (
+	DataDir       = DefaultDataDir
 	BinDir        = "data/bin"
 	IndexDir      = "data/indexes"
 	CompressedDir = "data/compressed"
 	SeedDir       = "data/seed"
 	KeysDir       = "data/keys"
+	OplogDir      = "data/oplog"
+	ReplayDir     = "data/replay"
+	CompactionDir = "data/compaction"
+	ReplicaDir    = "data/replica"
+	LogsDir       = "data/logs"
+	TrashDir      = "data/trash"
+)
 
-	// Compression algorithms
-	AlgorithmHuffman = "huffman"
-	AlgorithmLZW     = "lzw"
-	AlgorithmUnknown = "unknown"
+// Header flags, stored in the flags byte of FormatVersionFlags headers
+const (
+	// FlagNamesEncrypted marks a file whose names are RSA encrypted
+	FlagNamesEncrypted byte = 1 << 0
+
+	// FlagNamesPlaintext marks a file whose names are stored as plain text
+	// Files with neither name flag follow the global crypto setting
+	FlagNamesPlaintext byte = 1 << 1
 )
 
-// CalculateHeaderSize returns the total header size for a given filename
+// CalculateHeaderSize returns the total header size for a given filename in the current format version
 func CalculateHeaderSize(filename string) int {
-	return HeaderFixedSize + len(filename)
+	return HeaderSizeForVersion(CurrentFormatVersion, len(filename))
+}
+
+// HeaderSizeForVersion returns the total header size for a filename length in a given format version
+func HeaderSizeForVersion(version, filenameLen int) int {
+	size := HeaderFixedSize + filenameLen
+	if version >= FormatVersionFlags {
+		size += HeaderFlagsSize
+	}
+	if version >= FormatVersionIDSize {
+		size += HeaderIDSizeSize
+	}
+	return size + NextIDFieldSize(version) - HeaderFieldSize
 }
 
-// BinPath returns the full path for a file in the bin directory
+// NextIDFieldSize returns the size of the nextId header field in a given format version
+func NextIDFieldSize(version int) int {
+	if version >= FormatVersionWideNextID {
+		return WideNextIDFieldSize
+	}
+	return HeaderFieldSize
+}
+
+// headerCountsSize returns the size of the header counts in a given format version
+func headerCountsSize(version int) int {
+	return HeaderFieldSize*2 + NextIDFieldSize(version)
+}
+
+// ValidIDSize reports whether size is a supported ID width in bytes
+func ValidIDSize(size int) bool {
+	return size == LegacyIDSize || size == DefaultIDSize || size == WideIDSize
+}
+
+// BinPath returns the full path for a file in the bin directory, at its current generation
 func BinPath(filename string) string {
-	return filepath.Join(BinDir, filename)
+	return ResolveBinPath(BinDir, filename)
 }
 
 // IndexPath returns the full path for a file in the indexes directory
@@ -64,6 +181,16 @@ func IndexPath(filename string) string {
 	return filepath.Join(IndexDir, filename)
 }
 
+// OplogPath returns the path of the operation log file
+func OplogPath() string {
+	return filepath.Join(OplogDir, "oplog.bin")
+}
+
+// LogPath returns the path of the application log file in the data directory
+func LogPath() string {
+	return filepath.Join(LogsDir, "app.log")
+}
+
 // CompressedPath returns the full path for a file in the compressed directory
 func CompressedPath(filename string) string {
 	return filepath.Join(CompressedDir, filename)
@@ -82,6 +209,12 @@ func DetectCompressionAlgorithm(filename string) string {
 	if strings.Contains(filename, ".lzw.") {
 		return AlgorithmLZW
 	}
+	if strings.Contains(filename, ".gzip.") {
+		return AlgorithmGzip
+	}
+	if strings.Contains(filename, ".zstd.") {
+		return AlgorithmZstd
+	}
 	return AlgorithmUnknown
 }
 
@@ -94,5 +227,8 @@ func CompressedFilename(originalName, algorithm string) string {
 func DecompressedFilename(compressedName string) string {
 	name := strings.TrimSuffix(compressedName, ".huffman.compressed")
 	name = strings.TrimSuffix(name, ".lzw.compressed")
+	name = strings.TrimSuffix(name, ".gzip.compressed")
+	name = strings.TrimSuffix(name, ".zstd.compressed")
+	name = strings.TrimSuffix(name, ".compressed")
 	return name
 }
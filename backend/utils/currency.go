@@ -0,0 +1,113 @@
+package utils
+
+import (
+	"BinaryCRUD/backend/money"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+)
+
+// BaseCurrency is the currency prices are assumed to be in when no currency is stored
+const BaseCurrency = "USD"
+
+// CurrencyRates is a conversion table to a base currency
+// Each rate is the value of one unit of the currency in the base currency
+type CurrencyRates struct {
+	Base  string             `json:"base"`
+	Rates map[string]float64 `json:"rates"`
+}
+
+// DefaultCurrencyRates returns a table that only knows the base currency
+func DefaultCurrencyRates() *CurrencyRates {
+	return &CurrencyRates{Base: BaseCurrency, Rates: map[string]float64{}}
+}
+
+// LoadCurrencyRates reads a conversion table from a JSON file
+// A missing file yields the default table
+func LoadCurrencyRates(path string) (*CurrencyRates, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return DefaultCurrencyRates(), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read currency rates: %w", err)
+	}
+
+	var rates CurrencyRates
+	if err := json.Unmarshal(data, &rates); err != nil {
+		return nil, fmt.Errorf("failed to parse currency rates: %w", err)
+	}
+	if rates.Base == "" {
+		rates.Base = BaseCurrency
+	}
+	if err := ValidateCurrencyCode(rates.Base); err != nil {
+		return nil, err
+	}
+	if rates.Rates == nil {
+		rates.Rates = map[string]float64{}
+	}
+	for code, rate := range rates.Rates {
+		if err := ValidateCurrencyCode(code); err != nil {
+			return nil, err
+		}
+		if rate <= 0 || math.IsInf(rate, 0) || math.IsNaN(rate) {
+			return nil, fmt.Errorf("invalid rate %v for %s", rate, code)
+		}
+	}
+	return &rates, nil
+}
+
+// ToBase converts an amount in cents of the given currency to cents of the base currency
+// An empty code means the amount is already in the base currency
+func (r *CurrencyRates) ToBase(cents uint64, code string) (uint64, error) {
+	if code == "" || code == r.Base {
+		return cents, nil
+	}
+	rate, ok := r.Rates[code]
+	if !ok {
+		return 0, fmt.Errorf("no conversion rate from %s to %s", code, r.Base)
+	}
+	converted := math.Round(float64(cents) * rate)
+	if converted > float64(MaxPrice) {
+		return 0, ErrPriceOverflow
+	}
+	return uint64(converted), nil
+}
+
+// ValidateCurrencyCode checks that code is a three-letter uppercase ISO 4217 code
+func ValidateCurrencyCode(code string) error {
+	if len(code) != 3 || strings.ToUpper(code) != code || strings.IndexFunc(code, func(r rune) bool { return r < 'A' || r > 'Z' }) >= 0 {
+		return fmt.Errorf("invalid currency code %q (expected three uppercase letters)", code)
+	}
+	return nil
+}
+
+// EncodeCurrency serializes a currency code for the ExtCurrency extension
+func EncodeCurrency(code string) ([]byte, error) {
+	if err := ValidateCurrencyCode(code); err != nil {
+		return nil, err
+	}
+	return []byte(code), nil
+}
+
+// DecodeCurrency reads the ExtCurrency extension
+// Returns an empty code when the price is in the base currency
+func DecodeCurrency(ext map[byte][]byte) (string, error) {
+	data, ok := ext[ExtCurrency]
+	if !ok {
+		return "", nil
+	}
+	if err := ValidateCurrencyCode(string(data)); err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// FormatPrice writes an amount in cents in the locale its currency is usually written in, e.g. $1,234.56 or 1.234,56 €
+// Unknown currencies are written as 1,234.56 XYZ
+func FormatPrice(cents uint64, code string) string {
+	locale, _ := money.LookupLocale(money.HomeLocale(code))
+	return money.Format(cents, code, locale)
+}
@@ -2,6 +2,7 @@ package utils
 
 import (
 	"BinaryCRUD/backend/index"
+	"context"
 	"fmt"
 	"log"
 	"os"
@@ -14,23 +15,60 @@ import (
 func IndexPathFromBinFile(filePath string) string {
 	baseName := filepath.Base(filePath)
 	baseName = strings.TrimSuffix(baseName, ".bin")
-	return filepath.Join("data", "indexes", baseName+".idx")
+	return filepath.Join(IndexDir, baseName+".idx")
+}
+
+// IndexDirtyExt is the extension of the marker left next to an index while it is behind its data file
+const IndexDirtyExt = ".dirty"
+
+// IndexDirtyPath returns the path of the dirty marker of an index
+func IndexDirtyPath(indexPath string) string {
+	return indexPath + IndexDirtyExt
+}
+
+// MarkIndexDirty leaves the dirty marker of an index before its data file changes
+// If the process dies before the index is saved again, the marker tells the next load to rebuild it
+func MarkIndexDirty(indexPath string) error {
+	path := IndexDirtyPath(indexPath)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", path, err)
+	}
+	if err := os.WriteFile(path, nil, 0644); err != nil {
+		return fmt.Errorf("failed to mark index dirty: %w", err)
+	}
+	return nil
+}
+
+// ClearIndexDirty removes the dirty marker of an index once it was saved after the data file changed
+func ClearIndexDirty(indexPath string) error {
+	if err := os.Remove(IndexDirtyPath(indexPath)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to clear index dirty marker: %w", err)
+	}
+	return nil
+}
+
+// IndexDirty reports whether an index was left behind its data file
+func IndexDirty(indexPath string) bool {
+	_, err := os.Stat(IndexDirtyPath(indexPath))
+	return err == nil
 }
 
 // RebuildFunc is a function type for index rebuilding
 type RebuildFunc func(binFilePath, indexPath string) error
 
-// initializeBTreeIndex is a generic helper for B+ tree index initialization
-func initializeBTreeIndex(filePath string, rebuildFn func(string, string) (*index.BTree, error)) (string, *index.BTree) {
-	indexPath := IndexPathFromBinFile(filePath)
-
+// loadBTreeIndex is a generic helper for B+ tree index initialization
+// A saved index is trusted unless its dirty marker shows a write that never reached it
+func loadBTreeIndex(filePath, indexPath string, rebuildFn func(context.Context, string, string) (*index.BTree, error)) *index.BTree {
 	tree, err := index.Load(indexPath)
+	if err == nil && IndexDirty(indexPath) {
+		err = fmt.Errorf("index was not saved after the last write")
+	}
 	if err != nil {
-		log.Printf("Index load failed for %s, rebuilding from data file...", indexPath)
-		tree, err = rebuildFn(filePath, indexPath)
+		log.Printf("Index load failed for %s (%v), rebuilding from data file...", indexPath, err)
+		tree, err = rebuildFn(context.Background(), filePath, indexPath)
 		if err != nil {
 			log.Printf("Index rebuild failed: %v, creating empty tree", err)
-			tree = index.NewBTree(DefaultBTreeOrder)
+			tree = index.NewBTree(BTreeOrder)
 		} else {
 			log.Printf("Index rebuilt successfully for %s", indexPath)
 		}
@@ -38,7 +76,7 @@ func initializeBTreeIndex(filePath string, rebuildFn func(string, string) (*inde
 		os.Remove(indexPath + ".tmp")
 	}
 
-	return indexPath, tree
+	return tree
 }
 
 // InitializeDAOIndex creates an index path and loads or creates a B+ tree index for items
@@ -46,24 +84,58 @@ func initializeBTreeIndex(filePath string, rebuildFn func(string, string) (*inde
 // Index files are stored in data/indexes/ directory
 // If index is missing or corrupted, it will be rebuilt from the .bin file
 func InitializeDAOIndex(filePath string) (string, *index.BTree) {
-	return initializeBTreeIndex(filePath, RebuildBTreeIndex)
+	indexPath := IndexPathFromBinFile(filePath)
+	return indexPath, LoadDAOIndex(filePath, indexPath)
+}
+
+// LoadDAOIndex loads the item index at indexPath, rebuilding it from the .bin file when corrupted
+// Unlike InitializeDAOIndex it does not resolve paths, so it is safe to run while the data directory changes
+func LoadDAOIndex(filePath, indexPath string) *index.BTree {
+	return loadBTreeIndex(filePath, indexPath, RebuildBTreeIndex)
 }
 
 // InitializeCollectionDAOIndex creates an index for collections (orders/promotions)
 // If index is missing or corrupted, it will be rebuilt from the .bin file
 func InitializeCollectionDAOIndex(filePath string) (string, *index.BTree) {
-	return initializeBTreeIndex(filePath, RebuildCollectionBTreeIndex)
+	indexPath := IndexPathFromBinFile(filePath)
+	return indexPath, LoadCollectionDAOIndex(filePath, indexPath)
+}
+
+// LoadCollectionDAOIndex loads a collection index at indexPath, rebuilding it from the .bin file when corrupted
+func LoadCollectionDAOIndex(filePath, indexPath string) *index.BTree {
+	return loadBTreeIndex(filePath, indexPath, RebuildCollectionBTreeIndex)
+}
+
+// LoadRecordIndex loads the index of a file of generic records, rebuilding it from the .bin file when corrupted
+func LoadRecordIndex(filePath, indexPath string) *index.BTree {
+	return loadBTreeIndex(filePath, indexPath, RebuildRecordBTreeIndex)
 }
 
 // InitializeOrderPromotionIndex creates an extensible hash index for order-promotion relationships
 // If index is missing or corrupted, it will be rebuilt from the .bin file
 func InitializeOrderPromotionIndex(filePath string, bucketSize int) (string, *index.ExtensibleHash) {
 	indexPath := IndexPathFromBinFile(filePath)
+	return indexPath, LoadOrderPromotionIndex(filePath, indexPath, bucketSize)
+}
 
+// LoadOrderPromotionIndex loads the hash index at indexPath, rebuilding it from the .bin file when stale or corrupted
+// or when its dirty marker shows a write that never reached it
+func LoadOrderPromotionIndex(filePath, indexPath string, bucketSize int) *index.ExtensibleHash {
 	hashIndex, err := index.LoadExtensibleHash(indexPath)
+	if _, statErr := os.Stat(filePath); err == nil && statErr == nil {
+		// An index saved before the last change to the data file is stale
+		indexGeneration, tracked := hashIndex.Generation()
+		dataGeneration, genErr := ReadGeneration(filePath)
+		if genErr != nil || !tracked || indexGeneration != dataGeneration {
+			err = fmt.Errorf("index generation %d does not match data generation %d", indexGeneration, dataGeneration)
+		}
+	}
+	if err == nil && IndexDirty(indexPath) {
+		err = fmt.Errorf("index was not saved after the last write")
+	}
 	if err != nil {
-		log.Printf("Hash index load failed for %s, rebuilding from data file...", indexPath)
-		hashIndex, err = RebuildExtensibleHashIndex(filePath, indexPath, bucketSize)
+		log.Printf("Hash index load failed for %s (%v), rebuilding from data file...", indexPath, err)
+		hashIndex, err = RebuildExtensibleHashIndex(context.Background(), filePath, indexPath, bucketSize)
 		if err != nil {
 			log.Printf("Hash index rebuild failed: %v, creating empty hash", err)
 			hashIndex = index.NewExtensibleHash(bucketSize)
@@ -74,7 +146,7 @@ func InitializeOrderPromotionIndex(filePath string, bucketSize int) (string, *in
 		os.Remove(indexPath + ".tmp")
 	}
 
-	return indexPath, hashIndex
+	return hashIndex
 }
 
 // DeleteFromBTreeIndex handles the common delete pattern for B+ tree indexed DAOs.
@@ -84,7 +156,7 @@ func DeleteFromBTreeIndex(tree *index.BTree, indexPath, filePath string, id uint
 	// Remove from index first
 	err := tree.Delete(id)
 	if err != nil {
-		return fmt.Errorf("%s not found: %w", entityName, err)
+		return WithCode(CodeNotFound, fmt.Errorf("%s not found: %w", entityName, err), RecordDetails(entityName, id))
 	}
 
 	// Save updated index
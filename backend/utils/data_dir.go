@@ -0,0 +1,147 @@
+package utils
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// DefaultDataDir is the data directory used when none is configured
+const DefaultDataDir = "data"
+
+// DataDirEnv is the environment variable that overrides the data directory
+const DataDirEnv = "BINARYCRUD_DATA_DIR"
+
+// movedDataDirs are the generated subdirectories moved by MoveDataDir
+var movedDataDirs = []string{"bin", "indexes", "compressed", "keys", "oplog", "replay", "compaction", "replica", "logs", "trash"}
+
+// copiedDataDirs are the subdirectories copied by MoveDataDir, leaving the originals in place
+var copiedDataDirs = []string{"seed"}
+
+// movedDataFiles are the files at the root of the data directory moved by MoveDataDir
+var movedDataFiles = []string{ConfigFile}
+
+// SetDataDir makes every path helper resolve inside root
+func SetDataDir(root string) {
+	DataDir = root
+	BinDir = filepath.Join(root, "bin")
+	IndexDir = filepath.Join(root, "indexes")
+	CompressedDir = filepath.Join(root, "compressed")
+	SeedDir = filepath.Join(root, "seed")
+	KeysDir = filepath.Join(root, "keys")
+	OplogDir = filepath.Join(root, "oplog")
+	ReplayDir = filepath.Join(root, "replay")
+	CompactionDir = filepath.Join(root, "compaction")
+	ReplicaDir = filepath.Join(root, "replica")
+	LogsDir = filepath.Join(root, "logs")
+	TrashDir = filepath.Join(root, "trash")
+}
+
+// DataDirFromEnv returns the data directory set in DataDirEnv, or DefaultDataDir
+func DataDirFromEnv() string {
+	if dir := os.Getenv(DataDirEnv); dir != "" {
+		return dir
+	}
+	return DefaultDataDir
+}
+
+// MoveDataDir moves the generated data and config from one data directory to another and copies its seed files
+// Fails before moving anything when the target already holds data
+// Returns the subdirectories and files that were moved or copied
+func MoveDataDir(from, to string) ([]string, error) {
+	for _, name := range append(append([]string{}, movedDataDirs...), copiedDataDirs...) {
+		if entries, err := os.ReadDir(filepath.Join(to, name)); err == nil && len(entries) > 0 {
+			if _, err := os.Stat(filepath.Join(from, name)); err == nil {
+				return nil, fmt.Errorf("%s already contains %s data", to, name)
+			}
+		}
+	}
+
+	if err := os.MkdirAll(to, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %w", err)
+	}
+
+	var moved []string
+	for _, name := range movedDataDirs {
+		src, dst := filepath.Join(from, name), filepath.Join(to, name)
+		if _, err := os.Stat(src); os.IsNotExist(err) {
+			continue
+		}
+		os.Remove(dst) // an empty target directory is in the way of the rename
+		if err := os.Rename(src, dst); err != nil {
+			// Rename fails across file systems, copy instead
+			if err := copyDir(src, dst); err != nil {
+				return moved, fmt.Errorf("failed to move %s: %w", name, err)
+			}
+			os.RemoveAll(src)
+		}
+		moved = append(moved, name)
+	}
+
+	for _, name := range movedDataFiles {
+		src, dst := filepath.Join(from, name), filepath.Join(to, name)
+		if _, err := os.Stat(src); os.IsNotExist(err) {
+			continue
+		}
+		if err := copyFile(src, dst); err != nil {
+			return moved, fmt.Errorf("failed to move %s: %w", name, err)
+		}
+		os.Remove(src)
+		moved = append(moved, name)
+	}
+
+	for _, name := range copiedDataDirs {
+		src, dst := filepath.Join(from, name), filepath.Join(to, name)
+		if _, err := os.Stat(src); os.IsNotExist(err) {
+			continue
+		}
+		if err := copyDir(src, dst); err != nil {
+			return moved, fmt.Errorf("failed to copy %s: %w", name, err)
+		}
+		moved = append(moved, name)
+	}
+
+	return moved, nil
+}
+
+// copyDir recursively copies the files of src into dst
+func copyDir(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if entry.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		return copyFile(path, target)
+	})
+}
+
+// copyFile copies a single file, keeping its permissions
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
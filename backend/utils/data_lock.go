@@ -0,0 +1,65 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ErrDataDirLocked means another running instance holds the data directory lock
+var ErrDataDirLocked = WithCode(CodeConflict, errors.New("data directory is in use by another instance"), nil)
+
+// errFileLocked is returned by tryLockFile when the lock is held elsewhere
+var errFileLocked = errors.New("file is locked")
+
+// DataLockFile is the name of the lock file inside the data directory
+const DataLockFile = ".lock"
+
+// DataLock is an exclusive lock on the data directory, held for the lifetime of an instance
+type DataLock struct {
+	file *os.File
+}
+
+// LockDataDir takes the lock on dir so only one instance writes to its files
+// Fails with ErrDataDirLocked when another process holds it; the lock file records the owner's PID
+func LockDataDir(dir string) (*DataLock, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %w", err)
+	}
+
+	file, err := os.OpenFile(filepath.Join(dir, DataLockFile), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file: %w", err)
+	}
+
+	if err := tryLockFile(file); err != nil {
+		owner, _ := os.ReadFile(file.Name())
+		file.Close()
+		if errors.Is(err, errFileLocked) {
+			if pid := strings.TrimSpace(string(owner)); pid != "" {
+				return nil, fmt.Errorf("%w (pid %s)", ErrDataDirLocked, pid)
+			}
+			return nil, ErrDataDirLocked
+		}
+		return nil, fmt.Errorf("failed to lock data directory: %w", err)
+	}
+
+	if err := file.Truncate(0); err == nil {
+		file.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0)
+	}
+	return &DataLock{file: file}, nil
+}
+
+// Release unlocks the data directory
+func (l *DataLock) Release() error {
+	if l == nil || l.file == nil {
+		return nil
+	}
+	unlockFile(l.file)
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
@@ -7,12 +7,13 @@ import (
 )
 
 // entryMatcher defines how to match an entry and where its tombstone is located
+// The tombstone follows keyFields IDs, whose width is read from the file header
 type entryMatcher struct {
-	match        func(entryData []byte) bool
-	tombstonePos func(entryPos int64) int64
-	minSize      int
+	match        func(entryData []byte, idSize int) bool
+	keyFields    int
 	notFoundErr  string
 	alreadyDelErr string
+	bumpGeneration bool // composite key tables count modifications in the nextId header field
 }
 
 // softDeleteCore is the shared implementation for soft deletion
@@ -28,34 +29,39 @@ func softDeleteCore(filePath string, mu *sync.Mutex, matcher entryMatcher, onDel
 	}
 	defer file.Close()
 
-	_, entitiesCount, tombstoneCount, nextId, err := ReadHeader(file)
-	if err != nil {
+	if _, _, _, _, err = ReadHeader(file); err != nil {
 		return fmt.Errorf("failed to read header: %w", err)
 	}
+	idSize, err := ReadIDSize(file)
+	if err != nil {
+		return err
+	}
+	tombstoneOffset := matcher.keyFields * idSize // relative offset within entry
 
 	entries, err := SplitFileIntoEntries(filePath)
 	if err != nil {
 		return fmt.Errorf("failed to split file into entries: %w", err)
 	}
 
+	foundDeleted := false
 	for _, entry := range entries {
 		entryData := entry.Data
-		if len(entryData) < matcher.minSize {
+		if len(entryData) < tombstoneOffset+TombstoneSize {
 			continue
 		}
 
-		if !matcher.match(entryData) {
+		if !matcher.match(entryData, idSize) {
 			continue
 		}
 
-		// Found the entry - check tombstone
-		tombstoneOffset := matcher.tombstonePos(0) // relative offset within entry
+		// Found the entry - check tombstone, an active duplicate may appear later in the file
 		if entryData[tombstoneOffset] != 0x00 {
-			return fmt.Errorf(matcher.alreadyDelErr)
+			foundDeleted = true
+			continue
 		}
 
 		// Write tombstone
-		tombstoneFilePos := entry.Position + matcher.tombstonePos(0)
+		tombstoneFilePos := entry.Position + int64(tombstoneOffset)
 		if _, err = file.Seek(tombstoneFilePos, 0); err != nil {
 			return fmt.Errorf("failed to seek to tombstone: %w", err)
 		}
@@ -66,13 +72,19 @@ func softDeleteCore(filePath string, mu *sync.Mutex, matcher entryMatcher, onDel
 			return fmt.Errorf("failed to sync tombstone to disk: %w", err)
 		}
 
-		if err = UpdateHeader(file, entitiesCount, tombstoneCount+1, nextId); err != nil {
+		err = ModifyHeader(file, func(counts *HeaderCounts) {
+			counts.TombstoneCount++
+			if matcher.bumpGeneration {
+				counts.NextId++
+			}
+		})
+		if err != nil {
 			return fmt.Errorf("failed to update header: %w", err)
 		}
 
 		if onDelete != nil {
-			// Extract ID for callback (first IDSize bytes)
-			id, _, _ := ReadFixedNumber(IDSize, entryData, 0)
+			// Extract ID for callback (first idSize bytes)
+			id, _, _ := ReadFixedNumber(idSize, entryData, 0)
 			if err = onDelete(id); err != nil {
 				fmt.Printf("Warning: onDelete callback failed: %v\n", err)
 			}
@@ -81,6 +93,9 @@ func softDeleteCore(filePath string, mu *sync.Mutex, matcher entryMatcher, onDel
 		return nil
 	}
 
+	if foundDeleted {
+		return fmt.Errorf(matcher.alreadyDelErr)
+	}
 	return fmt.Errorf(matcher.notFoundErr)
 }
 
@@ -89,12 +104,11 @@ func softDeleteCore(filePath string, mu *sync.Mutex, matcher entryMatcher, onDel
 // The optional indexDeleteFunc can be provided to also remove the entry from an index
 func SoftDeleteByID(filePath string, id uint64, mu *sync.Mutex, indexDeleteFunc func(uint64) error) error {
 	matcher := entryMatcher{
-		match: func(entryData []byte) bool {
-			entryID, _, err := ReadFixedNumber(IDSize, entryData, 0)
+		match: func(entryData []byte, idSize int) bool {
+			entryID, _, err := ReadFixedNumber(idSize, entryData, 0)
 			return err == nil && entryID == id
 		},
-		tombstonePos:  func(_ int64) int64 { return int64(IDSize) },
-		minSize:       IDSize + TombstoneSize,
+		keyFields:     1,
 		notFoundErr:   fmt.Sprintf("entry with ID %d not found", id),
 		alreadyDelErr: fmt.Sprintf("entry with ID %d is already deleted", id),
 	}
@@ -103,24 +117,112 @@ func SoftDeleteByID(filePath string, id uint64, mu *sync.Mutex, indexDeleteFunc
 
 // SoftDeleteByCompositeKey performs a logical deletion for entries with composite keys
 // Used for junction tables like order_promotions where the key is (orderID, promotionID)
-// Format: [orderID(2)][promotionID(2)][tombstone(1)]
+// Format: [orderID(idSize)][promotionID(idSize)][tombstone(1)]
+// Increments the file generation (see AppendEntryManual)
 func SoftDeleteByCompositeKey(filePath string, key1, key2 uint64, mu *sync.Mutex) error {
 	matcher := entryMatcher{
-		match: func(entryData []byte) bool {
-			entryKey1, offset, err := ReadFixedNumber(IDSize, entryData, 0)
+		match: func(entryData []byte, idSize int) bool {
+			entryKey1, offset, err := ReadFixedNumber(idSize, entryData, 0)
 			if err != nil {
 				return false
 			}
-			entryKey2, _, err := ReadFixedNumber(IDSize, entryData, offset)
+			entryKey2, _, err := ReadFixedNumber(idSize, entryData, offset)
 			if err != nil {
 				return false
 			}
 			return entryKey1 == key1 && entryKey2 == key2
 		},
-		tombstonePos:  func(_ int64) int64 { return int64(IDSize * 2) },
-		minSize:       IDSize*2 + TombstoneSize,
+		keyFields:     2,
 		notFoundErr:   fmt.Sprintf("entry with composite key (%d, %d) not found", key1, key2),
 		alreadyDelErr: fmt.Sprintf("entry with composite key (%d, %d) is already deleted", key1, key2),
+		bumpGeneration: true,
 	}
 	return softDeleteCore(filePath, mu, matcher, nil)
 }
+
+// SoftDeleteWhere tombstones every active entry keyed by one ID that match accepts, in a single pass over the file
+// The file is synced and its header updated once for all of them
+// Returns the IDs of the tombstoned entries in file order
+func SoftDeleteWhere(filePath string, match func(entryData []byte, idSize int) bool) ([]uint64, error) {
+	entries, idSize, err := softDeleteWhereCore(filePath, 1, false, match)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]uint64, 0, len(entries))
+	for _, entryData := range entries {
+		id, _, _ := ReadFixedNumber(idSize, entryData, 0)
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// SoftDeleteCompositeWhere is SoftDeleteWhere for composite key tables such as order_promotions
+// The file generation advances once per tombstoned entry, as with SoftDeleteByCompositeKey
+// Returns the keys of the tombstoned entries in file order
+func SoftDeleteCompositeWhere(filePath string, match func(entryData []byte, idSize int) bool) ([][2]uint64, error) {
+	entries, idSize, err := softDeleteWhereCore(filePath, 2, true, match)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([][2]uint64, 0, len(entries))
+	for _, entryData := range entries {
+		key1, offset, _ := ReadFixedNumber(idSize, entryData, 0)
+		key2, _, _ := ReadFixedNumber(idSize, entryData, offset)
+		keys = append(keys, [2]uint64{key1, key2})
+	}
+	return keys, nil
+}
+
+// softDeleteWhereCore tombstones the active entries that match accepts, whose tombstone follows keyFields IDs
+// Returns the data of the tombstoned entries and the ID width of the file
+func softDeleteWhereCore(filePath string, keyFields int, bumpGeneration bool, match func(entryData []byte, idSize int) bool) ([][]byte, int, error) {
+	file, err := os.OpenFile(filePath, os.O_RDWR, 0644)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	idSize, err := ReadIDSize(file)
+	if err != nil {
+		return nil, 0, err
+	}
+	tombstoneOffset := keyFields * idSize
+
+	entries, err := SplitFileIntoEntries(filePath)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to split file into entries: %w", err)
+	}
+
+	var deleted [][]byte
+	for _, entry := range entries {
+		entryData := entry.Data
+		if len(entryData) < tombstoneOffset+TombstoneSize || entryData[tombstoneOffset] != 0x00 {
+			continue
+		}
+		if !match(entryData, idSize) {
+			continue
+		}
+
+		if _, err = file.WriteAt([]byte{0x01}, entry.Position+int64(tombstoneOffset)); err != nil {
+			return nil, 0, fmt.Errorf("failed to write tombstone: %w", err)
+		}
+		deleted = append(deleted, entryData)
+	}
+	if len(deleted) == 0 {
+		return deleted, idSize, nil
+	}
+
+	if err = file.Sync(); err != nil {
+		return nil, 0, fmt.Errorf("failed to sync tombstones to disk: %w", err)
+	}
+	err = ModifyHeader(file, func(counts *HeaderCounts) {
+		counts.TombstoneCount += len(deleted)
+		if bumpGeneration {
+			counts.NextId += len(deleted)
+		}
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to update header: %w", err)
+	}
+	return deleted, idSize, nil
+}
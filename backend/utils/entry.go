@@ -10,6 +10,7 @@ import (
 type EntryInfo struct {
 	Data     []byte // The raw entry data (without record separator)
 	Position int64  // File offset where this entry starts
+	IDSize   int    // ID width of the file the entry was read from
 }
 
 // SplitFileIntoEntries reads a binary file and splits it into individual entries
@@ -22,18 +23,29 @@ func SplitFileIntoEntries(filePath string) ([]EntryInfo, error) {
 		return nil, fmt.Errorf("failed to read file: %w", err)
 	}
 
+	return splitEntries(fileData)
+}
+
+// splitEntries splits the content of a binary file into its entries, which point into fileData
+func splitEntries(fileData []byte) ([]EntryInfo, error) {
 	// Check minimum size for header
 	if len(fileData) < MagicSize+FilenameLengthSize {
 		return []EntryInfo{}, nil
 	}
 
-	// Get actual header size by reading filename length
-	filenameLen := int(fileData[MagicSize])
-	headerSize := CalculateHeaderSize(string(fileData[MagicSize+FilenameLengthSize : MagicSize+FilenameLengthSize+filenameLen]))
+	// Get actual header size by reading format version and filename length
+	headerSize, err := headerSizeFromPrefix(fileData[:MagicSize+FilenameLengthSize])
+	if err != nil {
+		return nil, err
+	}
 
 	if len(fileData) < headerSize {
 		return []EntryInfo{}, nil
 	}
+	idSize, err := idSizeFromHeader(fileData[:headerSize])
+	if err != nil {
+		return nil, err
+	}
 
 	entries := make([]EntryInfo, 0)
 	offset := headerSize
@@ -68,6 +80,7 @@ func SplitFileIntoEntries(filePath string) ([]EntryInfo, error) {
 		entries = append(entries, EntryInfo{
 			Data:     recordData,
 			Position: int64(newOffset), // Position points to start of record data (after length)
+			IDSize:   idSize,
 		})
 
 		// Move to next record
@@ -80,14 +93,20 @@ func SplitFileIntoEntries(filePath string) ([]EntryInfo, error) {
 // EnsureFileExists creates a binary file with an empty header if it doesn't exist
 // The filename is extracted from the filePath (without .bin extension) and stored in the header
 func EnsureFileExists(filePath string) error {
+	return EnsureFileExistsWithFlags(filePath, 0)
+}
+
+// EnsureFileExistsWithFlags creates a binary file with an empty header carrying flags if it doesn't exist
+// The flags of an existing file are left as they are
+func EnsureFileExistsWithFlags(filePath string, flags byte) error {
 	// Check if file already exists
 	if _, err := os.Stat(filePath); err == nil {
 		// File exists, nothing to do
 		return nil
 	}
 
-	// Extract just the filename from the path, without the .bin extension
-	basename := filepath.Base(filePath)
+	// Extract just the filename from the path, without its generation and the .bin extension
+	basename := LogicalBinName(filepath.Base(filePath))
 	filename := basename[:len(basename)-len(filepath.Ext(basename))]
 
 	// Create the file
@@ -98,7 +117,7 @@ func EnsureFileExists(filePath string) error {
 	defer file.Close()
 
 	// Write empty header with filename
-	header, err := WriteHeader(filename, 0, 0, 0)
+	header, err := WriteHeaderWithFlags(filename, flags, 0, 0, 0)
 	if err != nil {
 		return fmt.Errorf("failed to create header: %w", err)
 	}
@@ -126,8 +145,7 @@ func GetHeaderSize(filePath string) (int, error) {
 		return 0, fmt.Errorf("failed to read header")
 	}
 
-	filenameLen := int(header[MagicSize])
-	return CalculateHeaderSize(string(make([]byte, filenameLen))), nil
+	return headerSizeFromPrefix(header)
 }
 
 // GetHeaderSizeFromFile reads header size from an open file (resets position)
@@ -152,18 +170,17 @@ func GetHeaderSizeFromFile(file *os.File) (int, error) {
 		return 0, fmt.Errorf("failed to read header")
 	}
 
-	filenameLen := int(header[MagicSize])
-
-	// Read filename to get actual size
-	filenameBytes := make([]byte, filenameLen)
-	n, err = file.Read(filenameBytes)
-	if err != nil || n < filenameLen {
-		file.Seek(currentPos, 0)
-		return 0, fmt.Errorf("failed to read filename")
-	}
-
 	// Restore original position
 	file.Seek(currentPos, 0)
 
-	return CalculateHeaderSize(string(filenameBytes)), nil
+	return headerSizeFromPrefix(header)
+}
+
+// headerSizeFromPrefix returns the header size from the magic bytes and filename length that start a file
+func headerSizeFromPrefix(prefix []byte) (int, error) {
+	version, err := VersionFromMagic(prefix[:MagicSize])
+	if err != nil {
+		return 0, err
+	}
+	return HeaderSizeForVersion(version, int(prefix[MagicSize])), nil
 }
@@ -0,0 +1,116 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+)
+
+// ErrorCode classifies an error so callers can react to it without parsing its message
+type ErrorCode string
+
+// Error codes, from the most to the least specific
+const (
+	CodeNotFound   ErrorCode = "NotFound"   // the record does not exist
+	CodeDeleted    ErrorCode = "Deleted"    // the record exists but is marked deleted
+	CodeValidation ErrorCode = "Validation" // the input was rejected
+	CodeCorruption ErrorCode = "Corruption" // a file does not match its format
+	CodeConflict   ErrorCode = "Conflict"   // the operation clashes with existing data or the app state
+	CodeIO         ErrorCode = "IO"         // reading or writing a file failed
+	CodeCancelled  ErrorCode = "Cancelled"  // the user aborted the operation
+	CodeInternal   ErrorCode = "Internal"   // anything not classified
+)
+
+// CodedError attaches a code and details to an error, keeping its message
+// An empty code only adds details, the code comes from an error further down the chain
+type CodedError struct {
+	Code    ErrorCode
+	Details map[string]any
+	Err     error
+}
+
+// Error returns the message of the wrapped error
+func (e *CodedError) Error() string {
+	if e.Err == nil {
+		return string(e.Code)
+	}
+	return e.Err.Error()
+}
+
+// Unwrap returns the wrapped error
+func (e *CodedError) Unwrap() error {
+	return e.Err
+}
+
+// Is matches the code sentinels, so errors.Is(err, ErrNotFound) holds for any NotFound error
+func (e *CodedError) Is(target error) bool {
+	sentinel, ok := target.(*CodedError)
+	return ok && sentinel.Err == nil && sentinel.Code == e.Code
+}
+
+// Sentinels for errors.Is, one per code
+var (
+	ErrNotFound   = &CodedError{Code: CodeNotFound}
+	ErrDeleted    = &CodedError{Code: CodeDeleted}
+	ErrValidation = &CodedError{Code: CodeValidation}
+	ErrCorruption = &CodedError{Code: CodeCorruption}
+	ErrConflict   = &CodedError{Code: CodeConflict}
+	ErrIO         = &CodedError{Code: CodeIO}
+	ErrCancelled  = &CodedError{Code: CodeCancelled}
+)
+
+// WithCode classifies err with code and optional details; a nil err stays nil
+func WithCode(code ErrorCode, err error, details map[string]any) error {
+	if err == nil {
+		return nil
+	}
+	return &CodedError{Code: code, Details: details, Err: err}
+}
+
+// WithDetails adds details to err without changing its code; a nil err stays nil
+func WithDetails(err error, details map[string]any) error {
+	return WithCode("", err, details)
+}
+
+// RecordDetails returns the details identifying a record of kind ("item", "order"...)
+func RecordDetails(kind string, id uint64) map[string]any {
+	return map[string]any{"entity": kind, "id": id}
+}
+
+// ErrorCodeOf returns the code of the outermost classified error in the chain of err
+// Unclassified file errors are IO, and cancelled contexts are Cancelled
+func ErrorCodeOf(err error) ErrorCode {
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		if coded, ok := e.(*CodedError); ok && coded.Code != "" {
+			return coded.Code
+		}
+	}
+
+	if errors.Is(err, context.Canceled) {
+		return CodeCancelled
+	}
+
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return CodeIO
+	}
+	return CodeInternal
+}
+
+// ErrorDetails merges the details along the chain of err, outer errors taking precedence
+func ErrorDetails(err error) map[string]any {
+	details := make(map[string]any)
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		coded, ok := e.(*CodedError)
+		if !ok {
+			continue
+		}
+		for key, value := range coded.Details {
+			if _, set := details[key]; !set {
+				details[key] = value
+			}
+		}
+	}
+	return details
+}
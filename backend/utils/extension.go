@@ -0,0 +1,346 @@
+package utils
+
+import (
+	"BinaryCRUD/backend/crypto"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Extension tags stored in the optional record trailer
+const (
+	// ExtDiscount holds a promotion discount: [discountType(1)][value(4)]
+	ExtDiscount byte = 0x01
+
+	// ExtStock holds the stock quantity of an item: [quantity(4)]
+	ExtStock byte = 0x02
+
+	// ExtOrderStatus holds the lifecycle status of an order: [status(1)]
+	ExtOrderStatus byte = 0x03
+
+	// ExtCreatedAt holds the creation time of a record: [unixNanos(8)]
+	ExtCreatedAt byte = 0x04
+
+	// ExtCurrency holds the ISO 4217 currency code of an item price: [code(3)]
+	ExtCurrency byte = 0x05
+
+	// ExtPadding fills the unused tail of a reused record slot: [zeros...]
+	// It is dropped when decoding, so it never reaches the parsed extensions
+	ExtPadding byte = 0x06
+
+	// ExtExternalID holds the UUID other systems use to refer to an item: [uuid(16)]
+	ExtExternalID byte = 0x07
+
+	// ExtNameHash holds the keyed hash of the normalized name of a collection stored encrypted: [hash(16)]
+	ExtNameHash byte = 0x08
+)
+
+// PaddingOverhead is the size of an empty ExtPadding field: [tag(1)][length(2)]
+const PaddingOverhead = 3
+
+// Discount types
+const (
+	DiscountNone    byte = 0x00
+	DiscountPercent byte = 0x01 // value is a percentage (0-100)
+	DiscountFixed   byte = 0x02 // value is an amount in cents
+)
+
+// Discount is the discount a promotion grants on an order
+type Discount struct {
+	Type  byte
+	Value uint64
+}
+
+// EncodeExtensions serializes extension fields as a trailer appended after a record's fixed fields
+// Format: repeated [tag(1)][length(2)][value...], sorted by tag
+func EncodeExtensions(ext map[byte][]byte) ([]byte, error) {
+	tags := make([]int, 0, len(ext))
+	for tag := range ext {
+		tags = append(tags, int(tag))
+	}
+	sort.Ints(tags)
+
+	var trailer []byte
+	for _, tag := range tags {
+		value := ext[byte(tag)]
+		lengthBytes, err := WriteFixedNumber(2, uint64(len(value)))
+		if err != nil {
+			return nil, fmt.Errorf("extension 0x%02x too long: %w", tag, err)
+		}
+		trailer = append(trailer, byte(tag))
+		trailer = append(trailer, lengthBytes...)
+		trailer = append(trailer, value...)
+	}
+
+	return trailer, nil
+}
+
+// DecodeExtensions parses a record trailer written by EncodeExtensions
+// Returns nil when the trailer is empty or only holds padding
+func DecodeExtensions(data []byte) (map[byte][]byte, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	ext := make(map[byte][]byte)
+	offset := 0
+	for offset < len(data) {
+		tag := data[offset]
+		length, next, err := ReadFixedNumber(2, data, offset+1)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read extension length: %w", err)
+		}
+		if next+int(length) > len(data) {
+			return nil, fmt.Errorf("extension 0x%02x exceeds record", tag)
+		}
+		if tag != ExtPadding {
+			ext[tag] = append([]byte{}, data[next:next+int(length)]...)
+		}
+		offset = next + int(length)
+	}
+
+	if len(ext) == 0 {
+		return nil, nil
+	}
+	return ext, nil
+}
+
+// EncodePadding returns an ExtPadding field that takes exactly size bytes
+// size must be 0 or at least PaddingOverhead
+func EncodePadding(size int) ([]byte, error) {
+	if size == 0 {
+		return nil, nil
+	}
+	if size < PaddingOverhead {
+		return nil, fmt.Errorf("padding of %d bytes is smaller than its %d byte header", size, PaddingOverhead)
+	}
+	lengthBytes, err := WriteFixedNumber(2, uint64(size-PaddingOverhead))
+	if err != nil {
+		return nil, fmt.Errorf("padding too long: %w", err)
+	}
+	padding := make([]byte, size)
+	padding[0] = ExtPadding
+	copy(padding[1:], lengthBytes)
+	return padding, nil
+}
+
+// NameHash returns the keyed hash of a normalized name for the ExtNameHash extension
+// Names that only differ in case or whitespace hash equally
+func NameHash(name string) ([]byte, error) {
+	hash, err := crypto.NameHash(KeysDir, NormalizeName(name))
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash name: %w", err)
+	}
+	return hash, nil
+}
+
+// EncodeDiscount serializes a discount for the ExtDiscount extension
+func EncodeDiscount(d Discount) ([]byte, error) {
+	if err := ValidateDiscount(d); err != nil {
+		return nil, err
+	}
+	valueBytes, err := WriteFixedNumber(4, d.Value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write discount value: %w", err)
+	}
+	return append([]byte{d.Type}, valueBytes...), nil
+}
+
+// DecodeDiscount reads the ExtDiscount extension, a missing extension means no discount
+func DecodeDiscount(ext map[byte][]byte) (Discount, error) {
+	data, ok := ext[ExtDiscount]
+	if !ok {
+		return Discount{Type: DiscountNone}, nil
+	}
+	if len(data) != 5 {
+		return Discount{}, fmt.Errorf("invalid discount extension length %d", len(data))
+	}
+	value, _, err := ReadFixedNumber(4, data, 1)
+	if err != nil {
+		return Discount{}, err
+	}
+	return Discount{Type: data[0], Value: value}, nil
+}
+
+// ValidateDiscount checks the discount type and bounds
+func ValidateDiscount(d Discount) error {
+	switch d.Type {
+	case DiscountNone:
+		return nil
+	case DiscountPercent:
+		if d.Value > 100 {
+			return fmt.Errorf("percentage discount %d exceeds 100", d.Value)
+		}
+		return nil
+	case DiscountFixed:
+		return ValidatePrice(d.Value)
+	default:
+		return fmt.Errorf("unknown discount type %d", d.Type)
+	}
+}
+
+// Amount returns the discount granted on the given total, never more than the total itself
+func (d Discount) Amount(total uint64) uint64 {
+	var amount uint64
+	switch d.Type {
+	case DiscountPercent:
+		amount = total * d.Value / 100
+	case DiscountFixed:
+		amount = d.Value
+	}
+	if amount > total {
+		return total
+	}
+	return amount
+}
+
+// DiscountTypeName returns the name used for a discount type in the API
+func DiscountTypeName(t byte) string {
+	switch t {
+	case DiscountPercent:
+		return "percent"
+	case DiscountFixed:
+		return "fixed"
+	default:
+		return "none"
+	}
+}
+
+// ParseDiscountType converts an API discount type name to its stored value
+func ParseDiscountType(name string) (byte, error) {
+	switch name {
+	case "", "none":
+		return DiscountNone, nil
+	case "percent":
+		return DiscountPercent, nil
+	case "fixed":
+		return DiscountFixed, nil
+	default:
+		return 0, fmt.Errorf("unknown discount type %q (expected percent or fixed)", name)
+	}
+}
+
+// EncodeStock serializes a stock quantity for the ExtStock extension
+func EncodeStock(quantity uint64) ([]byte, error) {
+	data, err := WriteFixedNumber(4, quantity)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write stock: %w", err)
+	}
+	return data, nil
+}
+
+// DecodeStock reads the ExtStock extension
+// Returns false when the item does not track stock
+func DecodeStock(ext map[byte][]byte) (uint64, bool, error) {
+	data, ok := ext[ExtStock]
+	if !ok {
+		return 0, false, nil
+	}
+	quantity, _, err := ReadFixedNumber(4, data, 0)
+	if err != nil || len(data) != 4 {
+		return 0, false, fmt.Errorf("invalid stock extension")
+	}
+	return quantity, true, nil
+}
+
+// WithExtension returns a copy of ext with tag set to value (or removed when value is nil)
+func WithExtension(ext map[byte][]byte, tag byte, value []byte) map[byte][]byte {
+	result := make(map[byte][]byte, len(ext)+1)
+	for k, v := range ext {
+		result[k] = v
+	}
+	if value == nil {
+		delete(result, tag)
+	} else {
+		result[tag] = value
+	}
+	return result
+}
+
+// Order statuses, orders without the ExtOrderStatus extension are pending
+const (
+	OrderPending   byte = 0x00
+	OrderPaid      byte = 0x01
+	OrderShipped   byte = 0x02
+	OrderCancelled byte = 0x03
+)
+
+// orderStatusNames maps order statuses to their API names
+var orderStatusNames = map[byte]string{
+	OrderPending:   "pending",
+	OrderPaid:      "paid",
+	OrderShipped:   "shipped",
+	OrderCancelled: "cancelled",
+}
+
+// orderTransitions lists the statuses each status may move to
+var orderTransitions = map[byte][]byte{
+	OrderPending: {OrderPaid, OrderCancelled},
+	OrderPaid:    {OrderShipped, OrderCancelled},
+}
+
+// DecodeOrderStatus reads the ExtOrderStatus extension, a missing extension means pending
+func DecodeOrderStatus(ext map[byte][]byte) (byte, error) {
+	data, ok := ext[ExtOrderStatus]
+	if !ok {
+		return OrderPending, nil
+	}
+	if len(data) != 1 {
+		return 0, fmt.Errorf("invalid order status extension length %d", len(data))
+	}
+	if _, known := orderStatusNames[data[0]]; !known {
+		return 0, fmt.Errorf("unknown order status %d", data[0])
+	}
+	return data[0], nil
+}
+
+// OrderStatusName returns the API name of an order status
+func OrderStatusName(status byte) string {
+	if name, ok := orderStatusNames[status]; ok {
+		return name
+	}
+	return fmt.Sprintf("unknown(%d)", status)
+}
+
+// ParseOrderStatus converts an API order status name to its stored value
+func ParseOrderStatus(name string) (byte, error) {
+	for status, statusName := range orderStatusNames {
+		if statusName == name {
+			return status, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown order status %q (expected pending, paid, shipped or cancelled)", name)
+}
+
+// ValidateOrderTransition checks that an order may move from one status to another
+func ValidateOrderTransition(from, to byte) error {
+	for _, allowed := range orderTransitions[from] {
+		if allowed == to {
+			return nil
+		}
+	}
+	return fmt.Errorf("cannot change order status from %s to %s", OrderStatusName(from), OrderStatusName(to))
+}
+
+// EncodeTimestamp serializes a time for timestamp extensions such as ExtCreatedAt
+func EncodeTimestamp(t time.Time) []byte {
+	data, _ := WriteFixedNumber(8, uint64(t.UnixNano()))
+	return data
+}
+
+// DecodeTimestamp reads a timestamp extension
+// Returns false when the record has no such timestamp
+func DecodeTimestamp(ext map[byte][]byte, tag byte) (time.Time, bool, error) {
+	data, ok := ext[tag]
+	if !ok {
+		return time.Time{}, false, nil
+	}
+	if len(data) != 8 {
+		return time.Time{}, false, fmt.Errorf("invalid timestamp extension length %d", len(data))
+	}
+	nanos, _, err := ReadFixedNumber(8, data, 0)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	return time.Unix(0, int64(nanos)).UTC(), true, nil
+}
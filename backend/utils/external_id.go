@@ -0,0 +1,46 @@
+package utils
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// externalIDSize is the size of a UUID stored in the ExtExternalID extension
+const externalIDSize = 16
+
+// EncodeExternalID serializes a UUID in its canonical 8-4-4-4-12 form for the ExtExternalID extension
+func EncodeExternalID(uuid string) ([]byte, error) {
+	groups := strings.Split(uuid, "-")
+	if len(groups) != 5 || len(groups[0]) != 8 || len(groups[1]) != 4 || len(groups[2]) != 4 || len(groups[3]) != 4 || len(groups[4]) != 12 {
+		return nil, fmt.Errorf("invalid external ID %q: expected a UUID like 123e4567-e89b-12d3-a456-426614174000", uuid)
+	}
+	data, err := hex.DecodeString(strings.Join(groups, ""))
+	if err != nil {
+		return nil, fmt.Errorf("invalid external ID %q: %w", uuid, err)
+	}
+	return data, nil
+}
+
+// DecodeExternalID reads the ExtExternalID extension in canonical lowercase form
+// Returns an empty string when the record has no external ID
+func DecodeExternalID(ext map[byte][]byte) (string, error) {
+	data, ok := ext[ExtExternalID]
+	if !ok {
+		return "", nil
+	}
+	if len(data) != externalIDSize {
+		return "", fmt.Errorf("invalid external ID extension")
+	}
+	text := hex.EncodeToString(data)
+	return text[:8] + "-" + text[8:12] + "-" + text[12:16] + "-" + text[16:20] + "-" + text[20:], nil
+}
+
+// NormalizeExternalID returns the canonical lowercase form of a UUID
+func NormalizeExternalID(uuid string) (string, error) {
+	data, err := EncodeExternalID(uuid)
+	if err != nil {
+		return "", err
+	}
+	return DecodeExternalID(map[byte][]byte{ExtExternalID: data})
+}
@@ -1,10 +1,10 @@
 package utils
 
 import (
+	"BinaryCRUD/backend/index"
 	"fmt"
 	"os"
 	"path/filepath"
-	"strings"
 )
 
 // LogFunc is a function type for logging messages
@@ -32,10 +32,13 @@ func RemoveBinFile(filename string, log LogFunc) error {
 	return RemoveFile(BinPath(filename), log)
 }
 
-// RemoveIndexForBin deletes the index file corresponding to a .bin file
+// RemoveIndexForBin deletes the index file corresponding to a .bin file, with its delta log if any
 func RemoveIndexForBin(binFilename string, log LogFunc) error {
-	indexName := strings.TrimSuffix(binFilename, ".bin") + ".idx"
-	return RemoveFile(IndexPath(indexName), log)
+	indexPath := IndexPathFromBinFile(BinPath(binFilename))
+	if err := RemoveFile(indexPath, log); err != nil {
+		return err
+	}
+	return RemoveFile(index.HashLogPath(indexPath), log)
 }
 
 // RemoveCompressedFile deletes a compressed file from data/compressed
@@ -53,6 +56,13 @@ func CreateFile(filePath string) (*os.File, error) {
 		return nil, fmt.Errorf("failed to create directory %s: %w", dir, err)
 	}
 
+	// A mark left by a removed file of the same name does not apply to the new one
+	if _, err := os.Stat(filePath); os.IsNotExist(err) {
+		if err := os.Remove(IDMarkPath(filePath)); err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to remove stale ID mark: %w", err)
+		}
+	}
+
 	// Use 0600 permissions (read/write by owner only)
 	file, err := os.OpenFile(filePath, os.O_CREATE|os.O_EXCL|os.O_RDWR, 0600)
 	if err != nil {
@@ -67,7 +77,8 @@ func CreateFile(filePath string) (*os.File, error) {
 
 // WriteToFile writes binary data to the given file.
 func WriteToFile(file *os.File, data []byte) error {
-	_, err := file.Write(data)
+	n, err := file.Write(data)
+	CountBytesWritten(n)
 	if err != nil {
 		return fmt.Errorf("failed to write to file: %w", err)
 	}
@@ -110,28 +121,33 @@ func WriteHeaderToFile(file *os.File, header []byte) error {
 }
 
 // AppendEntry appends an entry to the file with auto-assigned ID and tombstone
-// Format: [recordLength(2)][ID(2)][tombstone(1)][entry data]
+// Format: [recordLength(2)][ID(idSize)][tombstone(1)][entry data]
 func AppendEntry(file *os.File, entryWithoutId []byte) error {
-	// Read current header to get nextId
-	_, entitiesCount, tombstoneCount, nextId, err := ReadHeader(file)
+	nextId, err := NextID(file)
 	if err != nil {
-		return fmt.Errorf("failed to read header: %w", err)
+		return err
 	}
 
+	return AppendEntryWithID(file, nextId, entryWithoutId)
+}
+
+// BuildRecord builds the complete active record of an entry, ready to be appended to a file with IDs of idSize bytes
+// Format: [recordLength(2)][ID(idSize)][tombstone(1)][entry data]
+func BuildRecord(id uint64, idSize int, entryWithoutId []byte) ([]byte, error) {
 	// Calculate record length (everything after the length field itself)
-	// recordLength = ID(2) + tombstone(1) + len(entryWithoutId)
-	recordLength := IDSize + TombstoneSize + len(entryWithoutId)
+	// recordLength = ID(idSize) + tombstone(1) + len(entryWithoutId)
+	recordLength := idSize + TombstoneSize + len(entryWithoutId)
 
 	// Generate record length field (2 bytes)
 	lengthBytes, err := WriteFixedNumber(RecordLengthSize, uint64(recordLength))
 	if err != nil {
-		return fmt.Errorf("failed to write record length: %w", err)
+		return nil, fmt.Errorf("failed to write record length: %w", err)
 	}
 
-	// Generate ID field (2 bytes)
-	idBytes, err := WriteFixedNumber(IDSize, uint64(nextId))
+	// Generate ID field (idSize bytes), failing rather than wrapping IDs that do not fit
+	idBytes, err := WriteFixedNumber(idSize, id)
 	if err != nil {
-		return fmt.Errorf("failed to write ID: %w", err)
+		return nil, fmt.Errorf("failed to write ID: %w", err)
 	}
 
 	// Generate tombstone field (1 byte, value 0x00 for active records)
@@ -143,6 +159,32 @@ func AppendEntry(file *os.File, entryWithoutId []byte) error {
 	completeRecord = append(completeRecord, idBytes...)
 	completeRecord = append(completeRecord, tombstoneBytes...)
 	completeRecord = append(completeRecord, entryWithoutId...)
+	return completeRecord, nil
+}
+
+// AppendEntryWithID appends an entry to the file using an explicit ID
+// The header nextId is raised to id+1 when the given ID is not below it
+// Format: [recordLength(2)][ID(idSize)][tombstone(1)][entry data]
+func AppendEntryWithID(file *os.File, id uint64, entryWithoutId []byte) error {
+	// Validate the header before appending
+	_, _, _, _, err := ReadHeader(file)
+	if err != nil {
+		return fmt.Errorf("failed to read header: %w", err)
+	}
+	idSize, err := ReadIDSize(file)
+	if err != nil {
+		return err
+	}
+
+	completeRecord, err := BuildRecord(id, idSize, entryWithoutId)
+	if err != nil {
+		return err
+	}
+
+	// Record the ID as handed out before it reaches the file, so it is never assigned again
+	if err := RaiseIDMark(file.Name(), id+1); err != nil {
+		return err
+	}
 
 	// Seek to end of file
 	_, err = file.Seek(0, 2) // 2 = io.SeekEnd
@@ -162,18 +204,17 @@ func AppendEntry(file *os.File, entryWithoutId []byte) error {
 		return fmt.Errorf("failed to sync entry to disk: %w", err)
 	}
 
-	// Update header with incremented counts
-	err = UpdateHeader(file, entitiesCount+1, tombstoneCount, nextId+1)
+	// Update header with incremented counts, never moving nextId backwards so auto-assigned IDs stay unique
+	err = ModifyHeader(file, func(counts *HeaderCounts) {
+		counts.EntitiesCount++
+		if int(id) >= counts.NextId {
+			counts.NextId = int(id) + 1
+		}
+	})
 	if err != nil {
 		return fmt.Errorf("failed to update header: %w", err)
 	}
 
-	// Force write header to disk
-	err = file.Sync()
-	if err != nil {
-		return fmt.Errorf("failed to sync header to disk: %w", err)
-	}
-
 	return nil
 }
 
@@ -181,9 +222,11 @@ func AppendEntry(file *os.File, entryWithoutId []byte) error {
 // This is used for junction tables with composite keys that don't need auto-incrementing IDs
 // Format: [recordLength(2)][entry data including tombstone]
 // The caller is responsible for including all fields (keys, tombstone, etc.) in entryData
+// Composite key tables have no IDs, so the nextId header field is incremented as a generation
+// counter that lets indexes detect changes made without them
 func AppendEntryManual(file *os.File, entryData []byte) error {
-	// Read current header to get counts
-	_, entitiesCount, tombstoneCount, nextId, err := ReadHeader(file)
+	// Validate the header before appending
+	_, _, _, _, err := ReadHeader(file)
 	if err != nil {
 		return fmt.Errorf("failed to read header: %w", err)
 	}
@@ -220,17 +263,90 @@ func AppendEntryManual(file *os.File, entryData []byte) error {
 		return fmt.Errorf("failed to sync entry to disk: %w", err)
 	}
 
-	// Update header with incremented entity count (nextId stays same for composite key tables)
-	err = UpdateHeader(file, entitiesCount+1, tombstoneCount, nextId)
+	// Update header with incremented entity count and generation
+	err = ModifyHeader(file, func(counts *HeaderCounts) {
+		counts.EntitiesCount++
+		counts.NextId++
+	})
 	if err != nil {
 		return fmt.Errorf("failed to update header: %w", err)
 	}
 
-	// Force write header to disk
-	err = file.Sync()
+	return nil
+}
+
+// AppendEntriesManual appends several complete entries like AppendEntryManual, with one write, one sync
+// and one header update for all of them; the generation advances once per entry
+// Returns the file offset of each appended record, in order
+func AppendEntriesManual(file *os.File, entries [][]byte) ([]int64, error) {
+	if _, _, _, _, err := ReadHeader(file); err != nil {
+		return nil, fmt.Errorf("failed to read header: %w", err)
+	}
+
+	end, err := file.Seek(0, 2) // 2 = io.SeekEnd
 	if err != nil {
-		return fmt.Errorf("failed to sync header to disk: %w", err)
+		return nil, fmt.Errorf("failed to seek to end: %w", err)
 	}
 
-	return nil
+	offsets := make([]int64, 0, len(entries))
+	var records []byte
+	for _, entryData := range entries {
+		lengthBytes, err := WriteFixedNumber(RecordLengthSize, uint64(len(entryData)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to write record length: %w", err)
+		}
+		offsets = append(offsets, end+int64(len(records)))
+		records = append(records, lengthBytes...)
+		records = append(records, entryData...)
+	}
+	if len(records) == 0 {
+		return offsets, nil
+	}
+
+	if err := WriteToFile(file, records); err != nil {
+		return nil, fmt.Errorf("failed to write records: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		return nil, fmt.Errorf("failed to sync entries to disk: %w", err)
+	}
+
+	err = ModifyHeader(file, func(counts *HeaderCounts) {
+		counts.EntitiesCount += len(entries)
+		counts.NextId += len(entries)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update header: %w", err)
+	}
+	return offsets, nil
+}
+
+// PatchField overwrites a fixed-width field of a record in place
+// recordOffset points at the record length prefix (as stored in the B+ tree index) and
+// fieldOffset is relative to the record data, e.g. the ID size of the file for the tombstone
+func PatchField(file *os.File, recordOffset int64, fieldOffset int, value []byte) error {
+	idSize, err := ReadIDSize(file)
+	if err != nil {
+		return err
+	}
+
+	lengthBytes := make([]byte, RecordLengthSize)
+	if _, err := file.ReadAt(lengthBytes, recordOffset); err != nil {
+		return fmt.Errorf("failed to read record length at offset %d: %w", recordOffset, err)
+	}
+	recordLength, _, err := ReadFixedNumber(RecordLengthSize, lengthBytes, 0)
+	if err != nil {
+		return err
+	}
+	if fieldOffset < idSize || fieldOffset+len(value) > int(recordLength) {
+		return fmt.Errorf("field at %d (%d bytes) is outside record of %d bytes", fieldOffset, len(value), recordLength)
+	}
+
+	if _, err := file.WriteAt(value, recordOffset+RecordLengthSize+int64(fieldOffset)); err != nil {
+		return fmt.Errorf("failed to patch field: %w", err)
+	}
+	CountBytesWritten(len(value))
+	if err := file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync patch to disk: %w", err)
+	}
+	return signAfterWrite(file)
 }
@@ -0,0 +1,47 @@
+//go:build windows
+
+package utils
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// lockOffset is where the locked byte sits. Windows locks are mandatory for the range they cover, so the
+// lock is taken far past the end of any data file to leave reads and writes through other handles alone
+const lockOffset = 1 << 62
+
+// lockRegion returns the position of the locked byte as LockFileEx expects it
+func lockRegion() *windows.Overlapped {
+	return &windows.Overlapped{
+		Offset:     uint32(lockOffset & 0xFFFFFFFF),
+		OffsetHigh: uint32(lockOffset >> 32),
+	}
+}
+
+// lockRange locks the byte at lockOffset with the given LockFileEx flags
+func lockRange(file *os.File, flags uint32) error {
+	return windows.LockFileEx(windows.Handle(file.Fd()), flags, 0, 1, 0, lockRegion())
+}
+
+// lockFile takes an exclusive lock on a file, waiting for other processes to release theirs
+func lockFile(file *os.File) error {
+	return lockRange(file, windows.LOCKFILE_EXCLUSIVE_LOCK)
+}
+
+// unlockFile releases a lock taken by lockFile
+func unlockFile(file *os.File) error {
+	return windows.UnlockFileEx(windows.Handle(file.Fd()), 0, 1, 0, lockRegion())
+}
+
+// tryLockFile takes an exclusive lock on a file without waiting
+// Returns errFileLocked when another process holds the lock
+func tryLockFile(file *os.File) error {
+	err := lockRange(file, windows.LOCKFILE_EXCLUSIVE_LOCK|windows.LOCKFILE_FAIL_IMMEDIATELY)
+	if errors.Is(err, windows.ERROR_LOCK_VIOLATION) {
+		return errFileLocked
+	}
+	return err
+}
@@ -54,13 +54,17 @@ func ReadEntryAtOffset(file *os.File, offset int64) ([]byte, error) {
 // FindByIDSequential performs a sequential scan of a binary file to find an entry by ID
 // Returns the complete entry data (including ID) and nil error if found
 // Returns nil and an error if not found or on file read errors
-// Format: [recordLength(2)][ID(2)][tombstone(1)][data...]
+// Format: [recordLength(2)][ID(idSize)][tombstone(1)][data...]
 func FindByIDSequential(file *os.File, targetID uint64) ([]byte, error) {
 	// Get actual header size (variable due to filename)
 	headerSize, err := GetHeaderSizeFromFile(file)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get header size: %w", err)
 	}
+	idSize, err := ReadIDSize(file)
+	if err != nil {
+		return nil, err
+	}
 
 	// Seek to the start of the first entry (after header)
 	_, err = file.Seek(int64(headerSize), 0)
@@ -76,11 +80,12 @@ func FindByIDSequential(file *os.File, targetID uint64) ([]byte, error) {
 
 	// If file is empty (no entries), return not found
 	if len(fileData) == 0 {
-		return nil, fmt.Errorf("entry with ID %d not found", targetID)
+		return nil, WithCode(CodeNotFound, fmt.Errorf("entry with ID %d not found", targetID), nil)
 	}
 
 	// Parse records using length-prefixed format
 	offset := 0
+	var deleted []byte
 	for offset < len(fileData) {
 		// Check if we have enough bytes for the length field
 		if offset+RecordLengthSize > len(fileData) {
@@ -95,24 +100,30 @@ func FindByIDSequential(file *os.File, targetID uint64) ([]byte, error) {
 
 		// Validate record length
 		if err := ValidateRecordLength(recordLength); err != nil {
-			return nil, fmt.Errorf("invalid record at offset %d: %w", offset, err)
+			return nil, WithCode(CodeCorruption, fmt.Errorf("invalid record at offset %d: %w", offset, err), nil)
 		}
 
 		// Check if we have enough bytes for the complete record
 		if lengthEnd+int(recordLength) > len(fileData) {
-			return nil, fmt.Errorf("incomplete record at offset %d", offset)
+			return nil, WithCode(CodeCorruption, fmt.Errorf("incomplete record at offset %d", offset), nil)
 		}
 
 		// Extract the record data (without length prefix)
 		entryData := fileData[lengthEnd : lengthEnd+int(recordLength)]
 
-		// Each record starts with ID (IDSize bytes)
-		if len(entryData) >= IDSize {
+		// Each record starts with ID (idSize bytes)
+		if len(entryData) >= idSize {
 			// Read the ID from the entry
-			entryID, _, err := ReadFixedNumber(IDSize, entryData, 0)
+			entryID, _, err := ReadFixedNumber(idSize, entryData, 0)
 			if err == nil && entryID == targetID {
 				// Found it! Return the complete entry data (including ID)
-				return entryData, nil
+				// A deleted match may have been replaced by a newer version later in the file
+				if len(entryData) <= idSize || entryData[idSize] == 0x00 {
+					return entryData, nil
+				}
+				if deleted == nil {
+					deleted = entryData
+				}
 			}
 		}
 
@@ -120,6 +131,10 @@ func FindByIDSequential(file *os.File, targetID uint64) ([]byte, error) {
 		offset = lengthEnd + int(recordLength)
 	}
 
+	if deleted != nil {
+		return deleted, nil
+	}
+
 	// Not found
-	return nil, fmt.Errorf("entry with ID %d not found", targetID)
+	return nil, WithCode(CodeNotFound, fmt.Errorf("entry with ID %d not found", targetID), nil)
 }
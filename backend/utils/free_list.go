@@ -0,0 +1,159 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+)
+
+// FreeSlot is the space of a tombstoned record that a new record can be written into
+type FreeSlot struct {
+	Offset int64 // file offset of the record length prefix, as stored in the B+ tree index
+	Length int   // record length (everything after the length prefix)
+}
+
+// FreeList tracks the tombstoned record slots of an ID-keyed data file
+// Records are written in place when a slot fits them exactly or leaves room for an ExtPadding field
+type FreeList struct {
+	slots  []FreeSlot
+	idSize int // ID width of the file
+}
+
+// BuildFreeList scans a data file for tombstoned records
+// A missing file has an empty free list
+func BuildFreeList(filePath string) (*FreeList, error) {
+	idSize, err := IDSizeFromPath(filePath)
+	if err != nil {
+		return nil, err
+	}
+	list := &FreeList{idSize: idSize}
+	if _, err := os.Stat(filePath); os.IsNotExist(err) {
+		return list, nil
+	}
+
+	entries, err := SplitFileIntoEntries(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		if len(entry.Data) > idSize && entry.Data[idSize] != 0x00 {
+			list.slots = append(list.slots, FreeSlot{
+				Offset: entry.Position - RecordLengthSize,
+				Length: len(entry.Data),
+			})
+		}
+	}
+	return list, nil
+}
+
+// Len returns the number of free slots
+func (l *FreeList) Len() int {
+	return len(l.slots)
+}
+
+// Add records the slot of the tombstoned record at offset
+func (l *FreeList) Add(file *os.File, offset int64) error {
+	for _, slot := range l.slots {
+		if slot.Offset == offset {
+			return nil
+		}
+	}
+
+	header := make([]byte, RecordLengthSize+l.idSize+TombstoneSize)
+	if _, err := file.ReadAt(header, offset); err != nil {
+		return fmt.Errorf("failed to read record at offset %d: %w", offset, err)
+	}
+	if header[RecordLengthSize+l.idSize] == 0x00 {
+		return fmt.Errorf("record at offset %d is not deleted", offset)
+	}
+
+	length, _, err := ReadFixedNumber(RecordLengthSize, header, 0)
+	if err != nil {
+		return err
+	}
+	l.slots = append(l.slots, FreeSlot{Offset: offset, Length: int(length)})
+	return nil
+}
+
+// take removes and returns the smallest slot that can hold a record of the given length
+func (l *FreeList) take(length int) (FreeSlot, bool) {
+	best := -1
+	for i, slot := range l.slots {
+		if slot.Length != length && slot.Length < length+PaddingOverhead {
+			continue
+		}
+		if best < 0 || slot.Length < l.slots[best].Length {
+			best = i
+		}
+	}
+	if best < 0 {
+		return FreeSlot{}, false
+	}
+
+	slot := l.slots[best]
+	l.slots = append(l.slots[:best], l.slots[best+1:]...)
+	return slot, true
+}
+
+// WriteEntryWithID writes an entry into a free slot when one fits, otherwise appends it like AppendEntryWithID
+// Returns the offset of the record, as stored in the B+ tree index
+// A reused slot is written while still tombstoned and only then marked active, so an interrupted
+// write leaves a deleted record behind instead of a corrupt active one
+func (l *FreeList) WriteEntryWithID(file *os.File, id uint64, entryWithoutId []byte) (int64, error) {
+	recordLength := l.idSize + TombstoneSize + len(entryWithoutId)
+	slot, ok := l.take(recordLength)
+	if !ok {
+		offset, err := file.Seek(0, 2)
+		if err != nil {
+			return 0, fmt.Errorf("failed to seek to end: %w", err)
+		}
+		return offset, AppendEntryWithID(file, id, entryWithoutId)
+	}
+
+	if err := writeEntryInSlot(file, slot, l.idSize, id, entryWithoutId); err != nil {
+		return 0, err
+	}
+	return slot.Offset, nil
+}
+
+// writeEntryInSlot overwrites a tombstoned record with a new entry padded to the slot length
+func writeEntryInSlot(file *os.File, slot FreeSlot, idSize int, id uint64, entryWithoutId []byte) error {
+	padding, err := EncodePadding(slot.Length - idSize - TombstoneSize - len(entryWithoutId))
+	if err != nil {
+		return err
+	}
+
+	idBytes, err := WriteFixedNumber(idSize, id)
+	if err != nil {
+		return fmt.Errorf("failed to write ID: %w", err)
+	}
+	if err := RaiseIDMark(file.Name(), id+1); err != nil {
+		return err
+	}
+
+	// The length prefix stays as it is, only the record body is replaced
+	body := CombineBytes(idBytes, []byte{0x01}, entryWithoutId, padding)
+	tombstonePos := slot.Offset + RecordLengthSize + int64(idSize)
+	if _, err := file.WriteAt(body, slot.Offset+RecordLengthSize); err != nil {
+		return fmt.Errorf("failed to write record: %w", err)
+	}
+	CountBytesWritten(len(body))
+	if err := file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync entry to disk: %w", err)
+	}
+	if _, err := file.WriteAt([]byte{0x00}, tombstonePos); err != nil {
+		return fmt.Errorf("failed to activate record: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync entry to disk: %w", err)
+	}
+
+	return ModifyHeader(file, func(counts *HeaderCounts) {
+		if counts.TombstoneCount > 0 {
+			counts.TombstoneCount--
+		}
+		if int(id) >= counts.NextId {
+			counts.NextId = int(id) + 1
+		}
+	})
+}
@@ -6,17 +6,80 @@ import (
 	"os"
 )
 
-// WriteHeader creates a header byte slice with filename and counts
-// Format: [magic(4)][filenameLen(1)][filename(N)][entitiesCount(4)][tombstoneCount(4)][nextId(4)]
+// MagicForVersion returns the magic bytes identifying a file format version
+func MagicForVersion(version int) ([]byte, error) {
+	if version == FormatVersionLegacy {
+		return BDATMagic, nil
+	}
+	if version <= FormatVersionLegacy || version > CurrentFormatVersion {
+		return nil, fmt.Errorf("unsupported format version %d", version)
+	}
+	return append(append([]byte{}, VersionedMagicPrefix...), byte(version)), nil
+}
+
+// VersionFromMagic returns the file format version encoded in the magic bytes
+func VersionFromMagic(magic []byte) (int, error) {
+	if len(magic) < MagicSize {
+		return 0, fmt.Errorf("magic too short: expected %d bytes, got %d", MagicSize, len(magic))
+	}
+	if bytes.Equal(magic[:MagicSize], BDATMagic) {
+		return FormatVersionLegacy, nil
+	}
+	if !bytes.Equal(magic[:len(VersionedMagicPrefix)], VersionedMagicPrefix) {
+		return 0, WithCode(CodeCorruption, fmt.Errorf("invalid magic bytes: expected BDAT or BDV"), nil)
+	}
+	version := int(magic[len(VersionedMagicPrefix)])
+	if version <= FormatVersionLegacy || version > CurrentFormatVersion {
+		return 0, fmt.Errorf("unsupported format version %d", version)
+	}
+	return version, nil
+}
+
+// WriteHeader creates a header byte slice with filename and counts using the current format version
+// Format: [magic(4)][filenameLen(1)][filename(N)][entitiesCount(4)][tombstoneCount(4)][nextId(8)][flags(1)][idSize(1)]
 func WriteHeader(filename string, entitiesCount, tombstoneCount, nextId int) ([]byte, error) {
+	return WriteHeaderWithFlags(filename, 0, entitiesCount, tombstoneCount, nextId)
+}
+
+// WriteHeaderWithFlags creates a current format version header with the given header flags
+// The file gets the configured IDSize
+func WriteHeaderWithFlags(filename string, flags byte, entitiesCount, tombstoneCount, nextId int) ([]byte, error) {
+	return writeHeader(CurrentFormatVersion, filename, flags, IDSize, entitiesCount, tombstoneCount, nextId)
+}
+
+// WriteHeaderWithIDSize creates a current format version header for a file whose IDs are idSize bytes wide
+func WriteHeaderWithIDSize(filename string, flags byte, idSize int, entitiesCount, tombstoneCount, nextId int) ([]byte, error) {
+	return writeHeader(CurrentFormatVersion, filename, flags, idSize, entitiesCount, tombstoneCount, nextId)
+}
+
+// WriteHeaderVersion creates a header byte slice for a specific format version
+// Versions before FormatVersionFlags have no flags byte, versions before FormatVersionIDSize
+// have no ID size byte and versions before FormatVersionWideNextID have a 4 byte nextId
+func WriteHeaderVersion(version int, filename string, entitiesCount, tombstoneCount, nextId int) ([]byte, error) {
+	return writeHeader(version, filename, 0, IDSize, entitiesCount, tombstoneCount, nextId)
+}
+
+// writeHeader creates a header byte slice, writing flags and ID size only for versions that have them
+func writeHeader(version int, filename string, flags byte, idSize int, entitiesCount, tombstoneCount, nextId int) ([]byte, error) {
 	if len(filename) > 255 {
 		return nil, fmt.Errorf("filename too long: max 255 bytes, got %d", len(filename))
 	}
+	if version >= FormatVersionIDSize && !ValidIDSize(idSize) {
+		return nil, fmt.Errorf("unsupported ID size %d", idSize)
+	}
+	if version >= FormatVersionIDSize && idSize > NextIDFieldSize(version) {
+		return nil, fmt.Errorf("ID size %d needs format version %d", idSize, FormatVersionWideNextID)
+	}
+
+	magic, err := MagicForVersion(version)
+	if err != nil {
+		return nil, err
+	}
 
 	var header bytes.Buffer
 
-	// Magic bytes
-	header.Write(BDATMagic)
+	// Magic bytes (encode the format version)
+	header.Write(magic)
 
 	// Filename length (1 byte)
 	header.WriteByte(byte(len(filename)))
@@ -38,13 +101,23 @@ func WriteHeader(filename string, entitiesCount, tombstoneCount, nextId int) ([]
 	}
 	header.Write(tombstoneBytes)
 
-	// Next ID (4 bytes)
-	nextIdBytes, err := WriteFixedNumber(HeaderFieldSize, uint64(nextId))
+	// Next ID (4 or 8 bytes)
+	nextIdBytes, err := WriteFixedNumber(NextIDFieldSize(version), uint64(nextId))
 	if err != nil {
 		return nil, fmt.Errorf("failed to write nextId: %w", err)
 	}
 	header.Write(nextIdBytes)
 
+	// Flags (1 byte)
+	if version >= FormatVersionFlags {
+		header.WriteByte(flags)
+	}
+
+	// ID size (1 byte)
+	if version >= FormatVersionIDSize {
+		header.WriteByte(byte(idSize))
+	}
+
 	return header.Bytes(), nil
 }
 
@@ -63,8 +136,9 @@ func ReadHeader(file *os.File) (string, int, int, int, error) {
 	if err != nil || n != MagicSize {
 		return "", 0, 0, 0, fmt.Errorf("failed to read magic bytes")
 	}
-	if !bytes.Equal(magic, BDATMagic) {
-		return "", 0, 0, 0, fmt.Errorf("invalid magic bytes: expected BDAT")
+	version, err := VersionFromMagic(magic)
+	if err != nil {
+		return "", 0, 0, 0, err
 	}
 
 	// Read filename length
@@ -83,10 +157,10 @@ func ReadHeader(file *os.File) (string, int, int, int, error) {
 	}
 	filename := string(filenameBytes)
 
-	// Read counts (3 x 4 bytes)
-	countsBytes := make([]byte, HeaderFieldSize*3)
+	// Read counts
+	countsBytes := make([]byte, headerCountsSize(version))
 	n, err = file.Read(countsBytes)
-	if err != nil || n != HeaderFieldSize*3 {
+	if err != nil || n != len(countsBytes) {
 		return "", 0, 0, 0, fmt.Errorf("failed to read counts")
 	}
 
@@ -101,7 +175,7 @@ func ReadHeader(file *os.File) (string, int, int, int, error) {
 		return "", 0, 0, 0, fmt.Errorf("failed to read tombstoneCount: %w", err)
 	}
 
-	nextId, _, err := ReadFixedNumber(HeaderFieldSize, countsBytes, offset)
+	nextId, _, err := ReadFixedNumber(NextIDFieldSize(version), countsBytes, offset)
 	if err != nil {
 		return "", 0, 0, 0, fmt.Errorf("failed to read nextId: %w", err)
 	}
@@ -117,13 +191,14 @@ func ReadHeaderFromBytes(data []byte) (string, int, int, int, int, error) {
 	}
 
 	// Check magic
-	if !bytes.Equal(data[:MagicSize], BDATMagic) {
-		return "", 0, 0, 0, 0, fmt.Errorf("invalid magic bytes: expected BDAT")
+	version, err := VersionFromMagic(data[:MagicSize])
+	if err != nil {
+		return "", 0, 0, 0, 0, err
 	}
 
 	// Read filename length
 	filenameLen := int(data[MagicSize])
-	headerSize := CalculateHeaderSize(string(data[MagicSize+FilenameLengthSize : MagicSize+FilenameLengthSize+filenameLen]))
+	headerSize := HeaderSizeForVersion(version, filenameLen)
 
 	if len(data) < headerSize {
 		return "", 0, 0, 0, 0, fmt.Errorf("data too short for header with filename")
@@ -134,7 +209,7 @@ func ReadHeaderFromBytes(data []byte) (string, int, int, int, int, error) {
 
 	// Read counts
 	countsStart := MagicSize + FilenameLengthSize + filenameLen
-	countsBytes := data[countsStart : countsStart+HeaderFieldSize*3]
+	countsBytes := data[countsStart : countsStart+headerCountsSize(version)]
 
 	offset := 0
 	entitiesCount, offset, err := ReadFixedNumber(HeaderFieldSize, countsBytes, offset)
@@ -147,7 +222,7 @@ func ReadHeaderFromBytes(data []byte) (string, int, int, int, int, error) {
 		return "", 0, 0, 0, 0, fmt.Errorf("failed to read tombstoneCount: %w", err)
 	}
 
-	nextId, _, err := ReadFixedNumber(HeaderFieldSize, countsBytes, offset)
+	nextId, _, err := ReadFixedNumber(NextIDFieldSize(version), countsBytes, offset)
 	if err != nil {
 		return "", 0, 0, 0, 0, fmt.Errorf("failed to read nextId: %w", err)
 	}
@@ -155,37 +230,269 @@ func ReadHeaderFromBytes(data []byte) (string, int, int, int, int, error) {
 	return filename, int(entitiesCount), int(tombstoneCount), int(nextId), headerSize, nil
 }
 
-// UpdateHeader updates the header in the file with new values (keeps same filename)
-func UpdateHeader(file *os.File, entitiesCount, tombstoneCount, nextId int) error {
-	// First read current filename
-	filename, _, _, _, err := ReadHeader(file)
+// FileHeader holds every field of a data file header, with the size of the header and of the whole file
+type FileHeader struct {
+	Filename       string
+	Version        int
+	EntitiesCount  int // records written, tombstoned ones included
+	TombstoneCount int
+	NextId         int
+	Flags          byte
+	IDSize         int
+	HeaderSize     int
+	FileSize       int64
+}
+
+// ReadHeaderOnly reads the header of the file at path without reading any record,
+// for stats that only need the counts
+func ReadHeaderOnly(path string) (*FileHeader, error) {
+	file, err := os.Open(path)
 	if err != nil {
-		return fmt.Errorf("failed to read current header: %w", err)
+		return nil, err
 	}
+	defer file.Close()
 
-	// Generate new header with same filename
-	header, err := WriteHeader(filename, entitiesCount, tombstoneCount, nextId)
+	info, err := file.Stat()
 	if err != nil {
-		return fmt.Errorf("failed to generate header: %w", err)
+		return nil, fmt.Errorf("failed to stat %s: %w", path, err)
 	}
 
-	// Seek to beginning
-	_, err = file.Seek(0, 0)
+	prefix := make([]byte, MagicSize+FilenameLengthSize)
+	if _, err := file.ReadAt(prefix, 0); err != nil {
+		return nil, WithCode(CodeCorruption, fmt.Errorf("failed to read header of %s: %w", path, err), nil)
+	}
+	headerSize, err := headerSizeFromPrefix(prefix)
 	if err != nil {
-		return fmt.Errorf("failed to seek to beginning: %w", err)
+		return nil, err
+	}
+	data := make([]byte, headerSize)
+	if _, err := file.ReadAt(data, 0); err != nil {
+		return nil, WithCode(CodeCorruption, fmt.Errorf("failed to read header of %s: %w", path, err), nil)
+	}
+
+	filename, entitiesCount, tombstoneCount, nextId, _, err := ReadHeaderFromBytes(data)
+	if err != nil {
+		return nil, err
+	}
+	idSize, err := idSizeFromHeader(data)
+	if err != nil {
+		return nil, err
+	}
+	version, _ := VersionFromMagic(data)
+	var flags byte
+	if version >= FormatVersionFlags {
+		flags = data[MagicSize+FilenameLengthSize+len(filename)+headerCountsSize(version)]
 	}
 
-	// Write the header
-	_, err = file.Write(header)
+	return &FileHeader{
+		Filename:       filename,
+		Version:        version,
+		EntitiesCount:  entitiesCount,
+		TombstoneCount: tombstoneCount,
+		NextId:         nextId,
+		Flags:          flags,
+		IDSize:         idSize,
+		HeaderSize:     headerSize,
+		FileSize:       info.Size(),
+	}, nil
+}
+
+// ReadHeaderVersion reads the format version from a file's magic bytes
+func ReadHeaderVersion(file *os.File) (int, error) {
+	magic := make([]byte, MagicSize)
+	n, err := file.ReadAt(magic, 0)
+	if err != nil || n != MagicSize {
+		return 0, fmt.Errorf("failed to read magic bytes")
+	}
+	return VersionFromMagic(magic)
+}
+
+// ReadHeaderFlags reads the flags byte of a file's header
+// Files older than FormatVersionFlags have no flags and report 0
+func ReadHeaderFlags(file *os.File) (byte, error) {
+	offset, version, err := headerCountsOffset(file)
 	if err != nil {
+		return 0, err
+	}
+	if version < FormatVersionFlags {
+		return 0, nil
+	}
+
+	flags := make([]byte, HeaderFlagsSize)
+	if _, err := file.ReadAt(flags, offset+int64(headerCountsSize(version))); err != nil {
+		return 0, fmt.Errorf("failed to read header flags: %w", err)
+	}
+	return flags[0], nil
+}
+
+// ReadHeaderFlagsFromPath reads the header flags of the file at filePath
+// A missing file has no flags
+func ReadHeaderFlagsFromPath(filePath string) (byte, error) {
+	file, err := os.Open(filePath)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	return ReadHeaderFlags(file)
+}
+
+// ReadIDSize reads the ID width of a file from its header
+// Files older than FormatVersionIDSize use LegacyIDSize
+func ReadIDSize(file *os.File) (int, error) {
+	prefix := make([]byte, MagicSize+FilenameLengthSize)
+	if _, err := file.ReadAt(prefix, 0); err != nil {
+		return 0, fmt.Errorf("failed to read magic bytes: %w", err)
+	}
+	headerSize, err := headerSizeFromPrefix(prefix)
+	if err != nil {
+		return 0, err
+	}
+	header := make([]byte, headerSize)
+	if _, err := file.ReadAt(header, 0); err != nil {
+		return 0, fmt.Errorf("failed to read header: %w", err)
+	}
+	return idSizeFromHeader(header)
+}
+
+// IDSizeFromPath reads the ID width of the file at filePath
+// A missing file will be created with the configured IDSize
+func IDSizeFromPath(filePath string) (int, error) {
+	file, err := os.Open(filePath)
+	if os.IsNotExist(err) {
+		return IDSize, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	return ReadIDSize(file)
+}
+
+// idSizeFromHeader returns the ID width recorded in a complete header
+func idSizeFromHeader(header []byte) (int, error) {
+	version, err := VersionFromMagic(header)
+	if err != nil {
+		return 0, err
+	}
+	if version < FormatVersionIDSize {
+		return LegacyIDSize, nil
+	}
+	if len(header) < MagicSize+FilenameLengthSize {
+		return 0, fmt.Errorf("data too short for header")
+	}
+	headerSize := HeaderSizeForVersion(version, int(header[MagicSize]))
+	if len(header) < headerSize {
+		return 0, fmt.Errorf("data too short for header with filename")
+	}
+	idSize := int(header[headerSize-HeaderIDSizeSize])
+	if !ValidIDSize(idSize) || idSize > NextIDFieldSize(version) {
+		return 0, fmt.Errorf("unsupported ID size %d", idSize)
+	}
+	return idSize, nil
+}
+
+// HeaderCounts holds the header fields that change as records are written
+type HeaderCounts struct {
+	EntitiesCount  int
+	TombstoneCount int
+	NextId         int
+}
+
+// UpdateHeader overwrites the counts in the header of a file (keeps same filename and format version)
+func UpdateHeader(file *os.File, entitiesCount, tombstoneCount, nextId int) error {
+	return ModifyHeader(file, func(counts *HeaderCounts) {
+		*counts = HeaderCounts{EntitiesCount: entitiesCount, TombstoneCount: tombstoneCount, NextId: nextId}
+	})
+}
+
+// ModifyHeader reads the header counts, lets update change them and writes them back
+// The file is locked exclusively for the whole read-modify-write and the counts are written with a
+// single WriteAt at their fixed offset, so updates from other processes cannot interleave
+func ModifyHeader(file *os.File, update func(counts *HeaderCounts)) error {
+	if err := lockFile(file); err != nil {
+		return fmt.Errorf("failed to lock file: %w", err)
+	}
+	defer unlockFile(file)
+
+	offset, version, err := headerCountsOffset(file)
+	if err != nil {
+		return fmt.Errorf("failed to read current header: %w", err)
+	}
+
+	// entitiesCount and tombstoneCount are HeaderFieldSize wide, nextId depends on the version
+	fieldSizes := [3]int{HeaderFieldSize, HeaderFieldSize, NextIDFieldSize(version)}
+	countsBytes := make([]byte, headerCountsSize(version))
+	if _, err := file.ReadAt(countsBytes, offset); err != nil {
+		return fmt.Errorf("failed to read counts: %w", err)
+	}
+	var fields [3]uint64
+	fieldOffset := 0
+	for i := range fields {
+		fields[i], fieldOffset, err = ReadFixedNumber(fieldSizes[i], countsBytes, fieldOffset)
+		if err != nil {
+			return fmt.Errorf("failed to read header field %d: %w", i, err)
+		}
+	}
+
+	counts := HeaderCounts{EntitiesCount: int(fields[0]), TombstoneCount: int(fields[1]), NextId: int(fields[2])}
+	update(&counts)
+
+	newBytes := make([]byte, 0, len(countsBytes))
+	for i, value := range []int{counts.EntitiesCount, counts.TombstoneCount, counts.NextId} {
+		fieldBytes, err := WriteFixedNumber(fieldSizes[i], uint64(value))
+		if err != nil {
+			return fmt.Errorf("failed to write header field: %w", err)
+		}
+		newBytes = append(newBytes, fieldBytes...)
+	}
+
+	if _, err := file.WriteAt(newBytes, offset); err != nil {
 		return fmt.Errorf("failed to write header: %w", err)
 	}
 
 	// Force write to disk
-	err = file.Sync()
-	if err != nil {
+	if err := file.Sync(); err != nil {
 		return fmt.Errorf("failed to sync header to disk: %w", err)
 	}
 
-	return nil
+	// Signed while still locked, so the signature covers this write
+	return signAfterWrite(file)
+}
+
+// headerCountsOffset returns the file offset of the header counts, which follow the filename,
+// and the format version that decides their width
+func headerCountsOffset(file *os.File) (int64, int, error) {
+	prefix := make([]byte, MagicSize+FilenameLengthSize)
+	if _, err := file.ReadAt(prefix, 0); err != nil {
+		return 0, 0, fmt.Errorf("failed to read magic bytes: %w", err)
+	}
+	version, err := VersionFromMagic(prefix[:MagicSize])
+	if err != nil {
+		return 0, 0, err
+	}
+	return int64(MagicSize + FilenameLengthSize + int(prefix[MagicSize])), version, nil
+}
+
+// ReadGeneration returns the generation of a composite key table, stored in its nextId header field
+// A missing file has generation 0
+func ReadGeneration(filePath string) (uint64, error) {
+	file, err := os.Open(filePath)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	_, _, _, generation, err := ReadHeader(file)
+	if err != nil {
+		return 0, err
+	}
+	return uint64(generation), nil
 }
@@ -0,0 +1,80 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// IDMarkExt is appended to the logical name of a data file to name the file holding its ID high-water mark
+const IDMarkExt = ".ids"
+
+// idMarkSize is the width of the high-water mark stored in an ID mark file
+const idMarkSize = 8
+
+// IDMarkPath returns the path of the file holding the ID high-water mark of a data file
+// It is named after the logical file, so the mark outlives the generations written by compaction
+func IDMarkPath(path string) string {
+	return filepath.Join(filepath.Dir(path), LogicalBinName(filepath.Base(path))+IDMarkExt)
+}
+
+// ReadIDMark returns the lowest ID never handed out for a data file, or 0 when no mark was recorded
+func ReadIDMark(path string) (uint64, error) {
+	data, err := os.ReadFile(IDMarkPath(path))
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read ID mark: %w", err)
+	}
+	mark, _, err := ReadFixedNumber(idMarkSize, data, 0)
+	if err != nil {
+		return 0, fmt.Errorf("invalid ID mark: %w", err)
+	}
+	return mark, nil
+}
+
+// RaiseIDMark records that every ID below next was handed out for a data file, never lowering the mark
+// The mark is synced before the record using the ID is written, so a crash can leave a gap but never reuse an ID
+func RaiseIDMark(path string, next uint64) error {
+	mark, err := ReadIDMark(path)
+	if err != nil {
+		return err
+	}
+	if next <= mark {
+		return nil
+	}
+
+	markBytes, err := WriteFixedNumber(idMarkSize, next)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(IDMarkPath(path), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open ID mark: %w", err)
+	}
+	defer file.Close()
+
+	// The mark is a single 8 byte field overwritten in place, like the header counts
+	if _, err := file.WriteAt(markBytes, 0); err != nil {
+		return fmt.Errorf("failed to write ID mark: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		return fmt.Errorf("failed to sync ID mark: %w", err)
+	}
+	return nil
+}
+
+// NextID returns the next auto-assigned ID of an open data file
+// This is the header nextId, unless a failed header write or a compaction moved it below the high-water mark
+func NextID(file *os.File) (uint64, error) {
+	_, _, _, nextId, err := ReadHeader(file)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read header: %w", err)
+	}
+	mark, err := ReadIDMark(file.Name())
+	if err != nil {
+		return 0, err
+	}
+	return max(uint64(nextId), mark), nil
+}
@@ -0,0 +1,145 @@
+package utils
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// LogConfig decides which log entries are kept and when the log file is rotated
+type LogConfig struct {
+	Level    string `json:"level"`    // minimum level kept: debug, info, warn or error
+	MaxBytes int64  `json:"maxBytes"` // rotate app.log once a line would grow it past this, 0 never rotates
+	MaxFiles int    `json:"maxFiles"` // rotated files kept as app.log.1 (newest) to app.log.N
+}
+
+// DefaultLogConfig keeps info and above in app.log and 3 rotated copies of up to 5MB
+func DefaultLogConfig() LogConfig {
+	return LogConfig{Level: "info", MaxBytes: 5 << 20, MaxFiles: 3}
+}
+
+// Validate checks the level name and rotation limits
+func (c LogConfig) Validate() error {
+	if _, err := ParseLogLevel(c.Level); err != nil {
+		return err
+	}
+	if c.MaxBytes < 0 || c.MaxFiles < 0 {
+		return fmt.Errorf("log rotation limits cannot be negative")
+	}
+	return nil
+}
+
+// ParseLogLevel converts a level name to its slog level, an empty name is debug so every entry passes
+func ParseLogLevel(name string) (slog.Level, error) {
+	switch strings.ToLower(name) {
+	case "", "debug":
+		return slog.LevelDebug, nil
+	case "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	default:
+		return 0, fmt.Errorf("unknown log level %q (expected debug, info, warn or error)", name)
+	}
+}
+
+// RotatedLogPath returns the path of the nth most recent rotated copy of a log file
+func RotatedLogPath(path string, n int) string {
+	return fmt.Sprintf("%s.%d", path, n)
+}
+
+// RotatingFile is an append-only log file moved aside once it reaches its size limit
+// It is safe for concurrent use
+type RotatingFile struct {
+	mu       sync.Mutex
+	path     string
+	maxBytes int64
+	maxFiles int
+	file     *os.File
+	size     int64
+}
+
+// OpenRotatingFile opens path for appending, creating it and its directory if needed
+func OpenRotatingFile(path string, maxBytes int64, maxFiles int) (*RotatingFile, error) {
+	f := &RotatingFile{path: path, maxBytes: maxBytes, maxFiles: maxFiles}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// open opens the log file and reads its current size
+func (f *RotatingFile) open() error {
+	if err := os.MkdirAll(filepath.Dir(f.path), 0700); err != nil {
+		return fmt.Errorf("failed to create log directory: %w", err)
+	}
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to stat log file: %w", err)
+	}
+	f.file, f.size = file, info.Size()
+	return nil
+}
+
+// Write appends p, rotating the file first when p would take it past the size limit
+// A line is never split across files
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return 0, os.ErrClosed
+	}
+	if f.maxBytes > 0 && f.size > 0 && f.size+int64(len(p)) > f.maxBytes {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// rotate shifts the rotated copies up by one, dropping the oldest, and starts an empty file (must be called with lock held)
+func (f *RotatingFile) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return fmt.Errorf("failed to close log file: %w", err)
+	}
+	f.file = nil
+
+	if f.maxFiles == 0 {
+		os.Remove(f.path)
+	} else {
+		os.Remove(RotatedLogPath(f.path, f.maxFiles))
+		for n := f.maxFiles - 1; n >= 1; n-- {
+			os.Rename(RotatedLogPath(f.path, n), RotatedLogPath(f.path, n+1))
+		}
+		if err := os.Rename(f.path, RotatedLogPath(f.path, 1)); err != nil {
+			return fmt.Errorf("failed to rotate log file: %w", err)
+		}
+	}
+	return f.open()
+}
+
+// Close closes the log file; later writes fail
+func (f *RotatingFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
@@ -0,0 +1,207 @@
+package utils
+
+import (
+	"BinaryCRUD/backend/index"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ManifestFile names the current generation of every data file in a bin directory
+// Compaction writes the next generation of a file beside the current one and switches to it by
+// replacing the manifest, so a reader always resolves a complete file
+const ManifestFile = "manifest.json"
+
+// manifest is the content of ManifestFile
+type manifest struct {
+	Generations map[string]int `json:"generations"`
+}
+
+// readManifest returns the generation of each data file in dir
+// Files missing from the manifest, or a dir without one, are at generation 0
+func readManifest(dir string) (map[string]int, error) {
+	data, err := os.ReadFile(filepath.Join(dir, ManifestFile))
+	if os.IsNotExist(err) {
+		return map[string]int{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	var m manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+	if m.Generations == nil {
+		m.Generations = map[string]int{}
+	}
+	return m.Generations, nil
+}
+
+// writeManifest replaces the manifest of dir through a rename, so readers never see a partial one
+func writeManifest(dir string, generations map[string]int) error {
+	data, err := json.MarshalIndent(manifest{Generations: generations}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %w", err)
+	}
+
+	path := filepath.Join(dir, ManifestFile)
+	file, err := os.OpenFile(path+".tmp", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create manifest: %w", err)
+	}
+	if _, err := file.Write(append(data, '\n')); err != nil {
+		file.Close()
+		os.Remove(path + ".tmp")
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		os.Remove(path + ".tmp")
+		return fmt.Errorf("failed to sync manifest: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(path + ".tmp")
+		return fmt.Errorf("failed to close manifest: %w", err)
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		os.Remove(path + ".tmp")
+		return fmt.Errorf("failed to replace manifest: %w", err)
+	}
+	return nil
+}
+
+// GenerationFilename returns the file name of a generation of a data file
+// Generation 0 keeps the plain name: items.bin, then items.gen1.bin, items.gen2.bin...
+func GenerationFilename(name string, generation int) string {
+	if generation == 0 {
+		return name
+	}
+	ext := filepath.Ext(name)
+	return fmt.Sprintf("%s.gen%d%s", strings.TrimSuffix(name, ext), generation, ext)
+}
+
+// LogicalBinName strips the generation from a data file name: items.gen2.bin is items.bin
+func LogicalBinName(name string) string {
+	_, logical := splitGeneration(name)
+	return logical
+}
+
+// splitGeneration returns the generation of a data file name and its name without it
+func splitGeneration(name string) (int, string) {
+	ext := filepath.Ext(name)
+	stem := strings.TrimSuffix(name, ext)
+	i := strings.LastIndex(stem, ".gen")
+	if i < 0 {
+		return 0, name
+	}
+	generation, err := strconv.Atoi(stem[i+len(".gen"):])
+	if err != nil || generation <= 0 {
+		return 0, name
+	}
+	return generation, stem[:i] + ext
+}
+
+// ManifestGeneration returns the current generation of data file name in dir
+// An unreadable manifest counts as generation 0
+func ManifestGeneration(dir, name string) int {
+	generations, err := readManifest(dir)
+	if err != nil {
+		return 0
+	}
+	return generations[name]
+}
+
+// ResolveBinPath returns the path of the current generation of data file name in dir
+func ResolveBinPath(dir, name string) string {
+	return filepath.Join(dir, GenerationFilename(name, ManifestGeneration(dir, name)))
+}
+
+// IsCurrentBinFile reports whether a file in dir is a .bin file at its current generation
+// Files of older generations stay behind for readers that resolved them before a compaction
+func IsCurrentBinFile(dir, filename string) bool {
+	if !strings.HasSuffix(filename, ".bin") {
+		return false
+	}
+	generation, name := splitGeneration(filename)
+	return ManifestGeneration(dir, name) == generation
+}
+
+// IsCurrentGenerationFile reports whether a file in the bin or indexes directory belongs to the current generation
+// of the data file it is named after, like IsCurrentBinFile for the signatures, indexes and tombstone bitmaps:
+// once items.gen1.bin is current, items.bin, items.bin.sig and items.idx are not. ID marks, which outlive the
+// generations, and files not named after a data file always are
+func IsCurrentGenerationFile(binDir, filename string) bool {
+	if strings.HasSuffix(filename, IDMarkExt) {
+		return true
+	}
+	stem, rest, found := strings.Cut(filename, ".")
+	if !found {
+		return true
+	}
+	generation := 0
+	if suffix, ok := strings.CutPrefix(rest, "gen"); ok {
+		digits, _, _ := strings.Cut(suffix, ".")
+		if n, err := strconv.Atoi(digits); err == nil && n > 0 {
+			generation = n
+		}
+	}
+	return ManifestGeneration(binDir, stem+".bin") == generation
+}
+
+// CurrentBinFiles returns the data file names of the current generation of every .bin file in dir
+// Names are returned without their generation, ready for BinPath
+func CurrentBinFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read directory %s: %w", dir, err)
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() && IsCurrentBinFile(dir, entry.Name()) {
+			names = append(names, LogicalBinName(entry.Name()))
+		}
+	}
+	return names, nil
+}
+
+// commitGenerations makes the next generation of each data file current in dir
+// The generation before the one being replaced is deleted; the replaced one is kept until the next
+// commit so readers still holding its path can finish
+func commitGenerations(dir string, names []string) error {
+	generations, err := readManifest(dir)
+	if err != nil {
+		return err
+	}
+
+	var stale []string
+	for _, name := range names {
+		previous := generations[name]
+		generations[name] = previous + 1
+		if previous > 0 {
+			stale = append(stale, GenerationFilename(name, previous-1))
+		}
+	}
+	if err := writeManifest(dir, generations); err != nil {
+		return err
+	}
+
+	for _, filename := range stale {
+		path := filepath.Join(dir, filename)
+		os.Remove(path)
+		os.Remove(path + SignatureExt)
+		os.Remove(IndexPathFromBinFile(path))
+		os.Remove(index.HashLogPath(IndexPathFromBinFile(path)))
+		os.Remove(IndexDirtyPath(IndexPathFromBinFile(path)))
+		os.Remove(TombstoneBitmapPath(path))
+		os.Remove(TombstoneSlotsPath(path))
+	}
+	return nil
+}
@@ -0,0 +1,191 @@
+package utils
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// recordBytesWritten counts the bytes that record writes put in data files, compaction rewrites are not included
+var recordBytesWritten atomic.Uint64
+
+// CountBytesWritten adds n bytes written to a data file
+func CountBytesWritten(n int) {
+	if n > 0 {
+		recordBytesWritten.Add(uint64(n))
+	}
+}
+
+// RecordBytesWritten returns the bytes record writes put in data files since the process started
+func RecordBytesWritten() uint64 {
+	return recordBytesWritten.Load()
+}
+
+// Metrics counts operations, their errors and compactions since it was created
+// It is safe for concurrent use
+type Metrics struct {
+	mu          sync.Mutex
+	operations  map[string]uint64
+	errors      map[string]uint64
+	panics      map[string]uint64
+	durations   map[string]time.Duration // total time spent per operation
+	compactions int
+	compacting  time.Duration // total time spent compacting
+	longest     time.Duration
+	last        time.Duration
+	startBytes  uint64
+}
+
+// NewMetrics creates an empty collector; bytes written are counted from now on
+func NewMetrics() *Metrics {
+	return &Metrics{
+		operations: make(map[string]uint64),
+		errors:     make(map[string]uint64),
+		panics:     make(map[string]uint64),
+		durations:  make(map[string]time.Duration),
+		startBytes: RecordBytesWritten(),
+	}
+}
+
+// ObserveOperation counts one run of operation (e.g. "AddItem") that took duration, and an error when err is not nil
+func (m *Metrics) ObserveOperation(operation string, duration time.Duration, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.operations[operation]++
+	m.durations[operation] += duration
+	if err != nil {
+		m.errors[operation]++
+	}
+}
+
+// ObservePanic counts a panic recovered from operation, which is also observed as a failed run
+func (m *Metrics) ObservePanic(operation string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.panics[operation]++
+}
+
+// ObserveCompaction records how long a compaction took
+func (m *Metrics) ObserveCompaction(duration time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.compactions++
+	m.compacting += duration
+	m.longest = max(m.longest, duration)
+	m.last = duration
+}
+
+// MetricsSnapshot is a copy of the collected metrics
+type MetricsSnapshot struct {
+	Operations            map[string]uint64  `json:"operations"`            // runs per operation
+	Errors                map[string]uint64  `json:"errors"`                // failed runs per operation
+	Panics                map[string]uint64  `json:"panics"`                // runs per operation that panicked
+	OperationSeconds      map[string]float64 `json:"operationSeconds"`      // total time spent per operation
+	BytesWritten          uint64             `json:"bytesWritten"`          // record bytes written to data files
+	IndexedReads          int                `json:"indexedReads"`          // lookups answered through an index
+	FallbackScans         int                `json:"fallbackScans"`         // lookups that scanned a data file
+	Compactions           int                `json:"compactions"`           // compactions finished
+	CompactionSeconds     float64            `json:"compactionSeconds"`     // total time spent compacting
+	MaxCompactionSeconds  float64            `json:"maxCompactionSeconds"`  // longest compaction
+	LastCompactionSeconds float64            `json:"lastCompactionSeconds"` // most recent compaction, 0 before the first
+}
+
+// IndexHitRatio returns the share of lookups answered through an index, 1 before the first lookup
+func (s MetricsSnapshot) IndexHitRatio() float64 {
+	total := s.IndexedReads + s.FallbackScans
+	if total == 0 {
+		return 1
+	}
+	return float64(s.IndexedReads) / float64(total)
+}
+
+// Snapshot copies the collected metrics; index lookups are left for the caller, which owns the DAOs
+func (m *Metrics) Snapshot() MetricsSnapshot {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	snapshot := MetricsSnapshot{
+		Operations:            make(map[string]uint64, len(m.operations)),
+		Errors:                make(map[string]uint64, len(m.errors)),
+		Panics:                make(map[string]uint64, len(m.panics)),
+		OperationSeconds:      make(map[string]float64, len(m.durations)),
+		BytesWritten:          RecordBytesWritten() - m.startBytes,
+		Compactions:           m.compactions,
+		CompactionSeconds:     m.compacting.Seconds(),
+		MaxCompactionSeconds:  m.longest.Seconds(),
+		LastCompactionSeconds: m.last.Seconds(),
+	}
+	for operation, count := range m.operations {
+		snapshot.Operations[operation] = count
+	}
+	for operation, count := range m.errors {
+		snapshot.Errors[operation] = count
+	}
+	for operation, count := range m.panics {
+		snapshot.Panics[operation] = count
+	}
+	for operation, duration := range m.durations {
+		snapshot.OperationSeconds[operation] = duration.Seconds()
+	}
+	return snapshot
+}
+
+// WritePrometheus writes the snapshot in the Prometheus text exposition format
+func (s MetricsSnapshot) WritePrometheus(w io.Writer) error {
+	var err error
+	printf := func(format string, args ...any) {
+		if err == nil {
+			_, err = fmt.Fprintf(w, format, args...)
+		}
+	}
+	family := func(name, kind, help string) {
+		printf("# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+	}
+
+	family("bincrud_operations_total", "counter", "Operations run, by operation.")
+	for _, operation := range sortedKeys(s.Operations) {
+		printf("bincrud_operations_total{operation=%q} %d\n", operation, s.Operations[operation])
+	}
+	family("bincrud_operation_errors_total", "counter", "Operations that failed, by operation.")
+	for _, operation := range sortedKeys(s.Errors) {
+		printf("bincrud_operation_errors_total{operation=%q} %d\n", operation, s.Errors[operation])
+	}
+	family("bincrud_operation_panics_total", "counter", "Operations that panicked, by operation.")
+	for _, operation := range sortedKeys(s.Panics) {
+		printf("bincrud_operation_panics_total{operation=%q} %d\n", operation, s.Panics[operation])
+	}
+	family("bincrud_operation_duration_seconds", "summary", "Time spent in operations, by operation.")
+	for _, operation := range sortedKeys(s.Operations) {
+		printf("bincrud_operation_duration_seconds_sum{operation=%q} %g\n", operation, s.OperationSeconds[operation])
+		printf("bincrud_operation_duration_seconds_count{operation=%q} %d\n", operation, s.Operations[operation])
+	}
+	family("bincrud_bytes_written_total", "counter", "Record bytes written to data files.")
+	printf("bincrud_bytes_written_total %d\n", s.BytesWritten)
+	family("bincrud_index_lookups_total", "counter", "Record lookups, by whether an index answered them.")
+	printf("bincrud_index_lookups_total{result=\"hit\"} %d\n", s.IndexedReads)
+	printf("bincrud_index_lookups_total{result=\"scan\"} %d\n", s.FallbackScans)
+	family("bincrud_index_hit_ratio", "gauge", "Share of lookups answered through an index.")
+	printf("bincrud_index_hit_ratio %g\n", s.IndexHitRatio())
+	family("bincrud_compaction_duration_seconds", "summary", "Time spent compacting the data files.")
+	printf("bincrud_compaction_duration_seconds_sum %g\n", s.CompactionSeconds)
+	printf("bincrud_compaction_duration_seconds_count %d\n", s.Compactions)
+	family("bincrud_compaction_max_duration_seconds", "gauge", "Longest compaction.")
+	printf("bincrud_compaction_max_duration_seconds %g\n", s.MaxCompactionSeconds)
+	return err
+}
+
+// sortedKeys returns the keys of counts in order, so the export is stable
+func sortedKeys(counts map[string]uint64) []string {
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
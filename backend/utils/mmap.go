@@ -0,0 +1,79 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+)
+
+// MmapReads makes full-file scans map the file into memory instead of reading it
+// Set through the config file (see ApplyConfig); platforms without mmap fall back to reading
+var MmapReads = false
+
+// MappedFile holds the content of a file, memory-mapped when MmapReads is set and the platform allows it
+type MappedFile struct {
+	data   []byte
+	mapped bool
+}
+
+// OpenMappedFile loads a file for reading, mapping it into memory when possible
+// Any mapping failure falls back to reading the file, so callers never depend on mmap support
+func OpenMappedFile(path string) (*MappedFile, error) {
+	if MmapReads {
+		if data, err := mapFile(path); err == nil {
+			return &MappedFile{data: data, mapped: true}, nil
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	return &MappedFile{data: data}, nil
+}
+
+// Bytes returns the content of the file, only valid until Close when it is mapped
+func (m *MappedFile) Bytes() []byte {
+	return m.data
+}
+
+// Mapped reports whether the content is memory-mapped rather than read
+func (m *MappedFile) Mapped() bool {
+	return m.mapped
+}
+
+// Close unmaps a mapped file
+func (m *MappedFile) Close() error {
+	data := m.data
+	m.data = nil
+	if !m.mapped {
+		return nil
+	}
+	m.mapped = false
+	return unmapFile(data)
+}
+
+// ScanFileEntries calls fn with every entry of a binary file, stopping at the first error fn returns
+// Entry data may point into a mapped file and is only valid until fn returns; parse or copy what you keep
+// A missing file has no entries
+func ScanFileEntries(filePath string, fn func(entry EntryInfo) error) error {
+	if _, err := os.Stat(filePath); os.IsNotExist(err) {
+		return nil
+	}
+
+	file, err := OpenMappedFile(filePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	entries, err := splitEntries(file.Bytes())
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if err := fn(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,15 @@
+//go:build !unix
+
+package utils
+
+import "errors"
+
+// mapFile is unsupported without unix mmap, so files are always read
+func mapFile(path string) ([]byte, error) {
+	return nil, errors.New("memory-mapped files are not supported on this platform")
+}
+
+// unmapFile is never called without a mapping
+func unmapFile(data []byte) error {
+	return nil
+}
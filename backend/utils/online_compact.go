@@ -0,0 +1,226 @@
+package utils
+
+import (
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+)
+
+// Record keys for online compaction, counted in IDs whose width is read from the file header
+// The tombstone byte follows the key
+const (
+	IDKeyFields        = 1 // items, orders and promotions: [ID]
+	CompositeKeyFields = 2 // order_promotions: [orderID][promotionID]
+)
+
+// FileSnapshot is a copy of a data file taken while the application keeps writing to it
+// Records are only appended, tombstoned, patched, or written into the slot of a tombstoned
+// record, so the changes made after the snapshot are the records past Size plus the records
+// whose bytes changed in place
+type FileSnapshot struct {
+	LivePath  string
+	CopyPath  string
+	Size      int64 // size of the live file when the copy was taken
+	KeyFields int
+	IDSize    int                      // ID width of the live file
+	records   map[int64]snapshotRecord // records of the copy by position
+}
+
+// snapshotRecord remembers a record of the copy to detect in-place changes
+type snapshotRecord struct {
+	key    string
+	active bool
+	hash   uint64
+}
+
+// recordHash fingerprints a record's bytes
+func recordHash(data []byte) uint64 {
+	h := fnv.New64a()
+	h.Write(data)
+	return h.Sum64()
+}
+
+// TakeSnapshot copies livePath to copyPath and remembers which records were active
+// Must be called while the file's DAO is locked so no record is half-written
+func TakeSnapshot(livePath, copyPath string, keyFields int) (*FileSnapshot, error) {
+	src, err := os.Open(livePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", livePath, err)
+	}
+	defer src.Close()
+
+	dst, err := os.Create(copyPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create snapshot: %w", err)
+	}
+	size, err := io.Copy(dst, src)
+	if err == nil {
+		err = dst.Sync()
+	}
+	dst.Close()
+	if err != nil {
+		os.Remove(copyPath)
+		return nil, fmt.Errorf("failed to copy %s: %w", livePath, err)
+	}
+
+	entries, err := SplitFileIntoEntries(copyPath)
+	if err != nil {
+		return nil, err
+	}
+	idSize, err := IDSizeFromPath(copyPath)
+	if err != nil {
+		return nil, err
+	}
+	keySize := keyFields * idSize
+	records := make(map[int64]snapshotRecord)
+	for _, entry := range entries {
+		if len(entry.Data) > keySize {
+			records[entry.Position] = snapshotRecord{
+				key:    string(entry.Data[:keySize]),
+				active: entry.Data[keySize] == 0x00,
+				hash:   recordHash(entry.Data),
+			}
+		}
+	}
+
+	return &FileSnapshot{LivePath: livePath, CopyPath: copyPath, Size: size, KeyFields: keyFields, IDSize: idSize, records: records}, nil
+}
+
+// keySize returns the size of the record key in the live file
+func (s *FileSnapshot) keySize() int {
+	return s.KeyFields * s.IDSize
+}
+
+// CatchUp applies the changes made to the live file since the snapshot to the (compacted) copy:
+// records tombstoned or overwritten since are tombstoned in the copy, and active records appended
+// or written into a free slot since are appended
+// Must be called while the file's DAO is locked. Returns the number of changes applied
+func (s *FileSnapshot) CatchUp() (int, error) {
+	entries, err := SplitFileIntoEntries(s.LivePath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read %s: %w", s.LivePath, err)
+	}
+
+	// Records are copied as they are, so the compacted copy must keep the ID width of the live file
+	copyIDSize, err := IDSizeFromPath(s.CopyPath)
+	if err != nil {
+		return 0, err
+	}
+	if copyIDSize != s.IDSize {
+		return 0, fmt.Errorf("compacted copy has %d byte IDs, live file has %d byte IDs", copyIDSize, s.IDSize)
+	}
+
+	keySize := s.keySize()
+	tombstoned := make(map[string]bool)
+	var appended [][]byte
+	for _, entry := range entries {
+		if len(entry.Data) <= keySize {
+			continue
+		}
+		isActive := entry.Data[keySize] == 0x00
+		if entry.Position < s.Size {
+			before := s.records[entry.Position]
+			if before.hash == recordHash(entry.Data) {
+				continue
+			}
+			// The tombstone flipped, or a new record was written into the slot of a deleted one
+			if before.active {
+				tombstoned[before.key] = true
+			}
+			if isActive {
+				appended = append(appended, entry.Data)
+			}
+		} else if isActive {
+			appended = append(appended, entry.Data)
+		}
+	}
+
+	file, err := os.OpenFile(s.CopyPath, os.O_RDWR, 0644)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open compacted copy: %w", err)
+	}
+	defer file.Close()
+
+	flipped, err := s.applyTombstones(file, tombstoned)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, data := range appended {
+		lengthBytes, err := WriteFixedNumber(RecordLengthSize, uint64(len(data)))
+		if err != nil {
+			return 0, err
+		}
+		if _, err := file.Seek(0, io.SeekEnd); err != nil {
+			return 0, fmt.Errorf("failed to seek to end: %w", err)
+		}
+		if err := WriteToFile(file, CombineBytes(lengthBytes, data)); err != nil {
+			return 0, fmt.Errorf("failed to append record: %w", err)
+		}
+	}
+
+	if err := s.updateHeader(file, len(appended), flipped); err != nil {
+		return 0, err
+	}
+	if err := file.Sync(); err != nil {
+		return 0, fmt.Errorf("failed to sync compacted copy: %w", err)
+	}
+
+	return flipped + len(appended), nil
+}
+
+// applyTombstones flips the tombstone of active records in the copy whose key is in keys
+func (s *FileSnapshot) applyTombstones(file *os.File, keys map[string]bool) (int, error) {
+	if len(keys) == 0 {
+		return 0, nil
+	}
+
+	entries, err := SplitFileIntoEntries(s.CopyPath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read compacted copy: %w", err)
+	}
+
+	keySize := s.keySize()
+	flipped := 0
+	for _, entry := range entries {
+		if len(entry.Data) <= keySize || entry.Data[keySize] != 0x00 {
+			continue
+		}
+		if !keys[string(entry.Data[:keySize])] {
+			continue
+		}
+		if _, err := file.WriteAt([]byte{0x01}, entry.Position+int64(keySize)); err != nil {
+			return 0, fmt.Errorf("failed to write tombstone: %w", err)
+		}
+		flipped++
+	}
+	return flipped, nil
+}
+
+// updateHeader adds the caught-up records to the copy's counts and carries over the live nextId
+// For composite key tables nextId is the generation, which is bumped so existing indexes go stale
+func (s *FileSnapshot) updateHeader(file *os.File, appended, tombstoned int) error {
+	_, entitiesCount, tombstoneCount, nextId, err := ReadHeader(file)
+	if err != nil {
+		return fmt.Errorf("failed to read compacted header: %w", err)
+	}
+
+	live, err := os.Open(s.LivePath)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", s.LivePath, err)
+	}
+	_, _, _, liveNextId, err := ReadHeader(live)
+	live.Close()
+	if err != nil {
+		return fmt.Errorf("failed to read live header: %w", err)
+	}
+
+	if s.KeyFields == CompositeKeyFields {
+		nextId = liveNextId + 1
+	} else if liveNextId > nextId {
+		nextId = liveNextId
+	}
+
+	return UpdateHeader(file, entitiesCount+appended, tombstoneCount+tombstoned, nextId)
+}
@@ -1,15 +1,19 @@
 package utils
 
-import (
-	"fmt"
-)
+import "time"
 
 // Item represents a parsed item entry
 type Item struct {
-	ID        uint64
-	Name      string
-	Price     uint64
-	Tombstone byte
+	ID         uint64
+	Name       string
+	Price      uint64
+	Tombstone  byte
+	Extensions map[byte][]byte // optional trailer after the price
+}
+
+// RecordID returns the ID of the item, so items can be kept in a generic store
+func (i *Item) RecordID() uint64 {
+	return i.ID
 }
 
 // Collection represents a parsed collection (order/promotion) entry
@@ -20,154 +24,34 @@ type Collection struct {
 	ItemCount   uint64
 	ItemIDs     []uint64
 	Tombstone   byte
+	Extensions  map[byte][]byte // optional trailer after the item IDs
 }
 
 // OrderPromotion represents a parsed order-promotion relationship entry
+// AppliedAt and DiscountAmount are zero for links written before they were recorded
 type OrderPromotion struct {
-	OrderID     uint64
-	PromotionID uint64
-	Tombstone   byte
+	OrderID        uint64
+	PromotionID    uint64
+	Tombstone      byte
+	AppliedAt      time.Time // when the promotion was applied
+	DiscountAmount uint64    // discount in cents the promotion granted on the order when applied
 }
 
-// ParseItemEntry parses a binary item entry
-// Format: [ID(2)][tombstone(1)][nameLength(2)][name...][price(4)]
+// ParseItemEntry parses a binary item entry of a file with the configured IDSize with ItemCodec
+// Format: [ID(IDSize)][tombstone(1)][nameLength(2)][name...][price(4)]
 func ParseItemEntry(entryData []byte) (*Item, error) {
-	parseOffset := 0
-
-	// Read ID
-	entryID, parseOffset, err := ReadFixedNumber(IDSize, entryData, parseOffset)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read ID: %w", err)
-	}
-
-	// Read tombstone byte
-	if parseOffset >= len(entryData) {
-		return nil, fmt.Errorf("entry too short for tombstone")
-	}
-	tombstone := entryData[parseOffset]
-	parseOffset += TombstoneSize
-
-	// Read name size
-	nameSize, parseOffset, err := ReadFixedNumber(2, entryData, parseOffset)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read name size: %w", err)
-	}
-
-	// Read name
-	name, parseOffset, err := ReadFixedString(int(nameSize), entryData, parseOffset)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read name: %w", err)
-	}
-
-	// Read price
-	price, _, err := ReadFixedNumber(4, entryData, parseOffset)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read price: %w", err)
-	}
-
-	return &Item{
-		ID:        entryID,
-		Name:      name,
-		Price:     price,
-		Tombstone: tombstone,
-	}, nil
+	return ItemCodec.Decode(entryData, IDSize)
 }
 
-// ParseCollectionEntry parses a binary collection (order/promotion) entry
-// Format: [ID(2)][tombstone(1)][nameLength(2)][name...][totalPrice(4)][itemCount(4)][itemIDs...]
+// ParseCollectionEntry parses a binary collection (order/promotion) entry of a file with the configured IDSize with CollectionCodec
+// Format: [ID(IDSize)][tombstone(1)][nameLength(2)][name...][totalPrice(4)][itemCount(4)][itemIDs...]
+// Entries written by CompressEntry are decompressed first
 func ParseCollectionEntry(entryData []byte) (*Collection, error) {
-	parseOffset := 0
-
-	// Read ID
-	entryID, parseOffset, err := ReadFixedNumber(IDSize, entryData, parseOffset)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read ID: %w", err)
-	}
-
-	// Read tombstone byte
-	if parseOffset >= len(entryData) {
-		return nil, fmt.Errorf("entry too short for tombstone")
-	}
-	tombstone := entryData[parseOffset]
-	parseOffset += TombstoneSize
-
-	// Read name size
-	nameSize, parseOffset, err := ReadFixedNumber(2, entryData, parseOffset)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read name size: %w", err)
-	}
-
-	// Read name
-	ownerOrName, parseOffset, err := ReadFixedString(int(nameSize), entryData, parseOffset)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read name: %w", err)
-	}
-
-	// Read total price
-	totalPrice, parseOffset, err := ReadFixedNumber(4, entryData, parseOffset)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read total price: %w", err)
-	}
-
-	// Read item count
-	itemCount, parseOffset, err := ReadFixedNumber(4, entryData, parseOffset)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read item count: %w", err)
-	}
-
-	// Read item IDs (2 bytes each)
-	itemIDs := make([]uint64, itemCount)
-	for i := uint64(0); i < itemCount; i++ {
-		itemID, newOffset, err := ReadFixedNumber(IDSize, entryData, parseOffset)
-		if err != nil {
-			return nil, fmt.Errorf("failed to read item ID %d: %w", i, err)
-		}
-		itemIDs[i] = itemID
-		parseOffset = newOffset
-	}
-
-	return &Collection{
-		ID:          entryID,
-		OwnerOrName: ownerOrName,
-		TotalPrice:  totalPrice,
-		ItemCount:   itemCount,
-		ItemIDs:     itemIDs,
-		Tombstone:   tombstone,
-	}, nil
+	return CollectionCodec.Decode(entryData, IDSize)
 }
 
-// ParseOrderPromotionEntry parses a binary order-promotion relationship entry
-// Format: [orderID(2)][promotionID(2)][tombstone(1)]
+// ParseOrderPromotionEntry parses a binary order-promotion relationship entry of a file with the configured IDSize with OrderPromotionCodec
+// Format: [orderID(IDSize)][promotionID(IDSize)][tombstone(1)][appliedAt(8)][discountAmount(4)], the last two optional
 func ParseOrderPromotionEntry(entryData []byte) (*OrderPromotion, error) {
-	if len(entryData) < IDSize*2+TombstoneSize {
-		return nil, fmt.Errorf("entry too short: expected at least %d bytes, got %d", IDSize*2+TombstoneSize, len(entryData))
-	}
-
-	offset := 0
-
-	// Read orderID
-	orderID, newOffset, err := ReadFixedNumber(IDSize, entryData, offset)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read order ID: %w", err)
-	}
-	offset = newOffset
-
-	// Read promotionID
-	promotionID, newOffset, err := ReadFixedNumber(IDSize, entryData, offset)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read promotion ID: %w", err)
-	}
-	offset = newOffset
-
-	// Read tombstone
-	if offset >= len(entryData) {
-		return nil, fmt.Errorf("entry too short for tombstone")
-	}
-	tombstone := entryData[offset]
-
-	return &OrderPromotion{
-		OrderID:     orderID,
-		PromotionID: promotionID,
-		Tombstone:   tombstone,
-	}, nil
+	return OrderPromotionCodec.Decode(entryData, IDSize)
 }
@@ -2,83 +2,146 @@ package utils
 
 import (
 	"BinaryCRUD/backend/index"
+	"context"
 	"fmt"
 	"os"
+	"runtime"
+	"sync"
 )
 
+// RebuildWorkers is the number of goroutines parsing a file during an index rebuild, 0 uses one per CPU
+// Set through the config file (see ApplyConfig)
+var RebuildWorkers = 0
+
+// parallelRebuildMinEntries is the entry count below which a rebuild parses on a single goroutine
+const parallelRebuildMinEntries = 4096
+
 // EntryWithOffset contains entry data and its file offset
 type EntryWithOffset struct {
 	Data   []byte
 	Offset int64
+	IDSize int
 }
 
 // IterateEntries reads all entries from a binary file and calls the callback for each.
 // Returns early if the callback returns an error.
+// The file may be memory-mapped (see ScanFileEntries), so entry data is only valid during the callback
 func IterateEntries(binFilePath string, callback func(entry EntryWithOffset) error) error {
-	// Check if bin file exists
+	return ScanFileEntries(binFilePath, func(entry EntryInfo) error {
+		// Entry positions point after the length prefix, but indexes need the position of the prefix
+		return callback(EntryWithOffset{
+			Data:   entry.Data,
+			Offset: entry.Position - RecordLengthSize,
+			IDSize: entry.IDSize,
+		})
+	})
+}
+
+// parseEntriesParallel parses every entry of a binary file and returns the results in file order
+// Record boundaries are found in one cheap pass over the length prefixes, then the entries are split into
+// contiguous chunks parsed on RebuildWorkers goroutines; parse drops an entry by returning false
+// Every goroutine stops at the next entry once ctx is cancelled, and ctx.Err() is returned
+func parseEntriesParallel[T any](ctx context.Context, binFilePath string, parse func(entry EntryWithOffset) (T, bool)) ([]T, error) {
 	if _, err := os.Stat(binFilePath); os.IsNotExist(err) {
-		return nil // No data file, nothing to iterate
+		return nil, nil
 	}
 
-	// Get header size for this file
-	headerSize, err := GetHeaderSize(binFilePath)
+	file, err := OpenMappedFile(binFilePath)
 	if err != nil {
-		return fmt.Errorf("failed to get header size: %w", err)
+		return nil, err
 	}
+	defer file.Close()
 
-	entries, err := SplitFileIntoEntries(binFilePath)
+	entries, err := splitEntries(file.Bytes())
 	if err != nil {
-		return fmt.Errorf("failed to read entries: %w", err)
+		return nil, err
 	}
 
-	// Calculate file offsets (SplitFileIntoEntries returns position after length prefix,
-	// but we need position at the length prefix for indexing)
-	fileOffset := int64(headerSize)
-	for _, entry := range entries {
-		if err := callback(EntryWithOffset{
-			Data:   entry.Data,
-			Offset: fileOffset,
-		}); err != nil {
-			return err
-		}
-		// Move to next record: length prefix + data length
-		fileOffset += int64(RecordLengthSize + len(entry.Data))
+	workers := RebuildWorkers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if len(entries) < parallelRebuildMinEntries {
+		workers = 1
+	}
+	chunkSize := (len(entries) + workers - 1) / workers
+
+	// Each chunk keeps its own results, concatenating them in chunk order keeps the file order
+	chunks := make([][]T, workers)
+	var wg sync.WaitGroup
+	for w := range chunks {
+		start := min(w*chunkSize, len(entries))
+		end := min(start+chunkSize, len(entries))
+		wg.Add(1)
+		go func(w int, chunk []EntryInfo) {
+			defer wg.Done()
+			results := make([]T, 0, len(chunk))
+			for _, entry := range chunk {
+				if ctx.Err() != nil {
+					return
+				}
+				// Entry positions point after the length prefix, but indexes need the position of the prefix
+				result, ok := parse(EntryWithOffset{Data: entry.Data, Offset: entry.Position - RecordLengthSize, IDSize: entry.IDSize})
+				if ok {
+					results = append(results, result)
+				}
+			}
+			chunks[w] = results
+		}(w, entries[start:end])
+	}
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
 	}
 
-	return nil
+	var results []T
+	for _, chunk := range chunks {
+		results = append(results, chunk...)
+	}
+	return results, nil
+}
+
+// indexedID is the ID of a live entry and its file offset
+type indexedID struct {
+	id     uint64
+	offset int64
 }
 
-// IDExtractor is a function that extracts an ID and tombstone from entry data.
+// IDExtractor is a function that extracts an ID and tombstone from entry data of a file with IDs of idSize bytes.
 // Returns (id, tombstone, error).
-type IDExtractor func(data []byte) (uint64, byte, error)
+type IDExtractor func(data []byte, idSize int) (uint64, byte, error)
 
 // rebuildBTreeIndexGeneric is the common implementation for B+ tree index rebuilding.
-func rebuildBTreeIndexGeneric(binFilePath, indexPath string, extractor IDExtractor) (*index.BTree, error) {
-	tree := index.NewBTree(DefaultBTreeOrder)
+func rebuildBTreeIndexGeneric(ctx context.Context, binFilePath, indexPath string, extractor IDExtractor) (*index.BTree, error) {
+	tree := index.NewBTree(BTreeOrder)
 
-	err := IterateEntries(binFilePath, func(entry EntryWithOffset) error {
-		id, tombstone, err := extractor(entry.Data)
-		if err == nil && tombstone == 0x00 {
-			tree.Insert(id, entry.Offset)
-		}
-		return nil
+	// Entries are parsed in parallel, but the tree is only safe to fill from one goroutine
+	live, err := parseEntriesParallel(ctx, binFilePath, func(entry EntryWithOffset) (indexedID, bool) {
+		id, tombstone, err := extractor(entry.Data, entry.IDSize)
+		return indexedID{id: id, offset: entry.Offset}, err == nil && tombstone == 0x00
 	})
-
 	if err != nil {
 		return nil, err
 	}
+	for _, entry := range live {
+		tree.Insert(entry.id, entry.offset)
+	}
 
 	if err := tree.Save(indexPath); err != nil {
 		return nil, fmt.Errorf("failed to save rebuilt index: %w", err)
 	}
+	if err := ClearIndexDirty(indexPath); err != nil {
+		return nil, err
+	}
 
 	return tree, nil
 }
 
 // RebuildBTreeIndex scans a .bin file and rebuilds the B+ tree index for items
-func RebuildBTreeIndex(binFilePath string, indexPath string) (*index.BTree, error) {
-	return rebuildBTreeIndexGeneric(binFilePath, indexPath, func(data []byte) (uint64, byte, error) {
-		item, err := ParseItemEntry(data)
+// A cancelled ctx stops the scan and leaves the index file as it was
+func RebuildBTreeIndex(ctx context.Context, binFilePath string, indexPath string) (*index.BTree, error) {
+	return rebuildBTreeIndexGeneric(ctx, binFilePath, indexPath, func(data []byte, idSize int) (uint64, byte, error) {
+		item, err := ItemCodec.Decode(data, idSize)
 		if err != nil {
 			return 0, 0, err
 		}
@@ -88,9 +151,9 @@ func RebuildBTreeIndex(binFilePath string, indexPath string) (*index.BTree, erro
 
 // RebuildCollectionBTreeIndex scans a collection .bin file and rebuilds the B+ tree index
 // Works for orders.bin and promotions.bin
-func RebuildCollectionBTreeIndex(binFilePath string, indexPath string) (*index.BTree, error) {
-	return rebuildBTreeIndexGeneric(binFilePath, indexPath, func(data []byte) (uint64, byte, error) {
-		collection, err := ParseCollectionEntry(data)
+func RebuildCollectionBTreeIndex(ctx context.Context, binFilePath string, indexPath string) (*index.BTree, error) {
+	return rebuildBTreeIndexGeneric(ctx, binFilePath, indexPath, func(data []byte, idSize int) (uint64, byte, error) {
+		collection, err := CollectionCodec.Decode(data, idSize)
 		if err != nil {
 			return 0, 0, err
 		}
@@ -98,25 +161,55 @@ func RebuildCollectionBTreeIndex(binFilePath string, indexPath string) (*index.B
 	})
 }
 
+// RebuildRecordBTreeIndex scans a .bin file of generic records and rebuilds the B+ tree index
+// Only the ID and tombstone that start every record are read, so it works whatever the payload format
+func RebuildRecordBTreeIndex(ctx context.Context, binFilePath string, indexPath string) (*index.BTree, error) {
+	return rebuildBTreeIndexGeneric(ctx, binFilePath, indexPath, func(data []byte, idSize int) (uint64, byte, error) {
+		id, offset, err := ReadFixedNumber(idSize, data, 0)
+		if err != nil {
+			return 0, 0, err
+		}
+		if offset >= len(data) {
+			return 0, 0, fmt.Errorf("entry too short for tombstone")
+		}
+		return id, data[offset], nil
+	})
+}
+
 // RebuildExtensibleHashIndex scans an order_promotions.bin file and rebuilds the hash index
-func RebuildExtensibleHashIndex(binFilePath string, indexPath string, bucketSize int) (*index.ExtensibleHash, error) {
+func RebuildExtensibleHashIndex(ctx context.Context, binFilePath string, indexPath string, bucketSize int) (*index.ExtensibleHash, error) {
 	hashIndex := index.NewExtensibleHash(bucketSize)
 
-	err := IterateEntries(binFilePath, func(entry EntryWithOffset) error {
-		op, err := ParseOrderPromotionEntry(entry.Data)
-		if err == nil && op.Tombstone == 0x00 {
-			hashIndex.Insert(op.OrderID, op.PromotionID, entry.Offset)
+	type indexedPair struct {
+		orderID, promotionID uint64
+		offset               int64
+	}
+	live, err := parseEntriesParallel(ctx, binFilePath, func(entry EntryWithOffset) (indexedPair, bool) {
+		op, err := OrderPromotionCodec.Decode(entry.Data, entry.IDSize)
+		if err != nil || op.Tombstone != 0x00 {
+			return indexedPair{}, false
 		}
-		return nil
+		return indexedPair{orderID: op.OrderID, promotionID: op.PromotionID, offset: entry.Offset}, true
 	})
+	if err != nil {
+		return nil, err
+	}
+	for _, pair := range live {
+		hashIndex.Insert(pair.orderID, pair.promotionID, pair.offset)
+	}
 
+	generation, err := ReadGeneration(binFilePath)
 	if err != nil {
 		return nil, err
 	}
+	hashIndex.SetGeneration(generation)
 
 	if err := hashIndex.Save(indexPath); err != nil {
 		return nil, fmt.Errorf("failed to save rebuilt index: %w", err)
 	}
+	if err := ClearIndexDirty(indexPath); err != nil {
+		return nil, err
+	}
 
 	return hashIndex, nil
 }
@@ -0,0 +1,90 @@
+package utils
+
+import (
+	"bytes"
+	"compress/lzw"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// RecordCompressionEnabled makes collection writes LZW-compress large entry payloads
+// Compressed and plain records can be mixed in one file, reads handle both
+var RecordCompressionEnabled = false
+
+// RecordCompressionThreshold is the payload size below which entries are stored uncompressed
+const RecordCompressionThreshold = 256
+
+// CompressedEntryFlag is set in the nameLength field of a collection entry whose payload is compressed
+// Names are limited to far fewer than 0x8000 bytes, so the bit is never set in a plain entry
+const CompressedEntryFlag = 0x8000
+
+// compressedEntryHeaderSize is the size of the flag and compressed length fields
+const compressedEntryHeaderSize = 2 + 4
+
+// maxExpandedEntrySize bounds the size a compressed entry payload may expand to
+const maxExpandedEntrySize = 16 << 20
+
+// CompressEntry LZW-compresses a collection entry built without ID and tombstone
+// Format: [CompressedEntryFlag(2)][compressedLen(4)][LZW(entry)]
+// The entry is returned unchanged when compression is disabled, the entry is small or it does not shrink
+func CompressEntry(entryWithoutId []byte) ([]byte, error) {
+	if !RecordCompressionEnabled || len(entryWithoutId) < RecordCompressionThreshold {
+		return entryWithoutId, nil
+	}
+
+	var compressed bytes.Buffer
+	writer := lzw.NewWriter(&compressed, lzw.LSB, 8)
+	if _, err := writer.Write(entryWithoutId); err != nil {
+		return nil, fmt.Errorf("failed to compress entry: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("failed to compress entry: %w", err)
+	}
+	if compressedEntryHeaderSize+compressed.Len() >= len(entryWithoutId) {
+		return entryWithoutId, nil
+	}
+
+	entry := binary.BigEndian.AppendUint16(nil, CompressedEntryFlag)
+	entry = binary.BigEndian.AppendUint32(entry, uint32(compressed.Len()))
+	return append(entry, compressed.Bytes()...), nil
+}
+
+// IsCompressedEntry reports whether a collection entry (with an ID of idSize bytes and tombstone) has a compressed payload
+func IsCompressedEntry(entryData []byte, idSize int) bool {
+	offset := idSize + TombstoneSize
+	return len(entryData) >= offset+2 && binary.BigEndian.Uint16(entryData[offset:])&CompressedEntryFlag != 0
+}
+
+// ExpandEntry returns a collection entry with its payload decompressed
+// Plain entries are returned as they are; bytes after the compressed payload, like slot padding, are kept
+func ExpandEntry(entryData []byte, idSize int) ([]byte, error) {
+	if !IsCompressedEntry(entryData, idSize) {
+		return entryData, nil
+	}
+
+	offset := idSize + TombstoneSize + 2
+	compressedLen, offset, err := ReadFixedNumber(4, entryData, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read compressed length: %w", err)
+	}
+	if compressedLen > uint64(len(entryData)-offset) {
+		return nil, fmt.Errorf("compressed payload of %d bytes exceeds the record", compressedLen)
+	}
+	end := offset + int(compressedLen)
+
+	reader := lzw.NewReader(bytes.NewReader(entryData[offset:end]), lzw.LSB, 8)
+	defer reader.Close()
+	payload, err := io.ReadAll(io.LimitReader(reader, maxExpandedEntrySize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress entry: %w", err)
+	}
+	if len(payload) > maxExpandedEntrySize {
+		return nil, fmt.Errorf("decompressed entry exceeds %d bytes", maxExpandedEntrySize)
+	}
+
+	expanded := make([]byte, 0, idSize+TombstoneSize+len(payload)+len(entryData)-end)
+	expanded = append(expanded, entryData[:idSize+TombstoneSize]...)
+	expanded = append(expanded, payload...)
+	return append(expanded, entryData[end:]...), nil
+}
@@ -0,0 +1,57 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"os"
+)
+
+// NameTransform maps the stored bytes of a collection name to their new stored form
+type NameTransform func(stored []byte) ([]byte, error)
+
+// RewriteCollectionNames rewrites the name of every active collection in the given files
+// Like compaction, tombstoned records are dropped and the files are staged, replacing their
+// originals together only once all of them were written, so a failure leaves every file untouched
+// Returns the number of names rewritten
+func RewriteCollectionNames(transform NameTransform, filePaths ...string) (int, error) {
+	stage := &compactionStage{}
+	defer stage.discard()
+
+	rewritten := 0
+	for _, filePath := range filePaths {
+		if _, err := os.Stat(filePath); os.IsNotExist(err) {
+			continue
+		}
+
+		entries, err := SplitFileIntoEntries(filePath)
+		if err != nil {
+			return 0, err
+		}
+
+		var activeCollections []*Collection
+		for _, entry := range entries {
+			collection, err := CollectionCodec.Decode(entry.Data, entry.IDSize)
+			if err != nil || collection.Tombstone != 0x00 {
+				continue
+			}
+
+			name, err := transform([]byte(collection.OwnerOrName))
+			if err != nil {
+				return 0, fmt.Errorf("collection %d in %s: %w", collection.ID, filePath, err)
+			}
+			collection.OwnerOrName = string(name)
+			activeCollections = append(activeCollections, collection)
+		}
+
+		// Key rotation must run to the end once started, so it is not cancellable
+		if err := stageCollectionsFile(context.Background(), filePath, activeCollections, stage); err != nil {
+			return 0, err
+		}
+		rewritten += len(activeCollections)
+	}
+
+	if err := stage.commit(); err != nil {
+		return 0, fmt.Errorf("failed to replace rewritten files: %w", err)
+	}
+	return rewritten, nil
+}
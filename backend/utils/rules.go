@@ -0,0 +1,182 @@
+package utils
+
+import (
+	"fmt"
+	"regexp"
+	"sync"
+)
+
+// ValidationRule declares the limits for the records of one entity type, under "rules" in the config file
+// Zero fields declare nothing; the App then falls back to the global limits (maxNameLength,
+// maxItemsPerCollection, and maxPrice for item prices)
+// Prices are item prices for items and totals for orders, promotions and templates
+type ValidationRule struct {
+	MaxNameLength int    `json:"maxNameLength,omitempty"`
+	MinPrice      uint64 `json:"minPrice,omitempty"`
+	MaxPrice      uint64 `json:"maxPrice,omitempty"`
+	AllowedChars  string `json:"allowedChars,omitempty"` // regexp character class such as `\p{L}\p{N} '&.-`, empty allows any
+	MaxItems      int    `json:"maxItems,omitempty"`     // orders, promotions and templates only
+}
+
+// RuleEntities are the entity types rules can be declared for, as named in the config file
+var RuleEntities = []string{"item", "order", "promotion", "template"}
+
+// compiledRule is a rule with its allowed characters compiled
+type compiledRule struct {
+	ValidationRule
+	allowed *regexp.Regexp
+}
+
+var (
+	rulesMu         sync.RWMutex
+	validationRules = map[string]compiledRule{}
+)
+
+// allowedCharsPattern returns the pattern a name must match to use only the characters of a class
+func allowedCharsPattern(class string) string {
+	return "^[" + class + "]*$"
+}
+
+// Validate checks a rule against the limits of the record format
+func (r ValidationRule) Validate(entity string) error {
+	known := false
+	for _, name := range RuleEntities {
+		known = known || name == entity
+	}
+	if !known {
+		return fmt.Errorf("unknown entity %q, expected one of %v", entity, RuleEntities)
+	}
+	if r.MaxNameLength < 0 || r.MaxNameLength > maxConfigNameLength {
+		return fmt.Errorf("%s: maxNameLength must be between 0 and %d", entity, maxConfigNameLength)
+	}
+	if r.MaxPrice > DefaultMaxPrice {
+		return fmt.Errorf("%s: maxPrice must be at most %d", entity, uint64(DefaultMaxPrice))
+	}
+	if r.MaxPrice != 0 && r.MinPrice > r.MaxPrice {
+		return fmt.Errorf("%s: minPrice must not exceed maxPrice", entity)
+	}
+	if r.MaxItems < 0 || r.MaxItems > maxConfigItems {
+		return fmt.Errorf("%s: maxItems must be between 0 and %d", entity, maxConfigItems)
+	}
+	if r.MaxItems != 0 && entity == "item" {
+		return fmt.Errorf("item: maxItems only applies to orders, promotions and templates")
+	}
+	if r.AllowedChars != "" {
+		if _, err := regexp.Compile(allowedCharsPattern(r.AllowedChars)); err != nil {
+			return fmt.Errorf("%s: invalid allowedChars: %w", entity, err)
+		}
+	}
+	return nil
+}
+
+// SetValidationRules replaces the rules in effect; the rules must have been validated
+func SetValidationRules(rules map[string]ValidationRule) {
+	compiled := make(map[string]compiledRule, len(rules))
+	for entity, rule := range rules {
+		c := compiledRule{ValidationRule: rule}
+		if rule.AllowedChars != "" {
+			c.allowed = regexp.MustCompile(allowedCharsPattern(rule.AllowedChars))
+		}
+		compiled[entity] = c
+	}
+
+	rulesMu.Lock()
+	validationRules = compiled
+	rulesMu.Unlock()
+}
+
+// ruleFor returns the rule declared for an entity, empty when there is none
+func ruleFor(entity string) compiledRule {
+	rulesMu.RLock()
+	defer rulesMu.RUnlock()
+	return validationRules[entity]
+}
+
+// checkName applies the name limits of a rule
+func (r compiledRule) checkName(entity, name string) error {
+	if r.MaxNameLength != 0 && len(name) > r.MaxNameLength {
+		return WithCode(CodeValidation, fmt.Errorf("%s name exceeds maximum length of %d characters", entity, r.MaxNameLength), nil)
+	}
+	if r.allowed != nil && !r.allowed.MatchString(name) {
+		return WithCode(CodeValidation, fmt.Errorf("%s name %q contains characters that are not allowed", entity, name), nil)
+	}
+	return nil
+}
+
+// checkPrice applies the price bounds of a rule
+func (r compiledRule) checkPrice(entity string, price uint64) error {
+	if price < r.MinPrice {
+		return WithCode(CodeValidation, fmt.Errorf("%s price %d is below the minimum of %d cents", entity, price, r.MinPrice), nil)
+	}
+	if r.MaxPrice != 0 && price > r.MaxPrice {
+		return WithCode(CodeValidation, fmt.Errorf("%s price %d exceeds maximum of %d cents", entity, price, r.MaxPrice), nil)
+	}
+	return nil
+}
+
+// checkItems applies the item cap of a rule
+func (r compiledRule) checkItems(entity string, count int) error {
+	if r.MaxItems != 0 && count > r.MaxItems {
+		return WithCode(CodeValidation, fmt.Errorf("%s exceeds maximum of %d items", entity, r.MaxItems), nil)
+	}
+	return nil
+}
+
+// CheckRecordRules applies only the rule declared for an entity to a record about to be written
+// DAOs call it on every write, so records written through any path obey the rules in the config
+func CheckRecordRules(entity, name string, price uint64, itemCount int) error {
+	rule := ruleFor(entity)
+	if err := rule.checkName(entity, name); err != nil {
+		return err
+	}
+	if err := rule.checkPrice(entity, price); err != nil {
+		return err
+	}
+	return rule.checkItems(entity, itemCount)
+}
+
+// CheckPriceRule applies only the price bounds declared for an entity, for writes that change nothing else
+func CheckPriceRule(entity string, price uint64) error {
+	return ruleFor(entity).checkPrice(entity, price)
+}
+
+// ValidateEntityName validates a name like ValidateName, with the limits declared for the entity
+func ValidateEntityName(entity, name string) error {
+	rule := ruleFor(entity)
+	if len(name) == 0 {
+		return ErrNameEmpty
+	}
+	if rule.MaxNameLength == 0 {
+		if err := ValidateName(name); err != nil {
+			return err
+		}
+	}
+	return rule.checkName(entity, name)
+}
+
+// ValidateEntityPrice validates a price with the bounds declared for the entity
+// Without a declared maximum, item prices are held to the global maxPrice
+func ValidateEntityPrice(entity string, price uint64) error {
+	rule := ruleFor(entity)
+	if rule.MaxPrice == 0 && entity == "item" {
+		if err := ValidatePrice(price); err != nil {
+			return err
+		}
+	}
+	return rule.checkPrice(entity, price)
+}
+
+// ValidateEntityItemIDs validates the item IDs of an order, promotion or template like ValidateItemIDs,
+// with the item cap declared for the entity
+func ValidateEntityItemIDs(entity string, itemIDs []uint64) error {
+	rule := ruleFor(entity)
+	if len(itemIDs) == 0 {
+		return ErrNoItems
+	}
+	if rule.MaxItems == 0 {
+		if err := ValidateItemIDs(itemIDs); err != nil {
+			return err
+		}
+	}
+	return rule.checkItems(entity, len(itemIDs))
+}
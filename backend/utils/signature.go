@@ -0,0 +1,127 @@
+package utils
+
+import (
+	"BinaryCRUD/backend/crypto"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SignatureExt is appended to a data file's path to name the file holding its signature
+const SignatureExt = ".sig"
+
+// SigningEnabled makes every write to a .bin file in the bin directory update the file's signature
+var SigningEnabled = false
+
+// Signature statuses reported by VerifyFileSignature
+const (
+	SignatureValid    = "valid"
+	SignatureTampered = "tampered"
+	SignatureMissing  = "unsigned"
+)
+
+// SignaturePath returns the path of the signature file of a data file
+func SignaturePath(path string) string {
+	return path + SignatureExt
+}
+
+// SignFile signs the current contents of a data file with the signing key in the keys directory
+// The signature is written to a temp file and renamed, so it is never left half-written
+func SignFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	signature, err := crypto.Sign(KeysDir, data)
+	if err != nil {
+		return fmt.Errorf("failed to sign %s: %w", path, err)
+	}
+
+	sigPath := SignaturePath(path)
+	tmpPath := sigPath + ".tmp"
+	if err := os.WriteFile(tmpPath, signature, 0644); err != nil {
+		return fmt.Errorf("failed to write signature: %w", err)
+	}
+	if err := os.Rename(tmpPath, sigPath); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to write signature: %w", err)
+	}
+	return nil
+}
+
+// VerifyFileSignature checks a data file against its signature file
+func VerifyFileSignature(path string) (string, error) {
+	signature, err := os.ReadFile(SignaturePath(path))
+	if os.IsNotExist(err) {
+		return SignatureMissing, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read signature: %w", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	valid, err := crypto.VerifySignature(KeysDir, data, signature)
+	if err != nil {
+		return "", fmt.Errorf("failed to verify %s: %w", path, err)
+	}
+	if !valid {
+		return SignatureTampered, nil
+	}
+	return SignatureValid, nil
+}
+
+// SignBinFiles signs every .bin file in the bin directory and returns how many were signed
+func SignBinFiles() (int, error) {
+	paths, err := binFilePaths()
+	if err != nil {
+		return 0, err
+	}
+	for _, path := range paths {
+		if err := SignFile(path); err != nil {
+			return 0, err
+		}
+	}
+	return len(paths), nil
+}
+
+// RemoveSignatures deletes the signature file of every .bin file in the bin directory
+func RemoveSignatures() error {
+	matches, err := filepath.Glob(filepath.Join(BinDir, "*.bin"+SignatureExt))
+	if err != nil {
+		return err
+	}
+	for _, match := range matches {
+		if err := os.Remove(match); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove %s: %w", match, err)
+		}
+	}
+	return nil
+}
+
+// binFilePaths returns the path of every current .bin file in the bin directory
+func binFilePaths() ([]string, error) {
+	names, err := CurrentBinFiles(BinDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	for _, name := range names {
+		paths = append(paths, BinPath(name))
+	}
+	return paths, nil
+}
+
+// signAfterWrite re-signs a file after a write when signing is enabled
+// Only .bin files in the bin directory are signed, not staged copies or test files elsewhere
+func signAfterWrite(file *os.File) error {
+	path := file.Name()
+	if !SigningEnabled || !strings.HasSuffix(path, ".bin") || filepath.Clean(filepath.Dir(path)) != filepath.Clean(BinDir) {
+		return nil
+	}
+	return SignFile(path)
+}
@@ -0,0 +1,70 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// DirUsage is the space taken by the files under a directory
+type DirUsage struct {
+	Bytes int64 `json:"bytes"`
+	Files int   `json:"files"`
+}
+
+// ReadDirUsage adds up the sizes of the files under dir and its subdirectories
+// A missing directory uses no space; files removed while the directory is walked are skipped
+func ReadDirUsage(dir string) (DirUsage, error) {
+	var usage DirUsage
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil || d.IsDir() {
+			return err
+		}
+		info, err := d.Info()
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		usage.Bytes += info.Size()
+		usage.Files++
+		return nil
+	})
+	if err != nil {
+		return usage, fmt.Errorf("failed to read %s: %w", dir, err)
+	}
+	return usage, nil
+}
+
+// RecordBytes splits the records of a data file, length prefixes included, by their tombstone
+type RecordBytes struct {
+	Live       int64 // bytes of active records
+	Tombstoned int64 // bytes of tombstoned records, freed by a compaction
+}
+
+// ReadRecordBytes scans a data file and adds up the sizes of its active and tombstoned records
+// keyFields is the number of ID-sized fields before the tombstone: 1 for records keyed by ID,
+// 2 for order_promotions.bin. A missing file has no records
+func ReadRecordBytes(filePath string, keyFields int) (RecordBytes, error) {
+	var bytes RecordBytes
+	err := ScanFileEntries(filePath, func(entry EntryInfo) error {
+		size := int64(RecordLengthSize + len(entry.Data))
+		tombstone := keyFields * entry.IDSize
+		if tombstone < len(entry.Data) && entry.Data[tombstone] != 0x00 {
+			bytes.Tombstoned += size
+		} else {
+			bytes.Live += size
+		}
+		return nil
+	})
+	if err != nil {
+		return bytes, fmt.Errorf("failed to scan %s: %w", filePath, err)
+	}
+	return bytes, nil
+}
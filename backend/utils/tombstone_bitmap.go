@@ -0,0 +1,414 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+// TombstoneBitmapExt is the extension of the tombstone bitmap kept next to the index of a data file
+const TombstoneBitmapExt = ".tomb"
+
+// TombstoneSlotsExt is the extension of the file holding the record offset of every slot of a tombstone bitmap
+const TombstoneSlotsExt = ".slots"
+
+// tombstoneBitmapMagic starts every tombstone bitmap file
+var tombstoneBitmapMagic = []byte("BTMB")
+
+// tombstoneSlotsMagic starts every slot offsets file
+var tombstoneSlotsMagic = []byte("BTMS")
+
+// tombstoneBitmapHeaderSize is the size of [magic(4)][dataSize(8)][tombstoneCount(4)][slots(4)]
+const tombstoneBitmapHeaderSize = 20
+
+// tombstoneSlotsHeaderSize is the size of [magic(4)][dataSize(8)]
+const tombstoneSlotsHeaderSize = 12
+
+// sidecarsReadOnly is set while the data directory is opened read-only, e.g. when another instance holds
+// its lock; tombstone bitmaps are then kept in memory and not saved over the files of that instance
+var sidecarsReadOnly atomic.Bool
+
+// SetSidecarsReadOnly turns saving the tombstone bitmaps of the data files off or back on
+func SetSidecarsReadOnly(readOnly bool) {
+	sidecarsReadOnly.Store(readOnly)
+}
+
+// TombstoneBitmapPath returns the path of the tombstone bitmap of a data file, in the indexes directory
+func TombstoneBitmapPath(binFilePath string) string {
+	return strings.TrimSuffix(IndexPathFromBinFile(binFilePath), ".idx") + TombstoneBitmapExt
+}
+
+// TombstoneSlotsPath returns the path of the slot offsets of the tombstone bitmap of a data file
+func TombstoneSlotsPath(binFilePath string) string {
+	return strings.TrimSuffix(IndexPathFromBinFile(binFilePath), ".idx") + TombstoneSlotsExt
+}
+
+// TombstoneBitmap holds one bit per record slot of a data file, in file order, set when the record
+// in the slot is tombstoned, so scans can skip deleted records without parsing them
+// The bitmap records the size and tombstone count of the data file it describes; a file changed
+// behind its back no longer matches them and the bitmap is rebuilt from the records
+// File format: [magic(4)][dataSize(8)][tombstoneCount(4)][slots(4)][bits...]
+// The record offset of each slot, needed to update the bitmap, is kept in a second file so the data file
+// is not scanned again: [magic(4)][dataSize(8)][offset(8)...]
+// Flush writes only the bytes changed since the last one, the header last
+type TombstoneBitmap struct {
+	dataSize   int64
+	tombstones int
+	slots      int
+	bits       []byte
+	offsets    []int64 // record offsets by slot, read from the slots file or the data file when a slot is first updated
+	flushed    int     // slots whose offset is in the slots file
+	dirtyFrom  int     // range of the bytes of bits changed since the last flush, empty when dirtyFrom >= dirtyTo
+	dirtyTo    int
+	stale      bool // the files don't hold the bitmap as of the last flush, the next one rewrites them whole
+}
+
+// BuildTombstoneBitmap reads the tombstone of every record slot of a data file
+// A missing file has no slots
+func BuildTombstoneBitmap(filePath string) (*TombstoneBitmap, error) {
+	bitmap := &TombstoneBitmap{offsets: []int64{}, stale: true}
+	err := ScanFileEntries(filePath, func(entry EntryInfo) error {
+		bitmap.append(entry.Position-RecordLengthSize, len(entry.Data) > entry.IDSize && entry.Data[entry.IDSize] != 0x00)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan %s: %w", filePath, err)
+	}
+	return bitmap, bitmap.capture(filePath)
+}
+
+// LoadTombstoneBitmap reads the tombstone bitmap of a data file, rebuilding and saving it when it is
+// missing, unreadable or doesn't match the data file anymore
+// While the sidecars are read-only the rebuilt bitmap is only kept in memory
+func LoadTombstoneBitmap(filePath string) (*TombstoneBitmap, error) {
+	if bitmap, err := readTombstoneBitmap(TombstoneBitmapPath(filePath)); err == nil && bitmap.matches(filePath) {
+		return bitmap, nil
+	}
+
+	bitmap, err := BuildTombstoneBitmap(filePath)
+	if err != nil {
+		return nil, err
+	}
+	if err := bitmap.Flush(filePath); err != nil {
+		return nil, err
+	}
+	return bitmap, nil
+}
+
+// readTombstoneBitmap parses a tombstone bitmap file
+func readTombstoneBitmap(path string) (*TombstoneBitmap, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < tombstoneBitmapHeaderSize || !bytes.Equal(data[:len(tombstoneBitmapMagic)], tombstoneBitmapMagic) {
+		return nil, WithCode(CodeCorruption, fmt.Errorf("invalid tombstone bitmap %s", path), nil)
+	}
+
+	bitmap := &TombstoneBitmap{
+		dataSize:   int64(binary.BigEndian.Uint64(data[4:12])),
+		tombstones: int(binary.BigEndian.Uint32(data[12:16])),
+		slots:      int(binary.BigEndian.Uint32(data[16:20])),
+		bits:       data[tombstoneBitmapHeaderSize:],
+	}
+	if len(bitmap.bits) != (bitmap.slots+7)/8 {
+		return nil, WithCode(CodeCorruption, fmt.Errorf("tombstone bitmap %s holds %d bytes for %d slots", path, len(bitmap.bits), bitmap.slots), nil)
+	}
+	return bitmap, nil
+}
+
+// readSlotOffsets returns the record offset of every slot from the slots file of a data file, or nil
+// when the file is missing or wasn't written along with the bitmap
+func (b *TombstoneBitmap) readSlotOffsets(filePath string) []int64 {
+	data, err := os.ReadFile(TombstoneSlotsPath(filePath))
+	if err != nil || len(data) < tombstoneSlotsHeaderSize+8*b.slots || !bytes.Equal(data[:len(tombstoneSlotsMagic)], tombstoneSlotsMagic) {
+		return nil
+	}
+	if int64(binary.BigEndian.Uint64(data[4:12])) != b.dataSize {
+		return nil
+	}
+
+	offsets := make([]int64, b.slots)
+	for slot := range offsets {
+		offsets[slot] = int64(binary.BigEndian.Uint64(data[tombstoneSlotsHeaderSize+8*slot:]))
+		if offsets[slot] >= b.dataSize || (slot > 0 && offsets[slot] <= offsets[slot-1]) {
+			return nil
+		}
+	}
+	return offsets
+}
+
+// fileState returns the size and header tombstone count of a data file, zero for a missing file
+func fileState(filePath string) (int64, int, error) {
+	header, err := ReadHeaderOnly(filePath)
+	if os.IsNotExist(err) {
+		return 0, 0, nil
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+	return header.FileSize, header.TombstoneCount, nil
+}
+
+// matches reports whether the bitmap was taken from the data file as it is now
+func (b *TombstoneBitmap) matches(filePath string) bool {
+	size, tombstones, err := fileState(filePath)
+	return err == nil && size == b.dataSize && tombstones == b.tombstones
+}
+
+// capture records the size and tombstone count of the data file the bits now describe
+func (b *TombstoneBitmap) capture(filePath string) error {
+	size, tombstones, err := fileState(filePath)
+	if err != nil {
+		return err
+	}
+	b.dataSize, b.tombstones = size, tombstones
+	return nil
+}
+
+// header returns the bitmap file header for the data file state last captured
+func (b *TombstoneBitmap) header() []byte {
+	header := make([]byte, tombstoneBitmapHeaderSize)
+	copy(header, tombstoneBitmapMagic)
+	binary.BigEndian.PutUint64(header[4:12], uint64(b.dataSize))
+	binary.BigEndian.PutUint32(header[12:16], uint32(b.tombstones))
+	binary.BigEndian.PutUint32(header[16:20], uint32(b.slots))
+	return header
+}
+
+// slotsHeader returns the slots file header for the data file state last captured
+func (b *TombstoneBitmap) slotsHeader() []byte {
+	header := make([]byte, tombstoneSlotsHeaderSize)
+	copy(header, tombstoneSlotsMagic)
+	binary.BigEndian.PutUint64(header[4:12], uint64(b.dataSize))
+	return header
+}
+
+// Save rewrites the bitmap, and the slot offsets once they are known, next to the index of the data file
+// it describes, after the writes it was updated for
+func (b *TombstoneBitmap) Save(filePath string) error {
+	if err := b.capture(filePath); err != nil {
+		return err
+	}
+
+	if b.offsets != nil {
+		data := b.slotsHeader()
+		for _, offset := range b.offsets {
+			data = binary.BigEndian.AppendUint64(data, uint64(offset))
+		}
+		if err := replaceSidecar(TombstoneSlotsPath(filePath), data); err != nil {
+			return err
+		}
+	}
+	if err := replaceSidecar(TombstoneBitmapPath(filePath), append(b.header(), b.bits...)); err != nil {
+		return err
+	}
+
+	b.flushed = len(b.offsets)
+	b.dirtyFrom, b.dirtyTo = 0, 0
+	b.stale = false
+	return nil
+}
+
+// replaceSidecar writes data to path through a temp file, so a reader never sees it half written
+func replaceSidecar(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", path, err)
+	}
+	tempPath := path + ".tmp"
+	if err := os.WriteFile(tempPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", filepath.Base(path), err)
+	}
+	if err := os.Rename(tempPath, path); err != nil {
+		os.Remove(tempPath)
+		return fmt.Errorf("failed to save %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
+
+// Flush writes the changes made since the last flush after the writes they describe: the new slot offsets,
+// the changed bytes of the bitmap, then the headers. The first flush of a rebuilt bitmap saves it whole
+// While the sidecars are read-only nothing is written, and the next flush allowed saves the bitmap whole
+func (b *TombstoneBitmap) Flush(filePath string) error {
+	if sidecarsReadOnly.Load() {
+		b.stale = true
+		return nil
+	}
+	if b.stale {
+		return b.Save(filePath)
+	}
+	if err := b.capture(filePath); err != nil {
+		return err
+	}
+
+	if b.offsets != nil {
+		data := b.slotsHeader()
+		for _, offset := range b.offsets[b.flushed:] {
+			data = binary.BigEndian.AppendUint64(data, uint64(offset))
+		}
+		err := writeSidecarAt(TombstoneSlotsPath(filePath), int64(tombstoneSlotsHeaderSize+8*b.flushed), data[tombstoneSlotsHeaderSize:], data[:tombstoneSlotsHeaderSize])
+		if err != nil {
+			return b.Save(filePath)
+		}
+		b.flushed = len(b.offsets)
+	}
+
+	var changed []byte
+	if b.dirtyFrom < b.dirtyTo {
+		changed = b.bits[b.dirtyFrom:b.dirtyTo]
+	}
+	if err := writeSidecarAt(TombstoneBitmapPath(filePath), int64(tombstoneBitmapHeaderSize+b.dirtyFrom), changed, b.header()); err != nil {
+		return b.Save(filePath)
+	}
+	b.dirtyFrom, b.dirtyTo = 0, 0
+	return nil
+}
+
+// writeSidecarAt writes data at offset of an existing sidecar file, then header at its start
+func writeSidecarAt(path string, offset int64, data, header []byte) error {
+	file, err := os.OpenFile(path, os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if len(data) > 0 {
+		if _, err := file.WriteAt(data, offset); err != nil {
+			file.Close()
+			return err
+		}
+	}
+	if _, err := file.WriteAt(header, 0); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// Slots returns the number of record slots the bitmap covers
+func (b *TombstoneBitmap) Slots() int {
+	return b.slots
+}
+
+// Tombstoned reports whether the record in a slot is tombstoned; slots past the bitmap are not
+func (b *TombstoneBitmap) Tombstoned(slot int) bool {
+	if slot < 0 || slot >= b.slots {
+		return false
+	}
+	return b.bits[slot/8]&(1<<(slot%8)) != 0
+}
+
+// Count returns the number of tombstoned slots
+func (b *TombstoneBitmap) Count() int {
+	count := 0
+	for slot := 0; slot < b.slots; slot++ {
+		if b.Tombstoned(slot) {
+			count++
+		}
+	}
+	return count
+}
+
+// append adds a slot for the record at offset
+func (b *TombstoneBitmap) append(offset int64, tombstoned bool) {
+	if b.slots%8 == 0 {
+		b.bits = append(b.bits, 0)
+	}
+	b.offsets = append(b.offsets, offset)
+	b.slots++
+	b.set(b.slots-1, tombstoned)
+}
+
+// set flips the bit of a slot, remembering the byte for the next flush
+func (b *TombstoneBitmap) set(slot int, tombstoned bool) {
+	if tombstoned {
+		b.bits[slot/8] |= 1 << (slot % 8)
+	} else {
+		b.bits[slot/8] &^= 1 << (slot % 8)
+	}
+	if b.dirtyFrom >= b.dirtyTo {
+		b.dirtyFrom, b.dirtyTo = slot/8, slot/8+1
+		return
+	}
+	b.dirtyFrom, b.dirtyTo = min(b.dirtyFrom, slot/8), max(b.dirtyTo, slot/8+1)
+}
+
+// loadOffsets reads the record offset of every slot, from the slots file when it was written along with
+// the bitmap, scanning the data file otherwise
+func (b *TombstoneBitmap) loadOffsets(filePath string) error {
+	if offsets := b.readSlotOffsets(filePath); offsets != nil {
+		b.offsets, b.flushed = offsets, len(offsets)
+		return nil
+	}
+
+	offsets := make([]int64, 0, b.slots)
+	err := ScanFileEntries(filePath, func(entry EntryInfo) error {
+		if len(offsets) < b.slots {
+			offsets = append(offsets, entry.Position-RecordLengthSize)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to scan %s: %w", filePath, err)
+	}
+	if len(offsets) != b.slots {
+		return fmt.Errorf("tombstone bitmap covers %d slots, %s holds %d", b.slots, filePath, len(offsets))
+	}
+	// The slots file didn't follow the bitmap, it is written whole on the next flush
+	b.offsets = offsets
+	b.stale = true
+	return nil
+}
+
+// Set records that the record at offset, the offset of its length prefix as stored in the index, was
+// tombstoned or written; a record past the last slot was appended and gets a new slot
+// Fails when offset isn't the start of a known slot, the bitmap must then be rebuilt
+func (b *TombstoneBitmap) Set(filePath string, offset int64, tombstoned bool) error {
+	if b.offsets == nil {
+		if err := b.loadOffsets(filePath); err != nil {
+			return err
+		}
+	}
+
+	if b.slots == 0 || offset > b.offsets[b.slots-1] {
+		b.append(offset, tombstoned)
+		return nil
+	}
+	slot := sort.Search(b.slots, func(i int) bool { return b.offsets[i] >= offset })
+	if slot == b.slots || b.offsets[slot] != offset {
+		return fmt.Errorf("no record slot at offset %d of %s", offset, filePath)
+	}
+	b.set(slot, tombstoned)
+	return nil
+}
+
+// RecordBytes splits the bytes of the records of the data file by their tombstone without reading them:
+// each record runs from the offset of its slot to the next one, the last to the end of the file
+// Fails when the bitmap doesn't match the data file anymore, it must then be rebuilt
+func (b *TombstoneBitmap) RecordBytes(filePath string) (RecordBytes, error) {
+	var bytes RecordBytes
+	if !b.matches(filePath) {
+		return bytes, fmt.Errorf("tombstone bitmap doesn't match %s", filePath)
+	}
+	if b.offsets == nil {
+		if err := b.loadOffsets(filePath); err != nil {
+			return bytes, err
+		}
+	}
+
+	for slot, offset := range b.offsets {
+		end := b.dataSize
+		if slot+1 < b.slots {
+			end = b.offsets[slot+1]
+		}
+		if b.Tombstoned(slot) {
+			bytes.Tombstoned += end - offset
+		} else {
+			bytes.Live += end - offset
+		}
+	}
+	return bytes, nil
+}
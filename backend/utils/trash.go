@@ -0,0 +1,197 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// trashTimeFormat names trash entries by the time their files were deleted, so names sort by age
+const trashTimeFormat = "20060102-150405.000000"
+
+// TrashEntry is one deletion kept in the trash directory
+type TrashEntry struct {
+	Name      string    `json:"name"` // directory inside TrashDir, passed to RestoreFromTrash
+	DeletedAt time.Time `json:"deletedAt"`
+	Files     []string  `json:"files"` // paths relative to the data directory, e.g. bin/items.bin
+	Size      int64     `json:"size"`
+}
+
+// Trash moves files into one new entry of the trash directory instead of deleting them
+// The entry directory is only created with the first file
+type Trash struct {
+	name string
+}
+
+// NewTrash starts a trash entry named after the current time
+func NewTrash() *Trash {
+	return &Trash{}
+}
+
+// Name returns the entry the files were moved to, empty while no file was moved
+func (t *Trash) Name() string {
+	return t.name
+}
+
+// Move moves a file of the data directory into the trash entry, keeping its path relative to the data directory
+func (t *Trash) Move(path string) error {
+	rel, err := filepath.Rel(DataDir, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return fmt.Errorf("%s is outside the data directory", path)
+	}
+	if t.name == "" {
+		if t.name, err = createTrashEntry(); err != nil {
+			return err
+		}
+	}
+
+	target := filepath.Join(TrashDir, t.name, rel)
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return fmt.Errorf("failed to create trash directory: %w", err)
+	}
+	if err := os.Rename(path, target); err != nil {
+		return fmt.Errorf("failed to move %s to trash: %w", rel, err)
+	}
+	return nil
+}
+
+// createTrashEntry creates the directory of a new trash entry, unique even for deletions in the same microsecond
+func createTrashEntry() (string, error) {
+	if err := os.MkdirAll(TrashDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create trash directory: %w", err)
+	}
+	now := time.Now().UTC()
+	for {
+		name := now.Format(trashTimeFormat)
+		err := os.Mkdir(filepath.Join(TrashDir, name), 0755)
+		if err == nil {
+			return name, nil
+		}
+		if !os.IsExist(err) {
+			return "", fmt.Errorf("failed to create trash entry: %w", err)
+		}
+		now = now.Add(time.Microsecond)
+	}
+}
+
+// ListTrash returns the trash entries, newest first
+func ListTrash() ([]TrashEntry, error) {
+	dirs, err := os.ReadDir(TrashDir)
+	if os.IsNotExist(err) {
+		return []TrashEntry{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read trash directory: %w", err)
+	}
+
+	entries := make([]TrashEntry, 0, len(dirs))
+	for _, dir := range dirs {
+		deletedAt, err := time.Parse(trashTimeFormat, dir.Name())
+		if !dir.IsDir() || err != nil {
+			continue
+		}
+		entry := TrashEntry{Name: dir.Name(), DeletedAt: deletedAt, Files: []string{}}
+		root := filepath.Join(TrashDir, dir.Name())
+		err = filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			rel, _ := filepath.Rel(root, path)
+			entry.Files = append(entry.Files, filepath.ToSlash(rel))
+			entry.Size += info.Size()
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read trash entry %s: %w", dir.Name(), err)
+		}
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name > entries[j].Name })
+	return entries, nil
+}
+
+// trashEntryPath returns the directory of a trash entry, failing for names that are not entries
+func trashEntryPath(name string) (string, error) {
+	if _, err := time.Parse(trashTimeFormat, name); err != nil {
+		return "", WithCode(CodeValidation, fmt.Errorf("invalid trash entry: %s", name), map[string]any{"entry": name})
+	}
+	path := filepath.Join(TrashDir, name)
+	if info, err := os.Stat(path); err != nil || !info.IsDir() {
+		return "", WithCode(CodeNotFound, fmt.Errorf("trash entry not found: %s", name), map[string]any{"entry": name})
+	}
+	return path, nil
+}
+
+// RestoreFromTrash moves the files of a trash entry back into the data directory and removes the entry
+// Files in the way are moved to a new trash entry first, so restoring never loses data
+// Returns the restored files, relative to the data directory, and the entry holding the replaced ones
+func RestoreFromTrash(name string) (restored []string, replaced string, err error) {
+	root, err := trashEntryPath(name)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var files []string
+	err = filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, _ := filepath.Rel(root, path)
+		files = append(files, rel)
+		return nil
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read trash entry %s: %w", name, err)
+	}
+
+	displaced := NewTrash()
+	for _, rel := range files {
+		target := filepath.Join(DataDir, rel)
+		if _, err := os.Stat(target); err == nil {
+			if err := displaced.Move(target); err != nil {
+				return restored, displaced.Name(), err
+			}
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return restored, displaced.Name(), fmt.Errorf("failed to create %s: %w", filepath.Dir(rel), err)
+		}
+		if err := os.Rename(filepath.Join(root, rel), target); err != nil {
+			return restored, displaced.Name(), fmt.Errorf("failed to restore %s: %w", rel, err)
+		}
+		restored = append(restored, filepath.ToSlash(rel))
+	}
+
+	if err := os.RemoveAll(root); err != nil {
+		return restored, displaced.Name(), fmt.Errorf("failed to remove trash entry %s: %w", name, err)
+	}
+	return restored, displaced.Name(), nil
+}
+
+// PurgeTrash permanently removes the trash entries deleted before cutoff
+// Returns the names of the entries removed
+func PurgeTrash(cutoff time.Time) ([]string, error) {
+	entries, err := ListTrash()
+	if err != nil {
+		return nil, err
+	}
+
+	var purged []string
+	for _, entry := range entries {
+		if !entry.DeletedAt.Before(cutoff) {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(TrashDir, entry.Name)); err != nil {
+			return purged, fmt.Errorf("failed to purge trash entry %s: %w", entry.Name, err)
+		}
+		purged = append(purged, entry.Name)
+	}
+	return purged, nil
+}
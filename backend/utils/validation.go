@@ -4,37 +4,59 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
 )
 
-// Validation constants
-const (
+// Validation limits, tunable through the config file (see ApplyConfig)
+var (
 	// MaxNameLength is the maximum allowed length for names (customer, item, promotion)
-	MaxNameLength = 255
+	MaxNameLength = DefaultMaxNameLength
 
 	// MaxItemsPerCollection is the maximum number of items allowed in an order/promotion
-	MaxItemsPerCollection = 1000
+	MaxItemsPerCollection = DefaultMaxItemsPerCollection
+
+	// MaxPrice is the maximum price in cents
+	MaxPrice uint64 = DefaultMaxPrice
+
+	// SanitizeNames makes SanitizeName normalize names before they are validated and stored
+	SanitizeNames = true
+)
+
+// Validation constants
+const (
+	// DefaultMaxNameLength is the default maximum length for names
+	DefaultMaxNameLength = 255
+
+	// DefaultMaxItemsPerCollection is the default maximum number of items in an order/promotion
+	DefaultMaxItemsPerCollection = 1000
+
+	// DefaultMaxPrice is the default maximum price in cents (max uint32 = ~$42.9 million)
+	// Prices are stored in 4 bytes, so no configured maximum can exceed it
+	DefaultMaxPrice = math.MaxUint32
 
 	// MaxRecordSize is the maximum allowed size for a single record (1MB)
 	MaxRecordSize = 1 << 20
 
-	// MaxPrice is the maximum price in cents (max uint32 = ~$42.9 million)
-	MaxPrice = math.MaxUint32
-
 	// MaxFileCount is the maximum number of files allowed in an archive
 	MaxFileCount = 10000
 
 	// MaxDecompressedSize is the maximum allowed decompressed size (100MB)
 	MaxDecompressedSize = 100 * 1024 * 1024
+
+	// MaxStreamedSize is the maximum size restored from a streamed archive (16GB), which never sits in memory
+	MaxStreamedSize = 16 << 30
 )
 
-// Validation errors
+// Validation errors, all classified as CodeValidation
 var (
-	ErrNameEmpty     = errors.New("name cannot be empty")
-	ErrNameTooLong   = fmt.Errorf("name exceeds maximum length of %d characters", MaxNameLength)
-	ErrNoItems       = errors.New("must contain at least one item")
-	ErrTooManyItems  = fmt.Errorf("exceeds maximum of %d items", MaxItemsPerCollection)
-	ErrPriceOverflow = errors.New("price calculation would overflow")
-	ErrRecordTooLarge = fmt.Errorf("record size exceeds maximum of %d bytes", MaxRecordSize)
+	ErrNameEmpty     = WithCode(CodeValidation, errors.New("name cannot be empty"), nil)
+	ErrNameTooLong   = WithCode(CodeValidation, fmt.Errorf("name exceeds maximum length of %d characters", MaxNameLength), nil)
+	ErrNoItems       = WithCode(CodeValidation, errors.New("must contain at least one item"), nil)
+	ErrTooManyItems  = WithCode(CodeValidation, fmt.Errorf("exceeds maximum of %d items", MaxItemsPerCollection), nil)
+	ErrPriceOverflow = WithCode(CodeValidation, errors.New("price calculation would overflow"), nil)
+	ErrRecordTooLarge = WithCode(CodeValidation, fmt.Errorf("record size exceeds maximum of %d bytes", MaxRecordSize), nil)
 )
 
 // ValidateName validates a name string (customer name, item name, promotion name)
@@ -48,6 +70,22 @@ func ValidateName(name string) error {
 	return nil
 }
 
+// SanitizeName returns the form of a name that is validated and stored: Unicode NFC with surrounding
+// whitespace trimmed and inner whitespace collapsed to single spaces, so "Cafe\u0301 " and "Café" are one name
+// Names are returned unchanged when SanitizeNames is off
+func SanitizeName(name string) string {
+	if !SanitizeNames {
+		return name
+	}
+	return strings.Join(strings.Fields(norm.NFC.String(name)), " ")
+}
+
+// NormalizeName returns the form of a name used for duplicate detection:
+// NFC and lowercase with surrounding whitespace trimmed and inner whitespace collapsed
+func NormalizeName(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(norm.NFC.String(name)), " "))
+}
+
 // ValidateItemIDs validates a slice of item IDs for collections
 func ValidateItemIDs(itemIDs []uint64) error {
 	if len(itemIDs) == 0 {
@@ -62,7 +100,7 @@ func ValidateItemIDs(itemIDs []uint64) error {
 // ValidatePrice validates that a price is within acceptable bounds
 func ValidatePrice(priceInCents uint64) error {
 	if priceInCents > MaxPrice {
-		return fmt.Errorf("price %d exceeds maximum of %d cents", priceInCents, MaxPrice)
+		return WithCode(CodeValidation, fmt.Errorf("price %d exceeds maximum of %d cents", priceInCents, MaxPrice), nil)
 	}
 	return nil
 }
@@ -105,6 +143,14 @@ func ValidateDecompressedSize(size int) error {
 	return nil
 }
 
+// ValidateStreamedSize validates that the size restored from a streamed archive is within limits
+func ValidateStreamedSize(size int64) error {
+	if size > MaxStreamedSize {
+		return fmt.Errorf("decompressed size %d exceeds maximum of %d bytes", size, int64(MaxStreamedSize))
+	}
+	return nil
+}
+
 // ValidateOffset validates that an offset is within file bounds
 func ValidateOffset(offset int64, fileSize int64) error {
 	if offset < 0 {
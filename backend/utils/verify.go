@@ -0,0 +1,209 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// FileCheck is the result of checking the structure of one data file
+type FileCheck struct {
+	File       string
+	Records    int
+	Tombstones int
+	NextID     uint64   // next auto-assigned ID, zero for files without IDs
+	IDGaps     int      // IDs below NextID that no record holds, left by crashes or removed by compaction
+	Signature  string   // signature status, empty when the file was not checked against one
+	Problems   []string // empty when the file is consistent
+}
+
+// OK reports whether no problems were found
+func (c *FileCheck) OK() bool {
+	return len(c.Problems) == 0
+}
+
+// recordParsers parse the records of the known data files and return their tombstone byte
+var recordParsers = map[string]func(data []byte, idSize int) (byte, error){
+	"items.bin": func(data []byte, idSize int) (byte, error) {
+		item, err := ItemCodec.Decode(data, idSize)
+		if err != nil {
+			return 0, err
+		}
+		return item.Tombstone, nil
+	},
+	"orders.bin":     parseCollectionTombstone,
+	"promotions.bin": parseCollectionTombstone,
+	"templates.bin":  parseCollectionTombstone,
+	"order_promotions.bin": func(data []byte, idSize int) (byte, error) {
+		op, err := OrderPromotionCodec.Decode(data, idSize)
+		if err != nil {
+			return 0, err
+		}
+		return op.Tombstone, nil
+	},
+}
+
+// keylessFiles are the data files whose records have composite keys instead of IDs
+var keylessFiles = map[string]bool{"order_promotions.bin": true}
+
+// parseCollectionTombstone parses an order or promotion record
+func parseCollectionTombstone(data []byte, idSize int) (byte, error) {
+	collection, err := CollectionCodec.Decode(data, idSize)
+	if err != nil {
+		return 0, err
+	}
+	return collection.Tombstone, nil
+}
+
+// VerifyBinFile checks that a data file has a valid header, that every record can be read
+// and, for the known entity files, parsed, and that the header counts match the records
+// The signature is checked too when signing is enabled or the file has one
+func VerifyBinFile(path string) (*FileCheck, error) {
+	check := &FileCheck{File: filepath.Base(path)}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	_, entitiesCount, tombstoneCount, _, err := ReadHeader(file)
+	file.Close()
+	if err != nil {
+		check.Problems = append(check.Problems, fmt.Sprintf("invalid header: %v", err))
+		return check, nil
+	}
+
+	entries, err := SplitFileIntoEntries(path)
+	if err != nil {
+		check.Problems = append(check.Problems, err.Error())
+		return check, nil
+	}
+	check.Records = len(entries)
+
+	// SplitFileIntoEntries stops at a partial length field, report it as a truncated record
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+	end, err := GetHeaderSize(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) > 0 {
+		last := entries[len(entries)-1]
+		end = int(last.Position) + len(last.Data)
+	}
+	if int64(end) != info.Size() {
+		check.Problems = append(check.Problems, fmt.Sprintf("%d trailing bytes after the last record", info.Size()-int64(end)))
+	}
+
+	if parse, known := recordParsers[check.File]; known {
+		for _, entry := range entries {
+			tombstone, err := parse(entry.Data, entry.IDSize)
+			if err != nil {
+				check.Problems = append(check.Problems, fmt.Sprintf("record at offset %d: %v", entry.Position, err))
+				continue
+			}
+			if tombstone != 0x00 {
+				check.Tombstones++
+			}
+		}
+		if entitiesCount != check.Records {
+			check.Problems = append(check.Problems, fmt.Sprintf("header counts %d records, found %d", entitiesCount, check.Records))
+		}
+		if tombstoneCount != check.Tombstones {
+			check.Problems = append(check.Problems, fmt.Sprintf("header counts %d tombstones, found %d", tombstoneCount, check.Tombstones))
+		}
+	}
+
+	if !keylessFiles[check.File] {
+		if err := auditIDs(path, entries, check); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := os.Stat(SignaturePath(path)); SigningEnabled || err == nil {
+		status, err := VerifyFileSignature(path)
+		if err != nil {
+			return nil, err
+		}
+		check.Signature = status
+		if status != SignatureValid {
+			check.Problems = append(check.Problems, fmt.Sprintf("signature %s", status))
+		}
+	}
+
+	return check, nil
+}
+
+// auditIDs reports IDs held by more than one active record or that the allocator could hand out again,
+// and counts the gaps below the next ID
+func auditIDs(path string, entries []EntryInfo, check *FileCheck) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	next, err := NextID(file)
+	file.Close()
+	if err != nil {
+		return err
+	}
+	check.NextID = next
+
+	active := make(map[uint64]int)
+	seen := make(map[uint64]bool)
+	var reusable []uint64
+	for _, entry := range entries {
+		id, offset, err := ReadFixedNumber(entry.IDSize, entry.Data, 0)
+		if err != nil || offset >= len(entry.Data) {
+			continue
+		}
+		if id >= next && !seen[id] {
+			reusable = append(reusable, id)
+		}
+		seen[id] = true
+		if entry.Data[offset] == 0x00 {
+			active[id]++
+		}
+	}
+
+	for _, id := range reusable {
+		check.Problems = append(check.Problems, fmt.Sprintf("ID %d is not below the next ID %d and would be handed out again", id, next))
+	}
+	duplicated := make([]uint64, 0)
+	for id, count := range active {
+		if count > 1 {
+			duplicated = append(duplicated, id)
+		}
+	}
+	slices.Sort(duplicated)
+	for _, id := range duplicated {
+		check.Problems = append(check.Problems, fmt.Sprintf("ID %d is held by %d active records", id, active[id]))
+	}
+
+	held := 0
+	for id := range seen {
+		if id < next {
+			held++
+		}
+	}
+	check.IDGaps = int(next) - held
+	return nil
+}
+
+// VerifyBinFiles checks every .bin file in the bin directory
+func VerifyBinFiles() ([]*FileCheck, error) {
+	paths, err := binFilePaths()
+	if err != nil {
+		return nil, err
+	}
+	checks := make([]*FileCheck, 0, len(paths))
+	for _, path := range paths {
+		check, err := VerifyBinFile(path)
+		if err != nil {
+			return nil, err
+		}
+		checks = append(checks, check)
+	}
+	return checks, nil
+}
@@ -0,0 +1,44 @@
+package utils
+
+import (
+	"BinaryCRUD/backend/events"
+	"fmt"
+	"net/url"
+)
+
+// DefaultWebhookEvents are sent to webhooks that do not list their events: creates, deletes and promotion links
+var DefaultWebhookEvents = []events.Type{
+	events.ItemCreated, events.ItemDeleted,
+	events.OrderCreated, events.OrderDeleted,
+	events.PromotionCreated, events.PromotionDeleted,
+	events.PromotionApplied, events.PromotionRemoved,
+}
+
+// Webhook is an HTTP endpoint that receives data change events as JSON
+type Webhook struct {
+	URL    string        `json:"url"`
+	Events []events.Type `json:"events,omitempty"` // empty means DefaultWebhookEvents
+	Secret string        `json:"secret,omitempty"` // signs the body with HMAC-SHA256 when set
+}
+
+// Subscribed returns the event types delivered to the webhook
+func (w Webhook) Subscribed() []events.Type {
+	if len(w.Events) == 0 {
+		return DefaultWebhookEvents
+	}
+	return w.Events
+}
+
+// Validate checks the URL and the event names
+func (w Webhook) Validate() error {
+	u, err := url.Parse(w.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid webhook URL %q (expected http:// or https://)", w.URL)
+	}
+	for _, t := range w.Events {
+		if !events.Known(t) {
+			return fmt.Errorf("unknown webhook event %q", t)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,206 @@
+package webhook
+
+import (
+	"BinaryCRUD/backend/events"
+	"BinaryCRUD/backend/utils"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Delivery defaults
+const (
+	DefaultMaxAttempts = 5
+	DefaultBackoff     = time.Second      // delay before the first retry, doubled after each failure
+	maxBackoff         = 5 * time.Minute  // upper bound of the retry delay
+	requestTimeout     = 10 * time.Second // timeout of a single delivery attempt
+	closeTimeout       = time.Second      // how long Close waits for the workers once their requests are cancelled
+	queueSize          = 256              // deliveries queued per webhook
+)
+
+// Header names sent with every delivery
+const (
+	EventHeader     = "X-BinaryCRUD-Event"
+	SignatureHeader = "X-BinaryCRUD-Signature" // sha256=<hex HMAC of the body>, only with a secret
+)
+
+// Payload is the JSON body posted to a webhook
+type Payload struct {
+	events.Event
+	Data map[string]any `json:"data,omitempty"` // the record after the change, when it still exists
+}
+
+// delivery is one payload for one webhook
+type delivery struct {
+	hook    utils.Webhook
+	body    []byte
+	event   events.Type
+	attempt int
+}
+
+// Dispatcher posts payloads to the configured webhooks, each from its own background worker and queue,
+// so a slow or unreachable webhook only delays its own deliveries
+// Failed deliveries are retried with exponential backoff; a full queue drops new payloads
+type Dispatcher struct {
+	hooks       []utils.Webhook
+	client      *http.Client
+	logf        func(string)
+	MaxAttempts int
+	Backoff     time.Duration
+
+	queues []chan delivery // queue of each webhook, in the order of hooks
+	ctx    context.Context // cancelled by Close, aborting the requests in flight
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewDispatcher starts a dispatcher for the given webhooks, reporting failures through logf
+func NewDispatcher(hooks []utils.Webhook, logf func(string)) *Dispatcher {
+	ctx, cancel := context.WithCancel(context.Background())
+	d := &Dispatcher{
+		hooks:       hooks,
+		client:      &http.Client{Timeout: requestTimeout},
+		logf:        logf,
+		MaxAttempts: DefaultMaxAttempts,
+		Backoff:     DefaultBackoff,
+		queues:      make([]chan delivery, len(hooks)),
+		ctx:         ctx,
+		cancel:      cancel,
+	}
+	for i := range hooks {
+		d.queues[i] = make(chan delivery, queueSize)
+		d.wg.Add(1)
+		go d.run(d.queues[i])
+	}
+	return d
+}
+
+// Wants reports whether any webhook is subscribed to an event type
+func (d *Dispatcher) Wants(t events.Type) bool {
+	for _, hook := range d.hooks {
+		if subscribed(hook, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// Send queues a payload for every webhook subscribed to its event type
+func (d *Dispatcher) Send(payload Payload) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		d.logf(fmt.Sprintf("Webhook payload for %s not sent: %v", payload.Type, err))
+		return
+	}
+	for i, hook := range d.hooks {
+		if subscribed(hook, payload.Type) {
+			d.enqueue(d.queues[i], delivery{hook: hook, body: body, event: payload.Type, attempt: 1})
+		}
+	}
+}
+
+// Close stops the workers, cancelling the deliveries in flight; queued and pending retries are discarded
+// It waits at most closeTimeout for the workers to return
+func (d *Dispatcher) Close() {
+	d.cancel()
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(closeTimeout):
+		d.logf(fmt.Sprintf("Webhook workers still running %s after close, not waiting for them", closeTimeout))
+	}
+}
+
+// subscribed reports whether a webhook receives an event type
+func subscribed(hook utils.Webhook, t events.Type) bool {
+	for _, wanted := range hook.Subscribed() {
+		if wanted == t {
+			return true
+		}
+	}
+	return false
+}
+
+// enqueue adds a delivery to the queue of its webhook unless the dispatcher is closed or the queue is full
+func (d *Dispatcher) enqueue(queue chan delivery, job delivery) {
+	select {
+	case <-d.ctx.Done():
+	case queue <- job:
+	default:
+		d.logf(fmt.Sprintf("Webhook queue full, dropped %s for %s", job.event, job.hook.URL))
+	}
+}
+
+// run delivers the payloads queued for one webhook until the dispatcher is closed
+func (d *Dispatcher) run(queue chan delivery) {
+	defer d.wg.Done()
+	for {
+		select {
+		case <-d.ctx.Done():
+			return
+		case job := <-queue:
+			d.deliver(queue, job)
+		}
+	}
+}
+
+// deliver posts a payload and schedules a retry on the queue of its webhook when it fails
+func (d *Dispatcher) deliver(queue chan delivery, job delivery) {
+	err := d.post(job)
+	if err == nil || d.ctx.Err() != nil {
+		return
+	}
+	if job.attempt >= d.MaxAttempts {
+		d.logf(fmt.Sprintf("Webhook %s gave up on %s after %d attempts: %v", job.hook.URL, job.event, job.attempt, err))
+		return
+	}
+
+	delay := d.Backoff << (job.attempt - 1)
+	if delay > maxBackoff || delay <= 0 {
+		delay = maxBackoff
+	}
+	d.logf(fmt.Sprintf("Webhook %s failed on %s (attempt %d), retrying in %s: %v", job.hook.URL, job.event, job.attempt, delay, err))
+	job.attempt++
+	time.AfterFunc(delay, func() { d.enqueue(queue, job) })
+}
+
+// post sends one delivery attempt, any status other than 2xx is an error
+func (d *Dispatcher) post(job delivery) error {
+	req, err := http.NewRequestWithContext(d.ctx, http.MethodPost, job.hook.URL, bytes.NewReader(job.body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, string(job.event))
+	if job.hook.Secret != "" {
+		req.Header.Set(SignatureHeader, "sha256="+Sign(job.body, job.hook.Secret))
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("status %s", resp.Status)
+	}
+	return nil
+}
+
+// Sign returns the hex HMAC-SHA256 of a body, as sent in the signature header
+func Sign(body []byte, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"BinaryCRUD/backend/events"
+	"BinaryCRUD/backend/utils"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// compactionStatus tracks the background compactor
+type compactionStatus struct {
+	mu         sync.Mutex
+	running    bool
+	startedAt  time.Time
+	finishedAt time.Time
+	result     *CompactResult
+	err        error
+	done       chan struct{} // closed when the background compaction goroutine returns, nil before the first one
+	stopped    bool          // set when shutdown begins; no compaction starts afterwards
+}
+
+// compactionTarget is a data file compacted online through its DAO
+type compactionTarget struct {
+	name      string
+	keyFields int
+	locked    func(fn func(filePath string) error) error
+	replace   func(path string, prepare func() error) error
+	snapshot  *utils.FileSnapshot
+}
+
+// isCompacting reports whether a background compaction is running
+func (a *App) isCompacting() bool {
+	a.compaction.mu.Lock()
+	defer a.compaction.mu.Unlock()
+	return a.compaction.running
+}
+
+// beginCompaction marks a compaction as running, failing when another one already is
+// Every compaction, manual or background, must hold this flag since they all rewrite the same files
+func (a *App) beginCompaction() error {
+	a.compaction.mu.Lock()
+	defer a.compaction.mu.Unlock()
+	return a.beginCompactionLocked()
+}
+
+// canCompact reports whether a compaction could start now: none is running and shutdown has not begun
+func (a *App) canCompact() bool {
+	a.compaction.mu.Lock()
+	defer a.compaction.mu.Unlock()
+	return !a.compaction.running && !a.compaction.stopped
+}
+
+// beginCompactionLocked is beginCompaction for callers already holding a.compaction.mu
+func (a *App) beginCompactionLocked() error {
+	if a.compaction.stopped {
+		return utils.WithCode(utils.CodeConflict, fmt.Errorf("the application is shutting down"), nil)
+	}
+	if a.compaction.running {
+		return utils.WithCode(utils.CodeConflict, fmt.Errorf("a compaction is already running"), nil)
+	}
+	a.compaction.running = true
+	return nil
+}
+
+// endCompaction clears the running flag set by beginCompaction
+func (a *App) endCompaction() {
+	a.compaction.mu.Lock()
+	defer a.compaction.mu.Unlock()
+	a.compaction.running = false
+}
+
+// StartBackgroundCompaction compacts all files in a goroutine while the application keeps serving requests
+// Each file is copied under its DAO lock, the copies are compacted, and every DAO then switches to its
+// compacted file after catching up with the writes made in the meantime
+func (a *App) StartBackgroundCompaction() (err error) {
+	defer a.track("StartBackgroundCompaction", time.Now(), &err)
+	if err := a.checkWritable(); err != nil {
+		return err
+	}
+
+	a.compaction.mu.Lock()
+	defer a.compaction.mu.Unlock()
+
+	if err := a.beginCompactionLocked(); err != nil {
+		return err
+	}
+	a.compaction.startedAt = time.Now().UTC()
+	a.compaction.result = nil
+	a.compaction.err = nil
+	a.compaction.done = make(chan struct{})
+
+	a.logger.Info("Starting background compaction...")
+	go a.runBackgroundCompaction(a.compaction.done)
+	return nil
+}
+
+// waitForCompaction blocks until the background compaction goroutine, if any, has returned
+// Called before the DAOs are closed, since the goroutine keeps writing the data files until then
+func (a *App) waitForCompaction() {
+	a.compaction.mu.Lock()
+	done := a.compaction.done
+	a.compaction.mu.Unlock()
+	if done != nil {
+		<-done
+	}
+}
+
+// stopCompaction keeps any compaction from starting and waits for the running background one
+// Called when shutdown begins, so a delete served meanwhile cannot start a compaction on files being closed
+func (a *App) stopCompaction() {
+	a.compaction.mu.Lock()
+	a.compaction.stopped = true
+	a.compaction.mu.Unlock()
+	a.waitForCompaction()
+}
+
+// runBackgroundCompaction runs an online compaction and records its outcome, closing done when it returns
+func (a *App) runBackgroundCompaction(done chan struct{}) {
+	defer close(done)
+	start := time.Now()
+	result, err := a.compactOnline()
+	a.observeCompaction(start, err)
+
+	a.compaction.mu.Lock()
+	a.compaction.running = false
+	a.compaction.finishedAt = time.Now().UTC()
+	a.compaction.result = result
+	a.compaction.err = err
+	a.compaction.mu.Unlock()
+
+	if err != nil {
+		a.logger.Error(fmt.Sprintf("Background compaction failed: %v", err))
+		a.toast.Error(fmt.Sprintf("Background compaction failed: %v", err))
+		return
+	}
+
+	a.signDataFiles()
+	a.publish(events.Event{Type: events.Compacted})
+
+	removed := result.ItemsRemoved + result.OrdersRemoved + result.PromotionsRemoved + result.OrderPromotionsRemoved
+	a.logger.Info(fmt.Sprintf("Background compaction complete: %d records removed, %d orders affected, %d promotions affected",
+		removed, result.OrdersAffected, result.PromotionsAffected))
+	a.toast.Success(fmt.Sprintf("Background compaction removed %d records", removed))
+}
+
+// compactOnline snapshots, compacts and switches every data file without stopping writers
+func (a *App) compactOnline() (*CompactResult, error) {
+	dir := utils.CompactionDir
+	if err := os.RemoveAll(dir); err != nil {
+		return nil, fmt.Errorf("failed to clear compaction directory: %w", err)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create compaction directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	targets := []*compactionTarget{
+		{name: "items.bin", keyFields: utils.IDKeyFields, locked: a.itemDAO.WithFileLocked, replace: a.itemDAO.ReplaceFile},
+		{name: "orders.bin", keyFields: utils.IDKeyFields, locked: a.orderDAO.WithFileLocked, replace: a.orderDAO.ReplaceFile},
+		{name: "promotions.bin", keyFields: utils.IDKeyFields, locked: a.promotionDAO.WithFileLocked, replace: a.promotionDAO.ReplaceFile},
+		{name: "order_promotions.bin", keyFields: utils.CompositeKeyFields, locked: a.orderPromotionDAO.WithFileLocked, replace: a.orderPromotionDAO.ReplaceFile},
+	}
+
+	// Copy each file while its DAO is briefly locked
+	for _, target := range targets {
+		err := target.locked(func(livePath string) error {
+			if _, err := os.Stat(livePath); os.IsNotExist(err) {
+				return nil
+			}
+			snapshot, err := utils.TakeSnapshot(livePath, filepath.Join(dir, target.name), target.keyFields)
+			target.snapshot = snapshot
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to snapshot %s: %w", target.name, err)
+		}
+	}
+
+	// Compact the copies without holding any lock
+	result, err := utils.CompactFiles(context.Background(),
+		filepath.Join(dir, "items.bin"),
+		filepath.Join(dir, "orders.bin"),
+		filepath.Join(dir, "promotions.bin"),
+		filepath.Join(dir, "order_promotions.bin"),
+		a.itemBasePrice,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// Catch up with concurrent writes and switch each DAO to its compacted file
+	caughtUp := 0
+	for _, target := range targets {
+		if target.snapshot == nil {
+			continue
+		}
+		snapshot := target.snapshot
+		err := target.replace(snapshot.CopyPath, func() error {
+			changes, err := snapshot.CatchUp()
+			caughtUp += changes
+			return err
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to switch %s: %w", target.name, err)
+		}
+	}
+	a.logger.Info(fmt.Sprintf("Background compaction caught up with %d concurrent changes", caughtUp))
+
+	return newCompactResult(result), nil
+}
+
+// GetCompactionStatus returns the state of the background compactor and the outcome of its last run
+func (a *App) GetCompactionStatus() map[string]any {
+	defer a.track("GetCompactionStatus", time.Now(), nil)
+	a.compaction.mu.Lock()
+	defer a.compaction.mu.Unlock()
+
+	status := map[string]any{
+		"running": a.compaction.running,
+	}
+	if !a.compaction.startedAt.IsZero() {
+		status["startedAt"] = a.compaction.startedAt.Format(time.RFC3339)
+	}
+	if !a.compaction.finishedAt.IsZero() && !a.compaction.running {
+		status["finishedAt"] = a.compaction.finishedAt.Format(time.RFC3339)
+	}
+	if a.compaction.result != nil {
+		status["result"] = a.compaction.result
+	}
+	if a.compaction.err != nil {
+		status["error"] = a.compaction.err.Error()
+	}
+	return status
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"BinaryCRUD/backend/backup"
+	"BinaryCRUD/backend/crypto"
+	"BinaryCRUD/backend/events"
+	"BinaryCRUD/backend/utils"
+	"fmt"
+	"time"
+)
+
+// backupManifestToMap converts a backup manifest for the frontend
+func backupManifestToMap(path string, manifest *backup.Manifest) map[string]any {
+	files := make([]map[string]any, len(manifest.Files))
+	for i, f := range manifest.Files {
+		files[i] = map[string]any{
+			"path": f.Path,
+			"size": f.Size,
+		}
+	}
+
+	return map[string]any{
+		"path":      path,
+		"files":     files,
+		"fileCount": len(manifest.Files),
+		"totalSize": manifest.TotalSize(),
+		"encrypted": manifest.Encrypted,
+	}
+}
+
+// BackupDatabase writes all .bin files, indexes, keys and the oplog into a single archive
+// If passphrase is not empty the archive is encrypted with AES-GCM
+func (a *App) BackupDatabase(path string, passphrase string) (_ map[string]any, err error) {
+	defer a.track("BackupDatabase", time.Now(), &err)
+	if path == "" {
+		return nil, fmt.Errorf("backup path cannot be empty")
+	}
+
+	manifest, err := backup.Create(utils.DataDir, backup.DefaultDirs, path, passphrase)
+	if err != nil {
+		a.logger.Error(fmt.Sprintf("Backup failed: %v", err))
+		return nil, fmt.Errorf("backup failed: %w", err)
+	}
+
+	encrypted := ""
+	if manifest.Encrypted {
+		encrypted = " (encrypted)"
+	}
+	a.logger.Info(fmt.Sprintf("Backed up %d files (%d bytes) to %s%s", len(manifest.Files), manifest.TotalSize(), path, encrypted))
+
+	return backupManifestToMap(path, manifest), nil
+}
+
+// RestoreDatabase replaces the bin, index, key and oplog directories with the contents of a backup
+// The archive is fully verified before anything is replaced, then all DAOs are reloaded
+func (a *App) RestoreDatabase(path string, passphrase string) (_ map[string]any, err error) {
+	defer a.track("RestoreDatabase", time.Now(), &err)
+	if err := a.checkWritable(); err != nil {
+		return nil, err
+	}
+
+	a.closeDAOs()
+	manifest, err := backup.Restore(path, utils.DataDir, backup.DefaultDirs, passphrase)
+	if err != nil {
+		a.logger.Error(fmt.Sprintf("Restore failed: %v", err))
+		return nil, fmt.Errorf("restore failed: %w", err)
+	}
+
+	// Drop cached keys and in-memory indexes so they are loaded from the restored files
+	crypto.Reset()
+	a.reloadDAOs()
+	a.publish(events.Event{Type: events.DataReloaded})
+
+	a.logger.Info(fmt.Sprintf("Restored %d files (%d bytes) from %s", len(manifest.Files), manifest.TotalSize(), path))
+
+	return backupManifestToMap(path, manifest), nil
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"BinaryCRUD/backend/backup"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBackupDatabaseAfterCompactionSkipsThePreviousGeneration(t *testing.T) {
+	app := newTestApp(t)
+	compactWithDeletes(t, app)
+
+	path := filepath.Join(t.TempDir(), "backup.bbak")
+	if _, err := app.BackupDatabase(path, ""); err != nil {
+		t.Fatalf("BackupDatabase failed: %v", err)
+	}
+	manifest, _, err := backup.Read(path, "")
+	if err != nil {
+		t.Fatalf("Failed to read backup: %v", err)
+	}
+	found := false
+	for _, file := range manifest.Files {
+		name := filepath.Base(file.Path)
+		if name == "items.bin" || strings.HasPrefix(name, "items.idx") || name == "items.tomb" {
+			t.Errorf("Expected the previous generation to be left out, got %s", file.Path)
+		}
+		found = found || file.Path == "bin/items.gen1.bin"
+	}
+	if !found {
+		t.Errorf("Expected bin/items.gen1.bin in the backup, got %+v", manifest.Files)
+	}
+
+	if _, err := app.RestoreDatabase(path, ""); err != nil {
+		t.Fatalf("RestoreDatabase failed: %v", err)
+	}
+	items, err := app.GetAllItems(ListOptions{})
+	if err != nil || len(items) != 2 {
+		t.Fatalf("Expected the 2 items left by the compaction after the restore, got %d (err %v)", len(items), err)
+	}
+}
+
+func TestBackupDatabaseEncrypted(t *testing.T) {
+	app := newTestApp(t)
+	if _, err := app.AddItem("Burger", 899); err != nil {
+		t.Fatalf("Failed to add item: %v", err)
+	}
+	if _, err := app.CreateOrder("Alice", []uint64{0}); err != nil {
+		t.Fatalf("Failed to create order: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "backup.bbak")
+	result, err := app.BackupDatabase(path, "hunter2")
+	if err != nil {
+		t.Fatalf("BackupDatabase failed: %v", err)
+	}
+	if result["encrypted"] != true || result["path"] != path {
+		t.Errorf("Expected an encrypted backup at %s, got %v", path, result)
+	}
+
+	// The archive only opens with the passphrase it was written with
+	if _, _, err := backup.Read(path, ""); err == nil {
+		t.Error("Expected reading without the passphrase to fail")
+	}
+	if _, _, err := backup.Read(path, "wrong"); err == nil {
+		t.Error("Expected reading with the wrong passphrase to fail")
+	}
+	manifest, contents, err := backup.Read(path, "hunter2")
+	if err != nil {
+		t.Fatalf("Failed to read backup: %v", err)
+	}
+	if len(manifest.Files) != result["fileCount"] {
+		t.Errorf("Expected %v files, got %d", result["fileCount"], len(manifest.Files))
+	}
+
+	// Every data directory is included, the keys the order names need among them
+	dirs := map[string]bool{}
+	for _, file := range manifest.Files {
+		dirs[strings.SplitN(file.Path, "/", 2)[0]] = true
+	}
+	for _, dir := range backup.DefaultDirs {
+		if !dirs[dir] {
+			t.Errorf("Expected %s in the backup, got %+v", dir, manifest.Files)
+		}
+	}
+	if _, ok := contents["bin/orders.bin"]; !ok {
+		t.Error("Expected bin/orders.bin in the backup")
+	}
+
+	if _, err := app.BackupDatabase("", ""); err == nil {
+		t.Error("Expected an empty backup path to be rejected")
+	}
+}
+
+func TestRestoreDatabaseUndoesLaterChanges(t *testing.T) {
+	app := newTestApp(t)
+	for _, name := range []string{"Burger", "Fries"} {
+		if _, err := app.AddItem(name, 499); err != nil {
+			t.Fatalf("Failed to add item: %v", err)
+		}
+	}
+	if _, err := app.CreateOrder("Alice", []uint64{0, 1}); err != nil {
+		t.Fatalf("Failed to create order: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "backup.bbak")
+	if _, err := app.BackupDatabase(path, "hunter2"); err != nil {
+		t.Fatalf("BackupDatabase failed: %v", err)
+	}
+
+	if _, err := app.UpdateItem(0, "Cheeseburger", 999); err != nil {
+		t.Fatalf("Failed to update item: %v", err)
+	}
+	if err := app.DeleteItem(1); err != nil {
+		t.Fatalf("Failed to delete item: %v", err)
+	}
+	if _, err := app.AddItem("Salad", 699); err != nil {
+		t.Fatalf("Failed to add item: %v", err)
+	}
+	if err := app.DeleteOrder(0); err != nil {
+		t.Fatalf("Failed to delete order: %v", err)
+	}
+
+	// A wrong passphrase fails before anything is replaced
+	if _, err := app.RestoreDatabase(path, "wrong"); err == nil {
+		t.Fatal("Expected a restore with the wrong passphrase to fail")
+	}
+	if item, err := app.GetItem(0); err != nil || item["name"] != "Cheeseburger" {
+		t.Errorf("Expected the current data left in place, got %v (err %v)", item, err)
+	}
+
+	app.waitForCompaction()
+	if _, err := app.RestoreDatabase(path, "hunter2"); err != nil {
+		t.Fatalf("RestoreDatabase failed: %v", err)
+	}
+	items, err := app.GetAllItems(ListOptions{ActiveOnly: true})
+	if err != nil {
+		t.Fatalf("Failed to list items: %v", err)
+	}
+	if len(items) != 2 || items[0]["name"] != "Burger" || items[0]["priceInCents"] != uint64(499) || items[1]["name"] != "Fries" {
+		t.Errorf("Expected Burger and Fries as backed up, got %v", items)
+	}
+
+	// The order name is decrypted with the restored keys
+	order, err := app.orderDAO.Read(0)
+	if err != nil {
+		t.Fatalf("Failed to read restored order: %v", err)
+	}
+	if order.OwnerOrName != "Alice" || len(order.ItemIDs) != 2 {
+		t.Errorf("Expected Alice's order with both items, got %+v", order)
+	}
+	if id, err := app.AddItem("Soda", 199); err != nil || id != 2 {
+		t.Errorf("Expected IDs to continue from the backup, got %d (err %v)", id, err)
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"BinaryCRUD/backend/dao"
+	"BinaryCRUD/backend/oplog"
+	"BinaryCRUD/backend/utils"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ItemFilter selects the items DeleteItemsWhere deletes; an item must match every condition that is set
+type ItemFilter struct {
+	PriceEquals   *uint64 `json:"priceEquals,omitempty"`   // price in cents
+	NamePrefix    string  `json:"namePrefix,omitempty"`    // compared case-insensitively, with spaces collapsed
+	CreatedBefore string  `json:"createdBefore,omitempty"` // RFC 3339, compared with the creation in the audit trail
+}
+
+// empty reports whether the filter sets no condition
+func (f ItemFilter) empty() bool {
+	return f.PriceEquals == nil && strings.TrimSpace(f.NamePrefix) == "" && f.CreatedBefore == ""
+}
+
+// itemMatcher returns the test of a filter against a stored item
+// Items have no creation time of their own, so CreatedBefore uses their latest create in the audit trail;
+// items without one never match it
+func (a *App) itemMatcher(filter ItemFilter) (func(item *utils.Item) bool, error) {
+	if filter.empty() {
+		return nil, utils.WithCode(utils.CodeValidation, fmt.Errorf("filter must set at least one condition"), nil)
+	}
+
+	var createdAt map[uint64]time.Time
+	var cutoff time.Time
+	if filter.CreatedBefore != "" {
+		var err error
+		cutoff, err = time.Parse(time.RFC3339, filter.CreatedBefore)
+		if err != nil {
+			return nil, utils.WithCode(utils.CodeValidation, fmt.Errorf("invalid createdBefore %q: %w", filter.CreatedBefore, err), map[string]any{"createdBefore": filter.CreatedBefore})
+		}
+		entries, err := a.auditDAO.Query("item", nil)
+		if err != nil {
+			return nil, err
+		}
+		createdAt = make(map[uint64]time.Time)
+		for _, entry := range entries {
+			if entry.Action == dao.AuditCreate {
+				createdAt[entry.EntityID] = entry.Timestamp
+			}
+		}
+	}
+	prefix := utils.NormalizeName(filter.NamePrefix)
+
+	return func(item *utils.Item) bool {
+		if filter.PriceEquals != nil && item.Price != *filter.PriceEquals {
+			return false
+		}
+		if prefix != "" && !strings.HasPrefix(utils.NormalizeName(item.Name), prefix) {
+			return false
+		}
+		if createdAt != nil {
+			created, ok := createdAt[item.ID]
+			if !ok || !created.Before(cutoff) {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+// DeleteItemsWhere deletes every active item matching filter, tombstoning them in a single pass over the
+// items file and saving the index once
+// Each deletion is logged and audited like DeleteItem, so Undo can write the items back one by one
+// Returns the IDs of the deleted items
+func (a *App) DeleteItemsWhere(filter ItemFilter) (_ []uint64, err error) {
+	start := time.Now()
+	defer a.track("DeleteItemsWhere", start, &err)
+	if err := a.checkWritable(); err != nil {
+		return nil, err
+	}
+
+	match, err := a.itemMatcher(filter)
+	if err != nil {
+		return nil, err
+	}
+	deleted, err := a.itemDAO.DeleteWhere(match)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]uint64, 0, len(deleted))
+	for _, item := range deleted {
+		ids = append(ids, item.ID)
+		a.recordOp(oplog.Operation{Type: oplog.OpDeleteItem, ID: item.ID, Name: item.Name, Price: item.Price, Extensions: item.Extensions})
+		a.recordAudit(dao.AuditDelete, "item", item.ID, itemSummary(item.Name, item.Price), "")
+	}
+
+	a.logger.Info(fmt.Sprintf("Deleted %d item(s) matching filter in %s", len(ids), time.Since(start)))
+	if len(ids) > 0 {
+		a.toast.Success(fmt.Sprintf("Deleted %d item(s)", len(ids)))
+		a.checkCompactionPolicy()
+	}
+	return ids, nil
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"BinaryCRUD/backend/utils"
+	"testing"
+	"time"
+)
+
+func TestDeleteItemsWhere(t *testing.T) {
+	app := newTestApp(t)
+	var ids []uint64
+	for _, item := range []struct {
+		name  string
+		price uint64
+	}{{"Old Burger", 0}, {"old fries", 299}, {"Soda", 0}, {"Salad", 599}} {
+		id, err := app.AddItem(item.name, item.price)
+		if err != nil {
+			t.Fatalf("Failed to add item: %v", err)
+		}
+		ids = append(ids, id)
+	}
+
+	if _, err := app.DeleteItemsWhere(ItemFilter{}); utils.ErrorCodeOf(err) != utils.CodeValidation {
+		t.Errorf("Expected an empty filter to be rejected, got %v", err)
+	}
+
+	// Every condition set must match
+	free := uint64(0)
+	deleted, err := app.DeleteItemsWhere(ItemFilter{PriceEquals: &free, NamePrefix: "OLD"})
+	if err != nil {
+		t.Fatalf("DeleteItemsWhere failed: %v", err)
+	}
+	if len(deleted) != 1 || deleted[0] != ids[0] {
+		t.Fatalf("Expected only item %d deleted, got %v", ids[0], deleted)
+	}
+	if _, err := app.GetItem(ids[0]); err == nil {
+		t.Error("Expected the matching item to be deleted")
+	}
+	if _, err := app.GetItem(ids[2]); err != nil {
+		t.Errorf("Expected the item matching only the price to be kept: %v", err)
+	}
+
+	// Items created before the cutoff, by their creation in the audit trail
+	deleted, err = app.DeleteItemsWhere(ItemFilter{CreatedBefore: time.Now().Add(time.Minute).Format(time.RFC3339)})
+	if err != nil {
+		t.Fatalf("DeleteItemsWhere failed: %v", err)
+	}
+	if len(deleted) != 3 {
+		t.Errorf("Expected the 3 remaining items deleted, got %v", deleted)
+	}
+
+	// Each deletion can be undone
+	if _, err := app.Undo(); err != nil {
+		t.Fatalf("Undo failed: %v", err)
+	}
+	if item, err := app.GetItem(deleted[2]); err != nil || item["name"] != "Salad" {
+		t.Errorf("Expected Undo to restore the last deleted item, got %v (err %v)", item, err)
+	}
+}
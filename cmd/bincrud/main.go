@@ -0,0 +1,200 @@
+// Command bincrud reads and maintains a BinaryCRUD data directory without the GUI
+//
+// Usage:
+//
+//	bincrud [--data DIR] items list
+//	bincrud [--data DIR] items get ID
+//	bincrud [--data DIR] orders list|get ID
+//	bincrud [--data DIR] promotions list|get ID
+//	bincrud [--data DIR] compact
+//	bincrud [--data DIR] verify
+//	bincrud [--data DIR] export --json out.json
+package main
+
+import (
+	"BinaryCRUD/backend/dao"
+	"BinaryCRUD/backend/utils"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+)
+
+const usage = `usage: bincrud [--data DIR] <command>
+
+commands:
+  items list | items get ID
+  orders list | orders get ID
+  promotions list | promotions get ID
+  compact                  remove deleted records and rebuild the indexes
+  verify                   check headers, records and signatures of every data file
+  export --json FILE       write all records as a JSON document the app can import
+`
+
+func main() {
+	flags := flag.NewFlagSet("bincrud", flag.ExitOnError)
+	dataDir := flags.String("data", utils.DataDirFromEnv(), "data directory")
+	flags.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	flags.Parse(os.Args[1:])
+
+	utils.SetDataDir(*dataDir)
+	config, err := utils.LoadConfig(utils.ConfigPath())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: using default config: %v\n", err)
+	}
+	utils.ApplyConfig(config)
+
+	if err := run(flags.Args()); err != nil {
+		fmt.Fprintf(os.Stderr, "bincrud: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// run dispatches a command
+func run(args []string) error {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, usage)
+		return fmt.Errorf("missing command")
+	}
+
+	switch args[0] {
+	case "items", "orders", "promotions":
+		return runEntity(args[0], args[1:])
+	case "compact":
+		return runCompact()
+	case "verify":
+		return runVerify()
+	case "export":
+		return runExport(args[1:])
+	default:
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+}
+
+// runEntity runs the list and get subcommands of an entity
+func runEntity(entity string, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: bincrud %s list|get ID", entity)
+	}
+
+	switch args[0] {
+	case "list":
+		switch entity {
+		case "items":
+			return listItems()
+		case "orders":
+			return listCollections(dao.NewOrderDAO(utils.BinPath("orders.bin")).CollectionDAO, "CUSTOMER")
+		default:
+			return listCollections(dao.NewPromotionDAO(utils.BinPath("promotions.bin")).CollectionDAO, "NAME")
+		}
+	case "get":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: bincrud %s get ID", entity)
+		}
+		id, err := strconv.ParseUint(args[1], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid ID %q", args[1])
+		}
+		switch entity {
+		case "items":
+			return getItem(id)
+		case "orders":
+			return getCollection(dao.NewOrderDAO(utils.BinPath("orders.bin")).CollectionDAO, id, true)
+		default:
+			return getCollection(dao.NewPromotionDAO(utils.BinPath("promotions.bin")).CollectionDAO, id, false)
+		}
+	default:
+		return fmt.Errorf("unknown %s command %q", entity, args[0])
+	}
+}
+
+// runCompact compacts the data files while holding the data directory lock
+// Interrupting it before the files are replaced leaves them untouched
+func runCompact() error {
+	lock, err := utils.LockDataDir(utils.DataDir)
+	if err != nil {
+		return err
+	}
+	defer lock.Release()
+
+	rates, err := utils.LoadCurrencyRates(utils.SeedPath("currencies.json"))
+	if err != nil {
+		rates = utils.DefaultCurrencyRates()
+	}
+	basePrice := func(item *utils.Item) (uint64, error) {
+		code, _ := utils.DecodeCurrency(item.Extensions)
+		return rates.ToBase(item.Price, code)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	result, err := utils.CompactAll(ctx,
+		utils.BinPath("items.bin"),
+		utils.BinPath("orders.bin"),
+		utils.BinPath("promotions.bin"),
+		utils.BinPath("order_promotions.bin"),
+		basePrice,
+	)
+	if err != nil {
+		return fmt.Errorf("compaction failed: %w", err)
+	}
+	if utils.SigningEnabled {
+		if _, err := utils.SignBinFiles(); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("Removed %d items, %d orders, %d promotions and %d order-promotion links\n",
+		result.ItemsRemoved, result.OrdersRemoved, result.PromotionsRemoved, result.OrderPromotionsRemoved)
+	fmt.Printf("Cleaned item references in %d orders and %d promotions\n", result.OrdersAffected, result.PromotionsAffected)
+	return nil
+}
+
+// runVerify checks every data file and fails when any has problems
+func runVerify() error {
+	checks, err := utils.VerifyBinFiles()
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, check := range checks {
+		status := "ok"
+		if !check.OK() {
+			status = "FAILED"
+			failed++
+		}
+		fmt.Printf("%-24s %-6s %d records, %d deleted", check.File, status, check.Records, check.Tombstones)
+		if check.IDGaps > 0 {
+			fmt.Printf(", %d unused IDs", check.IDGaps)
+		}
+		if check.Signature != "" {
+			fmt.Printf(", signature %s", check.Signature)
+		}
+		fmt.Println()
+		for _, problem := range check.Problems {
+			fmt.Printf("  - %s\n", problem)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d files failed verification", failed, len(checks))
+	}
+	fmt.Printf("%d files verified\n", len(checks))
+	return nil
+}
+
+// runExport parses the export flags and writes the export document
+func runExport(args []string) error {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	path := flags.String("json", "", "output file")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *path == "" {
+		return fmt.Errorf("usage: bincrud export --json FILE")
+	}
+	return exportJSON(*path)
+}
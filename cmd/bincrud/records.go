@@ -0,0 +1,251 @@
+package main
+
+import (
+	"BinaryCRUD/backend/dao"
+	"BinaryCRUD/backend/utils"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// exportFormatVersion matches the version of the document written by the app's ExportAll
+const exportFormatVersion = 1
+
+// exportedItem, exportedPromotion, exportedOrder and exportedOrderPromotion mirror the
+// sections of the app's export document, so a CLI export can be imported by the app
+type exportedItem struct {
+	ID           uint64  `json:"id"`
+	Name         string  `json:"name"`
+	PriceInCents uint64  `json:"priceInCents"`
+	Stock        *uint64 `json:"stock,omitempty"`
+	Currency     string  `json:"currency,omitempty"`
+}
+
+type exportedPromotion struct {
+	ID            uint64   `json:"id"`
+	TotalPrice    uint64   `json:"totalPrice"`
+	Name          string   `json:"name"`
+	ItemIDs       []uint64 `json:"itemIDs"`
+	DiscountType  string   `json:"discountType,omitempty"`
+	DiscountValue uint64   `json:"discountValue,omitempty"`
+}
+
+type exportedOrder struct {
+	ID         uint64   `json:"id"`
+	TotalPrice uint64   `json:"totalPrice"`
+	Status     string   `json:"status,omitempty"`
+	CreatedAt  string   `json:"createdAt,omitempty"`
+	Owner      string   `json:"owner"`
+	ItemIDs    []uint64 `json:"itemIDs"`
+}
+
+type exportedOrderPromotion struct {
+	OrderID     uint64 `json:"orderID"`
+	PromotionID uint64 `json:"promotionID"`
+}
+
+// exportDocument is the JSON document written by the export command
+type exportDocument struct {
+	Version         int                      `json:"version"`
+	ExportedAt      string                   `json:"exportedAt"`
+	Items           []exportedItem           `json:"items"`
+	Promotions      []exportedPromotion      `json:"promotions"`
+	Orders          []exportedOrder          `json:"orders"`
+	OrderPromotions []exportedOrderPromotion `json:"orderPromotions"`
+}
+
+// newTable returns a writer aligning tab-separated columns on stdout
+func newTable() *tabwriter.Writer {
+	return tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+}
+
+// formatIDs writes a list of IDs as 1,2,3
+func formatIDs(ids []uint64) string {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = fmt.Sprint(id)
+	}
+	return strings.Join(parts, ",")
+}
+
+// listItems prints every active item
+func listItems() error {
+	items, err := dao.NewItemDAO(utils.BinPath("items.bin")).GetAll()
+	if err != nil {
+		return err
+	}
+
+	table := newTable()
+	fmt.Fprintln(table, "ID\tNAME\tPRICE\tSTOCK")
+	for _, item := range items {
+		if item.IsDeleted {
+			continue
+		}
+		fmt.Fprintf(table, "%d\t%s\t%s\t%s\n", item.ID, item.Name, itemPrice(&item), itemStock(&item))
+	}
+	return table.Flush()
+}
+
+// getItem prints one item
+func getItem(id uint64) error {
+	item, err := dao.NewItemDAO(utils.BinPath("items.bin")).ReadItem(id)
+	if err != nil {
+		return err
+	}
+
+	table := newTable()
+	fmt.Fprintf(table, "ID\t%d\n", item.ID)
+	fmt.Fprintf(table, "Name\t%s\n", item.Name)
+	fmt.Fprintf(table, "Price\t%s\n", itemPrice(item))
+	fmt.Fprintf(table, "Stock\t%s\n", itemStock(item))
+	return table.Flush()
+}
+
+// itemPrice formats the price of an item in its currency
+func itemPrice(item *dao.Item) string {
+	code, _ := utils.DecodeCurrency(item.Extensions)
+	if code == "" {
+		code = utils.BaseCurrency
+	}
+	return utils.FormatPrice(item.PriceInCents, code)
+}
+
+// itemStock formats the stock of an item, "-" when it is not tracked
+func itemStock(item *dao.Item) string {
+	quantity, tracked, err := utils.DecodeStock(item.Extensions)
+	if err != nil || !tracked {
+		return "-"
+	}
+	return fmt.Sprint(quantity)
+}
+
+// listCollections prints every active order or promotion
+func listCollections(collectionDAO *dao.CollectionDAO, nameColumn string) error {
+	collections, err := collectionDAO.GetAll()
+	if err != nil {
+		return err
+	}
+
+	table := newTable()
+	fmt.Fprintf(table, "ID\t%s\tTOTAL\tITEMS\n", nameColumn)
+	for _, collection := range collections {
+		if collection.IsDeleted {
+			continue
+		}
+		fmt.Fprintf(table, "%d\t%s\t%s\t%s\n", collection.ID, collection.OwnerOrName,
+			utils.FormatPrice(collection.TotalPrice, utils.BaseCurrency), formatIDs(collection.ItemIDs))
+	}
+	return table.Flush()
+}
+
+// getCollection prints one order or promotion, with the status of orders
+func getCollection(collectionDAO *dao.CollectionDAO, id uint64, isOrder bool) error {
+	collection, err := collectionDAO.Read(id)
+	if err != nil {
+		return err
+	}
+
+	table := newTable()
+	fmt.Fprintf(table, "ID\t%d\n", collection.ID)
+	fmt.Fprintf(table, "Name\t%s\n", collection.OwnerOrName)
+	fmt.Fprintf(table, "Total\t%s\n", utils.FormatPrice(collection.TotalPrice, utils.BaseCurrency))
+	fmt.Fprintf(table, "Items\t%s\n", formatIDs(collection.ItemIDs))
+	if isOrder {
+		status, err := utils.DecodeOrderStatus(collection.Extensions)
+		if err != nil {
+			status = utils.OrderPending
+		}
+		fmt.Fprintf(table, "Status\t%s\n", utils.OrderStatusName(status))
+	}
+	if discount, err := utils.DecodeDiscount(collection.Extensions); err == nil && discount.Type != utils.DiscountNone {
+		fmt.Fprintf(table, "Discount\t%s %d\n", utils.DiscountTypeName(discount.Type), discount.Value)
+	}
+	return table.Flush()
+}
+
+// exportJSON writes every active record to path in the app's export format
+func exportJSON(path string) error {
+	doc := exportDocument{
+		Version:         exportFormatVersion,
+		ExportedAt:      time.Now().UTC().Format(time.RFC3339),
+		Items:           []exportedItem{},
+		Promotions:      []exportedPromotion{},
+		Orders:          []exportedOrder{},
+		OrderPromotions: []exportedOrderPromotion{},
+	}
+
+	items, err := dao.NewItemDAO(utils.BinPath("items.bin")).GetAll()
+	if err != nil {
+		return fmt.Errorf("failed to read items: %w", err)
+	}
+	for _, item := range items {
+		if item.IsDeleted {
+			continue
+		}
+		exported := exportedItem{ID: item.ID, Name: item.Name, PriceInCents: item.PriceInCents}
+		exported.Currency, _ = utils.DecodeCurrency(item.Extensions)
+		if quantity, tracked, err := utils.DecodeStock(item.Extensions); err == nil && tracked {
+			exported.Stock = &quantity
+		}
+		doc.Items = append(doc.Items, exported)
+	}
+
+	promotions, err := dao.NewPromotionDAO(utils.BinPath("promotions.bin")).GetAll()
+	if err != nil {
+		return fmt.Errorf("failed to read promotions: %w", err)
+	}
+	for _, promotion := range promotions {
+		if promotion.IsDeleted {
+			continue
+		}
+		exported := exportedPromotion{ID: promotion.ID, TotalPrice: promotion.TotalPrice, Name: promotion.OwnerOrName, ItemIDs: promotion.ItemIDs}
+		if discount, err := utils.DecodeDiscount(promotion.Extensions); err == nil && discount.Type != utils.DiscountNone {
+			exported.DiscountType = utils.DiscountTypeName(discount.Type)
+			exported.DiscountValue = discount.Value
+		}
+		doc.Promotions = append(doc.Promotions, exported)
+	}
+
+	orders, err := dao.NewOrderDAO(utils.BinPath("orders.bin")).GetAll()
+	if err != nil {
+		return fmt.Errorf("failed to read orders: %w", err)
+	}
+	for _, order := range orders {
+		if order.IsDeleted {
+			continue
+		}
+		exported := exportedOrder{ID: order.ID, TotalPrice: order.TotalPrice, Owner: order.OwnerOrName, ItemIDs: order.ItemIDs}
+		status, err := utils.DecodeOrderStatus(order.Extensions)
+		if err != nil {
+			status = utils.OrderPending
+		}
+		exported.Status = utils.OrderStatusName(status)
+		if createdAt, ok, err := utils.DecodeTimestamp(order.Extensions, utils.ExtCreatedAt); err == nil && ok {
+			exported.CreatedAt = createdAt.Format(time.RFC3339Nano)
+		}
+		doc.Orders = append(doc.Orders, exported)
+	}
+
+	orderPromotions, err := dao.NewOrderPromotionDAO(utils.BinPath("order_promotions.bin")).GetAll()
+	if err != nil {
+		return fmt.Errorf("failed to read order-promotion relationships: %w", err)
+	}
+	for _, op := range orderPromotions {
+		doc.OrderPromotions = append(doc.OrderPromotions, exportedOrderPromotion{OrderID: op.OrderID, PromotionID: op.PromotionID})
+	}
+
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode export: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write export file: %w", err)
+	}
+
+	fmt.Printf("Exported %d items, %d promotions, %d orders and %d order-promotion links to %s\n",
+		len(doc.Items), len(doc.Promotions), len(doc.Orders), len(doc.OrderPromotions), path)
+	return nil
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"BinaryCRUD/backend/utils"
+	"fmt"
+	"time"
+)
+
+// compactedFiles lists the data files covered by compaction
+var compactedFiles = []string{"items.bin", "orders.bin", "promotions.bin", "order_promotions.bin"}
+
+// checkCompactionPolicy starts a background compaction when a file exceeds the compaction policy
+// Called after deletes; does nothing when auto-compaction is disabled, a compaction is running
+// or the application is shutting down
+func (a *App) checkCompactionPolicy() {
+	config := a.currentConfig()
+	if !config.AutoCompact || !a.canCompact() {
+		return
+	}
+
+	for _, name := range compactedFiles {
+		stats, err := utils.ReadFragmentation(utils.BinPath(name))
+		if err != nil || !config.Compaction.ShouldCompact(stats) {
+			continue
+		}
+
+		a.logger.Info(fmt.Sprintf("%s is %.0f%% tombstoned (%d of %d records), compacting automatically",
+			name, stats.Ratio()*100, stats.TombstoneCount, stats.EntitiesCount))
+		if err := a.StartBackgroundCompaction(); err != nil {
+			a.logger.Warn(fmt.Sprintf("Automatic compaction not started: %v", err))
+		}
+		return
+	}
+}
+
+// GetCompactionPolicy returns the automatic compaction policy
+func (a *App) GetCompactionPolicy() map[string]any {
+	defer a.track("GetCompactionPolicy", time.Now(), nil)
+	config := a.currentConfig()
+	return map[string]any{
+		"enabled":          config.AutoCompact,
+		"tombstonePercent": config.Compaction.TombstoneRatio * 100,
+		"maxFileBytes":     config.Compaction.MaxFileBytes,
+		"minTombstones":    config.Compaction.MinTombstones,
+	}
+}
+
+// SetCompactionPolicy configures automatic compaction after deletes
+// A file is compacted when more than tombstonePercent of its records are deleted, or when it is larger
+// than maxFileBytes and has deleted records; a zero threshold disables that rule
+func (a *App) SetCompactionPolicy(enabled bool, tombstonePercent float64, maxFileBytes int64, minTombstones int) (err error) {
+	defer a.track("SetCompactionPolicy", time.Now(), &err)
+	policy := utils.CompactionPolicy{
+		TombstoneRatio: tombstonePercent / 100,
+		MaxFileBytes:   maxFileBytes,
+		MinTombstones:  minTombstones,
+	}
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+
+	config := a.currentConfig()
+	config.AutoCompact = enabled
+	config.Compaction = policy
+	if _, err := a.UpdateConfig(config); err != nil {
+		return err
+	}
+	a.logger.Info(fmt.Sprintf("Compaction policy: enabled=%t, tombstones > %.0f%%, size > %d bytes, at least %d tombstones",
+		enabled, tombstonePercent, maxFileBytes, minTombstones))
+	return nil
+}
+
+// GetCompactionStats returns the fragmentation of every data file and whether the policy would compact it
+func (a *App) GetCompactionStats() (_ []map[string]any, err error) {
+	defer a.track("GetCompactionStats", time.Now(), &err)
+	policy := a.currentConfig().Compaction
+	result := make([]map[string]any, 0, len(compactedFiles))
+	for _, name := range compactedFiles {
+		stats, err := utils.ReadFragmentation(utils.BinPath(name))
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, map[string]any{
+			"file":           name,
+			"sizeBytes":      stats.SizeBytes,
+			"entitiesCount":  stats.EntitiesCount,
+			"tombstoneCount": stats.TombstoneCount,
+			"fragmentation":  stats.Ratio() * 100,
+			"shouldCompact":  policy.ShouldCompact(stats),
+		})
+	}
+	return result, nil
+}
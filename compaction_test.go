@@ -0,0 +1,244 @@
+package main
+
+import (
+	"BinaryCRUD/backend/utils"
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// populateForCompaction adds items and an order, deleting every other item with automatic compaction off
+// Returns the IDs of the items left active
+func populateForCompaction(t *testing.T, app *App) []uint64 {
+	t.Helper()
+
+	// The deletes would otherwise start an automatic compaction the tests do not control
+	config := app.GetConfig()
+	config.AutoCompact = false
+	if _, err := app.UpdateConfig(config); err != nil {
+		t.Fatalf("Failed to disable automatic compaction: %v", err)
+	}
+
+	var active []uint64
+	for i := 0; i < 10; i++ {
+		id, err := app.AddItem("Item "+string(rune('A'+i)), uint64(100+i))
+		if err != nil {
+			t.Fatalf("Failed to add item: %v", err)
+		}
+		if i%2 == 0 {
+			if err := app.DeleteItem(id); err != nil {
+				t.Fatalf("Failed to delete item: %v", err)
+			}
+			continue
+		}
+		active = append(active, id)
+	}
+	if _, err := app.CreateOrder("Customer", active); err != nil {
+		t.Fatalf("Failed to create order: %v", err)
+	}
+	return active
+}
+
+// compactWithDeletes writes four items, deletes two of them and compacts, so items.bin moves to its next generation
+func compactWithDeletes(t *testing.T, app *App) {
+	t.Helper()
+	config := app.GetConfig()
+	config.AutoCompact = false
+	if _, err := app.UpdateConfig(config); err != nil {
+		t.Fatalf("Failed to disable automatic compaction: %v", err)
+	}
+	var ids []uint64
+	for _, name := range []string{"Burger", "Fries", "Soda", "Salad"} {
+		id, err := app.AddItem(name, 500)
+		if err != nil {
+			t.Fatalf("Failed to add item: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	for _, id := range ids[:2] {
+		if err := app.DeleteItem(id); err != nil {
+			t.Fatalf("Failed to delete item: %v", err)
+		}
+	}
+	if _, err := app.Compact(); err != nil {
+		t.Fatalf("Compact failed: %v", err)
+	}
+	if utils.BinPath("items.bin") != filepath.Join(utils.BinDir, "items.gen1.bin") {
+		t.Fatalf("Expected the compaction to write generation 1, got %s", utils.BinPath("items.bin"))
+	}
+}
+
+func TestCompactRejectedWhileAnotherCompactionRuns(t *testing.T) {
+	app := newTestApp(t)
+	populateForCompaction(t, app)
+
+	if err := app.beginCompaction(); err != nil {
+		t.Fatalf("Failed to mark a compaction as running: %v", err)
+	}
+	_, err := app.Compact()
+	if code := utils.ErrorCodeOf(err); code != utils.CodeConflict {
+		t.Fatalf("Expected a Conflict error, got %v", err)
+	}
+	if err := app.StartBackgroundCompaction(); utils.ErrorCodeOf(err) != utils.CodeConflict {
+		t.Fatalf("Expected the background compaction to be rejected, got %v", err)
+	}
+	app.endCompaction()
+
+	if _, err := app.Compact(); err != nil {
+		t.Fatalf("Expected compaction to succeed once the other one finished, got %v", err)
+	}
+	if app.isCompacting() {
+		t.Error("Expected the running flag to be cleared after Compact")
+	}
+}
+
+func TestShutdownWaitsForAutomaticCompaction(t *testing.T) {
+	app := newTestApp(t)
+	if err := app.SetCompactionPolicy(true, 10, 0, 1); err != nil {
+		t.Fatalf("Failed to set compaction policy: %v", err)
+	}
+
+	var ids []uint64
+	for i := 0; i < 10; i++ {
+		id, err := app.AddItem("Item "+string(rune('A'+i)), 100)
+		if err != nil {
+			t.Fatalf("Failed to add item: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	// Every delete past the threshold tries to start a background compaction
+	for _, id := range ids[:6] {
+		if err := app.DeleteItem(id); err != nil {
+			t.Fatalf("Failed to delete item %d: %v", id, err)
+		}
+	}
+
+	app.shutdown(context.Background())
+
+	if app.isCompacting() {
+		t.Fatal("Expected shutdown to wait for the background compaction")
+	}
+	if _, err := os.Stat(utils.CompactionDir); !os.IsNotExist(err) {
+		t.Errorf("Expected the compaction directory to be removed before shutdown returned, got %v", err)
+	}
+	if err := app.StartBackgroundCompaction(); utils.ErrorCodeOf(err) != utils.CodeConflict {
+		t.Errorf("Expected no compaction to start after shutdown, got %v", err)
+	}
+	if _, err := app.Compact(); utils.ErrorCodeOf(err) != utils.CodeConflict {
+		t.Errorf("Expected Compact to be rejected after shutdown, got %v", err)
+	}
+}
+
+func TestConcurrentCompactions(t *testing.T) {
+	app := newTestApp(t)
+	active := populateForCompaction(t, app)
+
+	const runs = 8
+	errs := make([]error, runs)
+	var wg sync.WaitGroup
+	for i := 0; i < runs; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if i%2 == 0 {
+				errs[i] = app.StartBackgroundCompaction()
+				return
+			}
+			_, errs[i] = app.Compact()
+		}(i)
+	}
+	wg.Wait()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for app.isCompacting() {
+		if time.Now().After(deadline) {
+			t.Fatal("Background compaction did not finish")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	started := 0
+	for _, err := range errs {
+		if err == nil {
+			started++
+			continue
+		}
+		if code := utils.ErrorCodeOf(err); code != utils.CodeConflict {
+			t.Errorf("Expected concurrent compactions to be rejected with Conflict, got %v", err)
+		}
+	}
+	if started == 0 {
+		t.Fatal("Expected at least one compaction to run")
+	}
+
+	for _, id := range active {
+		if _, err := app.GetItem(id); err != nil {
+			t.Errorf("Item %d lost after concurrent compactions: %v", id, err)
+		}
+	}
+	orders, err := app.GetAllOrders("", ListOptions{})
+	if err != nil {
+		t.Fatalf("Failed to read orders: %v", err)
+	}
+	if len(orders) != 1 {
+		t.Fatalf("Expected 1 order, got %d", len(orders))
+	}
+}
+
+func TestCompactPreviewMatchesCompact(t *testing.T) {
+	app := newTestApp(t)
+	config := app.GetConfig()
+	config.AutoCompact = false
+	if _, err := app.UpdateConfig(config); err != nil {
+		t.Fatalf("Failed to disable automatic compaction: %v", err)
+	}
+
+	// Deleting last keeps the tombstones, whose slots later writes would reuse
+	var ids []uint64
+	for _, name := range []string{"Burger", "Fries", "Soda", "Salad"} {
+		id, err := app.AddItem(name, 500)
+		if err != nil {
+			t.Fatalf("Failed to add item: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	orderID, err := app.CreateOrder("Customer", ids)
+	if err != nil {
+		t.Fatalf("Failed to create order: %v", err)
+	}
+	for _, id := range ids[:2] {
+		if err := app.DeleteItem(id); err != nil {
+			t.Fatalf("Failed to delete item: %v", err)
+		}
+	}
+
+	before, err := os.ReadFile(utils.BinPath("items.bin"))
+	if err != nil {
+		t.Fatalf("Failed to read items file: %v", err)
+	}
+
+	preview, err := app.CompactPreview()
+	if err != nil {
+		t.Fatalf("CompactPreview failed: %v", err)
+	}
+	if preview.ItemsRemoved != 2 || !reflect.DeepEqual(preview.AffectedOrderIDs, []uint64{orderID}) || preview.ReclaimedBytes <= 0 {
+		t.Errorf("Unexpected preview: %+v", preview)
+	}
+	after, err := os.ReadFile(utils.BinPath("items.bin"))
+	if err != nil || !bytes.Equal(before, after) {
+		t.Fatalf("Expected the preview to leave the items file untouched (err %v)", err)
+	}
+
+	result, err := app.Compact()
+	if err != nil {
+		t.Fatalf("Compact failed: %v", err)
+	}
+	if !reflect.DeepEqual(preview, result) {
+		t.Errorf("Preview %+v does not match compaction %+v", preview, result)
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"BinaryCRUD/backend/compression"
+	"BinaryCRUD/backend/utils"
+	"bytes"
+	"fmt"
+	"os"
+	"time"
+)
+
+// BenchmarkCompression compresses and decompresses a .bin file in memory with every algorithm
+// Reports the compressed size, ratio and wall time of both directions for each, without writing anything
+func (a *App) BenchmarkCompression(filename string) (_ []map[string]any, err error) {
+	defer a.track("BenchmarkCompression", time.Now(), &err)
+	data, err := os.ReadFile(utils.BinPath(filename))
+	if err != nil {
+		return nil, utils.WithCode(utils.CodeNotFound, fmt.Errorf("file not found: %s", filename), map[string]any{"file": filename})
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("%s is empty", filename)
+	}
+	if err := utils.ValidateDecompressedSize(len(data)); err != nil {
+		return nil, fmt.Errorf("file too large to benchmark in memory: %w", err)
+	}
+
+	originalSize := int64(len(data))
+	results := make([]map[string]any, 0, len(compression.Algorithms()))
+	for _, algorithm := range compression.Algorithms() {
+		result := map[string]any{
+			"algorithm":    algorithm,
+			"originalSize": originalSize,
+		}
+		results = append(results, result)
+
+		compressor, err := compression.NewCompressor(algorithm)
+		if err != nil {
+			result["error"] = err.Error()
+			continue
+		}
+
+		start := time.Now()
+		compressed, err := compressor.Compress(data)
+		compressTime := time.Since(start)
+		if err != nil {
+			result["error"] = fmt.Sprintf("compression failed: %v", err)
+			continue
+		}
+
+		start = time.Now()
+		decompressed, err := compressor.Decompress(compressed)
+		decompressTime := time.Since(start)
+		if err != nil {
+			result["error"] = fmt.Sprintf("decompression failed: %v", err)
+			continue
+		}
+
+		compressedSize := int64(len(compressed))
+		result["compressedSize"] = compressedSize
+		result["ratio"] = fmt.Sprintf("%.2f%%", float64(compressedSize)/float64(originalSize)*100)
+		result["spaceSaved"] = fmt.Sprintf("%.2f%%", float64(originalSize-compressedSize)/float64(originalSize)*100)
+		result["compressMs"] = float64(compressTime.Microseconds()) / 1000
+		result["decompressMs"] = float64(decompressTime.Microseconds()) / 1000
+		result["verified"] = bytes.Equal(data, decompressed)
+
+		a.logger.Info(fmt.Sprintf("Benchmark %s with %s: %d -> %d bytes, compress %v, decompress %v",
+			filename, algorithm, originalSize, compressedSize, compressTime, decompressTime))
+	}
+
+	return results, nil
+}
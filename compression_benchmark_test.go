@@ -0,0 +1,58 @@
+package main
+
+import (
+	"BinaryCRUD/backend/compression"
+	"BinaryCRUD/backend/utils"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestBenchmarkCompression(t *testing.T) {
+	app := newTestApp(t)
+	for _, name := range []string{"Burger", "Fries", "Soda", "Salad"} {
+		if _, err := app.AddItem(name, 499); err != nil {
+			t.Fatalf("Failed to add item: %v", err)
+		}
+	}
+	app.waitForCompaction()
+	before, err := os.ReadFile(utils.BinPath("items.bin"))
+	if err != nil {
+		t.Fatalf("Failed to read items: %v", err)
+	}
+
+	results, err := app.BenchmarkCompression("items.bin")
+	if err != nil {
+		t.Fatalf("BenchmarkCompression failed: %v", err)
+	}
+	var algorithms []string
+	for _, result := range results {
+		algorithms = append(algorithms, result["algorithm"].(string))
+		if result["error"] != nil {
+			t.Errorf("Expected %s to succeed, got %v", result["algorithm"], result["error"])
+			continue
+		}
+		if result["verified"] != true || result["originalSize"] != int64(len(before)) {
+			t.Errorf("Expected %s to round trip %d bytes, got %v", result["algorithm"], len(before), result)
+		}
+		if size, ok := result["compressedSize"].(int64); !ok || size <= 0 {
+			t.Errorf("Expected a compressed size for %s, got %v", result["algorithm"], result["compressedSize"])
+		}
+	}
+	if !reflect.DeepEqual(algorithms, compression.Algorithms()) {
+		t.Errorf("Expected every algorithm in order, got %v", algorithms)
+	}
+
+	// Nothing is written, the file and the compressed directory are left as they were
+	after, err := os.ReadFile(utils.BinPath("items.bin"))
+	if err != nil || string(after) != string(before) {
+		t.Errorf("Expected items.bin unchanged (err %v)", err)
+	}
+	if entries, _ := os.ReadDir(utils.CompressedDir); len(entries) != 0 {
+		t.Errorf("Expected no compressed files, got %d", len(entries))
+	}
+
+	if _, err := app.BenchmarkCompression("missing.bin"); utils.ErrorCodeOf(err) != utils.CodeNotFound {
+		t.Errorf("Expected a missing file to be not found, got %v", err)
+	}
+}
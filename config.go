@@ -0,0 +1,74 @@
+package main
+
+import (
+	"BinaryCRUD/backend/utils"
+	"fmt"
+	"time"
+)
+
+// loadConfig reads the config file from the data directory and applies it, falling back to the defaults
+func loadConfig(logger *Logger) utils.Config {
+	config, err := utils.LoadConfig(utils.ConfigPath())
+	if err != nil {
+		logger.Warn(fmt.Sprintf("Using default config: %v", err))
+	}
+	utils.ApplyConfig(config)
+	if err := logger.Configure(config.Logging); err != nil {
+		logger.Warn(fmt.Sprintf("Logging to stderr: %v", err))
+	}
+	return config
+}
+
+// GetConfig returns the tunables currently in effect
+func (a *App) GetConfig() utils.Config {
+	defer a.track("GetConfig", time.Now(), nil)
+	return a.currentConfig()
+}
+
+// currentConfig returns the tunables currently in effect; safe to call while UpdateConfig runs
+func (a *App) currentConfig() utils.Config {
+	a.configMu.RLock()
+	defer a.configMu.RUnlock()
+	return a.config
+}
+
+// waitForIndexes blocks until every DAO has finished loading its index in the background
+func (a *App) waitForIndexes() {
+	a.itemDAO.WaitIndex()
+	a.orderDAO.WaitIndex()
+	a.promotionDAO.WaitIndex()
+	a.orderPromotionDAO.WaitIndex()
+	a.templateDAO.WaitIndex()
+}
+
+// UpdateConfig validates, applies and saves new tunables
+// Index tunables only apply to indexes built afterwards, e.g. by compaction or a rebuild,
+// and group commit to the item DAO opened next, at startup or when the DAOs are reloaded
+func (a *App) UpdateConfig(config utils.Config) (_ utils.Config, err error) {
+	defer a.track("UpdateConfig", time.Now(), &err)
+	if err := config.Validate(); err != nil {
+		return a.currentConfig(), fmt.Errorf("invalid config: %w", err)
+	}
+	if err := a.checkWritable(); err != nil {
+		return a.currentConfig(), err
+	}
+	if err := utils.SaveConfig(utils.ConfigPath(), config); err != nil {
+		return a.currentConfig(), err
+	}
+
+	// Indexes still loading read the tunables ApplyConfig replaces
+	a.waitForIndexes()
+	utils.ApplyConfig(config)
+	if err := a.logger.Configure(config.Logging); err != nil {
+		a.logger.Warn(fmt.Sprintf("Logging to stderr: %v", err))
+	}
+	a.itemDAO.SetCacheSize(config.ItemCacheSize)
+	a.configMu.Lock()
+	a.config = config
+	a.configMu.Unlock()
+	a.closeWebhooks()
+	a.startWebhooks()
+	a.logger.Info(fmt.Sprintf("Config updated: names up to %d characters, up to %d items per collection, prices up to %d cents",
+		config.MaxNameLength, config.MaxItemsPerCollection, config.MaxPrice))
+	return config, nil
+}
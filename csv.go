@@ -0,0 +1,262 @@
+package main
+
+import (
+	"BinaryCRUD/backend/dao"
+	"BinaryCRUD/backend/oplog"
+	"BinaryCRUD/backend/utils"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// itemsCSVHeader is the header row used for item CSV files
+var itemsCSVHeader = []string{"id", "name", "priceInCents", "currency", "stock"}
+
+// ExportItemsCSV writes all non-deleted items to a CSV file with columns id,name,priceInCents,currency,stock
+// The stock column is empty for items that do not track stock
+func (a *App) ExportItemsCSV(path string) (_ map[string]any, err error) {
+	defer a.track("ExportItemsCSV", time.Now(), &err)
+	if path == "" {
+		return nil, fmt.Errorf("export path cannot be empty")
+	}
+
+	items, err := a.itemDAO.GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read items: %w", err)
+	}
+
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create export directory: %w", err)
+		}
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CSV file: %w", err)
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	if err := writer.Write(itemsCSVHeader); err != nil {
+		return nil, fmt.Errorf("failed to write CSV header: %w", err)
+	}
+
+	exported := 0
+	for _, item := range items {
+		if item.IsDeleted {
+			continue
+		}
+		currency := itemCurrency(&item)
+		if currency == "" {
+			currency = a.currencyRates.Base
+		}
+		stock := ""
+		if quantity, tracked := itemStock(&item); tracked {
+			stock = strconv.FormatUint(quantity, 10)
+		}
+		row := []string{
+			strconv.FormatUint(item.ID, 10),
+			item.Name,
+			strconv.FormatUint(item.PriceInCents, 10),
+			currency,
+			stock,
+		}
+		if err := writer.Write(row); err != nil {
+			return nil, fmt.Errorf("failed to write item #%d: %w", item.ID, err)
+		}
+		exported++
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return nil, fmt.Errorf("failed to flush CSV file: %w", err)
+	}
+
+	a.logger.Info(fmt.Sprintf("Exported %d items to %s", exported, path))
+
+	return map[string]any{
+		"path":  path,
+		"items": exported,
+	}, nil
+}
+
+// csvItemRow is a validated row read from an items CSV file
+type csvItemRow struct {
+	line  int
+	entry ItemEntry
+}
+
+// ImportItemsCSV creates items from a CSV file with columns name,priceInCents (id is optional and ignored)
+// The currency and stock columns are optional, an empty currency means the base currency and an empty stock
+// an item that does not track stock.
+// Rows with invalid names or prices are reported as errors, rows whose name matches an
+// existing item or an earlier row are reported as duplicates and skipped.
+// With dryRun, nothing is written and the report lists what would be created.
+func (a *App) ImportItemsCSV(path string, dryRun bool) (_ map[string]any, err error) {
+	defer a.track("ImportItemsCSV", time.Now(), &err)
+	if !dryRun {
+		if err := a.checkWritable(); err != nil {
+			return nil, err
+		}
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open CSV file: %w", err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+
+	// Locate columns by name so the id column and column order are optional
+	nameCol, priceCol, currencyCol, stockCol := -1, -1, -1, -1
+	for i, column := range header {
+		switch strings.ToLower(strings.TrimSpace(column)) {
+		case "name":
+			nameCol = i
+		case "priceincents":
+			priceCol = i
+		case "currency":
+			currencyCol = i
+		case "stock":
+			stockCol = i
+		}
+	}
+	if nameCol < 0 || priceCol < 0 {
+		return nil, fmt.Errorf("CSV header must contain name and priceInCents columns")
+	}
+
+	// Names of earlier rows, normalized the way the item DAO's name index is
+	seen := make(map[string]bool)
+
+	var rows []csvItemRow
+	rowErrors := []string{}
+	duplicates := []string{}
+
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			rowErrors = append(rowErrors, fmt.Sprintf("line %d: %v", line, err))
+			continue
+		}
+		if nameCol >= len(record) || priceCol >= len(record) {
+			rowErrors = append(rowErrors, fmt.Sprintf("line %d: missing columns", line))
+			continue
+		}
+
+		name := strings.TrimSpace(record[nameCol])
+		if err := utils.ValidateEntityName("item", name); err != nil {
+			rowErrors = append(rowErrors, fmt.Sprintf("line %d: invalid name: %v", line, err))
+			continue
+		}
+
+		price, err := strconv.ParseUint(strings.TrimSpace(record[priceCol]), 10, 64)
+		if err != nil {
+			rowErrors = append(rowErrors, fmt.Sprintf("line %d: invalid price %q", line, record[priceCol]))
+			continue
+		}
+		if err := utils.ValidateEntityPrice("item", price); err != nil {
+			rowErrors = append(rowErrors, fmt.Sprintf("line %d: invalid price: %v", line, err))
+			continue
+		}
+
+		entry := ItemEntry{Name: name, PriceInCents: price}
+		if currencyCol >= 0 && currencyCol < len(record) {
+			entry.Currency = strings.ToUpper(strings.TrimSpace(record[currencyCol]))
+			if entry.Currency == a.currencyRates.Base {
+				entry.Currency = ""
+			}
+			if _, err := a.currencyRates.ToBase(price, entry.Currency); err != nil {
+				rowErrors = append(rowErrors, fmt.Sprintf("line %d: invalid currency: %v", line, err))
+				continue
+			}
+		}
+		if stockCol >= 0 && stockCol < len(record) && strings.TrimSpace(record[stockCol]) != "" {
+			stock, err := strconv.ParseUint(strings.TrimSpace(record[stockCol]), 10, 32)
+			if err != nil {
+				rowErrors = append(rowErrors, fmt.Sprintf("line %d: invalid stock %q", line, record[stockCol]))
+				continue
+			}
+			entry.Stock = &stock
+		}
+
+		key := utils.NormalizeName(name)
+		existing, err := a.itemDAO.FindByName(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read items: %w", err)
+		}
+		if seen[key] || len(existing) > 0 {
+			duplicates = append(duplicates, fmt.Sprintf("line %d: %s", line, name))
+			continue
+		}
+		seen[key] = true
+
+		rows = append(rows, csvItemRow{line: line, entry: entry})
+	}
+
+	created := make([]map[string]any, 0, len(rows))
+	for _, row := range rows {
+		item := row.entry
+		entry := map[string]any{
+			"line":         row.line,
+			"name":         item.Name,
+			"priceInCents": item.PriceInCents,
+		}
+		if item.Currency != "" {
+			entry["currency"] = item.Currency
+		}
+		if item.Stock != nil {
+			entry["stock"] = *item.Stock
+		}
+
+		if !dryRun {
+			ext, err := itemEntryExtensions(item)
+			if err != nil {
+				rowErrors = append(rowErrors, fmt.Sprintf("line %d: %v", row.line, err))
+				continue
+			}
+			id, err := a.itemDAO.WriteExtended(nil, item.Name, item.PriceInCents, ext)
+			if err != nil {
+				rowErrors = append(rowErrors, fmt.Sprintf("line %d: failed to create item: %v", row.line, err))
+				continue
+			}
+			entry["id"] = id
+			a.recordOp(oplog.Operation{Type: oplog.OpAddItem, ID: id, Name: item.Name, Price: item.PriceInCents, Extensions: ext})
+			a.recordAudit(dao.AuditCreate, "item", id, "", itemSummary(item.Name, item.PriceInCents))
+		}
+		created = append(created, entry)
+	}
+
+	if dryRun {
+		a.logger.Info(fmt.Sprintf("CSV dry run for %s: %d items would be created, %d duplicates, %d errors",
+			path, len(created), len(duplicates), len(rowErrors)))
+	} else {
+		a.logger.Info(fmt.Sprintf("Imported %d items from %s (%d duplicates skipped, %d errors)",
+			len(created), path, len(duplicates), len(rowErrors)))
+	}
+	for _, dup := range duplicates {
+		a.logger.Warn(fmt.Sprintf("Duplicate item name skipped at %s", dup))
+	}
+
+	return map[string]any{
+		"dryRun":     dryRun,
+		"created":    created,
+		"duplicates": duplicates,
+		"errors":     rowErrors,
+	}, nil
+}
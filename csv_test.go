@@ -0,0 +1,197 @@
+package main
+
+import (
+	"BinaryCRUD/backend/utils"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeCSV writes content to a CSV file in a temporary directory
+func writeCSV(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "items.csv")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write CSV: %v", err)
+	}
+	return path
+}
+
+// withEuroRate lets app price items in EUR
+func withEuroRate(app *App) {
+	app.currencyRates = &utils.CurrencyRates{Base: "USD", Rates: map[string]float64{"EUR": 1.08}}
+}
+
+func TestItemsCSVRoundTrip(t *testing.T) {
+	source := newTestApp(t)
+	withEuroRate(source)
+	var ids []uint64
+	for _, item := range []struct {
+		name  string
+		price uint64
+	}{{"Burger", 1299}, {"Fries, large", 450}, {`Say "cheese"`, 99}} {
+		id, err := source.AddItem(item.name, item.price)
+		if err != nil {
+			t.Fatalf("Failed to add %s: %v", item.name, err)
+		}
+		ids = append(ids, id)
+	}
+
+	if _, err := source.SetItemCurrency(ids[1], "EUR"); err != nil {
+		t.Fatalf("Failed to set currency: %v", err)
+	}
+	item, err := source.itemDAO.ReadItem(ids[2])
+	if err != nil {
+		t.Fatalf("Failed to read item: %v", err)
+	}
+	if err := source.setItemStock(item, 7); err != nil {
+		t.Fatalf("Failed to set stock: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "export", "items.csv")
+	result, err := source.ExportItemsCSV(path)
+	if err != nil {
+		t.Fatalf("Export failed: %v", err)
+	}
+	if result["items"] != 3 {
+		t.Errorf("Expected 3 exported items, got %v", result["items"])
+	}
+
+	target := newTestApp(t)
+	withEuroRate(target)
+	report, err := target.ImportItemsCSV(path, false)
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	if errs := report["errors"].([]string); len(errs) != 0 {
+		t.Errorf("Expected no errors, got %v", errs)
+	}
+
+	items, err := target.itemDAO.GetAll()
+	if err != nil {
+		t.Fatalf("Failed to read imported items: %v", err)
+	}
+	if len(items) != 3 {
+		t.Fatalf("Expected 3 imported items, got %d", len(items))
+	}
+	if items[1].Name != "Fries, large" || items[1].PriceInCents != 450 {
+		t.Errorf("Unexpected item %+v", items[1])
+	}
+	if items[2].Name != `Say "cheese"` {
+		t.Errorf("Expected quotes to survive the round trip, got %q", items[2].Name)
+	}
+
+	if currency := itemCurrency(&items[0]); currency != "" {
+		t.Errorf("Expected item 1 in the base currency, got %q", currency)
+	}
+	if currency := itemCurrency(&items[1]); currency != "EUR" {
+		t.Errorf("Expected item 2 in EUR, got %q", currency)
+	}
+	if _, tracked := itemStock(&items[0]); tracked {
+		t.Error("Expected item 1 not to track stock")
+	}
+	if stock, tracked := itemStock(&items[2]); !tracked || stock != 7 {
+		t.Errorf("Expected item 3 to have 7 in stock, got %d (tracked %v)", stock, tracked)
+	}
+}
+
+func TestImportItemsCSVInvalidCurrencyAndStock(t *testing.T) {
+	app := newTestApp(t)
+	withEuroRate(app)
+	path := writeCSV(t, "name,priceInCents,currency,stock\n"+
+		"Euro Item,100,eur,\n"+
+		"Yen Item,100,JPY,\n"+
+		"Negative Stock,100,,-1\n")
+
+	report, err := app.ImportItemsCSV(path, false)
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	errs := report["errors"].([]string)
+	if len(errs) != 2 || !strings.HasPrefix(errs[0], "line 3:") || !strings.HasPrefix(errs[1], "line 4:") {
+		t.Fatalf("Expected errors on lines 3 and 4, got %v", errs)
+	}
+
+	created := report["created"].([]map[string]any)
+	if len(created) != 1 {
+		t.Fatalf("Expected 1 created item, got %v", created)
+	}
+	item, err := app.itemDAO.ReadItem(created[0]["id"].(uint64))
+	if err != nil {
+		t.Fatalf("Failed to read item: %v", err)
+	}
+	if currency := itemCurrency(item); currency != "EUR" {
+		t.Errorf("Expected the lowercase code to be accepted as EUR, got %q", currency)
+	}
+}
+
+func TestImportItemsCSVDuplicatesUseNameNormalization(t *testing.T) {
+	app := newTestApp(t)
+	if _, err := app.AddItem("Cheese  Burger", 1000); err != nil {
+		t.Fatalf("Failed to add item: %v", err)
+	}
+
+	path := writeCSV(t, "name,priceInCents\n"+
+		"  cheese burger ,1100\n"+ // matches the existing item once whitespace is collapsed
+		"Milk Shake,500\n"+
+		"milk   shake,600\n") // matches the previous row
+
+	report, err := app.ImportItemsCSV(path, false)
+	if err != nil {
+		t.Fatalf("Import failed: %v", err)
+	}
+	duplicates := report["duplicates"].([]string)
+	if len(duplicates) != 2 {
+		t.Fatalf("Expected 2 duplicates, got %v", duplicates)
+	}
+	if !strings.HasPrefix(duplicates[0], "line 2:") || !strings.HasPrefix(duplicates[1], "line 4:") {
+		t.Errorf("Unexpected duplicate lines %v", duplicates)
+	}
+	if created := report["created"].([]map[string]any); len(created) != 1 {
+		t.Errorf("Expected 1 created item, got %v", created)
+	}
+}
+
+func TestImportItemsCSVMalformedRows(t *testing.T) {
+	app := newTestApp(t)
+	path := writeCSV(t, "id,name,priceInCents\n"+
+		"1,Valid,100\n"+
+		"2,,100\n"+ // empty name
+		"3,Bad Price,abc\n"+
+		"4,Too Expensive,999999999999\n"+
+		"5\n") // missing columns
+
+	report, err := app.ImportItemsCSV(path, true)
+	if err != nil {
+		t.Fatalf("Dry run failed: %v", err)
+	}
+	errs := report["errors"].([]string)
+	if len(errs) != 4 {
+		t.Fatalf("Expected 4 row errors, got %v", errs)
+	}
+	for i, line := range []string{"line 3:", "line 4:", "line 5:", "line 6:"} {
+		if !strings.HasPrefix(errs[i], line) {
+			t.Errorf("Expected error %d to start with %q, got %q", i, line, errs[i])
+		}
+	}
+	if created := report["created"].([]map[string]any); len(created) != 1 {
+		t.Errorf("Expected 1 item in the dry run report, got %v", created)
+	}
+
+	items, err := app.itemDAO.GetAll()
+	if err != nil {
+		t.Fatalf("Failed to read items: %v", err)
+	}
+	if len(items) != 0 {
+		t.Errorf("Expected the dry run to write nothing, got %d items", len(items))
+	}
+}
+
+func TestImportItemsCSVMissingColumns(t *testing.T) {
+	app := newTestApp(t)
+	path := writeCSV(t, "id,title\n1,Burger\n")
+	if _, err := app.ImportItemsCSV(path, false); err == nil {
+		t.Error("Expected an error for a header without name and priceInCents")
+	}
+}
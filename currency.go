@@ -0,0 +1,163 @@
+package main
+
+import (
+	"BinaryCRUD/backend/dao"
+	"BinaryCRUD/backend/money"
+	"BinaryCRUD/backend/oplog"
+	"BinaryCRUD/backend/utils"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// loadCurrencyRates reads the conversion table from the seed directory, falling back to the base currency only
+func loadCurrencyRates(logger *Logger) *utils.CurrencyRates {
+	rates, err := utils.LoadCurrencyRates(utils.SeedPath("currencies.json"))
+	if err != nil {
+		logger.Warn(fmt.Sprintf("Using %s only: %v", utils.BaseCurrency, err))
+		return utils.DefaultCurrencyRates()
+	}
+	return rates
+}
+
+// itemEntryExtensions returns the record extensions for the optional stock, currency and external ID of a seed item
+func itemEntryExtensions(entry ItemEntry) (map[byte][]byte, error) {
+	ext, err := stockExtensions(entry.Stock)
+	if err != nil {
+		return nil, err
+	}
+	if entry.Currency != "" {
+		data, err := utils.EncodeCurrency(entry.Currency)
+		if err != nil {
+			return nil, err
+		}
+		ext = utils.WithExtension(ext, utils.ExtCurrency, data)
+	}
+	if entry.ExternalID != "" {
+		data, err := utils.EncodeExternalID(entry.ExternalID)
+		if err != nil {
+			return nil, err
+		}
+		ext = utils.WithExtension(ext, utils.ExtExternalID, data)
+	}
+	return ext, nil
+}
+
+// itemCurrency returns the currency of an item price, empty for the base currency
+func itemCurrency(item *dao.Item) string {
+	code, err := utils.DecodeCurrency(item.Extensions)
+	if err != nil {
+		return ""
+	}
+	return code
+}
+
+// itemBasePrice returns the price of a stored item record converted to the base currency
+func (a *App) itemBasePrice(item *utils.Item) (uint64, error) {
+	code, _ := utils.DecodeCurrency(item.Extensions)
+	return a.currencyRates.ToBase(item.Price, code)
+}
+
+// addCurrencyFields adds the currency and formatted price of an item to an API response map
+func (a *App) addCurrencyFields(result map[string]any, item *dao.Item) map[string]any {
+	code := itemCurrency(item)
+	if code == "" {
+		code = a.currencyRates.Base
+	}
+	result["currency"] = code
+	result["formattedPrice"] = utils.FormatPrice(item.PriceInCents, code)
+	return result
+}
+
+// formatPrice writes an amount in the base currency
+func (a *App) formatPrice(cents uint64) string {
+	return utils.FormatPrice(cents, a.currencyRates.Base)
+}
+
+// FormatPrice writes an amount in cents of the base currency with the conventions of a locale such as
+// "en-US", "pt-BR" or "de-DE", so the frontend doesn't format prices on its own; an empty locale means en-US
+func (a *App) FormatPrice(cents uint64, locale string) (_ string, err error) {
+	defer a.track("FormatPrice", time.Now(), &err)
+	conventions, err := money.LookupLocale(locale)
+	if err != nil {
+		return "", utils.WithCode(utils.CodeValidation, err, nil)
+	}
+	return money.Format(cents, a.currencyRates.Base, conventions), nil
+}
+
+// ParsePrice reads a price typed in any known locale, such as "$1,234.56", "1.234,56 €" or "12.5", into cents
+// Fractions of a cent are rounded half to even
+func (a *App) ParsePrice(text string) (_ uint64, err error) {
+	defer a.track("ParsePrice", time.Now(), &err)
+	cents, err := money.Parse(text)
+	if err != nil {
+		return 0, utils.WithCode(utils.CodeValidation, err, nil)
+	}
+	if err := utils.ValidatePrice(cents); err != nil {
+		return 0, err
+	}
+	return cents, nil
+}
+
+// SetItemCurrency sets the currency an item's price is expressed in, an empty code means the base currency
+// Existing order totals are not recalculated
+func (a *App) SetItemCurrency(itemID uint64, code string) (_ map[string]any, err error) {
+	defer a.track("SetItemCurrency", time.Now(), &err)
+	if err := a.checkWritable(); err != nil {
+		return nil, err
+	}
+
+	item, err := a.itemDAO.ReadItem(itemID)
+	if err != nil {
+		return nil, err
+	}
+
+	var data []byte
+	if code != "" && code != a.currencyRates.Base {
+		if data, err = utils.EncodeCurrency(code); err != nil {
+			return nil, err
+		}
+		if _, err := a.currencyRates.ToBase(item.PriceInCents, code); err != nil {
+			return nil, err
+		}
+	}
+	ext := utils.WithExtension(item.Extensions, utils.ExtCurrency, data)
+
+	if err := a.itemDAO.UpdateExtensions(itemID, ext); err != nil {
+		return nil, fmt.Errorf("failed to update currency of item %d: %w", itemID, err)
+	}
+
+	before := itemCurrency(item)
+	if before == "" {
+		before = a.currencyRates.Base
+	}
+	after := code
+	if after == "" {
+		after = a.currencyRates.Base
+	}
+	a.recordOp(oplog.Operation{Type: oplog.OpUpdateItem, ID: itemID, Name: item.Name, Price: item.PriceInCents, Extensions: ext})
+	a.recordAudit(dao.AuditUpdate, "item", itemID, "currency "+before, "currency "+after)
+
+	a.logger.Info(fmt.Sprintf("Item #%d (%s) is now priced in %s", itemID, item.Name, after))
+	return a.GetItem(itemID)
+}
+
+// GetCurrencyRates returns the base currency and the conversion rates to it
+func (a *App) GetCurrencyRates() map[string]any {
+	defer a.track("GetCurrencyRates", time.Now(), nil)
+	codes := make([]string, 0, len(a.currencyRates.Rates))
+	for code := range a.currencyRates.Rates {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+
+	rates := make([]map[string]any, len(codes))
+	for i, code := range codes {
+		rates[i] = map[string]any{"currency": code, "rate": a.currencyRates.Rates[code]}
+	}
+
+	return map[string]any{
+		"base":  a.currencyRates.Base,
+		"rates": rates,
+	}
+}
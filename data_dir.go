@@ -0,0 +1,85 @@
+package main
+
+import (
+	"BinaryCRUD/backend/events"
+	"BinaryCRUD/backend/oplog"
+	"BinaryCRUD/backend/utils"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// GetDataDirectory returns the directory holding the data files
+func (a *App) GetDataDirectory() map[string]any {
+	defer a.track("GetDataDirectory", time.Now(), nil)
+	path, err := filepath.Abs(utils.DataDir)
+	if err != nil {
+		path = utils.DataDir
+	}
+	return map[string]any{
+		"path":    path,
+		"default": utils.DefaultDataDir,
+		"envVar":  utils.DataDirEnv,
+	}
+}
+
+// SetDataDirectory moves the data files to a new directory and switches the app to it
+// Seed files are copied, everything else is moved; fails when the target already holds data or a compaction is running
+func (a *App) SetDataDirectory(path string) (_ map[string]any, err error) {
+	defer a.track("SetDataDirectory", time.Now(), &err)
+	if err := a.checkWritable(); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(path) == "" {
+		return nil, fmt.Errorf("data directory cannot be empty")
+	}
+	// Held until the move is done, so no compaction starts on the files while they are moved
+	if err := a.beginCompaction(); err != nil {
+		return nil, err
+	}
+	defer a.endCompaction()
+
+	from, err := filepath.Abs(utils.DataDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve current data directory: %w", err)
+	}
+	to, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve data directory: %w", err)
+	}
+	if from == to {
+		return a.GetDataDirectory(), nil
+	}
+
+	// Take the new directory's lock before giving up the current one
+	lock, err := utils.LockDataDir(to)
+	if err != nil {
+		return nil, err
+	}
+
+	a.closeDAOs()
+	// The log file moves with the data, it is reopened in whichever directory is in use afterwards
+	a.logger.Close()
+	moved, err := utils.MoveDataDir(from, to)
+	if err != nil {
+		lock.Release()
+		a.logger.Configure(a.currentConfig().Logging)
+		a.logger.Error(fmt.Sprintf("Failed to move data to %s: %v", to, err))
+		return nil, fmt.Errorf("failed to move data: %w", err)
+	}
+
+	a.releaseDataDir()
+	a.dataLock = lock
+	utils.SetDataDir(to)
+	a.logger.Configure(a.currentConfig().Logging)
+	a.reloadDAOs()
+	a.oplog = oplog.New(utils.OplogPath())
+	a.currencyRates = loadCurrencyRates(a.logger)
+	a.publish(events.Event{Type: events.DataReloaded})
+
+	a.logger.Info(fmt.Sprintf("Data directory changed to %s (moved %s)", to, strings.Join(moved, ", ")))
+	result := a.GetDataDirectory()
+	result["moved"] = moved
+	return result, nil
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"BinaryCRUD/backend/utils"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSetDataDirectoryMovesTheData(t *testing.T) {
+	app := newTestApp(t)
+	id, err := app.AddItem("Burger", 899)
+	if err != nil {
+		t.Fatalf("Failed to add item: %v", err)
+	}
+	from := utils.DataDir
+
+	to := filepath.Join(t.TempDir(), "moved")
+	result, err := app.SetDataDirectory(to)
+	if err != nil {
+		t.Fatalf("SetDataDirectory failed: %v", err)
+	}
+	if result["path"] != to {
+		t.Errorf("Expected the data directory to be %s, got %v", to, result["path"])
+	}
+	if _, err := os.Stat(filepath.Join(to, "bin", "items.bin")); err != nil {
+		t.Errorf("Expected items.bin in the new directory: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(from, "bin", "items.bin")); !os.IsNotExist(err) {
+		t.Errorf("Expected items.bin to be gone from the old directory, got %v", err)
+	}
+
+	item, err := app.GetItem(id)
+	if err != nil || item["name"] != "Burger" {
+		t.Fatalf("Expected the item to be read from the new directory, got %v (err %v)", item, err)
+	}
+	if _, err := app.AddItem("Fries", 299); err != nil {
+		t.Fatalf("Failed to add item after the move: %v", err)
+	}
+	if _, err := app.Compact(); err != nil {
+		t.Errorf("Expected a compaction to start once the move is done: %v", err)
+	}
+}
+
+func TestSetDataDirectoryWaitsForNoCompaction(t *testing.T) {
+	app := newTestApp(t)
+	if _, err := app.AddItem("Burger", 899); err != nil {
+		t.Fatalf("Failed to add item: %v", err)
+	}
+	from := utils.DataDir
+
+	if err := app.beginCompaction(); err != nil {
+		t.Fatalf("Failed to begin a compaction: %v", err)
+	}
+	_, err := app.SetDataDirectory(filepath.Join(t.TempDir(), "moved"))
+	app.endCompaction()
+	if utils.ErrorCodeOf(err) != utils.CodeConflict {
+		t.Fatalf("Expected a Conflict while a compaction runs, got %v", err)
+	}
+	if utils.DataDir != from {
+		t.Errorf("Expected the data to stay in %s, got %s", from, utils.DataDir)
+	}
+}
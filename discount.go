@@ -0,0 +1,109 @@
+package main
+
+import (
+	"BinaryCRUD/backend/dao"
+	"BinaryCRUD/backend/oplog"
+	"BinaryCRUD/backend/utils"
+	"fmt"
+	"time"
+)
+
+// discountExtensions converts an API discount (type name + value) into record extensions
+// A "none" discount yields no extensions
+func discountExtensions(discountType string, value uint64) (map[byte][]byte, error) {
+	t, err := utils.ParseDiscountType(discountType)
+	if err != nil {
+		return nil, err
+	}
+	if t == utils.DiscountNone {
+		return nil, nil
+	}
+
+	data, err := utils.EncodeDiscount(utils.Discount{Type: t, Value: value})
+	if err != nil {
+		return nil, fmt.Errorf("invalid discount: %w", err)
+	}
+	return map[byte][]byte{utils.ExtDiscount: data}, nil
+}
+
+// promotionDiscount reads the discount stored on a promotion, unreadable discounts count as none
+func promotionDiscount(promotion *dao.Collection) utils.Discount {
+	discount, err := utils.DecodeDiscount(promotion.Extensions)
+	if err != nil {
+		return utils.Discount{Type: utils.DiscountNone}
+	}
+	return discount
+}
+
+// addDiscountFields adds the discount of a promotion to an API response map
+func addDiscountFields(result map[string]any, promotion *dao.Collection) map[string]any {
+	discount := promotionDiscount(promotion)
+	result["discountType"] = utils.DiscountTypeName(discount.Type)
+	result["discountValue"] = discount.Value
+	return result
+}
+
+// appliedLink returns the link of a promotion applied to an order at appliedAt, with the discount it grants on the
+// order total as a snapshot, so later changes to the promotion or the order don't rewrite history
+func appliedLink(order, promotion *dao.Collection, appliedAt time.Time) dao.OrderPromotion {
+	return dao.OrderPromotion{
+		OrderID:        order.ID,
+		PromotionID:    promotion.ID,
+		AppliedAt:      appliedAt,
+		DiscountAmount: promotionDiscount(promotion).Amount(order.TotalPrice),
+	}
+}
+
+// addLinkFields adds when a promotion was applied and the discount it granted then to an API response map
+// Links written before this was recorded have neither
+func addLinkFields(result map[string]any, link *dao.OrderPromotion) map[string]any {
+	if !link.AppliedAt.IsZero() {
+		result["appliedAt"] = link.AppliedAt.Format(time.RFC3339)
+		result["appliedDiscount"] = link.DiscountAmount
+	}
+	return result
+}
+
+// SetPromotionDiscount sets the discount a promotion grants on the orders it is applied to
+// discountType is "percent" (value 0-100), "fixed" (value in cents) or "none"
+func (a *App) SetPromotionDiscount(promotionID uint64, discountType string, value uint64) (err error) {
+	defer a.track("SetPromotionDiscount", time.Now(), &err)
+	if err := a.checkWritable(); err != nil {
+		return err
+	}
+
+	promotion, err := a.promotionDAO.Read(promotionID)
+	if err != nil {
+		return fmt.Errorf("failed to read promotion: %w", err)
+	}
+
+	ext, err := discountExtensions(discountType, value)
+	if err != nil {
+		return err
+	}
+
+	if err := a.promotionDAO.UpdateExtensions(promotionID, ext); err != nil {
+		return fmt.Errorf("failed to update promotion: %w", err)
+	}
+
+	before := promotionDiscount(promotion)
+	after := promotionDiscount(&dao.Collection{Extensions: ext})
+	a.recordOp(oplog.Operation{Type: oplog.OpUpdatePromotion, ID: promotionID, Name: promotion.OwnerOrName,
+		Price: promotion.TotalPrice, ItemIDs: promotion.ItemIDs, Extensions: ext})
+	a.recordAudit(dao.AuditUpdate, "promotion", promotionID, discountSummary(before), discountSummary(after))
+
+	a.logger.Info(fmt.Sprintf("Set discount of promotion #%d to %s", promotionID, discountSummary(after)))
+	return nil
+}
+
+// discountSummary describes a discount for logs and the audit trail
+func discountSummary(d utils.Discount) string {
+	switch d.Type {
+	case utils.DiscountPercent:
+		return fmt.Sprintf("%d%% off", d.Value)
+	case utils.DiscountFixed:
+		return fmt.Sprintf("$%.2f off", float64(d.Value)/100)
+	default:
+		return "no discount"
+	}
+}
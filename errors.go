@@ -0,0 +1,27 @@
+package main
+
+import (
+	"BinaryCRUD/backend/utils"
+	"errors"
+)
+
+// errCompactionRunning is returned by bindings that cannot run next to a background compaction
+var errCompactionRunning = utils.WithCode(utils.CodeConflict, errors.New("a background compaction is running"), nil)
+
+// errReadOnly is wrapped by the errors checkWritable returns, so APIs can tell read-only mode from other conflicts
+var errReadOnly = errors.New("read-only mode")
+
+// errorResponse describes an error for the frontend as {code, message, details}
+// code is one of the utils error codes, details identify the records involved (e.g. entity and id)
+func errorResponse(err error) map[string]any {
+	return map[string]any{
+		"code":    utils.ErrorCodeOf(err),
+		"message": err.Error(),
+		"details": utils.ErrorDetails(err),
+	}
+}
+
+// formatBindingError is the Wails error formatter, so a failed binding rejects with an errorResponse
+func formatBindingError(err error) any {
+	return errorResponse(err)
+}
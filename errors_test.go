@@ -0,0 +1,67 @@
+package main
+
+import (
+	"BinaryCRUD/backend/utils"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWriteErrorStatus(t *testing.T) {
+	cases := []struct {
+		name   string
+		err    error
+		status int
+		code   utils.ErrorCode
+	}{
+		{"not found", utils.WithCode(utils.CodeNotFound, errors.New("item not found"), nil), http.StatusNotFound, utils.CodeNotFound},
+		{"deleted", utils.WithCode(utils.CodeDeleted, errors.New("deleted item id 1"), nil), http.StatusGone, utils.CodeDeleted},
+		{"validation", fmt.Errorf("invalid name: %w", utils.ErrNameEmpty), http.StatusBadRequest, utils.CodeValidation},
+		{"conflict", errCompactionRunning, http.StatusConflict, utils.CodeConflict},
+		{"read-only", (&App{readOnly: true}).checkWritable(), http.StatusForbidden, utils.CodeConflict},
+		{"wrapped read-only", fmt.Errorf("failed to add item: %w", (&App{readOnlyReason: "replica"}).checkWritable()), http.StatusForbidden, utils.CodeConflict},
+		{"read-only look-alike", utils.WithCode(utils.CodeConflict, errors.New("read-only mode file is locked"), nil), http.StatusConflict, utils.CodeConflict},
+		{"corruption", utils.WithCode(utils.CodeCorruption, errors.New("invalid magic bytes"), nil), http.StatusInternalServerError, utils.CodeCorruption},
+		{"io", utils.WithCode(utils.CodeIO, errors.New("disk full"), nil), http.StatusInternalServerError, utils.CodeIO},
+		{"internal", errors.New("something else"), http.StatusBadRequest, utils.CodeInternal},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := httptest.NewRecorder()
+			writeError(recorder, tc.err)
+
+			if recorder.Code != tc.status {
+				t.Errorf("Expected status %d, got %d", tc.status, recorder.Code)
+			}
+			var body map[string]any
+			if err := json.Unmarshal(recorder.Body.Bytes(), &body); err != nil {
+				t.Fatalf("Failed to decode body: %v", err)
+			}
+			if body["code"] != string(tc.code) {
+				t.Errorf("Expected code %s, got %v", tc.code, body["code"])
+			}
+			if body["error"] != tc.err.Error() {
+				t.Errorf("Expected error %q, got %v", tc.err.Error(), body["error"])
+			}
+		})
+	}
+}
+
+func TestFormatBindingError(t *testing.T) {
+	err := utils.WithCode(utils.CodeDeleted, errors.New("order with ID 3 is deleted"), utils.RecordDetails("order", 3))
+	response, ok := formatBindingError(err).(map[string]any)
+	if !ok {
+		t.Fatalf("Expected a map, got %T", formatBindingError(err))
+	}
+	if response["code"] != utils.CodeDeleted || response["message"] != err.Error() {
+		t.Errorf("Unexpected response %v", response)
+	}
+	details := response["details"].(map[string]any)
+	if details["entity"] != "order" || details["id"] != uint64(3) {
+		t.Errorf("Unexpected details %v", details)
+	}
+}
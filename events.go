@@ -0,0 +1,63 @@
+package main
+
+import (
+	"BinaryCRUD/backend/events"
+	"BinaryCRUD/backend/oplog"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// DataChangedEvent is the Wails event emitted for every data change, next to "data:<Type>"
+const DataChangedEvent = "data:changed"
+
+// opEvents maps the logged operations to the events they publish
+var opEvents = map[oplog.OpType]events.Type{
+	oplog.OpAddItem:         events.ItemCreated,
+	oplog.OpUpdateItem:      events.ItemUpdated,
+	oplog.OpDeleteItem:      events.ItemDeleted,
+	oplog.OpCreateOrder:     events.OrderCreated,
+	oplog.OpUpdateOrder:     events.OrderUpdated,
+	oplog.OpDeleteOrder:     events.OrderDeleted,
+	oplog.OpCreatePromotion: events.PromotionCreated,
+	oplog.OpUpdatePromotion: events.PromotionUpdated,
+	oplog.OpDeletePromotion: events.PromotionDeleted,
+	oplog.OpApplyPromotion:  events.PromotionApplied,
+	oplog.OpRemovePromotion: events.PromotionRemoved,
+}
+
+// publish sends an event to the Go subscribers and, through them, to the frontend
+func (a *App) publish(event events.Event) {
+	a.events.Publish(event)
+}
+
+// publishOp publishes the event of a logged operation
+func (a *App) publishOp(op oplog.Operation) {
+	eventType, ok := opEvents[op.Type]
+	if !ok {
+		return
+	}
+	a.publish(events.Event{
+		Type:        eventType,
+		ID:          op.ID,
+		OrderID:     op.OrderID,
+		PromotionID: op.PromotionID,
+		Timestamp:   op.Timestamp,
+	})
+}
+
+// subscribe registers a Go handler for data change events, or for every event when no types are given
+// The returned function removes the subscription
+func (a *App) subscribe(handler events.Handler, types ...events.Type) func() {
+	return a.events.Subscribe(handler, types...)
+}
+
+// forwardEventsToFrontend emits every data change as the Wails events "data:<Type>" and "data:changed"
+func (a *App) forwardEventsToFrontend() func() {
+	return a.subscribe(func(event events.Event) {
+		if a.ctx == nil {
+			return
+		}
+		runtime.EventsEmit(a.ctx, "data:"+string(event.Type), event)
+		runtime.EventsEmit(a.ctx, DataChangedEvent, event)
+	})
+}
@@ -0,0 +1,376 @@
+package main
+
+import (
+	"BinaryCRUD/backend/dao"
+	"BinaryCRUD/backend/oplog"
+	"BinaryCRUD/backend/utils"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ExportFormatVersion is the version of the JSON export document
+const ExportFormatVersion = 1
+
+// ExportedItem is an item in the export document, seed fields plus its original ID
+type ExportedItem struct {
+	ID uint64 `json:"id"`
+	ItemEntry
+}
+
+// ExportedPromotion is a promotion in the export document
+type ExportedPromotion struct {
+	ID         uint64 `json:"id"`
+	TotalPrice uint64 `json:"totalPrice"`
+	PromotionEntry
+}
+
+// ExportedOrder is an order in the export document
+// Promotions are listed separately in OrderPromotions, not embedded
+type ExportedOrder struct {
+	ID         uint64 `json:"id"`
+	TotalPrice uint64 `json:"totalPrice"`
+	Status     string `json:"status,omitempty"`
+	CreatedAt  string `json:"createdAt,omitempty"`
+	OrderEntry
+}
+
+// ExportDocument is the JSON document written by ExportAll
+// Each section uses the seed entry format so it can be re-imported
+type ExportDocument struct {
+	Version         int                   `json:"version"`
+	ExportedAt      string                `json:"exportedAt"`
+	Items           []ExportedItem        `json:"items"`
+	Promotions      []ExportedPromotion   `json:"promotions"`
+	Orders          []ExportedOrder       `json:"orders"`
+	OrderPromotions []OrderPromotionEntry `json:"orderPromotions"`
+}
+
+// buildExportDocument collects all non-deleted records with decrypted names
+func (a *App) buildExportDocument() (*ExportDocument, error) {
+	doc := &ExportDocument{
+		Version:         ExportFormatVersion,
+		ExportedAt:      time.Now().UTC().Format(time.RFC3339),
+		Items:           []ExportedItem{},
+		Promotions:      []ExportedPromotion{},
+		Orders:          []ExportedOrder{},
+		OrderPromotions: []OrderPromotionEntry{},
+	}
+
+	items, err := a.itemDAO.GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read items: %w", err)
+	}
+	for _, item := range items {
+		if item.IsDeleted {
+			continue
+		}
+		exported := ExportedItem{
+			ID:        item.ID,
+			ItemEntry: ItemEntry{Name: item.Name, PriceInCents: item.PriceInCents, Currency: itemCurrency(&item), ExternalID: itemExternalID(&item)},
+		}
+		if quantity, tracked := itemStock(&item); tracked {
+			exported.Stock = &quantity
+		}
+		doc.Items = append(doc.Items, exported)
+	}
+
+	promotions, err := a.promotionDAO.GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read promotions: %w", err)
+	}
+	for _, promotion := range promotions {
+		if promotion.IsDeleted {
+			continue
+		}
+		exported := ExportedPromotion{
+			ID:             promotion.ID,
+			TotalPrice:     promotion.TotalPrice,
+			PromotionEntry: PromotionEntry{Name: promotion.OwnerOrName, ItemIDs: promotion.ItemIDs},
+		}
+		if discount := promotionDiscount(promotion); discount.Type != utils.DiscountNone {
+			exported.DiscountType = utils.DiscountTypeName(discount.Type)
+			exported.DiscountValue = discount.Value
+		}
+		doc.Promotions = append(doc.Promotions, exported)
+	}
+
+	orders, err := a.orderDAO.GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read orders: %w", err)
+	}
+	for _, order := range orders {
+		if order.IsDeleted {
+			continue
+		}
+		exported := ExportedOrder{
+			ID:         order.ID,
+			TotalPrice: order.TotalPrice,
+			Status:     utils.OrderStatusName(orderStatus(order)),
+			OrderEntry: OrderEntry{Owner: order.OwnerOrName, ItemIDs: order.ItemIDs},
+		}
+		if createdAt, ok := orderCreatedAt(order); ok {
+			exported.CreatedAt = createdAt.Format(time.RFC3339Nano)
+		}
+		doc.Orders = append(doc.Orders, exported)
+	}
+
+	orderPromotions, err := a.orderPromotionDAO.GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read order-promotion relationships: %w", err)
+	}
+	for _, op := range orderPromotions {
+		entry := OrderPromotionEntry{
+			OrderID:     op.OrderID,
+			PromotionID: op.PromotionID,
+		}
+		if !op.AppliedAt.IsZero() {
+			entry.AppliedAt = op.AppliedAt.Format(time.RFC3339Nano)
+			entry.DiscountAmount = op.DiscountAmount
+		}
+		doc.OrderPromotions = append(doc.OrderPromotions, entry)
+	}
+
+	return doc, nil
+}
+
+// ExportAll writes items, promotions, orders and order-promotion links to a JSON file
+// Deleted records are skipped and encrypted names are written decrypted
+func (a *App) ExportAll(path string) (_ map[string]any, err error) {
+	defer a.track("ExportAll", time.Now(), &err)
+	if path == "" {
+		return nil, fmt.Errorf("export path cannot be empty")
+	}
+
+	doc, err := a.buildExportDocument()
+	if err != nil {
+		a.logger.Error(fmt.Sprintf("Export failed: %v", err))
+		return nil, err
+	}
+
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode export: %w", err)
+	}
+
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create export directory: %w", err)
+		}
+	}
+
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write export file: %w", err)
+	}
+
+	a.logger.Info(fmt.Sprintf("Exported %d items, %d promotions, %d orders and %d order-promotion links to %s",
+		len(doc.Items), len(doc.Promotions), len(doc.Orders), len(doc.OrderPromotions), path))
+
+	return map[string]any{
+		"path":            path,
+		"items":           len(doc.Items),
+		"promotions":      len(doc.Promotions),
+		"orders":          len(doc.Orders),
+		"orderPromotions": len(doc.OrderPromotions),
+		"size":            len(data),
+	}, nil
+}
+
+// importConflicts lists records in the document whose IDs are already taken
+func (a *App) importConflicts(doc *ExportDocument) []string {
+	var conflicts []string
+
+	for _, item := range doc.Items {
+		if _, _, _, err := a.itemDAO.Read(item.ID); err == nil {
+			conflicts = append(conflicts, fmt.Sprintf("item #%d", item.ID))
+		}
+	}
+	for _, promotion := range doc.Promotions {
+		if _, err := a.promotionDAO.Read(promotion.ID); err == nil {
+			conflicts = append(conflicts, fmt.Sprintf("promotion #%d", promotion.ID))
+		}
+	}
+	for _, order := range doc.Orders {
+		if _, err := a.orderDAO.Read(order.ID); err == nil {
+			conflicts = append(conflicts, fmt.Sprintf("order #%d", order.ID))
+		}
+	}
+
+	return conflicts
+}
+
+// ImportAll loads a document written by ExportAll into the database
+// With preserveIDs, records keep their original IDs (failing up front on any ID conflict)
+// and each header's nextId ends up past the highest imported ID.
+// Without it, records get fresh IDs and item/order/promotion references are remapped.
+func (a *App) ImportAll(path string, preserveIDs bool) (_ map[string]any, err error) {
+	defer a.track("ImportAll", time.Now(), &err)
+	if err := a.checkWritable(); err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read import file: %w", err)
+	}
+
+	var doc ExportDocument
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse import file: %w", err)
+	}
+	if doc.Version != ExportFormatVersion {
+		return nil, fmt.Errorf("unsupported export version %d", doc.Version)
+	}
+
+	if preserveIDs {
+		if conflicts := a.importConflicts(&doc); len(conflicts) > 0 {
+			a.logger.Error(fmt.Sprintf("Import aborted, %d ID conflict(s): %s", len(conflicts), strings.Join(conflicts, ", ")))
+			return nil, fmt.Errorf("import conflicts with existing data: %s", strings.Join(conflicts, ", "))
+		}
+	}
+
+	// Write in ascending ID order so files stay sorted
+	sort.Slice(doc.Items, func(i, j int) bool { return doc.Items[i].ID < doc.Items[j].ID })
+	sort.Slice(doc.Promotions, func(i, j int) bool { return doc.Promotions[i].ID < doc.Promotions[j].ID })
+	sort.Slice(doc.Orders, func(i, j int) bool { return doc.Orders[i].ID < doc.Orders[j].ID })
+
+	// Old ID -> new ID, identity when IDs are preserved
+	itemIDs := make(map[uint64]uint64, len(doc.Items))
+	promotionIDs := make(map[uint64]uint64, len(doc.Promotions))
+	orderIDs := make(map[uint64]uint64, len(doc.Orders))
+	remap := func(ids []uint64) []uint64 {
+		mapped := make([]uint64, 0, len(ids))
+		for _, id := range ids {
+			if newID, ok := itemIDs[id]; ok {
+				mapped = append(mapped, newID)
+			} else {
+				mapped = append(mapped, id)
+			}
+		}
+		return mapped
+	}
+
+	result := &populationResult{}
+
+	for _, item := range doc.Items {
+		newID := item.ID
+		ext, err := itemEntryExtensions(item.ItemEntry)
+		if err == nil {
+			var id *uint64
+			if preserveIDs {
+				id = &item.ID
+			}
+			newID, err = a.itemDAO.WriteExtended(id, item.Name, item.PriceInCents, ext)
+		}
+		if err != nil {
+			a.logger.Error(fmt.Sprintf("Failed to import item #%d (%s): %v", item.ID, item.Name, err))
+			result.fail++
+			continue
+		}
+		itemIDs[item.ID] = newID
+		a.recordOp(oplog.Operation{Type: oplog.OpAddItem, ID: newID, Name: item.Name, Price: item.PriceInCents, Extensions: ext})
+		a.recordAudit(dao.AuditCreate, "item", newID, "", itemSummary(item.Name, item.PriceInCents))
+		result.success++
+	}
+
+	for _, promotion := range doc.Promotions {
+		newID := promotion.ID
+		itemRefs := remap(promotion.ItemIDs)
+		ext, err := discountExtensions(promotion.DiscountType, promotion.DiscountValue)
+		if err == nil {
+			var id *uint64
+			if preserveIDs {
+				id = &promotion.ID
+			}
+			newID, err = a.promotionDAO.WriteExtended(id, promotion.Name, promotion.TotalPrice, itemRefs, ext)
+		}
+		if err != nil {
+			a.logger.Error(fmt.Sprintf("Failed to import promotion #%d (%s): %v", promotion.ID, promotion.Name, err))
+			result.fail++
+			continue
+		}
+		promotionIDs[promotion.ID] = newID
+		a.recordOp(oplog.Operation{Type: oplog.OpCreatePromotion, ID: newID, Name: promotion.Name, Price: promotion.TotalPrice, ItemIDs: itemRefs, Extensions: ext})
+		a.recordAudit(dao.AuditCreate, "promotion", newID, "", collectionSummary(promotion.TotalPrice, itemRefs))
+		result.success++
+	}
+
+	for _, order := range doc.Orders {
+		newID := order.ID
+		itemRefs := remap(order.ItemIDs)
+		ext, err := orderExtensionsFromEntry(order.Status, order.CreatedAt)
+		if err == nil {
+			var id *uint64
+			if preserveIDs {
+				id = &order.ID
+			}
+			newID, err = a.orderDAO.WriteExtended(id, order.Owner, order.TotalPrice, itemRefs, ext)
+		}
+		if err != nil {
+			a.logger.Error(fmt.Sprintf("Failed to import order #%d (%s): %v", order.ID, order.Owner, err))
+			result.fail++
+			continue
+		}
+		orderIDs[order.ID] = newID
+		a.recordOp(oplog.Operation{Type: oplog.OpCreateOrder, ID: newID, Name: order.Owner, Price: order.TotalPrice, ItemIDs: itemRefs, Extensions: ext})
+		a.recordAudit(dao.AuditCreate, "order", newID, "", collectionSummary(order.TotalPrice, itemRefs))
+		result.success++
+	}
+
+	links := 0
+	for _, op := range doc.OrderPromotions {
+		orderID, orderOK := orderIDs[op.OrderID]
+		promotionID, promotionOK := promotionIDs[op.PromotionID]
+		if !orderOK || !promotionOK {
+			a.logger.Warn(fmt.Sprintf("Skipping link order #%d -> promotion #%d: record was not imported", op.OrderID, op.PromotionID))
+			result.fail++
+			continue
+		}
+		link, err := linkFromEntry(op, orderID, promotionID)
+		if err == nil {
+			err = a.orderPromotionDAO.WriteLink(link)
+		}
+		if err != nil {
+			a.logger.Error(fmt.Sprintf("Failed to import link order #%d -> promotion #%d: %v", op.OrderID, op.PromotionID, err))
+			result.fail++
+			continue
+		}
+		a.recordOp(oplog.Operation{Type: oplog.OpApplyPromotion, OrderID: orderID, PromotionID: promotionID, Price: link.DiscountAmount})
+		a.recordAudit(dao.AuditUpdate, "order", orderID, "", fmt.Sprintf("promotion #%d applied", promotionID))
+		links++
+	}
+
+	a.logger.Info(fmt.Sprintf("Imported %d items, %d promotions, %d orders and %d order-promotion links from %s (%d failed)",
+		len(itemIDs), len(promotionIDs), len(orderIDs), links, path, result.fail))
+
+	summary := map[string]any{
+		"items":           len(itemIDs),
+		"promotions":      len(promotionIDs),
+		"orders":          len(orderIDs),
+		"orderPromotions": links,
+		"failed":          result.fail,
+		"preserveIDs":     preserveIDs,
+	}
+	if result.fail > 0 {
+		return summary, fmt.Errorf("some entries failed to import: %d failed", result.fail)
+	}
+	return summary, nil
+}
+
+// linkFromEntry returns the link of an imported order-promotion entry between the imported order and promotion
+// Entries exported before links recorded their application keep no metadata
+func linkFromEntry(entry OrderPromotionEntry, orderID, promotionID uint64) (dao.OrderPromotion, error) {
+	link := dao.OrderPromotion{OrderID: orderID, PromotionID: promotionID, DiscountAmount: entry.DiscountAmount}
+	if entry.AppliedAt != "" {
+		t, err := time.Parse(time.RFC3339Nano, entry.AppliedAt)
+		if err != nil {
+			return link, fmt.Errorf("invalid appliedAt %q: %w", entry.AppliedAt, err)
+		}
+		link.AppliedAt = t.UTC()
+	}
+	return link, nil
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// readExport reads a document written by ExportAll, leaving out its export time
+// Records are listed in file order, where a rewritten record moves to the end, so items are sorted by ID
+func readExport(t *testing.T, path string) ExportDocument {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read export: %v", err)
+	}
+	var doc ExportDocument
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("Failed to parse export: %v", err)
+	}
+	doc.ExportedAt = ""
+	sort.Slice(doc.Items, func(i, j int) bool { return doc.Items[i].ID < doc.Items[j].ID })
+	return doc
+}
+
+func TestExportImportRoundTrip(t *testing.T) {
+	app := newTestApp(t)
+	for _, name := range []string{"Burger", "Fries", "Soda"} {
+		if _, err := app.AddItem(name, 499); err != nil {
+			t.Fatalf("Failed to add item: %v", err)
+		}
+	}
+	if _, err := app.AdjustStock(0, 5); err != nil {
+		t.Fatalf("Failed to set stock: %v", err)
+	}
+	if err := app.DeleteItem(2); err != nil {
+		t.Fatalf("Failed to delete item: %v", err)
+	}
+	promotionID, err := app.CreatePromotion("Combo", []uint64{0, 1})
+	if err != nil {
+		t.Fatalf("Failed to create promotion: %v", err)
+	}
+	if err := app.SetPromotionDiscount(promotionID, "percent", 10); err != nil {
+		t.Fatalf("Failed to set discount: %v", err)
+	}
+	orderID, err := app.CreateOrder("Alice", []uint64{0, 1})
+	if err != nil {
+		t.Fatalf("Failed to create order: %v", err)
+	}
+	if _, err := app.SetOrderStatus(orderID, "paid"); err != nil {
+		t.Fatalf("Failed to set status: %v", err)
+	}
+	if err := app.ApplyPromotionToOrder(orderID, promotionID); err != nil {
+		t.Fatalf("Failed to apply promotion: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "export.json")
+	summary, err := app.ExportAll(path)
+	if err != nil {
+		t.Fatalf("ExportAll failed: %v", err)
+	}
+	if summary["items"] != 2 || summary["promotions"] != 1 || summary["orders"] != 1 || summary["orderPromotions"] != 1 {
+		t.Fatalf("Expected the active records only, got %v", summary)
+	}
+	exported := readExport(t, path)
+
+	// Preserving IDs fails up front while the records still exist
+	if _, err := app.ImportAll(path, true); err == nil {
+		t.Fatal("Expected an import preserving IDs to fail on the existing records")
+	}
+
+	// Into an empty database the export comes back as it was written
+	app.waitForCompaction()
+	if err := app.DeleteAllFiles(); err != nil {
+		t.Fatalf("Failed to delete data: %v", err)
+	}
+	if summary, err := app.ImportAll(path, true); err != nil || summary["failed"] != 0 {
+		t.Fatalf("ImportAll failed: %v (%v)", err, summary)
+	}
+	again := filepath.Join(t.TempDir(), "again.json")
+	if _, err := app.ExportAll(again); err != nil {
+		t.Fatalf("ExportAll failed: %v", err)
+	}
+	if reexported := readExport(t, again); !reflect.DeepEqual(reexported, exported) {
+		t.Errorf("Expected the same document after a round trip\nbefore: %+v\nafter:  %+v", exported, reexported)
+	}
+
+	// Without preserving IDs the records get new ones and the references follow them
+	if _, err := app.ImportAll(path, false); err != nil {
+		t.Fatalf("ImportAll failed: %v", err)
+	}
+	order, err := app.orderDAO.Read(1)
+	if err != nil {
+		t.Fatalf("Failed to read imported order: %v", err)
+	}
+	if fmt.Sprint(order.ItemIDs) != "[2 3]" || order.OwnerOrName != "Alice" {
+		t.Errorf("Expected the copy to reference the new items, got %+v", order)
+	}
+	promotions, err := app.GetOrderPromotions(1)
+	if err != nil || len(promotions) != 1 || promotions[0]["id"] != uint64(1) {
+		t.Errorf("Expected the copy linked to the new promotion, got %v (err %v)", promotions, err)
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"BinaryCRUD/backend/dao"
+	"BinaryCRUD/backend/oplog"
+	"BinaryCRUD/backend/utils"
+	"fmt"
+	"time"
+)
+
+// itemExternalID returns the external ID of an item, empty when it has none
+func itemExternalID(item *dao.Item) string {
+	externalID, err := utils.DecodeExternalID(item.Extensions)
+	if err != nil {
+		return ""
+	}
+	return externalID
+}
+
+// addExternalIDField adds the external ID of an item to an API response map when it has one
+func addExternalIDField(result map[string]any, item *dao.Item) map[string]any {
+	if externalID := itemExternalID(item); externalID != "" {
+		result["externalId"] = externalID
+	}
+	return result
+}
+
+// GetItemByExternalID retrieves the active item another system refers to by externalID
+func (a *App) GetItemByExternalID(externalID string) (_ map[string]any, err error) {
+	defer a.track("GetItemByExternalID", time.Now(), &err)
+	id, err := a.itemDAO.FindByExternalID(externalID)
+	if err != nil {
+		return nil, err
+	}
+	return a.GetItem(id)
+}
+
+// SetItemExternalID sets the UUID other systems use to refer to an item, an empty externalID removes it
+// External IDs are unique among active items
+func (a *App) SetItemExternalID(itemID uint64, externalID string) (_ map[string]any, err error) {
+	defer a.track("SetItemExternalID", time.Now(), &err)
+	if err := a.checkWritable(); err != nil {
+		return nil, err
+	}
+
+	item, err := a.itemDAO.ReadItem(itemID)
+	if err != nil {
+		return nil, err
+	}
+
+	var data []byte
+	if externalID != "" {
+		if data, err = utils.EncodeExternalID(externalID); err != nil {
+			return nil, err
+		}
+	}
+	ext := utils.WithExtension(item.Extensions, utils.ExtExternalID, data)
+
+	if err := a.itemDAO.UpdateExtensions(itemID, ext); err != nil {
+		return nil, fmt.Errorf("failed to update external ID of item %d: %w", itemID, err)
+	}
+
+	before := itemExternalID(item)
+	after, _ := utils.DecodeExternalID(ext)
+	a.recordOp(oplog.Operation{Type: oplog.OpUpdateItem, ID: itemID, Name: item.Name, Price: item.PriceInCents, Extensions: ext})
+	a.recordAudit(dao.AuditUpdate, "item", itemID, "external ID "+before, "external ID "+after)
+
+	a.logger.Info(fmt.Sprintf("Item #%d (%s) external ID set to %q", itemID, item.Name, after))
+	return a.GetItem(itemID)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"BinaryCRUD/backend/utils"
+	"strings"
+	"testing"
+)
+
+func TestGetItemByExternalID(t *testing.T) {
+	app := newTestApp(t)
+	for _, name := range []string{"Burger", "Fries"} {
+		if _, err := app.AddItem(name, 499); err != nil {
+			t.Fatalf("Failed to add item: %v", err)
+		}
+	}
+
+	const externalID = "0b6f1c5e-8f2a-4d3b-9c1e-2a7d4e5f6a7b"
+	if _, err := app.SetItemExternalID(1, strings.ToUpper(externalID)); err != nil {
+		t.Fatalf("Failed to set external ID: %v", err)
+	}
+
+	// Lookups don't depend on the case the ID was given in
+	for _, lookup := range []string{externalID, strings.ToUpper(externalID)} {
+		item, err := app.GetItemByExternalID(lookup)
+		if err != nil {
+			t.Fatalf("GetItemByExternalID failed: %v", err)
+		}
+		if item["id"] != uint64(1) || item["name"] != "Fries" || item["externalId"] != externalID {
+			t.Errorf("Expected Fries with its external ID, got %v", item)
+		}
+	}
+
+	if _, err := app.SetItemExternalID(0, externalID); err == nil {
+		t.Error("Expected an external ID already used by an active item to be rejected")
+	}
+	if _, err := app.GetItemByExternalID("not-a-uuid"); err == nil {
+		t.Error("Expected an external ID that isn't a UUID to be rejected")
+	}
+
+	// A deleted item can no longer be found, so its ID is free again
+	if err := app.DeleteItem(1); err != nil {
+		t.Fatalf("Failed to delete item: %v", err)
+	}
+	if _, err := app.GetItemByExternalID(externalID); utils.ErrorCodeOf(err) != utils.CodeNotFound {
+		t.Errorf("Expected the deleted item to be not found, got %v", err)
+	}
+	if _, err := app.SetItemExternalID(0, externalID); err != nil {
+		t.Fatalf("Failed to reuse external ID: %v", err)
+	}
+	if item, err := app.GetItemByExternalID(externalID); err != nil || item["id"] != uint64(0) {
+		t.Errorf("Expected Burger by the reused external ID, got %v (err %v)", item, err)
+	}
+
+	// Clearing the external ID removes it from the lookup
+	if _, err := app.SetItemExternalID(0, ""); err != nil {
+		t.Fatalf("Failed to clear external ID: %v", err)
+	}
+	if _, err := app.GetItemByExternalID(externalID); utils.ErrorCodeOf(err) != utils.CodeNotFound {
+		t.Errorf("Expected a cleared external ID to be not found, got %v", err)
+	}
+}
@@ -0,0 +1,40 @@
+import { h } from "preact";
+import { Button } from "./Button";
+import { useProgress } from "../hooks/useProgress";
+import { systemService } from "../services/systemService";
+import { toast } from "../utils/toast";
+import { formatError } from "../utils/formatters";
+
+// Shows a bar for each running long operation, with a button cancelling it
+export const ProgressBars = () => {
+  const operations = useProgress();
+
+  const handleCancel = async (token: string) => {
+    try {
+      await systemService.cancelOperation(token);
+    } catch (err) {
+      toast.error(formatError(err));
+    }
+  };
+
+  return (
+    <>
+      {operations.map((op) => {
+        const percent = op.total > 0 ? Math.min(100, (op.done / op.total) * 100) : 0;
+        return (
+          <div className="progress-container" key={op.token}>
+            <span className="progress-label">
+              {op.operation} {op.total > 0 ? `${percent.toFixed(0)}%` : "..."}
+            </span>
+            <div className="progress-track">
+              <div className="progress-fill" style={{ width: `${percent}%` }} />
+            </div>
+            <Button variant="danger" onClick={() => handleCancel(op.token)}>
+              Cancel
+            </Button>
+          </div>
+        );
+      })}
+    </>
+  );
+};
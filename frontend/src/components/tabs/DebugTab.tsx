@@ -7,7 +7,8 @@ import { ItemList } from "../ItemList";
 import { DataTable } from "../DataTable";
 import { SubTabs } from "../SubTabs";
 import { Toggle } from "../Toggle";
-import { systemService } from "../../services/systemService";
+import { ProgressBars } from "../ProgressBar";
+import { systemService, HexDump } from "../../services/systemService";
 import { itemService, Item } from "../../services/itemService";
 import { orderService, Order } from "../../services/orderService";
 import { promotionService, Promotion } from "../../services/promotionService";
@@ -54,6 +55,7 @@ export const DebugTab = ({
     orders?: any;
     promotions?: any;
   }>({});
+  const [indexHex, setIndexHex] = useState<HexDump | null>(null);
   const [printData, setPrintData] = useState<{
     items?: Item[];
     orders?: Order[];
@@ -75,6 +77,8 @@ export const DebugTab = ({
   // Compression state
   const [selectedFile, setSelectedFile] = useState<string>("");
   const [selectedAlgorithm, setSelectedAlgorithm] = useState<string>("huffman");
+  const [encryptArchive, setEncryptArchive] = useState(false);
+  const [keepOriginal, setKeepOriginal] = useState(true);
   const [compressedFiles, setCompressedFiles] = useState<CompressedFile[]>([]);
   const [binFiles, setBinFiles] = useState<BinFile[]>([]);
   const [isCompressing, setIsCompressing] = useState(false);
@@ -150,10 +154,10 @@ export const DebugTab = ({
     setIsCompressing(true);
     try {
       if (selectedFile === "__all__") {
-        const result = await compressionService.compressAll(selectedAlgorithm);
+        const result = await compressionService.compressAll(selectedAlgorithm, encryptArchive);
         toast.success(`Compressed all files: ${result.spaceSaved} saved`);
       } else {
-        const result = await compressionService.compress(selectedFile, selectedAlgorithm);
+        const result = await compressionService.compress(selectedFile, selectedAlgorithm, keepOriginal);
         toast.success(`Compressed: ${result.spaceSaved} saved`);
       }
       await loadCompressedFiles();
@@ -226,6 +230,25 @@ export const DebugTab = ({
     }
   };
 
+  const handleCompactPreview = async () => {
+    try {
+      const preview = await systemService.compactPreview();
+      const removed =
+        preview.itemsRemoved + preview.ordersRemoved + preview.promotionsRemoved + preview.orderPromotionsRemoved;
+      const affected = preview.ordersAffected + preview.promotionsAffected;
+      if (removed === 0 && affected === 0) {
+        toast.info("Nothing to compact");
+      } else {
+        toast.info(
+          `Compaction would remove ${removed} records, clean ${affected} orders/promotions and reclaim ${preview.reclaimedBytes} bytes`
+        );
+      }
+      onRefreshLogs();
+    } catch (err) {
+      toast.error(formatError(err));
+    }
+  };
+
   // Consolidated index loading function
   const handlePrintIndex = async (type: IndexType) => {
     const indexNames: Record<IndexType, string> = {
@@ -250,10 +273,13 @@ export const DebugTab = ({
       }
       setPrintData({});
       setIndexData({ [type]: data });
+      // The index file only exists once the index has been saved
+      setIndexHex(await systemService.dumpFileHex(`${type}.idx`).catch(() => null));
       toast.success(`${indexName.charAt(0).toUpperCase() + indexName.slice(1)} index: ${data.count} entries`);
       onRefreshLogs();
     } catch (err) {
       setIndexData({});
+      setIndexHex(null);
       toast.error(`Failed to load ${indexName} index`);
     }
   };
@@ -281,6 +307,7 @@ export const DebugTab = ({
           break;
       }
       setIndexData({});
+      setIndexHex(null);
       setPrintData({ [type]: data });
       toast.success(`Loaded ${data.length} ${typeName}`);
     } catch (err) {
@@ -385,10 +412,18 @@ export const DebugTab = ({
           >
             {isCompacting ? "Compacting..." : "Compact Database"}
           </Button>
+          <Button
+            onClick={handleCompactPreview}
+            disabled={isCompacting}
+            onMouseEnter={() => onMessage("Show what compaction would remove without changing any file")}
+            onMouseLeave={() => onMessage(DEFAULT_MESSAGE)}
+          >
+            Preview Compaction
+          </Button>
           <Button
             variant="danger"
             onClick={handleDeleteAll}
-            onMouseEnter={() => onMessage("Delete all .bin, .idx, and compressed files")}
+            onMouseEnter={() => onMessage("Move all .bin, .idx, and compressed files to the trash")}
             onMouseLeave={() => onMessage(DEFAULT_MESSAGE)}
           >
             Delete All Files
@@ -400,6 +435,7 @@ export const DebugTab = ({
             onMouseEnter={() => onMessage("Toggle RSA encryption for data stored in binary files")}
             onMouseLeave={() => onMessage(DEFAULT_MESSAGE)}
           />
+          <ProgressBars />
         </div>
       )}
 
@@ -690,11 +726,29 @@ export const DebugTab = ({
               />
             </div>
           )}
+
+          {indexHex && (
+            <div className="details-card max-height-300">
+              <h3>
+                {indexHex.file} on disk ({indexHex.length} of {indexHex.size} bytes)
+              </h3>
+              <pre className="data-table-monospace">
+                {indexHex.lines
+                  .map(
+                    (line) =>
+                      `${line.offset.toString(16).padStart(8, "0")}  ${line.hex.padEnd(47)}  ${line.ascii.padEnd(16)}` +
+                      (line.regions ? `  ${line.regions.join(", ")}` : "")
+                  )
+                  .join("\n")}
+              </pre>
+            </div>
+          )}
         </>
       )}
 
       {subTab === "compress" && (
         <>
+          <ProgressBars />
           <div className="cart-container">
             {binFiles.length === 0 ? (
               <p style={{ color: "#333" }}>No .bin files found in data/bin/. Populate inventory first.</p>
@@ -719,9 +773,29 @@ export const DebugTab = ({
                   options={[
                     { value: "huffman", label: "Huffman" },
                     { value: "lzw", label: "LZW" },
+                    { value: "gzip", label: "Gzip" },
+                    { value: "zstd", label: "Zstandard" },
                   ]}
                   className="cart-select"
                 />
+                {selectedFile === "__all__" && (
+                  <Toggle
+                    checked={encryptArchive}
+                    onChange={setEncryptArchive}
+                    label="Encrypt"
+                    onMouseEnter={() => onMessage("Encrypt the archive with AES-GCM so it can leave the machine safely")}
+                    onMouseLeave={() => onMessage(DEFAULT_MESSAGE)}
+                  />
+                )}
+                {selectedFile !== "__all__" && (
+                  <Toggle
+                    checked={keepOriginal}
+                    onChange={setKeepOriginal}
+                    label="Keep original"
+                    onMouseEnter={() => onMessage("Keep the .bin file after compressing; it is only removed once the compressed copy is verified")}
+                    onMouseLeave={() => onMessage(DEFAULT_MESSAGE)}
+                  />
+                )}
                 <Button
                   onClick={handleCompress}
                   disabled={isCompressing || !selectedFile}
@@ -0,0 +1,22 @@
+import { useEffect } from "preact/hooks";
+import { EventsOn } from "../../wailsjs/runtime/runtime";
+
+export interface DataEvent {
+  type: string;
+  id: number;
+  orderId: number;
+  promotionId: number;
+  timestamp: string;
+}
+
+// Calls onChange for every data change emitted by the backend, or only for the given event types
+export function useDataEvents(onChange: (event: DataEvent) => void, types?: string[]) {
+  useEffect(() => {
+    const unsubscribe = EventsOn("data:changed", (event: DataEvent) => {
+      if (!types || types.includes(event.type)) {
+        onChange(event);
+      }
+    });
+    return unsubscribe;
+  }, [onChange, types?.join(",")]);
+}
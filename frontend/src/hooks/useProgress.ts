@@ -0,0 +1,23 @@
+import { useEffect, useState } from "preact/hooks";
+import { EventsOn } from "../../wailsjs/runtime/runtime";
+import { systemService, Progress } from "../services/systemService";
+
+// Tracks the long operations running in the backend from their "progress" events
+export function useProgress(): Progress[] {
+  const [operations, setOperations] = useState<Progress[]>([]);
+
+  useEffect(() => {
+    // Operations started before the component mounted are only known from the backend
+    systemService.getOperations().then(setOperations).catch(() => {});
+
+    const unsubscribe = EventsOn("progress", (progress: Progress) => {
+      setOperations((current) => {
+        const others = current.filter((op) => op.token !== progress.token);
+        return progress.finished ? others : [...others, progress];
+      });
+    });
+    return unsubscribe;
+  }, []);
+
+  return operations;
+}
@@ -14,6 +14,7 @@ export interface CompressedFile {
   algorithm: string;
   ratio: string;
   spaceSaved: string;
+  encrypted: boolean;
 }
 
 export interface CompressionResult {
@@ -32,9 +33,10 @@ export interface BinFile {
 export const compressionService = {
   compress: async (
     filename: string,
-    algorithm: string
+    algorithm: string,
+    keepOriginal: boolean
   ): Promise<CompressionResult> => {
-    const result = await CompressFile(filename, algorithm);
+    const result = await CompressFile(filename, algorithm, keepOriginal);
     return {
       outputFile: result.outputFile as string,
       originalSize: result.originalSize as number,
@@ -44,8 +46,11 @@ export const compressionService = {
     };
   },
 
-  compressAll: async (algorithm: string): Promise<CompressionResult> => {
-    const result = await CompressAllFiles(algorithm);
+  compressAll: async (
+    algorithm: string,
+    encrypt: boolean
+  ): Promise<CompressionResult> => {
+    const result = await CompressAllFiles(algorithm, encrypt);
     return {
       outputFile: result.outputFile as string,
       originalSize: result.originalSize as number,
@@ -75,6 +80,7 @@ export const compressionService = {
       algorithm: f.algorithm as string,
       ratio: f.ratio as string,
       spaceSaved: f.spaceSaved as string,
+      encrypted: f.encrypted as boolean,
     }));
   },
 
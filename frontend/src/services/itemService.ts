@@ -1,4 +1,4 @@
-import { AddItem, GetItem, DeleteItem, GetAllItems, SearchItems } from "../../wailsjs/go/main/App";
+import { AddItem, GetItem, DeleteItem, DeleteItemsWhere, GetAllItems, MergeItems, SearchItems, CountItems } from "../../wailsjs/go/main/App";
 
 export interface Item {
   id: number;
@@ -7,6 +7,18 @@ export interface Item {
   isDeleted?: boolean;
 }
 
+export interface ListOptions {
+  activeOnly?: boolean;
+  sortBy?: "id" | "name" | "price";
+  direction?: "asc" | "desc";
+}
+
+export interface ItemFilter {
+  priceEquals?: number;
+  namePrefix?: string;
+  createdBefore?: string;
+}
+
 export const itemService = {
   create: async (name: string, priceInCents: number): Promise<number> => {
     return AddItem(name, priceInCents);
@@ -25,8 +37,16 @@ export const itemService = {
     return DeleteItem(id);
   },
 
-  getAll: async (): Promise<Item[]> => {
-    const result = await GetAllItems();
+  deleteWhere: async (filter: ItemFilter): Promise<number[]> => {
+    return DeleteItemsWhere(filter as any);
+  },
+
+  merge: async (sourceId: number, targetId: number): Promise<Record<string, any>> => {
+    return MergeItems(sourceId, targetId);
+  },
+
+  getAll: async (options: ListOptions = {}): Promise<Item[]> => {
+    const result = await GetAllItems(options as any);
     return result as Item[];
   },
 
@@ -34,4 +54,8 @@ export const itemService = {
     const result = await SearchItems(pattern, algorithm);
     return result as Item[];
   },
+
+  count: async (activeOnly: boolean = true): Promise<number> => {
+    return CountItems(activeOnly);
+  },
 };
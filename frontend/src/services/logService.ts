@@ -1,14 +1,23 @@
-import { GetLogs, ClearLogs } from "../../wailsjs/go/main/App";
+import { GetLogs, GetLogsForEntity, ClearLogs } from "../../wailsjs/go/main/App";
 
 export interface LogEntry {
+  time: string;
   timestamp: string;
   level: string;
   message: string;
+  entity?: string;
+  id?: number;
+  operation?: string;
+  durationMs?: number;
 }
 
 export const logService = {
   getAll: async (): Promise<LogEntry[]> => {
-    return GetLogs();
+    return GetLogs("", 0, "");
+  },
+
+  getForEntity: async (entity: string, id: number): Promise<LogEntry[]> => {
+    return GetLogsForEntity(entity, id);
   },
 
   clear: async (): Promise<void> => {
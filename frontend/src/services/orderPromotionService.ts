@@ -2,8 +2,11 @@ import {
   CreateOrder,
   CreatePromotion,
   ApplyPromotionToOrder,
+  ApplyPromotionsToOrder,
+  CleanOrphanLinks,
   GetOrderWithPromotions,
   GetOrderPromotions,
+  GetPromotionUsageStats,
   RemovePromotionFromOrder,
 } from "../../wailsjs/go/main/App";
 
@@ -18,6 +21,8 @@ export interface OrderWithPromotions {
     name: string;
     totalPrice: number;
     itemCount: number;
+    appliedAt?: string;
+    appliedDiscount?: number;
   }>;
 }
 
@@ -34,6 +39,10 @@ export const orderPromotionService = {
     return ApplyPromotionToOrder(orderID, promotionID);
   },
 
+  applyPromotionsToOrder: async (orderID: number, promotionIDs: number[]): Promise<number[]> => {
+    return ApplyPromotionsToOrder(orderID, promotionIDs);
+  },
+
   getOrderWithPromotions: async (orderID: number): Promise<OrderWithPromotions> => {
     const result = await GetOrderWithPromotions(orderID);
     return {
@@ -53,4 +62,12 @@ export const orderPromotionService = {
   removePromotionFromOrder: async (orderID: number, promotionID: number): Promise<void> => {
     return RemovePromotionFromOrder(orderID, promotionID);
   },
+
+  getPromotionUsageStats: async (): Promise<Array<Record<string, any>>> => {
+    return GetPromotionUsageStats();
+  },
+
+  cleanOrphanLinks: async (): Promise<Record<string, number>> => {
+    return CleanOrphanLinks();
+  },
 };
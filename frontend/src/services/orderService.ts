@@ -1,4 +1,5 @@
-import { GetOrder, DeleteOrder, GetAllOrders } from "../../wailsjs/go/main/App";
+import { GetOrder, DeleteOrder, DuplicateOrder, GetAllOrders, CountOrders } from "../../wailsjs/go/main/App";
+import { ListOptions } from "./itemService";
 
 export interface Order {
   id: number;
@@ -27,8 +28,12 @@ export const orderService = {
     return DeleteOrder(id);
   },
 
-  getAll: async (): Promise<Order[]> => {
-    const result = await GetAllOrders();
+  duplicate: async (id: number): Promise<number> => {
+    return DuplicateOrder(id);
+  },
+
+  getAll: async (options: ListOptions = {}): Promise<Order[]> => {
+    const result = await GetAllOrders("", options as any);
     return result.map((item: any) => ({
       id: item.id,
       customer: item.customer,
@@ -39,4 +44,8 @@ export const orderService = {
       isDeleted: item.isDeleted,
     }));
   },
+
+  count: async (activeOnly: boolean = true): Promise<number> => {
+    return CountOrders(activeOnly);
+  },
 };
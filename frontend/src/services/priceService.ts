@@ -0,0 +1,11 @@
+import { FormatPrice, ParsePrice } from "../../wailsjs/go/main/App";
+
+export const priceService = {
+  format: async (cents: number, locale: string = ""): Promise<string> => {
+    return FormatPrice(cents, locale);
+  },
+
+  parse: async (text: string): Promise<number> => {
+    return ParsePrice(text);
+  },
+};
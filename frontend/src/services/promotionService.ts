@@ -1,4 +1,5 @@
-import { GetPromotion, DeletePromotion, GetAllPromotions } from "../../wailsjs/go/main/App";
+import { GetPromotion, DeletePromotion, GetAllPromotions, CountPromotions } from "../../wailsjs/go/main/App";
+import { ListOptions } from "./itemService";
 
 export interface Promotion {
   id: number;
@@ -25,8 +26,8 @@ export const promotionService = {
     return DeletePromotion(id);
   },
 
-  getAll: async (): Promise<Promotion[]> => {
-    const result = await GetAllPromotions();
+  getAll: async (options: ListOptions = {}): Promise<Promotion[]> => {
+    const result = await GetAllPromotions(options as any);
     return result.map((item: any) => ({
       id: item.id,
       name: item.name,
@@ -36,4 +37,8 @@ export const promotionService = {
       isDeleted: item.isDeleted,
     }));
   },
+
+  count: async (activeOnly: boolean = true): Promise<number> => {
+    return CountPromotions(activeOnly);
+  },
 };
@@ -6,7 +6,22 @@ import {
   GetPromotionIndexContents,
   GetEncryptionEnabled,
   SetEncryptionEnabled,
-  Compact
+  Compact,
+  CompactPreview,
+  GetMetrics,
+  DumpFileHex,
+  InspectFile,
+  GetIndexStructure,
+  GenerateSeedData,
+  GetOperations,
+  CancelOperation,
+  RebuildIndexes,
+  ListTrash,
+  RestoreFromTrash,
+  Undo,
+  GetUndoHistory,
+  GetFileStats,
+  GetStorageReport
 } from "../../wailsjs/go/main/App";
 
 export interface CompactResult {
@@ -16,6 +31,91 @@ export interface CompactResult {
   ordersRemoved: number;
   promotionsRemoved: number;
   orderPromotionsRemoved: number;
+  deletedItemIds: number[];
+  removedOrderIds: number[];
+  removedPromotionIds: number[];
+  affectedOrderIds: number[];
+  affectedPromotionIds: number[];
+  reclaimedBytes: number;
+}
+
+export interface TrashEntry {
+  name: string;
+  deletedAt: string;
+  files: string[];
+  size: number;
+}
+
+export interface Metrics {
+  operations: Record<string, number>;
+  errors: Record<string, number>;
+  panics: Record<string, number>;
+  operationSeconds: Record<string, number>;
+  bytesWritten: number;
+  indexedReads: number;
+  fallbackScans: number;
+  indexHitRatio: number;
+  compactions: number;
+  compactionSeconds: number;
+  maxCompactionSeconds: number;
+  lastCompactionSeconds: number;
+}
+
+export interface FileStats {
+  file: string;
+  entitiesCount: number;
+  tombstoneCount: number;
+  activeCount: number;
+  nextId: number;
+  sizeBytes: number;
+  headerBytes: number;
+  formatVersion: number;
+  idSize: number;
+}
+
+export interface DirUsage {
+  bytes: number;
+  files: number;
+}
+
+export interface FileUsage {
+  file: string;
+  sizeBytes: number;
+  headerBytes: number;
+  records: number;
+  activeRecords: number;
+  liveBytes: number;
+  tombstoneBytes: number;
+  fragmentation: number;
+  shouldCompact: boolean;
+}
+
+export interface StorageReport {
+  bin: DirUsage;
+  indexes: DirUsage;
+  compressed: DirUsage;
+  keys: DirUsage;
+  logs: DirUsage;
+  trash: DirUsage;
+  totalBytes: number;
+  files: FileUsage[];
+}
+
+export interface Progress {
+  token: string;
+  operation: string;
+  done: number;
+  total: number;
+  finished: boolean;
+}
+
+export interface HexDump {
+  file: string;
+  size: number;
+  offset: number;
+  length: number;
+  lines: { offset: number; hex: string; ascii: string; regions?: string[] }[];
+  regions: { start: number; end: number; label: string }[];
 }
 
 export const systemService = {
@@ -23,8 +123,8 @@ export const systemService = {
     return DeleteAllFiles();
   },
 
-  populateInventory: async (): Promise<void> => {
-    return PopulateInventory();
+  populateInventory: async (seed = 0, strict = false): Promise<Record<string, any>> => {
+    return PopulateInventory(seed, strict);
   },
 
   getIndexContents: async (): Promise<any> => {
@@ -50,4 +150,64 @@ export const systemService = {
   compact: async (): Promise<CompactResult> => {
     return Compact();
   },
+
+  compactPreview: async (): Promise<CompactResult> => {
+    return CompactPreview() as Promise<CompactResult>;
+  },
+
+  getMetrics: async (): Promise<Metrics> => {
+    return GetMetrics() as Promise<Metrics>;
+  },
+
+  dumpFileHex: async (filename: string, offset = 0, length = 0): Promise<HexDump> => {
+    return DumpFileHex(filename, offset, length) as Promise<HexDump>;
+  },
+
+  inspectFile: async (filename: string): Promise<any> => {
+    return InspectFile(filename);
+  },
+
+  getIndexStructure: async (entity: string): Promise<any> => {
+    return GetIndexStructure(entity);
+  },
+
+  generateSeedData: async (items: number, orders: number, promotions: number, seed = 0): Promise<any> => {
+    return GenerateSeedData(items, orders, promotions, seed);
+  },
+
+  getOperations: async (): Promise<Progress[]> => {
+    return GetOperations() as Promise<Progress[]>;
+  },
+
+  cancelOperation: async (token: string): Promise<void> => {
+    return CancelOperation(token);
+  },
+
+  rebuildIndexes: async (): Promise<void> => {
+    return RebuildIndexes();
+  },
+
+  listTrash: async (): Promise<TrashEntry[]> => {
+    return ListTrash() as Promise<TrashEntry[]>;
+  },
+
+  restoreFromTrash: async (entry: string): Promise<any> => {
+    return RestoreFromTrash(entry);
+  },
+
+  undo: async (): Promise<Record<string, any>> => {
+    return Undo();
+  },
+
+  getUndoHistory: async (): Promise<Record<string, any>[]> => {
+    return GetUndoHistory();
+  },
+
+  getFileStats: async (): Promise<FileStats[]> => {
+    return GetFileStats() as Promise<FileStats[]>;
+  },
+
+  getStorageReport: async (): Promise<StorageReport> => {
+    return GetStorageReport() as Promise<StorageReport>;
+  },
 };
@@ -0,0 +1,37 @@
+import { CreateTemplate, DeleteTemplate, GetAllTemplates, CreateOrderFromTemplate } from "../../wailsjs/go/main/App";
+import { ListOptions } from "./itemService";
+
+export interface Template {
+  id: number;
+  name: string;
+  totalPrice: number;
+  itemCount: number;
+  itemIDs: number[];
+  isDeleted?: boolean;
+}
+
+export const templateService = {
+  create: async (name: string, itemIDs: number[]): Promise<number> => {
+    return CreateTemplate(name, itemIDs);
+  },
+
+  delete: async (id: number): Promise<void> => {
+    return DeleteTemplate(id);
+  },
+
+  getAll: async (options: ListOptions = {}): Promise<Template[]> => {
+    const result = await GetAllTemplates(options as any);
+    return result.map((item: any) => ({
+      id: item.id,
+      name: item.name,
+      totalPrice: item.totalPrice,
+      itemCount: item.itemCount,
+      itemIDs: item.itemIDs,
+      isDeleted: item.isDeleted,
+    }));
+  },
+
+  createOrder: async (templateId: number, customerName: string): Promise<number> => {
+    return CreateOrderFromTemplate(templateId, customerName);
+  },
+};
@@ -58,7 +58,34 @@ export const COMPACT_SELECT_STYLE = {
   flex: 1,
 };
 
+export type ErrorCode =
+  | "NotFound"
+  | "Deleted"
+  | "Validation"
+  | "Corruption"
+  | "Conflict"
+  | "IO"
+  | "Internal";
+
+// AppError is what a failed App binding rejects with
+export interface AppError {
+  code: ErrorCode;
+  message: string;
+  details: Record<string, unknown>;
+}
+
+export const isAppError = (err: unknown): err is AppError => {
+  return typeof err === "object" && err !== null && "code" in err && "message" in err;
+};
+
+export const errorCode = (err: unknown): ErrorCode => {
+  return isAppError(err) ? err.code : "Internal";
+};
+
 export const formatError = (err: unknown): string => {
+  if (isAppError(err)) {
+    return err.message;
+  }
   return err instanceof Error ? err.message : String(err);
 };
 
@@ -4,4 +4,5 @@ export const toast = {
   success: (message: string) => EventsEmit("toast:success", message),
   error: (message: string) => EventsEmit("toast:error", message),
   warning: (message: string) => EventsEmit("toast:warning", message),
+  info: (message: string) => EventsEmit("toast:info", message),
 };
@@ -10,9 +10,9 @@ export function ClearLogs():Promise<void>;
 
 export function Compact():Promise<main.CompactResult>;
 
-export function CompressAllFiles(arg1:string):Promise<Record<string, any>>;
+export function CompressAllFiles(arg1:string,arg2:boolean):Promise<Record<string, any>>;
 
-export function CompressFile(arg1:string,arg2:string):Promise<Record<string, any>>;
+export function CompressFile(arg1:string,arg2:string,arg3:boolean):Promise<Record<string, any>>;
 
 export function CreateOrder(arg1:string,arg2:Array<number>):Promise<number>;
 
@@ -32,7 +32,7 @@ export function DeletePromotion(arg1:number):Promise<void>;
 
 export function GetAllItems():Promise<Array<Record<string, any>>>;
 
-export function GetAllOrders():Promise<Array<Record<string, any>>>;
+export function GetAllOrders(arg1:string):Promise<Array<Record<string, any>>>;
 
 export function GetAllPromotions():Promise<Array<Record<string, any>>>;
 
@@ -18,12 +18,12 @@ export function Compact() {
   return window['go']['main']['App']['Compact']();
 }
 
-export function CompressAllFiles(arg1) {
-  return window['go']['main']['App']['CompressAllFiles'](arg1);
+export function CompressAllFiles(arg1, arg2) {
+  return window['go']['main']['App']['CompressAllFiles'](arg1, arg2);
 }
 
-export function CompressFile(arg1, arg2) {
-  return window['go']['main']['App']['CompressFile'](arg1, arg2);
+export function CompressFile(arg1, arg2, arg3) {
+  return window['go']['main']['App']['CompressFile'](arg1, arg2, arg3);
 }
 
 export function CreateOrder(arg1, arg2) {
@@ -62,8 +62,8 @@ export function GetAllItems() {
   return window['go']['main']['App']['GetAllItems']();
 }
 
-export function GetAllOrders() {
-  return window['go']['main']['App']['GetAllOrders']();
+export function GetAllOrders(arg1) {
+  return window['go']['main']['App']['GetAllOrders'](arg1);
 }
 
 export function GetAllPromotions() {
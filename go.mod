@@ -2,7 +2,14 @@ module BinaryCRUD
 
 go 1.23
 
-require github.com/wailsapp/wails/v2 v2.10.2
+require (
+	github.com/klauspost/compress v1.18.0
+	github.com/wailsapp/wails/v2 v2.10.2
+	golang.org/x/sys v0.30.0
+	golang.org/x/text v0.22.0
+	google.golang.org/grpc v1.70.0
+	google.golang.org/protobuf v1.36.5
+)
 
 require (
 	github.com/bep/debounce v1.2.1 // indirect
@@ -30,8 +37,7 @@ require (
 	github.com/wailsapp/mimetype v1.4.1 // indirect
 	golang.org/x/crypto v0.33.0 // indirect
 	golang.org/x/net v0.35.0 // indirect
-	golang.org/x/sys v0.30.0 // indirect
-	golang.org/x/text v0.22.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20241202173237-19429a94021a // indirect
 )
 
 // replace github.com/wailsapp/wails/v2 v2.10.2 => /home/user/go/pkg/mod
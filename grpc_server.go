@@ -0,0 +1,402 @@
+package main
+
+import (
+	"BinaryCRUD/backend/dao"
+	"BinaryCRUD/backend/utils"
+	pb "BinaryCRUD/proto/binarycrudpb"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// grpcServer is the gRPC API served next to the window, started in startup and stopped in shutdown
+type grpcServer struct {
+	server *grpc.Server
+	addr   string
+	done   chan struct{} // closed once Serve returned
+}
+
+// startGRPCServer serves the gRPC services of proto/binarycrud.proto on a.grpcAddr in the background
+// The address is bound before returning, so a port in use is reported here instead of in the log
+func (a *App) startGRPCServer() error {
+	if a.grpcAPI != nil {
+		return fmt.Errorf("the gRPC API is already served on %s", a.grpcAPI.addr)
+	}
+	listener, err := net.Listen("tcp", a.grpcAddr)
+	if err != nil {
+		return fmt.Errorf("failed to serve the gRPC API: %w", err)
+	}
+
+	server := grpc.NewServer(
+		grpc.UnaryInterceptor(a.unaryInterceptor),
+		grpc.StreamInterceptor(a.streamInterceptor),
+	)
+	pb.RegisterItemServiceServer(server, &itemService{app: a})
+	pb.RegisterOrderServiceServer(server, &orderService{app: a})
+	pb.RegisterPromotionServiceServer(server, &promotionService{app: a})
+
+	api := &grpcServer{server: server, addr: listener.Addr().String(), done: make(chan struct{})}
+	a.grpcAPI = api
+
+	go func() {
+		defer close(api.done)
+		if err := server.Serve(listener); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+			a.logger.Error("gRPC API stopped: " + err.Error())
+		}
+	}()
+
+	a.logger.Info(fmt.Sprintf("Serving gRPC API on %s", api.addr))
+	return nil
+}
+
+// stopGRPCServer stops the gRPC API, letting calls in flight finish for up to APIShutdownTimeout
+func (a *App) stopGRPCServer() {
+	api := a.grpcAPI
+	if api == nil {
+		return
+	}
+	a.grpcAPI = nil
+
+	stopped := make(chan struct{})
+	go func() {
+		api.server.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(APIShutdownTimeout):
+		a.logger.Warn("gRPC API did not shut down cleanly")
+		api.server.Stop()
+	}
+	<-api.done
+	a.logger.Info("gRPC API stopped")
+}
+
+// grpcPackage is the proto package of the services, left out of the operation names
+const grpcPackage = "binarycrud.v1"
+
+// grpcOperation names an RPC in the metrics and logs, e.g. ItemService/Get
+func grpcOperation(fullMethod string) string {
+	return strings.TrimPrefix(fullMethod, "/"+grpcPackage+".")
+}
+
+// unaryInterceptor runs one call at a time like the REST API, tracks it like a binding and maps its error to a gRPC status
+func (a *App) unaryInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (_ any, err error) {
+	a.remoteMu.Lock()
+	defer a.remoteMu.Unlock()
+	defer func() { err = grpcError(err) }()
+	defer a.track(grpcOperation(info.FullMethod), time.Now(), &err)
+	return handler(ctx, req)
+}
+
+// streamInterceptor is unaryInterceptor for the streaming GetAll calls
+func (a *App) streamInterceptor(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	a.remoteMu.Lock()
+	defer a.remoteMu.Unlock()
+	defer func() { err = grpcError(err) }()
+	defer a.track(grpcOperation(info.FullMethod), time.Now(), &err)
+	return handler(srv, stream)
+}
+
+// grpcError converts an error to a gRPC status following its error code, like writeError does for HTTP statuses
+// Missing and deleted records are NotFound, read-only mode PermissionDenied, other conflicts FailedPrecondition,
+// rejected input InvalidArgument, corrupt files DataLoss, IO failures Internal and everything else Unknown
+func grpcError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	code := codes.Unknown
+	switch utils.ErrorCodeOf(err) {
+	case utils.CodeNotFound, utils.CodeDeleted:
+		code = codes.NotFound
+	case utils.CodeValidation:
+		code = codes.InvalidArgument
+	case utils.CodeConflict:
+		code = codes.FailedPrecondition
+		if isReadOnlyError(err) {
+			code = codes.PermissionDenied
+		}
+	case utils.CodeCorruption:
+		code = codes.DataLoss
+	case utils.CodeIO:
+		code = codes.Internal
+	}
+	return status.Error(code, err.Error())
+}
+
+// itemMessage converts an item to its gRPC message
+func (a *App) itemMessage(item *dao.Item) *pb.Item {
+	quantity, tracked := itemStock(item)
+	currency := itemCurrency(item)
+	if currency == "" {
+		currency = a.currencyRates.Base
+	}
+	return &pb.Item{
+		Id:           item.ID,
+		Name:         item.Name,
+		PriceInCents: item.PriceInCents,
+		Currency:     currency,
+		TracksStock:  tracked,
+		Stock:        quantity,
+		IsDeleted:    item.IsDeleted,
+	}
+}
+
+// orderMessage converts an order and the promotions applied to it to its gRPC message
+func (a *App) orderMessage(order *dao.Collection) (*pb.Order, error) {
+	applied, err := a.orderPromotionDAO.GetByOrderID(order.ID)
+	if err != nil {
+		return nil, err
+	}
+	promotionIDs := make([]uint64, len(applied))
+	for i, op := range applied {
+		promotionIDs[i] = op.PromotionID
+	}
+
+	message := &pb.Order{
+		Id:           order.ID,
+		CustomerName: order.OwnerOrName,
+		TotalPrice:   order.TotalPrice,
+		ItemIds:      order.ItemIDs,
+		Status:       utils.OrderStatusName(orderStatus(order)),
+		PromotionIds: promotionIDs,
+		IsDeleted:    order.IsDeleted,
+	}
+	if createdAt, ok := orderCreatedAt(order); ok {
+		message.CreatedAt = createdAt.Format(time.RFC3339)
+	}
+	return message, nil
+}
+
+// promotionMessage converts a promotion to its gRPC message
+func promotionMessage(promotion *dao.Collection) *pb.Promotion {
+	discount := promotionDiscount(promotion)
+	return &pb.Promotion{
+		Id:            promotion.ID,
+		Name:          promotion.OwnerOrName,
+		TotalPrice:    promotion.TotalPrice,
+		ItemIds:       promotion.ItemIDs,
+		DiscountType:  utils.DiscountTypeName(discount.Type),
+		DiscountValue: discount.Value,
+		IsDeleted:     promotion.IsDeleted,
+	}
+}
+
+// itemService implements ItemService, changing data through the same App methods as the bindings
+type itemService struct {
+	pb.UnimplementedItemServiceServer
+	app *App
+}
+
+// get reads an item and converts it to its gRPC message
+func (s *itemService) get(id uint64) (*pb.Item, error) {
+	item, err := s.app.itemDAO.ReadItem(id)
+	if err != nil {
+		return nil, err
+	}
+	return s.app.itemMessage(item), nil
+}
+
+// Create handles ItemService/Create
+func (s *itemService) Create(ctx context.Context, req *pb.CreateItemRequest) (*pb.Item, error) {
+	id, err := s.app.AddItem(req.Name, req.PriceInCents)
+	if err != nil {
+		return nil, err
+	}
+	return s.get(id)
+}
+
+// Get handles ItemService/Get
+func (s *itemService) Get(ctx context.Context, req *pb.Id) (*pb.Item, error) {
+	return s.get(req.Id)
+}
+
+// Update handles ItemService/Update
+func (s *itemService) Update(ctx context.Context, req *pb.UpdateItemRequest) (*pb.Item, error) {
+	if _, err := s.app.UpdateItem(req.Id, req.Name, req.PriceInCents); err != nil {
+		return nil, err
+	}
+	return s.get(req.Id)
+}
+
+// Delete handles ItemService/Delete
+func (s *itemService) Delete(ctx context.Context, req *pb.Id) (*pb.Empty, error) {
+	if err := s.app.DeleteItem(req.Id); err != nil {
+		return nil, err
+	}
+	return &pb.Empty{}, nil
+}
+
+// GetAll handles ItemService/GetAll
+func (s *itemService) GetAll(req *pb.Empty, stream grpc.ServerStreamingServer[pb.Item]) error {
+	items, err := s.app.itemDAO.GetAll()
+	if err != nil {
+		return err
+	}
+	for i := range items {
+		if err := stream.Send(s.app.itemMessage(&items[i])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// orderService implements OrderService, changing data through the same App methods as the bindings
+type orderService struct {
+	pb.UnimplementedOrderServiceServer
+	app *App
+}
+
+// get reads an order and converts it to its gRPC message
+func (s *orderService) get(id uint64) (*pb.Order, error) {
+	order, err := s.app.orderDAO.Read(id)
+	if err != nil {
+		return nil, err
+	}
+	return s.app.orderMessage(order)
+}
+
+// Create handles OrderService/Create
+func (s *orderService) Create(ctx context.Context, req *pb.CreateOrderRequest) (*pb.Order, error) {
+	id, err := s.app.CreateOrder(req.CustomerName, req.ItemIds)
+	if err != nil {
+		return nil, err
+	}
+	return s.get(id)
+}
+
+// Get handles OrderService/Get
+func (s *orderService) Get(ctx context.Context, req *pb.Id) (*pb.Order, error) {
+	return s.get(req.Id)
+}
+
+// Delete handles OrderService/Delete
+func (s *orderService) Delete(ctx context.Context, req *pb.Id) (*pb.Empty, error) {
+	if err := s.app.DeleteOrder(req.Id); err != nil {
+		return nil, err
+	}
+	return &pb.Empty{}, nil
+}
+
+// GetAll handles OrderService/GetAll
+func (s *orderService) GetAll(req *pb.ListOrdersRequest, stream grpc.ServerStreamingServer[pb.Order]) error {
+	filter := -1
+	if req.Status != "" {
+		parsed, err := utils.ParseOrderStatus(req.Status)
+		if err != nil {
+			return utils.WithCode(utils.CodeValidation, err, nil)
+		}
+		filter = int(parsed)
+	}
+
+	orders, err := s.app.orderDAO.GetAll()
+	if err != nil {
+		return err
+	}
+	for _, order := range orders {
+		if filter >= 0 && int(orderStatus(order)) != filter {
+			continue
+		}
+		message, err := s.app.orderMessage(order)
+		if err != nil {
+			return err
+		}
+		if err := stream.Send(message); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AddItem handles OrderService/AddItem
+func (s *orderService) AddItem(ctx context.Context, req *pb.OrderItemRequest) (*pb.Order, error) {
+	if _, err := s.app.AddItemToOrder(req.OrderId, req.ItemId); err != nil {
+		return nil, err
+	}
+	return s.get(req.OrderId)
+}
+
+// RemoveItem handles OrderService/RemoveItem
+func (s *orderService) RemoveItem(ctx context.Context, req *pb.OrderItemRequest) (*pb.Order, error) {
+	if _, err := s.app.RemoveItemFromOrder(req.OrderId, req.ItemId); err != nil {
+		return nil, err
+	}
+	return s.get(req.OrderId)
+}
+
+// ApplyPromotion handles OrderService/ApplyPromotion
+func (s *orderService) ApplyPromotion(ctx context.Context, req *pb.OrderPromotionRequest) (*pb.Order, error) {
+	if err := s.app.ApplyPromotionToOrder(req.OrderId, req.PromotionId); err != nil {
+		return nil, err
+	}
+	return s.get(req.OrderId)
+}
+
+// RemovePromotion handles OrderService/RemovePromotion
+func (s *orderService) RemovePromotion(ctx context.Context, req *pb.OrderPromotionRequest) (*pb.Order, error) {
+	if err := s.app.RemovePromotionFromOrder(req.OrderId, req.PromotionId); err != nil {
+		return nil, err
+	}
+	return s.get(req.OrderId)
+}
+
+// promotionService implements PromotionService, changing data through the same App methods as the bindings
+type promotionService struct {
+	pb.UnimplementedPromotionServiceServer
+	app *App
+}
+
+// get reads a promotion and converts it to its gRPC message
+func (s *promotionService) get(id uint64) (*pb.Promotion, error) {
+	promotion, err := s.app.promotionDAO.Read(id)
+	if err != nil {
+		return nil, err
+	}
+	return promotionMessage(promotion), nil
+}
+
+// Create handles PromotionService/Create
+func (s *promotionService) Create(ctx context.Context, req *pb.CreatePromotionRequest) (*pb.Promotion, error) {
+	id, err := s.app.CreatePromotion(req.Name, req.ItemIds)
+	if err != nil {
+		return nil, err
+	}
+	return s.get(id)
+}
+
+// Get handles PromotionService/Get
+func (s *promotionService) Get(ctx context.Context, req *pb.Id) (*pb.Promotion, error) {
+	return s.get(req.Id)
+}
+
+// Delete handles PromotionService/Delete
+func (s *promotionService) Delete(ctx context.Context, req *pb.Id) (*pb.Empty, error) {
+	if err := s.app.DeletePromotion(req.Id); err != nil {
+		return nil, err
+	}
+	return &pb.Empty{}, nil
+}
+
+// GetAll handles PromotionService/GetAll
+func (s *promotionService) GetAll(req *pb.Empty, stream grpc.ServerStreamingServer[pb.Promotion]) error {
+	promotions, err := s.app.promotionDAO.GetAll()
+	if err != nil {
+		return err
+	}
+	for _, promotion := range promotions {
+		if err := stream.Send(promotionMessage(promotion)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
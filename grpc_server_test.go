@@ -0,0 +1,277 @@
+package main
+
+import (
+	"BinaryCRUD/backend/utils"
+	pb "BinaryCRUD/proto/binarycrudpb"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+// grpcClients holds clients of every service served by a test app
+type grpcClients struct {
+	items      pb.ItemServiceClient
+	orders     pb.OrderServiceClient
+	promotions pb.PromotionServiceClient
+}
+
+// newTestGRPC serves the gRPC API of a new test app on a free port and connects to it
+func newTestGRPC(t *testing.T) (*App, grpcClients) {
+	t.Helper()
+	app := newTestApp(t)
+	config := app.GetConfig()
+	config.AutoCompact = false
+	if _, err := app.UpdateConfig(config); err != nil {
+		t.Fatalf("Failed to disable automatic compaction: %v", err)
+	}
+
+	app.grpcAddr = "127.0.0.1:0"
+	if err := app.startGRPCServer(); err != nil {
+		t.Fatalf("Failed to start the gRPC API: %v", err)
+	}
+	t.Cleanup(app.stopGRPCServer)
+
+	conn, err := grpc.NewClient(app.grpcAPI.addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return app, grpcClients{
+		items:      pb.NewItemServiceClient(conn),
+		orders:     pb.NewOrderServiceClient(conn),
+		promotions: pb.NewPromotionServiceClient(conn),
+	}
+}
+
+// expectCode fails the test unless err is a gRPC status with code
+func expectCode(t *testing.T, err error, code codes.Code) {
+	t.Helper()
+	if status.Code(err) != code {
+		t.Fatalf("Expected %s, got %v", code, err)
+	}
+}
+
+// receiveAll reads a GetAll stream to its end
+func receiveAll[T any](t *testing.T, stream grpc.ServerStreamingClient[T]) []*T {
+	t.Helper()
+	var messages []*T
+	for {
+		message, err := stream.Recv()
+		if err == io.EOF {
+			return messages
+		}
+		if err != nil {
+			t.Fatalf("Failed to receive: %v", err)
+		}
+		messages = append(messages, message)
+	}
+}
+
+func TestGRPCItemService(t *testing.T) {
+	_, clients := newTestGRPC(t)
+	ctx := context.Background()
+
+	burger, err := clients.items.Create(ctx, &pb.CreateItemRequest{Name: "Burger", PriceInCents: 1250})
+	if err != nil {
+		t.Fatalf("Failed to create item: %v", err)
+	}
+	if burger.Name != "Burger" || burger.PriceInCents != 1250 || burger.Currency == "" {
+		t.Errorf("Unexpected item %v", burger)
+	}
+	fries, err := clients.items.Create(ctx, &pb.CreateItemRequest{Name: "Fries", PriceInCents: 450})
+	if err != nil {
+		t.Fatalf("Failed to create item: %v", err)
+	}
+
+	got, err := clients.items.Get(ctx, &pb.Id{Id: burger.Id})
+	if err != nil || got.Name != "Burger" {
+		t.Fatalf("Expected Burger, got %v (err %v)", got, err)
+	}
+	updated, err := clients.items.Update(ctx, &pb.UpdateItemRequest{Id: burger.Id, Name: "Cheeseburger", PriceInCents: 1350})
+	if err != nil || updated.Name != "Cheeseburger" || updated.PriceInCents != 1350 {
+		t.Fatalf("Expected the updated item, got %v (err %v)", updated, err)
+	}
+
+	if _, err := clients.items.Delete(ctx, &pb.Id{Id: fries.Id}); err != nil {
+		t.Fatalf("Failed to delete item: %v", err)
+	}
+	_, err = clients.items.Get(ctx, &pb.Id{Id: fries.Id})
+	expectCode(t, err, codes.NotFound)
+
+	stream, err := clients.items.GetAll(ctx, &pb.Empty{})
+	if err != nil {
+		t.Fatalf("Failed to list items: %v", err)
+	}
+	items := receiveAll(t, stream)
+	if len(items) != 2 || items[0].Name != "Cheeseburger" || !items[1].IsDeleted {
+		t.Errorf("Expected Cheeseburger and the deleted Fries, got %v", items)
+	}
+}
+
+func TestGRPCOrderAndPromotionServices(t *testing.T) {
+	app, clients := newTestGRPC(t)
+	ctx := context.Background()
+
+	burger, err := app.AddItem("Burger", 1250)
+	if err != nil {
+		t.Fatalf("Failed to add item: %v", err)
+	}
+	fries, err := app.AddItem("Fries", 450)
+	if err != nil {
+		t.Fatalf("Failed to add item: %v", err)
+	}
+
+	order, err := clients.orders.Create(ctx, &pb.CreateOrderRequest{CustomerName: "Alice", ItemIds: []uint64{burger}})
+	if err != nil {
+		t.Fatalf("Failed to create order: %v", err)
+	}
+	if order.CustomerName != "Alice" || order.TotalPrice != 1250 || order.Status != "pending" || order.CreatedAt == "" {
+		t.Errorf("Unexpected order %v", order)
+	}
+
+	order, err = clients.orders.AddItem(ctx, &pb.OrderItemRequest{OrderId: order.Id, ItemId: fries})
+	if err != nil || len(order.ItemIds) != 2 {
+		t.Fatalf("Expected 2 items after adding fries, got %v (err %v)", order, err)
+	}
+	order, err = clients.orders.RemoveItem(ctx, &pb.OrderItemRequest{OrderId: order.Id, ItemId: fries})
+	if err != nil || len(order.ItemIds) != 1 {
+		t.Fatalf("Expected 1 item after removing fries, got %v (err %v)", order, err)
+	}
+
+	promotion, err := clients.promotions.Create(ctx, &pb.CreatePromotionRequest{Name: "Combo", ItemIds: []uint64{burger, fries}})
+	if err != nil {
+		t.Fatalf("Failed to create promotion: %v", err)
+	}
+	if promotion.Name != "Combo" || promotion.TotalPrice != 1700 || promotion.DiscountType != "none" {
+		t.Errorf("Unexpected promotion %v", promotion)
+	}
+	if got, err := clients.promotions.Get(ctx, &pb.Id{Id: promotion.Id}); err != nil || got.Id != promotion.Id {
+		t.Fatalf("Expected promotion %d, got %v (err %v)", promotion.Id, got, err)
+	}
+
+	order, err = clients.orders.ApplyPromotion(ctx, &pb.OrderPromotionRequest{OrderId: order.Id, PromotionId: promotion.Id})
+	if err != nil || len(order.PromotionIds) != 1 || order.PromotionIds[0] != promotion.Id {
+		t.Fatalf("Expected the applied promotion, got %v (err %v)", order, err)
+	}
+	order, err = clients.orders.RemovePromotion(ctx, &pb.OrderPromotionRequest{OrderId: order.Id, PromotionId: promotion.Id})
+	if err != nil || len(order.PromotionIds) != 0 {
+		t.Fatalf("Expected no applied promotions, got %v (err %v)", order, err)
+	}
+
+	if _, err := clients.orders.Create(ctx, &pb.CreateOrderRequest{CustomerName: "Bob", ItemIds: []uint64{fries}}); err != nil {
+		t.Fatalf("Failed to create order: %v", err)
+	}
+	if _, err := app.SetOrderStatus(order.Id, "paid"); err != nil {
+		t.Fatalf("Failed to pay order: %v", err)
+	}
+	stream, err := clients.orders.GetAll(ctx, &pb.ListOrdersRequest{})
+	if err != nil {
+		t.Fatalf("Failed to list orders: %v", err)
+	}
+	if orders := receiveAll(t, stream); len(orders) != 2 {
+		t.Errorf("Expected 2 orders, got %d", len(orders))
+	}
+	stream, err = clients.orders.GetAll(ctx, &pb.ListOrdersRequest{Status: "paid"})
+	if err != nil {
+		t.Fatalf("Failed to list orders: %v", err)
+	}
+	if orders := receiveAll(t, stream); len(orders) != 1 || orders[0].Id != order.Id {
+		t.Errorf("Expected only the paid order, got %v", orders)
+	}
+	stream, err = clients.orders.GetAll(ctx, &pb.ListOrdersRequest{Status: "lost"})
+	if err == nil {
+		_, err = stream.Recv()
+	}
+	expectCode(t, err, codes.InvalidArgument)
+
+	promotionStream, err := clients.promotions.GetAll(ctx, &pb.Empty{})
+	if err != nil {
+		t.Fatalf("Failed to list promotions: %v", err)
+	}
+	if promotions := receiveAll(t, promotionStream); len(promotions) != 1 {
+		t.Errorf("Expected 1 promotion, got %d", len(promotions))
+	}
+
+	if _, err := clients.promotions.Delete(ctx, &pb.Id{Id: promotion.Id}); err != nil {
+		t.Fatalf("Failed to delete promotion: %v", err)
+	}
+	_, err = clients.promotions.Get(ctx, &pb.Id{Id: promotion.Id})
+	expectCode(t, err, codes.NotFound)
+	if _, err := clients.orders.Delete(ctx, &pb.Id{Id: order.Id}); err != nil {
+		t.Fatalf("Failed to delete order: %v", err)
+	}
+	_, err = clients.orders.Get(ctx, &pb.Id{Id: order.Id})
+	expectCode(t, err, codes.NotFound)
+}
+
+func TestGRPCErrorCodes(t *testing.T) {
+	app, clients := newTestGRPC(t)
+	ctx := context.Background()
+
+	_, err := clients.items.Get(ctx, &pb.Id{Id: 99})
+	expectCode(t, err, codes.NotFound)
+	_, err = clients.items.Create(ctx, &pb.CreateItemRequest{Name: "", PriceInCents: 100})
+	expectCode(t, err, codes.InvalidArgument)
+
+	app.readOnly = true
+	_, err = clients.items.Create(ctx, &pb.CreateItemRequest{Name: "Burger", PriceInCents: 100})
+	expectCode(t, err, codes.PermissionDenied)
+}
+
+func TestGRPCErrorMapping(t *testing.T) {
+	cases := []struct {
+		name string
+		err  error
+		code codes.Code
+	}{
+		{"not found", utils.WithCode(utils.CodeNotFound, errors.New("item not found"), nil), codes.NotFound},
+		{"deleted", utils.WithCode(utils.CodeDeleted, errors.New("deleted item id 1"), nil), codes.NotFound},
+		{"validation", fmt.Errorf("invalid name: %w", utils.ErrNameEmpty), codes.InvalidArgument},
+		{"conflict", errCompactionRunning, codes.FailedPrecondition},
+		{"read-only", (&App{readOnly: true}).checkWritable(), codes.PermissionDenied},
+		{"corruption", utils.WithCode(utils.CodeCorruption, errors.New("invalid magic bytes"), nil), codes.DataLoss},
+		{"io", utils.WithCode(utils.CodeIO, errors.New("disk full"), nil), codes.Internal},
+		{"internal", errors.New("something else"), codes.Unknown},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := grpcError(tc.err)
+			if status.Code(err) != tc.code {
+				t.Errorf("Expected %s, got %v", tc.code, err)
+			}
+			if status.Convert(err).Message() != tc.err.Error() {
+				t.Errorf("Expected message %q, got %q", tc.err.Error(), status.Convert(err).Message())
+			}
+		})
+	}
+	if grpcError(nil) != nil {
+		t.Error("Expected no status for a nil error")
+	}
+}
+
+func TestGRPCServerStops(t *testing.T) {
+	app, clients := newTestGRPC(t)
+	if err := app.startGRPCServer(); err == nil {
+		t.Error("Expected starting the gRPC API twice to fail")
+	}
+
+	app.stopGRPCServer()
+	if app.grpcAPI != nil {
+		t.Error("Expected the gRPC API to be cleared after stopping")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	_, err := clients.items.Get(ctx, &pb.Id{Id: 0})
+	expectCode(t, err, codes.Unavailable)
+}
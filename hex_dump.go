@@ -0,0 +1,423 @@
+package main
+
+import (
+	"BinaryCRUD/backend/utils"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// hexDumpLineWidth is the number of bytes shown on each line of a hex dump
+	hexDumpLineWidth = 16
+
+	// DefaultHexDumpLength is how many bytes DumpFileHex returns when no length is given
+	DefaultHexDumpLength = 1024
+
+	// MaxHexDumpLength bounds the bytes returned by one DumpFileHex call, larger files are paged with offset
+	MaxHexDumpLength = 64 * 1024
+)
+
+// HexRegion is a structure of a file (its header, a record, an index entry) spanning [Start, End)
+type HexRegion struct {
+	Start int64  `json:"start"`
+	End   int64  `json:"end"`
+	Label string `json:"label"`
+}
+
+// HexLine is one line of a hex dump
+type HexLine struct {
+	Offset  int64    `json:"offset"`
+	Hex     string   `json:"hex"`
+	ASCII   string   `json:"ascii"`
+	Regions []string `json:"regions,omitempty"` // labels of the regions starting on this line
+}
+
+// DumpFileHex returns a hex and ASCII view of length bytes of a .bin or .idx file from offset
+// Record boundaries found by the parsers are returned as regions, so the debug screens can show the on-disk bytes
+// Only the requested bytes are read; the structures before them are skipped by seeking from one length or
+// count field to the next, and only the ones overlapping the requested bytes are parsed
+func (a *App) DumpFileHex(filename string, offset, length int64) (_ map[string]any, err error) {
+	defer a.track("DumpFileHex", time.Now(), &err)
+
+	path, err := hexDumpPath(filename)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, utils.WithCode(utils.CodeNotFound, fmt.Errorf("file not found: %s", filename), map[string]any{"file": filename})
+		}
+		return nil, fmt.Errorf("failed to open %s: %w", filename, err)
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat %s: %w", filename, err)
+	}
+
+	size := info.Size()
+	if offset < 0 || (offset > 0 && offset >= size) {
+		return nil, utils.WithCode(utils.CodeValidation, fmt.Errorf("offset %d is outside %s (%d bytes)", offset, filename, size), map[string]any{"file": filename, "offset": offset})
+	}
+	if length <= 0 {
+		length = DefaultHexDumpLength
+	}
+	length = min(length, MaxHexDumpLength, size-offset)
+
+	window := hexWindow{file: file, size: size, start: offset, end: offset + length}
+	data, err := window.read(offset, int(length))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", filename, err)
+	}
+
+	var regions []HexRegion
+	if strings.HasSuffix(filename, ".idx") {
+		regions = indexRegions(filename, window)
+	} else {
+		regions = binRegions(filename, path, window)
+	}
+
+	return map[string]any{
+		"file":    filename,
+		"size":    size,
+		"offset":  offset,
+		"length":  length,
+		"lines":   hexLines(data, offset, regions),
+		"regions": regions,
+	}, nil
+}
+
+// hexWindow is the part [start, end) of a file that is dumped
+type hexWindow struct {
+	file       io.ReaderAt
+	size       int64
+	start, end int64
+}
+
+// overlaps reports whether [start, end) shares bytes with the window
+func (w hexWindow) overlaps(start, end int64) bool {
+	return end > w.start && start < w.end
+}
+
+// read reads n bytes of the file from offset
+func (w hexWindow) read(offset int64, n int) ([]byte, error) {
+	if offset+int64(n) > w.size {
+		return nil, io.ErrUnexpectedEOF
+	}
+	data := make([]byte, n)
+	if read, err := w.file.ReadAt(data, offset); read < n {
+		return nil, err
+	}
+	return data, nil
+}
+
+// hexDumpPath resolves a data or index file name to the path of its current generation
+func hexDumpPath(filename string) (string, error) {
+	if filename == "" || filepath.Base(filename) != filename {
+		return "", utils.WithCode(utils.CodeValidation, fmt.Errorf("invalid file name: %q", filename), map[string]any{"file": filename})
+	}
+
+	switch filepath.Ext(filename) {
+	case ".bin":
+		return utils.ResolveBinPath(utils.BinDir, filename), nil
+	case ".idx":
+		binName := strings.TrimSuffix(filename, ".idx") + ".bin"
+		return utils.IndexPathFromBinFile(utils.ResolveBinPath(utils.BinDir, binName)), nil
+	default:
+		return "", utils.WithCode(utils.CodeValidation, fmt.Errorf("only .bin and .idx files can be dumped: %s", filename), map[string]any{"file": filename})
+	}
+}
+
+// hexLines formats data, which starts at offset in its file, as lines of hexDumpLineWidth bytes
+func hexLines(data []byte, offset int64, regions []HexRegion) []HexLine {
+	lines := make([]HexLine, 0, (len(data)+hexDumpLineWidth-1)/hexDumpLineWidth)
+	for start := 0; start < len(data); start += hexDumpLineWidth {
+		chunk := data[start:min(start+hexDumpLineWidth, len(data))]
+
+		var hexPart, asciiPart strings.Builder
+		for i, b := range chunk {
+			if i > 0 {
+				hexPart.WriteByte(' ')
+			}
+			fmt.Fprintf(&hexPart, "%02x", b)
+			if b >= 0x20 && b < 0x7f {
+				asciiPart.WriteByte(b)
+			} else {
+				asciiPart.WriteByte('.')
+			}
+		}
+
+		line := HexLine{Offset: offset + int64(start), Hex: hexPart.String(), ASCII: asciiPart.String()}
+		lineEnd := line.Offset + int64(len(chunk))
+		for _, region := range regions {
+			if region.Start >= line.Offset && region.Start < lineEnd {
+				line.Regions = append(line.Regions, region.Label)
+			}
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// binRegions returns the header and records of a data file that overlap the window, labelled with the parsed record
+func binRegions(filename, path string, w hexWindow) []HexRegion {
+	regions := make([]HexRegion, 0)
+	header, err := utils.ReadHeaderOnly(path)
+	if err != nil {
+		return append(regions, HexRegion{Start: 0, End: w.size, Label: fmt.Sprintf("unreadable header: %v", err)})
+	}
+	if w.overlaps(0, int64(header.HeaderSize)) {
+		regions = append(regions, HexRegion{
+			Start: 0,
+			End:   int64(header.HeaderSize),
+			Label: fmt.Sprintf("header: %d entities, %d tombstones, next id %d", header.EntitiesCount, header.TombstoneCount, header.NextId),
+		})
+	}
+
+	// Walk the length prefixes up to the end of the window, reading only the records inside it
+	for offset := int64(header.HeaderSize); offset+utils.RecordLengthSize <= w.size && offset < w.end; {
+		record, err := recordRegion(filename, w, offset, header.IDSize)
+		if err != nil {
+			return append(regions, HexRegion{Start: offset, End: w.size, Label: fmt.Sprintf("unreadable: %v", err)})
+		}
+		if w.overlaps(record.Start, record.End) {
+			regions = append(regions, record)
+		}
+		offset = record.End
+	}
+	return regions
+}
+
+// recordRegion reads the length prefix of the record at offset, and the record itself when it overlaps the window
+func recordRegion(filename string, w hexWindow, offset int64, idSize int) (HexRegion, error) {
+	prefix, err := w.read(offset, utils.RecordLengthSize)
+	if err != nil {
+		return HexRegion{}, fmt.Errorf("failed to read record length at offset %d: %w", offset, err)
+	}
+	recordLength, _, err := utils.ReadFixedNumber(utils.RecordLengthSize, prefix, 0)
+	if err != nil {
+		return HexRegion{}, fmt.Errorf("failed to read record length at offset %d: %w", offset, err)
+	}
+	if err := utils.ValidateRecordLength(recordLength); err != nil {
+		return HexRegion{}, fmt.Errorf("invalid record at offset %d: %w", offset, err)
+	}
+
+	position := offset + utils.RecordLengthSize
+	region := HexRegion{Start: offset, End: position + int64(recordLength)}
+	if region.End > w.size {
+		return HexRegion{}, fmt.Errorf("incomplete record at offset %d: expected %d bytes, only %d available",
+			position, recordLength, w.size-position)
+	}
+	if w.overlaps(region.Start, region.End) {
+		data, err := w.read(position, int(recordLength))
+		if err != nil {
+			return HexRegion{}, fmt.Errorf("failed to read record at offset %d: %w", offset, err)
+		}
+		region.Label = recordLabel(filename, utils.EntryInfo{Data: data, Position: position, IDSize: idSize})
+	}
+	return region, nil
+}
+
+// recordLabel describes a record with the parser of the data file it was read from
+func recordLabel(filename string, entry utils.EntryInfo) string {
+	var label string
+	var tombstone byte
+	switch utils.LogicalBinName(filename) {
+	case "items.bin":
+		item, err := utils.ItemCodec.Decode(entry.Data, entry.IDSize)
+		if err != nil {
+			return fmt.Sprintf("unreadable item: %v", err)
+		}
+		label, tombstone = fmt.Sprintf("item %d %q, %d cents", item.ID, item.Name, item.Price), item.Tombstone
+	case "orders.bin", "promotions.bin", "templates.bin":
+		collection, err := utils.CollectionCodec.Decode(entry.Data, entry.IDSize)
+		if err != nil {
+			return fmt.Sprintf("unreadable collection: %v", err)
+		}
+		entity := strings.TrimSuffix(utils.LogicalBinName(filename), "s.bin")
+		label = fmt.Sprintf("%s %d %q, %d items, %d cents", entity, collection.ID, collection.OwnerOrName, collection.ItemCount, collection.TotalPrice)
+		tombstone = collection.Tombstone
+	case "order_promotions.bin":
+		link, err := utils.OrderPromotionCodec.Decode(entry.Data, entry.IDSize)
+		if err != nil {
+			return fmt.Sprintf("unreadable link: %v", err)
+		}
+		label, tombstone = fmt.Sprintf("order %d, promotion %d", link.OrderID, link.PromotionID), link.Tombstone
+		if !link.AppliedAt.IsZero() {
+			label += fmt.Sprintf(", applied %s, %d cents off", link.AppliedAt.Format(time.RFC3339), link.DiscountAmount)
+		}
+	default:
+		return fmt.Sprintf("record of %d bytes", len(entry.Data))
+	}
+
+	if tombstone != 0x00 {
+		label += " (deleted)"
+	}
+	return label
+}
+
+// errIndexTruncated stops annotating an index file that ends in the middle of a structure
+var errIndexTruncated = errors.New("truncated")
+
+// indexWalker steps through the structures of an index file, keeping those that overlap the window
+type indexWalker struct {
+	w       hexWindow
+	offset  int64
+	regions []HexRegion
+}
+
+// add steps over a structure of size bytes, labelled with label when it overlaps the window
+func (r *indexWalker) add(size int64, label func() string) error {
+	if r.offset+size > r.w.size {
+		return errIndexTruncated
+	}
+	if r.w.overlaps(r.offset, r.offset+size) {
+		r.regions = append(r.regions, HexRegion{Start: r.offset, End: r.offset + size, Label: label()})
+	}
+	r.offset += size
+	return nil
+}
+
+// skip steps over count structures of size bytes outside the window without reading them
+func (r *indexWalker) skip(count uint64, size int64) error {
+	if fit := uint64((r.w.size - r.offset) / size); count > fit {
+		r.offset += int64(fit) * size
+		return errIndexTruncated
+	}
+	r.offset += int64(count) * size
+	return nil
+}
+
+// read reads n bytes at the current offset, failing like add when the file ends first
+func (r *indexWalker) read(n int) ([]byte, error) {
+	data, err := r.w.read(r.offset, n)
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, errIndexTruncated
+	}
+	return data, err
+}
+
+// indexRegions returns the structures written by BTree.Save or ExtensibleHash.Save that overlap the window
+func indexRegions(filename string, w hexWindow) []HexRegion {
+	r := &indexWalker{w: w, regions: make([]HexRegion, 0)}
+
+	var err error
+	if utils.LogicalBinName(strings.TrimSuffix(filename, ".idx")+".bin") == "order_promotions.bin" {
+		err = hashIndexRegions(r)
+	} else {
+		err = btreeIndexRegions(r)
+	}
+	if err == nil && r.offset < w.size {
+		err = errors.New("trailing bytes")
+	}
+	if err != nil && w.overlaps(r.offset, w.size) {
+		r.regions = append(r.regions, HexRegion{Start: r.offset, End: w.size, Label: fmt.Sprintf("unreadable: %v", err)})
+	}
+	return r.regions
+}
+
+// btreeIndexRegions annotates [count(8)][id(8)][offset(8)]... written big-endian by BTree.Save
+// The entries have a fixed size, so the ones before the window are skipped without being read
+func btreeIndexRegions(r *indexWalker) error {
+	header, err := r.read(8)
+	if err != nil {
+		return err
+	}
+	count := binary.BigEndian.Uint64(header)
+	if err := r.add(8, func() string { return "entry count" }); err != nil {
+		return err
+	}
+
+	before := uint64(0)
+	if r.w.start > r.offset {
+		before = min(count, uint64(r.w.start-r.offset)/16)
+	}
+	if err := r.skip(before, 16); err != nil {
+		return err
+	}
+	i := before
+	for ; i < count && r.offset < r.w.end; i++ {
+		entry, err := r.read(16)
+		if err != nil {
+			return err
+		}
+		id := binary.BigEndian.Uint64(entry[0:8])
+		recordOffset := int64(binary.BigEndian.Uint64(entry[8:16]))
+		if err := r.add(16, func() string { return fmt.Sprintf("id %d -> offset %d", id, recordOffset) }); err != nil {
+			return err
+		}
+	}
+	return r.skip(count-i, 16)
+}
+
+// hashIndexRegions annotates the header, buckets, directory and generation written little-endian by ExtensibleHash.Save
+// Only the bucket headers before the window are read, to find where the next bucket starts
+func hashIndexRegions(r *indexWalker) error {
+	header, err := r.read(12)
+	if err != nil {
+		return err
+	}
+	globalDepth := binary.LittleEndian.Uint32(header[0:4])
+	if globalDepth > 32 {
+		return fmt.Errorf("global depth %d out of range", globalDepth)
+	}
+	bucketSize := binary.LittleEndian.Uint32(header[4:8])
+	if err := r.add(8, func() string { return fmt.Sprintf("global depth %d, bucket size %d", globalDepth, bucketSize) }); err != nil {
+		return err
+	}
+	buckets := binary.LittleEndian.Uint32(header[8:12])
+	if err := r.add(4, func() string { return fmt.Sprintf("%d buckets", buckets) }); err != nil {
+		return err
+	}
+
+	for b := uint32(0); b < buckets; b++ {
+		bucket, err := r.read(8)
+		if err != nil {
+			return err
+		}
+		localDepth := binary.LittleEndian.Uint32(bucket[0:4])
+		entries := binary.LittleEndian.Uint32(bucket[4:8])
+		if err := r.add(8, func() string { return fmt.Sprintf("bucket %d: local depth %d, %d entries", b, localDepth, entries) }); err != nil {
+			return err
+		}
+		if !r.w.overlaps(r.offset, r.offset+24*int64(entries)) {
+			if err := r.skip(uint64(entries), 24); err != nil {
+				return err
+			}
+			continue
+		}
+		for e := uint32(0); e < entries; e++ {
+			entry, err := r.read(24)
+			if err != nil {
+				return err
+			}
+			label := fmt.Sprintf("order %d, promotion %d -> offset %d",
+				binary.LittleEndian.Uint64(entry[0:8]),
+				binary.LittleEndian.Uint64(entry[8:16]),
+				int64(binary.LittleEndian.Uint64(entry[16:24])))
+			if err := r.add(24, func() string { return label }); err != nil {
+				return err
+			}
+		}
+	}
+
+	directory := int64(4) << globalDepth
+	if err := r.add(directory, func() string { return fmt.Sprintf("directory of %d buckets", directory/4) }); err != nil {
+		return err
+	}
+	if r.w.size-r.offset == 8 {
+		generation, err := r.read(8)
+		if err != nil {
+			return err
+		}
+		return r.add(8, func() string { return fmt.Sprintf("generation %d", binary.LittleEndian.Uint64(generation)) })
+	}
+	return nil
+}
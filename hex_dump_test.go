@@ -0,0 +1,191 @@
+package main
+
+import (
+	"BinaryCRUD/backend/utils"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// regionLabels returns the labels of the regions of a DumpFileHex result
+func regionLabels(result map[string]any) []string {
+	var labels []string
+	for _, region := range result["regions"].([]HexRegion) {
+		labels = append(labels, region.Label)
+	}
+	return labels
+}
+
+func TestDumpFileHexAnnotatesRecords(t *testing.T) {
+	app := newTestApp(t)
+	if _, err := app.AddItem("Burger", 899); err != nil {
+		t.Fatalf("Failed to add item: %v", err)
+	}
+	friesID, err := app.AddItem("Fries", 349)
+	if err != nil {
+		t.Fatalf("Failed to add item: %v", err)
+	}
+	if err := app.DeleteItem(friesID); err != nil {
+		t.Fatalf("Failed to delete item: %v", err)
+	}
+
+	result, err := app.DumpFileHex("items.bin", 0, 0)
+	if err != nil {
+		t.Fatalf("Failed to dump items.bin: %v", err)
+	}
+	labels := regionLabels(result)
+	if len(labels) != 3 || !strings.HasPrefix(labels[0], "header: 2 entities, 1 tombstones") ||
+		labels[1] != `item 0 "Burger", 899 cents` || labels[2] != `item 1 "Fries", 349 cents (deleted)` {
+		t.Fatalf("Unexpected regions %q", labels)
+	}
+
+	magic, err := utils.MagicForVersion(utils.CurrentFormatVersion)
+	if err != nil {
+		t.Fatalf("Failed to get magic bytes: %v", err)
+	}
+	lines := result["lines"].([]HexLine)
+	if lines[0].Offset != 0 || !strings.HasPrefix(lines[0].Hex, fmt.Sprintf("%02x %02x", magic[0], magic[1])) || len(lines[0].ASCII) != 16 {
+		t.Errorf("Unexpected first line %+v", lines[0])
+	}
+	if len(lines[0].Regions) == 0 || lines[0].Regions[0] != labels[0] {
+		t.Errorf("Expected the header to start on the first line, got %q", lines[0].Regions)
+	}
+	if result["length"] != result["size"] {
+		t.Errorf("Expected the whole file, got %v of %v bytes", result["length"], result["size"])
+	}
+	found := false
+	for _, line := range lines {
+		if strings.Contains(line.ASCII, "Burger") {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("Expected the item name in the ASCII column")
+	}
+
+	// A window past the header only carries the regions it overlaps
+	regions := result["regions"].([]HexRegion)
+	window, err := app.DumpFileHex("items.bin", regions[2].Start, 4)
+	if err != nil {
+		t.Fatalf("Failed to dump a window: %v", err)
+	}
+	if window["length"] != int64(4) || len(window["lines"].([]HexLine)) != 1 {
+		t.Errorf("Expected one line of 4 bytes, got %v", window)
+	}
+	if labels := regionLabels(window); len(labels) != 1 || !strings.Contains(labels[0], "Fries") {
+		t.Errorf("Expected only the Fries record, got %q", labels)
+	}
+}
+
+func TestDumpFileHexAnnotatesIndexes(t *testing.T) {
+	app := newTestApp(t)
+	burger, err := app.AddItem("Burger", 899)
+	if err != nil {
+		t.Fatalf("Failed to add item: %v", err)
+	}
+	orderID, err := app.CreateOrder("Alice", []uint64{burger})
+	if err != nil {
+		t.Fatalf("Failed to create order: %v", err)
+	}
+	promotionID, err := app.CreatePromotion("Combo", []uint64{burger})
+	if err != nil {
+		t.Fatalf("Failed to create promotion: %v", err)
+	}
+	if err := app.ApplyPromotionToOrder(orderID, promotionID); err != nil {
+		t.Fatalf("Failed to apply promotion: %v", err)
+	}
+	app.closeDAOs()
+	app.reloadDAOs()
+
+	result, err := app.DumpFileHex("items.idx", 0, 0)
+	if err != nil {
+		t.Fatalf("Failed to dump items.idx: %v", err)
+	}
+	if labels := regionLabels(result); len(labels) != 2 || labels[0] != "entry count" || !strings.HasPrefix(labels[1], "id 0 -> offset ") {
+		t.Errorf("Unexpected B+ tree regions %q", labels)
+	}
+
+	result, err = app.DumpFileHex("order_promotions.idx", 0, 0)
+	if err != nil {
+		t.Fatalf("Failed to dump order_promotions.idx: %v", err)
+	}
+	labels := regionLabels(result)
+	if !strings.HasPrefix(labels[0], "global depth") || !strings.Contains(strings.Join(labels, "\n"), "order 0, promotion 0 -> offset") {
+		t.Errorf("Unexpected hash regions %q", labels)
+	}
+	for _, label := range labels {
+		if strings.HasPrefix(label, "unreadable") {
+			t.Errorf("Expected the whole hash index to be parsed, got %q", label)
+		}
+	}
+
+	result, err = app.DumpFileHex("order_promotions.bin", 0, 0)
+	if err != nil {
+		t.Fatalf("Failed to dump order_promotions.bin: %v", err)
+	}
+	if labels := regionLabels(result); !strings.HasPrefix(labels[len(labels)-1], "order 0, promotion 0, applied ") {
+		t.Errorf("Unexpected link regions %q", labels)
+	}
+}
+
+func TestDumpFileHexRejectsBadInput(t *testing.T) {
+	app := newTestApp(t)
+	if _, err := app.AddItem("Burger", 899); err != nil {
+		t.Fatalf("Failed to add item: %v", err)
+	}
+
+	for _, name := range []string{"", "../config.json", "items.json", "bin/items.bin"} {
+		if _, err := app.DumpFileHex(name, 0, 0); utils.ErrorCodeOf(err) != utils.CodeValidation {
+			t.Errorf("Expected %q to be rejected, got %v", name, err)
+		}
+	}
+	if _, err := app.DumpFileHex("items.bin", 1<<20, 16); utils.ErrorCodeOf(err) != utils.CodeValidation {
+		t.Errorf("Expected an offset past the end to be rejected, got %v", err)
+	}
+	if _, err := app.DumpFileHex("missing.bin", 0, 0); utils.ErrorCodeOf(err) != utils.CodeNotFound {
+		t.Errorf("Expected a missing file to be not found, got %v", err)
+	}
+}
+
+func TestDumpFileHexWindowsMatchTheWholeFile(t *testing.T) {
+	app := newTestApp(t)
+	for i := range 40 {
+		if _, err := app.AddItem(fmt.Sprintf("Item %d", i), uint64(100+i)); err != nil {
+			t.Fatalf("Failed to add item: %v", err)
+		}
+	}
+	app.closeDAOs()
+	app.reloadDAOs()
+
+	for _, filename := range []string{"items.bin", "items.idx"} {
+		whole, err := app.DumpFileHex(filename, 0, MaxHexDumpLength)
+		if err != nil {
+			t.Fatalf("Failed to dump %s: %v", filename, err)
+		}
+		size := whole["size"].(int64)
+		lines := whole["lines"].([]HexLine)
+
+		// Every window sees the bytes and the regions of the whole dump that it overlaps
+		for offset := int64(0); offset < size; offset += 37 {
+			window, err := app.DumpFileHex(filename, offset, 50)
+			if err != nil {
+				t.Fatalf("Failed to dump %s from %d: %v", filename, offset, err)
+			}
+			end := offset + window["length"].(int64)
+			var expected []string
+			for _, region := range whole["regions"].([]HexRegion) {
+				if region.End > offset && region.Start < end {
+					expected = append(expected, region.Label)
+				}
+			}
+			if got := regionLabels(window); fmt.Sprint(got) != fmt.Sprint(expected) {
+				t.Errorf("%s from %d: expected regions %q, got %q", filename, offset, expected, got)
+			}
+			first := window["lines"].([]HexLine)[0]
+			column := 3 * (offset % hexDumpLineWidth)
+			if whole := lines[offset/hexDumpLineWidth]; whole.Hex[column:column+2] != first.Hex[:2] {
+				t.Errorf("%s from %d: expected the bytes of the whole dump, got %q", filename, offset, first.Hex)
+			}
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"BinaryCRUD/backend/crypto"
+	"BinaryCRUD/backend/utils"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// InspectedRecord is one record of a data file as found on disk
+type InspectedRecord struct {
+	Offset     int64          `json:"offset"` // of the record length prefix
+	Length     int            `json:"length"` // of the record, without its length prefix
+	ID         uint64         `json:"id"`
+	Tombstone  byte           `json:"tombstone"`
+	Name       string         `json:"name,omitempty"`
+	NameLength int            `json:"nameLength,omitempty"` // stored bytes, longer than the name when it is encrypted
+	Encrypted  bool           `json:"encrypted,omitempty"`  // the name could not be decrypted with the keys on disk
+	Fields     map[string]any `json:"fields,omitempty"`
+	Error      string         `json:"error,omitempty"` // set when the record content could not be parsed
+}
+
+// InspectFile walks a .bin file and returns its header fields and every record, parsed for humans
+// Records that fail to parse carry an error, and bytes that cannot be split into records are returned as
+// unparsed regions, so a damaged file is shown as far as it can be read
+func (a *App) InspectFile(filename string) (_ map[string]any, err error) {
+	defer a.track("InspectFile", time.Now(), &err)
+
+	if filepath.Ext(filename) != ".bin" {
+		return nil, utils.WithCode(utils.CodeValidation, fmt.Errorf("only .bin files can be inspected: %s", filename), map[string]any{"file": filename})
+	}
+	path, err := hexDumpPath(filename)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, utils.WithCode(utils.CodeNotFound, fmt.Errorf("file not found: %s", filename), map[string]any{"file": filename})
+		}
+		return nil, fmt.Errorf("failed to read %s: %w", filename, err)
+	}
+
+	result := map[string]any{
+		"file":     filename,
+		"size":     len(data),
+		"records":  []InspectedRecord{},
+		"unparsed": []HexRegion{},
+	}
+	header, err := inspectHeader(path, data)
+	if err != nil {
+		result["unparsed"] = []HexRegion{{Start: 0, End: int64(len(data)), Label: fmt.Sprintf("unreadable header: %v", err)}}
+		return result, nil
+	}
+	result["header"] = header
+
+	names := nameOpener(utils.LogicalBinName(filename), header["namesEncrypted"].(bool))
+	records, unparsed := inspectRecords(filename, data, header["headerSize"].(int), header["idSize"].(int), names)
+	result["records"] = records
+	result["unparsed"] = unparsed
+	return result, nil
+}
+
+// inspectHeader returns the header fields of the data file at path, whose contents are data
+func inspectHeader(path string, data []byte) (map[string]any, error) {
+	name, entities, tombstones, nextID, headerSize, err := utils.ReadHeaderFromBytes(data)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+	version, err := utils.ReadHeaderVersion(file)
+	if err != nil {
+		return nil, err
+	}
+	flags, err := utils.ReadHeaderFlags(file)
+	if err != nil {
+		return nil, err
+	}
+	idSize, err := utils.ReadIDSize(file)
+	if err != nil {
+		return nil, err
+	}
+
+	// Files whose header doesn't record the name encryption follow the global setting, like the DAOs
+	namesEncrypted := crypto.IsEnabled()
+	switch {
+	case flags&utils.FlagNamesEncrypted != 0:
+		namesEncrypted = true
+	case flags&utils.FlagNamesPlaintext != 0:
+		namesEncrypted = false
+	}
+
+	return map[string]any{
+		"version":        version,
+		"filename":       name,
+		"entities":       entities,
+		"tombstones":     tombstones,
+		"nextId":         nextID,
+		"flags":          flags,
+		"idSize":         idSize,
+		"headerSize":     headerSize,
+		"namesEncrypted": namesEncrypted,
+	}, nil
+}
+
+// nameOpener returns how the collection names of a data file are read: as they are, or decrypted with the
+// data key when the file encrypts them; without a data key on disk encrypted names stay unreadable
+func nameOpener(logicalName string, namesEncrypted bool) func([]byte) (string, bool) {
+	plain := func(stored []byte) (string, bool) { return string(stored), true }
+	if logicalName == "items.bin" || !namesEncrypted {
+		return plain
+	}
+
+	unreadable := func([]byte) (string, bool) { return "", false }
+	if _, err := os.Stat(filepath.Join(utils.KeysDir, crypto.DataKeyFile)); err != nil {
+		return unreadable
+	}
+	fieldCipher, err := crypto.GetFieldCipher(utils.KeysDir)
+	if err != nil {
+		return unreadable
+	}
+	return func(stored []byte) (string, bool) {
+		name, err := fieldCipher.DecryptFromBytesIf(true, stored)
+		return name, err == nil
+	}
+}
+
+// inspectRecords splits the records after the header, parsing each one with the codec of its file
+// A record that fails to parse is kept with its error; a length prefix that cannot be trusted ends the walk,
+// since the next record boundary is unknown, and the rest of the file is returned as unparsed
+func inspectRecords(filename string, data []byte, headerSize, idSize int, names func([]byte) (string, bool)) ([]InspectedRecord, []HexRegion) {
+	records := make([]InspectedRecord, 0)
+	unparsed := make([]HexRegion, 0)
+
+	offset := headerSize
+	for offset < len(data) {
+		recordLength, dataStart, err := utils.ReadFixedNumber(utils.RecordLengthSize, data, offset)
+		if err == nil {
+			err = utils.ValidateRecordLength(recordLength)
+		}
+		if err == nil && dataStart+int(recordLength) > len(data) {
+			err = fmt.Errorf("record of %d bytes runs past the end of the file", recordLength)
+		}
+		if err != nil {
+			unparsed = append(unparsed, HexRegion{Start: int64(offset), End: int64(len(data)), Label: err.Error()})
+			break
+		}
+
+		record := InspectedRecord{Offset: int64(offset), Length: int(recordLength)}
+		inspectRecord(&record, filename, data[dataStart:dataStart+int(recordLength)], idSize, names)
+		records = append(records, record)
+		offset = dataStart + int(recordLength)
+	}
+	return records, unparsed
+}
+
+// inspectRecord fills record from the record data, with the codec of the data file it was read from
+func inspectRecord(record *InspectedRecord, filename string, entryData []byte, idSize int, names func([]byte) (string, bool)) {
+	switch utils.LogicalBinName(filename) {
+	case "items.bin":
+		item, err := utils.ItemCodec.Decode(entryData, idSize)
+		if err != nil {
+			record.Error = err.Error()
+			break
+		}
+		record.ID, record.Tombstone = item.ID, item.Tombstone
+		record.Name, record.NameLength = item.Name, len(item.Name)
+		record.Fields = map[string]any{"priceInCents": item.Price}
+		addExtensionFields(record.Fields, item.Extensions)
+	case "orders.bin", "promotions.bin", "templates.bin":
+		collection, err := utils.CollectionCodec.Decode(entryData, idSize)
+		if err != nil {
+			record.Error = err.Error()
+			break
+		}
+		record.ID, record.Tombstone = collection.ID, collection.Tombstone
+		record.NameLength = len(collection.OwnerOrName)
+		name, ok := names([]byte(collection.OwnerOrName))
+		record.Name, record.Encrypted = name, !ok
+		record.Fields = map[string]any{
+			"totalPrice": collection.TotalPrice,
+			"itemCount":  collection.ItemCount,
+			"itemIds":    collection.ItemIDs,
+			"compressed": utils.IsCompressedEntry(entryData, idSize),
+		}
+		addExtensionFields(record.Fields, collection.Extensions)
+	case "order_promotions.bin":
+		link, err := utils.OrderPromotionCodec.Decode(entryData, idSize)
+		if err != nil {
+			record.Error = err.Error()
+			break
+		}
+		record.ID, record.Tombstone = link.OrderID, link.Tombstone
+		record.Fields = map[string]any{"orderId": link.OrderID, "promotionId": link.PromotionID}
+		if !link.AppliedAt.IsZero() {
+			record.Fields["appliedAt"] = link.AppliedAt.Format(time.RFC3339Nano)
+			record.Fields["discountAmount"] = link.DiscountAmount
+		}
+	default:
+		// Other data files share the [ID(idSize)][tombstone(1)][payload...] layout of Store records
+		if len(entryData) < idSize+utils.TombstoneSize {
+			record.Error = fmt.Sprintf("record of %d bytes is too short for an ID and tombstone", len(entryData))
+			break
+		}
+		id, _, err := utils.ReadFixedNumber(idSize, entryData, 0)
+		if err != nil {
+			record.Error = err.Error()
+			break
+		}
+		record.ID, record.Tombstone = id, entryData[idSize]
+		record.Fields = map[string]any{"payloadLength": len(entryData) - idSize - utils.TombstoneSize}
+	}
+}
+
+// addExtensionFields lists the extension tags of a record, which follow its fixed fields
+func addExtensionFields(fields map[string]any, extensions map[byte][]byte) {
+	if len(extensions) == 0 {
+		return
+	}
+	tags := make(map[string]int, len(extensions))
+	for tag, value := range extensions {
+		tags[fmt.Sprintf("0x%02x", tag)] = len(value)
+	}
+	fields["extensions"] = tags
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"BinaryCRUD/backend/crypto"
+	"BinaryCRUD/backend/utils"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInspectFileParsesRecords(t *testing.T) {
+	app := newTestApp(t)
+	app.SetEncryptionEnabled(true)
+	burger, err := app.AddItem("Burger", 899)
+	if err != nil {
+		t.Fatalf("Failed to add item: %v", err)
+	}
+	fries, err := app.AddItem("Fries", 349)
+	if err != nil {
+		t.Fatalf("Failed to add item: %v", err)
+	}
+	if err := app.DeleteItem(fries); err != nil {
+		t.Fatalf("Failed to delete item: %v", err)
+	}
+	if _, err := app.CreateOrder("Alice", []uint64{burger}); err != nil {
+		t.Fatalf("Failed to create order: %v", err)
+	}
+
+	result, err := app.InspectFile("items.bin")
+	if err != nil {
+		t.Fatalf("Failed to inspect items.bin: %v", err)
+	}
+	header := inspectedHeader(t, result)
+	if header["entities"] != 2 || header["tombstones"] != 1 || header["nextId"] != 2 || header["version"] != utils.CurrentFormatVersion {
+		t.Errorf("Unexpected header %v", header)
+	}
+	records := result["records"].([]InspectedRecord)
+	if len(records) != 2 {
+		t.Fatalf("Expected 2 records, got %v", records)
+	}
+	if records[0].Name != "Burger" || records[0].Tombstone != 0 || records[0].Fields["priceInCents"] != uint64(899) {
+		t.Errorf("Unexpected first record %+v", records[0])
+	}
+	if records[1].ID != fries || records[1].Tombstone == 0 || records[1].Offset != records[0].Offset+int64(utils.RecordLengthSize+records[0].Length) {
+		t.Errorf("Unexpected second record %+v", records[1])
+	}
+
+	result, err = app.InspectFile("orders.bin")
+	if err != nil {
+		t.Fatalf("Failed to inspect orders.bin: %v", err)
+	}
+	if header := inspectedHeader(t, result); header["namesEncrypted"] != true {
+		t.Errorf("Expected encrypted names, got %v", header)
+	}
+	order := result["records"].([]InspectedRecord)[0]
+	if order.Name != "Alice" || order.Encrypted || order.NameLength <= len("Alice") {
+		t.Errorf("Expected the decrypted name of a longer stored field, got %+v", order)
+	}
+
+	// Without the data key the name stays encrypted
+	crypto.Reset()
+	if err := os.Rename(filepath.Join(utils.KeysDir, crypto.DataKeyFile), filepath.Join(utils.KeysDir, "moved.key")); err != nil {
+		t.Fatalf("Failed to move the data key: %v", err)
+	}
+	result, err = app.InspectFile("orders.bin")
+	if err != nil {
+		t.Fatalf("Failed to inspect orders.bin: %v", err)
+	}
+	if order := result["records"].([]InspectedRecord)[0]; order.Name != "" || !order.Encrypted {
+		t.Errorf("Expected an unreadable name, got %+v", order)
+	}
+}
+
+func TestInspectFileFlagsDamage(t *testing.T) {
+	app := newTestApp(t)
+	for _, name := range []string{"Burger", "Fries", "Soda"} {
+		if _, err := app.AddItem(name, 100); err != nil {
+			t.Fatalf("Failed to add item: %v", err)
+		}
+	}
+	app.closeDAOs()
+
+	result, err := app.InspectFile("items.bin")
+	if err != nil {
+		t.Fatalf("Failed to inspect items.bin: %v", err)
+	}
+	records := result["records"].([]InspectedRecord)
+
+	// Break the name length of the first record and cut the last one short
+	path := utils.BinPath("items.bin")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read items.bin: %v", err)
+	}
+	nameLength := records[0].Offset + int64(utils.RecordLengthSize) + int64(inspectedHeader(t, result)["idSize"].(int)) + utils.TombstoneSize
+	data[nameLength], data[nameLength+1] = 0xff, 0xff
+	data = data[:len(data)-2]
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("Failed to write items.bin: %v", err)
+	}
+
+	result, err = app.InspectFile("items.bin")
+	if err != nil {
+		t.Fatalf("Failed to inspect the damaged file: %v", err)
+	}
+	records = result["records"].([]InspectedRecord)
+	if len(records) != 2 || records[0].Error == "" || records[1].Name != "Fries" || records[1].Error != "" {
+		t.Errorf("Expected a broken record followed by Fries, got %+v", records)
+	}
+	unparsed := result["unparsed"].([]HexRegion)
+	if len(unparsed) != 1 || unparsed[0].End != int64(len(data)) || !strings.Contains(unparsed[0].Label, "past the end") {
+		t.Errorf("Expected the truncated record to be unparsed, got %+v", unparsed)
+	}
+
+	if _, err := app.InspectFile("items.idx"); utils.ErrorCodeOf(err) != utils.CodeValidation {
+		t.Errorf("Expected an index file to be rejected, got %v", err)
+	}
+}
+
+// inspectedHeader returns the header fields of an InspectFile result
+func inspectedHeader(t *testing.T, result map[string]any) map[string]any {
+	t.Helper()
+	fields, ok := result["header"].(map[string]any)
+	if !ok {
+		t.Fatalf("Expected a parsed header, got %v", result)
+	}
+	return fields
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"BinaryCRUD/backend/utils"
+	"errors"
+	"fmt"
+)
+
+// lockDataDir locks the data directory for this instance
+// When another instance already holds it, the app opens the data read-only instead of clobbering its files,
+// down to the tombstone bitmaps it would otherwise save beside the indexes
+func (a *App) lockDataDir() {
+	lock, err := utils.LockDataDir(utils.DataDir)
+	utils.SetSidecarsReadOnly(err != nil)
+	if err != nil {
+		if errors.Is(err, utils.ErrDataDirLocked) {
+			a.readOnlyReason = err.Error()
+		} else {
+			a.readOnlyReason = fmt.Sprintf("cannot lock the data directory: %v", err)
+		}
+		a.logger.Warn(fmt.Sprintf("Opening data read-only: %s", a.readOnlyReason))
+		return
+	}
+	a.dataLock = lock
+}
+
+// releaseDataDir unlocks the data directory on shutdown
+func (a *App) releaseDataDir() {
+	if err := a.dataLock.Release(); err != nil {
+		a.logger.Warn(fmt.Sprintf("Failed to release data directory lock: %v", err))
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"BinaryCRUD/backend/utils"
+	"os"
+	"testing"
+)
+
+func TestSecondInstanceLeavesTheTombstoneBitmapsAlone(t *testing.T) {
+	app := newTestApp(t)
+	for _, name := range []string{"Burger", "Fries", "Soda"} {
+		if _, err := app.AddItem(name, 500); err != nil {
+			t.Fatalf("Failed to add item: %v", err)
+		}
+	}
+	if err := app.DeleteItem(1); err != nil {
+		t.Fatalf("Failed to delete item: %v", err)
+	}
+	bitmap := utils.TombstoneBitmapPath(utils.BinPath("items.bin"))
+	app.waitForCompaction()
+	os.Remove(bitmap)
+
+	second := NewApp()
+	t.Cleanup(func() {
+		second.closeDAOs()
+		second.logger.Close()
+	})
+	if second.checkWritable() == nil {
+		t.Fatal("Expected the second instance to open the data read-only")
+	}
+	items, err := second.GetAllItems(ListOptions{ActiveOnly: true})
+	if err != nil || len(items) != 2 {
+		t.Fatalf("Expected 2 active items, got %d (err %v)", len(items), err)
+	}
+	if _, err := os.Stat(bitmap); !os.IsNotExist(err) {
+		t.Errorf("Expected the second instance not to save the tombstone bitmap, got %v", err)
+	}
+}
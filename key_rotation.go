@@ -0,0 +1,70 @@
+package main
+
+import (
+	"BinaryCRUD/backend/crypto"
+	"BinaryCRUD/backend/dao"
+	"BinaryCRUD/backend/utils"
+	"fmt"
+	"time"
+)
+
+// RotateDataKey replaces the AES data key and re-encrypts every encrypted name in orders.bin, promotions.bin, templates.bin and the oplog
+// The files are rewritten like a compaction and the old key is archived in the keys directory for recovery
+// Only the data key changes: the RSA key pair that wraps it is built in and is not rotated
+func (a *App) RotateDataKey() (_ map[string]any, err error) {
+	defer a.track("RotateDataKey", time.Now(), &err)
+	if err := a.checkWritable(); err != nil {
+		return nil, err
+	}
+
+	if a.isCompacting() {
+		return nil, errCompactionRunning
+	}
+
+	a.logger.Info("Starting data key rotation...")
+
+	// Files storing plain text names are left as they are
+	var files []string
+	collectionDAOs := []*dao.CollectionDAO{a.orderDAO.CollectionDAO, a.promotionDAO.CollectionDAO, a.templateDAO.CollectionDAO}
+	for i, filename := range []string{"orders.bin", "promotions.bin", "templates.bin"} {
+		encrypted, err := collectionDAOs[i].NamesEncrypted()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read encryption setting of %s: %w", filename, err)
+		}
+		if encrypted {
+			files = append(files, utils.BinPath(filename))
+		}
+	}
+
+	reencrypted := 0
+	archivedKey, err := crypto.RotateDataKey(utils.KeysDir, func(oldCipher, newCipher *crypto.FieldCipher) error {
+		reencrypt := func(stored []byte) ([]byte, error) {
+			return oldCipher.Reencrypt(stored, newCipher)
+		}
+		count, err := utils.RewriteCollectionNames(reencrypt, files...)
+		reencrypted = count
+		if err != nil {
+			return err
+		}
+		// The oplog keeps order and promotion names encrypted too, whatever the files do
+		logged, err := a.oplog.ReencryptNames(reencrypt)
+		reencrypted += logged
+		return err
+	})
+	if err != nil {
+		a.logger.Error(fmt.Sprintf("Data key rotation failed: %v", err))
+		return nil, fmt.Errorf("data key rotation failed: %w", err)
+	}
+
+	// Reload all DAOs to rebuild indexes from the rewritten files
+	a.reloadDAOs()
+	a.signDataFiles()
+
+	a.logger.Info(fmt.Sprintf("Data key rotation complete: %d name(s) re-encrypted, old key archived to %s", reencrypted, archivedKey))
+	a.toast.Success(fmt.Sprintf("Data key rotated, %d name(s) re-encrypted", reencrypted))
+
+	return map[string]any{
+		"reencrypted": reencrypted,
+		"archivedKey": archivedKey,
+	}, nil
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"BinaryCRUD/backend/dao"
+	"BinaryCRUD/backend/utils"
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// ListOptions selects and orders the records returned by the GetAll bindings
+// The zero value lists every record, deleted ones included, in file order
+type ListOptions struct {
+	ActiveOnly bool   `json:"activeOnly,omitempty"` // leave deleted records out, skipped through the tombstone bitmap
+	SortBy     string `json:"sortBy,omitempty"`     // "id", "name" or "price", empty keeps file order
+	Direction  string `json:"direction,omitempty"`  // "asc" (default) or "desc"
+}
+
+// validate rejects unknown sort keys and directions
+func (o ListOptions) validate() error {
+	switch o.SortBy {
+	case "", "id", "name", "price":
+	default:
+		return utils.WithCode(utils.CodeValidation, fmt.Errorf("unknown sortBy %q, expected id, name or price", o.SortBy),
+			map[string]any{"sortBy": o.SortBy})
+	}
+	switch o.Direction {
+	case "", "asc", "desc":
+	default:
+		return utils.WithCode(utils.CodeValidation, fmt.Errorf("unknown direction %q, expected asc or desc", o.Direction),
+			map[string]any{"direction": o.Direction})
+	}
+	return nil
+}
+
+// byIndex reports whether the records are read in ID order through the B+ tree index, which holds the
+// active records only, instead of being loaded and sorted
+func (o ListOptions) byIndex() bool {
+	return o.ActiveOnly && o.SortBy == "id"
+}
+
+// sortRecords orders records as the options ask, leaving file order when no sort key is given
+// Records read through the index are already in ascending ID order and only need reversing for desc
+// Names compare case-insensitively; ties fall back to ascending IDs whatever the direction
+func sortRecords[T any](records []T, opts ListOptions, id func(T) uint64, name func(T) string, price func(T) uint64) {
+	if opts.SortBy == "" {
+		return
+	}
+	desc := opts.Direction == "desc"
+	if opts.byIndex() {
+		if desc {
+			slices.Reverse(records)
+		}
+		return
+	}
+	sort.SliceStable(records, func(i, j int) bool {
+		a, b := records[i], records[j]
+		order := 0
+		switch opts.SortBy {
+		case "id":
+			order = cmp.Compare(id(a), id(b))
+		case "name":
+			order = strings.Compare(strings.ToLower(name(a)), strings.ToLower(name(b)))
+		case "price":
+			order = cmp.Compare(price(a), price(b))
+		}
+		if order == 0 {
+			return id(a) < id(b)
+		}
+		if desc {
+			return order > 0
+		}
+		return order < 0
+	})
+}
+
+// listCollections reads the orders, promotions or templates of a DAO as the options ask
+func listCollections(collections *dao.CollectionDAO, opts ListOptions) ([]*dao.Collection, error) {
+	var records []*dao.Collection
+	var err error
+	switch {
+	case opts.byIndex():
+		records, err = collections.GetAllActiveByID(context.Background())
+	case opts.ActiveOnly:
+		records, err = collections.GetAllActive(context.Background())
+	default:
+		records, err = collections.GetAll()
+	}
+	if err != nil {
+		return nil, err
+	}
+	sortRecords(records, opts,
+		func(c *dao.Collection) uint64 { return c.ID },
+		func(c *dao.Collection) string { return c.OwnerOrName },
+		func(c *dao.Collection) uint64 { return c.TotalPrice })
+	return records, nil
+}
@@ -1,17 +1,78 @@
 package main
 
 import (
+	"BinaryCRUD/backend/utils"
+	"bufio"
 	"context"
+	"encoding/json"
+	"fmt"
 	"log/slog"
 	"os"
 	"sync"
+	"time"
+)
+
+// Keys of the structured fields tying a log entry to an entity
+const (
+	logKeyEntity     = "entity"
+	logKeyID         = "id"
+	logKeyOperation  = "operation"
+	logKeyDurationMs = "durationMs"
 )
 
 // LogEntry represents a single log message with timestamp and level
+// Entries about an entity also carry its type, ID, the operation and how long it took
 type LogEntry struct {
-	Timestamp string `json:"timestamp"`
-	Level     string `json:"level"`
-	Message   string `json:"message"`
+	Time       time.Time `json:"time"`
+	Timestamp  string    `json:"timestamp"`
+	Level      string    `json:"level"`
+	Message    string    `json:"message"`
+	Entity     string    `json:"entity,omitempty"`     // "item", "order" or "promotion"
+	ID         *uint64   `json:"id,omitempty"`         // nil when the entry is not about a single record
+	Operation  string    `json:"operation,omitempty"`  // e.g. "create", "read", "update", "delete"
+	DurationMs float64   `json:"durationMs,omitempty"` // 0 when the operation was not timed
+	level      slog.Level
+}
+
+// newLogEntry builds the entry kept in memory for a log message
+func newLogEntry(t time.Time, level slog.Level, message string) LogEntry {
+	return LogEntry{
+		Time:      t,
+		Timestamp: t.Format("15:04:05.000"),
+		Level:     level.String(),
+		Message:   message,
+		level:     level,
+	}
+}
+
+// setField fills the entry field for a structured attribute, other attributes are only written to the file
+func (e *LogEntry) setField(attr slog.Attr) {
+	value := attr.Value.Resolve()
+	switch {
+	case attr.Key == logKeyEntity && value.Kind() == slog.KindString:
+		e.Entity = value.String()
+	case attr.Key == logKeyID && value.Kind() == slog.KindUint64:
+		id := value.Uint64()
+		e.ID = &id
+	case attr.Key == logKeyOperation && value.Kind() == slog.KindString:
+		e.Operation = value.String()
+	case attr.Key == logKeyDurationMs && value.Kind() == slog.KindFloat64:
+		e.DurationMs = value.Float64()
+	}
+}
+
+// entityLog returns the fields tying a log entry to an operation on one record
+// A non-zero start adds the time elapsed since then
+func entityLog(entity string, id uint64, operation string, start time.Time) []slog.Attr {
+	attrs := []slog.Attr{
+		slog.String(logKeyEntity, entity),
+		slog.Uint64(logKeyID, id),
+		slog.String(logKeyOperation, operation),
+	}
+	if !start.IsZero() {
+		attrs = append(attrs, slog.Float64(logKeyDurationMs, float64(time.Since(start).Microseconds())/1000))
+	}
+	return attrs
 }
 
 // InMemoryHandler captures logs in memory for UI display
@@ -19,47 +80,50 @@ type InMemoryHandler struct {
 	mu      sync.RWMutex
 	entries []LogEntry
 	maxSize int
+	level   slog.Leveler // minimum level kept in memory and passed on
 	next    slog.Handler // Chain to file handler
 }
 
-// NewInMemoryHandler creates a new in-memory handler
-func NewInMemoryHandler(maxSize int, next slog.Handler) *InMemoryHandler {
+// NewInMemoryHandler creates a new in-memory handler keeping entries at or above level
+func NewInMemoryHandler(maxSize int, level slog.Leveler, next slog.Handler) *InMemoryHandler {
 	return &InMemoryHandler{
 		entries: make([]LogEntry, 0, maxSize),
 		maxSize: maxSize,
+		level:   level,
 		next:    next,
 	}
 }
 
 // Handle implements slog.Handler interface
 func (h *InMemoryHandler) Handle(ctx context.Context, r slog.Record) error {
-	h.mu.Lock()
-	defer h.mu.Unlock()
+	entry := newLogEntry(r.Time, r.Level, r.Message)
+	r.Attrs(func(attr slog.Attr) bool {
+		entry.setField(attr)
+		return true
+	})
+	h.add(entry)
 
-	// Store in memory
-	entry := LogEntry{
-		Timestamp: r.Time.Format("15:04:05.000"),
-		Level:     r.Level.String(),
-		Message:   r.Message,
+	// Chain to next handler (file) if set
+	if h.next != nil {
+		return h.next.Handle(ctx, r)
 	}
+	return nil
+}
+
+// add stores an entry, dropping the oldest one once maxSize entries are kept
+func (h *InMemoryHandler) add(entry LogEntry) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
 
-	// If we've reached max size, remove oldest entry
 	if len(h.entries) >= h.maxSize {
 		h.entries = h.entries[1:]
 	}
-
 	h.entries = append(h.entries, entry)
-
-	// Chain to next handler (file) if set
-	if h.next != nil {
-		return h.next.Handle(ctx, r)
-	}
-	return nil
 }
 
 // Enabled implements slog.Handler interface
 func (h *InMemoryHandler) Enabled(ctx context.Context, level slog.Level) bool {
-	return true // Accept all log levels
+	return level >= h.level.Level()
 }
 
 // WithAttrs implements slog.Handler interface
@@ -68,6 +132,7 @@ func (h *InMemoryHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
 		return &InMemoryHandler{
 			entries: h.entries,
 			maxSize: h.maxSize,
+			level:   h.level,
 			next:    h.next.WithAttrs(attrs),
 		}
 	}
@@ -80,20 +145,43 @@ func (h *InMemoryHandler) WithGroup(name string) slog.Handler {
 		return &InMemoryHandler{
 			entries: h.entries,
 			maxSize: h.maxSize,
+			level:   h.level,
 			next:    h.next.WithGroup(name),
 		}
 	}
 	return h
 }
 
-// GetLogs returns all current log entries
-func (h *InMemoryHandler) GetLogs() []LogEntry {
+// GetLogs returns the entries at or above level logged after since, keeping the most recent limit (0 keeps all)
+// Entries are returned oldest first
+func (h *InMemoryHandler) GetLogs(level slog.Level, limit int, since time.Time) []LogEntry {
 	h.mu.RLock()
 	defer h.mu.RUnlock()
 
 	// Return a copy to avoid race conditions
-	logs := make([]LogEntry, len(h.entries))
-	copy(logs, h.entries)
+	logs := make([]LogEntry, 0, len(h.entries))
+	for _, entry := range h.entries {
+		if entry.level >= level && entry.Time.After(since) {
+			logs = append(logs, entry)
+		}
+	}
+	if limit > 0 && len(logs) > limit {
+		logs = logs[len(logs)-limit:]
+	}
+	return logs
+}
+
+// GetLogsForEntity returns the entries about one record, oldest first
+func (h *InMemoryHandler) GetLogsForEntity(entity string, id uint64) []LogEntry {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	logs := make([]LogEntry, 0)
+	for _, entry := range h.entries {
+		if entry.Entity == entity && entry.ID != nil && *entry.ID == id {
+			logs = append(logs, entry)
+		}
+	}
 	return logs
 }
 
@@ -105,39 +193,120 @@ func (h *InMemoryHandler) Clear() {
 	h.entries = make([]LogEntry, 0, h.maxSize)
 }
 
+// logOutput passes log lines on to the current log file, which changes with the data directory
+type logOutput struct {
+	mu   sync.Mutex
+	file *utils.RotatingFile // nil writes to stderr
+}
+
+// Write implements io.Writer
+func (o *logOutput) Write(p []byte) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.file == nil {
+		return os.Stderr.Write(p)
+	}
+	return o.file.Write(p)
+}
+
+// set switches to file, closing the previous one
+func (o *logOutput) set(file *utils.RotatingFile) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.file != nil {
+		o.file.Close()
+	}
+	o.file = file
+}
+
 // Logger wraps slog.Logger with in-memory handler
 type Logger struct {
 	logger  *slog.Logger
 	handler *InMemoryHandler
+	level   *slog.LevelVar
+	output  *logOutput
 }
 
 // NewLogger creates a new logger with in-memory and file handlers
+// The file is app.log in the logs directory of the data directory, written as JSON lines; its most
+// recent entries are loaded back so the logs of earlier runs stay available
 func NewLogger(maxSize int) *Logger {
-	// Create logs directory with restrictive permissions
-	os.MkdirAll("logs", 0700)
-
-	// Create file handler for persistent logs with restrictive permissions
-	logFile, err := os.OpenFile("logs/app.log", os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
-	if err != nil {
-		// Fallback to stderr if file creation fails
-		logFile = os.Stderr
-	}
+	level := new(slog.LevelVar)
+	output := &logOutput{}
 
-	// Create text handler for file output
-	fileHandler := slog.NewTextHandler(logFile, &slog.HandlerOptions{
+	// Create JSON handler for file output, filtered by the in-memory handler in front of it
+	fileHandler := slog.NewJSONHandler(output, &slog.HandlerOptions{
 		Level: slog.LevelDebug,
 	})
 
 	// Create in-memory handler chained with file handler
-	memHandler := NewInMemoryHandler(maxSize, fileHandler)
+	memHandler := NewInMemoryHandler(maxSize, level, fileHandler)
 
-	// Create logger
-	logger := slog.New(memHandler)
-
-	return &Logger{
-		logger:  logger,
+	l := &Logger{
+		logger:  slog.New(memHandler),
 		handler: memHandler,
+		level:   level,
+		output:  output,
+	}
+	l.loadPersisted()
+	if err := l.Configure(utils.DefaultLogConfig()); err != nil {
+		// Fallback to stderr if file creation fails
+		l.Warn(fmt.Sprintf("Logging to stderr: %v", err))
+	}
+	return l
+}
+
+// Configure applies the minimum level and rotation limits, reopening the log file in the current data directory
+func (l *Logger) Configure(config utils.LogConfig) error {
+	level, err := utils.ParseLogLevel(config.Level)
+	if err != nil {
+		return err
+	}
+	l.level.Set(level)
+
+	file, err := utils.OpenRotatingFile(utils.LogPath(), config.MaxBytes, config.MaxFiles)
+	if err != nil {
+		l.output.set(nil)
+		return err
+	}
+	l.output.set(file)
+	return nil
+}
+
+// loadPersisted reads the most recent entries of the log file into memory
+func (l *Logger) loadPersisted() {
+	file, err := os.Open(utils.LogPath())
+	if err != nil {
+		return
 	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var line struct {
+			Time       time.Time  `json:"time"`
+			Level      slog.Level `json:"level"`
+			Msg        string     `json:"msg"`
+			Entity     string     `json:"entity"`
+			ID         *uint64    `json:"id"`
+			Operation  string     `json:"operation"`
+			DurationMs float64    `json:"durationMs"`
+		}
+		// Lines written by older versions are not JSON, they are skipped
+		if json.Unmarshal(scanner.Bytes(), &line) != nil {
+			continue
+		}
+		entry := newLogEntry(line.Time, line.Level, line.Msg)
+		entry.Entity, entry.ID, entry.Operation, entry.DurationMs = line.Entity, line.ID, line.Operation, line.DurationMs
+		l.handler.add(entry)
+	}
+}
+
+// Close closes the log file, later entries go to stderr
+func (l *Logger) Close() {
+	l.output.set(nil)
 }
 
 // Info logs at INFO level
@@ -145,6 +314,11 @@ func (l *Logger) Info(message string) {
 	l.logger.Info(message)
 }
 
+// InfoWith logs at INFO level with structured fields such as those from entityLog
+func (l *Logger) InfoWith(message string, attrs ...slog.Attr) {
+	l.logger.LogAttrs(context.Background(), slog.LevelInfo, message, attrs...)
+}
+
 // Debug logs at DEBUG level
 func (l *Logger) Debug(message string) {
 	l.logger.Debug(message)
@@ -165,9 +339,14 @@ func (l *Logger) Log(message string) {
 	l.logger.Info(message)
 }
 
-// GetLogs returns all current log entries
-func (l *Logger) GetLogs() []LogEntry {
-	return l.handler.GetLogs()
+// GetLogs returns the entries at or above level logged after since, keeping the most recent limit (0 keeps all)
+func (l *Logger) GetLogs(level slog.Level, limit int, since time.Time) []LogEntry {
+	return l.handler.GetLogs(level, limit, since)
+}
+
+// GetLogsForEntity returns the entries about one record, oldest first
+func (l *Logger) GetLogsForEntity(entity string, id uint64) []LogEntry {
+	return l.handler.GetLogsForEntity(entity, id)
 }
 
 // Clear removes all log entries
@@ -2,6 +2,8 @@ package main
 
 import (
 	"embed"
+	"flag"
+	"os"
 
 	"github.com/wailsapp/wails/v2"
 	"github.com/wailsapp/wails/v2/pkg/options"
@@ -15,10 +17,40 @@ var assets embed.FS
 // Set via: go build -ldflags "-X main.CleanupOnExit=true"
 var CleanupOnExit string = "false"
 
+// ReadOnly starts the app in read-only mode, where every binding that modifies data fails
+// Set via: go build -ldflags "-X main.ReadOnly=true"
+var ReadOnly string = "false"
+
 func main() {
+	serve := flag.String("serve", "", "also serve the REST API on this address (e.g. :8080) while the window is open")
+	metrics := flag.Bool("metrics", false, "with -serve, also expose Prometheus metrics on /metrics")
+	grpcAddr := flag.String("grpc", "", "also serve the gRPC API of proto/binarycrud.proto on this address (e.g. :9090)")
+	replicaOf := flag.String("replica-of", "", "mirror a primary read-only: tcp://host:port or the primary's data directory")
+	replicateOn := flag.String("replicate-on", "", "ship the oplog to replicas connecting to this address (e.g. :7070 for this machine, 0.0.0.0:7070 for any)")
+	flag.Parse()
+
 	// Create an instance of the app structure
 	app := NewApp()
 
+	if *replicaOf != "" {
+		if err := app.StartReplica(*replicaOf); err != nil {
+			app.releaseDataDir()
+			println("Error:", err.Error())
+			os.Exit(1)
+		}
+	}
+	if *replicateOn != "" {
+		if _, err := app.StartReplicationServer(*replicateOn); err != nil {
+			app.releaseDataDir()
+			println("Error:", err.Error())
+			os.Exit(1)
+		}
+	}
+
+	app.apiAddr = *serve
+	app.apiMetrics = *metrics
+	app.grpcAddr = *grpcAddr
+
 	// Create application with options
 	err := wails.Run(&options.App{
 		Title:  "BinaryCRUD",
@@ -30,6 +62,7 @@ func main() {
 		BackgroundColour: &options.RGBA{R: 27, G: 38, B: 54, A: 1},
 		OnStartup:        app.startup,
 		OnShutdown:       app.shutdown,
+		ErrorFormatter:   formatBindingError,
 		Bind: []interface{}{
 			app,
 		},
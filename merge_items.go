@@ -0,0 +1,206 @@
+package main
+
+import (
+	"BinaryCRUD/backend/dao"
+	"BinaryCRUD/backend/oplog"
+	"BinaryCRUD/backend/utils"
+	"fmt"
+	"time"
+)
+
+// replaceItemID returns a copy of itemIDs with every occurrence of from replaced by to, false if from isn't listed
+func replaceItemID(itemIDs []uint64, from, to uint64) ([]uint64, bool) {
+	replaced := make([]uint64, len(itemIDs))
+	found := false
+	for i, id := range itemIDs {
+		if id == from {
+			id, found = to, true
+		}
+		replaced[i] = id
+	}
+	return replaced, found
+}
+
+// updatePromotionItems recalculates the total for a new item list and rewrites the promotion record
+func (a *App) updatePromotionItems(promotion *dao.Collection, itemIDs []uint64) error {
+	priceResult, err := a.calculateTotalPrice(itemIDs, false, fmt.Sprintf("promotion #%d", promotion.ID))
+	if err != nil {
+		return err
+	}
+
+	if err := a.promotionDAO.Update(promotion.ID, promotion.OwnerOrName, priceResult.TotalPrice, itemIDs); err != nil {
+		return fmt.Errorf("failed to update promotion: %w", err)
+	}
+	a.recordOp(oplog.Operation{Type: oplog.OpUpdatePromotion, ID: promotion.ID, Name: promotion.OwnerOrName, Price: priceResult.TotalPrice, ItemIDs: itemIDs, Extensions: promotion.Extensions})
+	a.recordAudit(dao.AuditUpdate, "promotion", promotion.ID,
+		collectionSummary(promotion.TotalPrice, promotion.ItemIDs),
+		collectionSummary(priceResult.TotalPrice, itemIDs))
+	return nil
+}
+
+// moveOrderItems points an order at a new item list
+// Pending orders get their total recalculated, later ones keep the total they were charged
+func (a *App) moveOrderItems(order *dao.Collection, itemIDs []uint64) error {
+	if orderStatus(order) == utils.OrderPending {
+		return a.updateOrderItems(order, itemIDs)
+	}
+
+	if err := a.orderDAO.Update(order.ID, order.OwnerOrName, order.TotalPrice, itemIDs); err != nil {
+		return fmt.Errorf("failed to update order: %w", err)
+	}
+	a.recordOp(oplog.Operation{Type: oplog.OpUpdateOrder, ID: order.ID, Name: order.OwnerOrName, Price: order.TotalPrice, ItemIDs: itemIDs, Extensions: order.Extensions})
+	a.recordAudit(dao.AuditUpdate, "order", order.ID,
+		collectionSummary(order.TotalPrice, order.ItemIDs),
+		collectionSummary(order.TotalPrice, itemIDs))
+	return nil
+}
+
+// restoreCollection writes an order or promotion back as it was read before a merge that failed,
+// logged like any update so a replay ends in the same state
+func (a *App) restoreCollection(kind string, store *dao.CollectionDAO, opType oplog.OpType, collection *dao.Collection) error {
+	current, err := store.Read(collection.ID)
+	if err != nil {
+		return fmt.Errorf("failed to read %s %d: %w", kind, collection.ID, err)
+	}
+	if err := store.Update(collection.ID, collection.OwnerOrName, collection.TotalPrice, collection.ItemIDs); err != nil {
+		return fmt.Errorf("failed to restore %s %d: %w", kind, collection.ID, err)
+	}
+	a.recordOp(oplog.Operation{Type: opType, ID: collection.ID, Name: collection.OwnerOrName, Price: collection.TotalPrice, ItemIDs: collection.ItemIDs, Extensions: collection.Extensions})
+	a.recordAudit(dao.AuditUpdate, kind, collection.ID,
+		collectionSummary(current.TotalPrice, current.ItemIDs),
+		collectionSummary(collection.TotalPrice, collection.ItemIDs))
+	return nil
+}
+
+// restoreStock sets the stock of an item back to quantity after a merge that failed
+func (a *App) restoreStock(id, quantity uint64) error {
+	item, err := a.itemDAO.ReadItem(id)
+	if err != nil {
+		return fmt.Errorf("failed to read item %d: %w", id, err)
+	}
+	return a.setItemStock(item, quantity)
+}
+
+// MergeItems folds a duplicate item into another one: every order and promotion listing sourceID lists
+// targetID instead, the source's stock is added to the target's when both track stock and the source is deleted
+// Pending orders and promotions get their totals recalculated, orders past pending keep what they were charged
+// The orders and promotions to rewrite are found through the item index of their DAOs and all read first;
+// when a step fails, the steps already done are undone in reverse order, so no reference is left half-moved
+// The mapping is kept in the audit trail of the target; the rewrites and the deletion are logged like any
+// update and delete, so Undo writes the source back but leaves the references on the target
+// Returns the IDs of the orders and promotions that were rewritten
+func (a *App) MergeItems(sourceID, targetID uint64) (_ map[string]any, err error) {
+	start := time.Now()
+	defer a.track("MergeItems", start, &err)
+	if err := a.checkWritable(); err != nil {
+		return nil, err
+	}
+
+	if sourceID == targetID {
+		return nil, utils.WithCode(utils.CodeValidation, fmt.Errorf("cannot merge item %d into itself", sourceID), utils.RecordDetails("item", sourceID))
+	}
+	source, err := a.itemDAO.ReadItem(sourceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read source item: %w", err)
+	}
+	target, err := a.itemDAO.ReadItem(targetID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read target item: %w", err)
+	}
+
+	orders, err := a.readCollectionsListing("order", a.orderDAO.CollectionDAO, sourceID)
+	if err != nil {
+		return nil, err
+	}
+	promotions, err := a.readCollectionsListing("promotion", a.promotionDAO.CollectionDAO, sourceID)
+	if err != nil {
+		return nil, err
+	}
+
+	var undo []func() error
+	defer func() {
+		if err == nil {
+			return
+		}
+		for i := len(undo) - 1; i >= 0; i-- {
+			if undoErr := undo[i](); undoErr != nil {
+				a.logger.Error(fmt.Sprintf("Failed to roll back the merge of item #%d into #%d: %v", sourceID, targetID, undoErr))
+			}
+		}
+	}()
+
+	orderIDs := []uint64{}
+	for _, order := range orders {
+		itemIDs, _ := replaceItemID(order.ItemIDs, sourceID, targetID)
+		if err := a.moveOrderItems(order, itemIDs); err != nil {
+			return nil, fmt.Errorf("failed to merge item into order %d: %w", order.ID, err)
+		}
+		undo = append(undo, func() error {
+			return a.restoreCollection("order", a.orderDAO.CollectionDAO, oplog.OpUpdateOrder, order)
+		})
+		orderIDs = append(orderIDs, order.ID)
+	}
+
+	promotionIDs := []uint64{}
+	for _, promotion := range promotions {
+		itemIDs, _ := replaceItemID(promotion.ItemIDs, sourceID, targetID)
+		if err := a.updatePromotionItems(promotion, itemIDs); err != nil {
+			return nil, fmt.Errorf("failed to merge item into promotion %d: %w", promotion.ID, err)
+		}
+		undo = append(undo, func() error {
+			return a.restoreCollection("promotion", a.promotionDAO.CollectionDAO, oplog.OpUpdatePromotion, promotion)
+		})
+		promotionIDs = append(promotionIDs, promotion.ID)
+	}
+
+	// The source is emptied before it is deleted, so undoing the deletion doesn't count its stock twice
+	if sourceStock, tracked := itemStock(source); tracked && sourceStock > 0 {
+		if targetStock, tracked := itemStock(target); tracked {
+			if err := a.setItemStock(target, targetStock+sourceStock); err != nil {
+				return nil, err
+			}
+			undo = append(undo, func() error { return a.restoreStock(targetID, targetStock) })
+			if source, err = a.itemDAO.ReadItem(sourceID); err != nil {
+				return nil, fmt.Errorf("failed to read source item: %w", err)
+			}
+			if err := a.setItemStock(source, 0); err != nil {
+				return nil, err
+			}
+			undo = append(undo, func() error { return a.restoreStock(sourceID, sourceStock) })
+		}
+	}
+
+	if err := a.DeleteItem(sourceID); err != nil {
+		return nil, fmt.Errorf("failed to delete merged item: %w", err)
+	}
+	a.recordAudit(dao.AuditUpdate, "item", targetID, "", fmt.Sprintf("merged item #%d %q", sourceID, source.Name))
+
+	a.logger.InfoWith(fmt.Sprintf("Merged item #%d (%s) into #%d (%s): %d order(s), %d promotion(s) rewritten",
+		sourceID, source.Name, targetID, target.Name, len(orderIDs), len(promotionIDs)), entityLog("item", targetID, "merge", start)...)
+	a.toast.Success(fmt.Sprintf("Merged %s into %s", source.Name, target.Name))
+
+	return map[string]any{
+		"sourceId":     sourceID,
+		"targetId":     targetID,
+		"orderIds":     orderIDs,
+		"promotionIds": promotionIDs,
+	}, nil
+}
+
+// readCollectionsListing reads the active orders or promotions that list the item, found through the item
+// index of their DAO
+func (a *App) readCollectionsListing(kind string, store *dao.CollectionDAO, itemID uint64) ([]*dao.Collection, error) {
+	ids, err := store.FindByItem(itemID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find %ss listing item %d: %w", kind, itemID, err)
+	}
+	collections := make([]*dao.Collection, 0, len(ids))
+	for _, id := range ids {
+		collection, err := store.Read(id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s %d: %w", kind, id, err)
+		}
+		collections = append(collections, collection)
+	}
+	return collections, nil
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"BinaryCRUD/backend/utils"
+	"testing"
+)
+
+func TestMergeItems(t *testing.T) {
+	app := newTestApp(t)
+	sourceID, err := app.AddItem("Coke", 250)
+	if err != nil {
+		t.Fatalf("Failed to add item: %v", err)
+	}
+	targetID, err := app.AddItem("Coca-Cola", 300)
+	if err != nil {
+		t.Fatalf("Failed to add item: %v", err)
+	}
+	if _, err := app.AdjustStock(sourceID, 5); err != nil {
+		t.Fatalf("Failed to set stock: %v", err)
+	}
+	if _, err := app.AdjustStock(targetID, 10); err != nil {
+		t.Fatalf("Failed to set stock: %v", err)
+	}
+
+	pendingID, err := app.CreateOrder("Pending", []uint64{sourceID, sourceID})
+	if err != nil {
+		t.Fatalf("Failed to create order: %v", err)
+	}
+	paidID, err := app.CreateOrder("Paid", []uint64{sourceID})
+	if err != nil {
+		t.Fatalf("Failed to create order: %v", err)
+	}
+	if _, err := app.SetOrderStatus(paidID, "paid"); err != nil {
+		t.Fatalf("Failed to pay order: %v", err)
+	}
+	promotionID, err := app.CreatePromotion("Combo", []uint64{sourceID, targetID})
+	if err != nil {
+		t.Fatalf("Failed to create promotion: %v", err)
+	}
+
+	if _, err := app.MergeItems(sourceID, sourceID); utils.ErrorCodeOf(err) != utils.CodeValidation {
+		t.Errorf("Expected merging an item into itself to be rejected, got %v", err)
+	}
+
+	result, err := app.MergeItems(sourceID, targetID)
+	if err != nil {
+		t.Fatalf("Failed to merge items: %v", err)
+	}
+	if orders := result["orderIds"].([]uint64); len(orders) != 2 {
+		t.Errorf("Expected both orders rewritten, got %v", orders)
+	}
+	if promotions := result["promotionIds"].([]uint64); len(promotions) != 1 || promotions[0] != promotionID {
+		t.Errorf("Expected the promotion rewritten, got %v", promotions)
+	}
+
+	// The pending order is repriced, the paid one keeps what it was charged
+	pending, err := app.GetOrder(pendingID)
+	if err != nil {
+		t.Fatalf("Failed to read order: %v", err)
+	}
+	if pending["totalPrice"] != uint64(600) {
+		t.Errorf("Expected the pending order repriced to 600, got %v", pending["totalPrice"])
+	}
+	paid, err := app.GetOrder(paidID)
+	if err != nil {
+		t.Fatalf("Failed to read order: %v", err)
+	}
+	if paid["totalPrice"] != uint64(250) {
+		t.Errorf("Expected the paid order to keep its total of 250, got %v", paid["totalPrice"])
+	}
+
+	// The 2 units left of the source are added to the target
+	target, err := app.GetItem(targetID)
+	if err != nil {
+		t.Fatalf("Failed to read item: %v", err)
+	}
+	if target["stock"] != uint64(12) {
+		t.Errorf("Expected the target stock to be 12, got %v", target["stock"])
+	}
+	if _, err := app.GetItem(sourceID); err == nil {
+		t.Error("Expected the source item to be deleted")
+	}
+}
+
+func TestMergeItemsRollsBackOnFailure(t *testing.T) {
+	app := newTestApp(t)
+	sourceID, err := app.AddItem("Coke", 250)
+	if err != nil {
+		t.Fatalf("Failed to add item: %v", err)
+	}
+	targetID, err := app.AddItem("Coca-Cola", 300)
+	if err != nil {
+		t.Fatalf("Failed to add item: %v", err)
+	}
+	if _, err := app.AdjustStock(sourceID, 5); err != nil {
+		t.Fatalf("Failed to set stock: %v", err)
+	}
+	if _, err := app.AdjustStock(targetID, 10); err != nil {
+		t.Fatalf("Failed to set stock: %v", err)
+	}
+	orderID, err := app.CreateOrder("Pending", []uint64{sourceID})
+	if err != nil {
+		t.Fatalf("Failed to create order: %v", err)
+	}
+	if _, err := app.CreatePromotion("Combo", []uint64{sourceID}); err != nil {
+		t.Fatalf("Failed to create promotion: %v", err)
+	}
+
+	// The order is rewritten first, then the promotion's new total breaks its price rule
+	config := app.GetConfig()
+	config.Rules = map[string]utils.ValidationRule{"promotion": {MaxPrice: 280}}
+	if _, err := app.UpdateConfig(config); err != nil {
+		t.Fatalf("Failed to update config: %v", err)
+	}
+	if _, err := app.MergeItems(sourceID, targetID); utils.ErrorCodeOf(err) != utils.CodeValidation {
+		t.Fatalf("Expected the merge to fail on the promotion rule, got %v", err)
+	}
+
+	order, err := app.GetOrder(orderID)
+	if err != nil {
+		t.Fatalf("Failed to read order: %v", err)
+	}
+	if items := order["itemIDs"].([]uint64); len(items) != 1 || items[0] != sourceID || order["totalPrice"] != uint64(250) {
+		t.Errorf("Expected the order rolled back to the source at 250, got %v", order)
+	}
+	source, err := app.GetItem(sourceID)
+	if err != nil {
+		t.Fatalf("Expected the source item to be kept, got %v", err)
+	}
+	if source["stock"] != uint64(4) {
+		t.Errorf("Expected the source stock to be kept at 4, got %v", source["stock"])
+	}
+
+	// With the rule lifted the merge goes through, finding the order through the restored item index
+	config.Rules = nil
+	if _, err := app.UpdateConfig(config); err != nil {
+		t.Fatalf("Failed to update config: %v", err)
+	}
+	result, err := app.MergeItems(sourceID, targetID)
+	if err != nil {
+		t.Fatalf("Failed to merge items: %v", err)
+	}
+	if orders := result["orderIds"].([]uint64); len(orders) != 1 || orders[0] != orderID {
+		t.Errorf("Expected the order rewritten, got %v", orders)
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"BinaryCRUD/backend/dao"
+	"BinaryCRUD/backend/utils"
+	"net/http"
+	"time"
+)
+
+// observeCompaction records the duration of a compaction that started at start, unless it failed
+func (a *App) observeCompaction(start time.Time, err error) {
+	if err == nil {
+		a.metrics.ObserveCompaction(time.Since(start))
+	}
+}
+
+// metricsSnapshot copies the collected metrics with the index lookups of every DAO
+// Index lookups are counted since the DAOs were last reloaded
+func (a *App) metricsSnapshot() utils.MetricsSnapshot {
+	snapshot := a.metrics.Snapshot()
+	for _, stats := range []dao.IndexStats{
+		a.itemDAO.IndexStats(),
+		a.orderDAO.IndexStats(),
+		a.promotionDAO.IndexStats(),
+		a.orderPromotionDAO.IndexStats(),
+	} {
+		snapshot.IndexedReads += stats.IndexedReads
+		snapshot.FallbackScans += stats.FallbackScans
+	}
+	return snapshot
+}
+
+// GetMetrics reports the calls, failures, panics and time spent per binding, bytes written, the index hit ratio
+// and compaction durations since the app started
+func (a *App) GetMetrics() map[string]any {
+	defer a.track("GetMetrics", time.Now(), nil)
+	snapshot := a.metricsSnapshot()
+	return map[string]any{
+		"operations":            snapshot.Operations,
+		"errors":                snapshot.Errors,
+		"panics":                snapshot.Panics,
+		"operationSeconds":      snapshot.OperationSeconds,
+		"bytesWritten":          snapshot.BytesWritten,
+		"indexedReads":          snapshot.IndexedReads,
+		"fallbackScans":         snapshot.FallbackScans,
+		"indexHitRatio":         snapshot.IndexHitRatio(),
+		"compactions":           snapshot.Compactions,
+		"compactionSeconds":     snapshot.CompactionSeconds,
+		"maxCompactionSeconds":  snapshot.MaxCompactionSeconds,
+		"lastCompactionSeconds": snapshot.LastCompactionSeconds,
+	}
+}
+
+// serveMetrics writes the metrics in the Prometheus text format
+func (s *apiServer) serveMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if err := s.app.metricsSnapshot().WritePrometheus(w); err != nil {
+		s.app.logger.Warn("Failed to write metrics: " + err.Error())
+	}
+}
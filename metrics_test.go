@@ -0,0 +1,54 @@
+package main
+
+import (
+	"BinaryCRUD/backend/utils"
+	"testing"
+)
+
+func TestGetMetrics(t *testing.T) {
+	app := newTestApp(t)
+	compactWithDeletes(t, app)
+
+	// Cached items don't reach the index, an order read does
+	orderID, err := app.CreateOrder("Alice", []uint64{2})
+	if err != nil {
+		t.Fatalf("Failed to create order: %v", err)
+	}
+	if _, err := app.GetOrder(orderID); err != nil {
+		t.Fatalf("Failed to get order: %v", err)
+	}
+	if _, err := app.GetItem(2); err != nil {
+		t.Fatalf("Failed to get item: %v", err)
+	}
+	if _, err := app.GetItem(0); err == nil {
+		t.Fatal("Expected reading a compacted away item to fail")
+	}
+
+	metrics := app.GetMetrics()
+	operations := metrics["operations"].(map[string]uint64)
+	errors := metrics["errors"].(map[string]uint64)
+	if operations["AddItem"] != 4 || operations["DeleteItem"] != 2 || operations["GetItem"] != 2 {
+		t.Errorf("Expected 4 adds, 2 deletes and 2 reads, got %v", operations)
+	}
+	if errors["GetItem"] != 1 || errors["AddItem"] != 0 {
+		t.Errorf("Expected only the failed read counted as an error, got %v", errors)
+	}
+	if seconds := metrics["operationSeconds"].(map[string]float64); seconds["AddItem"] <= 0 {
+		t.Errorf("Expected time spent adding items, got %v", seconds)
+	}
+	// Bytes are counted from when the app started, not the process
+	if written := metrics["bytesWritten"].(uint64); written == 0 || written > utils.RecordBytesWritten() {
+		t.Errorf("Expected the writes of this app counted in bytesWritten, got %d", written)
+	}
+	if metrics["indexedReads"].(int) == 0 || metrics["indexHitRatio"].(float64) <= 0 {
+		t.Errorf("Expected indexed reads, got %v at ratio %v", metrics["indexedReads"], metrics["indexHitRatio"])
+	}
+	if metrics["compactions"] != 1 || metrics["lastCompactionSeconds"].(float64) <= 0 || metrics["maxCompactionSeconds"] != metrics["lastCompactionSeconds"] {
+		t.Errorf("Expected one compaction timed, got %v", metrics)
+	}
+
+	// GetMetrics counts itself once it returns
+	if operations := app.GetMetrics()["operations"].(map[string]uint64); operations["GetMetrics"] != 1 {
+		t.Errorf("Expected the earlier GetMetrics call counted, got %v", operations["GetMetrics"])
+	}
+}
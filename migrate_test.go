@@ -0,0 +1,98 @@
+package main
+
+import (
+	"BinaryCRUD/backend/utils"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMigrateDatabaseAfterCompactionSkipsThePreviousGeneration(t *testing.T) {
+	app := newTestApp(t)
+	compactWithDeletes(t, app)
+
+	files, err := app.MigrateDatabase()
+	if err != nil {
+		t.Fatalf("MigrateDatabase failed: %v", err)
+	}
+	for _, file := range files {
+		if filepath.Base(file["file"].(string)) == "items.bin" {
+			t.Errorf("Expected the previous generation to be left out, got %v", file)
+		}
+	}
+}
+
+func TestMigrateDatabaseUpgradesOlderFiles(t *testing.T) {
+	app := newTestApp(t)
+	for _, name := range []string{"Burger", "Fries", "Soda"} {
+		if _, err := app.AddItem(name, 499); err != nil {
+			t.Fatalf("Failed to add item: %v", err)
+		}
+	}
+	if err := app.DeleteItem(1); err != nil {
+		t.Fatalf("Failed to delete item: %v", err)
+	}
+
+	// Rewrite the item file with the header of the version before the wide nextId
+	app.closeDAOs()
+	path := utils.BinPath("items.bin")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read items: %v", err)
+	}
+	filename, entitiesCount, tombstoneCount, nextId, headerSize, err := utils.ReadHeaderFromBytes(data)
+	if err != nil {
+		t.Fatalf("Failed to read header: %v", err)
+	}
+	header, err := utils.WriteHeaderVersion(utils.FormatVersionWideNextID-1, filename, entitiesCount, tombstoneCount, nextId)
+	if err != nil {
+		t.Fatalf("Failed to write header: %v", err)
+	}
+	if err := os.WriteFile(path, append(header, data[headerSize:]...), 0644); err != nil {
+		t.Fatalf("Failed to write items: %v", err)
+	}
+
+	files, err := app.MigrateDatabase()
+	if err != nil {
+		t.Fatalf("MigrateDatabase failed: %v", err)
+	}
+	migrated := 0
+	for _, file := range files {
+		if file["migrated"] != true {
+			continue
+		}
+		migrated++
+		if filepath.Base(file["file"].(string)) != "items.bin" || file["fromVersion"] != utils.FormatVersionWideNextID-1 || file["toVersion"] != utils.CurrentFormatVersion {
+			t.Errorf("Expected only the item file upgraded, got %v", file)
+		}
+	}
+	if migrated != 1 {
+		t.Errorf("Expected 1 migrated file, got %d", migrated)
+	}
+
+	// The records read back as they were written, deleted ones included
+	items, err := app.GetAllItems(ListOptions{ActiveOnly: true})
+	if err != nil {
+		t.Fatalf("Failed to list items: %v", err)
+	}
+	if len(items) != 2 || items[0]["name"] != "Burger" || items[1]["name"] != "Soda" {
+		t.Errorf("Expected Burger and Soda, got %v", items)
+	}
+	if _, err := app.GetItem(1); err == nil {
+		t.Error("Expected the deleted item to stay deleted")
+	}
+	if id, err := app.AddItem("Salad", 699); err != nil || id != 3 {
+		t.Errorf("Expected the next ID to carry over, got %d (err %v)", id, err)
+	}
+
+	// Once upgraded there is nothing left to migrate
+	files, err = app.MigrateDatabase()
+	if err != nil {
+		t.Fatalf("MigrateDatabase failed: %v", err)
+	}
+	for _, file := range files {
+		if file["migrated"] == true {
+			t.Errorf("Expected no file migrated twice, got %v", file)
+		}
+	}
+}
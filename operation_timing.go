@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+	"runtime/debug"
+	"time"
+)
+
+// track is deferred first thing by every binding, so a panic anywhere below it surfaces as an error
+// It recovers a panic into *err with the stack trace in the log, counts the call in the metrics and logs it
+// when it ran longer than the slowOperationMs config; err is nil for bindings without an error result
+func (a *App) track(operation string, start time.Time, err *error) {
+	var failure error
+	if recovered := recover(); recovered != nil {
+		failure = fmt.Errorf("%s failed unexpectedly: %v", operation, recovered)
+		a.logger.Error(fmt.Sprintf("%s panicked: %v\n%s", operation, recovered, debug.Stack()))
+		a.metrics.ObservePanic(operation)
+		if err != nil {
+			*err = failure
+		}
+	} else if err != nil {
+		failure = *err
+	}
+
+	elapsed := time.Since(start)
+	a.metrics.ObserveOperation(operation, elapsed, failure)
+
+	threshold := time.Duration(a.currentConfig().SlowOperationMs) * time.Millisecond
+	if threshold > 0 && elapsed > threshold {
+		a.logger.Warn(fmt.Sprintf("Slow operation: %s took %v (threshold %v)", operation, elapsed.Round(time.Millisecond), threshold))
+	}
+}
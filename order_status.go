@@ -0,0 +1,115 @@
+package main
+
+import (
+	"BinaryCRUD/backend/dao"
+	"BinaryCRUD/backend/oplog"
+	"BinaryCRUD/backend/utils"
+	"fmt"
+	"time"
+)
+
+// orderStatus returns the lifecycle status of an order, unreadable statuses count as pending
+func orderStatus(order *dao.Collection) byte {
+	status, err := utils.DecodeOrderStatus(order.Extensions)
+	if err != nil {
+		return utils.OrderPending
+	}
+	return status
+}
+
+// requirePendingOrder rejects changes to the items of an order that is no longer pending
+func requirePendingOrder(order *dao.Collection) error {
+	if status := orderStatus(order); status != utils.OrderPending {
+		return fmt.Errorf("order %d is %s and can no longer be modified", order.ID, utils.OrderStatusName(status))
+	}
+	return nil
+}
+
+// SetOrderStatus moves an order to a new status ("pending", "paid", "shipped" or "cancelled")
+// Allowed transitions: pending -> paid | cancelled, paid -> shipped | cancelled
+// Cancelling an order returns its items to stock
+func (a *App) SetOrderStatus(orderID uint64, status string) (_ map[string]any, err error) {
+	start := time.Now()
+	defer a.track("SetOrderStatus", start, &err)
+	if err := a.checkWritable(); err != nil {
+		return nil, err
+	}
+
+	newStatus, err := utils.ParseOrderStatus(status)
+	if err != nil {
+		return nil, err
+	}
+
+	order, err := a.orderDAO.Read(orderID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read order: %w", err)
+	}
+
+	current := orderStatus(order)
+	if err := utils.ValidateOrderTransition(current, newStatus); err != nil {
+		return nil, err
+	}
+
+	ext := utils.WithExtension(order.Extensions, utils.ExtOrderStatus, []byte{newStatus})
+	if err := a.orderDAO.UpdateExtensions(orderID, ext); err != nil {
+		return nil, fmt.Errorf("failed to update order: %w", err)
+	}
+	a.recordOp(oplog.Operation{Type: oplog.OpUpdateOrder, ID: orderID, Name: order.OwnerOrName,
+		Price: order.TotalPrice, ItemIDs: order.ItemIDs, Extensions: ext})
+	a.recordAudit(dao.AuditUpdate, "order", orderID,
+		"status "+utils.OrderStatusName(current), "status "+utils.OrderStatusName(newStatus))
+
+	if newStatus == utils.OrderCancelled {
+		a.restock(order.ItemIDs)
+	}
+
+	a.logger.InfoWith(fmt.Sprintf("Order #%d status changed from %s to %s",
+		orderID, utils.OrderStatusName(current), utils.OrderStatusName(newStatus)), entityLog("order", orderID, "set_status", start)...)
+
+	return a.GetOrder(orderID)
+}
+
+// newOrderExtensions returns the extensions stored on a newly created order
+func newOrderExtensions(createdAt time.Time) map[byte][]byte {
+	return map[byte][]byte{utils.ExtCreatedAt: utils.EncodeTimestamp(createdAt)}
+}
+
+// orderCreatedAt returns when an order was created, false for orders created before timestamps were recorded
+func orderCreatedAt(order *dao.Collection) (time.Time, bool) {
+	createdAt, ok, err := utils.DecodeTimestamp(order.Extensions, utils.ExtCreatedAt)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return createdAt, ok
+}
+
+// addOrderFields adds the status and creation time of an order to an API response map
+func addOrderFields(result map[string]any, order *dao.Collection) map[string]any {
+	result["status"] = utils.OrderStatusName(orderStatus(order))
+	if createdAt, ok := orderCreatedAt(order); ok {
+		result["createdAt"] = createdAt.Format(time.RFC3339)
+	}
+	return result
+}
+
+// orderExtensionsFromEntry rebuilds order extensions from exported status and RFC3339 creation time
+func orderExtensionsFromEntry(status, createdAt string) (map[byte][]byte, error) {
+	ext := map[byte][]byte{}
+	if status != "" {
+		s, err := utils.ParseOrderStatus(status)
+		if err != nil {
+			return nil, err
+		}
+		if s != utils.OrderPending {
+			ext[utils.ExtOrderStatus] = []byte{s}
+		}
+	}
+	if createdAt != "" {
+		t, err := time.Parse(time.RFC3339Nano, createdAt)
+		if err != nil {
+			return nil, fmt.Errorf("invalid createdAt %q: %w", createdAt, err)
+		}
+		ext[utils.ExtCreatedAt] = utils.EncodeTimestamp(t)
+	}
+	return ext, nil
+}
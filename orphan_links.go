@@ -0,0 +1,95 @@
+package main
+
+import (
+	"BinaryCRUD/backend/dao"
+	"BinaryCRUD/backend/oplog"
+	"BinaryCRUD/backend/utils"
+	"fmt"
+	"time"
+)
+
+// endpointGone reports whether a read failed because the record was deleted or no longer exists
+func endpointGone(err error) bool {
+	code := utils.ErrorCodeOf(err)
+	return code == utils.CodeNotFound || code == utils.CodeDeleted
+}
+
+// CleanOrphanLinks removes the order-promotion links whose order or promotion was deleted, or removed by
+// compaction, which would otherwise show up as "Deleted Order" or "Deleted Promotion" for good
+// The orphans are tombstoned in a single pass over order_promotions.bin and logged like
+// RemovePromotionFromOrder, but not kept for Undo since their endpoints can't come back with them
+// Returns the number of links checked, the orphans removed and how many lacked an order or a promotion
+func (a *App) CleanOrphanLinks() (_ map[string]any, err error) {
+	start := time.Now()
+	defer a.track("CleanOrphanLinks", start, &err)
+	if err := a.checkWritable(); err != nil {
+		return nil, err
+	}
+
+	links, err := a.orderPromotionDAO.GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read order promotions: %w", err)
+	}
+
+	// Each order and promotion is read once, however many links point at it
+	orders := make(map[uint64]bool)
+	promotions := make(map[uint64]bool)
+	exists := func(known map[uint64]bool, id uint64, read func(uint64) (*dao.Collection, error)) (bool, error) {
+		if found, checked := known[id]; checked {
+			return found, nil
+		}
+		_, err := read(id)
+		if err != nil && !endpointGone(err) {
+			return false, err
+		}
+		known[id] = err == nil
+		return known[id], nil
+	}
+
+	var orphans []dao.OrderPromotion
+	missingOrders, missingPromotions := 0, 0
+	for _, link := range links {
+		orderFound, err := exists(orders, link.OrderID, a.orderDAO.Read)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read order #%d: %w", link.OrderID, err)
+		}
+		promotionFound, err := exists(promotions, link.PromotionID, a.promotionDAO.Read)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read promotion #%d: %w", link.PromotionID, err)
+		}
+		if orderFound && promotionFound {
+			continue
+		}
+		if !orderFound {
+			missingOrders++
+		}
+		if !promotionFound {
+			missingPromotions++
+		}
+		orphans = append(orphans, *link)
+	}
+
+	removed, err := a.orderPromotionDAO.DeleteMany(orphans)
+	if err != nil {
+		return nil, fmt.Errorf("failed to remove orphan links: %w", err)
+	}
+	for _, link := range removed {
+		op := oplog.Operation{Type: oplog.OpRemovePromotion, OrderID: link.OrderID, PromotionID: link.PromotionID}
+		a.recordOp(op)
+		a.undo.drop(op)
+		a.recordAudit(dao.AuditUpdate, "order", link.OrderID, fmt.Sprintf("promotion #%d applied", link.PromotionID), "")
+	}
+
+	a.logger.Info(fmt.Sprintf("Removed %d orphan order-promotion links of %d in %s (%d without order, %d without promotion)",
+		len(removed), len(links), time.Since(start), missingOrders, missingPromotions))
+	if len(removed) > 0 {
+		a.toast.Success(fmt.Sprintf("Removed %d orphan link(s)", len(removed)))
+		a.checkCompactionPolicy()
+	}
+	return map[string]any{
+		"checked":           len(links),
+		"removed":           len(removed),
+		"missingOrders":     missingOrders,
+		"missingPromotions": missingPromotions,
+	}, nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"BinaryCRUD/backend/oplog"
+	"testing"
+)
+
+func TestCleanOrphanLinks(t *testing.T) {
+	app := newTestApp(t)
+	config := app.GetConfig()
+	config.AutoCompact = false
+	if _, err := app.UpdateConfig(config); err != nil {
+		t.Fatalf("Failed to disable automatic compaction: %v", err)
+	}
+
+	itemID, err := app.AddItem("Burger", 1000)
+	if err != nil {
+		t.Fatalf("Failed to add item: %v", err)
+	}
+	promotionID, err := app.CreatePromotion("Combo", []uint64{itemID})
+	if err != nil {
+		t.Fatalf("Failed to create promotion: %v", err)
+	}
+	var orderIDs []uint64
+	for _, customer := range []string{"Gone", "Kept"} {
+		id, err := app.CreateOrder(customer, []uint64{itemID})
+		if err != nil {
+			t.Fatalf("Failed to create order: %v", err)
+		}
+		if err := app.ApplyPromotionToOrder(id, promotionID); err != nil {
+			t.Fatalf("Failed to apply promotion: %v", err)
+		}
+		orderIDs = append(orderIDs, id)
+	}
+
+	// Compaction drops the deleted order but leaves its link behind
+	if err := app.DeleteOrder(orderIDs[0]); err != nil {
+		t.Fatalf("Failed to delete order: %v", err)
+	}
+	if _, err := app.Compact(); err != nil {
+		t.Fatalf("Failed to compact: %v", err)
+	}
+
+	result, err := app.CleanOrphanLinks()
+	if err != nil {
+		t.Fatalf("Failed to clean orphan links: %v", err)
+	}
+	if result["checked"] != 2 || result["removed"] != 1 || result["missingOrders"] != 1 || result["missingPromotions"] != 0 {
+		t.Errorf("Expected 1 of 2 links removed for a missing order, got %v", result)
+	}
+	if orders, _ := app.GetPromotionOrders(promotionID); len(orders) != 1 {
+		t.Errorf("Expected the kept order still linked, got %v", orders)
+	}
+
+	// The removal is not offered to Undo, which would link the missing order again
+	if entries := app.GetUndoHistory(); len(entries) > 0 && entries[0]["operation"] == oplog.OpRemovePromotion.String() {
+		t.Errorf("Expected orphan removals kept out of the undo history, got %v", entries[0])
+	}
+
+	result, err = app.CleanOrphanLinks()
+	if err != nil || result["removed"] != 0 {
+		t.Errorf("Expected nothing left to clean, got %v (err %v)", result, err)
+	}
+}
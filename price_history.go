@@ -0,0 +1,37 @@
+package main
+
+import (
+	"BinaryCRUD/backend/dao"
+	"fmt"
+	"time"
+)
+
+// recordPriceChange appends the previous price of an item to the price history
+// Failures are logged but never fail the update itself
+func (a *App) recordPriceChange(itemID, oldPrice, newPrice uint64) {
+	change := dao.PriceChange{ItemID: itemID, Timestamp: time.Now().UTC(), OldPrice: oldPrice, NewPrice: newPrice}
+	if _, err := a.priceHistoryDAO.Write(change); err != nil {
+		a.logger.Warn(fmt.Sprintf("Failed to record price change of item #%d: %v", itemID, err))
+	}
+}
+
+// GetItemPriceHistory returns the price changes of an item, oldest first
+func (a *App) GetItemPriceHistory(id uint64) (_ []map[string]any, err error) {
+	defer a.track("GetItemPriceHistory", time.Now(), &err)
+	changes, err := a.priceHistoryDAO.GetByItemID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]map[string]any, len(changes))
+	for i, change := range changes {
+		result[i] = map[string]any{
+			"timestamp":       change.Timestamp.Format(time.RFC3339),
+			"oldPriceInCents": change.OldPrice,
+			"newPriceInCents": change.NewPrice,
+		}
+	}
+
+	a.logger.Info(fmt.Sprintf("Retrieved %d price changes for item #%d", len(result), id))
+	return result, nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestGetItemPriceHistory(t *testing.T) {
+	app := newTestApp(t)
+	for _, name := range []string{"Burger", "Fries"} {
+		if _, err := app.AddItem(name, 499); err != nil {
+			t.Fatalf("Failed to add item: %v", err)
+		}
+	}
+	for _, update := range []struct {
+		id    uint64
+		name  string
+		price uint64
+	}{
+		{0, "Burger", 599},
+		{1, "Fries", 349},
+		{0, "Cheeseburger", 599}, // a rename keeps the price, so it isn't a change
+		{0, "Cheeseburger", 649},
+	} {
+		if _, err := app.UpdateItem(update.id, update.name, update.price); err != nil {
+			t.Fatalf("Failed to update item: %v", err)
+		}
+	}
+
+	prices := func(changes []map[string]any) string {
+		result := ""
+		for _, change := range changes {
+			if _, err := time.Parse(time.RFC3339, change["timestamp"].(string)); err != nil {
+				t.Errorf("Expected an RFC3339 timestamp, got %v", change["timestamp"])
+			}
+			result += fmt.Sprintf("%v->%v ", change["oldPriceInCents"], change["newPriceInCents"])
+		}
+		return result
+	}
+
+	// Each item only gets its own changes, oldest first
+	burger, err := app.GetItemPriceHistory(0)
+	if err != nil {
+		t.Fatalf("Failed to get price history: %v", err)
+	}
+	if got := prices(burger); got != "499->599 599->649 " {
+		t.Errorf("Expected two changes for Burger, got %q", got)
+	}
+	fries, err := app.GetItemPriceHistory(1)
+	if err != nil {
+		t.Fatalf("Failed to get price history: %v", err)
+	}
+	if got := prices(fries); got != "499->349 " {
+		t.Errorf("Expected one change for Fries, got %q", got)
+	}
+
+	if none, err := app.GetItemPriceHistory(42); err != nil || len(none) != 0 {
+		t.Errorf("Expected no changes for an unknown item, got %v (err %v)", none, err)
+	}
+}
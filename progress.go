@@ -0,0 +1,184 @@
+package main
+
+import (
+	"BinaryCRUD/backend/utils"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// ProgressEvent is the Wails event carrying the progress of long operations
+const ProgressEvent = "progress"
+
+// progressInterval is the minimum time between two progress events of an operation
+const progressInterval = 100 * time.Millisecond
+
+// Progress is the state of a running long operation, emitted as ProgressEvent
+// Done and Total count the units of the operation (records, files or bytes), Total is 0 while unknown
+type Progress struct {
+	Token     string `json:"token"` // identifies the run, for CancelOperation
+	Operation string `json:"operation"`
+	Done      int64  `json:"done"`
+	Total     int64  `json:"total"`
+	Finished  bool   `json:"finished"`
+}
+
+// operationRegistry holds the long operations that are running, by token
+type operationRegistry struct {
+	mu      sync.Mutex
+	running map[string]*operationProgress
+	next    uint64
+}
+
+// operationProgress reports the progress of one run of a long operation
+// Its context is cancelled by CancelOperation, the operation checks Err between units of work
+type operationProgress struct {
+	app      *App
+	ctx      context.Context
+	cancel   context.CancelFunc
+	mu       sync.Mutex
+	progress Progress
+	lastEmit time.Time
+}
+
+// startProgress registers a run of operation with total units and emits its first event
+// Finish must be called when the run ends, whether it succeeded or not
+func (a *App) startProgress(operation string, total int64) *operationProgress {
+	parent := a.ctx
+	if parent == nil {
+		parent = context.Background()
+	}
+	ctx, cancel := context.WithCancel(parent)
+
+	a.operations.mu.Lock()
+	a.operations.next++
+	token := fmt.Sprintf("%s-%d", operation, a.operations.next)
+	p := &operationProgress{
+		app:      a,
+		ctx:      ctx,
+		cancel:   cancel,
+		progress: Progress{Token: token, Operation: operation, Total: total},
+	}
+	if a.operations.running == nil {
+		a.operations.running = make(map[string]*operationProgress)
+	}
+	a.operations.running[token] = p
+	a.operations.mu.Unlock()
+
+	p.emit(true)
+	return p
+}
+
+// AddTotal grows the total once more of the work is known
+func (p *operationProgress) AddTotal(units int64) {
+	p.mu.Lock()
+	p.progress.Total += units
+	p.mu.Unlock()
+	p.emit(false)
+}
+
+// Advance records units of finished work
+func (p *operationProgress) Advance(units int64) {
+	p.mu.Lock()
+	p.progress.Done += units
+	p.mu.Unlock()
+	p.emit(false)
+}
+
+// Err returns a Cancelled error once the run was cancelled
+func (p *operationProgress) Err() error {
+	if p.ctx.Err() == nil {
+		return nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return utils.WithCode(utils.CodeCancelled, fmt.Errorf("%s was cancelled after %d of %d", p.progress.Operation, p.progress.Done, p.progress.Total), map[string]any{"token": p.progress.Token})
+}
+
+// Finish unregisters the run and emits its last event
+func (p *operationProgress) Finish() {
+	p.app.operations.mu.Lock()
+	delete(p.app.operations.running, p.progress.Token)
+	p.app.operations.mu.Unlock()
+
+	p.mu.Lock()
+	p.progress.Finished = true
+	p.mu.Unlock()
+	p.emit(true)
+	p.cancel()
+}
+
+// emit sends the progress to the frontend, at most once per progressInterval unless forced
+func (p *operationProgress) emit(force bool) {
+	p.mu.Lock()
+	if !force && time.Since(p.lastEmit) < progressInterval && p.progress.Done < p.progress.Total {
+		p.mu.Unlock()
+		return
+	}
+	p.lastEmit = time.Now()
+	progress := p.progress
+	p.mu.Unlock()
+
+	if p.app.ctx == nil {
+		return
+	}
+	runtime.EventsEmit(p.app.ctx, ProgressEvent, progress)
+}
+
+// Reader counts the bytes read from r as progress, failing the read once the run is cancelled
+func (p *operationProgress) Reader(r io.Reader) io.Reader {
+	return &progressReader{reader: r, progress: p}
+}
+
+// progressReader is the io.Reader returned by operationProgress.Reader
+type progressReader struct {
+	reader   io.Reader
+	progress *operationProgress
+}
+
+// Read reads from the wrapped reader and records the bytes read
+func (r *progressReader) Read(buf []byte) (int, error) {
+	if err := r.progress.Err(); err != nil {
+		return 0, err
+	}
+	n, err := r.reader.Read(buf)
+	r.progress.Advance(int64(n))
+	return n, err
+}
+
+// GetOperations returns the long operations that are running
+func (a *App) GetOperations() []Progress {
+	defer a.track("GetOperations", time.Now(), nil)
+	a.operations.mu.Lock()
+	defer a.operations.mu.Unlock()
+
+	operations := make([]Progress, 0, len(a.operations.running))
+	for _, p := range a.operations.running {
+		p.mu.Lock()
+		operations = append(operations, p.progress)
+		p.mu.Unlock()
+	}
+	sort.Slice(operations, func(i, j int) bool { return operations[i].Token < operations[j].Token })
+	return operations
+}
+
+// CancelOperation aborts the running operation with token
+// The operation stops at its next check and fails with a Cancelled error; records it already wrote are kept
+func (a *App) CancelOperation(token string) (err error) {
+	defer a.track("CancelOperation", time.Now(), &err)
+	a.operations.mu.Lock()
+	p, ok := a.operations.running[token]
+	a.operations.mu.Unlock()
+	if !ok {
+		return utils.WithCode(utils.CodeNotFound, fmt.Errorf("no running operation %q", token), map[string]any{"token": token})
+	}
+
+	p.cancel()
+	a.logger.Info(fmt.Sprintf("Cancelling %s", token))
+	return nil
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"BinaryCRUD/backend/utils"
+	"testing"
+	"time"
+)
+
+func TestCancelOperation(t *testing.T) {
+	app := newTestApp(t)
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := app.GenerateSeedData(20000, 0, 0, 1)
+		done <- err
+	}()
+
+	var operations []Progress
+	deadline := time.Now().Add(5 * time.Second)
+	for len(operations) == 0 && time.Now().Before(deadline) {
+		operations = app.GetOperations()
+		time.Sleep(time.Millisecond)
+	}
+	if len(operations) != 1 || operations[0].Operation != "GenerateSeedData" || operations[0].Total != 20000 {
+		t.Fatalf("Expected the running GenerateSeedData, got %+v", operations)
+	}
+	if err := app.CancelOperation(operations[0].Token); err != nil {
+		t.Fatalf("Failed to cancel %s: %v", operations[0].Token, err)
+	}
+
+	if err := <-done; utils.ErrorCodeOf(err) != utils.CodeCancelled {
+		t.Fatalf("Expected a Cancelled error, got %v", err)
+	}
+	if operations := app.GetOperations(); len(operations) != 0 {
+		t.Errorf("Expected no running operation after the cancellation, got %+v", operations)
+	}
+	if err := app.CancelOperation(operations[0].Token); utils.ErrorCodeOf(err) != utils.CodeNotFound {
+		t.Errorf("Expected NotFound cancelling a finished operation, got %v", err)
+	}
+}
+
+func TestRebuildIndexes(t *testing.T) {
+	app := newTestApp(t)
+	if _, err := app.GenerateSeedData(20, 10, 3, 1); err != nil {
+		t.Fatalf("Failed to generate seed data: %v", err)
+	}
+	if err := app.RebuildIndexes(); err != nil {
+		t.Fatalf("Failed to rebuild the indexes: %v", err)
+	}
+
+	if count := app.itemDAO.GetIndexTree().Size(); count != 20 {
+		t.Errorf("Expected 20 items in the rebuilt index, got %d", count)
+	}
+	if _, err := app.GetOrder(9); err != nil {
+		t.Errorf("Failed to read an order through the rebuilt index: %v", err)
+	}
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"BinaryCRUD/backend/dao"
+	"BinaryCRUD/backend/query"
+	"BinaryCRUD/backend/utils"
+	"fmt"
+	"math"
+	"time"
+)
+
+// lookupID returns the record ID of an id = N condition, ok is false for other conditions
+func lookupID(condition query.Condition) (uint64, bool) {
+	number, isNumber := condition.Value.(float64)
+	if condition.Column != "id" || !isNumber || number < 0 || number != math.Trunc(number) {
+		return 0, false
+	}
+	return uint64(number), true
+}
+
+// itemSource exposes items.bin to queries
+type itemSource struct {
+	itemDAO *dao.ItemDAO
+}
+
+// Columns lists the item columns
+func (s itemSource) Columns() []string {
+	return []string{"id", "name", "price", "currency", "stock"}
+}
+
+// row converts an item to a query row
+func (s itemSource) row(item *dao.Item) query.Row {
+	row := query.Row{
+		"id":       item.ID,
+		"name":     item.Name,
+		"price":    item.PriceInCents,
+		"currency": utils.BaseCurrency,
+		"stock":    nil,
+	}
+	if code := itemCurrency(item); code != "" {
+		row["currency"] = code
+	}
+	if quantity, tracked := itemStock(item); tracked {
+		row["stock"] = quantity
+	}
+	return row
+}
+
+// Scan returns every active item
+func (s itemSource) Scan() ([]query.Row, error) {
+	items, err := s.itemDAO.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	rows := make([]query.Row, 0, len(items))
+	for i := range items {
+		if !items[i].IsDeleted {
+			rows = append(rows, s.row(&items[i]))
+		}
+	}
+	return rows, nil
+}
+
+// Lookup uses the B+ tree for id and the name index for name
+// A failed read falls back to the scan, which reports real errors
+func (s itemSource) Lookup(condition query.Condition) ([]query.Row, bool, error) {
+	var ids []uint64
+	if id, ok := lookupID(condition); ok {
+		ids = []uint64{id}
+	} else if name, isText := condition.Value.(string); condition.Column == "name" && isText {
+		found, err := s.itemDAO.FindByName(name)
+		if err != nil {
+			return nil, false, nil
+		}
+		ids = found
+	} else {
+		return nil, false, nil
+	}
+
+	rows := make([]query.Row, 0, len(ids))
+	for _, id := range ids {
+		item, err := s.itemDAO.ReadItem(id)
+		if err != nil {
+			return nil, false, nil
+		}
+		rows = append(rows, s.row(item))
+	}
+	return rows, true, nil
+}
+
+// collectionSource exposes orders.bin or promotions.bin to queries
+type collectionSource struct {
+	collectionDAO *dao.CollectionDAO
+	isOrder       bool
+}
+
+// Columns lists the order or promotion columns
+func (s collectionSource) Columns() []string {
+	if s.isOrder {
+		return []string{"id", "customer", "total", "items", "status", "createdAt"}
+	}
+	return []string{"id", "name", "total", "items", "discountType", "discountValue"}
+}
+
+// row converts an order or promotion to a query row
+func (s collectionSource) row(collection *dao.Collection) query.Row {
+	row := query.Row{
+		"id":    collection.ID,
+		"total": collection.TotalPrice,
+		"items": collection.ItemCount,
+	}
+	if s.isOrder {
+		row["customer"] = collection.OwnerOrName
+		row["status"] = utils.OrderStatusName(orderStatus(collection))
+		row["createdAt"] = nil
+		if createdAt, ok := orderCreatedAt(collection); ok {
+			row["createdAt"] = createdAt.Format(time.RFC3339)
+		}
+	} else {
+		discount := promotionDiscount(collection)
+		row["name"] = collection.OwnerOrName
+		row["discountType"] = utils.DiscountTypeName(discount.Type)
+		row["discountValue"] = discount.Value
+	}
+	return row
+}
+
+// Scan returns every active order or promotion
+func (s collectionSource) Scan() ([]query.Row, error) {
+	collections, err := s.collectionDAO.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	rows := make([]query.Row, 0, len(collections))
+	for _, collection := range collections {
+		if !collection.IsDeleted {
+			rows = append(rows, s.row(collection))
+		}
+	}
+	return rows, nil
+}
+
+// Lookup uses the B+ tree for id
+func (s collectionSource) Lookup(condition query.Condition) ([]query.Row, bool, error) {
+	id, ok := lookupID(condition)
+	if !ok {
+		return nil, false, nil
+	}
+	collection, err := s.collectionDAO.Read(id)
+	if err != nil {
+		return nil, false, nil
+	}
+	return []query.Row{s.row(collection)}, true, nil
+}
+
+// querySource returns the source for a table name
+func (a *App) querySource(table string) (query.Source, error) {
+	switch table {
+	case "items":
+		return itemSource{itemDAO: a.itemDAO}, nil
+	case "orders":
+		return collectionSource{collectionDAO: a.orderDAO.CollectionDAO, isOrder: true}, nil
+	case "promotions":
+		return collectionSource{collectionDAO: a.promotionDAO.CollectionDAO}, nil
+	}
+	return nil, fmt.Errorf("unknown table %q (expected items, orders or promotions)", table)
+}
+
+// Query runs a read-only SELECT statement against items, orders or promotions
+// e.g. SELECT * FROM items WHERE price > 500 ORDER BY name LIMIT 20
+// Deleted records are never returned; prices and totals are in cents
+func (a *App) Query(statement string) (_ map[string]any, err error) {
+	defer a.track("Query", time.Now(), &err)
+	q, err := query.Parse(statement)
+	if err != nil {
+		return nil, fmt.Errorf("invalid query: %w", err)
+	}
+	source, err := a.querySource(q.Table)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	result, err := query.Execute(q, source)
+	if err != nil {
+		return nil, fmt.Errorf("query failed: %w", err)
+	}
+	elapsed := time.Since(start)
+
+	rows := make([]map[string]any, len(result.Rows))
+	for i, row := range result.Rows {
+		rows[i] = row
+	}
+
+	a.logger.Info(fmt.Sprintf("Query on %s returned %d row(s) using %s in %s", q.Table, len(rows), result.Plan, elapsed))
+
+	return map[string]any{
+		"columns":   result.Columns,
+		"rows":      rows,
+		"count":     len(rows),
+		"plan":      result.Plan,
+		"elapsedMs": float64(elapsed.Microseconds()) / 1000,
+	}, nil
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"BinaryCRUD/backend/utils"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// checkWritable returns an error when the app may not modify the data directory
+func (a *App) checkWritable() error {
+	if a.readOnlyReason != "" {
+		return utils.WithCode(utils.CodeConflict, fmt.Errorf("%w: %s", errReadOnly, a.readOnlyReason), map[string]any{"readOnly": true})
+	}
+	if a.readOnly {
+		return utils.WithCode(utils.CodeConflict, fmt.Errorf("%w: changes to the data are disabled", errReadOnly), map[string]any{"readOnly": true})
+	}
+	return nil
+}
+
+// isReadOnlyError reports whether err was returned by checkWritable
+func isReadOnlyError(err error) bool {
+	return errors.Is(err, errReadOnly)
+}
+
+// GetReadOnly returns whether data changes are disabled and why
+func (a *App) GetReadOnly() map[string]any {
+	defer a.track("GetReadOnly", time.Now(), nil)
+	reason := a.readOnlyReason
+	if reason == "" && a.readOnly {
+		reason = "read-only mode enabled"
+	}
+	return map[string]any{
+		"readOnly": a.checkWritable() != nil,
+		"reason":   reason,
+	}
+}
+
+// SetReadOnly toggles read-only mode at runtime
+// Leaving read-only mode takes the data directory lock, and fails while another instance holds it
+func (a *App) SetReadOnly(enabled bool) (err error) {
+	defer a.track("SetReadOnly", time.Now(), &err)
+	if !enabled && a.isReplica() {
+		return fmt.Errorf("cannot leave read-only mode: %s, stop the replica first", a.readOnlyReason)
+	}
+	if !enabled && a.dataLock == nil {
+		a.readOnlyReason = ""
+		a.lockDataDir()
+		if a.readOnlyReason != "" {
+			return fmt.Errorf("cannot leave read-only mode: %s", a.readOnlyReason)
+		}
+	}
+
+	a.readOnly = enabled
+	if enabled {
+		a.logger.Info("Read-only mode enabled")
+	} else {
+		a.logger.Info("Read-only mode disabled")
+	}
+	return nil
+}
@@ -0,0 +1,307 @@
+package main
+
+import (
+	"BinaryCRUD/backend/crypto"
+	"BinaryCRUD/backend/events"
+	"BinaryCRUD/backend/replication"
+	"BinaryCRUD/backend/utils"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ReplicaPollInterval is how often a replica asks its primary for new operations
+const ReplicaPollInterval = time.Second
+
+// replicationState tracks the replication server of a primary and the sync loop of a replica
+type replicationState struct {
+	mu       sync.Mutex
+	server   *replication.Server
+	replica  *replication.Replica
+	stop     chan struct{}
+	done     chan struct{}
+	lastSync time.Time
+	applied  int
+	failed   int
+	offset   int64
+	behind   int64
+	err      error
+}
+
+// replicationSource parses a primary: tcp://host:port, or the path of its data directory on a shared volume
+// A TCP primary is authenticated with the replication secret in the keys directory
+func replicationSource(primary string) (replication.Source, error) {
+	if addr, ok := strings.CutPrefix(primary, "tcp://"); ok {
+		if addr == "" {
+			return nil, fmt.Errorf("missing primary address")
+		}
+		secret, err := crypto.ReplicationKey(utils.KeysDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load the replication secret: %w", err)
+		}
+		return &replication.TCPSource{Addr: addr, Secret: secret}, nil
+	}
+	if primary == "" {
+		return nil, fmt.Errorf("missing primary (expected tcp://host:port or a data directory)")
+	}
+	logPath, err := filepath.Rel(utils.DataDir, utils.OplogPath())
+	if err != nil {
+		return nil, fmt.Errorf("failed to locate the oplog: %w", err)
+	}
+	return replication.DirSource{Path: filepath.Join(primary, logPath)}, nil
+}
+
+// StartReplicationServer ships this instance's oplog to replicas connecting to addr that hold its replication secret
+// An address without a host, such as ":7070", only accepts replicas on this machine
+func (a *App) StartReplicationServer(addr string) (_ map[string]any, err error) {
+	defer a.track("StartReplicationServer", time.Now(), &err)
+	a.replication.mu.Lock()
+	defer a.replication.mu.Unlock()
+
+	if a.replication.server != nil {
+		return nil, fmt.Errorf("the replication server is already running on %s", a.replication.server.Addr())
+	}
+	if a.replication.replica != nil {
+		return nil, fmt.Errorf("a replica cannot serve other replicas")
+	}
+
+	secret, err := crypto.ReplicationKey(utils.KeysDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the replication secret: %w", err)
+	}
+	server, err := replication.Listen(addr, a.oplog.Path(), secret, a.logger.Warn)
+	if err != nil {
+		return nil, err
+	}
+	a.replication.server = server
+	a.logger.Info(fmt.Sprintf("Shipping the oplog to replicas on %s", server.Addr()))
+	return map[string]any{"addr": server.Addr()}, nil
+}
+
+// StopReplicationServer stops shipping the oplog to replicas
+func (a *App) StopReplicationServer() (err error) {
+	defer a.track("StopReplicationServer", time.Now(), &err)
+	a.replication.mu.Lock()
+	defer a.replication.mu.Unlock()
+
+	if a.replication.server == nil {
+		return fmt.Errorf("the replication server is not running")
+	}
+	err = a.replication.server.Close()
+	a.replication.server = nil
+	a.logger.Info("Replication server stopped")
+	return err
+}
+
+// StartReplica turns this instance into a read-only replica of primary
+// The data directory must not hold data of its own; operations of the primary are applied every second
+func (a *App) StartReplica(primary string) (err error) {
+	defer a.track("StartReplica", time.Now(), &err)
+	if err := a.checkWritable(); err != nil {
+		return err
+	}
+
+	a.replication.mu.Lock()
+	defer a.replication.mu.Unlock()
+
+	if a.replication.server != nil {
+		return fmt.Errorf("stop the replication server before becoming a replica")
+	}
+	source, err := replicationSource(primary)
+	if err != nil {
+		return err
+	}
+	replica := &replication.Replica{Source: source, LogPath: a.oplog.Path(), BinDir: utils.ReplicaDir}
+	if err := replica.Prepare(utils.BinDir); err != nil {
+		return err
+	}
+
+	a.replication.replica = replica
+	a.replication.stop = make(chan struct{})
+	a.replication.done = make(chan struct{})
+	a.replication.err = nil
+	a.readOnlyReason = "replica of " + source.String()
+	go a.runReplica(replica, a.replication.stop, a.replication.done)
+
+	a.logger.Info(fmt.Sprintf("Replicating from %s", source))
+	return nil
+}
+
+// StopReplica stops syncing and makes the data writable again, promoting the replica to a primary
+// Its oplog is a copy of the primary's, so new operations continue it
+func (a *App) StopReplica() (err error) {
+	defer a.track("StopReplica", time.Now(), &err)
+	a.replication.mu.Lock()
+	replica := a.replication.replica
+	stop, done := a.replication.stop, a.replication.done
+	a.replication.replica = nil
+	a.replication.mu.Unlock()
+
+	if replica == nil {
+		return fmt.Errorf("this instance is not a replica")
+	}
+	close(stop)
+	<-done
+
+	if closer, ok := replica.Source.(interface{ Close() error }); ok {
+		closer.Close()
+	}
+	// New writes go to the live files, so the working copy is out of date from now on
+	if err := os.RemoveAll(replica.BinDir); err != nil {
+		a.logger.Warn(fmt.Sprintf("Failed to remove the replica working copy: %v", err))
+	}
+	a.readOnlyReason = ""
+	a.logger.Info(fmt.Sprintf("Stopped replicating from %s, data is writable", replica.Source))
+	return nil
+}
+
+// runReplica syncs the replica until stop is closed
+// While the replica catches up, syncs follow each other without waiting
+func (a *App) runReplica(replica *replication.Replica, stop, done chan struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(ReplicaPollInterval)
+	defer ticker.Stop()
+	for {
+		if a.syncReplica(replica) {
+			select {
+			case <-stop:
+				return
+			default:
+				continue
+			}
+		}
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// syncReplica runs one sync and reloads the DAOs when data changed
+// Returns true when the sync made progress and the primary has more to fetch
+func (a *App) syncReplica(replica *replication.Replica) bool {
+	result, err := replica.Sync()
+
+	a.replication.mu.Lock()
+	previous := a.replication.err
+	a.replication.lastSync = time.Now().UTC()
+	a.replication.err = err
+	if result != nil {
+		a.replication.applied += result.Applied
+		a.replication.failed += result.Failed
+		a.replication.offset = result.Offset
+		a.replication.behind = result.Behind
+	}
+	a.replication.mu.Unlock()
+
+	if err != nil {
+		// Log an outage once, not on every poll
+		if previous == nil || previous.Error() != err.Error() {
+			a.logger.Warn(fmt.Sprintf("Replication failed: %v", err))
+		}
+		return false
+	}
+	if previous != nil {
+		a.logger.Info(fmt.Sprintf("Replication from %s resumed", replica.Source))
+	}
+
+	for _, replicaErr := range result.Errors {
+		a.logger.Warn(fmt.Sprintf("Replication: %s", replicaErr))
+	}
+	if result.Reset {
+		a.logger.Warn(fmt.Sprintf("The oplog of %s was reset, replica data cleared", replica.Source))
+	}
+	if result.Applied > 0 || result.Reset {
+		if err := a.installReplicaFiles(replica); err != nil {
+			a.logger.Error(fmt.Sprintf("Failed to install replicated files: %v", err))
+		}
+		a.publish(events.Event{Type: events.DataReloaded})
+	}
+	if result.Applied > 0 {
+		a.logger.Info(fmt.Sprintf("Replicated %d operation(s) from %s", result.Applied, replica.Source))
+	}
+	return result.Behind > 0 && result.Applied+result.Failed > 0
+}
+
+// installReplicaFiles copies the working copy of a replica over the live files
+// Each DAO swaps its file and rebuilds its index under its lock, like an online compaction
+func (a *App) installReplicaFiles(replica *replication.Replica) error {
+	targets := []struct {
+		name    string
+		replace func(path string, prepare func() error) error
+	}{
+		{"items.bin", a.itemDAO.ReplaceFile},
+		{"orders.bin", a.orderDAO.ReplaceFile},
+		{"promotions.bin", a.promotionDAO.ReplaceFile},
+		{"order_promotions.bin", a.orderPromotionDAO.ReplaceFile},
+	}
+
+	if err := os.MkdirAll(utils.BinDir, 0755); err != nil {
+		return fmt.Errorf("failed to create bin directory: %w", err)
+	}
+	for _, target := range targets {
+		staged := utils.BinPath(target.name) + ".replica"
+		if err := replica.Export(target.name, staged); err != nil {
+			return err
+		}
+		if err := target.replace(staged, nil); err != nil {
+			os.Remove(staged)
+			return fmt.Errorf("failed to install %s: %w", target.name, err)
+		}
+	}
+	return nil
+}
+
+// isReplica reports whether this instance is syncing from a primary
+func (a *App) isReplica() bool {
+	a.replication.mu.Lock()
+	defer a.replication.mu.Unlock()
+	return a.replication.replica != nil
+}
+
+// GetReplicationStatus returns the role of this instance and, for a replica, how far it has synced
+func (a *App) GetReplicationStatus() map[string]any {
+	defer a.track("GetReplicationStatus", time.Now(), nil)
+	a.replication.mu.Lock()
+	defer a.replication.mu.Unlock()
+
+	status := map[string]any{"role": "standalone"}
+	if a.replication.server != nil {
+		status["role"] = "primary"
+		status["addr"] = a.replication.server.Addr()
+	}
+	if a.replication.replica != nil {
+		status["role"] = "replica"
+		status["primary"] = a.replication.replica.Source.String()
+		status["applied"] = a.replication.applied
+		status["failed"] = a.replication.failed
+		status["offset"] = a.replication.offset
+		status["behindBytes"] = a.replication.behind
+		if !a.replication.lastSync.IsZero() {
+			status["lastSync"] = a.replication.lastSync.Format(time.RFC3339)
+		}
+		if a.replication.err != nil {
+			status["error"] = a.replication.err.Error()
+		}
+	}
+	return status
+}
+
+// stopReplication stops the replication server and the replica loop, if running
+func (a *App) stopReplication() {
+	a.replication.mu.Lock()
+	server, replica := a.replication.server, a.replication.replica
+	a.replication.mu.Unlock()
+
+	if server != nil {
+		a.StopReplicationServer()
+	}
+	if replica != nil {
+		a.StopReplica()
+	}
+}
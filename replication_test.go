@@ -0,0 +1,73 @@
+package main
+
+import (
+	"BinaryCRUD/backend/crypto"
+	"BinaryCRUD/backend/oplog"
+	"BinaryCRUD/backend/replication"
+	"BinaryCRUD/backend/utils"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStartReplicaOverLoopback(t *testing.T) {
+	app := newTestApp(t)
+
+	// The primary shares the keys directory, as a replica holding copies of its keys would
+	primary := oplog.New(filepath.Join(t.TempDir(), "oplog.bin"))
+	for _, op := range []oplog.Operation{
+		{Type: oplog.OpAddItem, ID: 0, Name: "Burger", Price: 899},
+		{Type: oplog.OpAddItem, ID: 1, Name: "Fries", Price: 450},
+		{Type: oplog.OpCreateOrder, ID: 0, Name: "Alice", Price: 1349, ItemIDs: []uint64{0, 1}},
+	} {
+		op.Timestamp = time.Now().UTC()
+		if err := primary.Append(op); err != nil {
+			t.Fatalf("Failed to append to the primary log: %v", err)
+		}
+	}
+	secret, err := crypto.ReplicationKey(utils.KeysDir)
+	if err != nil {
+		t.Fatalf("Failed to load the replication secret: %v", err)
+	}
+	server, err := replication.Listen("127.0.0.1:0", primary.Path(), secret, func(message string) { t.Log(message) })
+	if err != nil {
+		t.Fatalf("Failed to start the primary: %v", err)
+	}
+	defer server.Close()
+
+	if err := app.StartReplica("tcp://" + server.Addr()); err != nil {
+		t.Fatalf("StartReplica failed: %v", err)
+	}
+	t.Cleanup(app.stopReplication)
+
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		items, err := app.GetAllItems(ListOptions{})
+		if err == nil && len(items) == 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Replica did not catch up: %v items, status %v", len(items), app.GetReplicationStatus())
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	orders, err := app.GetAllOrders("", ListOptions{})
+	if err != nil || len(orders) != 1 || orders[0]["customer"] != "Alice" {
+		t.Fatalf("Expected the replicated order of Alice, got %v (err %v)", orders, err)
+	}
+	status := app.GetReplicationStatus()
+	if status["role"] != "replica" || status["applied"] != 3 {
+		t.Errorf("Unexpected replication status %v", status)
+	}
+	if _, err := app.AddItem("Soda", 300); utils.ErrorCodeOf(err) != utils.CodeConflict {
+		t.Errorf("Expected a replica to reject writes, got %v", err)
+	}
+
+	if err := app.StopReplica(); err != nil {
+		t.Fatalf("StopReplica failed: %v", err)
+	}
+	if _, err := app.AddItem("Soda", 300); err != nil {
+		t.Errorf("Expected a promoted replica to accept writes, got %v", err)
+	}
+}
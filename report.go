@@ -0,0 +1,277 @@
+package main
+
+import (
+	"BinaryCRUD/backend/dao"
+	"BinaryCRUD/backend/utils"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// salesReportTopItems is the number of best-selling items listed in a sales report
+const salesReportTopItems = 10
+
+// parseReportBound parses an optional RFC3339 report bound, empty means unbounded
+func parseReportBound(value, name string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s timestamp %q (expected RFC3339): %w", name, value, err)
+	}
+	return &t, nil
+}
+
+// GetSalesReport summarizes orders created between fromTs and toTs (RFC3339, inclusive, empty for no bound)
+// Cancelled orders are left out, as are orders without a creation time when a bound is given
+func (a *App) GetSalesReport(fromTs, toTs string) (_ map[string]any, err error) {
+	defer a.track("GetSalesReport", time.Now(), &err)
+	from, err := parseReportBound(fromTs, "from")
+	if err != nil {
+		return nil, err
+	}
+	to, err := parseReportBound(toTs, "to")
+	if err != nil {
+		return nil, err
+	}
+	if from != nil && to != nil && to.Before(*from) {
+		return nil, fmt.Errorf("report range ends before it starts")
+	}
+
+	orders, err := a.orderDAO.GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read orders: %w", err)
+	}
+	links, err := a.orderPromotionDAO.GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read order promotions: %w", err)
+	}
+	promotionsByOrder := make(map[uint64][]*dao.OrderPromotion)
+	for _, link := range links {
+		promotionsByOrder[link.OrderID] = append(promotionsByOrder[link.OrderID], link)
+	}
+
+	discounts := make(map[uint64]utils.Discount)
+	promotionNames := make(map[uint64]string)
+	itemQuantities := make(map[uint64]uint64)
+	promotionUsage := make(map[uint64]uint64)
+	var revenue, subtotal, discountTotal, orderCount, undated uint64
+
+	for _, order := range orders {
+		if order.IsDeleted || orderStatus(order) == utils.OrderCancelled {
+			continue
+		}
+		createdAt, dated := orderCreatedAt(order)
+		if !dated {
+			undated++
+			if from != nil || to != nil {
+				continue
+			}
+		} else if (from != nil && createdAt.Before(*from)) || (to != nil && createdAt.After(*to)) {
+			continue
+		}
+
+		// Same discount rules as GetOrderWithPromotions: each discount applies to the subtotal
+		// Links that recorded the discount granted when they were applied count that amount instead
+		orderDiscount := uint64(0)
+		for _, link := range promotionsByOrder[order.ID] {
+			promotionID := link.PromotionID
+			discount, known := discounts[promotionID]
+			if !known {
+				discount = utils.Discount{Type: utils.DiscountNone}
+				if promotion, err := a.promotionDAO.Read(promotionID); err == nil {
+					discount = promotionDiscount(promotion)
+					promotionNames[promotionID] = promotion.OwnerOrName
+				}
+				discounts[promotionID] = discount
+			}
+			if link.AppliedAt.IsZero() {
+				orderDiscount += discount.Amount(order.TotalPrice)
+			} else {
+				orderDiscount += link.DiscountAmount
+			}
+			promotionUsage[promotionID]++
+		}
+		if orderDiscount > order.TotalPrice {
+			orderDiscount = order.TotalPrice
+		}
+
+		for _, itemID := range order.ItemIDs {
+			itemQuantities[itemID]++
+		}
+		subtotal += order.TotalPrice
+		discountTotal += orderDiscount
+		revenue += order.TotalPrice - orderDiscount
+		orderCount++
+	}
+
+	result := map[string]any{
+		"revenue":        revenue,
+		"subtotal":       subtotal,
+		"discountTotal":  discountTotal,
+		"orderCount":     orderCount,
+		"undatedOrders":  undated,
+		"topItems":       a.topSellingItems(itemQuantities, salesReportTopItems),
+		"promotionUsage": promotionUsageList(promotionUsage, promotionNames),
+	}
+	if from != nil {
+		result["from"] = from.Format(time.RFC3339)
+	}
+	if to != nil {
+		result["to"] = to.Format(time.RFC3339)
+	}
+
+	a.logger.Info(fmt.Sprintf("Sales report: %d orders, revenue %d cents", orderCount, revenue))
+	return result, nil
+}
+
+// GetPopularItems returns the n items that appear most often across active orders, with their names and counts
+// Cancelled orders are not counted, an item listed twice in an order counts twice
+func (a *App) GetPopularItems(n int) (_ []map[string]any, err error) {
+	defer a.track("GetPopularItems", time.Now(), &err)
+	if n <= 0 {
+		return nil, fmt.Errorf("n must be positive, got %d", n)
+	}
+
+	orders, err := a.orderDAO.GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read orders: %w", err)
+	}
+
+	counts := make(map[uint64]uint64)
+	for _, order := range orders {
+		if order.IsDeleted || orderStatus(order) == utils.OrderCancelled {
+			continue
+		}
+		for _, itemID := range order.ItemIDs {
+			counts[itemID]++
+		}
+	}
+
+	result := a.topSellingItems(counts, n)
+	a.logger.Info(fmt.Sprintf("Retrieved %d popular items", len(result)))
+	return result, nil
+}
+
+// topSellingItems returns up to limit items ordered by quantity sold, then by ID
+func (a *App) topSellingItems(quantities map[uint64]uint64, limit int) []map[string]any {
+	ids := make([]uint64, 0, len(quantities))
+	for id := range quantities {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if quantities[ids[i]] != quantities[ids[j]] {
+			return quantities[ids[i]] > quantities[ids[j]]
+		}
+		return ids[i] < ids[j]
+	})
+	if len(ids) > limit {
+		ids = ids[:limit]
+	}
+
+	result := make([]map[string]any, len(ids))
+	for i, id := range ids {
+		name := "Deleted Item"
+		if item, err := a.itemDAO.ReadItem(id); err == nil {
+			name = item.Name
+		}
+		result[i] = map[string]any{
+			"id":       id,
+			"name":     name,
+			"quantity": quantities[id],
+		}
+	}
+	return result
+}
+
+// promotionUsageList lists how many reported orders used each promotion, most used first
+func promotionUsageList(usage map[uint64]uint64, names map[uint64]string) []map[string]any {
+	ids := make([]uint64, 0, len(usage))
+	for id := range usage {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if usage[ids[i]] != usage[ids[j]] {
+			return usage[ids[i]] > usage[ids[j]]
+		}
+		return ids[i] < ids[j]
+	})
+
+	result := make([]map[string]any, len(ids))
+	for i, id := range ids {
+		name, ok := names[id]
+		if !ok {
+			name = "Deleted Promotion"
+		}
+		result[i] = map[string]any{
+			"id":     id,
+			"name":   name,
+			"orders": usage[id],
+		}
+	}
+	return result
+}
+
+// GetPromotionUsageStats returns, for every active promotion, the active orders it is applied to, their
+// subtotal and the discount it granted on them, least used first to help decide which promotions to retire
+// Cancelled and deleted orders are not counted; links that recorded their discount count that amount,
+// older ones the promotion's current discount
+func (a *App) GetPromotionUsageStats() (_ []map[string]any, err error) {
+	defer a.track("GetPromotionUsageStats", time.Now(), &err)
+	promotions, err := a.promotionDAO.GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read promotions: %w", err)
+	}
+
+	// Orders shared by several promotions are read once
+	orders := make(map[uint64]*dao.Collection)
+	result := make([]map[string]any, 0, len(promotions))
+	for _, promotion := range promotions {
+		if promotion.IsDeleted {
+			continue
+		}
+		links, err := a.orderPromotionDAO.GetByPromotionID(promotion.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read orders of promotion #%d: %w", promotion.ID, err)
+		}
+
+		discount := promotionDiscount(promotion)
+		var orderCount, orderTotal, discountTotal uint64
+		for _, link := range links {
+			order, read := orders[link.OrderID]
+			if !read {
+				order, _ = a.orderDAO.Read(link.OrderID)
+				orders[link.OrderID] = order
+			}
+			if order == nil || orderStatus(order) == utils.OrderCancelled {
+				continue
+			}
+			orderCount++
+			orderTotal += order.TotalPrice
+			if link.AppliedAt.IsZero() {
+				discountTotal += discount.Amount(order.TotalPrice)
+			} else {
+				discountTotal += link.DiscountAmount
+			}
+		}
+
+		result = append(result, addDiscountFields(map[string]any{
+			"id":            promotion.ID,
+			"name":          promotion.OwnerOrName,
+			"orderCount":    orderCount,
+			"orderTotal":    orderTotal,
+			"discountTotal": discountTotal,
+		}, promotion))
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i]["orderCount"] != result[j]["orderCount"] {
+			return result[i]["orderCount"].(uint64) < result[j]["orderCount"].(uint64)
+		}
+		return result[i]["id"].(uint64) < result[j]["id"].(uint64)
+	})
+
+	a.logger.Info(fmt.Sprintf("Retrieved usage of %d promotions", len(result)))
+	return result, nil
+}
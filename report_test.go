@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// seedReportOrders adds Burger (0), Fries (1) and Soda (2) and five orders written with fixed creation times:
+// 0 on Jan 10 with Burger and Fries and a 10% promotion, 1 on Feb 10 with two Burgers and a Soda,
+// 2 on Mar 10 with Fries (cancelled), 3 on Feb 20 with a Soda (deleted) and 4 without a creation time
+// with Fries and Soda
+func seedReportOrders(t *testing.T, app *App) {
+	t.Helper()
+	for _, item := range []struct {
+		name  string
+		price uint64
+	}{{"Burger", 899}, {"Fries", 349}, {"Soda", 199}} {
+		if _, err := app.AddItem(item.name, item.price); err != nil {
+			t.Fatalf("Failed to add item: %v", err)
+		}
+	}
+
+	day := func(month time.Month, d int) map[byte][]byte {
+		return newOrderExtensions(time.Date(2026, month, d, 12, 0, 0, 0, time.UTC))
+	}
+	for _, order := range []struct {
+		customer string
+		total    uint64
+		itemIDs  []uint64
+		ext      map[byte][]byte
+	}{
+		{"Alice", 1248, []uint64{0, 1}, day(time.January, 10)},
+		{"Bruno", 1997, []uint64{0, 0, 2}, day(time.February, 10)},
+		{"Carla", 349, []uint64{1}, day(time.March, 10)},
+		{"Diego", 199, []uint64{2}, day(time.February, 20)},
+		{"Elena", 548, []uint64{1, 2}, nil},
+	} {
+		if _, err := app.orderDAO.WriteExtended(nil, order.customer, order.total, order.itemIDs, order.ext); err != nil {
+			t.Fatalf("Failed to write order: %v", err)
+		}
+	}
+	if _, err := app.SetOrderStatus(2, "cancelled"); err != nil {
+		t.Fatalf("Failed to cancel order: %v", err)
+	}
+	if err := app.DeleteOrder(3); err != nil {
+		t.Fatalf("Failed to delete order: %v", err)
+	}
+
+	promotionID, err := app.CreatePromotion("Combo", []uint64{0, 1})
+	if err != nil {
+		t.Fatalf("Failed to create promotion: %v", err)
+	}
+	if err := app.SetPromotionDiscount(promotionID, "percent", 10); err != nil {
+		t.Fatalf("Failed to set discount: %v", err)
+	}
+	if err := app.ApplyPromotionToOrder(0, promotionID); err != nil {
+		t.Fatalf("Failed to apply promotion: %v", err)
+	}
+}
+
+// itemQuantities formats the id and quantity of report items as "id:quantity"
+func itemQuantities(items []map[string]any) []string {
+	result := make([]string, len(items))
+	for i, item := range items {
+		result[i] = fmt.Sprintf("%v:%v", item["id"], item["quantity"])
+	}
+	return result
+}
+
+func TestGetSalesReport(t *testing.T) {
+	app := newTestApp(t)
+	seedReportOrders(t, app)
+
+	// Cancelled and deleted orders are left out, the undated one counts without bounds
+	report, err := app.GetSalesReport("", "")
+	if err != nil {
+		t.Fatalf("Failed to get sales report: %v", err)
+	}
+	if report["orderCount"] != uint64(3) || report["undatedOrders"] != uint64(1) {
+		t.Errorf("Expected 3 orders, 1 undated, got %v and %v", report["orderCount"], report["undatedOrders"])
+	}
+	if report["subtotal"] != uint64(3793) || report["discountTotal"] != uint64(124) || report["revenue"] != uint64(3669) {
+		t.Errorf("Expected 3793 - 124 = 3669 cents, got %v - %v = %v", report["subtotal"], report["discountTotal"], report["revenue"])
+	}
+	if got := itemQuantities(report["topItems"].([]map[string]any)); fmt.Sprint(got) != "[0:3 1:2 2:2]" {
+		t.Errorf("Expected Burger, Fries and Soda by quantity, got %v", got)
+	}
+	usage := report["promotionUsage"].([]map[string]any)
+	if len(usage) != 1 || usage[0]["name"] != "Combo" || usage[0]["orders"] != uint64(1) {
+		t.Errorf("Expected Combo used by one order, got %v", usage)
+	}
+
+	// A range only keeps the dated orders created inside it
+	report, err = app.GetSalesReport("2026-02-01T00:00:00Z", "2026-02-28T23:59:59Z")
+	if err != nil {
+		t.Fatalf("Failed to get sales report: %v", err)
+	}
+	if report["orderCount"] != uint64(1) || report["revenue"] != uint64(1997) || report["discountTotal"] != uint64(0) {
+		t.Errorf("Expected only Bruno's order, got %d orders for %v cents", report["orderCount"], report["revenue"])
+	}
+	if got := itemQuantities(report["topItems"].([]map[string]any)); fmt.Sprint(got) != "[0:2 2:1]" {
+		t.Errorf("Expected two Burgers and a Soda, got %v", got)
+	}
+	if report["from"] != "2026-02-01T00:00:00Z" || len(report["promotionUsage"].([]map[string]any)) != 0 {
+		t.Errorf("Expected the range echoed and no promotion used, got %v", report)
+	}
+
+	if _, err := app.GetSalesReport("2026-03-01T00:00:00Z", "2026-02-01T00:00:00Z"); err == nil {
+		t.Error("Expected a range ending before it starts to be rejected")
+	}
+	if _, err := app.GetSalesReport("yesterday", ""); err == nil {
+		t.Error("Expected a timestamp that isn't RFC3339 to be rejected")
+	}
+}
+
+func TestGetPopularItems(t *testing.T) {
+	app := newTestApp(t)
+	seedReportOrders(t, app)
+
+	// Burger is in order 0 once and order 1 twice; ties keep ascending IDs
+	popular, err := app.GetPopularItems(2)
+	if err != nil {
+		t.Fatalf("Failed to get popular items: %v", err)
+	}
+	if got := itemQuantities(popular); fmt.Sprint(got) != "[0:3 1:2]" || popular[0]["name"] != "Burger" {
+		t.Errorf("Expected Burger then Fries, got %v", popular)
+	}
+
+	// Items deleted since they were ordered are still counted, under a placeholder name
+	if err := app.DeleteItem(2); err != nil {
+		t.Fatalf("Failed to delete item: %v", err)
+	}
+	popular, err = app.GetPopularItems(10)
+	if err != nil {
+		t.Fatalf("Failed to get popular items: %v", err)
+	}
+	if got := itemQuantities(popular); fmt.Sprint(got) != "[0:3 1:2 2:2]" || popular[2]["name"] != "Deleted Item" {
+		t.Errorf("Expected the deleted Soda last, got %v", popular)
+	}
+
+	if _, err := app.GetPopularItems(0); err == nil {
+		t.Error("Expected n of 0 to be rejected")
+	}
+}
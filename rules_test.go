@@ -0,0 +1,42 @@
+package main
+
+import (
+	"BinaryCRUD/backend/utils"
+	"testing"
+)
+
+func TestValidationRulesFromConfig(t *testing.T) {
+	app := newTestApp(t)
+	itemID, err := app.AddItem("Burger", 899)
+	if err != nil {
+		t.Fatalf("Failed to add item: %v", err)
+	}
+
+	config := app.GetConfig()
+	config.Rules = map[string]utils.ValidationRule{
+		"item":     {AllowedChars: `\p{L}\p{N} `},
+		"template": {MaxItems: 2},
+		"order":    {MaxPrice: 1000},
+	}
+	if _, err := app.UpdateConfig(config); err != nil {
+		t.Fatalf("Failed to update config: %v", err)
+	}
+
+	if _, err := app.AddItem("Burger!", 899); utils.ErrorCodeOf(err) != utils.CodeValidation {
+		t.Errorf("Expected the item rule to reject the name, got %v", err)
+	}
+	if _, err := app.CreateTemplate("Lunch", []uint64{itemID, itemID, itemID}); utils.ErrorCodeOf(err) != utils.CodeValidation {
+		t.Errorf("Expected the template item cap, got %v", err)
+	}
+	if _, err := app.CreateTemplate("Lunch", []uint64{itemID, itemID}); err != nil {
+		t.Errorf("Expected a template within the cap, got %v", err)
+	}
+
+	// The order total is only known once priced, so the DAO applies the rule when writing it
+	if _, err := app.CreateOrder("Customer", []uint64{itemID, itemID}); utils.ErrorCodeOf(err) != utils.CodeValidation {
+		t.Errorf("Expected the order total limit, got %v", err)
+	}
+	if _, err := app.CreateOrder("Customer", []uint64{itemID}); err != nil {
+		t.Errorf("Expected an order within the limit, got %v", err)
+	}
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"BinaryCRUD/backend/dao"
+	"BinaryCRUD/backend/oplog"
+	"BinaryCRUD/backend/utils"
+	"fmt"
+	"math/rand/v2"
+	"time"
+)
+
+// MaxGeneratedRecords bounds each count given to GenerateSeedData
+const MaxGeneratedRecords = 100000
+
+// Word lists the seed data generator builds names from
+var (
+	seedAdjectives = []string{"Classic", "Spicy", "Crispy", "Grilled", "Smoked", "Double", "Vegan", "Cheesy", "Honey", "Garlic", "Loaded", "Mini"}
+	seedFoods      = []string{"Burger", "Fries", "Wrap", "Salad", "Pizza", "Taco", "Nuggets", "Hot Dog", "Sandwich", "Soup", "Milkshake", "Soda", "Brownie", "Onion Rings"}
+	seedFirstNames = []string{"Ana", "Bruno", "Carla", "Diego", "Elena", "Felipe", "Gabriela", "Hugo", "Isabel", "João", "Karina", "Lucas", "Marina", "Nina", "Otávio", "Paula"}
+	seedLastNames  = []string{"Silva", "Santos", "Oliveira", "Souza", "Lima", "Pereira", "Costa", "Rodrigues", "Almeida", "Nascimento", "Carvalho", "Ribeiro"}
+	seedPromoNames = []string{"Combo", "Lunch Deal", "Family Pack", "Happy Hour", "Weekend Special", "Student Menu", "Snack Box"}
+)
+
+// seedReferenceTime is the date generated orders are dated back from
+// It is fixed rather than the clock so that runs with the same seed write byte-identical files
+var seedReferenceTime = time.Date(2025, time.January, 1, 12, 0, 0, 0, time.UTC)
+
+// seedGenerator synthesizes random records from a random source
+type seedGenerator struct {
+	rng       *rand.Rand
+	itemNames map[string]bool
+}
+
+// newSeedGenerator creates a generator replaying seed, or a random seed when it is 0
+// The seed in use is returned so the run can be replayed
+func newSeedGenerator(seed int64) (*seedGenerator, int64) {
+	for seed == 0 {
+		seed = rand.Int64()
+	}
+	return &seedGenerator{rng: rand.New(rand.NewPCG(uint64(seed), 0)), itemNames: make(map[string]bool)}, seed
+}
+
+// pick returns a random element of words
+func (g *seedGenerator) pick(words []string) string {
+	return words[g.rng.IntN(len(words))]
+}
+
+// itemName returns a food name not used by an earlier generated item
+func (g *seedGenerator) itemName() string {
+	name := g.pick(seedAdjectives) + " " + g.pick(seedFoods)
+	for n := 2; g.itemNames[name]; n++ {
+		name = fmt.Sprintf("%s %s %d", g.pick(seedAdjectives), g.pick(seedFoods), n)
+	}
+	g.itemNames[name] = true
+	return name
+}
+
+// price returns a menu price between $0.99 and $29.99 ending in 9 cents
+func (g *seedGenerator) price() uint64 {
+	return uint64(g.rng.IntN(30))*100 + 99
+}
+
+// basket returns between 1 and maxItems distinct item IDs
+func (g *seedGenerator) basket(itemIDs []uint64, maxItems int) []uint64 {
+	count := 1 + g.rng.IntN(min(maxItems, len(itemIDs)))
+	basket := make([]uint64, 0, count)
+	for _, i := range g.rng.Perm(len(itemIDs))[:count] {
+		basket = append(basket, itemIDs[i])
+	}
+	return basket
+}
+
+// createdAt returns an order date within the 90 days before seedReferenceTime, to the second
+func (g *seedGenerator) createdAt() time.Time {
+	return seedReferenceTime.Add(-time.Duration(g.rng.Int64N(int64(90 * 24 * time.Hour)))).Truncate(time.Second)
+}
+
+// GenerateSeedData writes items, orders and promotions of random but realistic data through the DAOs
+// Orders and promotions are built from baskets of the generated items, for load testing the indexes and compaction
+// The same non-zero seed on empty data files writes byte-identical .bin files while names are stored in plaintext
+// (encrypted names use random nonces); 0 picks a random seed, returned in the result so the run can be replayed
+func (a *App) GenerateSeedData(items, orders, promotions int, seed int64) (_ map[string]any, err error) {
+	start := time.Now()
+	defer a.track("GenerateSeedData", start, &err)
+	if err := a.checkWritable(); err != nil {
+		return nil, err
+	}
+
+	for name, count := range map[string]int{"items": items, "orders": orders, "promotions": promotions} {
+		if count < 0 || count > MaxGeneratedRecords {
+			return nil, utils.WithCode(utils.CodeValidation, fmt.Errorf("%s must be between 0 and %d", name, MaxGeneratedRecords), map[string]any{name: count})
+		}
+	}
+	if items == 0 && orders+promotions > 0 {
+		return nil, utils.WithCode(utils.CodeValidation, fmt.Errorf("orders and promotions need at least one generated item"), nil)
+	}
+
+	g, seed := newSeedGenerator(seed)
+	p := a.startProgress("GenerateSeedData", int64(items+orders+promotions))
+	defer p.Finish()
+
+	itemIDs := make([]uint64, 0, items)
+	prices := make(map[uint64]uint64, items)
+	for range items {
+		if err := p.Err(); err != nil {
+			return nil, err
+		}
+		name, price := g.itemName(), g.price()
+		id, err := a.itemDAO.Write(name, price)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate item %d: %w", len(itemIDs)+1, err)
+		}
+		a.recordOp(oplog.Operation{Type: oplog.OpAddItem, ID: id, Name: name, Price: price})
+		a.recordAudit(dao.AuditCreate, "item", id, "", itemSummary(name, price))
+		itemIDs = append(itemIDs, id)
+		prices[id] = price
+		p.Advance(1)
+	}
+
+	total := func(basket []uint64) uint64 {
+		var sum uint64
+		for _, id := range basket {
+			sum += prices[id]
+		}
+		return sum
+	}
+
+	for i := range promotions {
+		if err := p.Err(); err != nil {
+			return nil, err
+		}
+		name := fmt.Sprintf("%s #%d", g.pick(seedPromoNames), i+1)
+		basket := g.basket(itemIDs, 4)
+		var ext map[byte][]byte
+		if g.rng.IntN(2) == 0 {
+			if ext, err = discountExtensions("percent", uint64(5*(1+g.rng.IntN(6)))); err != nil {
+				return nil, err
+			}
+		}
+		id, err := a.promotionDAO.WriteExtended(nil, name, total(basket), basket, ext)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate promotion %d: %w", i+1, err)
+		}
+		a.recordOp(oplog.Operation{Type: oplog.OpCreatePromotion, ID: id, Name: name, Price: total(basket), ItemIDs: basket, Extensions: ext})
+		a.recordAudit(dao.AuditCreate, "promotion", id, "", collectionSummary(total(basket), basket))
+		p.Advance(1)
+	}
+
+	for i := range orders {
+		if err := p.Err(); err != nil {
+			return nil, err
+		}
+		customer := g.pick(seedFirstNames) + " " + g.pick(seedLastNames)
+		basket := g.basket(itemIDs, 6)
+		ext := newOrderExtensions(g.createdAt())
+		id, err := a.orderDAO.WriteExtended(nil, customer, total(basket), basket, ext)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate order %d: %w", i+1, err)
+		}
+		a.recordOp(oplog.Operation{Type: oplog.OpCreateOrder, ID: id, Name: customer, Price: total(basket), ItemIDs: basket, Extensions: ext})
+		a.recordAudit(dao.AuditCreate, "order", id, "", collectionSummary(total(basket), basket))
+		p.Advance(1)
+	}
+
+	a.logger.Info(fmt.Sprintf("Generated %d items, %d promotions and %d orders with seed %d in %s", items, promotions, orders, seed, time.Since(start).Round(time.Millisecond)))
+	return map[string]any{
+		"items":      items,
+		"orders":     orders,
+		"promotions": promotions,
+		"seed":       seed,
+		"durationMs": time.Since(start).Milliseconds(),
+	}, nil
+}
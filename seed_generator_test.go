@@ -0,0 +1,153 @@
+package main
+
+import (
+	"BinaryCRUD/backend/utils"
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGenerateSeedData(t *testing.T) {
+	app := newTestApp(t)
+	result, err := app.GenerateSeedData(50, 40, 10, 0)
+	if err != nil {
+		t.Fatalf("Failed to generate seed data: %v", err)
+	}
+	if result["items"] != 50 || result["orders"] != 40 || result["promotions"] != 10 || result["seed"] == int64(0) {
+		t.Errorf("Unexpected counts %v", result)
+	}
+
+	names := make(map[string]bool)
+	prices := make(map[uint64]uint64)
+	for id := uint64(0); id < 50; id++ {
+		item, err := app.GetItem(id)
+		if err != nil {
+			t.Fatalf("Failed to read generated item %d: %v", id, err)
+		}
+		name := item["name"].(string)
+		if names[name] {
+			t.Errorf("Item name %q generated twice", name)
+		}
+		names[name] = true
+		prices[id] = item["priceInCents"].(uint64)
+	}
+
+	for id := uint64(0); id < 40; id++ {
+		order, err := app.GetOrder(id)
+		if err != nil {
+			t.Fatalf("Failed to read generated order %d: %v", id, err)
+		}
+		itemIDs := order["itemIDs"].([]uint64)
+		if len(itemIDs) < 1 || len(itemIDs) > 6 {
+			t.Errorf("Order %d has %d items", id, len(itemIDs))
+		}
+		var total uint64
+		for _, itemID := range itemIDs {
+			total += prices[itemID]
+		}
+		if order["totalPrice"] != total {
+			t.Errorf("Order %d total %v does not match its items (%d)", id, order["totalPrice"], total)
+		}
+	}
+}
+
+// readBinFiles returns the contents of every .bin file in the data directory by name
+// audit.bin is left out, as it records the wall clock of each write
+func readBinFiles(t *testing.T) map[string][]byte {
+	t.Helper()
+	paths, err := filepath.Glob(filepath.Join(utils.BinDir, "*.bin"))
+	if err != nil {
+		t.Fatalf("Failed to list data files: %v", err)
+	}
+	contents := make(map[string][]byte, len(paths))
+	for _, path := range paths {
+		if filepath.Base(path) == "audit.bin" {
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", path, err)
+		}
+		contents[filepath.Base(path)] = data
+	}
+	return contents
+}
+
+// compareBinFiles reports every .bin file that differs or exists in only one of two runs
+func compareBinFiles(t *testing.T, first, replayed map[string][]byte, seed int64) {
+	t.Helper()
+	if len(first) == 0 {
+		t.Fatalf("Expected data files to compare")
+	}
+	for name, data := range first {
+		if other, ok := replayed[name]; !ok || !bytes.Equal(data, other) {
+			t.Errorf("%s differs between two runs with seed %d", name, seed)
+		}
+	}
+	for name := range replayed {
+		if _, ok := first[name]; !ok {
+			t.Errorf("%s was only written by the replayed run with seed %d", name, seed)
+		}
+	}
+}
+
+func TestGenerateSeedDataIsReproducible(t *testing.T) {
+	generate := func(seed int64) (map[string][]byte, int64) {
+		app := newTestApp(t)
+		app.SetEncryptionEnabled(false)
+		result, err := app.GenerateSeedData(30, 20, 5, seed)
+		if err != nil {
+			t.Fatalf("Failed to generate seed data: %v", err)
+		}
+		return readBinFiles(t), result["seed"].(int64)
+	}
+
+	first, seed := generate(0)
+	replayed, replayedSeed := generate(seed)
+	if replayedSeed != seed {
+		t.Errorf("Expected seed %d in the result, got %d", seed, replayedSeed)
+	}
+	compareBinFiles(t, first, replayed, seed)
+
+	other, _ := generate(seed + 1)
+	if bytes.Equal(first["orders.bin"], other["orders.bin"]) {
+		t.Errorf("Expected another seed to generate other orders")
+	}
+}
+
+func TestGenerateSeedDataRejectsInvalidCounts(t *testing.T) {
+	app := newTestApp(t)
+	for _, counts := range [][3]int{{-1, 0, 0}, {0, 5, 0}, {MaxGeneratedRecords + 1, 0, 0}} {
+		if _, err := app.GenerateSeedData(counts[0], counts[1], counts[2], 0); utils.ErrorCodeOf(err) != utils.CodeValidation {
+			t.Errorf("Expected a validation error for %v, got another error", counts)
+		}
+	}
+}
+
+func TestPopulateInventoryIsReproducible(t *testing.T) {
+	populate := func(seed int64) map[string][]byte {
+		app := newTestApp(t)
+		app.SetEncryptionEnabled(false)
+		writeSeedFiles(t, map[string]string{
+			"items.json":            `[{"name": "Burger", "priceInCents": 899}, {"name": "Fries", "priceInCents": 299}]`,
+			"promotions.json":       `[{"name": "Combo", "itemIDs": [0, 1]}, {"name": "Fry day", "itemIDs": [1], "discountType": "percent", "discountValue": 10}]`,
+			"orders.json":           `[{"owner": "Ana", "itemIDs": [0, 1], "promotionIDs": [0]}, {"owner": "Bruno", "itemIDs": [1]}]`,
+			"order_promotions.json": `[{"orderID": 1, "promotionID": 1}]`,
+		})
+		result, err := app.PopulateInventory(seed, false)
+		if err != nil {
+			t.Fatalf("Failed to populate inventory: %v", err)
+		}
+		if result.OrderPromotions != 2 || result.Seed != seed {
+			t.Fatalf("Expected 2 promotions applied with seed %d, got %+v", seed, result)
+		}
+		return readBinFiles(t)
+	}
+
+	first, replayed := populate(7), populate(7)
+	if _, ok := first["order_promotions.bin"]; !ok {
+		t.Fatalf("Expected order_promotions.bin among the compared files, got %d files", len(first))
+	}
+	compareBinFiles(t, first, replayed, 7)
+}
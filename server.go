@@ -0,0 +1,510 @@
+package main
+
+import (
+	"BinaryCRUD/backend/events"
+	"BinaryCRUD/backend/utils"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// APIShutdownTimeout is how long shutdown waits for REST API requests in flight
+const APIShutdownTimeout = 5 * time.Second
+
+// apiServer exposes the App bindings as a JSON REST API
+// Requests are handled one at a time, together with gRPC calls, see App.remoteMu
+type apiServer struct {
+	app *App
+}
+
+// itemRequest is the body of item create and update requests
+type itemRequest struct {
+	Name         string `json:"name"`
+	PriceInCents uint64 `json:"priceInCents"`
+}
+
+// collectionRequest is the body of order and promotion create requests
+type collectionRequest struct {
+	Name    string   `json:"name"`
+	ItemIDs []uint64 `json:"itemIds"`
+}
+
+// compressRequest is the body of compress and decompress requests
+type compressRequest struct {
+	Filename     string `json:"filename"`
+	Algorithm    string `json:"algorithm"`
+	KeepOriginal bool   `json:"keepOriginal"`
+	Encrypt      bool   `json:"encrypt"`
+}
+
+// restServer is the REST API served next to the window, started in startup and stopped in shutdown
+type restServer struct {
+	server *http.Server
+	addr   string
+	cancel context.CancelFunc // ends the requests still open, such as event streams
+	done   chan struct{}      // closed once Serve returned
+}
+
+// startAPIServer serves the REST API on a.apiAddr in the background
+// The address is bound before returning, so a port in use is reported here instead of in the log
+func (a *App) startAPIServer() error {
+	if a.api != nil {
+		return fmt.Errorf("the REST API is already served on %s", a.api.addr)
+	}
+	listener, err := net.Listen("tcp", a.apiAddr)
+	if err != nil {
+		return fmt.Errorf("failed to serve the REST API: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	api := &restServer{
+		server: &http.Server{
+			Handler:     a.apiHandler(a.apiMetrics),
+			BaseContext: func(net.Listener) context.Context { return ctx },
+		},
+		addr:   listener.Addr().String(),
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	a.api = api
+
+	go func() {
+		defer close(api.done)
+		if err := api.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			a.logger.Error("REST API stopped: " + err.Error())
+		}
+	}()
+
+	a.logger.Info(fmt.Sprintf("Serving REST API on %s", api.addr))
+	return nil
+}
+
+// stopAPIServer stops the REST API, letting requests in flight finish for up to APIShutdownTimeout
+func (a *App) stopAPIServer() {
+	api := a.api
+	if api == nil {
+		return
+	}
+	a.api = nil
+
+	ctx, cancel := context.WithTimeout(context.Background(), APIShutdownTimeout)
+	defer cancel()
+	api.cancel()
+	if err := api.server.Shutdown(ctx); err != nil {
+		a.logger.Warn("REST API did not shut down cleanly: " + err.Error())
+		api.server.Close()
+	}
+	<-api.done
+	a.logger.Info("REST API stopped")
+}
+
+// apiHandler routes the REST API, and the metrics endpoint when metrics is set
+func (a *App) apiHandler(metrics bool) http.Handler {
+	s := &apiServer{app: a}
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /api/items", s.listItems)
+	mux.HandleFunc("POST /api/items", s.createItem)
+	mux.HandleFunc("GET /api/items/{id}", withID(s.getItem))
+	mux.HandleFunc("PUT /api/items/{id}", withID(s.updateItem))
+	mux.HandleFunc("DELETE /api/items/{id}", withID(s.deleteItem))
+	mux.HandleFunc("GET /api/items/external/{externalId}", s.getItemByExternalID)
+
+	mux.HandleFunc("GET /api/orders", s.listOrders)
+	mux.HandleFunc("POST /api/orders", s.createOrder)
+	mux.HandleFunc("GET /api/orders/{id}", withID(s.getOrder))
+	mux.HandleFunc("DELETE /api/orders/{id}", withID(s.deleteOrder))
+	mux.HandleFunc("POST /api/orders/{id}/items/{itemId}", withID(s.addItemToOrder))
+	mux.HandleFunc("DELETE /api/orders/{id}/items/{itemId}", withID(s.removeItemFromOrder))
+	mux.HandleFunc("GET /api/orders/{id}/promotions", withID(s.getOrderPromotions))
+	mux.HandleFunc("POST /api/orders/{id}/promotions/{promotionId}", withID(s.applyPromotion))
+	mux.HandleFunc("DELETE /api/orders/{id}/promotions/{promotionId}", withID(s.removePromotion))
+
+	mux.HandleFunc("GET /api/promotions", s.listPromotions)
+	mux.HandleFunc("POST /api/promotions", s.createPromotion)
+	mux.HandleFunc("GET /api/promotions/{id}", withID(s.getPromotion))
+	mux.HandleFunc("DELETE /api/promotions/{id}", withID(s.deletePromotion))
+
+	mux.HandleFunc("POST /api/compact", s.compact)
+	mux.HandleFunc("GET /api/compressed", s.listCompressed)
+	mux.HandleFunc("POST /api/compress", s.compress)
+	mux.HandleFunc("POST /api/decompress", s.decompress)
+
+	// The event stream stays open, so it must not hold the request lock
+	root := http.NewServeMux()
+	root.Handle("/", s.serialize(mux))
+	root.HandleFunc("GET /api/events", s.streamEvents)
+	if metrics {
+		root.HandleFunc("GET /metrics", s.serveMetrics)
+	}
+	return root
+}
+
+// serialize runs one request at a time
+func (s *apiServer) serialize(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.app.remoteMu.Lock()
+		defer s.app.remoteMu.Unlock()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// streamEvents sends data change events as Server-Sent Events until the client disconnects
+// Events are dropped for a client that falls too far behind
+func (s *apiServer) streamEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, fmt.Errorf("streaming is not supported"))
+		return
+	}
+
+	pending := make(chan events.Event, 64)
+	unsubscribe := s.app.subscribe(func(event events.Event) {
+		select {
+		case pending <- event:
+		default:
+		}
+	})
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event := <-pending:
+			data, err := json.Marshal(event)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
+			flusher.Flush()
+		}
+	}
+}
+
+// writeJSON writes a JSON response with the given status
+func writeJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
+
+// writeError writes an error as {"error": message, "code": code, "details": details}
+// The status follows the error code: missing records are 404, deleted ones 410, read-only mode 403, other
+// conflicts 409, corrupt files and IO failures 500, and everything else 400
+func writeError(w http.ResponseWriter, err error) {
+	response := errorResponse(err)
+	status := http.StatusBadRequest
+	switch response["code"] {
+	case utils.CodeNotFound:
+		status = http.StatusNotFound
+	case utils.CodeDeleted:
+		status = http.StatusGone
+	case utils.CodeConflict:
+		status = http.StatusConflict
+		if isReadOnlyError(err) {
+			status = http.StatusForbidden
+		}
+	case utils.CodeCorruption, utils.CodeIO:
+		status = http.StatusInternalServerError
+	}
+	response["error"] = response["message"]
+	delete(response, "message")
+	writeJSON(w, status, response)
+}
+
+// writeResult writes value, or err when the call failed
+func writeResult(w http.ResponseWriter, status int, value any, err error) {
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, status, value)
+}
+
+// decodeBody decodes the JSON body of a request into value
+func decodeBody(r *http.Request, value any) error {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(value); err != nil {
+		return fmt.Errorf("invalid request body: %w", err)
+	}
+	return nil
+}
+
+// pathID parses a numeric path parameter
+func pathID(r *http.Request, name string) (uint64, error) {
+	id, err := strconv.ParseUint(r.PathValue(name), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q", name, r.PathValue(name))
+	}
+	return id, nil
+}
+
+// listOptions parses the activeOnly, sortBy and direction query parameters of a list request
+func listOptions(r *http.Request) (ListOptions, error) {
+	query := r.URL.Query()
+	opts := ListOptions{SortBy: query.Get("sortBy"), Direction: query.Get("direction")}
+	if value := query.Get("activeOnly"); value != "" {
+		activeOnly, err := strconv.ParseBool(value)
+		if err != nil {
+			return opts, fmt.Errorf("invalid activeOnly %q", value)
+		}
+		opts.ActiveOnly = activeOnly
+	}
+	return opts, nil
+}
+
+// withID parses the {id} path parameter and passes it to handle
+func withID(handle func(http.ResponseWriter, *http.Request, uint64)) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := pathID(r, "id")
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		handle(w, r, id)
+	}
+}
+
+// listItems handles GET /api/items
+func (s *apiServer) listItems(w http.ResponseWriter, r *http.Request) {
+	opts, err := listOptions(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	items, err := s.app.GetAllItems(opts)
+	writeResult(w, http.StatusOK, items, err)
+}
+
+// createItem handles POST /api/items
+func (s *apiServer) createItem(w http.ResponseWriter, r *http.Request) {
+	var req itemRequest
+	if err := decodeBody(r, &req); err != nil {
+		writeError(w, err)
+		return
+	}
+	id, err := s.app.AddItem(req.Name, req.PriceInCents)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	item, err := s.app.GetItem(id)
+	writeResult(w, http.StatusCreated, item, err)
+}
+
+// getItem handles GET /api/items/{id}
+func (s *apiServer) getItem(w http.ResponseWriter, r *http.Request, id uint64) {
+	item, err := s.app.GetItem(id)
+	writeResult(w, http.StatusOK, item, err)
+}
+
+// getItemByExternalID handles GET /api/items/external/{externalId}
+func (s *apiServer) getItemByExternalID(w http.ResponseWriter, r *http.Request) {
+	item, err := s.app.GetItemByExternalID(r.PathValue("externalId"))
+	writeResult(w, http.StatusOK, item, err)
+}
+
+// updateItem handles PUT /api/items/{id}
+func (s *apiServer) updateItem(w http.ResponseWriter, r *http.Request, id uint64) {
+	var req itemRequest
+	if err := decodeBody(r, &req); err != nil {
+		writeError(w, err)
+		return
+	}
+	item, err := s.app.UpdateItem(id, req.Name, req.PriceInCents)
+	writeResult(w, http.StatusOK, item, err)
+}
+
+// deleteItem handles DELETE /api/items/{id}
+func (s *apiServer) deleteItem(w http.ResponseWriter, r *http.Request, id uint64) {
+	err := s.app.DeleteItem(id)
+	writeResult(w, http.StatusOK, map[string]any{"deleted": id}, err)
+}
+
+// listOrders handles GET /api/orders
+func (s *apiServer) listOrders(w http.ResponseWriter, r *http.Request) {
+	opts, err := listOptions(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	orders, err := s.app.GetAllOrders(r.URL.Query().Get("status"), opts)
+	writeResult(w, http.StatusOK, orders, err)
+}
+
+// createOrder handles POST /api/orders
+func (s *apiServer) createOrder(w http.ResponseWriter, r *http.Request) {
+	var req collectionRequest
+	if err := decodeBody(r, &req); err != nil {
+		writeError(w, err)
+		return
+	}
+	id, err := s.app.CreateOrder(req.Name, req.ItemIDs)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	order, err := s.app.GetOrder(id)
+	writeResult(w, http.StatusCreated, order, err)
+}
+
+// getOrder handles GET /api/orders/{id}
+func (s *apiServer) getOrder(w http.ResponseWriter, r *http.Request, id uint64) {
+	order, err := s.app.GetOrderWithPromotions(id)
+	writeResult(w, http.StatusOK, order, err)
+}
+
+// deleteOrder handles DELETE /api/orders/{id}
+func (s *apiServer) deleteOrder(w http.ResponseWriter, r *http.Request, id uint64) {
+	err := s.app.DeleteOrder(id)
+	writeResult(w, http.StatusOK, map[string]any{"deleted": id}, err)
+}
+
+// addItemToOrder handles POST /api/orders/{id}/items/{itemId}
+func (s *apiServer) addItemToOrder(w http.ResponseWriter, r *http.Request, id uint64) {
+	itemID, err := pathID(r, "itemId")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	order, err := s.app.AddItemToOrder(id, itemID)
+	writeResult(w, http.StatusOK, order, err)
+}
+
+// removeItemFromOrder handles DELETE /api/orders/{id}/items/{itemId}
+func (s *apiServer) removeItemFromOrder(w http.ResponseWriter, r *http.Request, id uint64) {
+	itemID, err := pathID(r, "itemId")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	order, err := s.app.RemoveItemFromOrder(id, itemID)
+	writeResult(w, http.StatusOK, order, err)
+}
+
+// getOrderPromotions handles GET /api/orders/{id}/promotions
+func (s *apiServer) getOrderPromotions(w http.ResponseWriter, r *http.Request, id uint64) {
+	promotions, err := s.app.GetOrderPromotions(id)
+	writeResult(w, http.StatusOK, promotions, err)
+}
+
+// applyPromotion handles POST /api/orders/{id}/promotions/{promotionId}
+func (s *apiServer) applyPromotion(w http.ResponseWriter, r *http.Request, id uint64) {
+	promotionID, err := pathID(r, "promotionId")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if err := s.app.ApplyPromotionToOrder(id, promotionID); err != nil {
+		writeError(w, err)
+		return
+	}
+	order, err := s.app.GetOrderWithPromotions(id)
+	writeResult(w, http.StatusOK, order, err)
+}
+
+// removePromotion handles DELETE /api/orders/{id}/promotions/{promotionId}
+func (s *apiServer) removePromotion(w http.ResponseWriter, r *http.Request, id uint64) {
+	promotionID, err := pathID(r, "promotionId")
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if err := s.app.RemovePromotionFromOrder(id, promotionID); err != nil {
+		writeError(w, err)
+		return
+	}
+	order, err := s.app.GetOrderWithPromotions(id)
+	writeResult(w, http.StatusOK, order, err)
+}
+
+// listPromotions handles GET /api/promotions
+func (s *apiServer) listPromotions(w http.ResponseWriter, r *http.Request) {
+	opts, err := listOptions(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	promotions, err := s.app.GetAllPromotions(opts)
+	writeResult(w, http.StatusOK, promotions, err)
+}
+
+// createPromotion handles POST /api/promotions
+func (s *apiServer) createPromotion(w http.ResponseWriter, r *http.Request) {
+	var req collectionRequest
+	if err := decodeBody(r, &req); err != nil {
+		writeError(w, err)
+		return
+	}
+	id, err := s.app.CreatePromotion(req.Name, req.ItemIDs)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	promotion, err := s.app.GetPromotion(id)
+	writeResult(w, http.StatusCreated, promotion, err)
+}
+
+// getPromotion handles GET /api/promotions/{id}
+func (s *apiServer) getPromotion(w http.ResponseWriter, r *http.Request, id uint64) {
+	promotion, err := s.app.GetPromotion(id)
+	writeResult(w, http.StatusOK, promotion, err)
+}
+
+// deletePromotion handles DELETE /api/promotions/{id}
+func (s *apiServer) deletePromotion(w http.ResponseWriter, r *http.Request, id uint64) {
+	err := s.app.DeletePromotion(id)
+	writeResult(w, http.StatusOK, map[string]any{"deleted": id}, err)
+}
+
+// compact handles POST /api/compact
+func (s *apiServer) compact(w http.ResponseWriter, r *http.Request) {
+	result, err := s.app.Compact()
+	writeResult(w, http.StatusOK, result, err)
+}
+
+// listCompressed handles GET /api/compressed
+func (s *apiServer) listCompressed(w http.ResponseWriter, r *http.Request) {
+	files, err := s.app.GetCompressedFiles()
+	writeResult(w, http.StatusOK, files, err)
+}
+
+// compress handles POST /api/compress
+// It compresses one bin file, or every bin file into an archive when filename is empty
+func (s *apiServer) compress(w http.ResponseWriter, r *http.Request) {
+	var req compressRequest
+	if err := decodeBody(r, &req); err != nil {
+		writeError(w, err)
+		return
+	}
+	if req.Filename == "" {
+		result, err := s.app.CompressAllFiles(req.Algorithm, req.Encrypt)
+		writeResult(w, http.StatusOK, result, err)
+		return
+	}
+	result, err := s.app.CompressFile(req.Filename, req.Algorithm, req.KeepOriginal)
+	writeResult(w, http.StatusOK, result, err)
+}
+
+// decompress handles POST /api/decompress
+func (s *apiServer) decompress(w http.ResponseWriter, r *http.Request) {
+	var req compressRequest
+	if err := decodeBody(r, &req); err != nil {
+		writeError(w, err)
+		return
+	}
+	result, err := s.app.DecompressFile(req.Filename)
+	writeResult(w, http.StatusOK, result, err)
+}
@@ -0,0 +1,321 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestAPI serves the REST API of a new test app, with automatic compaction off so deletes do not start one
+func newTestAPI(t *testing.T, metrics bool) (*App, string) {
+	t.Helper()
+	app := newTestApp(t)
+	config := app.GetConfig()
+	config.AutoCompact = false
+	if _, err := app.UpdateConfig(config); err != nil {
+		t.Fatalf("Failed to disable automatic compaction: %v", err)
+	}
+
+	server := httptest.NewServer(app.apiHandler(metrics))
+	t.Cleanup(server.Close)
+	return app, server.URL
+}
+
+// apiCall sends a request to the REST API and decodes the JSON response
+func apiCall(t *testing.T, baseURL, method, path string, body any) (int, any) {
+	t.Helper()
+	var reader io.Reader
+	switch value := body.(type) {
+	case nil:
+	case string:
+		reader = strings.NewReader(value)
+	default:
+		data, err := json.Marshal(value)
+		if err != nil {
+			t.Fatalf("Failed to encode body: %v", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	request, err := http.NewRequest(method, baseURL+path, reader)
+	if err != nil {
+		t.Fatalf("Failed to build request: %v", err)
+	}
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatalf("%s %s failed: %v", method, path, err)
+	}
+	defer response.Body.Close()
+
+	var decoded any
+	data, err := io.ReadAll(response.Body)
+	if err != nil {
+		t.Fatalf("Failed to read response: %v", err)
+	}
+	if len(data) > 0 && strings.HasPrefix(response.Header.Get("Content-Type"), "application/json") {
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			t.Fatalf("Failed to decode response of %s %s: %v", method, path, err)
+		}
+	} else {
+		decoded = string(data)
+	}
+	return response.StatusCode, decoded
+}
+
+// expectStatus calls the REST API and fails the test unless it answers with status
+func expectStatus(t *testing.T, baseURL string, status int, method, path string, body any) any {
+	t.Helper()
+	got, decoded := apiCall(t, baseURL, method, path, body)
+	if got != status {
+		t.Fatalf("%s %s: expected status %d, got %d (%v)", method, path, status, got, decoded)
+	}
+	return decoded
+}
+
+// jsonID reads the id field of a decoded JSON object
+func jsonID(t *testing.T, value any) uint64 {
+	t.Helper()
+	object, ok := value.(map[string]any)
+	if !ok {
+		t.Fatalf("Expected an object, got %v", value)
+	}
+	id, ok := object["id"].(float64)
+	if !ok {
+		t.Fatalf("Expected an id in %v", object)
+	}
+	return uint64(id)
+}
+
+// jsonLen returns the length of a decoded JSON array
+func jsonLen(t *testing.T, value any) int {
+	t.Helper()
+	list, ok := value.([]any)
+	if !ok {
+		t.Fatalf("Expected an array, got %v", value)
+	}
+	return len(list)
+}
+
+func TestAPIItemRoutes(t *testing.T) {
+	app, url := newTestAPI(t, false)
+
+	burger := jsonID(t, expectStatus(t, url, http.StatusCreated, "POST", "/api/items", map[string]any{"name": "Burger", "priceInCents": 1250}))
+	fries := jsonID(t, expectStatus(t, url, http.StatusCreated, "POST", "/api/items", map[string]any{"name": "Fries", "priceInCents": 450}))
+
+	if n := jsonLen(t, expectStatus(t, url, http.StatusOK, "GET", "/api/items", nil)); n != 2 {
+		t.Errorf("Expected 2 items, got %d", n)
+	}
+	item := expectStatus(t, url, http.StatusOK, "GET", fmt.Sprintf("/api/items/%d", burger), nil).(map[string]any)
+	if item["name"] != "Burger" {
+		t.Errorf("Expected Burger, got %v", item)
+	}
+
+	updated := expectStatus(t, url, http.StatusOK, "PUT", fmt.Sprintf("/api/items/%d", burger), map[string]any{"name": "Cheeseburger", "priceInCents": 1350}).(map[string]any)
+	if updated["name"] != "Cheeseburger" || updated["priceInCents"] != float64(1350) {
+		t.Errorf("Expected the updated item, got %v", updated)
+	}
+
+	const externalID = "0b6f1c5e-8f2a-4d3b-9c1e-2a7d4e5f6a7b"
+	if _, err := app.SetItemExternalID(burger, externalID); err != nil {
+		t.Fatalf("Failed to set external ID: %v", err)
+	}
+	if id := jsonID(t, expectStatus(t, url, http.StatusOK, "GET", "/api/items/external/"+externalID, nil)); id != burger {
+		t.Errorf("Expected item %d by external ID, got %d", burger, id)
+	}
+
+	expectStatus(t, url, http.StatusOK, "DELETE", fmt.Sprintf("/api/items/%d", fries), nil)
+	expectStatus(t, url, http.StatusGone, "GET", fmt.Sprintf("/api/items/%d", fries), nil)
+}
+
+func TestAPIOrderAndPromotionRoutes(t *testing.T) {
+	_, url := newTestAPI(t, false)
+
+	burger := jsonID(t, expectStatus(t, url, http.StatusCreated, "POST", "/api/items", map[string]any{"name": "Burger", "priceInCents": 1250}))
+	fries := jsonID(t, expectStatus(t, url, http.StatusCreated, "POST", "/api/items", map[string]any{"name": "Fries", "priceInCents": 450}))
+
+	order := jsonID(t, expectStatus(t, url, http.StatusCreated, "POST", "/api/orders", map[string]any{"name": "Alice", "itemIds": []uint64{burger}}))
+	if n := jsonLen(t, expectStatus(t, url, http.StatusOK, "GET", "/api/orders", nil)); n != 1 {
+		t.Errorf("Expected 1 order, got %d", n)
+	}
+	if id := jsonID(t, expectStatus(t, url, http.StatusOK, "GET", fmt.Sprintf("/api/orders/%d", order), nil)); id != order {
+		t.Errorf("Expected order %d, got %d", order, id)
+	}
+
+	withFries := expectStatus(t, url, http.StatusOK, "POST", fmt.Sprintf("/api/orders/%d/items/%d", order, fries), nil).(map[string]any)
+	if withFries["itemCount"] != float64(2) {
+		t.Errorf("Expected 2 items after adding fries, got %v", withFries)
+	}
+	withoutFries := expectStatus(t, url, http.StatusOK, "DELETE", fmt.Sprintf("/api/orders/%d/items/%d", order, fries), nil).(map[string]any)
+	if withoutFries["itemCount"] != float64(1) {
+		t.Errorf("Expected 1 item after removing fries, got %v", withoutFries)
+	}
+
+	promotion := jsonID(t, expectStatus(t, url, http.StatusCreated, "POST", "/api/promotions", map[string]any{"name": "Combo", "itemIds": []uint64{burger, fries}}))
+	if n := jsonLen(t, expectStatus(t, url, http.StatusOK, "GET", "/api/promotions", nil)); n != 1 {
+		t.Errorf("Expected 1 promotion, got %d", n)
+	}
+	if id := jsonID(t, expectStatus(t, url, http.StatusOK, "GET", fmt.Sprintf("/api/promotions/%d", promotion), nil)); id != promotion {
+		t.Errorf("Expected promotion %d, got %d", promotion, id)
+	}
+
+	promotionPath := fmt.Sprintf("/api/orders/%d/promotions/%d", order, promotion)
+	expectStatus(t, url, http.StatusOK, "POST", promotionPath, nil)
+	if n := jsonLen(t, expectStatus(t, url, http.StatusOK, "GET", fmt.Sprintf("/api/orders/%d/promotions", order), nil)); n != 1 {
+		t.Errorf("Expected 1 applied promotion, got %d", n)
+	}
+	expectStatus(t, url, http.StatusOK, "DELETE", promotionPath, nil)
+	if n := jsonLen(t, expectStatus(t, url, http.StatusOK, "GET", fmt.Sprintf("/api/orders/%d/promotions", order), nil)); n != 0 {
+		t.Errorf("Expected no applied promotions, got %d", n)
+	}
+
+	expectStatus(t, url, http.StatusOK, "DELETE", fmt.Sprintf("/api/promotions/%d", promotion), nil)
+	expectStatus(t, url, http.StatusGone, "GET", fmt.Sprintf("/api/promotions/%d", promotion), nil)
+	expectStatus(t, url, http.StatusOK, "DELETE", fmt.Sprintf("/api/orders/%d", order), nil)
+	expectStatus(t, url, http.StatusGone, "GET", fmt.Sprintf("/api/orders/%d", order), nil)
+}
+
+func TestAPIMaintenanceRoutes(t *testing.T) {
+	_, url := newTestAPI(t, true)
+
+	for _, name := range []string{"Burger", "Fries", "Soda"} {
+		expectStatus(t, url, http.StatusCreated, "POST", "/api/items", map[string]any{"name": name, "priceInCents": 300})
+	}
+	expectStatus(t, url, http.StatusOK, "DELETE", "/api/items/1", nil)
+
+	compacted := expectStatus(t, url, http.StatusOK, "POST", "/api/compact", nil).(map[string]any)
+	if len(compacted) == 0 {
+		t.Error("Expected a compaction result")
+	}
+
+	compressed := expectStatus(t, url, http.StatusOK, "POST", "/api/compress", map[string]any{"filename": "items.bin", "algorithm": "gzip", "keepOriginal": true}).(map[string]any)
+	outputFile, _ := compressed["outputFile"].(string)
+	if outputFile == "" {
+		t.Fatalf("Expected an output file, got %v", compressed)
+	}
+	expectStatus(t, url, http.StatusOK, "POST", "/api/compress", map[string]any{"algorithm": "gzip"})
+	if n := jsonLen(t, expectStatus(t, url, http.StatusOK, "GET", "/api/compressed", nil)); n != 2 {
+		t.Errorf("Expected 2 compressed files, got %d", n)
+	}
+	expectStatus(t, url, http.StatusOK, "POST", "/api/decompress", map[string]any{"filename": outputFile})
+	expectStatus(t, url, http.StatusNotFound, "POST", "/api/decompress", map[string]any{"filename": "missing.bin.gz"})
+
+	metrics, _ := expectStatus(t, url, http.StatusOK, "GET", "/metrics", nil).(string)
+	if !strings.Contains(metrics, "AddItem") {
+		t.Errorf("Expected metrics for AddItem, got %q", metrics)
+	}
+}
+
+func TestAPIMetricsNeedFlag(t *testing.T) {
+	_, url := newTestAPI(t, false)
+	expectStatus(t, url, http.StatusNotFound, "GET", "/metrics", nil)
+}
+
+func TestAPIErrorStatus(t *testing.T) {
+	app, url := newTestAPI(t, false)
+
+	expectStatus(t, url, http.StatusBadRequest, "GET", "/api/items/abc", nil)
+	expectStatus(t, url, http.StatusBadRequest, "POST", "/api/items", `{"name": "Burger", "price": 1}`)
+	expectStatus(t, url, http.StatusBadRequest, "POST", "/api/items", map[string]any{"name": "", "priceInCents": 100})
+	expectStatus(t, url, http.StatusNotFound, "GET", "/api/items/99", nil)
+	expectStatus(t, url, http.StatusBadRequest, "POST", "/api/orders/0/items/abc", nil)
+
+	app.readOnly = true
+	body := expectStatus(t, url, http.StatusForbidden, "POST", "/api/items", map[string]any{"name": "Burger", "priceInCents": 100}).(map[string]any)
+	if body["code"] == nil || body["error"] == nil {
+		t.Errorf("Expected an error code and message, got %v", body)
+	}
+}
+
+func TestAPIEventStream(t *testing.T) {
+	_, url := newTestAPI(t, false)
+
+	response, err := http.Get(url + "/api/events")
+	if err != nil {
+		t.Fatalf("Failed to open event stream: %v", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK || response.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("Expected an event stream, got %d %s", response.StatusCode, response.Header.Get("Content-Type"))
+	}
+
+	expectStatus(t, url, http.StatusCreated, "POST", "/api/items", map[string]any{"name": "Burger", "priceInCents": 1250})
+
+	lines := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(response.Body)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		close(lines)
+	}()
+	select {
+	case line := <-lines:
+		if line != "event: ItemCreated" {
+			t.Errorf("Expected an ItemCreated event, got %q", line)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for an event")
+	}
+}
+
+func TestAPIServerStartsAndStops(t *testing.T) {
+	app := newTestApp(t)
+	app.apiAddr = "127.0.0.1:0"
+
+	if err := app.startAPIServer(); err != nil {
+		t.Fatalf("Failed to start the REST API: %v", err)
+	}
+	url := "http://" + app.api.addr
+	if err := app.startAPIServer(); err == nil {
+		t.Error("Expected starting the REST API twice to fail")
+	}
+
+	expectStatus(t, url, http.StatusCreated, "POST", "/api/items", map[string]any{"name": "Burger", "priceInCents": 1250})
+
+	// An open event stream must not hold up the shutdown
+	stream, err := http.Get(url + "/api/events")
+	if err != nil {
+		t.Fatalf("Failed to open event stream: %v", err)
+	}
+	defer stream.Body.Close()
+
+	stopped := make(chan struct{})
+	go func() {
+		app.stopAPIServer()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(APIShutdownTimeout + time.Second):
+		t.Fatal("Timed out stopping the REST API")
+	}
+
+	if app.api != nil {
+		t.Error("Expected the REST API to be cleared after stopping")
+	}
+	if _, err := http.Get(url + "/api/items"); err == nil {
+		t.Error("Expected the REST API to refuse requests after stopping")
+	}
+}
+
+func TestAPIServerReportsAddressInUse(t *testing.T) {
+	first := newTestApp(t)
+	first.apiAddr = "127.0.0.1:0"
+	if err := first.startAPIServer(); err != nil {
+		t.Fatalf("Failed to start the REST API: %v", err)
+	}
+	defer first.stopAPIServer()
+
+	second := &App{apiAddr: first.api.addr, logger: first.logger}
+	if err := second.startAPIServer(); err == nil {
+		second.stopAPIServer()
+		t.Error("Expected a busy address to fail at startup")
+	}
+}
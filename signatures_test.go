@@ -0,0 +1,75 @@
+package main
+
+import (
+	"BinaryCRUD/backend/utils"
+	"bytes"
+	"os"
+	"testing"
+)
+
+func TestVerifySignatures(t *testing.T) {
+	app := newTestApp(t)
+	t.Cleanup(func() { utils.SigningEnabled = false })
+	for _, name := range []string{"Burger", "Fries"} {
+		if _, err := app.AddItem(name, 499); err != nil {
+			t.Fatalf("Failed to add item: %v", err)
+		}
+	}
+	if _, err := app.CreateOrder("Alice", []uint64{0, 1}); err != nil {
+		t.Fatalf("Failed to create order: %v", err)
+	}
+
+	statuses := func() map[string]any {
+		t.Helper()
+		results, err := app.VerifySignatures()
+		if err != nil {
+			t.Fatalf("VerifySignatures failed: %v", err)
+		}
+		result := map[string]any{}
+		for _, file := range results {
+			result[file["file"].(string)] = file["status"]
+		}
+		return result
+	}
+
+	if got := statuses(); got["items.bin"] != utils.SignatureMissing || got["orders.bin"] != utils.SignatureMissing {
+		t.Errorf("Expected unsigned files before signing is enabled, got %v", got)
+	}
+
+	// Enabling signs the existing files, later writes keep their signatures current
+	if err := app.SetSigningEnabled(true); err != nil {
+		t.Fatalf("Failed to enable signing: %v", err)
+	}
+	if _, err := app.AddItem("Soda", 199); err != nil {
+		t.Fatalf("Failed to add item: %v", err)
+	}
+	for file, status := range statuses() {
+		if status != utils.SignatureValid {
+			t.Errorf("Expected %s to be valid, got %v", file, status)
+		}
+	}
+
+	// A change made outside the app only flags the file it was made in
+	path := utils.BinPath("items.bin")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read items: %v", err)
+	}
+	if err := os.WriteFile(path, bytes.Replace(data, []byte("Fries"), []byte("Fried"), 1), 0644); err != nil {
+		t.Fatalf("Failed to write items: %v", err)
+	}
+	got := statuses()
+	if got["items.bin"] != utils.SignatureTampered || got["orders.bin"] != utils.SignatureValid {
+		t.Errorf("Expected only items.bin tampered, got %v", got)
+	}
+
+	// Disabling removes the signatures instead of leaving them to go stale
+	if err := app.SetSigningEnabled(false); err != nil {
+		t.Fatalf("Failed to disable signing: %v", err)
+	}
+	for file, status := range statuses() {
+		if status != utils.SignatureMissing {
+			t.Errorf("Expected %s to be unsigned, got %v", file, status)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"BinaryCRUD/backend/dao"
+	"BinaryCRUD/backend/oplog"
+	"BinaryCRUD/backend/utils"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// stockExtensions returns the record extensions for an optional initial stock quantity
+func stockExtensions(stock *uint64) (map[byte][]byte, error) {
+	if stock == nil {
+		return nil, nil
+	}
+	data, err := utils.EncodeStock(*stock)
+	if err != nil {
+		return nil, err
+	}
+	return map[byte][]byte{utils.ExtStock: data}, nil
+}
+
+// itemStock returns the stock of an item, false when the item does not track stock
+func itemStock(item *dao.Item) (uint64, bool) {
+	quantity, tracked, err := utils.DecodeStock(item.Extensions)
+	if err != nil {
+		return 0, false
+	}
+	return quantity, tracked
+}
+
+// addStockFields adds the stock of an item to an API response map
+func addStockFields(result map[string]any, item *dao.Item) map[string]any {
+	quantity, tracked := itemStock(item)
+	result["tracksStock"] = tracked
+	if tracked {
+		result["stock"] = quantity
+	}
+	return result
+}
+
+// setItemStock rewrites an item with a new stock quantity
+func (a *App) setItemStock(item *dao.Item, quantity uint64) error {
+	data, err := utils.EncodeStock(quantity)
+	if err != nil {
+		return err
+	}
+	ext := utils.WithExtension(item.Extensions, utils.ExtStock, data)
+
+	if err := a.itemDAO.UpdateExtensions(item.ID, ext); err != nil {
+		return fmt.Errorf("failed to update stock of item %d: %w", item.ID, err)
+	}
+
+	before := "untracked"
+	if previous, tracked := itemStock(item); tracked {
+		before = fmt.Sprintf("stock %d", previous)
+	}
+	a.recordOp(oplog.Operation{Type: oplog.OpUpdateItem, ID: item.ID, Name: item.Name, Price: item.PriceInCents, Extensions: ext})
+	a.recordAudit(dao.AuditUpdate, "item", item.ID, before, fmt.Sprintf("stock %d", quantity))
+
+	item.Extensions = ext
+	return nil
+}
+
+// reserveStock decrements the stock of every tracked item in itemIDs (an ID listed twice takes two units)
+// Nothing is changed unless every tracked item has enough stock
+func (a *App) reserveStock(itemIDs []uint64) error {
+	needed := make(map[uint64]uint64)
+	for _, id := range itemIDs {
+		needed[id]++
+	}
+
+	ids := make([]uint64, 0, len(needed))
+	for id := range needed {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	items := make([]*dao.Item, 0, len(ids))
+	for _, id := range ids {
+		item, err := a.itemDAO.ReadItem(id)
+		if err != nil {
+			return fmt.Errorf("failed to read item %d: %w", id, err)
+		}
+		if quantity, tracked := itemStock(item); tracked {
+			if quantity < needed[id] {
+				return fmt.Errorf("insufficient stock for item %d (%s): %d available, %d requested", id, item.Name, quantity, needed[id])
+			}
+			items = append(items, item)
+		}
+	}
+
+	for _, item := range items {
+		quantity, _ := itemStock(item)
+		if err := a.setItemStock(item, quantity-needed[item.ID]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// restock returns one unit per listed item to the stock of tracked items that still exist
+func (a *App) restock(itemIDs []uint64) {
+	returned := make(map[uint64]uint64)
+	for _, id := range itemIDs {
+		returned[id]++
+	}
+
+	for id, count := range returned {
+		item, err := a.itemDAO.ReadItem(id)
+		if err != nil {
+			continue
+		}
+		quantity, tracked := itemStock(item)
+		if !tracked {
+			continue
+		}
+		if err := a.setItemStock(item, quantity+count); err != nil {
+			a.logger.Warn(fmt.Sprintf("Failed to restock item #%d: %v", id, err))
+		}
+	}
+}
+
+// AdjustStock changes the stock of an item by delta and returns the updated item
+// Items that do not track stock yet start from zero, stock can never go below zero
+func (a *App) AdjustStock(itemID uint64, delta int64) (_ map[string]any, err error) {
+	defer a.track("AdjustStock", time.Now(), &err)
+	if err := a.checkWritable(); err != nil {
+		return nil, err
+	}
+
+	item, err := a.itemDAO.ReadItem(itemID)
+	if err != nil {
+		return nil, err
+	}
+
+	quantity, _ := itemStock(item)
+	if delta < 0 && uint64(-delta) > quantity {
+		return nil, fmt.Errorf("cannot remove %d units from item %d, only %d in stock", -delta, itemID, quantity)
+	}
+
+	newQuantity := quantity + uint64(delta)
+	if delta < 0 {
+		newQuantity = quantity - uint64(-delta)
+	}
+	if err := a.setItemStock(item, newQuantity); err != nil {
+		return nil, err
+	}
+
+	a.logger.Info(fmt.Sprintf("Adjusted stock of item #%d (%s) by %+d to %d", itemID, item.Name, delta, newQuantity))
+
+	return addStockFields(map[string]any{
+		"id":           item.ID,
+		"name":         item.Name,
+		"priceInCents": item.PriceInCents,
+	}, item), nil
+}
+
+// GetLowStockReport lists tracked items whose stock is at or below threshold, lowest stock first
+func (a *App) GetLowStockReport(threshold uint64) (_ []map[string]any, err error) {
+	defer a.track("GetLowStockReport", time.Now(), &err)
+	items, err := a.itemDAO.GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	type lowStock struct {
+		item     dao.Item
+		quantity uint64
+	}
+	var low []lowStock
+	for _, item := range items {
+		if item.IsDeleted {
+			continue
+		}
+		if quantity, tracked := itemStock(&item); tracked && quantity <= threshold {
+			low = append(low, lowStock{item: item, quantity: quantity})
+		}
+	}
+	sort.SliceStable(low, func(i, j int) bool { return low[i].quantity < low[j].quantity })
+
+	result := make([]map[string]any, len(low))
+	for i, entry := range low {
+		result[i] = map[string]any{
+			"id":           entry.item.ID,
+			"name":         entry.item.Name,
+			"priceInCents": entry.item.PriceInCents,
+			"stock":        entry.quantity,
+		}
+	}
+
+	a.logger.Info(fmt.Sprintf("Low stock report: %d items at or below %d units", len(result), threshold))
+	return result, nil
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestGetLowStockReport(t *testing.T) {
+	app := newTestApp(t)
+	for _, item := range []struct {
+		name  string
+		stock int64
+	}{{"Burger", 2}, {"Fries", 10}, {"Soda", 0}, {"Water", -1}, {"Shake", 1}} {
+		id, err := app.AddItem(item.name, 299)
+		if err != nil {
+			t.Fatalf("Failed to add item: %v", err)
+		}
+		if item.stock >= 0 {
+			if _, err := app.AdjustStock(id, item.stock); err != nil {
+				t.Fatalf("Failed to set stock: %v", err)
+			}
+		}
+	}
+	// Ordering a Fries takes it out of stock; Shake is deleted; Water doesn't track stock
+	if _, err := app.CreateOrder("Alice", []uint64{1}); err != nil {
+		t.Fatalf("Failed to create order: %v", err)
+	}
+	if err := app.DeleteItem(4); err != nil {
+		t.Fatalf("Failed to delete item: %v", err)
+	}
+
+	report := func(threshold uint64) string {
+		t.Helper()
+		items, err := app.GetLowStockReport(threshold)
+		if err != nil {
+			t.Fatalf("Failed to get low stock report: %v", err)
+		}
+		result := make([]string, len(items))
+		for i, item := range items {
+			result[i] = fmt.Sprintf("%v:%v", item["name"], item["stock"])
+		}
+		return fmt.Sprint(result)
+	}
+	if got := report(5); got != "[Soda:0 Burger:2]" {
+		t.Errorf("Expected Soda and Burger, lowest first, got %v", got)
+	}
+	if got := report(9); got != "[Soda:0 Burger:2 Fries:9]" {
+		t.Errorf("Expected Fries after the order took one, got %v", got)
+	}
+	if got := report(0); got != "[Soda:0]" {
+		t.Errorf("Expected only the item out of stock, got %v", got)
+	}
+}
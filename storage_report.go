@@ -0,0 +1,109 @@
+package main
+
+import (
+	"BinaryCRUD/backend/dao"
+	"BinaryCRUD/backend/utils"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"time"
+)
+
+// recordStore is a DAO whose header counts and tombstone bitmap describe its data file
+type recordStore interface {
+	Counts() (dao.RecordCounts, error)
+	RecordBytes() (utils.RecordBytes, error)
+}
+
+// GetStorageReport returns the disk space used by each part of the data directory and, for every .bin
+// file, how much of it is taken by live and by tombstoned records, so users know when to compact or compress
+// Directory sizes include every file under them; the record counts come from the file headers and the
+// record bytes from the tombstone bitmaps, so only order_promotions.bin, which has no bitmap, is scanned
+func (a *App) GetStorageReport() (_ map[string]any, err error) {
+	defer a.track("GetStorageReport", time.Now(), &err)
+	report := map[string]any{}
+	var total int64
+	for _, dir := range []struct{ name, path string }{
+		{"bin", utils.BinDir},
+		{"indexes", utils.IndexDir},
+		{"compressed", utils.CompressedDir},
+		{"keys", utils.KeysDir},
+		{"logs", utils.LogsDir},
+		{"trash", utils.TrashDir},
+	} {
+		dirUsage, err := utils.ReadDirUsage(dir.path)
+		if err != nil {
+			return nil, err
+		}
+		report[dir.name] = dirUsage
+		total += dirUsage.Bytes
+	}
+
+	names, err := utils.CurrentBinFiles(utils.BinDir)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+
+	stores := map[string]recordStore{
+		"items.bin":      a.itemDAO,
+		"orders.bin":     a.orderDAO,
+		"promotions.bin": a.promotionDAO,
+	}
+	policy := a.currentConfig().Compaction
+	files := make([]map[string]any, 0, len(names))
+	for _, name := range names {
+		path := utils.BinPath(name)
+		header, err := utils.ReadHeaderOnly(path)
+		if os.IsNotExist(err) {
+			continue // removed by a compaction since the directory was listed
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read header of %s: %w", name, err)
+		}
+		counts := dao.RecordCounts{Total: header.EntitiesCount, Active: header.EntitiesCount - header.TombstoneCount}
+		var records utils.RecordBytes
+		if store, ok := stores[name]; ok {
+			if counts, err = store.Counts(); err != nil {
+				return nil, err
+			}
+			records, err = store.RecordBytes()
+		} else {
+			keyFields := utils.IDKeyFields
+			if name == "order_promotions.bin" {
+				keyFields = utils.CompositeKeyFields
+			}
+			records, err = utils.ReadRecordBytes(path, keyFields)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		fragmentation := 0.0
+		if records.Live+records.Tombstoned > 0 {
+			fragmentation = float64(records.Tombstoned) / float64(records.Live+records.Tombstoned) * 100
+		}
+		shouldCompact := slices.Contains(compactedFiles, name) && policy.ShouldCompact(utils.FileFragmentation{
+			Path:           path,
+			SizeBytes:      header.FileSize,
+			EntitiesCount:  header.EntitiesCount,
+			TombstoneCount: header.TombstoneCount,
+		})
+		files = append(files, map[string]any{
+			"file":           name,
+			"sizeBytes":      header.FileSize,
+			"headerBytes":    header.HeaderSize,
+			"records":        counts.Total,
+			"activeRecords":  counts.Active,
+			"liveBytes":      records.Live,
+			"tombstoneBytes": records.Tombstoned,
+			"fragmentation":  fragmentation,
+			"shouldCompact":  shouldCompact,
+		})
+	}
+
+	report["totalBytes"] = total
+	report["files"] = files
+	return report, nil
+}
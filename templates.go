@@ -0,0 +1,114 @@
+package main
+
+import (
+	"BinaryCRUD/backend/dao"
+	"fmt"
+	"time"
+)
+
+// CreateTemplate saves a named item list, an order without a customer, that orders can be placed from
+// The total at the current prices is stored for display; orders placed from it are priced when they are created
+// Templates are kept in templates.bin and audited, but not logged in the oplog
+func (a *App) CreateTemplate(name string, itemIDs []uint64) (_ uint64, err error) {
+	start := time.Now()
+	defer a.track("CreateTemplate", start, &err)
+	if err := a.checkWritable(); err != nil {
+		return 0, err
+	}
+
+	if err := a.validateCollectionInput(name, itemIDs, "template"); err != nil {
+		return 0, err
+	}
+
+	priceResult, err := a.calculateTotalPrice(itemIDs, true, "template")
+	if err != nil {
+		return 0, err
+	}
+
+	assignedID, err := a.templateDAO.Write(name, priceResult.TotalPrice, itemIDs)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create template: %w", err)
+	}
+	a.recordAudit(dao.AuditCreate, "template", assignedID, "", collectionSummary(priceResult.TotalPrice, itemIDs))
+
+	a.logger.InfoWith(fmt.Sprintf("Created template #%d %s with %d items", assignedID, name, len(itemIDs)),
+		entityLog("template", assignedID, dao.AuditCreate, start)...)
+	return assignedID, nil
+}
+
+// GetAllTemplates retrieves the templates, including deleted ones unless opts.ActiveOnly is set,
+// in file order or sorted as opts asks
+func (a *App) GetAllTemplates(opts ListOptions) (_ []map[string]any, err error) {
+	defer a.track("GetAllTemplates", time.Now(), &err)
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	templates, err := listCollections(a.templateDAO.CollectionDAO, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]map[string]any, len(templates))
+	for i, template := range templates {
+		result[i] = map[string]any{
+			"id":         template.ID,
+			"name":       template.OwnerOrName,
+			"totalPrice": template.TotalPrice,
+			"itemCount":  template.ItemCount,
+			"itemIDs":    template.ItemIDs,
+			"isDeleted":  template.IsDeleted,
+		}
+	}
+
+	a.logger.Info(fmt.Sprintf("Retrieved %d templates", len(templates)))
+	return result, nil
+}
+
+// DeleteTemplate deletes a template, the orders placed from it are kept
+func (a *App) DeleteTemplate(id uint64) (err error) {
+	start := time.Now()
+	defer a.track("DeleteTemplate", start, &err)
+	if err := a.checkWritable(); err != nil {
+		return err
+	}
+
+	before := ""
+	if template, err := a.templateDAO.Read(id); err == nil {
+		before = collectionSummary(template.TotalPrice, template.ItemIDs)
+	}
+
+	if err := a.templateDAO.Delete(id); err != nil {
+		return err
+	}
+	a.recordAudit(dao.AuditDelete, "template", id, before, "")
+
+	a.logger.InfoWith(fmt.Sprintf("Deleted template #%d", id), entityLog("template", id, dao.AuditDelete, start)...)
+	return nil
+}
+
+// CreateOrderFromTemplate places an order for customerName with the items of a template, priced at current prices
+// Items deleted since the template was saved are left out; it fails when none of them remain
+// Returns the ID of the new order
+func (a *App) CreateOrderFromTemplate(templateID uint64, customerName string) (_ uint64, err error) {
+	defer a.track("CreateOrderFromTemplate", time.Now(), &err)
+	if err := a.checkWritable(); err != nil {
+		return 0, err
+	}
+
+	template, err := a.templateDAO.Read(templateID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read template: %w", err)
+	}
+
+	itemIDs, err := a.remainingItemIDs(template.ItemIDs, "template", templateID)
+	if err != nil {
+		return 0, err
+	}
+
+	orderID, err := a.CreateOrder(customerName, itemIDs)
+	if err != nil {
+		return 0, err
+	}
+	a.logger.Info(fmt.Sprintf("Created order #%d from template #%d (%s)", orderID, templateID, template.OwnerOrName))
+	return orderID, nil
+}
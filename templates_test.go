@@ -0,0 +1,63 @@
+package main
+
+import (
+	"BinaryCRUD/backend/utils"
+	"testing"
+)
+
+func TestCreateOrderFromTemplate(t *testing.T) {
+	app := newTestApp(t)
+	burgerID, err := app.AddItem("Burger", 899)
+	if err != nil {
+		t.Fatalf("Failed to add item: %v", err)
+	}
+	friesID, err := app.AddItem("Fries", 299)
+	if err != nil {
+		t.Fatalf("Failed to add item: %v", err)
+	}
+
+	if _, err := app.CreateTemplate("Lunch", nil); err == nil {
+		t.Error("Expected a template without items to be rejected")
+	}
+	templateID, err := app.CreateTemplate("Lunch", []uint64{burgerID, friesID})
+	if err != nil {
+		t.Fatalf("Failed to create template: %v", err)
+	}
+	templates, err := app.GetAllTemplates(ListOptions{})
+	if err != nil || len(templates) != 1 || templates[0]["name"] != "Lunch" || templates[0]["totalPrice"] != uint64(1198) {
+		t.Fatalf("Expected the Lunch template at 1198, got %v (err %v)", templates, err)
+	}
+
+	// Orders are priced when they are placed
+	if _, err := app.UpdateItem(burgerID, "Burger", 999); err != nil {
+		t.Fatalf("Failed to update item: %v", err)
+	}
+	orderID, err := app.CreateOrderFromTemplate(templateID, "Customer")
+	if err != nil {
+		t.Fatalf("Failed to create order from template: %v", err)
+	}
+	order, err := app.GetOrder(orderID)
+	if err != nil {
+		t.Fatalf("Failed to read order: %v", err)
+	}
+	if order["customerName"] != "Customer" || order["totalPrice"] != uint64(1298) {
+		t.Errorf("Expected an order for Customer at 1298, got %v", order)
+	}
+
+	if err := app.DeleteItem(burgerID); err != nil {
+		t.Fatalf("Failed to delete item: %v", err)
+	}
+	if err := app.DeleteItem(friesID); err != nil {
+		t.Fatalf("Failed to delete item: %v", err)
+	}
+	if _, err := app.CreateOrderFromTemplate(templateID, "Customer"); utils.ErrorCodeOf(err) != utils.CodeValidation {
+		t.Errorf("Expected a template with no items left to be rejected, got %v", err)
+	}
+
+	if err := app.DeleteTemplate(templateID); err != nil {
+		t.Fatalf("Failed to delete template: %v", err)
+	}
+	if _, err := app.CreateOrderFromTemplate(templateID, "Customer"); utils.ErrorCodeOf(err) != utils.CodeDeleted {
+		t.Errorf("Expected a deleted template to be rejected, got %v", err)
+	}
+}
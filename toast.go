@@ -16,25 +16,30 @@ func NewToast(app *App) *Toast {
 
 // Success shows a success toast
 func (t *Toast) Success(message string) {
-	runtime.EventsEmit(t.app.ctx, "toast:success", message)
+	t.Show(message, "success")
 }
 
 // Error shows an error toast
 func (t *Toast) Error(message string) {
-	runtime.EventsEmit(t.app.ctx, "toast:error", message)
+	t.Show(message, "error")
 }
 
 // Warning shows a warning toast
 func (t *Toast) Warning(message string) {
-	runtime.EventsEmit(t.app.ctx, "toast:warning", message)
+	t.Show(message, "warning")
 }
 
 // Info shows an info toast
 func (t *Toast) Info(message string) {
-	runtime.EventsEmit(t.app.ctx, "toast:info", message)
+	t.Show(message, "info")
 }
 
 // Show shows a toast with a custom type
+// Without a frontend (before startup, in tests) the message is logged instead
 func (t *Toast) Show(message string, toastType string) {
+	if t.app.ctx == nil {
+		t.app.logger.Info(message)
+		return
+	}
 	runtime.EventsEmit(t.app.ctx, "toast:"+toastType, message)
 }
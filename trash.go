@@ -0,0 +1,89 @@
+package main
+
+import (
+	"BinaryCRUD/backend/crypto"
+	"BinaryCRUD/backend/events"
+	"BinaryCRUD/backend/utils"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ListTrash returns the files deleted by DeleteAllFiles and DeleteCompressedFile that are still in the trash,
+// one entry per deletion, newest first
+func (a *App) ListTrash() (_ []utils.TrashEntry, err error) {
+	defer a.track("ListTrash", time.Now(), &err)
+	return utils.ListTrash()
+}
+
+// RestoreFromTrash moves the files of a trash entry back where they were deleted from
+// Files that took their place meanwhile are moved to a new trash entry, returned as "replaced"
+// Restoring data files reloads every DAO, like RestoreDatabase
+func (a *App) RestoreFromTrash(entry string) (_ map[string]any, err error) {
+	defer a.track("RestoreFromTrash", time.Now(), &err)
+	if err := a.checkWritable(); err != nil {
+		return nil, err
+	}
+
+	entries, err := utils.ListTrash()
+	if err != nil {
+		return nil, err
+	}
+	reload := false
+	for _, e := range entries {
+		if e.Name != entry {
+			continue
+		}
+		for _, file := range e.Files {
+			if !strings.HasPrefix(file, "compressed/") {
+				reload = true
+			}
+		}
+	}
+
+	if reload {
+		// Held until the DAOs are reloaded, so no compaction starts on the files being restored
+		if err := a.beginCompaction(); err != nil {
+			return nil, err
+		}
+		defer a.endCompaction()
+		a.closeDAOs()
+	}
+	restored, replaced, err := utils.RestoreFromTrash(entry)
+	if reload {
+		// Drop cached keys and in-memory indexes so they are loaded from the restored files
+		crypto.Reset()
+		a.reloadDAOs()
+		a.publish(events.Event{Type: events.DataReloaded})
+	}
+	if err != nil {
+		a.logger.Error(fmt.Sprintf("Failed to restore trash entry %s: %v", entry, err))
+		return nil, err
+	}
+
+	a.logger.Info(fmt.Sprintf("Restored %d file(s) from trash entry %s", len(restored), entry))
+	if replaced != "" {
+		a.logger.Info(fmt.Sprintf("Files replaced by the restore were moved to trash entry %s", replaced))
+	}
+	a.toast.Success(fmt.Sprintf("Restored %d file(s) from trash", len(restored)))
+	return map[string]any{
+		"entry":    entry,
+		"restored": restored,
+		"replaced": replaced,
+	}, nil
+}
+
+// purgeTrash permanently removes the trash entries older than the configured retention
+func (a *App) purgeTrash() {
+	days := a.currentConfig().TrashRetentionDays
+	if days == 0 || a.checkWritable() != nil {
+		return
+	}
+	purged, err := utils.PurgeTrash(time.Now().AddDate(0, 0, -days))
+	if err != nil {
+		a.logger.Warn(fmt.Sprintf("Failed to purge trash: %v", err))
+	}
+	if len(purged) > 0 {
+		a.logger.Info(fmt.Sprintf("Purged %d trash entries older than %d days", len(purged), days))
+	}
+}
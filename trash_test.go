@@ -0,0 +1,78 @@
+package main
+
+import (
+	"BinaryCRUD/backend/utils"
+	"testing"
+)
+
+func TestDeleteAllFilesCanBeRestored(t *testing.T) {
+	app := newTestApp(t)
+	id, err := app.AddItem("Burger", 899)
+	if err != nil {
+		t.Fatalf("Failed to add item: %v", err)
+	}
+
+	if err := app.DeleteAllFiles(); err != nil {
+		t.Fatalf("DeleteAllFiles failed: %v", err)
+	}
+	if _, err := app.GetItem(id); err == nil {
+		t.Fatal("Expected the item to be gone after DeleteAllFiles")
+	}
+	if _, err := app.AddItem("Soda", 199); err != nil {
+		t.Fatalf("Failed to add item after DeleteAllFiles: %v", err)
+	}
+
+	entries, err := app.ListTrash()
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("Expected 1 trash entry, got %d (err %v)", len(entries), err)
+	}
+	result, err := app.RestoreFromTrash(entries[0].Name)
+	if err != nil {
+		t.Fatalf("RestoreFromTrash failed: %v", err)
+	}
+	if result["replaced"] == "" {
+		t.Error("Expected the files written since the deletion to be moved to the trash")
+	}
+
+	item, err := app.GetItem(id)
+	if err != nil {
+		t.Fatalf("Expected the item to be restored: %v", err)
+	}
+	if item["name"] != "Burger" {
+		t.Errorf("Expected the restored item to be Burger, got %v", item["name"])
+	}
+}
+
+func TestRestoreFromTrashWaitsForNoCompaction(t *testing.T) {
+	app := newTestApp(t)
+	id, err := app.AddItem("Burger", 899)
+	if err != nil {
+		t.Fatalf("Failed to add item: %v", err)
+	}
+	if err := app.DeleteAllFiles(); err != nil {
+		t.Fatalf("DeleteAllFiles failed: %v", err)
+	}
+	entries, err := app.ListTrash()
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("Expected 1 trash entry, got %d (err %v)", len(entries), err)
+	}
+
+	if err := app.beginCompaction(); err != nil {
+		t.Fatalf("Failed to begin a compaction: %v", err)
+	}
+	_, err = app.RestoreFromTrash(entries[0].Name)
+	app.endCompaction()
+	if utils.ErrorCodeOf(err) != utils.CodeConflict {
+		t.Fatalf("Expected a Conflict while a compaction runs, got %v", err)
+	}
+
+	if _, err := app.RestoreFromTrash(entries[0].Name); err != nil {
+		t.Fatalf("RestoreFromTrash failed: %v", err)
+	}
+	if _, err := app.GetItem(id); err != nil {
+		t.Errorf("Expected the item to be restored: %v", err)
+	}
+	if _, err := app.Compact(); err != nil {
+		t.Errorf("Expected a compaction to start once the restore is done: %v", err)
+	}
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"BinaryCRUD/backend/dao"
+	"BinaryCRUD/backend/oplog"
+	"BinaryCRUD/backend/utils"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// MaxUndoHistory is the number of recent mutations Undo can reverse
+const MaxUndoHistory = 50
+
+// undoHistory holds the logged mutations of this session that Undo can reverse, oldest first
+type undoHistory struct {
+	mu      sync.Mutex
+	ops     []oplog.Operation
+	undoing sync.Mutex // one Undo at a time, so it finds the operation its reversal logged
+}
+
+// undoable reports whether Undo can reverse an operation
+// Updates are not: the oplog records the new state of the record but not the one it replaced
+func undoable(op oplog.Operation) bool {
+	switch op.Type {
+	case oplog.OpAddItem, oplog.OpCreateOrder, oplog.OpCreatePromotion,
+		oplog.OpDeleteItem, oplog.OpDeleteOrder, oplog.OpDeletePromotion,
+		oplog.OpApplyPromotion, oplog.OpRemovePromotion:
+		return true
+	}
+	return false
+}
+
+// push adds an undoable operation to the history, dropping the oldest one past MaxUndoHistory
+func (h *undoHistory) push(op oplog.Operation) {
+	if !undoable(op) {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.ops = append(h.ops, op)
+	if len(h.ops) > MaxUndoHistory {
+		h.ops = h.ops[len(h.ops)-MaxUndoHistory:]
+	}
+}
+
+// pop removes and returns the most recent operation
+func (h *undoHistory) pop() (oplog.Operation, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(h.ops) == 0 {
+		return oplog.Operation{}, false
+	}
+	op := h.ops[len(h.ops)-1]
+	h.ops = h.ops[:len(h.ops)-1]
+	return op, true
+}
+
+// drop removes the most recent operation of the same type on the same record as op
+// Reversals are logged like any mutation, drop keeps them out of the history
+func (h *undoHistory) drop(op oplog.Operation) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for i := len(h.ops) - 1; i >= 0; i-- {
+		o := h.ops[i]
+		if o.Type == op.Type && o.ID == op.ID && o.OrderID == op.OrderID && o.PromotionID == op.PromotionID {
+			h.ops = append(h.ops[:i], h.ops[i+1:]...)
+			return
+		}
+	}
+}
+
+// list returns the operations in the history, most recent first
+func (h *undoHistory) list() []oplog.Operation {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	ops := make([]oplog.Operation, 0, len(h.ops))
+	for i := len(h.ops) - 1; i >= 0; i-- {
+		ops = append(ops, h.ops[i])
+	}
+	return ops
+}
+
+// describeOp returns what an operation did, for reporting what Undo reverses
+func describeOp(op oplog.Operation) map[string]any {
+	description := map[string]any{
+		"operation": op.Type.String(),
+		"timestamp": op.Timestamp.Format(time.RFC3339Nano),
+	}
+	switch op.Type {
+	case oplog.OpAddItem, oplog.OpDeleteItem:
+		description["entity"], description["id"], description["name"] = "item", op.ID, op.Name
+	case oplog.OpCreateOrder, oplog.OpDeleteOrder:
+		description["entity"], description["id"], description["name"] = "order", op.ID, op.Name
+	case oplog.OpCreatePromotion, oplog.OpDeletePromotion:
+		description["entity"], description["id"], description["name"] = "promotion", op.ID, op.Name
+	case oplog.OpApplyPromotion, oplog.OpRemovePromotion:
+		description["entity"], description["orderId"], description["promotionId"] = "order_promotion", op.OrderID, op.PromotionID
+	}
+	return description
+}
+
+// GetUndoHistory returns the mutations Undo can reverse, most recent first
+// Only creates, deletes and promotion applications of this session are kept, up to MaxUndoHistory
+func (a *App) GetUndoHistory() []map[string]any {
+	defer a.track("GetUndoHistory", time.Now(), nil)
+	ops := a.undo.list()
+	history := make([]map[string]any, 0, len(ops))
+	for _, op := range ops {
+		history = append(history, describeOp(op))
+	}
+	return history
+}
+
+// Undo reverses the most recent undoable mutation: a create is deleted, a delete is written back with
+// its ID and a promotion application is removed (and the other way round)
+// The reversal is logged in the oplog like any mutation, so replays and replicas follow it
+// Returns the operation that was undone; an operation that cannot be reversed stays in the history
+func (a *App) Undo() (_ map[string]any, err error) {
+	start := time.Now()
+	defer a.track("Undo", start, &err)
+	if err := a.checkWritable(); err != nil {
+		return nil, err
+	}
+
+	a.undo.undoing.Lock()
+	defer a.undo.undoing.Unlock()
+
+	op, ok := a.undo.pop()
+	if !ok {
+		return nil, utils.WithCode(utils.CodeNotFound, fmt.Errorf("nothing to undo"), nil)
+	}
+	reversal, err := a.reverse(op)
+	if err != nil {
+		a.undo.push(op)
+		return nil, fmt.Errorf("failed to undo %s: %w", op.Type, err)
+	}
+	a.undo.drop(reversal)
+
+	result := describeOp(op)
+	a.logger.Info(fmt.Sprintf("Undid %s (%v #%v)", op.Type, result["entity"], undoTarget(op)))
+	a.toast.Info(fmt.Sprintf("Undid %s", op.Type))
+	return result, nil
+}
+
+// undoTarget returns the record an operation changed, for log messages
+func undoTarget(op oplog.Operation) string {
+	if op.Type == oplog.OpApplyPromotion || op.Type == oplog.OpRemovePromotion {
+		return fmt.Sprintf("%d/%d", op.OrderID, op.PromotionID)
+	}
+	return fmt.Sprintf("%d", op.ID)
+}
+
+// reverse performs the inverse of op through the App methods, returning the operation it logged
+func (a *App) reverse(op oplog.Operation) (oplog.Operation, error) {
+	switch op.Type {
+	case oplog.OpAddItem:
+		return oplog.Operation{Type: oplog.OpDeleteItem, ID: op.ID}, a.DeleteItem(op.ID)
+	case oplog.OpCreateOrder:
+		return oplog.Operation{Type: oplog.OpDeleteOrder, ID: op.ID}, a.DeleteOrder(op.ID)
+	case oplog.OpCreatePromotion:
+		return oplog.Operation{Type: oplog.OpDeletePromotion, ID: op.ID}, a.DeletePromotion(op.ID)
+	case oplog.OpApplyPromotion:
+		return oplog.Operation{Type: oplog.OpRemovePromotion, OrderID: op.OrderID, PromotionID: op.PromotionID},
+			a.RemovePromotionFromOrder(op.OrderID, op.PromotionID)
+	case oplog.OpRemovePromotion:
+		return oplog.Operation{Type: oplog.OpApplyPromotion, OrderID: op.OrderID, PromotionID: op.PromotionID},
+			a.ApplyPromotionToOrder(op.OrderID, op.PromotionID)
+	case oplog.OpDeleteItem:
+		return oplog.Operation{Type: oplog.OpAddItem, ID: op.ID}, a.restoreItem(op)
+	case oplog.OpDeleteOrder:
+		return oplog.Operation{Type: oplog.OpCreateOrder, ID: op.ID}, a.restoreOrder(op)
+	case oplog.OpDeletePromotion:
+		return oplog.Operation{Type: oplog.OpCreatePromotion, ID: op.ID}, a.restorePromotion(op)
+	}
+	return oplog.Operation{}, fmt.Errorf("%s cannot be undone", op.Type)
+}
+
+// requireDeletedState rejects deletes logged without the record they deleted
+func requireDeletedState(op oplog.Operation) error {
+	if op.Name == "" {
+		return fmt.Errorf("the deleted record %d was not logged", op.ID)
+	}
+	return nil
+}
+
+// restoreItem writes a deleted item back with its ID, as logged by DeleteItem
+func (a *App) restoreItem(op oplog.Operation) error {
+	if err := requireDeletedState(op); err != nil {
+		return err
+	}
+	if _, err := a.itemDAO.WriteExtended(&op.ID, op.Name, op.Price, op.Extensions); err != nil {
+		return err
+	}
+	a.recordOp(oplog.Operation{Type: oplog.OpAddItem, ID: op.ID, Name: op.Name, Price: op.Price, Extensions: op.Extensions})
+	a.recordAudit(dao.AuditCreate, "item", op.ID, "", itemSummary(op.Name, op.Price))
+	return nil
+}
+
+// restoreOrder writes a deleted order back with its ID, taking again the stock its deletion returned
+func (a *App) restoreOrder(op oplog.Operation) error {
+	if err := requireDeletedState(op); err != nil {
+		return err
+	}
+	restocked := orderStatus(&dao.Collection{Extensions: op.Extensions}) != utils.OrderCancelled
+	if restocked {
+		if err := a.reserveStock(op.ItemIDs); err != nil {
+			return err
+		}
+	}
+	if _, err := a.orderDAO.WriteExtended(&op.ID, op.Name, op.Price, op.ItemIDs, op.Extensions); err != nil {
+		if restocked {
+			a.restock(op.ItemIDs)
+		}
+		return err
+	}
+	a.recordOp(oplog.Operation{Type: oplog.OpCreateOrder, ID: op.ID, Name: op.Name, Price: op.Price, ItemIDs: op.ItemIDs, Extensions: op.Extensions})
+	a.recordAudit(dao.AuditCreate, "order", op.ID, "", collectionSummary(op.Price, op.ItemIDs))
+	return nil
+}
+
+// restorePromotion writes a deleted promotion back with its ID
+func (a *App) restorePromotion(op oplog.Operation) error {
+	if err := requireDeletedState(op); err != nil {
+		return err
+	}
+	if _, err := a.promotionDAO.WriteExtended(&op.ID, op.Name, op.Price, op.ItemIDs, op.Extensions); err != nil {
+		return err
+	}
+	a.recordOp(oplog.Operation{Type: oplog.OpCreatePromotion, ID: op.ID, Name: op.Name, Price: op.Price, ItemIDs: op.ItemIDs, Extensions: op.Extensions})
+	a.recordAudit(dao.AuditCreate, "promotion", op.ID, "", collectionSummary(op.Price, op.ItemIDs))
+	return nil
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"BinaryCRUD/backend/utils"
+	"testing"
+)
+
+func TestUndoRestoresDeletedItemAndRemovesCreatedOne(t *testing.T) {
+	app := newTestApp(t)
+	kept, err := app.AddItem("Burger", 899)
+	if err != nil {
+		t.Fatalf("Failed to add item: %v", err)
+	}
+	added, err := app.AddItem("Fries", 399)
+	if err != nil {
+		t.Fatalf("Failed to add item: %v", err)
+	}
+	if err := app.DeleteItem(kept); err != nil {
+		t.Fatalf("Failed to delete item: %v", err)
+	}
+
+	undone, err := app.Undo()
+	if err != nil {
+		t.Fatalf("Undo failed: %v", err)
+	}
+	if undone["operation"] != "DeleteItem" || undone["id"] != kept {
+		t.Errorf("Expected the delete of item %d to be undone, got %v", kept, undone)
+	}
+	item, err := app.GetItem(kept)
+	if err != nil || item["name"] != "Burger" {
+		t.Fatalf("Expected the deleted item to be back, got %v (err %v)", item, err)
+	}
+
+	// The restore is not itself in the history, the next Undo reverses the create before the delete
+	if _, err := app.Undo(); err != nil {
+		t.Fatalf("Undo failed: %v", err)
+	}
+	if _, err := app.GetItem(added); err == nil {
+		t.Error("Expected the created item to be deleted by Undo")
+	}
+
+	if history := app.GetUndoHistory(); len(history) != 1 || history[0]["id"] != kept {
+		t.Errorf("Expected only the first create left in the history, got %v", history)
+	}
+}
+
+func TestUndoPromotionApplication(t *testing.T) {
+	app := newTestApp(t)
+	itemID, err := app.AddItem("Burger", 899)
+	if err != nil {
+		t.Fatalf("Failed to add item: %v", err)
+	}
+	orderID, err := app.CreateOrder("Customer", []uint64{itemID})
+	if err != nil {
+		t.Fatalf("Failed to create order: %v", err)
+	}
+	promotionID, err := app.CreatePromotion("Combo", []uint64{itemID})
+	if err != nil {
+		t.Fatalf("Failed to create promotion: %v", err)
+	}
+	if err := app.ApplyPromotionToOrder(orderID, promotionID); err != nil {
+		t.Fatalf("Failed to apply promotion: %v", err)
+	}
+
+	if _, err := app.Undo(); err != nil {
+		t.Fatalf("Undo failed: %v", err)
+	}
+	promotions, err := app.GetOrderPromotions(orderID)
+	if err != nil || len(promotions) != 0 {
+		t.Errorf("Expected the promotion to be removed from the order, got %v (err %v)", promotions, err)
+	}
+}
+
+func TestUndoWithEmptyHistory(t *testing.T) {
+	app := newTestApp(t)
+	if _, err := app.Undo(); utils.ErrorCodeOf(err) != utils.CodeNotFound {
+		t.Errorf("Expected a NotFound error, got %v", err)
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"BinaryCRUD/backend/events"
+	"BinaryCRUD/backend/webhook"
+	"fmt"
+)
+
+// startWebhooks starts delivering data change events to the webhooks of the config
+func (a *App) startWebhooks() {
+	hooks := a.currentConfig().Webhooks
+	if len(hooks) == 0 {
+		return
+	}
+
+	dispatcher := webhook.NewDispatcher(hooks, a.logger.Warn)
+	unsubscribe := a.subscribe(func(event events.Event) {
+		if dispatcher.Wants(event.Type) {
+			dispatcher.Send(webhook.Payload{Event: event, Data: a.webhookData(event)})
+		}
+	})
+	a.stopWebhooks = func() {
+		unsubscribe()
+		dispatcher.Close()
+	}
+	a.logger.Info(fmt.Sprintf("Delivering events to %d webhook(s)", len(hooks)))
+}
+
+// closeWebhooks stops the webhook worker, dropping undelivered events
+func (a *App) closeWebhooks() {
+	if a.stopWebhooks != nil {
+		a.stopWebhooks()
+		a.stopWebhooks = nil
+	}
+}
+
+// webhookData returns the record an event refers to, nil when it was deleted
+// Promotion links carry the order the promotion was applied to or removed from
+func (a *App) webhookData(event events.Event) map[string]any {
+	var data map[string]any
+	var err error
+	switch event.Type {
+	case events.ItemCreated, events.ItemUpdated:
+		data, err = a.GetItem(event.ID)
+	case events.OrderCreated, events.OrderUpdated:
+		data, err = a.GetOrder(event.ID)
+	case events.PromotionCreated, events.PromotionUpdated:
+		data, err = a.GetPromotion(event.ID)
+	case events.PromotionApplied, events.PromotionRemoved:
+		data, err = a.GetOrder(event.OrderID)
+	}
+	if err != nil {
+		return nil
+	}
+	return data
+}